@@ -0,0 +1,153 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// accessLogResponseWriter records the status and size of a response for the access and audit logs.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader records the status code before writing it.
+func (w *accessLogResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes of the response body.
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+
+	return n, err
+}
+
+// Flush implements the http.Flusher interface for streaming responses.
+func (w *accessLogResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface for WebSocket connections,
+// which are logged with the 101 Switching Protocols status.
+func (w *accessLogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("responseWriter does not implement http.Hijacker")
+	}
+
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+// accessLogMiddleware logs an entry for every request once it is served, with its method,
+// path, target context, status, response size, duration, client address and request ID.
+// Requests whose path, without the base URL, starts with one of the excluded prefixes,
+// like static assets, are not logged.
+func accessLogMiddleware(baseURL string, excludedPaths []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Path, baseURL)
+
+			if isExcludedPath(path, excludedPaths) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			start := time.Now()
+			rw := &accessLogResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			fields := map[string]string{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      strconv.Itoa(status),
+				"bytes":       strconv.Itoa(rw.bytes),
+				"duration_ms": fmt.Sprintf("%.2f", float64(time.Since(start).Microseconds())/1000),
+				"client":      clientAddr(r),
+			}
+
+			if cluster := targetCluster(path); cluster != "" {
+				fields["cluster"] = cluster
+			}
+
+			logger.LogCtx(r.Context(), logger.LevelInfo, fields, nil, "access")
+		})
+	}
+}
+
+// isExcludedPath tells whether the path starts with one of the excluded prefixes.
+func isExcludedPath(path string, excludedPaths []string) bool {
+	for _, prefix := range excludedPaths {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// targetCluster returns the cluster a request is for, from its /clusters/{clusterName}/ path.
+func targetCluster(path string) string {
+	rest, ok := strings.CutPrefix(path, "/clusters/")
+	if !ok {
+		return ""
+	}
+
+	cluster, _, _ := strings.Cut(rest, "/")
+
+	return cluster
+}
+
+// clientAddr returns the host of the client address of the request.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
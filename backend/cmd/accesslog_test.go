@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var entries []map[string]string
+
+	originalLogFunc := logger.SetLogFunc(func(level uint, str map[string]string, err interface{}, msg string) {
+		if msg == "access" {
+			entries = append(entries, str)
+		}
+	})
+	defer logger.SetLogFunc(originalLogFunc)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	})
+
+	middleware := accessLogMiddleware("/headlamp", []string{"/static/"})
+	wrapped := requestIDMiddleware(middleware(handler))
+
+	req := httptest.NewRequest(http.MethodDelete, "/headlamp/clusters/minikube/api/v1/pods/nginx", nil)
+	req.RemoteAddr = "10.0.0.1:4242"
+	rr := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rr, req)
+
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	assert.Equal(t, http.MethodDelete, entry["method"])
+	assert.Equal(t, "/headlamp/clusters/minikube/api/v1/pods/nginx", entry["path"])
+	assert.Equal(t, "minikube", entry["cluster"])
+	assert.Equal(t, "404", entry["status"])
+	assert.Equal(t, "9", entry["bytes"])
+	assert.Equal(t, "10.0.0.1", entry["client"])
+	assert.NotEmpty(t, entry["duration_ms"])
+	assert.Equal(t, rr.Header().Get(logger.RequestIDHeader), entry[logger.RequestIDField])
+
+	// Excluded paths are not logged.
+	wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/headlamp/static/main.js", nil))
+	assert.Len(t, entries, 1)
+}
+
+func TestTargetCluster(t *testing.T) {
+	assert.Equal(t, "minikube", targetCluster("/clusters/minikube/version"))
+	assert.Equal(t, "minikube", targetCluster("/clusters/minikube"))
+	assert.Equal(t, "", targetCluster("/config"))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// apiTokenMiddleware authenticates the requests with an API token in the X-HEADLAMP-API-TOKEN
+// header, so automation and CLI tools can call the backend without a browser session. Their
+// context carries the name of the token, and they are accepted where the backend token is.
+// The requests with an invalid API token are rejected. The header is not passed on, so it
+// isn't proxied to the clusters.
+func apiTokenMiddleware(authenticator *auth.APITokenAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(auth.APITokenHeader)
+			if token == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			r.Header.Del(auth.APITokenHeader)
+
+			name, err := authenticator.Authenticate(token)
+			if err != nil {
+				logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"path": r.URL.Path},
+					err, "rejecting request with invalid API token")
+				http.Error(w, "invalid API token", http.StatusUnauthorized)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithAPITokenName(r.Context(), name)))
+		})
+	}
+}
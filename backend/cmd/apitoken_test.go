@@ -0,0 +1,75 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenMiddleware(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-secret")
+
+	tokensFile := filepath.Join(t.TempDir(), "tokens.csv")
+	require.NoError(t, os.WriteFile(tokensFile, []byte("ci-token,ci\n"), 0o600))
+
+	authenticator, err := auth.NewAPITokenAuthenticator(auth.APITokenOptions{TokensFile: tokensFile})
+	require.NoError(t, err)
+
+	var gotName, gotHeader string
+
+	handler := apiTokenMiddleware(authenticator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotName = auth.APITokenNameFromContext(r.Context())
+		gotHeader = r.Header.Get(auth.APITokenHeader)
+
+		// The API token is accepted where the backend token is.
+		if err := checkHeadlampBackendToken(w, r); err != nil {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/cluster/minikube", nil)
+		if token != "" {
+			req.Header.Set(auth.APITokenHeader, token)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve("ci-token"))
+	assert.Equal(t, "ci", gotName)
+	assert.Empty(t, gotHeader)
+
+	assert.Equal(t, http.StatusUnauthorized, serve("wrong-token"))
+
+	// Without an API token, the backend token is still required.
+	assert.Equal(t, http.StatusForbidden, serve(""))
+	assert.Empty(t, gotName)
+}
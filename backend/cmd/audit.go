@@ -0,0 +1,168 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// requestUser returns who made the request: the user of its token, or the API token it is
+// authenticated with, or "" if unknown.
+func requestUser(r *http.Request) string {
+	if name := auth.APITokenNameFromContext(r.Context()); name != "" {
+		return "apitoken:" + name
+	}
+
+	return audit.UserFromToken(requestToken(r))
+}
+
+// requestToken returns the token the request is made with: the one overriding the
+// credentials of the context if any, as the request is made as its user, or else the
+// bearer token or cookie of the cluster.
+func requestToken(r *http.Request) string {
+	if tokenOverride, err := auth.TokenOverride(r); err == nil && tokenOverride != "" {
+		return tokenOverride
+	}
+
+	_, token := auth.ParseClusterAndToken(r)
+
+	return token
+}
+
+// newAuditEvent creates an audit event of the kind, telling who made the request.
+func newAuditEvent(r *http.Request, kind string) audit.Event {
+	return audit.Event{
+		Kind:      kind,
+		Time:      time.Now().UTC(),
+		RequestID: logger.RequestIDFromContext(r.Context()),
+		User:      requestUser(r),
+		Session:   r.Header.Get("X-HEADLAMP-USER-ID"),
+		Client:    clientAddr(r),
+	}
+}
+
+// recordAuditEvent records the event, if auditing is enabled.
+func (c *HeadlampConfig) recordAuditEvent(r *http.Request, event audit.Event) {
+	if c.auditRecorder == nil {
+		return
+	}
+
+	if err := c.auditRecorder.Record(event); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": event.Context, "verb": event.Verb},
+			err, "recording audit event")
+	}
+}
+
+// auditRequest starts auditing a request proxied to a cluster. The event is taken from
+// the request before it is rewritten for the cluster. It returns the response writer
+// to serve the request with, and the function recording the event once it is served.
+func (c *HeadlampConfig) auditRequest(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	event := newAuditEvent(r, audit.KindRequest)
+	event.Context = mux.Vars(r)["clusterName"]
+	event.SetRequest(r.Method, "/"+mux.Vars(r)["api"])
+
+	rw := &accessLogResponseWriter{ResponseWriter: w}
+
+	return rw, func() {
+		event.SetStatus(rw.status)
+		event.DryRun = rw.Header().Get(DryRunHeader) == "true"
+		c.recordAuditEvent(r, event)
+	}
+}
+
+// contextAuditEvent creates the audit event of an operation made on a context of the store
+// for a request. The context, which may be nil, gives the source and the original name of
+// the context, and err the outcome of the operation.
+func contextAuditEvent(r *http.Request, verb, name string, kContext *kubeconfig.Context, err error) audit.Event {
+	event := newAuditEvent(r, audit.KindContext)
+	event.Verb = verb
+	event.Context = name
+
+	if kContext != nil {
+		event.Source = kContext.SourceStr()
+
+		if kContext.OriginalName != name {
+			event.OriginalName = kContext.OriginalName
+		}
+	}
+
+	event.SetError(err)
+
+	return event
+}
+
+// handleAuditContexts returns the recent operations on the contexts, oldest first.
+// They can be filtered with the context, since (RFC 3339) and limit query parameters.
+func (c *HeadlampConfig) handleAuditContexts(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	query := audit.Query{Context: r.URL.Query().Get("context")}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "invalid since, must be an RFC 3339 time", http.StatusBadRequest)
+
+			return
+		}
+
+		query.Since = t
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+
+			return
+		}
+
+		query.Limit = n
+	}
+
+	if c.auditRecorder == nil {
+		http.Error(w, "audit history is disabled", http.StatusNotFound)
+
+		return
+	}
+
+	events, ok := c.auditRecorder.ContextEvents(query)
+	if !ok {
+		http.Error(w, "audit history is disabled", http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding audit events")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink keeps the audit events in memory.
+type memorySink struct {
+	events []audit.Event
+}
+
+func (s *memorySink) Record(event audit.Event) error {
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestAuditRequest(t *testing.T) {
+	sink := &memorySink{}
+	c := &HeadlampConfig{auditRecorder: audit.NewRecorderWithSinks(sink)}
+
+	api := "apis/apps/v1/namespaces/default/deployments/nginx"
+
+	req := httptest.NewRequest(http.MethodDelete, "/clusters/minikube/"+api, nil)
+	req.Header.Set("X-HEADLAMP-USER-ID", "user-1")
+	req = mux.SetURLVars(req, map[string]string{
+		"clusterName": "minikube",
+		"api":         api,
+	})
+
+	rr := httptest.NewRecorder()
+
+	w, finish := c.auditRequest(rr, req)
+	w.WriteHeader(http.StatusForbidden)
+	finish()
+
+	require.Len(t, sink.events, 1)
+
+	event := sink.events[0]
+	assert.Equal(t, audit.KindRequest, event.Kind)
+	assert.Equal(t, "minikube", event.Context)
+	assert.Equal(t, "user-1", event.Session)
+	assert.Equal(t, "delete", event.Verb)
+	assert.Equal(t, "deployments", event.Resource)
+	assert.Equal(t, "default", event.Namespace)
+	assert.Equal(t, "nginx", event.Name)
+	assert.Equal(t, http.StatusForbidden, event.Status)
+	assert.Equal(t, audit.OutcomeFailure, event.Outcome)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAuditContexts(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "secret")
+
+	recorder, err := audit.NewRecorder(audit.Options{HistorySize: 10})
+	require.NoError(t, err)
+
+	c := &HeadlampConfig{auditRecorder: recorder}
+
+	req := httptest.NewRequest(http.MethodPost, "/cluster", nil)
+	req.Header.Set("X-HEADLAMP-USER-ID", "admin-1")
+
+	kContext := &kubeconfig.Context{
+		Name:         "arn:aws:eks:us-east-1:123:cluster--prod",
+		OriginalName: "arn:aws:eks:us-east-1:123:cluster/prod",
+		Source:       kubeconfig.DynamicCluster,
+	}
+
+	c.recordAuditEvent(req, contextAuditEvent(req, audit.VerbAddContext, kContext.Name, kContext, nil))
+	c.recordAuditEvent(req, contextAuditEvent(req, audit.VerbRemoveContext, "other", nil, errors.New("key not found")))
+
+	query := func(target, token string) (*httptest.ResponseRecorder, []audit.Event) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", token)
+
+		rr := httptest.NewRecorder()
+		c.handleAuditContexts(rr, req)
+
+		var events []audit.Event
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&events))
+		}
+
+		return rr, events
+	}
+
+	rr, _ := query("/audit/contexts", "wrong")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	_, events := query("/audit/contexts", "secret")
+	require.Len(t, events, 2)
+
+	assert.Equal(t, audit.KindContext, events[0].Kind)
+	assert.Equal(t, audit.VerbAddContext, events[0].Verb)
+	assert.Equal(t, "admin-1", events[0].Session)
+	assert.Equal(t, "dynamic_cluster", events[0].Source)
+	assert.Equal(t, kContext.OriginalName, events[0].OriginalName)
+	assert.Equal(t, audit.OutcomeSuccess, events[0].Outcome)
+
+	assert.Equal(t, audit.OutcomeFailure, events[1].Outcome)
+	assert.Equal(t, "key not found", events[1].Error)
+
+	_, events = query("/audit/contexts?context=other", "secret")
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.VerbRemoveContext, events[0].Verb)
+
+	rr, _ = query("/audit/contexts?since=yesterday", "secret")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
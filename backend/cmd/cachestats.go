@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// cacheStats returns the statistics of the named caches: the contexts, the tokens, and the
+// discovery documents if they are cached.
+func (c *HeadlampConfig) cacheStats() map[string]cache.Stats {
+	stats := map[string]cache.Stats{}
+
+	if c.KubeConfigStore != nil {
+		stats["contexts"] = c.KubeConfigStore.Stats()
+	}
+
+	if c.cache != nil {
+		stats["tokens"] = c.cache.Stats()
+	}
+
+	if c.discoveryCache != nil {
+		stats["discovery"] = c.discoveryCache.Stats()
+	}
+
+	return stats
+}
+
+// handleCacheStats returns the statistics of the caches, by name.
+func (c *HeadlampConfig) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(c.cacheStats()); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding cache stats")
+	}
+}
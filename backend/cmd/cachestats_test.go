@@ -0,0 +1,76 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/k8cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCacheStats(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-token")
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube"}))
+
+	tokens := cache.New[interface{}]()
+	require.NoError(t, tokens.Set(context.Background(), "refresh-token", "secret"))
+
+	c := &HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{KubeConfigStore: store},
+		cache:       tokens,
+	}
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/cache-stats", nil)
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", token)
+
+		rr := httptest.NewRecorder()
+		c.handleCacheStats(rr, req)
+
+		return rr
+	}
+
+	rr := request("backend-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "secret")
+
+	var stats map[string]cache.Stats
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Equal(t, 1, stats["contexts"].Entries)
+	assert.Equal(t, 1, stats["tokens"].Entries)
+	assert.Equal(t, int64(len("refresh-token")+len("secret")), stats["tokens"].Bytes)
+	assert.NotContains(t, stats, "discovery")
+
+	c.discoveryCache = k8cache.NewDiscoveryCache(cache.New[string](), nil)
+
+	rr = request("backend-token")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Contains(t, stats, "discovery")
+
+	assert.Equal(t, http.StatusForbidden, request("wrong").Code)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 )
 
@@ -52,3 +53,23 @@ type RenameClusterRequest struct {
 	Source         string `json:"source"`
 	Stateless      bool   `json:"stateless"`
 }
+
+// PatchClusterRequest is the request body structure for updating a dynamic cluster in place.
+// The fields left out are unchanged.
+type PatchClusterRequest struct {
+	// Token is the new bearer token of the cluster.
+	Token *string `json:"token,omitempty"`
+	// Server is the new URL of the API server of the cluster.
+	Server *string `json:"server,omitempty"`
+	// TTL is the new time to live of a stateless cluster, as a duration like 30m.
+	TTL *string `json:"ttl,omitempty"`
+	// Labels replace the labels of the cluster.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ClusterMetadataRequest is the request body structure for updating the display metadata of a cluster.
+type ClusterMetadataRequest struct {
+	kubeconfig.DisplayMetadata
+	Source    string `json:"source"`
+	Stateless bool   `json:"stateless"`
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/helm"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// clusterEventComponent is the component reporting the Events recorded in the clusters.
+	clusterEventComponent = "headlamp"
+	// clusterEventUserAnnotation is the annotation of the Events telling who made the action.
+	clusterEventUserAnnotation = "headlamp.dev/user"
+	// clusterEventTimeout is the timeout of the recording of an Event in a cluster.
+	clusterEventTimeout = 10 * time.Second
+	// unverifiedUser is the user of the Events of the requests not authenticated by the backend.
+	unverifiedUser = "unverified"
+)
+
+// scaleKinds are the kinds of the usual resources with a scale subresource, by resource.
+var scaleKinds = map[string]string{
+	"deployments":            "Deployment",
+	"statefulsets":           "StatefulSet",
+	"replicasets":            "ReplicaSet",
+	"replicationcontrollers": "ReplicationController",
+}
+
+// clusterEvent is an action of the backend on a cluster, recorded as a Kubernetes Event
+// in it, so the audit trails of the cluster show the changes made through Headlamp.
+type clusterEvent struct {
+	object corev1.ObjectReference
+	// reason is the reason of the Event if the action succeeded, with Failed appended if not.
+	reason  string
+	message string
+	// user is who the action was made for, "" if unknown.
+	user string
+	// err is why the action failed, nil if it succeeded.
+	err error
+}
+
+// kubeEvent returns the Kubernetes Event of the action.
+func (e clusterEvent) kubeEvent(now time.Time) *corev1.Event {
+	eventType, reason, message := corev1.EventTypeNormal, e.reason, e.message+" through Headlamp"
+	if e.user != "" {
+		message += " by " + e.user
+	}
+
+	if e.err != nil {
+		eventType, reason = corev1.EventTypeWarning, reason+"Failed"
+		message += ": " + e.err.Error()
+	}
+
+	// The Events of the cluster-scoped objects, like the nodes, go to the default namespace.
+	namespace := e.object.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: e.object.Name + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject:      e.object,
+		Reason:              reason,
+		Message:             message,
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: clusterEventComponent},
+		FirstTimestamp:      metav1.NewTime(now),
+		LastTimestamp:       metav1.NewTime(now),
+		Count:               1,
+		ReportingController: clusterEventComponent,
+	}
+
+	if e.user != "" {
+		event.Annotations = map[string]string{clusterEventUserAnnotation: e.user}
+	}
+
+	return event
+}
+
+// eventUser returns who made the request, for the Events recorded in the clusters: the API
+// token it is authenticated with, or the user of its OIDC token if the backend verified it at
+// login. Unlike the audit log, the user the other tokens claim isn't trusted, as anyone could
+// make such a token, so they are recorded as unverifiedUser.
+func (c *HeadlampConfig) eventUser(r *http.Request) string {
+	if name := auth.APITokenNameFromContext(r.Context()); name != "" {
+		return "apitoken:" + name
+	}
+
+	if c.cache != nil {
+		if user := auth.VerifiedUser(r.Context(), c.cache, requestToken(r)); user != "" {
+			return user
+		}
+	}
+
+	return unverifiedUser
+}
+
+// recordClusterEvent records the event in the cluster of the context with the clientset, if
+// the context records the actions of the backend. A failure to record it is only logged, as
+// the action itself is done.
+func (c *HeadlampConfig) recordClusterEvent(clientset kubernetes.Interface, kContext *kubeconfig.Context,
+	event clusterEvent,
+) {
+	if !kContext.RecordsEvents(c.recordClusterEvents) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterEventTimeout)
+	defer cancel()
+
+	kubeEvent := event.kubeEvent(time.Now())
+
+	_, err := clientset.CoreV1().Events(kubeEvent.Namespace).Create(ctx, kubeEvent, metav1.CreateOptions{})
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": kContext.Name, "reason": kubeEvent.Reason},
+			err, "recording cluster event")
+	}
+}
+
+// nodeDrainEvent returns the event of the drain of a node.
+func nodeDrainEvent(nodeName, user string, err error) clusterEvent {
+	return clusterEvent{
+		object:  corev1.ObjectReference{APIVersion: "v1", Kind: "Node", Name: nodeName},
+		reason:  "NodeDrain",
+		message: fmt.Sprintf("Node %s drained", nodeName),
+		user:    user,
+		err:     err,
+	}
+}
+
+// helmReleaseEvent returns the event of an action on a helm release. The releases aren't
+// Kubernetes objects, so the Event is about a helm.sh Release in the namespace of the release.
+func helmReleaseEvent(result helm.ActionResult, user string) clusterEvent {
+	action := result.Action
+	if action != "" {
+		action = strings.ToUpper(action[:1]) + action[1:]
+	}
+
+	return clusterEvent{
+		object: corev1.ObjectReference{
+			APIVersion: "helm.sh/v3",
+			Kind:       "Release",
+			Namespace:  result.Namespace,
+			Name:       result.Release,
+		},
+		reason:  "Helm" + action,
+		message: fmt.Sprintf("Helm %s of release %s", result.Action, result.Release),
+		user:    user,
+		err:     result.Err,
+	}
+}
+
+// scaleEvent returns the event of a request proxied to the scale subresource of a resource.
+func scaleEvent(info apirequest.Info, user string) clusterEvent {
+	kind := scaleKinds[info.Resource]
+	if kind == "" {
+		kind = info.Resource
+	}
+
+	apiVersion := info.APIVersion
+	if info.APIGroup != "" {
+		apiVersion = info.APIGroup + "/" + info.APIVersion
+	}
+
+	return clusterEvent{
+		object: corev1.ObjectReference{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Namespace:  info.Namespace,
+			Name:       info.Name,
+		},
+		reason:  "Scale",
+		message: fmt.Sprintf("%s %s scaled", kind, info.Name),
+		user:    user,
+	}
+}
+
+// isScaleRequest tells whether the request proxied to a cluster changes the scale of a resource.
+func isScaleRequest(method string, info apirequest.Info) bool {
+	return info.Subresource == "scale" && info.Name != "" &&
+		(method == http.MethodPut || method == http.MethodPatch)
+}
+
+// recordScaleEvent records the scaling of the resource of the request, proxied to the cluster
+// of the context with the response writer rw, if it succeeded. It is recorded in the
+// background, with the token of the request, or tokenOverride if set.
+func (c *HeadlampConfig) recordScaleEvent(r *http.Request, rw *accessLogResponseWriter,
+	kContext *kubeconfig.Context, info apirequest.Info, user, tokenOverride string,
+) {
+	if rw.status < http.StatusOK || rw.status >= http.StatusMultipleChoices {
+		return
+	}
+
+	token := tokenOverride
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	clientset, err := kContext.ClientSetWithToken(token)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": kContext.Name}, err, "getting client")
+
+		return
+	}
+
+	go c.recordClusterEvent(clientset, kContext, scaleEvent(info, user))
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/helm"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestClusterEventKubeEvent(t *testing.T) {
+	now := time.Now()
+
+	event := nodeDrainEvent("node-1", "alice@example.com", nil).kubeEvent(now)
+	assert.Equal(t, metav1.NamespaceDefault, event.Namespace)
+	assert.Equal(t, "node-1.", event.GenerateName)
+	assert.Equal(t, "Node", event.InvolvedObject.Kind)
+	assert.Equal(t, corev1.EventTypeNormal, event.Type)
+	assert.Equal(t, "NodeDrain", event.Reason)
+	assert.Equal(t, "Node node-1 drained through Headlamp by alice@example.com", event.Message)
+	assert.Equal(t, "alice@example.com", event.Annotations[clusterEventUserAnnotation])
+	assert.Equal(t, clusterEventComponent, event.Source.Component)
+
+	event = helmReleaseEvent(helm.ActionResult{
+		Action: "install", Release: "nginx", Namespace: "web", Err: errors.New("chart not found"),
+	}, "").kubeEvent(now)
+	assert.Equal(t, "web", event.Namespace)
+	assert.Equal(t, corev1.EventTypeWarning, event.Type)
+	assert.Equal(t, "HelmInstallFailed", event.Reason)
+	assert.Equal(t, "Helm install of release nginx through Headlamp: chart not found", event.Message)
+	assert.Empty(t, event.Annotations)
+
+	// An action the helm handler didn't name doesn't panic.
+	event = helmReleaseEvent(helm.ActionResult{Release: "nginx", Namespace: "web"}, "").kubeEvent(now)
+	assert.Equal(t, "Helm", event.Reason)
+
+	info := apirequest.Parse(http.MethodPatch, "/apis/apps/v1/namespaces/web/deployments/nginx/scale", nil)
+	event = scaleEvent(info, "bob").kubeEvent(now)
+	assert.Equal(t, "web", event.Namespace)
+	assert.Equal(t, "apps/v1", event.InvolvedObject.APIVersion)
+	assert.Equal(t, "Deployment", event.InvolvedObject.Kind)
+	assert.Equal(t, "Deployment nginx scaled through Headlamp by bob", event.Message)
+}
+
+func TestEventUser(t *testing.T) {
+	c := &HeadlampConfig{cache: cache.New[interface{}]()}
+
+	// A JWT claiming alice@example.com, which anyone could make.
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"email": "alice@example.com"}`))
+	token := "header." + claims + ".signature"
+
+	request := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/drain-node", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return req
+	}
+
+	assert.Equal(t, unverifiedUser, c.eventUser(request(token)))
+
+	req := request(token)
+	assert.Equal(t, "apitoken:ci", c.eventUser(req.WithContext(auth.WithAPITokenName(req.Context(), "ci"))))
+
+	// The token was verified at login.
+	require.NoError(t, auth.CacheVerifiedUser(context.Background(), c.cache, token, "alice@example.com",
+		time.Now().Add(time.Hour)))
+	assert.Equal(t, "alice@example.com", c.eventUser(request(token)))
+}
+
+func TestRecordClusterEvent(t *testing.T) {
+	recordEvents := func(record *bool) *kubeconfig.Context {
+		kContext := &kubeconfig.Context{Name: "test", KubeContext: &api.Context{}}
+		if record != nil {
+			kContext.KubeContext.Extensions = map[string]runtime.Object{
+				"headlamp_info": &kubeconfig.CustomObject{RecordEvents: record},
+			}
+		}
+
+		return kContext
+	}
+
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name      string
+		byDefault bool
+		kContext  *kubeconfig.Context
+		want      int
+	}{
+		{name: "disabled by default", kContext: recordEvents(nil), want: 0},
+		{name: "enabled by default", byDefault: true, kContext: recordEvents(nil), want: 1},
+		{name: "enabled for the context", kContext: recordEvents(&enabled), want: 1},
+		{name: "disabled for the context", byDefault: true, kContext: recordEvents(&disabled), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			c := &HeadlampConfig{recordClusterEvents: tt.byDefault}
+
+			c.recordClusterEvent(clientset, tt.kContext, nodeDrainEvent("node-1", "", nil))
+
+			events, err := clientset.CoreV1().Events(metav1.NamespaceDefault).List(context.Background(),
+				metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Len(t, events.Items, tt.want)
+		})
+	}
+}
+
+func TestScaleRecordsClusterEvent(t *testing.T) {
+	recorded := make(chan corev1.Event, 1)
+
+	cluster := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/scale"):
+			_, _ = w.Write([]byte(`{"kind": "Scale", "apiVersion": "autoscaling/v1", "spec": {"replicas": 3}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/web/events":
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			// The clientsets may send the events as protobuf.
+			obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(body, nil, nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			event, _ := obj.(*corev1.Event)
+			if !assert.NotNil(t, event) {
+				return
+			}
+
+			recorded <- *event
+
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(event)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer cluster.Close()
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cluster.Certificate().Raw})
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "test",
+		KubeContext: &api.Context{Cluster: "test", AuthInfo: "test"},
+		Cluster:     &api.Cluster{Server: cluster.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{Token: "stored"},
+	}))
+
+	c := HeadlampConfig{
+		HeadlampCFG:         &headlampconfig.HeadlampCFG{KubeConfigStore: kubeConfigStore},
+		cache:               cache.New[interface{}](),
+		telemetryConfig:     GetDefaultTestTelemetryConfig(),
+		telemetryHandler:    &telemetry.RequestHandler{},
+		recordClusterEvents: true,
+	}
+
+	handler := createHeadlampHandler(&c)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPatch,
+		"/clusters/test/apis/apps/v1/namespaces/web/deployments/nginx/scale", strings.NewReader(`{"spec":{"replicas":3}}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	select {
+	case event := <-recorded:
+		assert.Equal(t, "Scale", event.Reason)
+		assert.Equal(t, "nginx", event.InvolvedObject.Name)
+		assert.Equal(t, "Deployment", event.InvolvedObject.Kind)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scaling wasn't recorded as an event")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// addDisplayMetadata adds the display metadata that is set to the metadata of a cluster.
+func addDisplayMetadata(metadata map[string]interface{}, display kubeconfig.DisplayMetadata) {
+	if display.Icon != "" {
+		metadata["icon"] = display.Icon
+	}
+
+	if display.Color != "" {
+		metadata["color"] = display.Color
+	}
+
+	if display.Description != "" {
+		metadata["description"] = display.Description
+	}
+}
+
+// updateClusterMetadata updates the icon, color and description of a cluster. They're written
+// to the headlamp_info extension of the context in its kubeconfig file, so they're the same for
+// all the users, or kept in the store only for the stateless clusters.
+func (c *HeadlampConfig) updateClusterMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+	clusterName := mux.Vars(r)["name"]
+
+	_, span := telemetry.CreateSpan(ctx, r, "cluster-metadata", "updateClusterMetadata",
+		attribute.String("cluster", clusterName),
+	)
+	defer span.End()
+
+	c.telemetryHandler.RecordRequestCount(ctx, r, attribute.String("cluster", clusterName))
+
+	var reqBody ClusterMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		c.handleError(w, ctx, span, err, "failed to decode request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := reqBody.Validate(); err != nil {
+		c.handleError(w, ctx, span, err, "invalid cluster metadata", http.StatusBadRequest)
+		return
+	}
+
+	kContext, err := c.KubeConfigStore.GetContext(clusterName)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "failed to get context", http.StatusNotFound)
+		return
+	}
+
+	updated := *kContext
+	updated.DisplayMetadata = reqBody.DisplayMetadata
+
+	if !reqBody.Stateless {
+		kubeContext, err := c.writeDisplayMetadata(reqBody.Source, kContext, reqBody.DisplayMetadata)
+		if err != nil {
+			c.recordAuditEvent(r, contextAuditEvent(r, audit.VerbUpdateMetadata, clusterName, kContext, err))
+			c.handleError(w, ctx, span, err, "failed to write cluster metadata", http.StatusInternalServerError)
+
+			return
+		}
+
+		updated.KubeContext = kubeContext
+	}
+
+	if kContext.Internal {
+		err = c.KubeConfigStore.AddContextWithKeyAndTTL(&updated, clusterName, c.statelessContextTTL())
+	} else {
+		err = c.KubeConfigStore.AddContext(&updated)
+	}
+
+	c.recordAuditEvent(r, contextAuditEvent(r, audit.VerbUpdateMetadata, clusterName, kContext, err))
+
+	if err != nil {
+		c.handleError(w, ctx, span, err, "failed to update context in the store", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	c.getConfig(w, r)
+
+	c.telemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "updateClusterMetadata"))
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{
+		"duration_ms": fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+		"api.route":   "updateClusterMetadata",
+	}, nil, "Completed updateClusterMetadata request")
+}
+
+// writeDisplayMetadata writes the display metadata to the headlamp_info extension of the context
+// in the kubeconfig file of source, keeping its other fields, and returns the updated context.
+func (c *HeadlampConfig) writeDisplayMetadata(source string, kContext *kubeconfig.Context,
+	metadata kubeconfig.DisplayMetadata,
+) (*api.Context, error) {
+	path, config, err := c.getPathAndLoadKubeconfig(source, kContext.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	contextName := findMatchingContextName(config, kContext.Name)
+	if _, ok := config.Contexts[contextName]; !ok && kContext.OriginalName != "" {
+		contextName = kContext.OriginalName
+	}
+
+	contextConfig, ok := config.Contexts[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in %s", kContext.Name, path)
+	}
+
+	customObj := kubeconfig.CustomObject{}
+
+	if info := contextConfig.Extensions["headlamp_info"]; info != nil {
+		if customObj, err = MarshalCustomObject(info, contextName); err != nil {
+			return nil, err
+		}
+	}
+
+	customObj.DisplayMetadata = metadata
+
+	if contextConfig.Extensions == nil {
+		contextConfig.Extensions = map[string]runtime.Object{}
+	}
+
+	contextConfig.Extensions["headlamp_info"] = &customObj
+
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": contextName},
+			err, "writing kubeconfig file")
+
+		return nil, err
+	}
+
+	return contextConfig, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+//nolint:funlen
+func TestUpdateClusterMetadata(t *testing.T) {
+	kubeConfigByte, err := os.ReadFile("./headlamp_testdata/kubeconfig")
+	require.NoError(t, err)
+
+	kubeConfigPath := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfigPath, kubeConfigByte, 0o600))
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	require.NoError(t, kubeconfig.LoadAndStoreKubeConfigs(kubeConfigStore, kubeConfigPath, kubeconfig.KubeConfig, nil))
+
+	stateless := &kubeconfig.Context{Name: "stateless", Source: kubeconfig.DynamicCluster, Internal: true}
+	require.NoError(t, kubeConfigStore.AddContextWithKeyAndTTL(stateless, "statelessuser1", time.Minute))
+
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			KubeConfigPath:        kubeConfigPath,
+			EnableDynamicClusters: true,
+			KubeConfigStore:       kubeConfigStore,
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+	handler := createHeadlampHandler(&c)
+
+	metadata := kubeconfig.DisplayMetadata{Icon: "mdi:fire", Color: "#d32f2f", Description: "Production"}
+
+	t.Run("kubeconfig", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PUT", "/cluster/minikube/metadata",
+			ClusterMetadataRequest{DisplayMetadata: metadata, Source: "kubeconfig"})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		kContext, err := kubeConfigStore.GetContext("minikube")
+		require.NoError(t, err)
+		assert.Equal(t, metadata, kContext.DisplayMetadata)
+
+		// It's written to the kubeconfig, so it's loaded again on restart.
+		config, err := clientcmd.LoadFromFile(kubeConfigPath)
+		require.NoError(t, err)
+
+		customObj, err := MarshalCustomObject(config.Contexts["minikube"].Extensions["headlamp_info"], "minikube")
+		require.NoError(t, err)
+		assert.Equal(t, metadata, customObj.DisplayMetadata)
+		assert.Contains(t, config.Contexts["minikube"].Extensions, "context_info")
+
+		for _, cluster := range c.getClusters() {
+			if cluster.Name == "minikube" {
+				assert.Equal(t, "mdi:fire", cluster.Metadata["icon"])
+				assert.Equal(t, "#d32f2f", cluster.Metadata["color"])
+				assert.Equal(t, "Production", cluster.Metadata["description"])
+			}
+		}
+	})
+
+	t.Run("stateless", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PUT", "/cluster/statelessuser1/metadata",
+			ClusterMetadataRequest{DisplayMetadata: metadata, Stateless: true})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		kContext, err := kubeConfigStore.GetContext("statelessuser1")
+		require.NoError(t, err)
+		assert.Equal(t, metadata, kContext.DisplayMetadata)
+		assert.True(t, kContext.Internal)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PUT", "/cluster/minikube/metadata",
+			ClusterMetadataRequest{DisplayMetadata: kubeconfig.DisplayMetadata{Color: "red"}, Source: "kubeconfig"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		kContext, err := kubeConfigStore.GetContext("minikube")
+		require.NoError(t, err)
+		assert.Equal(t, metadata, kContext.DisplayMetadata)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PUT", "/cluster/unknown/metadata",
+			ClusterMetadataRequest{DisplayMetadata: metadata, Source: "kubeconfig"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
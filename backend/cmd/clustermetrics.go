@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/clustermetrics"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// clusterMetricsResponse are the metrics of the requested clusters.
+type clusterMetricsResponse struct {
+	Clusters []*clustermetrics.Snapshot `json:"clusters"`
+}
+
+// clusterToken returns the token of the user for the cluster, from the Authorization
+// header or else from the cluster cookie.
+func clusterToken(r *http.Request, cluster string) string {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if token != "" {
+		return token
+	}
+
+	token, _ = auth.GetTokenFromCookie(r, cluster)
+
+	return token
+}
+
+// handleClusterMetrics returns the node and pod metrics of the clusters of the cluster query
+// parameters, which can be repeated. The metrics are polled and cached by the aggregator,
+// so the clients share them.
+func (c *HeadlampConfig) handleClusterMetrics(w http.ResponseWriter, r *http.Request) {
+	clusters := r.URL.Query()["cluster"]
+	if len(clusters) == 0 {
+		http.Error(w, "cluster is required", http.StatusBadRequest)
+
+		return
+	}
+
+	resp := clusterMetricsResponse{Clusters: make([]*clustermetrics.Snapshot, 0, len(clusters))}
+
+	for _, cluster := range clusters {
+		resp.Clusters = append(resp.Clusters, c.clusterMetrics.Get(r.Context(), cluster, clusterToken(r, cluster)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding cluster metrics")
+	}
+}
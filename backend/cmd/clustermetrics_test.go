@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/clustermetrics"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleClusterMetrics(t *testing.T) {
+	fetch := func(ctx context.Context, cluster, token string) (*clustermetrics.Snapshot, error) {
+		return &clustermetrics.Snapshot{
+			Cluster: cluster,
+			Nodes:   []clustermetrics.NodeMetrics{{Name: "node-" + token}},
+		}, nil
+	}
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "one"}))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "two"}))
+
+	c := &HeadlampConfig{
+		clusterMetrics: clustermetrics.NewAggregator(cache.New[interface{}](), time.Minute, store, fetch),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/cluster-metrics", nil)
+	rr := httptest.NewRecorder()
+	c.handleClusterMetrics(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/cluster-metrics?cluster=one&cluster=two&cluster=three", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+
+	rr = httptest.NewRecorder()
+	c.handleClusterMetrics(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp clusterMetricsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Clusters, 3)
+	assert.Equal(t, "one", resp.Clusters[0].Cluster)
+	assert.Equal(t, "two", resp.Clusters[1].Cluster)
+	assert.Equal(t, "node-abc", resp.Clusters[0].Nodes[0].Name)
+
+	// Clusters that aren't in the store get an error.
+	assert.Equal(t, "three", resp.Clusters[2].Cluster)
+	assert.NotEmpty(t, resp.Clusters[2].Error)
+	assert.Empty(t, resp.Clusters[2].Nodes)
+}
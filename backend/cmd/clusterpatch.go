@@ -0,0 +1,128 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// errNotDynamicCluster is returned when patching a cluster that wasn't added dynamically.
+var errNotDynamicCluster = errors.New("only the dynamically added clusters can be patched")
+
+// contextUpdate returns the store update of the request.
+func (req PatchClusterRequest) contextUpdate() (kubeconfig.ContextUpdate, error) {
+	update := kubeconfig.ContextUpdate{
+		Token:  req.Token,
+		Server: req.Server,
+		Labels: req.Labels,
+	}
+
+	if req.TTL != nil {
+		ttl, err := time.ParseDuration(*req.TTL)
+		if err != nil {
+			return kubeconfig.ContextUpdate{}, fmt.Errorf("%w: ttl: %v", kubeconfig.ErrInvalidUpdate, err)
+		}
+
+		update.TTL = &ttl
+	}
+
+	return update, update.Validate()
+}
+
+// patchCluster updates the token, server, ttl or labels of a dynamic cluster in the store,
+// without removing it, so the watches open on it aren't broken. Only the stateless clusters
+// have a ttl.
+func (c *HeadlampConfig) patchCluster(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+	clusterName := mux.Vars(r)["name"]
+
+	_, span := telemetry.CreateSpan(ctx, r, "cluster-management", "patchCluster",
+		attribute.String("cluster", clusterName),
+	)
+	defer span.End()
+
+	c.telemetryHandler.RecordRequestCount(ctx, r, attribute.String("cluster", clusterName))
+
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	var reqBody PatchClusterRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		c.handleError(w, ctx, span, err, "failed to decode request body", http.StatusBadRequest)
+		return
+	}
+
+	update, err := reqBody.contextUpdate()
+	if err != nil {
+		c.handleError(w, ctx, span, err, "invalid cluster update", http.StatusBadRequest)
+		return
+	}
+
+	kContext, err := c.KubeConfigStore.GetContext(clusterName)
+	if err != nil {
+		c.handleError(w, ctx, span, err, "failed to get context", http.StatusNotFound)
+		return
+	}
+
+	if kContext.Source != kubeconfig.DynamicCluster {
+		c.handleError(w, ctx, span, errNotDynamicCluster, "cluster is not a dynamic cluster", http.StatusBadRequest)
+		return
+	}
+
+	if update.TTL != nil && !kContext.Internal {
+		err := fmt.Errorf("%w: only the stateless clusters have a ttl", kubeconfig.ErrInvalidUpdate)
+		c.handleError(w, ctx, span, err, "invalid cluster update", http.StatusBadRequest)
+
+		return
+	}
+
+	updated, err := c.KubeConfigStore.UpdateContext(clusterName, update)
+	c.recordAuditEvent(r, contextAuditEvent(r, audit.VerbUpdateContext, clusterName, kContext, err))
+
+	if err != nil {
+		c.handleError(w, ctx, span, err, "failed to update context in the store", http.StatusInternalServerError)
+		return
+	}
+
+	if c.capabilities != nil && update.Server != nil {
+		c.capabilities.Detect(updated)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	c.getConfig(w, r)
+
+	c.telemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "patchCluster"))
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{
+		"duration_ms": fmt.Sprintf("%d", time.Since(start).Milliseconds()),
+		"api.route":   "patchCluster",
+	}, nil, "Completed patchCluster request")
+}
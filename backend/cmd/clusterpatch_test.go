@@ -0,0 +1,133 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+//nolint:funlen
+func TestPatchCluster(t *testing.T) {
+	kubeConfigStore := kubeconfig.NewContextStore()
+
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "patch-dynamic",
+		Source:      kubeconfig.DynamicCluster,
+		KubeContext: &api.Context{Cluster: "patch-dynamic", AuthInfo: "patch-dynamic"},
+		Cluster:     &api.Cluster{Server: "https://old.example.com"},
+		AuthInfo:    &api.AuthInfo{Token: "old-token"},
+	}))
+	require.NoError(t, kubeConfigStore.AddContextWithKeyAndTTL(&kubeconfig.Context{
+		Name:        "patch-stateless",
+		Source:      kubeconfig.DynamicCluster,
+		Internal:    true,
+		KubeContext: &api.Context{},
+		Cluster:     &api.Cluster{Server: "https://stateless.example.com"},
+	}, "patch-statelessuser1", time.Minute))
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "patch-kubeconfig",
+		Source:      kubeconfig.KubeConfig,
+		KubeContext: &api.Context{},
+		Cluster:     &api.Cluster{Server: "https://kubeconfig.example.com"},
+	}))
+
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			EnableDynamicClusters: true,
+			KubeConfigStore:       kubeConfigStore,
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+	handler := createHeadlampHandler(&c)
+
+	token, server, ttl := "new-token", "https://new.example.com", "1h"
+
+	t.Run("dynamic", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PATCH", "/cluster/patch-dynamic",
+			PatchClusterRequest{Token: &token, Server: &server, Labels: map[string]string{"env": "prod"}})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		kContext, err := kubeConfigStore.GetContext("patch-dynamic")
+		require.NoError(t, err)
+		assert.Equal(t, "new-token", kContext.AuthInfo.Token)
+		assert.Equal(t, "https://new.example.com", kContext.Cluster.Server)
+
+		for _, cluster := range c.getClusters() {
+			if cluster.Name == "patch-dynamic" {
+				assert.Equal(t, "https://new.example.com", cluster.Server)
+				assert.Equal(t, map[string]string{"env": "prod"}, cluster.Metadata["labels"])
+			}
+		}
+	})
+
+	t.Run("stateless ttl", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PATCH", "/cluster/patch-statelessuser1",
+			PatchClusterRequest{TTL: &ttl})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		kContext, err := kubeConfigStore.GetContext("patch-statelessuser1")
+		require.NoError(t, err)
+		assert.True(t, kContext.Internal)
+	})
+
+	t.Run("ttl of a cluster without one", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PATCH", "/cluster/patch-dynamic",
+			PatchClusterRequest{TTL: &ttl})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalidServer := "not a url"
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PATCH", "/cluster/patch-dynamic",
+			PatchClusterRequest{Server: &invalidServer})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		kContext, err := kubeConfigStore.GetContext("patch-dynamic")
+		require.NoError(t, err)
+		assert.Equal(t, "https://new.example.com", kContext.Cluster.Server)
+	})
+
+	t.Run("not dynamic", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PATCH", "/cluster/patch-kubeconfig",
+			PatchClusterRequest{Token: &token})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rr, err := getResponseFromRestrictedEndpoint(handler, "PATCH", "/cluster/patch-unknown",
+			PatchClusterRequest{Token: &token})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// configEpoch tells apart the revisions of the contexts of different processes, as they
+// start over on every restart.
+var configEpoch = time.Now().UnixNano()
+
+// configETag returns the entity tag of the config, which changes every time the contexts
+// or their detected capabilities change.
+func (c *HeadlampConfig) configETag() string {
+	var capabilitiesRevision uint64
+	if c.capabilities != nil {
+		capabilitiesRevision = c.capabilities.Revision()
+	}
+
+	return fmt.Sprintf(`"%x-%x-%x"`, configEpoch, c.KubeConfigStore.Revision(), capabilitiesRevision)
+}
+
+// handleConfig returns the config, or only tells the client its copy is still up to date
+// if it sent the current entity tag in If-None-Match, so polling the clusters is cheap.
+func (c *HeadlampConfig) handleConfig(w http.ResponseWriter, r *http.Request) {
+	etag := c.configETag()
+
+	w.Header().Set("ETag", etag)
+	// The clients have to revalidate the config every time they use it.
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	c.getConfig(w, r)
+}
+
+// etagMatches tells whether the If-None-Match header matches the entity tag, comparing
+// the tags weakly as RFC 9110 asks for.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
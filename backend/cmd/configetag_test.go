@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleConfigETag(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{
+		Name:        "minikube",
+		KubeContext: &api.Context{Cluster: "minikube"},
+		Cluster:     &api.Cluster{Server: "https://minikube:6443"},
+	}))
+
+	c := &HeadlampConfig{HeadlampCFG: &headlampconfig.HeadlampCFG{KubeConfigStore: store}}
+
+	request := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/config", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		rr := httptest.NewRecorder()
+		c.handleConfig(rr, req)
+
+		return rr
+	}
+
+	rr := request("")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "minikube")
+
+	etag := rr.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rr = request(etag)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNotModified, request(`"other", W/`+etag).Code)
+	assert.Equal(t, http.StatusOK, request(`"other"`).Code)
+
+	require.NoError(t, store.RemoveContext("minikube"))
+
+	rr = request(etag)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	assert.NotContains(t, rr.Body.String(), "minikube")
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// contextGroupsFileName is the name of the default groups file, in the kubeconfigs directory.
+const contextGroupsFileName = "context-groups.json"
+
+// contextGroupsFile returns the file the context groups are persisted to: the one of the
+// config, or the default one unless running in-cluster, where they're kept in memory.
+func contextGroupsFile(conf *config.Config) string {
+	if conf.ContextGroupsFile != "" || conf.InCluster {
+		return conf.ContextGroupsFile
+	}
+
+	dir, err := config.MakeHeadlampKubeConfigsDir()
+	if err != nil {
+		logger.Log(logger.LevelWarn, nil, err, "getting the context groups directory, keeping them in memory")
+
+		return ""
+	}
+
+	return filepath.Join(dir, contextGroupsFileName)
+}
+
+// ClusterGroup is a context group with the clusters in it.
+type ClusterGroup struct {
+	kubeconfig.ContextGroup
+	Clusters []Cluster `json:"clusters"`
+}
+
+// clusterGroups are the clusters nested by group, as listed by /cluster-groups.
+type clusterGroups struct {
+	Groups []ClusterGroup `json:"groups"`
+	// Ungrouped are the clusters in no group.
+	Ungrouped []Cluster `json:"ungrouped"`
+}
+
+// getClusterGroups returns the clusters of getClusters nested by the groups of the store.
+func (c *HeadlampConfig) getClusterGroups() clusterGroups {
+	contexts := map[string]*kubeconfig.Context{}
+
+	stored, err := c.KubeConfigStore.GetContexts()
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "failed to get contexts")
+	}
+
+	for _, kContext := range stored {
+		contexts[kContext.Name] = kContext
+	}
+
+	clusters := c.getClusters()
+	grouped := make(map[string]bool, len(clusters))
+	result := clusterGroups{Groups: []ClusterGroup{}, Ungrouped: []Cluster{}}
+
+	for _, group := range c.KubeConfigStore.ContextGroups() {
+		clusterGroup := ClusterGroup{ContextGroup: group, Clusters: []Cluster{}}
+
+		for _, cluster := range clusters {
+			kContext, ok := contexts[cluster.Name]
+			if ok && group.Contains(kContext) {
+				clusterGroup.Clusters = append(clusterGroup.Clusters, cluster)
+				grouped[cluster.Name] = true
+			}
+		}
+
+		result.Groups = append(result.Groups, clusterGroup)
+	}
+
+	for _, cluster := range clusters {
+		if !grouped[cluster.Name] {
+			result.Ungrouped = append(result.Ungrouped, cluster)
+		}
+	}
+
+	return result
+}
+
+// handleClusterGroups lists the clusters nested by group.
+func (c *HeadlampConfig) handleClusterGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(c.getClusterGroups()); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding cluster groups")
+	}
+}
+
+// handleClusterGroup sets the group of the name in the path on PUT, with a body like
+// {"members": ["minikube"], "selector": "env=prod"}, and removes it on DELETE. It answers
+// with the clusters nested by group.
+func (c *HeadlampConfig) handleClusterGroup(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	switch r.Method {
+	case http.MethodPut:
+		var group kubeconfig.ContextGroup
+		if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+
+			return
+		}
+
+		group.Name = name
+
+		if err := c.KubeConfigStore.SetContextGroup(group); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, kubeconfig.ErrInvalidContextGroup) {
+				status = http.StatusBadRequest
+			}
+
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+	case http.MethodDelete:
+		if err := c.KubeConfigStore.RemoveContextGroup(name); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, kubeconfig.ErrContextGroupNotFound) {
+				status = http.StatusNotFound
+			}
+
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+	}
+
+	c.handleClusterGroups(w, r)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleClusterGroups(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-token")
+
+	store := kubeconfig.NewContextStore()
+
+	for name, labels := range map[string]map[string]string{
+		"prod-eu": {"env": "prod"},
+		"prod-us": {"env": "prod"},
+		"dev":     {"env": "dev"},
+		"local":   nil,
+	} {
+		require.NoError(t, store.AddContext(&kubeconfig.Context{
+			Name:        name,
+			KubeContext: &api.Context{Cluster: name},
+			Cluster:     &api.Cluster{Server: "https://" + name + ":6443"},
+			Labels:      labels,
+		}))
+	}
+
+	c := &HeadlampConfig{HeadlampCFG: &headlampconfig.HeadlampCFG{KubeConfigStore: store}}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/cluster-groups", c.handleClusterGroups).Methods("GET")
+	router.HandleFunc("/cluster-groups/{name}", c.handleClusterGroup).Methods("PUT", "DELETE")
+
+	request := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", token)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := request(http.MethodPut, "/cluster-groups/prod", `{"selector": "env=prod"}`, "backend-token")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = request(http.MethodPut, "/cluster-groups/team-a", `{"members": ["dev", "prod-eu"]}`, "backend-token")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = request(http.MethodGet, "/cluster-groups", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var groups clusterGroups
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &groups))
+
+	names := func(clusters []Cluster) []string {
+		clusterNames := []string{}
+		for _, cluster := range clusters {
+			clusterNames = append(clusterNames, cluster.Name)
+		}
+
+		return clusterNames
+	}
+
+	require.Len(t, groups.Groups, 2)
+	assert.Equal(t, "prod", groups.Groups[0].Name)
+	assert.ElementsMatch(t, []string{"prod-eu", "prod-us"}, names(groups.Groups[0].Clusters))
+	assert.Equal(t, "team-a", groups.Groups[1].Name)
+	assert.ElementsMatch(t, []string{"dev", "prod-eu"}, names(groups.Groups[1].Clusters))
+	assert.Equal(t, []string{"local"}, names(groups.Ungrouped))
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "/cluster-groups/Bad_Name", `{}`, "backend-token").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPut, "/cluster-groups/other", `{}`, "wrong").Code)
+
+	rr = request(http.MethodDelete, "/cluster-groups/prod", "", "backend-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &groups))
+	assert.Len(t, groups.Groups, 1)
+	assert.ElementsMatch(t, []string{"local", "prod-us"}, names(groups.Ungrouped))
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/cluster-groups/prod", "", "backend-token").Code)
+}
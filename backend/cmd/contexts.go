@@ -0,0 +1,443 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	cfg "github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const contextsUsage = `Usage: headlamp-server contexts [flags] <command> [args]
+
+Commands:
+  list               List the contexts, with their original and sanitized names.
+  inspect <name>     Print a context as JSON.
+  add <kubeconfig>   Add the contexts of a kubeconfig file as dynamic clusters.
+  remove <name>      Remove a dynamic cluster.
+
+The commands are run against a running backend, or with -offline against its
+store of dynamic clusters, which a running backend loads on restart or SIGHUP.
+
+Flags:
+`
+
+// contextsRequestTimeout is the timeout of the requests to the backend.
+const contextsRequestTimeout = 30 * time.Second
+
+// contextInfo is a context as printed by the contexts command.
+type contextInfo struct {
+	Name         string `json:"name"`
+	OriginalName string `json:"originalName"`
+	Source       string `json:"source,omitempty"`
+	Server       string `json:"server,omitempty"`
+	AuthType     string `json:"authType,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	KubeConfig   string `json:"kubeconfig,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// contextsCommand runs the contexts subcommands.
+type contextsCommand struct {
+	server   string
+	socket   string
+	token    string
+	apiToken string
+	offline  bool
+	output   string
+	client   *http.Client
+	out      io.Writer
+}
+
+// runContexts runs the contexts command with args, and returns its exit code.
+func runContexts(args []string, out io.Writer) int {
+	c := &contextsCommand{out: out}
+
+	f := flag.NewFlagSet("contexts", flag.ContinueOnError)
+	f.SetOutput(out)
+	f.Usage = func() {
+		fmt.Fprint(out, contextsUsage)
+		f.PrintDefaults()
+	}
+
+	f.StringVar(&c.server, "server", "http://localhost:4466", "URL of the running backend, with its base-url")
+	f.StringVar(&c.socket, "socket", "", "Unix socket of the running backend, see listen-socket")
+	f.StringVar(&c.token, "token", os.Getenv("HEADLAMP_BACKEND_TOKEN"), "Backend token, HEADLAMP_BACKEND_TOKEN by default")
+	f.StringVar(&c.apiToken, "api-token", "", "API token to authenticate with instead of the backend token")
+	f.BoolVar(&c.offline, "offline", false, "Work on the store of dynamic clusters instead of a running backend")
+	f.StringVar(&c.output, "o", "table", "Output format of list: table or json")
+
+	if err := f.Parse(args); err != nil {
+		return 2 //nolint:mnd
+	}
+
+	if f.NArg() == 0 {
+		f.Usage()
+
+		return 2 //nolint:mnd
+	}
+
+	c.client = &http.Client{Timeout: contextsRequestTimeout}
+
+	if c.socket != "" {
+		c.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", c.socket)
+			},
+		}
+	}
+
+	if err := c.run(f.Arg(0), f.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// run runs the subcommand with its args.
+func (c *contextsCommand) run(command string, args []string) error {
+	wantArgs := map[string]int{"list": 0, "inspect": 1, "add": 1, "remove": 1}
+
+	n, ok := wantArgs[command]
+	if !ok {
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if len(args) != n {
+		return fmt.Errorf("%s needs %d argument(s)", command, n)
+	}
+
+	switch command {
+	case "list":
+		return c.list()
+	case "inspect":
+		return c.inspect(args[0])
+	case "add":
+		return c.add(args[0])
+	default:
+		return c.remove(args[0])
+	}
+}
+
+// contexts returns the contexts of the backend, or of its store if offline.
+func (c *contextsCommand) contexts() ([]contextInfo, error) {
+	if c.offline {
+		return storeContexts()
+	}
+
+	data, err := c.do(http.MethodGet, "/config", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var conf clientConfig
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil, fmt.Errorf("decoding backend config: %w", err)
+	}
+
+	infos := make([]contextInfo, 0, len(conf.Clusters))
+	for _, cluster := range conf.Clusters {
+		infos = append(infos, clusterContextInfo(cluster))
+	}
+
+	return infos, nil
+}
+
+// clusterContextInfo returns the context info of a cluster returned by the backend.
+func clusterContextInfo(cluster Cluster) contextInfo {
+	info := contextInfo{
+		Name:         cluster.Name,
+		OriginalName: cluster.Name,
+		Server:       cluster.Server,
+		AuthType:     cluster.AuthType,
+		Error:        cluster.Error,
+	}
+
+	if originalName, ok := cluster.Metadata["originalName"].(string); ok && originalName != "" {
+		info.OriginalName = originalName
+	}
+
+	info.Source, _ = cluster.Metadata["source"].(string)
+	info.Namespace, _ = cluster.Metadata["namespace"].(string)
+
+	if origin, ok := cluster.Metadata["origin"].(map[string]interface{}); ok {
+		info.KubeConfig, _ = origin["kubeconfig"].(string)
+	}
+
+	return info
+}
+
+// storeContexts returns the contexts of the store of dynamic clusters.
+func storeContexts() ([]contextInfo, error) {
+	path, err := cfg.DefaultHeadlampKubeConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return []contextInfo{}, nil
+	}
+
+	// The store has no contexts left once the last one was removed.
+	if config, err := clientcmd.LoadFromFile(path); err == nil && len(config.Contexts) == 0 {
+		return []contextInfo{}, nil
+	}
+
+	contexts, contextErrors, err := kubeconfig.LoadContextsFromFile(path, kubeconfig.DynamicCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]contextInfo, 0, len(contexts)+len(contextErrors))
+	for i := range contexts {
+		infos = append(infos, kubeContextInfo(&contexts[i]))
+	}
+
+	for _, contextError := range contextErrors {
+		infos = append(infos, contextInfo{
+			Name:         contextError.ContextName,
+			OriginalName: contextError.ContextName,
+			Error:        contextError.Error.Error(),
+		})
+	}
+
+	return infos, nil
+}
+
+// kubeContextInfo returns the context info of a context loaded from a kubeconfig.
+func kubeContextInfo(kContext *kubeconfig.Context) contextInfo {
+	info := contextInfo{
+		Name:         kContext.Name,
+		OriginalName: kContext.OriginalName,
+		Source:       kContext.SourceStr(),
+		AuthType:     kContext.AuthType(),
+		KubeConfig:   kContext.KubeConfigPath,
+		Namespace:    kContext.Namespace,
+		Error:        kContext.Error,
+	}
+
+	if info.OriginalName == "" {
+		info.OriginalName = kContext.Name
+	}
+
+	if kContext.Cluster != nil {
+		info.Server = kContext.Cluster.Server
+	}
+
+	return info
+}
+
+// findContext returns the context of the original or sanitized name.
+func findContext(infos []contextInfo, name string) (contextInfo, error) {
+	for _, info := range infos {
+		if info.Name == name || info.OriginalName == name {
+			return info, nil
+		}
+	}
+
+	return contextInfo{}, fmt.Errorf("context %q not found", name)
+}
+
+func (c *contextsCommand) list() error {
+	infos, err := c.contexts()
+	if err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return c.printJSON(infos)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(w, "NAME\tORIGINAL NAME\tSOURCE\tSERVER\tERROR")
+
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.Name, info.OriginalName, info.Source, info.Server, info.Error)
+	}
+
+	return w.Flush()
+}
+
+func (c *contextsCommand) inspect(name string) error {
+	infos, err := c.contexts()
+	if err != nil {
+		return err
+	}
+
+	info, err := findContext(infos, name)
+	if err != nil {
+		return err
+	}
+
+	return c.printJSON(info)
+}
+
+func (c *contextsCommand) add(path string) error {
+	contexts, contextErrors, err := kubeconfig.LoadContextsFromFile(path, kubeconfig.DynamicCluster)
+	if err != nil {
+		return err
+	}
+
+	if len(contextErrors) > 0 {
+		return fmt.Errorf("context %s: %w", contextErrors[0].ContextName, contextErrors[0].Error)
+	}
+
+	if c.offline {
+		err = addToStore(path)
+	} else {
+		err = c.addToBackend(path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for i := range contexts {
+		info := kubeContextInfo(&contexts[i])
+		fmt.Fprintf(c.out, "added %s (original name %s)\n", info.Name, info.OriginalName)
+	}
+
+	return nil
+}
+
+// addToBackend adds the contexts of the kubeconfig file to the running backend.
+func (c *contextsCommand) addToBackend(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	kubeConfig := base64.StdEncoding.EncodeToString(data)
+
+	body, err := json.Marshal(ClusterReq{KubeConfig: &kubeConfig})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(http.MethodPost, "/cluster", body)
+
+	return err
+}
+
+// addToStore adds the contexts of the kubeconfig file to the store of dynamic clusters.
+func addToStore(path string) error {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	dir, err := cfg.MakeHeadlampKubeConfigsDir()
+	if err != nil {
+		return err
+	}
+
+	return kubeconfig.WriteToFile(*config, dir)
+}
+
+func (c *contextsCommand) remove(name string) error {
+	infos, err := c.contexts()
+	if err != nil {
+		return err
+	}
+
+	info, err := findContext(infos, name)
+	if err != nil {
+		return err
+	}
+
+	if c.offline {
+		// The store has the original names.
+		err = kubeconfig.RemoveContextFromFile(info.OriginalName, info.KubeConfig)
+	} else {
+		_, err = c.do(http.MethodDelete, "/cluster/"+url.PathEscape(info.Name), nil)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "removed %s (original name %s)\n", info.Name, info.OriginalName)
+
+	return nil
+}
+
+// do sends a request to the backend and returns the response body, or an error if
+// the request doesn't succeed.
+func (c *contextsCommand) do(method, path string, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contextsRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.server, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.token != "" {
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", c.token)
+	}
+
+	if c.apiToken != "" {
+		req.Header.Set(auth.APITokenHeader, c.apiToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
+
+func (c *contextsCommand) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
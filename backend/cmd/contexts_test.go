@@ -0,0 +1,166 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const contextsTestKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: team/prod
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func writeContextsKubeConfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(path, []byte(contextsTestKubeConfig), 0o600))
+
+	return path
+}
+
+//nolint:funlen
+func TestContextsCommand(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-token")
+
+	var added ClusterReq
+
+	var removed string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-HEADLAMP_BACKEND-TOKEN") != "backend-token" {
+			http.Error(w, "access denied", http.StatusForbidden)
+
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/config":
+			_ = json.NewEncoder(w).Encode(clientConfig{Clusters: []Cluster{{
+				Name:   "team--prod",
+				Server: "https://127.0.0.1:6443",
+				Metadata: map[string]interface{}{
+					"source":       "dynamic_cluster",
+					"originalName": "team/prod",
+				},
+			}}})
+		case r.Method == http.MethodPost && r.URL.Path == "/cluster":
+			_ = json.NewDecoder(r.Body).Decode(&added)
+
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete:
+			removed = r.URL.Path
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	run := func(args ...string) (int, string) {
+		var out bytes.Buffer
+
+		code := runContexts(append([]string{"-server", server.URL}, args...), &out)
+
+		return code, out.String()
+	}
+
+	code, out := run("list")
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "team--prod")
+	assert.Contains(t, out, "team/prod")
+
+	code, out = run("inspect", "team/prod")
+	require.Equal(t, 0, code)
+
+	var info contextInfo
+	require.NoError(t, json.Unmarshal([]byte(out), &info))
+	assert.Equal(t, contextInfo{
+		Name:         "team--prod",
+		OriginalName: "team/prod",
+		Source:       "dynamic_cluster",
+		Server:       "https://127.0.0.1:6443",
+	}, info)
+
+	code, out = run("add", writeContextsKubeConfig(t))
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "added team--prod (original name team/prod)")
+	require.NotNil(t, added.KubeConfig)
+
+	kubeConfig, err := base64.StdEncoding.DecodeString(*added.KubeConfig)
+	require.NoError(t, err)
+	assert.Equal(t, contextsTestKubeConfig, string(kubeConfig))
+
+	code, _ = run("remove", "team/prod")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "/cluster/team--prod", removed)
+
+	code, _ = run("inspect", "missing")
+	assert.Equal(t, 1, code)
+
+	code, _ = run("-token", "wrong", "list")
+	assert.Equal(t, 1, code)
+
+	code, _ = run("unknown")
+	assert.Equal(t, 1, code)
+}
+
+func TestContextsCommandOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var out bytes.Buffer
+
+	require.Equal(t, 0, runContexts([]string{"-offline", "add", writeContextsKubeConfig(t)}, &out))
+
+	out.Reset()
+	require.Equal(t, 0, runContexts([]string{"-offline", "-o", "json", "list"}, &out))
+
+	var infos []contextInfo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &infos))
+	require.Len(t, infos, 1)
+	assert.Equal(t, "team--prod", infos[0].Name)
+	assert.Equal(t, "team/prod", infos[0].OriginalName)
+
+	require.Equal(t, 0, runContexts([]string{"-offline", "remove", "team--prod"}, &out))
+
+	out.Reset()
+	require.Equal(t, 0, runContexts([]string{"-offline", "-o", "json", "list"}, &out))
+	assert.JSONEq(t, "[]", out.String())
+}
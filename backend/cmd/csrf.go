@@ -0,0 +1,194 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+const (
+	// CSRFCookieName is the cookie holding the CSRF token of a browser session.
+	CSRFCookieName = "headlamp-csrf"
+	// CSRFHeader is the header the client echoes the CSRF token in.
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFQueryParam is the query parameter the client echoes the CSRF token in on the
+	// WebSocket requests, whose headers browsers can't set.
+	CSRFQueryParam = "csrfToken"
+	// CSRFSubprotocolPrefix prefixes the CSRF token when the client offers it as a WebSocket
+	// subprotocol instead of the query parameter.
+	CSRFSubprotocolPrefix = "csrf.headlamp.dev."
+	// csrfTokenBytes is the number of random bytes of a CSRF token.
+	csrfTokenBytes = 32
+)
+
+// clusterBackendRoutes are the routes under /clusters/{clusterName}/ served by the
+// backend itself, the other ones being proxied to the cluster.
+var clusterBackendRoutes = []string{"portforward", "exec", "attach", "helm", "set-token", "rehydrate"}
+
+// csrfTokenResponse is the response of the CSRF token endpoint.
+type csrfTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// handleCSRFToken issues a CSRF token. It is set in a SameSite cookie and returned
+// in the response, for the client to echo it in the X-CSRF-Token header.
+func handleCSRFToken(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "generating CSRF token")
+		http.Error(w, "generating CSRF token", http.StatusInternalServerError)
+
+		return
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   auth.IsSecureContext(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := json.NewEncoder(w).Encode(csrfTokenResponse{Token: token}); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "writing CSRF token")
+	}
+}
+
+// csrfMiddleware rejects the requests changing the backend state, i.e. not GET, HEAD or
+// OPTIONS, whose X-CSRF-Token header doesn't match their CSRF cookie (double-submit).
+// The requests proxied to the clusters are left to the cluster, and the requests with the
+// backend token or an API token don't come from a browser session, so they aren't checked.
+func csrfMiddleware(baseURL string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !needsCSRFCheck(r, strings.TrimPrefix(r.URL.Path, baseURL)) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if !validCSRFToken(r) {
+				logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"method": r.Method, "path": r.URL.Path},
+					nil, "rejecting request with invalid CSRF token")
+				http.Error(w, "invalid CSRF token", http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// needsCSRFCheck tells whether the request, to path without the base URL, is checked.
+func needsCSRFCheck(r *http.Request, path string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	if isClusterProxyPath(path) || auth.APITokenNameFromContext(r.Context()) != "" {
+		return false
+	}
+
+	backendToken := os.Getenv("HEADLAMP_BACKEND_TOKEN")
+
+	return backendToken == "" || r.Header.Get("X-HEADLAMP_BACKEND-TOKEN") != backendToken
+}
+
+// isClusterProxyPath tells whether requests to the path are proxied to a cluster.
+func isClusterProxyPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/clusters/")
+	if !ok {
+		return false
+	}
+
+	_, route, ok := strings.Cut(rest, "/")
+	if !ok {
+		return false
+	}
+
+	route, _, _ = strings.Cut(route, "/")
+
+	return !slices.Contains(clusterBackendRoutes, route)
+}
+
+// validCSRFToken tells whether the X-CSRF-Token header of the request matches its CSRF cookie.
+func validCSRFToken(r *http.Request) bool {
+	return matchesCSRFCookie(r, r.Header.Get(CSRFHeader))
+}
+
+// matchesCSRFCookie tells whether token is the CSRF cookie of the request.
+func matchesCSRFCookie(r *http.Request, token string) bool {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) == 1
+}
+
+// checkWebSocketCSRF checks the CSRF token of a WebSocket request running commands in the
+// clusters, like exec and attach, which are GETs the CSRF middleware lets through. Browsers
+// can't set headers on WebSocket requests, so the token is echoed in the csrfToken query
+// parameter or as a subprotocol prefixed with CSRFSubprotocolPrefix. The requests with the
+// backend token or an API token don't come from a browser session. It answers with 403 and
+// returns false if the token is missing or wrong.
+func checkWebSocketCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if auth.APITokenNameFromContext(r.Context()) != "" {
+		return true
+	}
+
+	backendToken := os.Getenv("HEADLAMP_BACKEND_TOKEN")
+	if backendToken != "" && r.Header.Get("X-HEADLAMP_BACKEND-TOKEN") == backendToken {
+		return true
+	}
+
+	if matchesCSRFCookie(r, r.URL.Query().Get(CSRFQueryParam)) {
+		return true
+	}
+
+	for _, protocol := range websocket.Subprotocols(r) {
+		token, ok := strings.CutPrefix(protocol, CSRFSubprotocolPrefix)
+		if ok && matchesCSRFCookie(r, token) {
+			return true
+		}
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"path": r.URL.Path},
+		nil, "rejecting WebSocket request with invalid CSRF token")
+	http.Error(w, "invalid CSRF token", http.StatusForbidden)
+
+	return false
+}
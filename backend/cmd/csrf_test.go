@@ -0,0 +1,150 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsClusterProxyPath(t *testing.T) {
+	assert.True(t, isClusterProxyPath("/clusters/minikube/api/v1/pods"))
+	assert.True(t, isClusterProxyPath("/clusters/minikube/version"))
+	assert.False(t, isClusterProxyPath("/clusters/minikube/helm/releases/install"))
+	assert.False(t, isClusterProxyPath("/clusters/minikube/portforward"))
+	assert.False(t, isClusterProxyPath("/clusters/minikube/set-token"))
+	assert.False(t, isClusterProxyPath("/cluster/minikube"))
+	assert.False(t, isClusterProxyPath("/clusters/minikube"))
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-secret")
+
+	// Get a token.
+	rr := httptest.NewRecorder()
+	handleCSRFToken(rr, httptest.NewRequest(http.MethodGet, "/csrf-token", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp csrfTokenResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.NotEmpty(t, resp.Token)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, CSRFCookieName, cookies[0].Name)
+	assert.Equal(t, resp.Token, cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+
+	handler := csrfMiddleware("/headlamp")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		cookie       string
+		header       string
+		backendToken string
+		want         int
+	}{
+		{"get", http.MethodGet, "/headlamp/config", "", "", "", http.StatusNoContent},
+		{"missing_token", http.MethodDelete, "/headlamp/cluster/minikube", "", "", "", http.StatusForbidden},
+		{"missing_header", http.MethodDelete, "/headlamp/cluster/minikube", resp.Token, "", "", http.StatusForbidden},
+		{"wrong_header", http.MethodPut, "/headlamp/cluster/minikube", resp.Token, "other", "", http.StatusForbidden},
+		{"valid", http.MethodPost, "/headlamp/cluster", resp.Token, resp.Token, "", http.StatusNoContent},
+		{"helm", http.MethodPost, "/headlamp/clusters/minikube/helm/release/install", "", "", "", http.StatusForbidden},
+		{"proxy", http.MethodPost, "/headlamp/clusters/minikube/api/v1/namespaces", "", "", "", http.StatusNoContent},
+		{"backend_token", http.MethodPost, "/headlamp/cluster", "", "", "backend-secret", http.StatusNoContent},
+		{"wrong_backend_token", http.MethodPost, "/headlamp/cluster", "", "", "wrong", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+
+			if tt.backendToken != "" {
+				req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", tt.backendToken)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
+
+func TestCheckWebSocketCSRF(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-secret")
+
+	tests := []struct {
+		name         string
+		cookie       string
+		query        string
+		subprotocol  string
+		backendToken string
+		want         bool
+	}{
+		{"missing_token", "csrf-token", "", "", "", false},
+		{"query", "csrf-token", "csrf-token", "", "", true},
+		{"wrong_query", "csrf-token", "other", "", "", false},
+		{"query_without_cookie", "", "csrf-token", "", "", false},
+		{"subprotocol", "csrf-token", "", CSRFSubprotocolPrefix + "csrf-token", "", true},
+		{"wrong_subprotocol", "csrf-token", "", CSRFSubprotocolPrefix + "other", "", false},
+		{"backend_token", "", "", "", "backend-secret", true},
+		{"wrong_backend_token", "", "", "", "wrong", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/exec?"+CSRFQueryParam+"="+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+
+			if tt.subprotocol != "" {
+				req.Header.Set("Sec-WebSocket-Protocol", "v5.channel.k8s.io, "+tt.subprotocol)
+			}
+
+			if tt.backendToken != "" {
+				req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", tt.backendToken)
+			}
+
+			rr := httptest.NewRecorder()
+
+			assert.Equal(t, tt.want, checkWebSocketCSRF(rr, req))
+
+			if !tt.want {
+				assert.Equal(t, http.StatusForbidden, rr.Code)
+			}
+		})
+	}
+}
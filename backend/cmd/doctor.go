@@ -0,0 +1,199 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/doctor"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// handleDoctor checks the stored contexts, or only the one of the context query
+// parameter, and returns their reports. It requires the backend token.
+func (c *HeadlampConfig) handleDoctor(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	contexts, err := c.KubeConfigStore.GetContexts()
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "getting contexts")
+		http.Error(w, "getting contexts", http.StatusInternalServerError)
+
+		return
+	}
+
+	name := r.URL.Query().Get("context")
+	checked := make([]*kubeconfig.Context, 0, len(contexts))
+
+	for _, kContext := range contexts {
+		// Dynamic clusters of other users are not shown, like in the clusters list.
+		if kContext.Internal || (name != "" && kContext.Name != name) {
+			continue
+		}
+
+		checked = append(checked, kContext)
+	}
+
+	if name != "" && len(checked) == 0 {
+		kubeconfig.WriteError(r.Context(), w, &kubeconfig.Error{Code: kubeconfig.CodeContextNotFound, Context: name},
+			http.StatusNotFound)
+
+		return
+	}
+
+	reports := doctor.New().CheckAll(r.Context(), checked)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(reports); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding doctor reports")
+	}
+}
+
+// runDoctor checks the contexts of the kubeconfig and of the dynamic clusters store, as
+// configured by the config flags args, and prints their reports. It returns the exit
+// code, 1 if a context has an error.
+func runDoctor(args []string, out io.Writer) int {
+	conf, err := config.Parse(append([]string{"doctor"}, args...))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		return 1
+	}
+
+	contexts, err := doctorContexts(conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		return 1
+	}
+
+	reports := doctor.New().CheckAll(context.Background(), contexts)
+
+	if err := printDoctorReports(out, reports); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		return 1
+	}
+
+	for _, report := range reports {
+		if report.Status == doctor.StatusError {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// doctorContexts loads the contexts the backend would load with conf.
+func doctorContexts(conf *config.Config) ([]*kubeconfig.Context, error) {
+	skipFunc := kubeconfig.SkipKubeContextInCommaSeparatedString(conf.SkippedKubeContexts)
+
+	contexts, err := loadDoctorContexts(conf.KubeConfigPath, kubeconfig.KubeConfig, skipFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// The directory of the dynamic clusters isn't created if missing, only read if there.
+	dir, err := config.HeadlampKubeConfigsDir()
+	if err != nil {
+		return contexts, nil
+	}
+
+	dynamicClusters := filepath.Join(dir, "config")
+
+	if _, err := os.Stat(dynamicClusters); errors.Is(err, fs.ErrNotExist) {
+		return contexts, nil
+	}
+
+	dynamicContexts, err := loadDoctorContexts(dynamicClusters, kubeconfig.DynamicCluster, skipFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(contexts, dynamicContexts...), nil
+}
+
+// loadDoctorContexts loads the contexts of the kubeconfig files. The contexts which could
+// not be loaded are returned with their error, so they are reported too.
+func loadDoctorContexts(paths string, source int, skipFunc func(kubeconfig.Context) bool,
+) ([]*kubeconfig.Context, error) {
+	loaded, contextErrors, err := kubeconfig.LoadContextsFromMultipleFiles(paths, source)
+	if err != nil {
+		return nil, err
+	}
+
+	contexts := make([]*kubeconfig.Context, 0, len(loaded)+len(contextErrors))
+
+	for i := range loaded {
+		if !skipFunc(loaded[i]) {
+			contexts = append(contexts, &loaded[i])
+		}
+	}
+
+	for _, contextError := range contextErrors {
+		// The files that couldn't be loaded at all are reported by their path.
+		name := contextError.ContextName
+		if name == "" {
+			name = contextError.KubeConfigPath
+		}
+
+		contexts = append(contexts, &kubeconfig.Context{
+			Name:  name,
+			Error: contextError.Error.Error(),
+		})
+	}
+
+	return contexts, nil
+}
+
+// printDoctorReports prints the reports as a list of checks per context.
+func printDoctorReports(out io.Writer, reports []doctor.Report) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0) //nolint:mnd
+
+	for _, report := range reports {
+		name := report.Context
+		if report.OriginalName != "" && report.OriginalName != report.Context {
+			name += " (" + report.OriginalName + ")"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, report.Server, report.Status)
+
+		for _, check := range report.Checks {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", check.Status, check.Name, check.Message)
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/doctor"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDoctor(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-token")
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "broken", Error: "cluster not found"}))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "other-user", Error: "hidden", Internal: true}))
+
+	c := &HeadlampConfig{HeadlampCFG: &headlampconfig.HeadlampCFG{KubeConfigStore: store}}
+
+	request := func(query string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/doctor"+query, nil)
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", token)
+
+		rr := httptest.NewRecorder()
+		c.handleDoctor(rr, req)
+
+		return rr
+	}
+
+	rr := request("", "backend-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var reports []doctor.Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &reports))
+	require.Len(t, reports, 1)
+	assert.Equal(t, "broken", reports[0].Context)
+	assert.Equal(t, doctor.StatusError, reports[0].Status)
+
+	rr = request("?context=missing", "backend-token")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"context not found: \"missing\"","code":"context_not_found"}`, rr.Body.String())
+	assert.Equal(t, http.StatusForbidden, request("", "wrong").Code)
+}
+
+func TestPrintDoctorReports(t *testing.T) {
+	var out bytes.Buffer
+
+	require.NoError(t, printDoctorReports(&out, []doctor.Report{{
+		Context:      "team--prod",
+		OriginalName: "team/prod",
+		Server:       "https://127.0.0.1:6443",
+		Status:       doctor.StatusError,
+		Checks:       []doctor.Check{{Name: doctor.CheckTCP, Status: doctor.StatusError, Message: "connection refused"}},
+	}}))
+
+	assert.Contains(t, out.String(), "team--prod (team/prod)")
+	assert.Contains(t, out.String(), "connection refused")
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DryRunHeader flags the responses to the mutating requests that were made dry runs.
+const DryRunHeader = "X-Headlamp-Dry-Run"
+
+// dryRunExemptGroups are the API groups whose resources are reviews creating nothing,
+// e.g. SelfSubjectAccessReview, which keep working in dry run mode.
+var dryRunExemptGroups = []string{"authorization.k8s.io", "authentication.k8s.io"}
+
+// mutatingVerbs are the verbs of the Kubernetes requests changing the clusters.
+var mutatingVerbs = []string{"create", "update", "patch", "delete", "deletecollection"}
+
+// isDryRun tells whether a request proxied to the context is to be made a dry run: it
+// is mutating and the dry run mode is enabled, for every context or for this one.
+func (c *HeadlampConfig) isDryRun(r *http.Request, info apirequest.Info, kContext *kubeconfig.Context) bool {
+	if !audit.IsMutating(r.Method) || !info.IsResourceRequest() {
+		return false
+	}
+
+	for _, group := range dryRunExemptGroups {
+		if info.APIGroup == group {
+			return false
+		}
+	}
+
+	return c.dryRun || kContext.IsDryRun()
+}
+
+// setDryRun makes the request a dry run, which the API server validates without
+// persisting anything, and flags the response as such.
+func setDryRun(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	query.Set("dryRun", "All")
+	r.URL.RawQuery = query.Encode()
+
+	w.Header().Set(DryRunHeader, "true")
+}
+
+// dryRunMiddleware rejects, in dry run mode, the requests to the routes of the backend which
+// would change the clusters, like helm installs, exec sessions or port forwards. Unlike the
+// requests proxied to the clusters, they can't be made dry runs. The node drains name their
+// cluster in their body, so handleNodeDrain checks the dry run mode of their context.
+func (c *HeadlampConfig) dryRunMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action, ok := c.backendRouteAction(r)
+		if !ok || !action.isMutating() {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if kContext := c.backendRouteContext(r); c.dryRun || (kContext != nil && kContext.IsDryRun()) {
+			writeDryRunForbidden(w, r, action)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isMutating tells whether the action changes the cluster.
+func (a backendAction) isMutating() bool {
+	for _, verb := range a.verbs {
+		if slices.Contains(mutatingVerbs, verb) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backendRouteContext returns the context of a request to a cluster-scoped route of the
+// backend, nil if there is none.
+func (c *HeadlampConfig) backendRouteContext(r *http.Request) *kubeconfig.Context {
+	clusterName := mux.Vars(r)["clusterName"]
+	if clusterName == "" || c.KubeConfigStore == nil {
+		return nil
+	}
+
+	// Browsers cannot set headers on WebSocket requests, so the user ID can come in the query too.
+	userID := r.Header.Get("X-HEADLAMP-USER-ID")
+	if userID == "" {
+		userID = r.URL.Query().Get("userId")
+	}
+
+	kContext, err := c.KubeConfigStore.GetContext(clusterName + userID)
+	if err != nil {
+		return nil
+	}
+
+	return kContext
+}
+
+// writeDryRunForbidden rejects a request which would change the cluster in dry run mode, with
+// a Kubernetes Forbidden status as for the verbs that aren't allowed.
+func writeDryRunForbidden(w http.ResponseWriter, r *http.Request, action backendAction) {
+	err := fmt.Errorf("%s is not allowed in dry run mode, as it would change the cluster", action.resource)
+
+	status := apierrors.NewForbidden(schema.GroupResource{Resource: action.resource}, "", err).Status()
+
+	logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"resource": action.resource, "path": r.URL.Path},
+		err, "rejecting request in dry run mode")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(DryRunHeader, "true")
+	w.WriteHeader(http.StatusForbidden)
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "writing forbidden status")
+	}
+}
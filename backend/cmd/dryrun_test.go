@@ -0,0 +1,154 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestIsDryRun(t *testing.T) {
+	plainContext := &kubeconfig.Context{Name: "plain", KubeContext: &api.Context{}}
+	dryRunContext := &kubeconfig.Context{Name: "training", KubeContext: &api.Context{
+		Extensions: map[string]runtime.Object{"headlamp_info": &kubeconfig.CustomObject{DryRun: true}},
+	}}
+
+	deployment := "/apis/apps/v1/namespaces/default/deployments/nginx"
+	review := "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"
+
+	tests := []struct {
+		name     string
+		global   bool
+		method   string
+		path     string
+		kContext *kubeconfig.Context
+		want     bool
+	}{
+		{"disabled", false, http.MethodDelete, deployment, plainContext, false},
+		{"global", true, http.MethodDelete, deployment, plainContext, true},
+		{"context", false, http.MethodPatch, deployment, dryRunContext, true},
+		{"read", true, http.MethodGet, deployment, dryRunContext, false},
+		{"review", true, http.MethodPost, review, dryRunContext, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &HeadlampConfig{dryRun: tt.global}
+			r := httptest.NewRequest(tt.method, "/clusters/test"+tt.path, nil)
+
+			assert.Equal(t, tt.want, c.isDryRun(r, apirequest.Parse(tt.method, tt.path, nil), tt.kContext))
+		})
+	}
+}
+
+func TestSetDryRun(t *testing.T) {
+	r := httptest.NewRequest(http.MethodDelete,
+		"/api/v1/namespaces/default/pods/nginx?dryRun=None&gracePeriodSeconds=0", nil)
+	w := httptest.NewRecorder()
+
+	setDryRun(w, r)
+
+	assert.Equal(t, []string{"All"}, r.URL.Query()["dryRun"])
+	assert.Equal(t, "0", r.URL.Query().Get("gracePeriodSeconds"))
+	assert.Equal(t, "true", w.Header().Get(DryRunHeader))
+}
+
+func TestDryRunMiddleware(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "plain", KubeContext: &api.Context{}}))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "training", KubeContext: &api.Context{
+		Extensions: map[string]runtime.Object{"headlamp_info": &kubeconfig.CustomObject{DryRun: true}},
+	}}))
+
+	tests := []struct {
+		name    string
+		global  bool
+		cluster string
+		method  string
+		route   string
+		want    int
+	}{
+		{"exec", false, "training", http.MethodGet, "exec?namespace=ns&pod=pod&command=sh", http.StatusForbidden},
+		{"attach", false, "training", http.MethodGet, "attach?namespace=ns&pod=pod", http.StatusForbidden},
+		{"portforward", false, "training", http.MethodPost, "portforward", http.StatusForbidden},
+		{"helm_install", false, "training", http.MethodPost, "helm/release/install", http.StatusForbidden},
+		{"helm_upgrade", false, "training", http.MethodPut, "helm/releases/upgrade", http.StatusForbidden},
+		{"helm_rollback", false, "training", http.MethodPut, "helm/releases/rollback", http.StatusForbidden},
+		{"helm_uninstall", false, "training", http.MethodDelete, "helm/releases/uninstall", http.StatusForbidden},
+		{"helm_list", false, "training", http.MethodGet, "helm/releases/list", http.StatusNoContent},
+		{"helm_preview", false, "training", http.MethodPost, "helm/releases/upgrade/preview", http.StatusNoContent},
+		{"plain_context", false, "plain", http.MethodPost, "helm/release/install", http.StatusNoContent},
+		{"global", true, "plain", http.MethodGet, "exec?namespace=ns&pod=pod&command=sh", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &HeadlampConfig{
+				HeadlampCFG: &headlampconfig.HeadlampCFG{KubeConfigStore: store},
+				dryRun:      tt.global,
+			}
+
+			r := mux.NewRouter()
+			r.Use(c.dryRunMiddleware)
+			r.PathPrefix("/clusters/{clusterName}/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(tt.method, "/clusters/"+tt.cluster+"/"+tt.route, nil))
+
+			assert.Equal(t, tt.want, rr.Code)
+
+			if tt.want == http.StatusForbidden {
+				assert.Equal(t, "true", rr.Header().Get(DryRunHeader))
+				assert.Contains(t, rr.Body.String(), "dry run mode")
+			}
+		})
+	}
+}
+
+func TestDryRunNodeDrain(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "training", KubeContext: &api.Context{
+		Extensions: map[string]runtime.Object{"headlamp_info": &kubeconfig.CustomObject{DryRun: true}},
+	}}))
+
+	c := HeadlampConfig{
+		HeadlampCFG:      &headlampconfig.HeadlampCFG{KubeConfigStore: store},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+
+	rr, err := getResponseFromRestrictedEndpoint(createHeadlampHandler(&c), http.MethodPost, "/drain-node",
+		map[string]string{"cluster": "training", "nodeName": "node"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "dry run mode")
+}
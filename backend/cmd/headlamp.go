@@ -35,33 +35,38 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	oidc "github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gobwas/glob"
-	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
 	auth "github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/clustermetrics"
 	cfg "github.com/kubernetes-sigs/headlamp/backend/pkg/config"
 
 	headlampcfg "github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/helm"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/k8cache"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/podexec"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/portforward"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/spa"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/tunnel"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 
@@ -84,6 +89,59 @@ type HeadlampConfig struct {
 	telemetryConfig           cfg.Config
 	oidcScopes                []string
 	telemetryHandler          *telemetry.RequestHandler
+	// accessLog enables the access log, except for the paths starting with accessLogExclude.
+	accessLog        bool
+	accessLogExclude []string
+	// auditRecorder records the requests changing cluster resources, if auditing is enabled.
+	auditRecorder *audit.Recorder
+	// allowedVerbs are the verbs allowed in the requests proxied to the clusters, all if empty.
+	allowedVerbs []string
+	// dryRun makes every mutating request proxied to the clusters a dry run.
+	dryRun bool
+	// contextNamespace makes the list, watch and get requests for namespaced resources without a
+	// namespace use the namespace of their context.
+	contextNamespace bool
+	// recordClusterEvents records the actions of the backend on the clusters as Kubernetes Events,
+	// for the contexts not setting it themselves.
+	recordClusterEvents bool
+	// csrfProtection requires a CSRF token for the requests changing the backend state.
+	csrfProtection bool
+	// apiTokens authenticates the requests with an API token, if API tokens are configured.
+	apiTokens *auth.APITokenAuthenticator
+	// headless serves only the APIs, without the frontend and plugins.
+	headless bool
+	// pluginCatalog installs plugins from OCI registries.
+	pluginCatalog *plugins.Catalog
+	// ociPlugins are the plugins to install from OCI registries on startup, by name.
+	ociPlugins map[string]string
+	// pluginVerifier checks the plugin signatures, if plugin verification is enabled.
+	pluginVerifier *plugins.Verifier
+	// pluginBackends serve the endpoints plugins contribute under /plugins/{name}/api.
+	pluginBackends *plugins.Backends
+	// enablePluginBackends starts the backend processes of the plugins.
+	enablePluginBackends bool
+	// helmRepoRefreshInterval is how often the Helm repository indexes are refreshed, 0 to not refresh them.
+	helmRepoRefreshInterval time.Duration
+	// clusterMetrics polls and caches the metrics-server metrics of the clusters, if enabled.
+	clusterMetrics *clustermetrics.Aggregator
+	// discoveryCache caches the API discovery documents of the clusters, if enabled.
+	discoveryCache *k8cache.DiscoveryCache
+	// capabilities caches the detected version, platform and notable APIs of the clusters.
+	capabilities *kubeconfig.CapabilityCache
+	// openAPICache caches the OpenAPI v3 schemas of the clusters, if enabled.
+	openAPICache *k8cache.OpenAPICache
+	// tunnelServer accepts the reverse tunnels of the in-cluster agents, if a tunnel token is set.
+	tunnelServer *tunnel.Server
+	// remoteKubeConfigs loads the contexts of the kubeconfigs registered by URL.
+	remoteKubeConfigs *kubeconfig.RemoteSources
+	// remoteKubeConfigRefreshInterval is how often the kubeconfigs registered by URL are
+	// revalidated, 0 to not revalidate them.
+	remoteKubeConfigRefreshInterval time.Duration
+	// shutdownDrainTimeout is how long the in-flight requests are given to finish on shutdown.
+	shutdownDrainTimeout time.Duration
+	// insecureWarned are the contexts skipping the TLS verification that were warned about, by
+	// key and server, so they're only warned about on their first use.
+	insecureWarned sync.Map
 }
 
 const DrainNodeCacheTTL = 20 // seconds
@@ -98,6 +156,10 @@ const JWTExpirationTTL = 10 * time.Second // seconds
 
 const kubeConfigSource = "kubeconfig" // source for kubeconfig contexts
 
+// requestLogSampler samples the logs of the requests proxied to the clusters, which
+// are logged for every request and can flood the output with busy watch traffic.
+var requestLogSampler = logger.NewSampler(100, 100, time.Minute)
+
 const (
 	// TokenCacheFileMode is the file mode for token cache files.
 	TokenCacheFileMode = 0o600 // octal
@@ -249,12 +311,26 @@ func addPluginRoutes(config *HeadlampConfig, r *mux.Router) {
 	// This is only available when running locally.
 	if !config.UseInCluster {
 		addPluginDeleteRoute(config, r)
+
+		if config.pluginCatalog != nil {
+			addPluginCatalogRoutes(config, r)
+		}
 	}
 
 	addPluginListRoute(config, r)
+	addPluginBackendRoutes(config, r)
+
+	if config.pluginVerifier != nil {
+		r.HandleFunc("/plugin-verification", config.handlePluginVerification).Methods("GET")
+	}
 
 	// Serve plugins
-	pluginHandler := http.StripPrefix(config.BaseURL+"/plugins/", http.FileServer(http.Dir(config.PluginDir)))
+	var pluginFiles http.Handler = http.FileServer(http.Dir(config.PluginDir))
+	if config.pluginVerifier != nil {
+		pluginFiles = verifiedPluginsHandler(config.pluginVerifier, config.PluginDir, pluginFiles)
+	}
+
+	pluginHandler := http.StripPrefix(config.BaseURL+"/plugins/", pluginFiles)
 	// If we're running locally, then do not cache the plugins. This ensures that reloading them (development,
 	// update) will actually get the new content.
 	if !config.UseInCluster {
@@ -264,8 +340,12 @@ func addPluginRoutes(config *HeadlampConfig, r *mux.Router) {
 	r.PathPrefix("/plugins/").Handler(pluginHandler)
 
 	if config.StaticPluginDir != "" {
-		staticPluginsHandler := http.StripPrefix(config.BaseURL+"/static-plugins/",
-			http.FileServer(http.Dir(config.StaticPluginDir)))
+		var staticPluginFiles http.Handler = http.FileServer(http.Dir(config.StaticPluginDir))
+		if config.pluginVerifier != nil {
+			staticPluginFiles = verifiedPluginsHandler(config.pluginVerifier, config.StaticPluginDir, staticPluginFiles)
+		}
+
+		staticPluginsHandler := http.StripPrefix(config.BaseURL+"/static-plugins/", staticPluginFiles)
 		r.PathPrefix("/static-plugins/").Handler(staticPluginsHandler)
 	}
 }
@@ -292,11 +372,11 @@ func addPluginDeleteRoute(config *HeadlampConfig, r *mux.Router) {
 			config.Metrics.PluginDeleteCount.Add(ctx, 1)
 		}
 
-		logger.Log(logger.LevelInfo, nil, nil, "Received DELETE request for plugin: "+mux.Vars(r)["name"])
+		logger.LogCtx(r.Context(), logger.LevelInfo, nil, nil, "Received DELETE request for plugin: "+mux.Vars(r)["name"])
 
 		if err := checkHeadlampBackendToken(w, r); err != nil {
 			config.telemetryHandler.RecordError(span, err, " Invalid backend token")
-			logger.Log(logger.LevelWarn, nil, err, "Invalid backend token for DELETE /plugins/{name}")
+			logger.LogCtx(r.Context(), logger.LevelWarn, nil, err, "Invalid backend token for DELETE /plugins/{name}")
 			return
 		}
 
@@ -304,11 +384,11 @@ func addPluginDeleteRoute(config *HeadlampConfig, r *mux.Router) {
 		if err != nil {
 			config.telemetryHandler.RecordError(span, err, "Failed to delete plugin")
 
-			logger.Log(logger.LevelError, nil, err, "Error deleting plugin: "+pluginName)
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "Error deleting plugin: "+pluginName)
 			http.Error(w, "Error deleting plugin", http.StatusInternalServerError)
 			return
 		}
-		logger.Log(logger.LevelInfo, nil, nil, "Plugin deleted successfully: "+pluginName)
+		logger.LogCtx(r.Context(), logger.LevelInfo, nil, nil, "Plugin deleted successfully: "+pluginName)
 
 		w.WriteHeader(http.StatusOK)
 	}).Methods("DELETE")
@@ -333,7 +413,7 @@ func addPluginListRoute(config *HeadlampConfig, r *mux.Router) {
 			config.Metrics.PluginLoadCount.Add(ctx, 1)
 		}
 
-		logger.Log(logger.LevelInfo, nil, nil, "Received GET request for plugin list")
+		logger.LogCtx(r.Context(), logger.LevelInfo, nil, nil, "Received GET request for plugin list")
 
 		w.Header().Set("Content-Type", "application/json")
 		pluginsList, err := config.cache.Get(context.Background(), plugins.PluginListKey)
@@ -348,14 +428,17 @@ func addPluginListRoute(config *HeadlampConfig, r *mux.Router) {
 				span.SetAttributes(attribute.Int("plugins.count", len(list)))
 			}
 		}
+		if list, ok := pluginsList.([]string); ok && config.pluginVerifier != nil {
+			pluginsList = config.pluginVerifier.Filter(list, config.StaticPluginDir, config.PluginDir)
+		}
 		if err := json.NewEncoder(w).Encode(pluginsList); err != nil {
-			logger.Log(logger.LevelError, nil, err, "encoding plugins base paths list")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding plugins base paths list")
 		} else {
 			// Notify that the client has requested the plugins list. So we can start sending
 			// refresh requests.
 			if err := config.cache.Set(context.Background(), plugins.PluginCanSendRefreshKey, true); err != nil {
 				config.telemetryHandler.RecordError(span, err, "Failed to set plugin-can-send-refresh key")
-				logger.Log(logger.LevelError, nil, err, "setting plugin-can-send-refresh key failed")
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "setting plugin-can-send-refresh key failed")
 			} else if config.Telemetry != nil {
 				span.SetStatus(codes.Ok, "Plugin list retrieved successfully")
 			}
@@ -370,7 +453,12 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 	config.StaticPluginDir = os.Getenv("HEADLAMP_STATIC_PLUGINS_DIR")
 
 	logger.Log(logger.LevelInfo, nil, nil, "Creating Headlamp handler")
-	logger.Log(logger.LevelInfo, nil, nil, "Listen address: "+fmt.Sprintf("%s:%d", config.ListenAddr, config.Port))
+	if config.ListenSocket != "" {
+		logger.Log(logger.LevelInfo, nil, nil, "Listen socket: "+config.ListenSocket)
+	} else {
+		logger.Log(logger.LevelInfo, nil, nil, "Listen address: "+fmt.Sprintf("%s:%d", config.ListenAddr, config.Port))
+	}
+
 	logger.Log(logger.LevelInfo, nil, nil, "Kubeconfig path: "+kubeConfigPath)
 	logger.Log(logger.LevelInfo, nil, nil, "Static plugin dir: "+config.StaticPluginDir)
 	logger.Log(logger.LevelInfo, nil, nil, "Plugins dir: "+config.PluginDir)
@@ -380,15 +468,35 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 	logger.Log(logger.LevelInfo, nil, nil, "TLS certificate path: "+config.TLSCertPath)
 	logger.Log(logger.LevelInfo, nil, nil, "TLS key path: "+config.TLSKeyPath)
 
-	plugins.PopulatePluginsCache(config.StaticPluginDir, config.PluginDir, config.cache)
+	if config.headless {
+		logger.Log(logger.LevelInfo, nil, nil, "Headless mode: the frontend and plugins are not served")
+	} else {
+		config.installOCIPlugins()
+		plugins.PopulatePluginsCache(config.StaticPluginDir, config.PluginDir, config.cache)
+		config.startPluginBackends()
+	}
+
+	if config.clusterMetrics != nil {
+		go config.clusterMetrics.Run(context.Background())
+	}
+
+	if config.EnableHelm && config.helmRepoRefreshInterval > 0 {
+		go helm.RefreshRepositoriesEvery(context.Background(), config.helmRepoRefreshInterval)
+	}
+
+	if config.remoteKubeConfigs != nil && config.remoteKubeConfigRefreshInterval > 0 {
+		go config.remoteKubeConfigs.Run(context.Background(), config.remoteKubeConfigRefreshInterval)
+	}
 
 	skipFunc := kubeconfig.SkipKubeContextInCommaSeparatedString(config.SkippedKubeContexts)
 
 	if !config.UseInCluster || config.WatchPluginsChanges {
 		// in-cluster mode is unlikely to want reloading plugins.
-		pluginEventChan := make(chan string)
-		go plugins.Watch(config.PluginDir, pluginEventChan)
-		go plugins.HandlePluginEvents(config.StaticPluginDir, config.PluginDir, pluginEventChan, config.cache)
+		if !config.headless {
+			pluginEventChan := make(chan string)
+			go plugins.Watch(config.PluginDir, pluginEventChan)
+			go plugins.HandlePluginEvents(config.StaticPluginDir, config.PluginDir, pluginEventChan, config.cache)
+		}
 		// in-cluster mode is unlikely to want reloading kubeconfig.
 		go kubeconfig.LoadAndWatchFiles(config.KubeConfigStore, kubeConfigPath, kubeconfig.KubeConfig, skipFunc)
 	}
@@ -417,7 +525,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		}
 	}
 
-	if config.StaticDir != "" {
+	if config.StaticDir != "" && !config.headless {
 		baseURLReplace(config.StaticDir, config.BaseURL)
 	}
 
@@ -430,6 +538,10 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		r = baseRoute.PathPrefix(config.BaseURL).Subrouter()
 	}
 
+	// The allowed verbs and the dry run mode apply to the routes of the backend acting on the
+	// clusters too.
+	r.Use(config.verbsMiddleware, config.dryRunMiddleware)
+
 	fmt.Println("*** Headlamp Server ***")
 	fmt.Println("  API Routers:")
 
@@ -459,7 +571,9 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		logger.Log(logger.LevelError, nil, err, "loading dynamic kubeconfig")
 	}
 
-	addPluginRoutes(config, r)
+	if !config.headless {
+		addPluginRoutes(config, r)
+	}
 
 	// Setup port forwarding handlers.
 	r.HandleFunc("/clusters/{clusterName}/portforward", func(w http.ResponseWriter, r *http.Request) {
@@ -477,6 +591,32 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		portforward.GetPortForwardByID(config.cache, w, r)
 	}).Methods("GET")
 
+	r.HandleFunc("/portforwards", func(w http.ResponseWriter, r *http.Request) {
+		if err := checkHeadlampBackendToken(w, r); err != nil {
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+			return
+		}
+
+		portforward.GetAllPortForwards(config.cache, w, r)
+	}).Methods("GET")
+
+	// Stop the port forwards of contexts that expire or are removed.
+	go portforward.WatchContexts(context.Background(), config.KubeConfigStore, config.cache)
+
+	// Setup pod exec and attach streaming handlers.
+	r.HandleFunc("/clusters/{clusterName}/exec", func(w http.ResponseWriter, r *http.Request) {
+		if checkWebSocketCSRF(w, r) {
+			podexec.HandleExec(config.KubeConfigStore, w, r)
+		}
+	}).Methods("GET")
+
+	r.HandleFunc("/clusters/{clusterName}/attach", func(w http.ResponseWriter, r *http.Request) {
+		if checkWebSocketCSRF(w, r) {
+			podexec.HandleAttach(config.KubeConfigStore, w, r)
+		}
+	}).Methods("GET")
+
 	config.handleClusterRequests(r)
 
 	r.HandleFunc("/externalproxy", func(w http.ResponseWriter, r *http.Request) {
@@ -486,7 +626,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		}
 
 		if proxyURL == "" {
-			logger.Log(logger.LevelError, map[string]string{"proxyURL": proxyURL},
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"proxyURL": proxyURL},
 				errors.New("proxy URL is empty"), "proxy URL is empty")
 			http.Error(w, "proxy URL is empty", http.StatusBadRequest)
 
@@ -495,7 +635,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		url, err := url.Parse(proxyURL)
 		if err != nil {
-			logger.Log(logger.LevelError, map[string]string{"proxyURL": proxyURL},
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"proxyURL": proxyURL},
 				err, "The provided proxy URL is invalid")
 			http.Error(w, fmt.Sprintf("The provided proxy URL is invalid: %v", err), http.StatusBadRequest)
 
@@ -513,7 +653,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		}
 
 		if !isURLContainedInProxyURLs {
-			logger.Log(logger.LevelError, nil, err, "no allowed proxy url match, request denied")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "no allowed proxy url match, request denied")
 			http.Error(w, "no allowed proxy url match, request denied ", http.StatusBadRequest)
 
 			return
@@ -523,7 +663,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		proxyReq, err := http.NewRequestWithContext(ctx, r.Method, proxyURL, r.Body)
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "creating request")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "creating request")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
 			return
@@ -545,7 +685,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		resp, err := client.Do(proxyReq)
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "making request")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "making request")
 			http.Error(w, err.Error(), http.StatusBadGateway)
 
 			return
@@ -560,7 +700,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		case "gzip":
 			reader, err = gzip.NewReader(resp.Body)
 			if err != nil {
-				logger.Log(logger.LevelError, nil, err, "reading gzip response")
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "reading gzip response")
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 
 				return
@@ -572,7 +712,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		respBody, err := io.ReadAll(reader)
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "reading response")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "reading response")
 			http.Error(w, err.Error(), http.StatusBadGateway)
 
 			return
@@ -580,7 +720,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		_, err = w.Write(respBody)
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "writing response")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "writing response")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
 			return
@@ -590,7 +730,34 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 	})
 
 	// Configuration
-	r.HandleFunc("/config", config.getConfig).Methods("GET")
+	r.HandleFunc("/config", config.handleConfig).Methods("GET")
+
+	// Runtime log level
+	r.HandleFunc("/log-level", handleLogLevel).Methods("GET", "PUT")
+
+	// Audit trail of the context operations
+	r.HandleFunc("/audit/contexts", config.handleAuditContexts).Methods("GET")
+
+	// Diagnostics of the contexts
+	r.HandleFunc("/doctor", config.handleDoctor).Methods("GET")
+
+	// Statistics of the caches
+	r.HandleFunc("/cache-stats", config.handleCacheStats).Methods("GET")
+
+	// Contexts and kubeconfig files skipped while loading the kubeconfig files
+	r.HandleFunc("/kubeconfig-errors", config.handleKubeConfigErrors).Methods("GET")
+
+	// Clusters nested by group, and the groups
+	r.HandleFunc("/cluster-groups", config.handleClusterGroups).Methods("GET")
+	r.HandleFunc("/cluster-groups/{name}", config.handleClusterGroup).Methods("PUT", "DELETE")
+
+	// Node and pod metrics, polled and cached for all the clients
+	if config.clusterMetrics != nil {
+		r.HandleFunc("/cluster-metrics", config.handleClusterMetrics).Methods("GET")
+	}
+
+	// CSRF token for the requests changing the backend state
+	r.HandleFunc("/csrf-token", handleCSRFToken).Methods("GET")
 
 	// Auth token management
 	r.HandleFunc("/auth/set-token", config.handleSetToken).Methods("POST")
@@ -598,6 +765,14 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 	// Websocket connections
 	r.HandleFunc("/wsMultiplexer", config.multiplexer.HandleClientWebSocket)
 
+	// Reverse tunnels of the agents of the clusters with no inbound connectivity
+	if config.tunnelServer != nil {
+		r.Handle("/tunnel", config.tunnelServer).Methods("GET")
+	}
+
+	// Server-Sent Events fallback for when WebSocket upgrades are not possible
+	r.HandleFunc("/sseMultiplexer", config.multiplexer.HandleClientSSE).Methods("GET")
+
 	config.addClusterSetupRoute(r)
 
 	oauthRequestMap := make(map[string]*OauthConfig)
@@ -615,7 +790,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		kContext, err := config.KubeConfigStore.GetContext(cluster)
 		if err != nil {
-			logger.Log(logger.LevelError, map[string]string{"cluster": cluster},
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": cluster},
 				err, "failed to get context")
 
 			http.NotFound(w, r)
@@ -624,7 +799,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		oidcAuthConfig, err := kContext.OidcConfig()
 		if err != nil {
-			logger.Log(logger.LevelError, map[string]string{"cluster": cluster},
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": cluster},
 				err, "failed to get oidc config")
 
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -639,7 +814,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		provider, err := oidc.NewProvider(ctx, oidcAuthConfig.IdpIssuerURL)
 		if err != nil {
-			logger.Log(logger.LevelError, map[string]string{"idpIssuerURL": oidcAuthConfig.IdpIssuerURL},
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"idpIssuerURL": oidcAuthConfig.IdpIssuerURL},
 				err, "failed to get provider")
 
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -679,14 +854,14 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 		decodedState, err := base64.StdEncoding.DecodeString(state)
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "failed to decode state")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "failed to decode state")
 			http.Error(w, "wrong state set, invalid request "+err.Error(), http.StatusBadRequest)
 
 			return
 		}
 
 		if state == "" {
-			logger.Log(logger.LevelError, nil, err, "invalid request state is empty")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid request state is empty")
 			http.Error(w, "invalid request state is empty", http.StatusBadRequest)
 
 			return
@@ -696,7 +871,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		if oauthConfig, ok := oauthRequestMap[state]; ok {
 			oauth2Token, err := oauthConfig.Config.Exchange(oauthConfig.Ctx, r.URL.Query().Get("code"))
 			if err != nil {
-				logger.Log(logger.LevelError, nil, err, "failed to exchange token")
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "failed to exchange token")
 				http.Error(w, "Failed to exchange token: "+err.Error(), http.StatusInternalServerError)
 
 				return
@@ -709,7 +884,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 			rawUserToken, ok := oauth2Token.Extra(tokenType).(string)
 			if !ok {
-				logger.Log(logger.LevelError, nil, err, fmt.Sprintf("no %s field in oauth2 token", tokenType))
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, fmt.Sprintf("no %s field in oauth2 token", tokenType))
 				http.Error(w, fmt.Sprintf("No %s field in oauth2 token.", tokenType), http.StatusInternalServerError)
 
 				return
@@ -717,7 +892,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 			if err := config.cache.Set(context.Background(),
 				fmt.Sprintf("oidc-token-%s", rawUserToken), oauth2Token.RefreshToken); err != nil {
-				logger.Log(logger.LevelError, nil, err, "failed to cache refresh token")
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "failed to cache refresh token")
 				http.Error(w, "Failed to cache refresh token: "+err.Error(), http.StatusInternalServerError)
 
 				return
@@ -725,7 +900,7 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 
 			idToken, err := oauthConfig.Verifier.Verify(oauthConfig.Ctx, rawUserToken)
 			if err != nil {
-				logger.Log(logger.LevelError, nil, err, "failed to verify ID Token")
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "failed to verify ID Token")
 				http.Error(w, "Failed to verify ID Token: "+err.Error(), http.StatusInternalServerError)
 
 				return
@@ -737,12 +912,18 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 			}{oauth2Token, new(json.RawMessage)}
 
 			if err := idToken.Claims(&resp.IDTokenClaims); err != nil {
-				logger.Log(logger.LevelError, nil, err, "failed to get id token claims")
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "failed to get id token claims")
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 
 				return
 			}
 
+			// The token is verified, so its user can be trusted in the cluster events.
+			if err := auth.CacheVerifiedUser(r.Context(), config.cache, rawUserToken,
+				audit.UserFromToken(rawUserToken), idToken.Expiry); err != nil {
+				logger.LogCtx(r.Context(), logger.LevelError, nil, err, "failed to cache the user of the token")
+			}
+
 			var redirectURL string
 			if config.DevMode {
 				redirectURL = "http://localhost:3000/"
@@ -767,22 +948,8 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 	})
 
 	// Serve the frontend if needed
-	if spa.UseEmbeddedFiles {
-		r.PathPrefix("/").Handler(spa.NewEmbeddedHandler(spa.StaticFilesEmbed, "index.html", config.BaseURL))
-	} else if config.StaticDir != "" {
-		staticPath := config.StaticDir
-
-		if isWindows {
-			// We support unix paths on windows. So "frontend/static" works.
-			if strings.Contains(config.StaticDir, "/") {
-				staticPath = filepath.FromSlash(config.StaticDir)
-			}
-		}
-
-		spa := spa.NewHandler(staticPath, "index.html", config.BaseURL)
-		r.PathPrefix("/").Handler(spa)
-
-		http.Handle("/", r)
+	if !config.headless {
+		addFrontendRoutes(config, r)
 	}
 
 	// On dev mode we're loose about where connections come from
@@ -790,13 +957,16 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 		headers := handlers.AllowedHeaders([]string{
 			"X-HEADLAMP_BACKEND-TOKEN", "X-Requested-With", "Content-Type",
 			"Authorization", "Forward-To",
-			"KUBECONFIG", "X-HEADLAMP-USER-ID",
+			"KUBECONFIG", "X-HEADLAMP-USER-ID", logger.RequestIDHeader, CSRFHeader, auth.APITokenHeader,
+			auth.TokenOverrideHeader,
 		})
 		methods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "DELETE", "PATCH", "OPTIONS"})
+		exposedHeaders := handlers.ExposedHeaders([]string{logger.RequestIDHeader, DryRunHeader})
 
 		return handlers.CORS(
 			headers,
 			methods,
+			exposedHeaders,
 			handlers.AllowCredentials(),
 			handlers.AllowedOriginValidator(func(s string) bool { return true }),
 		)(r)
@@ -805,6 +975,27 @@ func createHeadlampHandler(config *HeadlampConfig) http.Handler {
 	return r
 }
 
+// addFrontendRoutes serves the frontend, embedded or from the static dir, if any.
+func addFrontendRoutes(config *HeadlampConfig, r *mux.Router) {
+	if spa.UseEmbeddedFiles {
+		r.PathPrefix("/").Handler(spa.NewEmbeddedHandler(spa.StaticFilesEmbed, "index.html", config.BaseURL))
+	} else if config.StaticDir != "" {
+		staticPath := config.StaticDir
+
+		if isWindows {
+			// We support unix paths on windows. So "frontend/static" works.
+			if strings.Contains(config.StaticDir, "/") {
+				staticPath = filepath.FromSlash(config.StaticDir)
+			}
+		}
+
+		spa := spa.NewHandler(staticPath, "index.html", config.BaseURL)
+		r.PathPrefix("/").Handler(spa)
+
+		http.Handle("/", r)
+	}
+}
+
 // configureTLSContext configures TLS settings for the HTTP client in the context.
 // If skipTLSVerify is true, TLS verification will be skipped.
 // If caCert is provided, it will be added to the certificate pool for TLS verification.
@@ -871,7 +1062,7 @@ func refreshAndCacheNewToken(oidcAuthConfig *kubeconfig.OidcConfig,
 func (c *HeadlampConfig) refreshAndSetToken(oidcAuthConfig *kubeconfig.OidcConfig,
 	cache cache.Cache[interface{}], token string,
 	w http.ResponseWriter, r *http.Request, cluster string, span trace.Span, ctx context.Context,
-) {
+) error {
 	// The token type to use
 	tokenType := "id_token"
 	if c.oidcUseAccessToken {
@@ -891,10 +1082,12 @@ func (c *HeadlampConfig) refreshAndSetToken(oidcAuthConfig *kubeconfig.OidcConfi
 		idpIssuerURL,
 	)
 	if err != nil {
-		logger.Log(logger.LevelError, map[string]string{"cluster": cluster},
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": cluster},
 			err, "failed to refresh token")
 		c.telemetryHandler.RecordError(span, err, "Token refresh failed")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error", "token_refresh_failure"))
+
+		return err
 	} else if newToken != nil {
 		var newTokenString string
 		if c.oidcUseAccessToken {
@@ -908,6 +1101,8 @@ func (c *HeadlampConfig) refreshAndSetToken(oidcAuthConfig *kubeconfig.OidcConfi
 
 		c.telemetryHandler.RecordEvent(span, "Token refreshed successfully")
 	}
+
+	return nil
 }
 
 func (c *HeadlampConfig) incrementRequestCounter(ctx context.Context) {
@@ -956,7 +1151,7 @@ func (c *HeadlampConfig) handleGetContextError(err error, cluster string, w http
 	span trace.Span, ctx context.Context, start time.Time, next http.Handler,
 ) bool {
 	if err != nil {
-		logger.Log(logger.LevelError, map[string]string{"cluster": cluster},
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": cluster},
 			err, "failed to get context")
 		c.telemetryHandler.RecordError(span, err, "Failed to get context")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error", "get_context_failure"))
@@ -1037,8 +1232,18 @@ func (c *HeadlampConfig) OIDCTokenRefreshMiddleware(next http.Handler) http.Hand
 			return
 		}
 
-		// refresh and cache new token
-		c.refreshAndSetToken(oidcAuthConfig, c.cache, token, w, r, cluster, span, ctx)
+		// refresh and cache new token, telling the client to log in again if the token already
+		// expired and couldn't be refreshed
+		err = c.refreshAndSetToken(oidcAuthConfig, c.cache, token, w, r, cluster, span, ctx)
+		if err != nil && auth.IsTokenExpired(token) {
+			kubeconfig.WriteError(ctx, w, &kubeconfig.Error{Code: kubeconfig.CodeAuthExpired, Context: cluster, Err: err},
+				http.StatusUnauthorized)
+			c.telemetryHandler.RecordDuration(ctx, start,
+				attribute.String("api.route", "OIDCTokenRefreshMiddleware"),
+				attribute.String("status", "auth_expired"))
+
+			return
+		}
 
 		next.ServeHTTP(w, r)
 		c.telemetryHandler.RecordDuration(ctx, start,
@@ -1072,6 +1277,10 @@ func StartHeadlampServer(config *HeadlampConfig) {
 	config.Metrics = metrics
 	config.telemetryHandler = telemetry.NewRequestHandler(tel, metrics)
 
+	if config.multiplexer != nil {
+		config.multiplexer.metrics = metrics
+	}
+
 	router := mux.NewRouter()
 
 	if config.Telemetry != nil && config.Metrics != nil {
@@ -1080,7 +1289,7 @@ func StartHeadlampServer(config *HeadlampConfig) {
 	}
 
 	// Copy static files as squashFS is read-only (AppImage)
-	if config.StaticDir != "" {
+	if config.StaticDir != "" && !config.headless {
 		dir, err := os.MkdirTemp(os.TempDir(), ".headlamp")
 		if err != nil {
 			logger.Log(logger.LevelError, nil, err, "Failed to create static dir")
@@ -1099,12 +1308,44 @@ func StartHeadlampServer(config *HeadlampConfig) {
 	handler := createHeadlampHandler(config)
 	handler = config.OIDCTokenRefreshMiddleware(handler)
 
-	addr := fmt.Sprintf("%s:%d", config.ListenAddr, config.Port)
+	if config.pluginBackends != nil {
+		defer config.pluginBackends.Stop()
+	}
 
-	if config.TLSCertPath != "" && config.TLSKeyPath != "" {
-		err = http.ListenAndServeTLS(addr, config.TLSCertPath, config.TLSKeyPath, handler) //nolint:gosec
-	} else {
-		err = http.ListenAndServe(addr, handler) //nolint:gosec
+	if config.csrfProtection {
+		handler = csrfMiddleware(config.BaseURL)(handler)
+	}
+
+	if config.apiTokens != nil {
+		handler = apiTokenMiddleware(config.apiTokens)(handler)
+	}
+
+	if config.accessLog {
+		handler = accessLogMiddleware(config.BaseURL, config.accessLogExclude)(handler)
+	}
+
+	handler = requestIDMiddleware(handler)
+
+	tlsConfig, err := serverTLSConfig(config.HeadlampCFG)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "Failed to set up TLS")
+		return
+	}
+
+	listener, err := listen(config.HeadlampCFG)
+	if err == nil {
+		server := &http.Server{ //nolint:gosec
+			Handler:   handler,
+			TLSConfig: tlsConfig,
+		}
+
+		err = config.serveUntilSignal(server, func() error {
+			if tlsConfig != nil {
+				return server.ServeTLS(listener, "", "")
+			}
+
+			return server.Serve(listener)
+		})
 	}
 
 	if err != nil {
@@ -1139,7 +1380,7 @@ func getHelmHandler(c *HeadlampConfig, w http.ResponseWriter, r *http.Request) (
 
 	context, err := c.KubeConfigStore.GetContext(clusterName)
 	if err != nil {
-		logger.Log(
+		logger.LogCtx(r.Context(),
 			logger.LevelError, map[string]string{"clusterName": clusterName},
 			err, "failed to get context")
 		c.telemetryHandler.RecordError(span, err, "failed to get context")
@@ -1154,7 +1395,7 @@ func getHelmHandler(c *HeadlampConfig, w http.ResponseWriter, r *http.Request) (
 
 	helmHandler, err := helm.NewHandler(context.ClientConfig(), c.cache, namespace)
 	if err != nil {
-		logger.Log(logger.LevelError, map[string]string{"namespace": namespace},
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"namespace": namespace},
 			err, "failed to create helm handler")
 		c.telemetryHandler.RecordError(span, err, "failed to create helm handler")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error", "helm handler creation failure"))
@@ -1164,6 +1405,21 @@ func getHelmHandler(c *HeadlampConfig, w http.ResponseWriter, r *http.Request) (
 		return nil, err
 	}
 
+	if context.RecordsEvents(c.recordClusterEvents) {
+		user := c.eventUser(r)
+
+		helmHandler.OnActionDone = func(result helm.ActionResult) {
+			clientset, err := context.ClientSetWithToken("")
+			if err != nil {
+				logger.Log(logger.LevelError, map[string]string{"cluster": clusterName}, err, "getting client")
+
+				return
+			}
+
+			c.recordClusterEvent(clientset, context, helmReleaseEvent(result, user))
+		}
+	}
+
 	c.telemetryHandler.RecordDuration(ctx, start, attribute.String("status", "success"))
 	c.telemetryHandler.RecordEvent(span, "Successfully created helm handler")
 
@@ -1174,6 +1430,11 @@ func getHelmHandler(c *HeadlampConfig, w http.ResponseWriter, r *http.Request) (
 // This check is to prevent access except for from the app.
 // The app sets HEADLAMP_BACKEND_TOKEN, and gives the token to the frontend.
 func checkHeadlampBackendToken(w http.ResponseWriter, r *http.Request) error {
+	// The requests authenticated with an API token are allowed too.
+	if auth.APITokenNameFromContext(r.Context()) != "" {
+		return nil
+	}
+
 	backendToken := r.Header.Get("X-HEADLAMP_BACKEND-TOKEN")
 	backendTokenEnv := os.Getenv("HEADLAMP_BACKEND_TOKEN")
 
@@ -1219,7 +1480,8 @@ func handleClusterHelm(c *HeadlampConfig, router *mux.Router) {
 				attribute.String("operation", operation))
 			c.telemetryHandler.RecordRequestCount(ctx, r)
 
-			logger.Log(logger.LevelInfo, map[string]string{"route": route}, nil, "Dispatching helm operation: "+operation)
+			logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{"route": route},
+				nil, "Dispatching helm operation: "+operation)
 			handler(w, r)
 		}
 
@@ -1242,6 +1504,9 @@ func handleClusterHelm(c *HeadlampConfig, router *mux.Router) {
 		case strings.HasSuffix(path, "/releases/upgrade") && r.Method == http.MethodPut:
 			routeHandler("/releases/upgrade", "UpgradeRelease", helmHandler.UpgradeRelease)
 			return
+		case strings.HasSuffix(path, "/releases/upgrade/preview") && r.Method == http.MethodPost:
+			routeHandler("/releases/upgrade/preview", "PreviewUpgrade", helmHandler.PreviewUpgrade)
+			return
 		case strings.HasSuffix(path, "/releases") && r.Method == http.MethodGet:
 			routeHandler("/releases", "GetRelease", helmHandler.GetRelease)
 			return
@@ -1257,6 +1522,15 @@ func handleClusterHelm(c *HeadlampConfig, router *mux.Router) {
 		case strings.HasSuffix(path, "/repositories/update") && r.Method == http.MethodPut:
 			routeHandler("/repositories/update", "UpdateRepository", helmHandler.UpdateRepository)
 			return
+		case strings.HasSuffix(path, "/registries") && r.Method == http.MethodGet:
+			routeHandler("/registries", "ListRegistries", helmHandler.ListRegistries)
+			return
+		case strings.HasSuffix(path, "/registries/login") && r.Method == http.MethodPost:
+			routeHandler("/registries/login", "RegistryLogin", helmHandler.RegistryLogin)
+			return
+		case strings.HasSuffix(path, "/registries/logout") && r.Method == http.MethodPost:
+			routeHandler("/registries/logout", "RegistryLogout", helmHandler.RegistryLogout)
+			return
 		case strings.HasSuffix(path, "/charts") && r.Method == http.MethodGet:
 			routeHandler("/charts", "ListCharts", helmHandler.ListCharts)
 			return
@@ -1264,7 +1538,7 @@ func handleClusterHelm(c *HeadlampConfig, router *mux.Router) {
 			routeHandler("/action/status", "GetActionStatus", helmHandler.GetActionStatus)
 			return
 		default:
-			logger.Log(logger.LevelError, map[string]string{"path": path}, nil, "Unknown helm API route")
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"path": path}, nil, "Unknown helm API route")
 
 			c.telemetryHandler.RecordEvent(span, "Unknown API route", attribute.String("path", path))
 			span.SetStatus(codes.Error, "Unknown API route")
@@ -1280,16 +1554,46 @@ func handleClusterHelm(c *HeadlampConfig, router *mux.Router) {
 func (c *HeadlampConfig) handleError(w http.ResponseWriter, ctx context.Context,
 	span trace.Span, err error, msg string, status int,
 ) {
-	logger.Log(logger.LevelError, nil, err, msg)
+	logger.LogCtx(ctx, logger.LevelError, nil, err, msg)
 	c.telemetryHandler.RecordError(span, err, msg)
 	c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", msg))
-	http.Error(w, err.Error(), status)
+	kubeconfig.WriteError(ctx, w, err, status)
 }
 
 func clusterRequestHandler(c *HeadlampConfig) http.Handler { //nolint:funlen
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		ctx := r.Context()
+		ctx := logger.WithComponent(r.Context(), logger.ComponentProxy)
+
+		if c.auditRecorder != nil && audit.IsMutating(r.Method) {
+			var finish func()
+
+			w, finish = c.auditRequest(w, r)
+			defer finish()
+		}
+
+		info := apirequest.Parse(r.Method, "/"+mux.Vars(r)["api"], r.URL.Query())
+		if !c.isVerbAllowed(info.Verb) {
+			writeVerbForbidden(w, r, info)
+
+			return
+		}
+
+		// Who scales a resource is taken before the token overriding the credentials is removed.
+		scaleUser := ""
+		if isScaleRequest(r.Method, info) {
+			scaleUser = c.eventUser(r)
+		}
+
+		// The token overriding the credentials of the context isn't sent to the cluster as is.
+		tokenOverride, err := auth.TokenOverride(r)
+		r.Header.Del(auth.TokenOverrideHeader)
+
+		if err != nil {
+			kubeconfig.WriteError(ctx, w, err, http.StatusBadRequest)
+
+			return
+		}
 
 		ctx, span := telemetry.CreateSpan(ctx, r, "cluster-api", "handleClusterAPI",
 			attribute.String("cluster", mux.Vars(r)["clusterName"]),
@@ -1304,11 +1608,11 @@ func clusterRequestHandler(c *HeadlampConfig) http.Handler { //nolint:funlen
 
 		contextKey, err := c.getContextKeyForRequest(r)
 		if err != nil {
-			c.handleError(w, ctx, span, err, "failed to get context key", http.StatusBadRequest)
+			c.handleError(w, ctx, span, err, "failed to get context key", contextKeyErrorStatus(err))
 			return
 		}
 
-		kContext, err := c.KubeConfigStore.GetContext(contextKey)
+		kContext, err := kubeconfig.GetContextWithSpan(ctx, c.KubeConfigStore, contextKey)
 		if err != nil {
 			c.handleError(w, ctx, span, err, "failed to get context", http.StatusNotFound)
 			return
@@ -1325,6 +1629,10 @@ func clusterRequestHandler(c *HeadlampConfig) http.Handler { //nolint:funlen
 			return
 		}
 
+		if kContext.Insecure {
+			c.warnInsecureContext(r, contextKey, kContext)
+		}
+
 		// Record attributes about the proxy request
 		span.SetAttributes(
 			attribute.String("cluster.server", kContext.Cluster.Server),
@@ -1340,16 +1648,51 @@ func clusterRequestHandler(c *HeadlampConfig) http.Handler { //nolint:funlen
 		r.URL.Path = mux.Vars(r)["api"]
 		r.URL.Scheme = clusterURL.Scheme
 
+		if c.contextNamespace {
+			r.URL.Path = c.withContextNamespace(kContext, info, r.URL.Path)
+		}
+
+		_, tokenSpan := telemetry.CreateSpan(ctx, r, "auth", "GetTokenFromCookie")
+
 		token, err := auth.GetTokenFromCookie(r, mux.Vars(r)["clusterName"])
 		if err == nil && token != "" {
 			r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 		}
 
+		tokenSpan.SetAttributes(attribute.Bool("token.found", err == nil && token != ""))
+		tokenSpan.End()
+
 		// Process WebSocket protocol headers if present
 		processWebSocketProtocolHeader(r)
 		plugins.HandlePluginReload(c.cache, w)
 
-		if err = kContext.ProxyRequest(w, r); err != nil {
+		dryRun := c.isDryRun(r, info, kContext)
+		if dryRun {
+			setDryRun(w, r)
+		}
+
+		if isScaleRequest(r.Method, info) && !dryRun && kContext.RecordsEvents(c.recordClusterEvents) {
+			rw := &accessLogResponseWriter{ResponseWriter: w}
+			w = rw
+
+			defer c.recordScaleEvent(r, rw, kContext, info, scaleUser, tokenOverride)
+		}
+
+		// The upstream call is traced as a child of the request span.
+		r = r.WithContext(ctx)
+
+		// The upstream request is cancelled with r when the client goes away, e.g. a browser
+		// aborting a large list, so it stops loading the API server.
+		if tokenOverride != "" {
+			err = kContext.ProxyRequestWithToken(w, r, tokenOverride)
+		} else {
+			err = kContext.ProxyRequest(w, r)
+		}
+
+		c.telemetryHandler.RecordUpstreamOutcome(ctx, telemetry.Outcome(ctx),
+			attribute.String("cluster", contextKey), attribute.String("kind", "proxy"))
+
+		if err != nil {
 			c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", "proxy_error"),
 				attribute.String("cluster", contextKey))
 			c.handleError(w, ctx, span, err, "failed to proxy request", http.StatusInternalServerError)
@@ -1371,11 +1714,18 @@ func clusterRequestHandler(c *HeadlampConfig) http.Handler { //nolint:funlen
 func handleClusterAPI(c *HeadlampConfig, router *mux.Router) {
 	router.HandleFunc("/clusters/{clusterName}/set-token", c.handleSetToken).Methods("POST")
 
+	if c.EnableDynamicClusters {
+		router.HandleFunc("/clusters/{clusterName}/rehydrate", c.handleStatelessRehydrate).Methods("POST")
+	}
+
 	handler := clusterRequestHandler(c)
 	if c.CacheEnabled {
 		handler = CacheMiddleWare(c)(handler)
 	}
 
+	handler = DiscoveryCacheMiddleware(c)(handler)
+	handler = OpenAPICacheMiddleware(c)(handler)
+
 	router.PathPrefix("/clusters/{clusterName}/{api:.*}").Handler(handler)
 }
 
@@ -1387,7 +1737,7 @@ func recordRequestCompletion(c *HeadlampConfig, ctx context.Context,
 		attribute.String("http.method", r.Method),
 		attribute.String("http.path", r.URL.Path),
 		attribute.String("cluster", mux.Vars(r)["clusterName"]))
-	logger.Log(logger.LevelInfo,
+	requestLogSampler.LogCtx(ctx, logger.LevelInfo,
 		map[string]string{"duration_ms": fmt.Sprintf("%.2f", duration)},
 		nil, "Request completed successfully")
 }
@@ -1503,20 +1853,50 @@ func (c *HeadlampConfig) getClusters() []Cluster {
 
 		clusterID := context.ClusterID
 
+		originalName := context.OriginalName
+		if originalName == "" {
+			originalName = context.Name
+		}
+
+		metadata := map[string]interface{}{
+			"source":     source,
+			"namespace":  context.Namespace,
+			"extensions": context.KubeContext.Extensions,
+			"origin": map[string]interface{}{
+				"kubeconfig": kubeconfigPath,
+			},
+			"originalName": originalName,
+			"clusterID":    clusterID,
+		}
+
+		addDisplayMetadata(metadata, context.DisplayMetadata)
+
+		if len(context.Labels) > 0 {
+			metadata["labels"] = context.Labels
+		}
+
+		if context.Insecure {
+			metadata["insecure"] = true
+		}
+
+		// The extensions of the cluster and the context, other than headlamp_info, which is in
+		// the extensions of the kube context.
+		if len(context.Extensions) > 0 {
+			metadata["kubeconfigExtensions"] = context.Extensions
+		}
+
+		// The capabilities are detected in the background, so they're missing until then.
+		if c.capabilities != nil {
+			if capabilities := c.capabilities.Get(context); capabilities != nil {
+				metadata["capabilities"] = capabilities
+			}
+		}
+
 		clusters = append(clusters, Cluster{
 			Name:     context.Name,
 			Server:   context.Cluster.Server,
 			AuthType: context.AuthType(),
-			Metadata: map[string]interface{}{
-				"source":     source,
-				"namespace":  context.KubeContext.Namespace,
-				"extensions": context.KubeContext.Extensions,
-				"origin": map[string]interface{}{
-					"kubeconfig": kubeconfigPath,
-				},
-				"originalName": context.Name,
-				"clusterID":    clusterID,
-			},
+			Metadata: metadata,
 		})
 	}
 
@@ -1616,7 +1996,7 @@ func (c *HeadlampConfig) getConfig(w http.ResponseWriter, r *http.Request) {
 	clientConfig := clientConfig{c.getClusters(), c.EnableDynamicClusters}
 
 	if err := json.NewEncoder(w).Encode(&clientConfig); err != nil {
-		logger.Log(logger.LevelError, nil, err, "encoding config")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding config")
 	}
 }
 
@@ -1635,7 +2015,7 @@ func (c *HeadlampConfig) addCluster(w http.ResponseWriter, r *http.Request) {
 	if err := checkHeadlampBackendToken(w, r); err != nil {
 		c.telemetryHandler.RecordError(span, err, "invalid backend token")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", "invalid token"))
-		logger.Log(logger.LevelError, nil, err, "invalid token")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
 
 		return
 	}
@@ -1666,16 +2046,23 @@ func (c *HeadlampConfig) addCluster(w http.ResponseWriter, r *http.Request) {
 		c.telemetryHandler.RecordError(span, errors.New("no contexts found in kubeconfig"), "no contexts found in kubeconfig")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", "no_contexts_found"))
 		http.Error(w, "getting contexts from kubeconfig", http.StatusBadRequest)
-		logger.Log(logger.LevelError, nil, errors.New("no contexts found in kubeconfig"), "getting contexts from kubeconfig")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, errors.New("no contexts found in kubeconfig"),
+			"getting contexts from kubeconfig")
 
 		return
 	}
 
-	setupErrors = c.addContextsToStore(contexts, setupErrors)
+	setupErrors = c.addContextsToStore(r, contexts, setupErrors)
 	if err := c.handleSetupErrors(setupErrors, ctx, w, span); err != nil {
 		return
 	}
 
+	if c.capabilities != nil {
+		for i := range contexts {
+			c.capabilities.Detect(&contexts[i])
+		}
+	}
+
 	if c.Telemetry != nil {
 		span.SetAttributes(attribute.Int("contexts.added", len(contexts)))
 		span.SetStatus(codes.Ok, "Cluster added successfully")
@@ -1689,7 +2076,7 @@ func (c *HeadlampConfig) addCluster(w http.ResponseWriter, r *http.Request) {
 func decodeClusterRequest(r *http.Request) (ClusterReq, error) {
 	var clusterReq ClusterReq
 	if err := json.NewDecoder(r.Body).Decode(&clusterReq); err != nil {
-		logger.Log(logger.LevelError, nil, err, "decoding cluster info")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "decoding cluster info")
 		return ClusterReq{}, fmt.Errorf("decoding cluster info: %w", err)
 	}
 
@@ -1718,6 +2105,15 @@ func (c *HeadlampConfig) handleSetupErrors(setupErrors []error,
 		}
 
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", "setup_context_error"))
+
+		for _, setupErr := range setupErrors {
+			if errors.Is(setupErr, kubeconfig.ErrQuotaExceeded) || errors.Is(setupErr, kubeconfig.ErrInsecureContext) {
+				http.Error(w, setupErr.Error(), http.StatusForbidden)
+
+				return setupErrors
+			}
+		}
+
 		http.Error(w, "setting up contexts from kubeconfig", http.StatusBadRequest)
 
 		return setupErrors
@@ -1807,11 +2203,18 @@ func (c *HeadlampConfig) writeKubeConfig(kubeConfigBase64 string) error {
 	return kubeconfig.WriteToFile(*config, kubeConfigPersistenceDir)
 }
 
-// addContextsToStore adds the contexts to the store.
-func (c *HeadlampConfig) addContextsToStore(contexts []kubeconfig.Context, setupErrors []error) []error {
+// addContextsToStore adds the contexts to the store, for the request r.
+func (c *HeadlampConfig) addContextsToStore(r *http.Request, contexts []kubeconfig.Context,
+	setupErrors []error,
+) []error {
 	for i := range contexts {
 		contexts[i].Source = kubeconfig.DynamicCluster
-		if err := c.KubeConfigStore.AddContext(&contexts[i]); err != nil {
+		contexts[i].Owner = clientIDForRequest(r)
+
+		err := c.KubeConfigStore.AddContext(&contexts[i])
+		c.recordAuditEvent(r, contextAuditEvent(r, audit.VerbAddContext, contexts[i].Name, &contexts[i], err))
+
+		if err != nil {
 			setupErrors = append(setupErrors, err)
 		}
 	}
@@ -1832,7 +2235,7 @@ func (c *HeadlampConfig) deleteCluster(w http.ResponseWriter, r *http.Request) {
 		duration := time.Since(start).Milliseconds()
 
 		c.telemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "/cluster/delete"))
-		logger.Log(logger.LevelInfo, map[string]string{
+		logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{
 			"duration_ms": fmt.Sprintf("%d", duration),
 			"api.route":   "/cluster/delete",
 		}, nil, "Completed deleteCluster request")
@@ -1843,18 +2246,29 @@ func (c *HeadlampConfig) deleteCluster(w http.ResponseWriter, r *http.Request) {
 	if err := checkHeadlampBackendToken(w, r); err != nil {
 		c.telemetryHandler.RecordError(span, err, "invalid backend token")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", "invalid_token"))
-		logger.Log(logger.LevelError, nil, err, "invalid token")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
 
 		return
 	}
 
+	// The context is kept to audit where it came from.
+	kContext, _ := c.KubeConfigStore.GetContext(name)
+
 	err := c.KubeConfigStore.RemoveContext(name)
+	c.recordAuditEvent(r, contextAuditEvent(r, audit.VerbRemoveContext, name, kContext, err))
+
 	if err != nil {
 		c.handleError(w, ctx, span, err, "failed to delete cluster", http.StatusInternalServerError)
 
 		return
 	}
 
+	if c.capabilities != nil {
+		c.capabilities.Forget(name)
+	}
+
+	portforward.StopPortForwardsForContext(c.cache, name)
+
 	c.handleDeleteCluster(w, r, ctx, span, name)
 
 	c.getConfig(w, r)
@@ -1874,7 +2288,7 @@ func (c *HeadlampConfig) handleDeleteCluster(
 		return
 	}
 
-	logger.Log(logger.LevelInfo, map[string]string{"cluster": name, "proxy": name},
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{"cluster": name, "proxy": name},
 		nil, "removed cluster successfully")
 }
 
@@ -1913,7 +2327,9 @@ func (c *HeadlampConfig) getKubeConfigPath(source string) (string, error) {
 }
 
 // Handler for renaming a stateless cluster.
-func (c *HeadlampConfig) handleStatelessClusterRename(w http.ResponseWriter, r *http.Request, clusterName string) {
+func (c *HeadlampConfig) handleStatelessClusterRename(w http.ResponseWriter, r *http.Request,
+	clusterName, newClusterName string,
+) {
 	ctx := r.Context()
 	start := time.Now()
 
@@ -1925,8 +2341,16 @@ func (c *HeadlampConfig) handleStatelessClusterRename(w http.ResponseWriter, r *
 
 	defer span.End()
 
-	if err := c.KubeConfigStore.RemoveContext(clusterName); err != nil {
-		logger.Log(logger.LevelError, map[string]string{"cluster": clusterName},
+	kContext, _ := c.KubeConfigStore.GetContext(clusterName)
+
+	err := c.KubeConfigStore.RemoveContext(clusterName)
+
+	event := contextAuditEvent(r, audit.VerbRename, clusterName, kContext, err)
+	event.NewName = newClusterName
+	c.recordAuditEvent(r, event)
+
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": clusterName},
 			err, "decoding request body")
 		c.telemetryHandler.RecordError(span, err, "decoding request body")
 		c.telemetryHandler.RecordErrorCount(ctx, attribute.String("error.type", "remove_context_failure"))
@@ -1940,7 +2364,7 @@ func (c *HeadlampConfig) handleStatelessClusterRename(w http.ResponseWriter, r *
 
 	duration := time.Since(start).Milliseconds()
 	c.telemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "handleStatelessClusterRename"))
-	logger.Log(logger.LevelInfo, map[string]string{
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{
 		"duration_ms": fmt.Sprintf("%d", duration),
 		"api.route":   "handleStatelessClusterRename",
 	}, nil, "Completed stateless cluster rename")
@@ -2058,7 +2482,7 @@ func (c *HeadlampConfig) renameCluster(w http.ResponseWriter, r *http.Request) {
 	// Handle stateless clusters separately
 	if reqBody.Stateless {
 		c.telemetryHandler.RecordEvent(span, "Delegating to handleStatelessClusterRename")
-		c.handleStatelessClusterRename(w, r, clusterName)
+		c.handleStatelessClusterRename(w, r, clusterName, reqBody.NewClusterName)
 
 		return
 	}
@@ -2069,7 +2493,7 @@ func (c *HeadlampConfig) renameCluster(w http.ResponseWriter, r *http.Request) {
 
 	// Record success metrics and logging
 	c.telemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "renameCluster"))
-	logger.Log(logger.LevelInfo, map[string]string{
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{
 		"duration_ms": fmt.Sprintf("%d", time.Since(start).Milliseconds()),
 		"api.route":   "renameCluster",
 	}, nil, "Completed renameCluster request")
@@ -2087,9 +2511,12 @@ func (c *HeadlampConfig) handleClusterRename(w http.ResponseWriter, r *http.Requ
 
 	isUnique := CheckUniqueName(config.Contexts, clusterName, reqBody.NewClusterName)
 	if !isUnique {
-		http.Error(w, "custom name already in use", http.StatusBadRequest)
-		logger.Log(logger.LevelError, map[string]string{"cluster": clusterName},
-			err, "cluster name already exists in the kubeconfig")
+		err := &kubeconfig.Error{
+			Code:    kubeconfig.CodeNameConflict,
+			Context: reqBody.NewClusterName,
+			Err:     errors.New("custom name already in use"),
+		}
+		c.handleError(w, ctx, span, err, "cluster name already exists in the kubeconfig", http.StatusConflict)
 
 		return err
 	}
@@ -2101,7 +2528,19 @@ func (c *HeadlampConfig) handleClusterRename(w http.ResponseWriter, r *http.Requ
 		return err
 	}
 
-	if errs := c.updateCustomContextToCache(config, clusterName); len(errs) > 0 {
+	errs := c.updateCustomContextToCache(config, clusterName)
+
+	event := contextAuditEvent(r, audit.VerbRename, clusterName, nil, errors.Join(errs...))
+	event.NewName = reqBody.NewClusterName
+	event.Source = reqBody.Source
+
+	if contextName != clusterName {
+		event.OriginalName = contextName
+	}
+
+	c.recordAuditEvent(r, event)
+
+	if len(errs) > 0 {
 		c.handleError(w, ctx, span, err, "failed to update context to cache", http.StatusInternalServerError)
 		return errors.New("failed to update context cache")
 	}
@@ -2194,6 +2633,17 @@ func (c *HeadlampConfig) addClusterSetupRoute(r *mux.Router) {
 
 	// Rename a cluster
 	r.HandleFunc("/cluster/{name}", c.renameCluster).Methods("PUT")
+
+	// Update a dynamic cluster in place
+	r.HandleFunc("/cluster/{name}", c.patchCluster).Methods("PATCH")
+
+	// Update the display metadata of a cluster
+	r.HandleFunc("/cluster/{name}/metadata", c.updateClusterMetadata).Methods("PUT")
+
+	// Kubeconfigs registered by URL
+	if c.remoteKubeConfigs != nil {
+		r.HandleFunc("/kubeconfig-urls", c.handleKubeConfigURLs).Methods("GET", "POST", "DELETE")
+	}
 }
 
 /*
@@ -2210,6 +2660,7 @@ func (c *HeadlampConfig) handleNodeDrain(w http.ResponseWriter, r *http.Request)
 	var drainPayload struct {
 		Cluster  string `json:"cluster"`
 		NodeName string `json:"nodeName"`
+		drainOptions
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&drainPayload); err != nil {
@@ -2238,6 +2689,13 @@ func (c *HeadlampConfig) handleNodeDrain(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The drain evicts the pods of the node, which can't be made a dry run.
+	if c.dryRun || ctxtProxy.IsDryRun() {
+		writeDryRunForbidden(w, r, nodeDrainAction)
+
+		return
+	}
+
 	clientset, err := ctxtProxy.ClientSetWithToken(token)
 	if err != nil {
 		c.handleError(w, ctx, span, err, "getting client", http.StatusInternalServerError)
@@ -2259,52 +2717,11 @@ func (c *HeadlampConfig) handleNodeDrain(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	c.drainNode(clientset, drainPayload.NodeName, drainPayload.Cluster)
-}
-
-func (c *HeadlampConfig) drainNode(clientset *kubernetes.Clientset, nodeName string, cluster string) {
-	go func() {
-		nodeClient := clientset.CoreV1().Nodes()
-		ctx := context.Background()
-		cacheKey := uuid.NewSHA1(uuid.Nil, []byte(nodeName+cluster)).String()
-		cacheItemTTL := DrainNodeCacheTTL * time.Minute
-
-		node, err := nodeClient.Get(context.TODO(), nodeName, v1.GetOptions{})
-		if err != nil {
-			_ = c.cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
-			return
-		}
-
-		// cordon the node first
-		node.Spec.Unschedulable = true
-
-		_, err = nodeClient.Update(context.TODO(), node, v1.UpdateOptions{})
-		if err != nil {
-			_ = c.cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
-			return
-		}
+	user := c.eventUser(r)
 
-		pods, err := clientset.CoreV1().Pods("").List(context.TODO(),
-			v1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
-		if err != nil {
-			_ = c.cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
-			return
-		}
-
-		var gracePeriod int64 = 0
-
-		for _, pod := range pods.Items {
-			// ignore daemonsets
-			if pod.ObjectMeta.Labels["kubernetes.io/created-by"] == "daemonset-controller" {
-				continue
-			}
-
-			_ = clientset.CoreV1().Pods(pod.Namespace).Delete(context.TODO(),
-				pod.Name, v1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
-		}
-
-		_ = c.cache.SetWithTTL(ctx, cacheKey, "success", cacheItemTTL)
-	}()
+	c.drainNode(clientset, drainPayload.NodeName, drainPayload.Cluster, drainPayload.drainOptions, func(err error) {
+		c.recordClusterEvent(clientset, ctxtProxy, nodeDrainEvent(drainPayload.NodeName, user, err))
+	})
 }
 
 /*
@@ -2345,7 +2762,7 @@ func (c *HeadlampConfig) handleNodeDrainStatus(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	cacheKey := uuid.NewSHA1(uuid.Nil, []byte(drainPayload.NodeName+drainPayload.Cluster)).String()
+	cacheKey := drainCacheKey(drainPayload.Cluster, drainPayload.NodeName)
 
 	cacheItem, err := c.cache.Get(ctx, cacheKey)
 	if err != nil {
@@ -2355,13 +2772,20 @@ func (c *HeadlampConfig) handleNodeDrainStatus(w http.ResponseWriter, r *http.Re
 	}
 	// Prepare successful response
 	responsePayload := struct {
-		ID      string `json:"id"`
-		Cluster string `json:"cluster"`
+		ID       string         `json:"id"`
+		Cluster  string         `json:"cluster"`
+		Progress *DrainProgress `json:"progress,omitempty"`
 	}{
 		ID:      cacheItem.(string),
 		Cluster: drainPayload.Cluster,
 	}
 
+	if c.multiplexer != nil {
+		if progress, ok := c.multiplexer.drains.get(drainPayload.Cluster, drainPayload.NodeName); ok {
+			responsePayload.Progress = &progress
+		}
+	}
+
 	c.telemetryHandler.RecordEvent(span, "Drain status found", attribute.String("cache.key", cacheKey))
 
 	if err = json.NewEncoder(w).Encode(responsePayload); err != nil {
@@ -2371,7 +2795,8 @@ func (c *HeadlampConfig) handleNodeDrainStatus(w http.ResponseWriter, r *http.Re
 	}
 
 	c.telemetryHandler.RecordDuration(ctx, start, attribute.String("api.route", "handleNodeDrainStatus"))
-	logger.Log(logger.LevelInfo, map[string]string{"duration_ms": fmt.Sprintf("%d", time.Since(start).Milliseconds())},
+	logger.LogCtx(r.Context(), logger.LevelInfo,
+		map[string]string{"duration_ms": fmt.Sprintf("%d", time.Since(start).Milliseconds())},
 		nil, "handleNodeDrainStatus completed")
 }
 
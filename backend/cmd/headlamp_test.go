@@ -37,6 +37,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
@@ -317,6 +318,11 @@ func TestDynamicClustersKubeConfig(t *testing.T) {
 	assert.NotNil(t, minikubeCluster)
 	assert.Equal(t, minikubeName, minikubeCluster.Name)
 	assert.Equal(t, "default", minikubeCluster.Metadata["namespace"])
+
+	extensions, ok := minikubeCluster.Metadata["kubeconfigExtensions"].(map[string]json.RawMessage)
+	require.True(t, ok)
+	assert.Contains(t, extensions, "cluster_info")
+	assert.Contains(t, extensions, "context_info")
 }
 
 func TestInvalidKubeConfig(t *testing.T) {
@@ -558,6 +564,53 @@ func TestDeletePlugin(t *testing.T) {
 	assert.True(t, os.IsNotExist(err))
 }
 
+func TestHeadlessMode(t *testing.T) {
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			UseInCluster:    false,
+			KubeConfigPath:  config.GetDefaultKubeConfigPath(),
+			PluginDir:       t.TempDir(),
+			KubeConfigStore: kubeconfig.NewContextStore(),
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+		headless:         true,
+	}
+
+	handler := createHeadlampHandler(&c)
+
+	rr, err := getResponse(handler, "GET", "/plugins", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr, err = getResponse(handler, "GET", "/config", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestPortForwardsRequiresToken(t *testing.T) {
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			KubeConfigStore: kubeconfig.NewContextStore(),
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+
+	handler := createHeadlampHandler(&c)
+
+	rr, err := getResponse(handler, "GET", "/portforwards", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "GET", "/portforwards", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
+
 func TestHandleClusterAPI_XForwardedHost(t *testing.T) {
 	// Create a new server for testing
 	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -610,6 +663,65 @@ func TestHandleClusterAPI_XForwardedHost(t *testing.T) {
 	assert.Equal(t, "OK", rr.Body.String())
 }
 
+func TestHandleClusterAPITokenOverride(t *testing.T) {
+	proxyServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The override is sent as the credentials, not in its own header.
+		assert.Empty(t, r.Header.Get(auth.TokenOverrideHeader))
+
+		_, err := w.Write([]byte(r.Header.Get("Authorization")))
+		require.NoError(t, err)
+	}))
+	defer proxyServer.Close()
+
+	// The credentials of the contexts are only sent over TLS.
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxyServer.Certificate().Raw})
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{
+		Name:        "test",
+		KubeContext: &api.Context{Cluster: "test", AuthInfo: "test"},
+		Cluster:     &api.Cluster{Server: proxyServer.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{Token: "stored"},
+	}))
+
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			KubeConfigStore: kubeConfigStore,
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+
+	handler := createHeadlampHandler(&c)
+
+	serve := func(tokenOverride string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", "/clusters/test/version", nil)
+		require.NoError(t, err)
+
+		if tokenOverride != "" {
+			req.Header.Set(auth.TokenOverrideHeader, tokenOverride)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve("Bearer elevated")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Bearer elevated", rr.Body.String())
+
+	// The override isn't kept for the next requests.
+	rr = serve("")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Bearer stored", rr.Body.String())
+
+	rr = serve("not a token")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
 // handleClusterRenameRequest handles a cluster rename request.
 func handleClusterRenameRequest(
 	t *testing.T,
@@ -0,0 +1,38 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// warnInsecureContext logs a warning, and records an audit event, the first time the context
+// at key, which skips the TLS verification of its API server, is used.
+func (c *HeadlampConfig) warnInsecureContext(r *http.Request, key string, kContext *kubeconfig.Context) {
+	if _, warned := c.insecureWarned.LoadOrStore(key+"|"+kContext.Cluster.Server, struct{}{}); warned {
+		return
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"cluster": key, "server": kContext.Cluster.Server},
+		nil, "using a cluster skipping TLS verification, its API server could be impersonated")
+
+	c.recordAuditEvent(r, contextAuditEvent(r, audit.VerbUseInsecure, key, kContext, nil))
+}
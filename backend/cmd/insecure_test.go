@@ -0,0 +1,56 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestWarnInsecureContext(t *testing.T) {
+	sink := &memorySink{}
+	c := &HeadlampConfig{auditRecorder: audit.NewRecorderWithSinks(sink)}
+
+	kContext := &kubeconfig.Context{
+		Name:     "minikube",
+		Cluster:  &api.Cluster{Server: "https://127.0.0.1:6443", InsecureSkipTLSVerify: true},
+		Insecure: true,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/version", nil)
+
+	c.warnInsecureContext(req, "minikube", kContext)
+	c.warnInsecureContext(req, "minikube", kContext)
+
+	// Only the first use is warned about.
+	require.Len(t, sink.events, 1)
+	assert.Equal(t, audit.VerbUseInsecure, sink.events[0].Verb)
+	assert.Equal(t, "minikube", sink.events[0].Context)
+
+	// Unless the context points to another server since.
+	kContext.Cluster = &api.Cluster{Server: "https://127.0.0.1:7443", InsecureSkipTLSVerify: true}
+	c.warnInsecureContext(req, "minikube", kContext)
+
+	assert.Len(t, sink.events, 2)
+}
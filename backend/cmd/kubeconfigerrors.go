@@ -0,0 +1,40 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// handleKubeConfigErrors returns the contexts and kubeconfig files that were skipped the
+// last time the kubeconfig files were loaded, with the reason why, so they can be fixed.
+func (c *HeadlampConfig) handleKubeConfigErrors(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(c.KubeConfigStore.LoadErrors()); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding kubeconfig errors")
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleKubeConfigErrors(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-token")
+
+	store := kubeconfig.NewContextStore()
+	store.SetLoadErrors("/kube/config", []kubeconfig.LoadError{{
+		Kind:           kubeconfig.LoadErrorContext,
+		KubeConfigPath: "/kube/config",
+		ContextName:    "broken",
+		Error:          "user nobody not found",
+	}})
+
+	c := &HeadlampConfig{HeadlampCFG: &headlampconfig.HeadlampCFG{KubeConfigStore: store}}
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/kubeconfig-errors", nil)
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", token)
+
+		rr := httptest.NewRecorder()
+		c.handleKubeConfigErrors(rr, req)
+
+		return rr
+	}
+
+	rr := request("backend-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var loadErrors []kubeconfig.LoadError
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &loadErrors))
+	require.Len(t, loadErrors, 1)
+	assert.Equal(t, "broken", loadErrors[0].ContextName)
+
+	assert.Equal(t, http.StatusForbidden, request("wrong").Code)
+}
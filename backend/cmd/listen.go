@@ -0,0 +1,114 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+)
+
+// defaultSocketMode is the permissions of the listen socket if none are given.
+const defaultSocketMode os.FileMode = 0o600
+
+// staleSocketTimeout is how long to wait for a running server on an existing socket.
+const staleSocketTimeout = time.Second
+
+// parseSocketMode parses the octal listen-socket-mode flag.
+func parseSocketMode(mode string) os.FileMode {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return defaultSocketMode
+	}
+
+	return os.FileMode(m)
+}
+
+// listen returns the listener the server accepts connections on: the Unix socket
+// ListenSocket if it is set, else the TCP address ListenAddr:Port.
+func listen(config *headlampconfig.HeadlampCFG) (net.Listener, error) {
+	if config.ListenSocket == "" {
+		return net.Listen("tcp", fmt.Sprintf("%s:%d", config.ListenAddr, config.Port))
+	}
+
+	return listenSocket(config.ListenSocket, config.ListenSocketMode)
+}
+
+// listenSocket listens on the Unix socket at path and sets its permissions to mode.
+// A socket left behind by a previous server is replaced, but not one still in use.
+func listenSocket(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+
+		return nil, fmt.Errorf("setting permissions of %s: %w", path, err)
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket removes the socket at path if no server is listening on it.
+// It returns an error if path is not a socket, or if a server is listening on it.
+func removeStaleSocket(path string) error {
+	stale, err := staleSocket(path)
+	if err != nil || !stale {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// staleSocket tells whether there's a socket at path which no server is listening on.
+// It returns an error if path is not a socket, or if a server is listening on it.
+func staleSocket(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return false, fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleSocketTimeout)
+	if err == nil {
+		conn.Close()
+
+		return false, fmt.Errorf("listening on %s: %w", path, syscall.EADDRINUSE)
+	}
+
+	return true, nil
+}
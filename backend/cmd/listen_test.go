@@ -0,0 +1,88 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketPath returns a socket path short enough for the Unix socket path limit.
+func socketPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "hl")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "headlamp.sock")
+}
+
+func TestListenSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket permissions are not supported on windows")
+	}
+
+	path := socketPath(t)
+
+	listener, err := listenSocket(path, 0o600)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// A socket still in use is not replaced.
+	_, err = listenSocket(path, 0o600)
+	assert.ErrorIs(t, err, syscall.EADDRINUSE)
+
+	// A socket left behind is replaced.
+	unixListener, ok := listener.(*net.UnixListener)
+	require.True(t, ok)
+	unixListener.SetUnlinkOnClose(false)
+	require.NoError(t, listener.Close())
+
+	listener, err = listenSocket(path, 0o660)
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+}
+
+func TestListenSocketNotASocket(t *testing.T) {
+	path := socketPath(t)
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := listenSocket(path, 0o600)
+	assert.ErrorContains(t, err, "is not a socket")
+}
+
+func TestParseSocketMode(t *testing.T) {
+	assert.Equal(t, os.FileMode(0o660), parseSocketMode("0660"))
+	assert.Equal(t, defaultSocketMode, parseSocketMode("invalid"))
+}
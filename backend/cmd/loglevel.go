@@ -0,0 +1,97 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// logLevelRequest is the payload to change the log level.
+type logLevelRequest struct {
+	// Level is the new log level: debug, info, warn or error, optionally followed by
+	// per-component overrides, e.g. "info,proxy=debug".
+	Level string `json:"level"`
+	// Duration, if set, makes the change temporary, e.g. "15m".
+	Duration string `json:"duration,omitempty"`
+}
+
+// logLevelResponse is the current log level.
+type logLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// handleLogLevel returns the log level on GET and changes it on PUT, so debug logging
+// can be enabled without restarting the backend. It requires the backend token.
+func handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		if !setLogLevel(w, r) {
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(logLevelResponse{Level: logger.GetLevel()}); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding log level")
+	}
+}
+
+// setLogLevel changes the log level as given by the request. It writes the error
+// response and returns false if the request is invalid.
+func setLogLevel(w http.ResponseWriter, r *http.Request) bool {
+	var req logLevelRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid log level payload: "+err.Error(), http.StatusBadRequest)
+
+		return false
+	}
+
+	var err error
+
+	if req.Duration == "" {
+		err = logger.SetLevel(req.Level)
+	} else {
+		var d time.Duration
+
+		d, err = time.ParseDuration(req.Duration)
+		if err == nil {
+			err = logger.SetLevelFor(req.Level, d)
+		}
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return false
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelInfo,
+		map[string]string{"level": req.Level, "duration": req.Duration}, nil, "log level changed")
+
+	return true
+}
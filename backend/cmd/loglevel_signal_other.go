@@ -0,0 +1,40 @@
+//go:build !windows
+
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// watchLogLevelSignal toggles debug logging every time the process receives SIGUSR1.
+func watchLogLevelSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		for range signals {
+			level := logger.ToggleDebug()
+			logger.Log(logger.LevelInfo, map[string]string{"level": level}, nil, "log level toggled by SIGUSR1")
+		}
+	}()
+}
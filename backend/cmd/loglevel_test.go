@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleLogLevel(t *testing.T) {
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "test-token")
+	t.Cleanup(func() { _ = logger.SetLevel("info") })
+
+	request := func(method, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/log-level", strings.NewReader(body))
+		req.Header.Set("X-HEADLAMP_BACKEND-TOKEN", token)
+
+		rr := httptest.NewRecorder()
+		handleLogLevel(rr, req)
+
+		return rr
+	}
+
+	// The backend token is required.
+	rr := request(http.MethodPut, `{"level":"debug"}`, "wrong-token")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "info", logger.GetLevel())
+
+	rr = request(http.MethodPut, `{"level":"debug"}`, "test-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp logLevelResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "debug", resp.Level)
+
+	rr = request(http.MethodGet, "", "test-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "debug", resp.Level)
+
+	rr = request(http.MethodPut, `{"level":"loud"}`, "test-token")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = request(http.MethodPut, `{"level":"debug","duration":"soon"}`, "test-token")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = request(http.MethodPut, `{"level":"warn","duration":"1h"}`, "test-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "warn", logger.GetLevel())
+}
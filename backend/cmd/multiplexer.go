@@ -17,12 +17,18 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"sync"
 	"time"
 
@@ -30,6 +36,9 @@ import (
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"k8s.io/client-go/rest"
 )
 
@@ -49,10 +58,25 @@ const (
 	HeartbeatInterval = 30 * time.Second
 	// HandshakeTimeout is the timeout for the handshake with the client.
 	HandshakeTimeout = 45 * time.Second
+	// ClientWriteTimeout is the time a write to a client may take before the client is
+	// dropped, so that a client not reading doesn't hold up the other subscribers.
+	ClientWriteTimeout = 10 * time.Second
 	// CleanupRoutineInterval is the interval at which the multiplexer cleans up unused connections.
 	CleanupRoutineInterval = 5 * time.Minute
+	// DefaultIdleTimeout is the time a client WebSocket may stay silent, pongs included,
+	// before the multiplexer drops it.
+	DefaultIdleTimeout = 90 * time.Second
+	// DefaultResumeWindow is the time cluster connections are kept after their client
+	// disconnects, so that a reconnecting client can resume them.
+	DefaultResumeWindow = 2 * time.Minute
+	// HistorySize is the number of recent messages kept per connection to replay on resume.
+	HistorySize = 256
 )
 
+// ErrResumeForbidden is returned when a client resumes a connection with another token than
+// the one the connection was established with.
+var ErrResumeForbidden = errors.New("the connection to resume was established with another token")
+
 // ConnectionState represents the current state of a connection.
 type ConnectionState string
 
@@ -71,6 +95,9 @@ type Connection struct {
 	ClusterID string
 	// UserID is the ID of the user.
 	UserID string
+	// clientID is the ID of the client which opened the connection, which it counts against
+	// in the subscription limit.
+	clientID string
 	// Path is the path of the connection.
 	Path string
 	// Query is the query of the connection.
@@ -79,8 +106,9 @@ type Connection struct {
 	WSConn *websocket.Conn
 	// Status is the status of the connection.
 	Status ConnectionStatus
-	// Client is the WebSocket connection to the client.
-	Client *WSConnLock
+	// subscribers are the clients the messages of the connection are fanned out to, e.g. the
+	// browser tabs of a user watching the same resource. It is protected by mu and writeMu.
+	subscribers []subscriber
 	// Done is a channel to signal when the connection is done.
 	Done chan struct{}
 	// mu is a mutex to synchronize access to the connection.
@@ -91,6 +119,18 @@ type Connection struct {
 	closed bool
 	// Authentication token.
 	Token *string
+	// seq is the sequence number of the last DATA message of the connection.
+	// It is protected by writeMu.
+	seq uint64
+	// history holds the most recent DATA messages, to replay them when a client resumes.
+	// It is protected by writeMu.
+	history []Message
+	// detachTimer closes the connection if no client resumes it after its client disconnected.
+	detachTimer *time.Timer
+	// ctx is cancelled when the connection is closed, cancelling its dialing to the cluster.
+	ctx context.Context
+	// cancel cancels ctx.
+	cancel context.CancelFunc
 }
 
 // Message represents a WebSocket message structure.
@@ -109,6 +149,9 @@ type Message struct {
 	Binary bool `json:"binary,omitempty"`
 	// Type is the type of the message.
 	Type string `json:"type"`
+	// Seq is the sequence number of a DATA message within its connection.
+	// In a RESUME message it is the sequence number of the last message seen by the client.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 // Multiplexer manages multiple WebSocket connections.
@@ -121,6 +164,31 @@ type Multiplexer struct {
 	upgrader websocket.Upgrader
 	// kubeConfigStore is the kubeconfig store.
 	kubeConfigStore kubeconfig.ContextStore
+	// idleTimeout is the time a client may stay silent before it is dropped.
+	// Zero disables the timeout.
+	idleTimeout time.Duration
+	// resumeWindow is the time connections of a disconnected client are kept for it to resume.
+	resumeWindow time.Duration
+	// limits caps the connections and subscriptions of each client.
+	limits clientLimits
+	// metrics records the multiplexer metrics. It is nil when metrics are not set up.
+	metrics *telemetry.Metrics
+	// drains streams the progress of the node drains to the clients subscribed to them.
+	drains *drainTracker
+	// contexts sends the changes of the contexts to the clients subscribed to them.
+	contexts *contextNotifier
+	// clients are the connected WebSocket clients, closed on shutdown.
+	clients clientRegistry
+}
+
+// StreamClient is the client side of a multiplexed stream. The WebSocket and the
+// Server-Sent Events transports both implement it, so messages from the cluster are
+// delivered to the client using the same Message protocol regardless of transport.
+type StreamClient interface {
+	// WriteJSON writes the JSON encoding of v to the client.
+	WriteJSON(v interface{}) error
+	// Close closes the client connection.
+	Close() error
 }
 
 // WSConnLock provides a thread-safe wrapper around a WebSocket connection.
@@ -144,11 +212,16 @@ func NewWSConnLock(conn *websocket.Conn) *WSConnLock {
 }
 
 // WriteJSON writes the JSON encoding of v as a message to the WebSocket connection.
-// It ensures thread-safety by using a mutex lock during the write operation.
+// It ensures thread-safety by using a mutex lock during the write operation, and fails
+// if the write takes longer than ClientWriteTimeout.
 func (conn *WSConnLock) WriteJSON(v interface{}) error {
 	conn.writeMu.Lock()
 	defer conn.writeMu.Unlock()
 
+	if err := conn.conn.SetWriteDeadline(time.Now().Add(ClientWriteTimeout)); err != nil {
+		return err
+	}
+
 	return conn.conn.WriteJSON(v)
 }
 
@@ -167,11 +240,16 @@ func (conn *WSConnLock) ReadMessage() (messageType int, p []byte, err error) {
 }
 
 // WriteMessage writes a message to the WebSocket connection with the given type and payload.
-// It ensures thread-safety by using a mutex lock during the write operation.
+// It ensures thread-safety by using a mutex lock during the write operation, and fails
+// if the write takes longer than ClientWriteTimeout.
 func (conn *WSConnLock) WriteMessage(messageType int, data []byte) error {
 	conn.writeMu.Lock()
 	defer conn.writeMu.Unlock()
 
+	if err := conn.conn.SetWriteDeadline(time.Now().Add(ClientWriteTimeout)); err != nil {
+		return err
+	}
+
 	return conn.conn.WriteMessage(messageType, data)
 }
 
@@ -190,6 +268,14 @@ func NewMultiplexer(kubeConfigStore kubeconfig.ContextStore) *Multiplexer {
 	return &Multiplexer{
 		connections:     make(map[string]*Connection),
 		kubeConfigStore: kubeConfigStore,
+		idleTimeout:     DefaultIdleTimeout,
+		resumeWindow:    DefaultResumeWindow,
+		limits: clientLimits{
+			connections: make(map[string]int),
+			clients:     make(map[StreamClient]string),
+		},
+		drains:   newDrainTracker(),
+		contexts: newContextNotifier(kubeConfigStore),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true
@@ -215,7 +301,7 @@ func (c *Connection) updateStatus(state ConnectionState, err error) {
 		c.Status.Error = err.Error()
 	}
 
-	if c.Client == nil {
+	if len(c.subscribers) == 0 {
 		return
 	}
 
@@ -227,35 +313,44 @@ func (c *Connection) updateStatus(state ConnectionState, err error) {
 		return
 	}
 
+	statusMsg, jsonErr := c.statusMessage()
+	if jsonErr != nil {
+		logger.Log(logger.LevelError, map[string]string{"clusterID": c.ClusterID}, jsonErr, "marshaling status message")
+
+		return
+	}
+
+	if err := c.broadcast(statusMsg); err != nil {
+		if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			logger.Log(logger.LevelError, map[string]string{"clusterID": c.ClusterID}, err, "writing status message to client")
+		}
+
+		c.closed = true
+	}
+}
+
+// statusMessage returns the STATUS message of the current status of the connection.
+// The caller holds mu.
+func (c *Connection) statusMessage() (Message, error) {
 	statusData := struct {
 		State string `json:"state"`
 		Error string `json:"error"`
 	}{
-		State: string(state),
+		State: string(c.Status.State),
 		Error: c.Status.Error,
 	}
 
-	jsonData, jsonErr := json.Marshal(statusData)
-	if jsonErr != nil {
-		logger.Log(logger.LevelError, map[string]string{"clusterID": c.ClusterID}, jsonErr, "marshaling status message")
-
-		return
+	jsonData, err := json.Marshal(statusData)
+	if err != nil {
+		return Message{}, err
 	}
 
-	statusMsg := Message{
+	return Message{
 		ClusterID: c.ClusterID,
 		Path:      c.Path,
 		Data:      string(jsonData),
 		Type:      "STATUS",
-	}
-
-	if err := c.Client.WriteJSON(statusMsg); err != nil {
-		if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-			logger.Log(logger.LevelError, map[string]string{"clusterID": c.ClusterID}, err, "writing status message to client")
-		}
-
-		c.closed = true
-	}
+	}, nil
 }
 
 // establishClusterConnection creates a new WebSocket connection to a Kubernetes cluster.
@@ -264,18 +359,34 @@ func (m *Multiplexer) establishClusterConnection(
 	userID,
 	path,
 	query string,
-	clientConn *WSConnLock,
+	clientConn StreamClient,
 	token *string,
 ) (*Connection, error) {
-	config, err := m.getClusterConfigWithFallback(clusterID, userID)
+	connection, err := m.dialClusterConnection(
+		m.createConnection(clusterID, userID, path, query, clientConn, token))
 	if err != nil {
-		logger.Log(logger.LevelError, map[string]string{"clusterID": clusterID}, err, "getting cluster config")
 		return nil, err
 	}
 
-	connection := m.createConnection(clusterID, userID, path, query, clientConn, token)
+	m.mutex.Lock()
+	connKey := m.createConnectionKey(clusterID, path, userID, query, token)
+	m.connections[connKey] = connection
+	m.mutex.Unlock()
+
+	go m.monitorConnection(connection)
 
-	wsURL := createWebSocketURL(config.Host, path, query)
+	return connection, nil
+}
+
+// dialClusterConnection opens the WebSocket of a new connection to its cluster.
+func (m *Multiplexer) dialClusterConnection(connection *Connection) (*Connection, error) {
+	config, err := m.getClusterConfigWithFallback(connection.ClusterID, connection.UserID)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"clusterID": connection.ClusterID}, err, "getting cluster config")
+		return nil, err
+	}
+
+	wsURL := createWebSocketURL(config.Host, connection.Path, connection.Query)
 
 	tlsConfig, err := rest.TLSConfigFor(config)
 	if err != nil {
@@ -284,7 +395,7 @@ func (m *Multiplexer) establishClusterConnection(
 		return nil, fmt.Errorf("failed to get TLS config: %v", err)
 	}
 
-	conn, err := m.dialWebSocket(wsURL, tlsConfig, config.Host, token)
+	conn, err := m.dialWebSocket(connection.ctx, wsURL, tlsConfig, config.Host, connection.Token)
 	if err != nil {
 		connection.updateStatus(StateError, err)
 
@@ -294,13 +405,6 @@ func (m *Multiplexer) establishClusterConnection(
 	connection.WSConn = conn
 	connection.updateStatus(StateConnected, nil)
 
-	m.mutex.Lock()
-	connKey := m.createConnectionKey(clusterID, path, userID)
-	m.connections[connKey] = connection
-	m.mutex.Unlock()
-
-	go m.monitorConnection(connection)
-
 	return connection, nil
 }
 
@@ -315,7 +419,7 @@ func (m *Multiplexer) getClusterConfigWithFallback(clusterID, userID string) (*r
 
 		config, err = m.getClusterConfig(combinedKey)
 		if err != nil {
-			return nil, fmt.Errorf("getting cluster config: %v", err)
+			return nil, fmt.Errorf("getting cluster config: %w", err)
 		}
 	}
 
@@ -328,26 +432,37 @@ func (m *Multiplexer) createConnection(
 	userID,
 	path,
 	query string,
-	clientConn *WSConnLock,
+	clientConn StreamClient,
 	token *string,
 ) *Connection {
+	var subscribers []subscriber
+	if clientConn != nil {
+		subscribers = []subscriber{{client: clientConn, query: query}}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Connection{
-		ClusterID: clusterID,
-		UserID:    userID,
-		Path:      path,
-		Query:     query,
-		Client:    clientConn,
-		Done:      make(chan struct{}),
+		ClusterID:   clusterID,
+		UserID:      userID,
+		clientID:    m.clientIDOf(clientConn),
+		Path:        path,
+		Query:       query,
+		subscribers: subscribers,
+		Done:        make(chan struct{}),
 		Status: ConnectionStatus{
 			State:   StateConnecting,
 			LastMsg: time.Now(),
 		},
-		Token: token,
+		Token:  token,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
-// dialWebSocket establishes a WebSocket connection.
+// dialWebSocket establishes a WebSocket connection, unless ctx is cancelled first.
 func (m *Multiplexer) dialWebSocket(
+	ctx context.Context,
 	wsURL string,
 	tlsConfig *tls.Config,
 	host string,
@@ -366,7 +481,8 @@ func (m *Multiplexer) dialWebSocket(
 		headers.Set("Authorization", "Bearer "+*token)
 	}
 
-	conn, resp, err := dialer.Dial(
+	conn, resp, err := dialer.DialContext(
+		ctx,
 		wsURL,
 		headers,
 	)
@@ -421,23 +537,29 @@ func (m *Multiplexer) reconnect(conn *Connection) (*Connection, error) {
 		conn.WSConn.Close()
 	}
 
-	newConn, err := m.establishClusterConnection(
-		conn.ClusterID,
-		conn.UserID,
-		conn.Path,
-		conn.Query,
-		conn.Client,
-		conn.Token,
-	)
+	newConn := m.createConnection(conn.ClusterID, conn.UserID, conn.Path, conn.Query, nil, conn.Token)
+
+	// The new connection takes over the subscribers of the old one.
+	conn.mu.RLock()
+	newConn.subscribers = slices.Clone(conn.subscribers)
+	conn.mu.RUnlock()
+
+	// Closing the connection while it reconnects cancels the dialing.
+	if conn.ctx != nil {
+		stop := context.AfterFunc(conn.ctx, newConn.cancel)
+		defer stop()
+	}
+
+	newConn, err := m.dialClusterConnection(newConn)
 	if err != nil {
 		logger.Log(logger.LevelError, map[string]string{"clusterID": conn.ClusterID}, err, "reconnecting to cluster")
 
 		return nil, err
 	}
 
-	m.mutex.Lock()
-	m.connections[m.createConnectionKey(conn.ClusterID, conn.Path, conn.UserID)] = newConn
-	m.mutex.Unlock()
+	m.replaceConnection(conn, newConn)
+
+	go m.monitorConnection(newConn)
 
 	return newConn, nil
 }
@@ -446,7 +568,7 @@ func (m *Multiplexer) reconnect(conn *Connection) (*Connection, error) {
 func (m *Multiplexer) HandleClientWebSocket(w http.ResponseWriter, r *http.Request) {
 	clientConn, err := m.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		logger.Log(logger.LevelError, nil, err, "upgrading connection")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "upgrading connection")
 		return
 	}
 
@@ -454,15 +576,59 @@ func (m *Multiplexer) HandleClientWebSocket(w http.ResponseWriter, r *http.Reque
 
 	lockClientConn := NewWSConnLock(clientConn)
 
+	clientID := clientIDForRequest(r)
+	if err := m.acquireClientConnection(clientID); err != nil {
+		var limitErr *LimitExceededError
+		if errors.As(err, &limitErr) {
+			m.rejectClientConnection(lockClientConn, limitErr)
+		}
+
+		return
+	}
+
+	defer m.releaseClientConnection(clientID)
+
+	m.registerClient(lockClientConn, clientID)
+	defer m.unregisterClient(lockClientConn)
+
+	if !m.clients.track(lockClientConn) {
+		writeRestartClose(lockClientConn)
+
+		return
+	}
+
+	defer m.clients.untrack(lockClientConn)
+
+	m.extendIdleDeadline(clientConn)
+	clientConn.SetPongHandler(func(string) error {
+		m.extendIdleDeadline(clientConn)
+
+		return nil
+	})
+
+	stopPing := make(chan struct{})
+	defer close(stopPing)
+
+	go m.pingClient(lockClientConn, stopPing)
+
 	for {
 		msg, err := m.readClientMessage(clientConn)
 		if err != nil {
 			break
 		}
 
+		m.extendIdleDeadline(clientConn)
+
 		// Check if it's a close message
 		if msg.Type == "CLOSE" {
-			m.CloseConnection(msg.ClusterID, msg.Path, msg.UserID)
+			m.unsubscribe(msg, lockClientConn)
+
+			continue
+		}
+
+		// Subscribe to the changes of the contexts visible to the user.
+		if msg.Type == "CONTEXTS" {
+			m.contexts.subscribe(msg.UserID, lockClientConn)
 
 			continue
 		}
@@ -472,6 +638,21 @@ func (m *Multiplexer) HandleClientWebSocket(w http.ResponseWriter, r *http.Reque
 			break
 		}
 
+		if msg.Type == "RESUME" {
+			if err := m.resumeConnection(msg, lockClientConn, &token); err != nil {
+				m.handleConnectionError(lockClientConn, msg, err)
+			}
+
+			continue
+		}
+
+		// Subscribe to the progress of the drain of the node in the path.
+		if msg.Type == "DRAIN" {
+			m.drains.subscribe(msg.ClusterID, msg.Path, lockClientConn)
+
+			continue
+		}
+
 		conn, err := m.getOrCreateConnection(msg, lockClientConn, &token)
 		if err != nil {
 			m.handleConnectionError(lockClientConn, msg, err)
@@ -487,7 +668,178 @@ func (m *Multiplexer) HandleClientWebSocket(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	m.cleanupConnections()
+	m.drains.unsubscribe(lockClientConn)
+	m.contexts.unsubscribe(lockClientConn)
+	m.detachClient(lockClientConn)
+}
+
+// extendIdleDeadline pushes the read deadline of a client connection idleTimeout into the future.
+func (m *Multiplexer) extendIdleDeadline(clientConn *websocket.Conn) {
+	if m.idleTimeout <= 0 {
+		return
+	}
+
+	if err := clientConn.SetReadDeadline(time.Now().Add(m.idleTimeout)); err != nil {
+		logger.Log(logger.LevelError, nil, err, "setting client read deadline")
+	}
+}
+
+// pingClient sends a ping to the client every HeartbeatInterval until stop is closed.
+// The client's pongs keep the connection from hitting the idle timeout.
+func (m *Multiplexer) pingClient(clientConn *WSConnLock, stop <-chan struct{}) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := clientConn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// detachClient detaches a disconnected client from its connections. The connections left
+// without subscribers keep reading from the cluster into their history, and are closed if
+// no client resumes them within the resume window.
+func (m *Multiplexer) detachClient(client StreamClient) {
+	m.mutex.RLock()
+
+	detached := make([]*Connection, 0)
+
+	for _, conn := range m.connections {
+		if conn.unsubscribe(client) && conn.isDetached() {
+			detached = append(detached, conn)
+		}
+	}
+
+	m.mutex.RUnlock()
+
+	for _, conn := range detached {
+		if m.resumeWindow <= 0 {
+			m.closeConnection(conn)
+
+			continue
+		}
+
+		conn.mu.Lock()
+		conn.detachTimer = time.AfterFunc(m.resumeWindow, func() {
+			m.mutex.RLock()
+			current := m.isTracked(conn)
+			m.mutex.RUnlock()
+
+			// The connection may have been resumed or replaced in the meantime.
+			if current && conn.isDetached() {
+				m.closeConnection(conn)
+			}
+		})
+		conn.mu.Unlock()
+	}
+}
+
+// resumeConnection attaches a reconnecting client to the connection it was streaming and
+// replays the messages it missed, i.e. those with a sequence number after msg.Seq.
+// If the connection is gone, or the missed messages are no longer in its history, the client
+// is sent a RESYNC message, so it can discard its state, and a new connection is established.
+func (m *Multiplexer) resumeConnection(msg Message, clientConn StreamClient, token *string) error {
+	connKey := m.createConnectionKey(msg.ClusterID, msg.Path, msg.UserID, msg.Query, token)
+
+	m.mutex.RLock()
+	conn, exists := m.connections[connKey]
+	m.mutex.RUnlock()
+
+	if exists {
+		resumed, err := conn.reattach(clientConn, msg.Query, token, msg.Seq)
+		if err != nil || resumed {
+			return err
+		}
+
+		m.closeConnection(conn)
+	}
+
+	resyncMsg := Message{
+		ClusterID: msg.ClusterID,
+		Path:      msg.Path,
+		Query:     msg.Query,
+		UserID:    msg.UserID,
+		Type:      "RESYNC",
+	}
+
+	if err := clientConn.WriteJSON(resyncMsg); err != nil {
+		return err
+	}
+
+	_, err := m.getOrCreateConnection(msg, clientConn, token)
+
+	return err
+}
+
+// reattach attaches client to the connection and replays the messages after lastSeq.
+// It returns false, leaving the connection untouched, if the connection is closed or
+// if some of those messages are no longer in its history. It returns ErrResumeForbidden if
+// the token isn't the one of the connection, as the messages were fetched with it.
+func (c *Connection) reattach(client StreamClient, query string, token *string, lastSeq uint64) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if !sameToken(c.Token, token) {
+		return false, ErrResumeForbidden
+	}
+
+	if c.closed || lastSeq > c.seq {
+		return false, nil
+	}
+
+	// The oldest message still in the history must directly follow the last seen one.
+	if lastSeq < c.seq && (len(c.history) == 0 || c.history[0].Seq > lastSeq+1) {
+		return false, nil
+	}
+
+	if c.detachTimer != nil {
+		c.detachTimer.Stop()
+		c.detachTimer = nil
+	}
+
+	sub := subscriber{client: client, query: query}
+	if !c.isSubscribed(client) {
+		c.subscribers = append(c.subscribers, sub)
+	}
+
+	for _, msg := range c.history {
+		if msg.Seq <= lastSeq {
+			continue
+		}
+
+		if err := sub.write(msg); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"clusterID": c.ClusterID}, err, "replaying message to client")
+
+			break
+		}
+	}
+
+	return true, nil
+}
+
+// sameToken tells whether two tokens are the same, a missing token being the same as an
+// empty one.
+func sameToken(a, b *string) bool {
+	var tokenA, tokenB string
+
+	if a != nil {
+		tokenA = *a
+	}
+
+	if b != nil {
+		tokenB = *b
+	}
+
+	return subtle.ConstantTimeCompare([]byte(tokenA), []byte(tokenB)) == 1
 }
 
 // readClientMessage reads a message from the client WebSocket connection.
@@ -512,15 +864,38 @@ func (m *Multiplexer) readClientMessage(clientConn *websocket.Conn) (Message, er
 }
 
 // getOrCreateConnection gets an existing connection or creates a new one if it doesn't exist.
-// If a connection exists and a new token is provided, it updates the token to ensure it's fresh.
-func (m *Multiplexer) getOrCreateConnection(msg Message, clientConn *WSConnLock, token *string) (*Connection, error) {
-	connKey := m.createConnectionKey(msg.ClusterID, msg.Path, msg.UserID)
+// The client is subscribed to an existing connection, sharing its cluster WebSocket with the
+// other subscribers, if the connection can replay the events the client missed.
+// The connections are only shared by the clients with the same token, as the events are
+// fetched with it, so a client with a new token gets a connection of its own.
+func (m *Multiplexer) getOrCreateConnection(msg Message, clientConn StreamClient, token *string) (*Connection, error) {
+	connKey := m.createConnectionKey(msg.ClusterID, msg.Path, msg.UserID, msg.Query, token)
 
 	m.mutex.RLock()
 	conn, exists := m.connections[connKey]
 	m.mutex.RUnlock()
 
+	// A detached connection is left for its client to resume. A new request for the same
+	// resource starts over with a fresh connection.
+	if exists && conn.isDetached() {
+		m.closeConnection(conn)
+
+		exists = false
+	}
+
+	// A connection that can't serve the client keeps serving its subscribers, and the
+	// client gets a connection of its own.
+	if exists && !conn.subscribe(clientConn, msg.Query) {
+		m.setAside(connKey, conn)
+
+		exists = false
+	}
+
 	if !exists {
+		if err := m.checkSubscriptionLimit(m.clientIDOf(clientConn)); err != nil {
+			return nil, err
+		}
+
 		var err error
 
 		conn, err = m.establishClusterConnection(msg.ClusterID, msg.UserID, msg.Path, msg.Query, clientConn, token)
@@ -535,28 +910,37 @@ func (m *Multiplexer) getOrCreateConnection(msg Message, clientConn *WSConnLock,
 			return nil, err
 		}
 
-		go m.handleClusterMessages(conn, clientConn)
-	} else if token != nil {
-		// Check if the token is different before updating
-		conn.mu.Lock()
-		if conn.Token == nil || *conn.Token != *token {
-			// Update the token only if it's new
-			conn.Token = token
-		}
-		conn.mu.Unlock()
+		go m.handleClusterMessages(conn)
 	}
 
 	return conn, nil
 }
 
+// isDetached returns whether the connection has lost its client.
+func (c *Connection) isDetached() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.subscribers) == 0
+}
+
 // handleConnectionError handles errors that occur when establishing a connection.
-func (m *Multiplexer) handleConnectionError(clientConn *WSConnLock, msg Message, err error) {
+func (m *Multiplexer) handleConnectionError(clientConn StreamClient, msg Message, err error) {
+	var limitErr *LimitExceededError
+	if errors.As(err, &limitErr) {
+		m.writeLimitError(clientConn, msg, limitErr)
+
+		return
+	}
+
 	errorMsg := struct {
-		ClusterID string `json:"clusterId"`
-		Error     string `json:"error"`
+		ClusterID string               `json:"clusterId"`
+		Error     string               `json:"error"`
+		Code      kubeconfig.ErrorCode `json:"code,omitempty"`
 	}{
 		ClusterID: msg.ClusterID,
 		Error:     err.Error(),
+		Code:      kubeconfig.Code(err),
 	}
 
 	if err = clientConn.WriteJSON(errorMsg); err != nil {
@@ -590,8 +974,9 @@ func (m *Multiplexer) writeMessageToCluster(conn *Connection, data []byte) error
 }
 
 // handleClusterMessages handles messages from a cluster connection.
-func (m *Multiplexer) handleClusterMessages(conn *Connection, clientConn *WSConnLock) {
+func (m *Multiplexer) handleClusterMessages(conn *Connection) {
 	defer m.cleanupConnection(conn)
+	defer m.recordWatchOutcome(conn)
 
 	var lastResourceVersion string
 
@@ -600,7 +985,7 @@ func (m *Multiplexer) handleClusterMessages(conn *Connection, clientConn *WSConn
 		case <-conn.Done:
 			return
 		default:
-			if err := m.processClusterMessage(conn, clientConn, &lastResourceVersion); err != nil {
+			if err := m.processClusterMessage(conn, &lastResourceVersion); err != nil {
 				return
 			}
 		}
@@ -608,11 +993,7 @@ func (m *Multiplexer) handleClusterMessages(conn *Connection, clientConn *WSConn
 }
 
 // processClusterMessage processes a single message from the cluster.
-func (m *Multiplexer) processClusterMessage(
-	conn *Connection,
-	clientConn *WSConnLock,
-	lastResourceVersion *string,
-) error {
+func (m *Multiplexer) processClusterMessage(conn *Connection, lastResourceVersion *string) error {
 	messageType, message, err := conn.WSConn.ReadMessage()
 	if err != nil {
 		if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
@@ -629,11 +1010,11 @@ func (m *Multiplexer) processClusterMessage(
 		return err
 	}
 
-	if err := m.sendIfNewResourceVersion(message, conn, clientConn, lastResourceVersion); err != nil {
+	if err := m.sendIfNewResourceVersion(message, conn, lastResourceVersion); err != nil {
 		return err
 	}
 
-	return m.sendDataMessage(conn, clientConn, messageType, message)
+	return m.sendDataMessage(conn, messageType, message)
 }
 
 // sendIfNewResourceVersion checks the version of a resource from an incoming message
@@ -646,7 +1027,6 @@ func (m *Multiplexer) processClusterMessage(
 // Parameters:
 //   - message: The JSON-encoded message containing resource information.
 //   - conn: The connection object representing the current connection.
-//   - clientConn: The WebSocket connection to the client.
 //   - lastResourceVersion: A pointer to the last known resource version string.
 //
 // Returns:
@@ -654,7 +1034,6 @@ func (m *Multiplexer) processClusterMessage(
 func (m *Multiplexer) sendIfNewResourceVersion(
 	message []byte,
 	conn *Connection,
-	clientConn *WSConnLock,
 	lastResourceVersion *string,
 ) error {
 	var obj map[string]interface{}
@@ -687,14 +1066,14 @@ func (m *Multiplexer) sendIfNewResourceVersion(
 	if rv != *lastResourceVersion {
 		*lastResourceVersion = rv
 
-		return m.sendCompleteMessage(conn, clientConn)
+		return m.sendCompleteMessage(conn)
 	}
 
 	return nil
 }
 
 // sendCompleteMessage sends a COMPLETE message to the client.
-func (m *Multiplexer) sendCompleteMessage(conn *Connection, clientConn *WSConnLock) error {
+func (m *Multiplexer) sendCompleteMessage(conn *Connection) error {
 	conn.mu.RLock()
 	if conn.closed {
 		conn.mu.RUnlock()
@@ -714,7 +1093,12 @@ func (m *Multiplexer) sendCompleteMessage(conn *Connection, clientConn *WSConnLo
 	conn.writeMu.Lock()
 	defer conn.writeMu.Unlock()
 
-	err := clientConn.WriteJSON(completeMsg)
+	// The client is detached, it resumes from the DATA messages in the history.
+	if len(conn.subscribers) == 0 {
+		return nil
+	}
+
+	err := conn.broadcast(completeMsg)
 	if err != nil {
 		logger.Log(logger.LevelInfo, nil, err, "connection closed while writing complete message")
 
@@ -724,22 +1108,31 @@ func (m *Multiplexer) sendCompleteMessage(conn *Connection, clientConn *WSConnLo
 	return nil
 }
 
-// sendDataMessage sends the actual data message to the client.
-func (m *Multiplexer) sendDataMessage(
-	conn *Connection,
-	clientConn *WSConnLock,
-	messageType int,
-	message []byte,
-) error {
+// sendDataMessage sends the actual data message to the client. The message is numbered and
+// kept in the connection history, so it can be replayed if the client resumes the connection.
+func (m *Multiplexer) sendDataMessage(conn *Connection, messageType int, message []byte) error {
 	dataMsg := m.createWrapperMessage(conn, messageType, message)
 
 	conn.writeMu.Lock()
-	defer conn.writeMu.Unlock()
 
-	if err := clientConn.WriteJSON(dataMsg); err != nil {
-		return err
+	conn.seq++
+	dataMsg.Seq = conn.seq
+
+	conn.history = append(conn.history, dataMsg)
+	if len(conn.history) > HistorySize {
+		conn.history = conn.history[len(conn.history)-HistorySize:]
 	}
 
+	if len(conn.subscribers) > 0 {
+		if err := conn.broadcast(dataMsg); err != nil {
+			conn.writeMu.Unlock()
+
+			return err
+		}
+	}
+
+	conn.writeMu.Unlock()
+
 	conn.mu.Lock()
 	conn.Status.LastMsg = time.Now()
 	conn.mu.Unlock()
@@ -747,20 +1140,41 @@ func (m *Multiplexer) sendDataMessage(
 	return nil
 }
 
+// recordWatchOutcome counts the watch of a connection that stopped reading from its cluster as
+// cancelled if the connection was closed, e.g. by its client, or completed if the cluster ended it.
+func (m *Multiplexer) recordWatchOutcome(conn *Connection) {
+	if m.metrics == nil || conn.ctx == nil {
+		return
+	}
+
+	m.metrics.UpstreamRequests.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("kind", "watch"),
+		attribute.String("outcome", telemetry.Outcome(conn.ctx)),
+	))
+}
+
+// cancelUpstream cancels the context of the connection, so its dialing to the cluster stops.
+func (c *Connection) cancelUpstream() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
 // cleanupConnection performs cleanup for a connection.
 func (m *Multiplexer) cleanupConnection(conn *Connection) {
 	conn.mu.Lock()
 	defer conn.mu.Unlock() // Ensure the mutex is unlocked even if an error occurs
 
 	conn.closed = true
+	conn.cancelUpstream()
 
 	if conn.WSConn != nil {
 		conn.WSConn.Close()
 	}
 
+	// The key may already belong to a newer connection for the same resource.
 	m.mutex.Lock()
-	connKey := m.createConnectionKey(conn.ClusterID, conn.Path, conn.UserID)
-	delete(m.connections, connKey)
+	m.untrack(conn)
 	m.mutex.Unlock()
 }
 
@@ -791,6 +1205,7 @@ func (m *Multiplexer) cleanupConnections() {
 
 	for key, conn := range m.connections {
 		conn.updateStatus(StateClosed, nil)
+		conn.cancelUpstream()
 		close(conn.Done)
 
 		if conn.WSConn != nil {
@@ -805,7 +1220,7 @@ func (m *Multiplexer) cleanupConnections() {
 func (m *Multiplexer) getClusterConfig(clusterID string) (*rest.Config, error) {
 	ctxtProxy, err := m.kubeConfigStore.GetContext(clusterID)
 	if err != nil {
-		return nil, fmt.Errorf("getting context: %v", err)
+		return nil, fmt.Errorf("getting context: %w", err)
 	}
 
 	clientConfig, err := ctxtProxy.RESTConfig()
@@ -816,14 +1231,30 @@ func (m *Multiplexer) getClusterConfig(clusterID string) (*rest.Config, error) {
 	return clientConfig, nil
 }
 
-// CloseConnection closes a specific connection based on its identifier.
+// CloseConnection closes the connections of the user to the resource at path, whatever their query.
 func (m *Multiplexer) CloseConnection(clusterID, path, userID string) {
-	connKey := m.createConnectionKey(clusterID, path, userID)
+	m.mutex.RLock()
+
+	var matching []*Connection
 
+	for _, conn := range m.connections {
+		if conn.ClusterID == clusterID && conn.Path == path && conn.UserID == userID {
+			matching = append(matching, conn)
+		}
+	}
+
+	m.mutex.RUnlock()
+
+	for _, conn := range matching {
+		m.closeConnection(conn)
+	}
+}
+
+// closeConnection closes a connection and stops tracking it.
+func (m *Multiplexer) closeConnection(conn *Connection) {
 	m.mutex.Lock()
 
-	conn, exists := m.connections[connKey]
-	if !exists {
+	if !m.isTracked(conn) {
 		m.mutex.Unlock()
 		// Don't log error for non-existent connections during cleanup
 		return
@@ -842,7 +1273,7 @@ func (m *Multiplexer) CloseConnection(clusterID, path, userID string) {
 	conn.closed = true
 	conn.mu.Unlock()
 
-	delete(m.connections, connKey)
+	m.untrack(conn)
 	m.mutex.Unlock()
 
 	// Lock the connection mutex before accessing shared resources
@@ -850,6 +1281,7 @@ func (m *Multiplexer) CloseConnection(clusterID, path, userID string) {
 	defer conn.mu.Unlock() // Ensure the mutex is unlocked after the operations
 
 	// Close the Done channel and connections after removing from map
+	conn.cancelUpstream()
 	close(conn.Done)
 
 	if conn.WSConn != nil {
@@ -857,9 +1289,23 @@ func (m *Multiplexer) CloseConnection(clusterID, path, userID string) {
 	}
 }
 
-// createConnectionKey creates a unique key for a connection based on cluster ID, path, and user ID.
-func (m *Multiplexer) createConnectionKey(clusterID, path, userID string) string {
-	return fmt.Sprintf("%s:%s:%s", clusterID, path, userID)
+// createConnectionKey creates a unique key for a connection based on cluster ID, path, user ID,
+// query and token. The resource version is left out of the query, the subscribers watching from
+// different versions sharing the connection. The user ID is chosen by the client, so the token is
+// in the key, hashed, for the clients of other users not to share the connection.
+func (m *Multiplexer) createConnectionKey(clusterID, path, userID, query string, token *string) string {
+	key := fmt.Sprintf("%s:%s:%s", clusterID, path, userID)
+
+	if token != nil && *token != "" {
+		sum := sha256.Sum256([]byte(*token))
+		key += "@" + hex.EncodeToString(sum[:])
+	}
+
+	if shared := sharedQuery(query); shared != "" {
+		key += "?" + shared
+	}
+
+	return key
 }
 
 // createWebSocketURL creates a WebSocket URL from the given parameters.
@@ -0,0 +1,146 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// ContextNotification is the data of the CONTEXT messages telling the multiplexer clients that
+// subscribed with a CONTEXTS message about the changes of the contexts, so they can refresh
+// their cluster list or prompt for the renewal of an expiring context.
+type ContextNotification struct {
+	// Type is the type of the change: ADDED, MODIFIED, REMOVED, EXPIRING or EXPIRED.
+	Type kubeconfig.ContextEventType `json:"type"`
+	// Name is the name of the cluster of the context, as the clients know it.
+	Name string `json:"name"`
+	// ExpiresAt is when the context expires, for the contexts with a ttl.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// ExpiresInSeconds is the number of seconds left before the context expires.
+	ExpiresInSeconds int64 `json:"expiresInSeconds,omitempty"`
+}
+
+// contextNotifier sends the changes of the contexts to the clients subscribed to them.
+type contextNotifier struct {
+	store kubeconfig.ContextStore
+	// watch starts watching the store on the first subscription.
+	watch sync.Once
+
+	mu sync.Mutex
+	// subscribers are the user IDs of the subscribed clients.
+	subscribers map[StreamClient]string
+}
+
+func newContextNotifier(store kubeconfig.ContextStore) *contextNotifier {
+	return &contextNotifier{
+		store:       store,
+		subscribers: map[StreamClient]string{},
+	}
+}
+
+// subscribe sends the changes of the contexts visible to the user to the client.
+func (n *contextNotifier) subscribe(userID string, client StreamClient) {
+	n.watch.Do(func() {
+		// The multiplexer lives as long as the server, so the store is watched until exit.
+		go n.run(n.store.Watch(context.Background()))
+	})
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.subscribers[client] = userID
+}
+
+// unsubscribe stops sending the changes of the contexts to the client.
+func (n *contextNotifier) unsubscribe(client StreamClient) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.subscribers, client)
+}
+
+// run sends the events of the watch to the subscribers until the watch ends.
+func (n *contextNotifier) run(events <-chan kubeconfig.ContextEvent) {
+	for event := range events {
+		n.publish(event)
+	}
+}
+
+// publish sends a change of a context to the subscribers it's visible to.
+func (n *contextNotifier) publish(event kubeconfig.ContextEvent) {
+	notification := ContextNotification{
+		Type: event.Type,
+		Name: event.Key,
+	}
+
+	// The key of a stateless context has the ID of its user appended to its name.
+	if event.Context != nil && event.Context.Internal {
+		notification.Name = event.Context.Name
+	}
+
+	if !event.ExpiresAt.IsZero() {
+		notification.ExpiresAt = &event.ExpiresAt
+		notification.ExpiresInSeconds = int64(time.Until(event.ExpiresAt).Round(time.Second).Seconds())
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"context": notification.Name}, err,
+			"marshaling context notification")
+
+		return
+	}
+
+	msg := Message{
+		ClusterID: notification.Name,
+		Data:      string(data),
+		Type:      "CONTEXT",
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for client, userID := range n.subscribers {
+		if !contextVisibleTo(event, userID) {
+			continue
+		}
+
+		if err := client.WriteJSON(msg); err != nil &&
+			!websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			logger.Log(logger.LevelError, map[string]string{"context": notification.Name}, err,
+				"writing context notification to client")
+		}
+	}
+}
+
+// contextVisibleTo tells whether the context of the event is visible to the user. The stateless
+// contexts are internal, and only visible to the user they were stored for.
+func contextVisibleTo(event kubeconfig.ContextEvent, userID string) bool {
+	if event.Context == nil || !event.Context.Internal {
+		return true
+	}
+
+	return userID != "" && strings.HasSuffix(event.Key, userID)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readContextNotification reads the next CONTEXT message sent to the client.
+func readContextNotification(t *testing.T, client *WSConnLock) ContextNotification {
+	t.Helper()
+
+	var msg Message
+
+	require.NoError(t, client.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	require.NoError(t, client.ReadJSON(&msg))
+	require.Equal(t, "CONTEXT", msg.Type)
+
+	var notification ContextNotification
+
+	require.NoError(t, json.Unmarshal([]byte(msg.Data), &notification))
+	assert.Equal(t, notification.Name, msg.ClusterID)
+
+	return notification
+}
+
+func TestContextNotifier(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	m := NewMultiplexer(store)
+
+	firstClient, firstServer := createTestWebSocketConnection()
+	defer firstServer.Close()
+
+	secondClient, secondServer := createTestWebSocketConnection()
+	defer secondServer.Close()
+
+	m.contexts.subscribe("user1", firstClient)
+	m.contexts.subscribe("user2", secondClient)
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "minikube", Internal: true}, "minikubeuser1", time.Hour))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "kind"}))
+
+	// The stateless context is only sent to its user, by its name.
+	notification := readContextNotification(t, firstClient)
+	assert.Equal(t, kubeconfig.ContextAdded, notification.Type)
+	assert.Equal(t, "minikube", notification.Name)
+	require.NotNil(t, notification.ExpiresAt)
+	assert.InDelta(t, time.Hour.Seconds(), notification.ExpiresInSeconds, 1)
+
+	for _, client := range []*WSConnLock{firstClient, secondClient} {
+		notification = readContextNotification(t, client)
+		assert.Equal(t, kubeconfig.ContextAdded, notification.Type)
+		assert.Equal(t, "kind", notification.Name)
+		assert.Nil(t, notification.ExpiresAt)
+	}
+
+	m.contexts.unsubscribe(firstClient)
+
+	require.NoError(t, store.RemoveContext("kind"))
+
+	notification = readContextNotification(t, secondClient)
+	assert.Equal(t, kubeconfig.ContextRemoved, notification.Type)
+	assert.Equal(t, "kind", notification.Name)
+
+	m.contexts.mu.Lock()
+	assert.Len(t, m.contexts.subscribers, 1)
+	m.contexts.mu.Unlock()
+}
+
+func TestContextVisibleTo(t *testing.T) {
+	shared := kubeconfig.ContextEvent{Key: "kind", Context: &kubeconfig.Context{Name: "kind"}}
+	stateless := kubeconfig.ContextEvent{
+		Key:     "minikubeuser1",
+		Context: &kubeconfig.Context{Name: "minikube", Internal: true},
+	}
+
+	assert.True(t, contextVisibleTo(shared, ""))
+	assert.True(t, contextVisibleTo(shared, "user2"))
+	assert.True(t, contextVisibleTo(stateless, "user1"))
+	assert.False(t, contextVisibleTo(stateless, "user2"))
+	assert.False(t, contextVisibleTo(stateless, ""))
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// LimitConnections is the name of the per-client WebSocket connection limit.
+	LimitConnections = "connections"
+	// LimitSubscriptions is the name of the per-client watch subscription limit.
+	LimitSubscriptions = "subscriptions"
+)
+
+// LimitExceededError is returned when a client goes over one of its multiplexer limits.
+type LimitExceededError struct {
+	// Limit is the name of the limit, LimitConnections or LimitSubscriptions.
+	Limit string `json:"limit"`
+	// Max is the value of the limit.
+	Max int `json:"max"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("too many %s for this client, the limit is %d", e.Limit, e.Max)
+}
+
+// clientLimits caps the number of WebSocket connections and watch subscriptions
+// of each client. A limit of zero disables it.
+type clientLimits struct {
+	// maxConnections is the maximum number of concurrent WebSocket connections per client.
+	maxConnections int
+	// maxSubscriptions is the maximum number of active watch subscriptions per client.
+	maxSubscriptions int
+	// mu is a mutex to synchronize access to connections.
+	mu sync.Mutex
+	// connections counts the open WebSocket connections of each client.
+	connections map[string]int
+	// clients are the IDs of the clients of the open client streams, the subscriptions they
+	// open count against them.
+	clients map[StreamClient]string
+}
+
+// clientIDForRequest identifies the client of a request by what authenticates it: the API
+// token, or else a hash of its bearer token or auth cookies. Only the clients with none of them
+// are identified by their remote host, which is the one of the proxy when there is one. The
+// userId the client sends isn't used, as the client could pick another one to get around
+// its limits.
+func clientIDForRequest(r *http.Request) string {
+	if name := auth.APITokenNameFromContext(r.Context()); name != "" {
+		return "apitoken:" + name
+	}
+
+	if hash := auth.CredentialsHash(r); hash != "" {
+		return "credentials:" + hash
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// acquireClientConnection reserves a WebSocket connection for the client.
+// It returns a LimitExceededError if the client already has the maximum number of connections.
+func (m *Multiplexer) acquireClientConnection(clientID string) error {
+	m.limits.mu.Lock()
+	defer m.limits.mu.Unlock()
+
+	if m.limits.maxConnections > 0 && m.limits.connections[clientID] >= m.limits.maxConnections {
+		m.recordLimitExceeded(LimitConnections)
+
+		return &LimitExceededError{Limit: LimitConnections, Max: m.limits.maxConnections}
+	}
+
+	m.limits.connections[clientID]++
+
+	if m.metrics != nil {
+		m.metrics.MultiplexerClients.Add(context.Background(), 1)
+	}
+
+	return nil
+}
+
+// releaseClientConnection releases a WebSocket connection reserved with acquireClientConnection.
+func (m *Multiplexer) releaseClientConnection(clientID string) {
+	m.limits.mu.Lock()
+	defer m.limits.mu.Unlock()
+
+	m.limits.connections[clientID]--
+	if m.limits.connections[clientID] <= 0 {
+		delete(m.limits.connections, clientID)
+	}
+
+	if m.metrics != nil {
+		m.metrics.MultiplexerClients.Add(context.Background(), -1)
+	}
+}
+
+// registerClient records that the subscriptions opened by the client stream count against
+// the client with the ID.
+func (m *Multiplexer) registerClient(client StreamClient, clientID string) {
+	m.limits.mu.Lock()
+	defer m.limits.mu.Unlock()
+
+	m.limits.clients[client] = clientID
+}
+
+// unregisterClient forgets a client stream registered with registerClient.
+func (m *Multiplexer) unregisterClient(client StreamClient) {
+	m.limits.mu.Lock()
+	defer m.limits.mu.Unlock()
+
+	delete(m.limits.clients, client)
+}
+
+// clientIDOf returns the ID of the client of the stream, "" if it wasn't registered.
+func (m *Multiplexer) clientIDOf(client StreamClient) string {
+	if client == nil {
+		return ""
+	}
+
+	m.limits.mu.Lock()
+	defer m.limits.mu.Unlock()
+
+	return m.limits.clients[client]
+}
+
+// checkSubscriptionLimit returns a LimitExceededError if the client already has the
+// maximum number of active watch subscriptions.
+func (m *Multiplexer) checkSubscriptionLimit(clientID string) error {
+	if m.limits.maxSubscriptions <= 0 {
+		return nil
+	}
+
+	m.mutex.RLock()
+
+	count := 0
+
+	for _, conn := range m.connections {
+		if conn.clientID == clientID {
+			count++
+		}
+	}
+
+	m.mutex.RUnlock()
+
+	if count >= m.limits.maxSubscriptions {
+		m.recordLimitExceeded(LimitSubscriptions)
+
+		return &LimitExceededError{Limit: LimitSubscriptions, Max: m.limits.maxSubscriptions}
+	}
+
+	return nil
+}
+
+// recordLimitExceeded counts a rejection by the given limit.
+func (m *Multiplexer) recordLimitExceeded(limit string) {
+	logger.Log(logger.LevelWarn, map[string]string{"limit": limit}, nil, "multiplexer client limit exceeded")
+
+	if m.metrics == nil {
+		return
+	}
+
+	m.metrics.MultiplexerLimitExceeded.Add(context.Background(), 1,
+		metric.WithAttributes(attribute.String("limit", limit)))
+}
+
+// writeLimitError sends an ERROR message describing the exceeded limit to the client.
+func (m *Multiplexer) writeLimitError(clientConn StreamClient, msg Message, limitErr *LimitExceededError) {
+	data, err := json.Marshal(struct {
+		Error string `json:"error"`
+		*LimitExceededError
+	}{
+		Error:              limitErr.Error(),
+		LimitExceededError: limitErr,
+	})
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "marshaling limit error")
+
+		return
+	}
+
+	errorMsg := Message{
+		ClusterID: msg.ClusterID,
+		Path:      msg.Path,
+		Query:     msg.Query,
+		UserID:    msg.UserID,
+		Data:      string(data),
+		Type:      "ERROR",
+	}
+
+	if err := clientConn.WriteJSON(errorMsg); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"clusterID": msg.ClusterID}, err, "writing limit error to client")
+	}
+}
+
+// rejectClientConnection tells the client that it has too many connections and closes
+// the WebSocket with a "try again later" close code.
+func (m *Multiplexer) rejectClientConnection(clientConn *WSConnLock, limitErr *LimitExceededError) {
+	m.writeLimitError(clientConn, Message{}, limitErr)
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, limitErr.Error())
+
+	clientConn.writeMu.Lock()
+	defer clientConn.writeMu.Unlock()
+
+	if err := clientConn.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second)); err != nil {
+		logger.Log(logger.LevelError, nil, err, "writing close message to client")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIDForRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/wsMultiplexer", nil)
+	req.RemoteAddr = "10.0.0.1:4242"
+	assert.Equal(t, "10.0.0.1", clientIDForRequest(req))
+
+	// The userId is chosen by the client, so it doesn't change its identity.
+	req = httptest.NewRequest(http.MethodGet, "/wsMultiplexer?userId=user-1", nil)
+	req.RemoteAddr = "10.0.0.1:4343"
+	assert.Equal(t, "10.0.0.1", clientIDForRequest(req))
+
+	// Behind a proxy, the clients with other credentials are other clients.
+	withCookie := func(value string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/wsMultiplexer", nil)
+		req.RemoteAddr = "10.0.0.1:4444"
+		req.AddCookie(&http.Cookie{Name: "headlamp-auth-minikube.0", Value: value})
+
+		return req
+	}
+
+	alice, bob := clientIDForRequest(withCookie("alice-token")), clientIDForRequest(withCookie("bob-token"))
+	assert.NotEqual(t, alice, bob)
+	assert.Equal(t, alice, clientIDForRequest(withCookie("alice-token")))
+	assert.NotContains(t, alice, "alice-token")
+
+	req = httptest.NewRequest(http.MethodGet, "/wsMultiplexer", nil)
+	req.Header.Set("Authorization", "Bearer alice-token")
+	assert.NotEqual(t, "10.0.0.1", clientIDForRequest(req))
+	assert.NotContains(t, clientIDForRequest(req), "alice-token")
+
+	req = req.WithContext(auth.WithAPITokenName(req.Context(), "ci"))
+	assert.Equal(t, "apitoken:ci", clientIDForRequest(req))
+}
+
+func TestAcquireClientConnection(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+	m.limits.maxConnections = 2
+
+	require.NoError(t, m.acquireClientConnection("client-1"))
+	require.NoError(t, m.acquireClientConnection("client-1"))
+	require.NoError(t, m.acquireClientConnection("client-2"))
+
+	err := m.acquireClientConnection("client-1")
+
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitConnections, limitErr.Limit)
+	assert.Equal(t, 2, limitErr.Max)
+
+	m.releaseClientConnection("client-1")
+	require.NoError(t, m.acquireClientConnection("client-1"))
+}
+
+func TestCheckSubscriptionLimit(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	m.connections["a"] = &Connection{UserID: "test-user", clientID: "10.0.0.1"}
+	m.connections["b"] = &Connection{UserID: "other-user", clientID: "10.0.0.2"}
+
+	// No limit by default.
+	require.NoError(t, m.checkSubscriptionLimit("10.0.0.1"))
+
+	m.limits.maxSubscriptions = 1
+
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, m.checkSubscriptionLimit("10.0.0.1"), &limitErr)
+	assert.Equal(t, LimitSubscriptions, limitErr.Limit)
+
+	require.NoError(t, m.checkSubscriptionLimit("10.0.0.3"))
+
+	// The error frame is sent instead of establishing a new connection, whatever the userId
+	// the client sends.
+	clientConn, clientServer := createTestWebSocketConnection()
+	defer clientServer.Close()
+
+	m.registerClient(clientConn, "10.0.0.1")
+
+	msg := Message{ClusterID: "test-cluster", Path: "/api/v1/pods", UserID: "new-user", Type: "REQUEST"}
+
+	_, err := m.getOrCreateConnection(msg, clientConn, nil)
+	require.ErrorAs(t, err, &limitErr)
+
+	m.handleConnectionError(clientConn, msg, err)
+
+	var errorMsg Message
+	require.NoError(t, clientConn.ReadJSON(&errorMsg))
+	assert.Equal(t, "ERROR", errorMsg.Type)
+	assert.Equal(t, "/api/v1/pods", errorMsg.Path)
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(errorMsg.Data), &data))
+	assert.Equal(t, LimitSubscriptions, data["limit"])
+	assert.EqualValues(t, 1, data["max"])
+}
+
+func TestHandleClientWebSocket_ConnectionLimit(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+	m.limits.maxConnections = 1
+
+	server := httptest.NewServer(http.HandlerFunc(m.HandleClientWebSocket))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dialer := newTestDialer()
+
+	first, resp, err := dialer.Dial(wsURL+"?userId=test-user", nil)
+	require.NoError(t, err)
+
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	defer first.Close()
+
+	assert.Eventually(t, func() bool {
+		m.limits.mu.Lock()
+		defer m.limits.mu.Unlock()
+
+		return m.limits.connections["127.0.0.1"] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Another userId from the same client doesn't get around the limit.
+	second, resp, err := dialer.Dial(wsURL+"?userId=other-user", nil)
+	require.NoError(t, err)
+
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	defer second.Close()
+
+	var msg Message
+	require.NoError(t, second.ReadJSON(&msg))
+	assert.Equal(t, "ERROR", msg.Type)
+	assert.Contains(t, msg.Data, LimitConnections)
+
+	_, _, err = second.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater))
+}
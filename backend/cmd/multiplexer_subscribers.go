@@ -0,0 +1,325 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// subscriber is a client subscribed to a connection.
+type subscriber struct {
+	// client is the connection to the client, either a WebSocket or an SSE stream.
+	client StreamClient
+	// query is the query the client subscribed with, which may differ from the one of the
+	// connection by its resource version. The client matches the messages by it.
+	query string
+}
+
+// write writes msg to the subscriber, with its query.
+func (s subscriber) write(msg Message) error {
+	if msg.Query != "" {
+		msg.Query = s.query
+	}
+
+	return s.client.WriteJSON(msg)
+}
+
+// broadcast writes msg to the subscribers of the connection. The writes fail after
+// ClientWriteTimeout, and the subscribers they fail for are closed, which unsubscribes them.
+// It returns an error only if msg couldn't be written to any of them. The caller holds writeMu.
+func (c *Connection) broadcast(msg Message) error {
+	var err error
+
+	delivered := false
+
+	for _, sub := range c.subscribers {
+		if writeErr := sub.write(msg); writeErr != nil {
+			err = writeErr
+
+			// The client may already be closed, which is what is wanted.
+			_ = sub.client.Close()
+
+			continue
+		}
+
+		delivered = true
+	}
+
+	if delivered {
+		return nil
+	}
+
+	return err
+}
+
+// isSubscribed tells whether client is subscribed to the connection. The caller holds mu or writeMu.
+func (c *Connection) isSubscribed(client StreamClient) bool {
+	for _, sub := range c.subscribers {
+		if sub.client == client {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subscribe subscribes client to the watch of the connection, sending it the current status and
+// replaying the events it missed. It returns false, leaving the connection untouched, if the
+// connection isn't a watch or may not have all the events after the version the client watches from.
+func (c *Connection) subscribe(client StreamClient, query string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	if c.isSubscribed(client) {
+		return true
+	}
+
+	if !isWatchQuery(c.Query) {
+		return false
+	}
+
+	replay, ok := c.replayFrom(queryResourceVersion(query))
+	if !ok {
+		return false
+	}
+
+	sub := subscriber{client: client, query: query}
+
+	statusMsg, err := c.statusMessage()
+	if err == nil {
+		err = sub.write(statusMsg)
+	}
+
+	for _, msg := range replay {
+		if err != nil {
+			break
+		}
+
+		err = sub.write(msg)
+	}
+
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"clusterID": c.ClusterID}, err, "replaying messages to subscriber")
+	}
+
+	c.subscribers = append(c.subscribers, sub)
+
+	return true
+}
+
+// replayFrom returns the messages of the history a subscriber watching from resourceVersion
+// needs, or false if the history may not have all of them. The caller holds writeMu.
+//
+// The resource versions are compared as numbers, as the etcd revisions they are in practice,
+// and the subscriptions with versions that aren't numbers aren't shared.
+func (c *Connection) replayFrom(resourceVersion string) ([]Message, bool) {
+	// The history has to go back to the start of the watch.
+	if c.seq > uint64(len(c.history)) {
+		return nil, false
+	}
+
+	start := queryResourceVersion(c.Query)
+	if resourceVersion == start {
+		return c.history, true
+	}
+
+	from, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	// A watch started later misses the events between the two versions.
+	startVersion, err := strconv.ParseUint(start, 10, 64)
+	if err != nil || from < startVersion {
+		return nil, false
+	}
+
+	replay := make([]Message, 0, len(c.history))
+
+	for _, msg := range c.history {
+		if version, ok := eventResourceVersion(msg); ok && version <= from {
+			continue
+		}
+
+		replay = append(replay, msg)
+	}
+
+	return replay, true
+}
+
+// unsubscribe unsubscribes client from the connection. It returns whether it was subscribed.
+func (c *Connection) unsubscribe(client StreamClient) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	for i, sub := range c.subscribers {
+		if sub.client == client {
+			c.subscribers = append(c.subscribers[:i:i], c.subscribers[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// unsubscribe unsubscribes client from the connections of the subscription of msg, closing
+// the ones left without subscribers.
+func (m *Multiplexer) unsubscribe(msg Message, client StreamClient) {
+	shared := sharedQuery(msg.Query)
+
+	m.mutex.RLock()
+
+	var matching []*Connection
+
+	for _, conn := range m.connections {
+		if conn.ClusterID == msg.ClusterID && conn.Path == msg.Path && conn.UserID == msg.UserID &&
+			sharedQuery(conn.Query) == shared {
+			matching = append(matching, conn)
+		}
+	}
+
+	m.mutex.RUnlock()
+
+	for _, conn := range matching {
+		if conn.unsubscribe(client) && conn.isDetached() {
+			m.closeConnection(conn)
+		}
+	}
+}
+
+// setAside moves a connection out of the key of its subscription for a new connection to take
+// it. The connection keeps streaming to its subscribers until they unsubscribe.
+func (m *Multiplexer) setAside(key string, conn *Connection) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.connections[key] == conn {
+		delete(m.connections, key)
+		m.connections[fmt.Sprintf("%s#%p", key, conn)] = conn
+	}
+}
+
+// replaceConnection puts newConn in place of conn, or under its own key if conn isn't tracked.
+func (m *Multiplexer) replaceConnection(conn, newConn *Connection) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for key, tracked := range m.connections {
+		if tracked == conn {
+			m.connections[key] = newConn
+
+			return
+		}
+	}
+
+	key := m.createConnectionKey(newConn.ClusterID, newConn.Path, newConn.UserID, newConn.Query, newConn.Token)
+	m.connections[key] = newConn
+}
+
+// isTracked tells whether the connection is tracked, under any key. The caller holds mutex.
+func (m *Multiplexer) isTracked(conn *Connection) bool {
+	for _, tracked := range m.connections {
+		if tracked == conn {
+			return true
+		}
+	}
+
+	return false
+}
+
+// untrack stops tracking the connection. The caller holds mutex.
+func (m *Multiplexer) untrack(conn *Connection) {
+	for key, tracked := range m.connections {
+		if tracked == conn {
+			delete(m.connections, key)
+		}
+	}
+}
+
+// sharedQuery returns the query without its resource version, which is the part of the query
+// the subscribers of a connection have in common.
+func sharedQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+
+	values.Del("resourceVersion")
+
+	return values.Encode()
+}
+
+// queryResourceVersion returns the resource version of a query.
+func queryResourceVersion(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+
+	return values.Get("resourceVersion")
+}
+
+// isWatchQuery tells whether a query is the one of a watch.
+func isWatchQuery(query string) bool {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return false
+	}
+
+	watch := values.Get("watch")
+
+	return watch == "true" || watch == "1"
+}
+
+// eventResourceVersion returns the resource version of the object of the watch event in a DATA message.
+func eventResourceVersion(msg Message) (uint64, bool) {
+	if msg.Binary {
+		return 0, false
+	}
+
+	var event struct {
+		Object struct {
+			Metadata struct {
+				ResourceVersion string `json:"resourceVersion"`
+			} `json:"metadata"`
+		} `json:"object"`
+	}
+
+	if err := json.Unmarshal([]byte(msg.Data), &event); err != nil {
+		return 0, false
+	}
+
+	version, err := strconv.ParseUint(event.Object.Metadata.ResourceVersion, 10, 64)
+
+	return version, err == nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchEvent returns a watch event of a pod with the resource version.
+func watchEvent(resourceVersion int) []byte {
+	return []byte(fmt.Sprintf(`{"type":"MODIFIED","object":{"metadata":{"resourceVersion":"%d"}}}`, resourceVersion))
+}
+
+func TestGetOrCreateConnection_SharedWatch(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	firstClient, firstServer := createTestWebSocketConnection()
+	defer firstServer.Close()
+
+	secondClient, secondServer := createTestWebSocketConnection()
+	defer secondServer.Close()
+
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods",
+		"watch=true&resourceVersion=100", firstClient)
+	conn.Status.State = StateConnected
+	m.connections[m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", conn.Query, nil)] = conn
+
+	for _, version := range []int{101, 102} {
+		require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, watchEvent(version)))
+	}
+
+	// The second client listed the pods at 101, so it only misses the event at 102.
+	secondConn, err := m.getOrCreateConnection(Message{
+		ClusterID: "test-cluster",
+		Path:      "/api/v1/pods",
+		Query:     "watch=true&resourceVersion=101",
+		UserID:    "test-user",
+	}, secondClient, nil)
+	require.NoError(t, err)
+	assert.Same(t, conn, secondConn)
+
+	var msg Message
+
+	require.NoError(t, secondClient.ReadJSON(&msg))
+	assert.Equal(t, "STATUS", msg.Type)
+
+	require.NoError(t, secondClient.ReadJSON(&msg))
+	assert.Equal(t, string(watchEvent(102)), msg.Data)
+	assert.Equal(t, "watch=true&resourceVersion=101", msg.Query)
+
+	// The new events are fanned out to both clients, each with its own query.
+	require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, watchEvent(103)))
+
+	require.NoError(t, secondClient.ReadJSON(&msg))
+	assert.Equal(t, string(watchEvent(103)), msg.Data)
+	assert.Equal(t, "watch=true&resourceVersion=101", msg.Query)
+
+	for seq := uint64(1); seq <= 3; seq++ {
+		require.NoError(t, firstClient.ReadJSON(&msg))
+		assert.Equal(t, seq, msg.Seq)
+	}
+
+	assert.Equal(t, string(watchEvent(103)), msg.Data)
+	assert.Equal(t, "watch=true&resourceVersion=100", msg.Query)
+
+	assert.Len(t, m.connections, 1)
+}
+
+func TestConnectionSubscribe_NotShared(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		events int
+	}{
+		{name: "older resource version", query: "watch=true&resourceVersion=50"},
+		{name: "no resource version", query: "watch=true"},
+		{name: "history not going back to the start", query: "watch=true&resourceVersion=100", events: HistorySize + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMultiplexer(kubeconfig.NewContextStore())
+
+			clientConn, clientServer := createTestWebSocketConnection()
+			defer clientServer.Close()
+
+			conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods",
+				"watch=true&resourceVersion=100", nil)
+			conn.subscribers = nil
+
+			for i := 0; i < tt.events; i++ {
+				require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, watchEvent(101+i)))
+			}
+
+			assert.False(t, conn.subscribe(clientConn, tt.query))
+			assert.True(t, conn.isDetached())
+		})
+	}
+
+	t.Run("not a watch", func(t *testing.T) {
+		clientConn, clientServer := createTestWebSocketConnection()
+		defer clientServer.Close()
+
+		conn := createTestConnection("test-cluster", "test-user", "/api/v1/namespaces/default/pods/p/log",
+			"follow=true", nil)
+		conn.subscribers = nil
+
+		assert.False(t, conn.subscribe(clientConn, "follow=true"))
+	})
+}
+
+func TestUnsubscribe(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	firstClient, firstServer := createTestWebSocketConnection()
+	defer firstServer.Close()
+
+	secondClient, secondServer := createTestWebSocketConnection()
+	defer secondServer.Close()
+
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "watch=true", firstClient)
+	m.connections[m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", conn.Query, nil)] = conn
+
+	require.True(t, conn.subscribe(secondClient, "watch=true"))
+
+	closeMsg := Message{
+		ClusterID: "test-cluster",
+		Path:      "/api/v1/pods",
+		Query:     "watch=true",
+		UserID:    "test-user",
+		Type:      "CLOSE",
+	}
+
+	// The connection is kept for the other subscriber.
+	m.unsubscribe(closeMsg, firstClient)
+	assert.False(t, conn.closed)
+	assert.Len(t, m.connections, 1)
+
+	m.unsubscribe(closeMsg, secondClient)
+	assert.True(t, conn.closed)
+	assert.Empty(t, m.connections)
+}
+
+func TestCreateConnectionKey_ResourceVersion(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	assert.Equal(t,
+		m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "watch=true&resourceVersion=1", nil),
+		m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "resourceVersion=2&watch=true", nil))
+	assert.NotEqual(t,
+		m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "watch=true", nil),
+		m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "watch=true&labelSelector=app%3Dweb", nil))
+}
+
+// failingClient is a StreamClient whose writes fail, recording whether it was closed.
+type failingClient struct {
+	closed bool
+}
+
+func (c *failingClient) WriteJSON(v interface{}) error {
+	return fmt.Errorf("write timeout")
+}
+
+func (c *failingClient) Close() error {
+	c.closed = true
+
+	return nil
+}
+
+func TestBroadcastDropsFailingSubscribers(t *testing.T) {
+	healthy := &recordingClient{}
+	failing := &failingClient{}
+
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "watch=true", nil)
+	conn.subscribers = []subscriber{{client: failing}, {client: healthy}}
+
+	require.NoError(t, conn.broadcast(Message{Type: "DATA", Data: "event"}))
+
+	assert.True(t, failing.closed)
+	require.Len(t, healthy.messages, 1)
+	assert.Equal(t, "event", healthy.messages[0].Data)
+
+	// Without any subscriber left to deliver to, the error is returned.
+	conn.subscribers = []subscriber{{client: failing}}
+	assert.Error(t, conn.broadcast(Message{Type: "DATA", Data: "event"}))
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
@@ -169,7 +170,8 @@ func TestDialWebSocket(t *testing.T) {
 	defer server.Close()
 
 	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
-	conn, err := m.dialWebSocket(wsURL, &tls.Config{InsecureSkipVerify: true}, server.URL, nil) //nolint:gosec
+	tlsConfig := &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+	conn, err := m.dialWebSocket(context.Background(), wsURL, tlsConfig, server.URL, nil)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, conn)
@@ -202,7 +204,8 @@ func TestDialWebSocket_WithToken(t *testing.T) {
 
 	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
 	token := "my-test-token"
-	conn, err := m.dialWebSocket(wsURL, &tls.Config{InsecureSkipVerify: true}, server.URL, &token) //nolint:gosec
+	tlsConfig := &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+	conn, err := m.dialWebSocket(context.Background(), wsURL, tlsConfig, server.URL, &token)
 	assert.NoError(t, err)
 	assert.NotNil(t, conn)
 
@@ -220,16 +223,62 @@ func TestDialWebSocket_Errors(t *testing.T) {
 	// Test invalid URL
 	tlsConfig := &tls.Config{InsecureSkipVerify: true} //nolint:gosec
 
-	ws, err := m.dialWebSocket("invalid-url", tlsConfig, "", nil)
+	ws, err := m.dialWebSocket(context.Background(), "invalid-url", tlsConfig, "", nil)
 	assert.Error(t, err)
 	assert.Nil(t, ws)
 
 	// Test unreachable URL
-	ws, err = m.dialWebSocket("ws://localhost:12345", tlsConfig, "", nil)
+	ws, err = m.dialWebSocket(context.Background(), "ws://localhost:12345", tlsConfig, "", nil)
 	assert.Error(t, err)
 	assert.Nil(t, ws)
 }
 
+func TestDialWebSocketCancelled(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	// The cluster accepts the TCP connection but never completes the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(HandshakeTimeout)
+		}
+	}()
+
+	conn := m.createConnection("minikube", "user", "/api/v1/pods", "watch=true", nil, nil)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.closeConnection(conn)
+	}()
+
+	// The connection isn't tracked yet, so closing it only cancels its context.
+	conn.cancelUpstream()
+
+	start := time.Now()
+	ws, err := m.dialWebSocket(conn.ctx, "ws://"+listener.Addr().String(), nil, "", nil)
+	require.Error(t, err)
+	assert.Nil(t, ws)
+	assert.Less(t, time.Since(start), HandshakeTimeout)
+}
+
+func TestCloseConnectionCancelsUpstream(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	conn := m.createConnection("minikube", "user", "/api/v1/pods", "watch=true", nil, nil)
+	m.connections[m.createConnectionKey("minikube", "/api/v1/pods", "user", "watch=true", nil)] = conn
+
+	require.NoError(t, conn.ctx.Err())
+
+	m.closeConnection(conn)
+	require.ErrorIs(t, conn.ctx.Err(), context.Canceled)
+}
+
 func TestMonitorConnection(t *testing.T) {
 	m := NewMultiplexer(kubeconfig.NewContextStore())
 	clientConn, clientServer := createTestWebSocketConnection()
@@ -306,7 +355,7 @@ func TestCleanupConnections(t *testing.T) {
 	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "", clientConn)
 	conn.WSConn = wsConn.conn
 
-	connKey := m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user")
+	connKey := m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "", nil)
 	m.connections[connKey] = conn
 
 	m.cleanupConnections()
@@ -327,7 +376,7 @@ func TestCloseConnection(t *testing.T) {
 	conn := createTestConnection("test-cluster-1", "test-user", "/api/v1/pods", "", clientConn)
 	conn.WSConn = wsConn.conn
 
-	connKey := m.createConnectionKey("test-cluster-1", "/api/v1/pods", "test-user")
+	connKey := m.createConnectionKey("test-cluster-1", "/api/v1/pods", "test-user", "", nil)
 	m.connections[connKey] = conn
 
 	m.CloseConnection("test-cluster-1", "/api/v1/pods", "test-user")
@@ -343,12 +392,12 @@ func createTestConnection(
 	client *WSConnLock,
 ) *Connection {
 	return &Connection{
-		ClusterID: clusterID,
-		UserID:    userID,
-		Path:      path,
-		Query:     query,
-		Client:    client,
-		Done:      make(chan struct{}),
+		ClusterID:   clusterID,
+		UserID:      userID,
+		Path:        path,
+		Query:       query,
+		subscribers: []subscriber{{client: client, query: query}},
+		Done:        make(chan struct{}),
 		Status: ConnectionStatus{
 			State:   StateConnecting,
 			LastMsg: time.Now(),
@@ -647,7 +696,8 @@ func TestReconnect(t *testing.T) {
 	newConn, err = m.reconnect(conn)
 	assert.Error(t, err)
 	assert.Nil(t, newConn)
-	assert.Contains(t, err.Error(), "getting context: key not found")
+	assert.ErrorIs(t, err, kubeconfig.ErrContextNotFound)
+	assert.Contains(t, err.Error(), "getting context: context not found")
 
 	// Test reconnection with closed connection
 	conn = m.createConnection("test-cluster", "test-user", "/api/v1/pods", "watch=true", clientConn, nil)
@@ -835,9 +885,9 @@ func TestUpdateStatus_WithError(t *testing.T) {
 	defer clientServer.Close()
 
 	conn := &Connection{
-		Status: ConnectionStatus{},
-		Done:   make(chan struct{}),
-		Client: clientConn,
+		Status:      ConnectionStatus{},
+		Done:        make(chan struct{}),
+		subscribers: []subscriber{{client: clientConn}},
 	}
 
 	// Test error state with message
@@ -982,10 +1032,10 @@ func TestSendIfNewResourceVersion_VersionComparison(t *testing.T) {
 	defer clientServer.Close()
 
 	conn := &Connection{
-		ClusterID: "test-cluster",
-		Path:      "/api/v1/pods",
-		UserID:    "test-user",
-		Client:    clientConn,
+		ClusterID:   "test-cluster",
+		Path:        "/api/v1/pods",
+		UserID:      "test-user",
+		subscribers: []subscriber{{client: clientConn}},
 	}
 
 	// Initialize lastVersion pointer
@@ -993,30 +1043,30 @@ func TestSendIfNewResourceVersion_VersionComparison(t *testing.T) {
 
 	// Test initial version
 	message := []byte(`{"metadata":{"resourceVersion":"100"}}`)
-	err := m.sendIfNewResourceVersion(message, conn, clientConn, &lastVersion)
+	err := m.sendIfNewResourceVersion(message, conn, &lastVersion)
 	require.NoError(t, err)
 	assert.Equal(t, "100", lastVersion)
 
 	// Test same version - should not send
-	err = m.sendIfNewResourceVersion(message, conn, clientConn, &lastVersion)
+	err = m.sendIfNewResourceVersion(message, conn, &lastVersion)
 	require.NoError(t, err)
 	assert.Equal(t, "100", lastVersion)
 
 	// Test newer version
 	message = []byte(`{"metadata":{"resourceVersion":"200"}}`)
-	err = m.sendIfNewResourceVersion(message, conn, clientConn, &lastVersion)
+	err = m.sendIfNewResourceVersion(message, conn, &lastVersion)
 	require.NoError(t, err)
 	assert.Equal(t, "200", lastVersion)
 
 	// Test invalid JSON
 	message = []byte(`invalid json`)
-	err = m.sendIfNewResourceVersion(message, conn, clientConn, &lastVersion)
+	err = m.sendIfNewResourceVersion(message, conn, &lastVersion)
 	assert.Error(t, err)
 	assert.Equal(t, "200", lastVersion) // Version should not change on error
 
 	// Test missing resourceVersion
 	message = []byte(`{"metadata":{}}`)
-	err = m.sendIfNewResourceVersion(message, conn, clientConn, &lastVersion)
+	err = m.sendIfNewResourceVersion(message, conn, &lastVersion)
 	require.NoError(t, err) // Should not error, but also not update version
 	assert.Equal(t, "200", lastVersion)
 }
@@ -1028,14 +1078,15 @@ func TestSendCompleteMessage_ClosedConnection(t *testing.T) {
 	defer clientServer.Close()
 
 	conn := &Connection{
-		ClusterID: "test-cluster",
-		Path:      "/api/v1/pods",
-		UserID:    "test-user",
-		Query:     "watch=true",
+		ClusterID:   "test-cluster",
+		Path:        "/api/v1/pods",
+		UserID:      "test-user",
+		Query:       "watch=true",
+		subscribers: []subscriber{{client: clientConn, query: "watch=true"}},
 	}
 
 	// Test successful complete message
-	err := m.sendCompleteMessage(conn, clientConn)
+	err := m.sendCompleteMessage(conn)
 	require.NoError(t, err)
 
 	// Verify the message
@@ -1054,7 +1105,7 @@ func TestSendCompleteMessage_ClosedConnection(t *testing.T) {
 
 	// Test sending to closed connection
 	clientConn.Close()
-	err = m.sendCompleteMessage(conn, clientConn)
+	err = m.sendCompleteMessage(conn)
 	assert.NoError(t, err)
 }
 
@@ -1101,14 +1152,15 @@ func TestSendCompleteMessage_ErrorConditions(t *testing.T) {
 			defer clientServer.Close()
 
 			conn := &Connection{
-				ClusterID: "test-cluster",
-				Path:      "/api/v1/pods",
-				UserID:    "test-user",
-				Query:     "watch=true",
+				ClusterID:   "test-cluster",
+				Path:        "/api/v1/pods",
+				UserID:      "test-user",
+				Query:       "watch=true",
+				subscribers: []subscriber{{client: clientConn, query: "watch=true"}},
 			}
 
 			tt.setupConn(conn, clientConn)
-			err := m.sendCompleteMessage(conn, clientConn)
+			err := m.sendCompleteMessage(conn)
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -1158,13 +1210,60 @@ func TestGetOrCreateConnection_TokenRefresh(t *testing.T) {
 	// Now send a new message with a new token
 	newToken := "new-refreshed-token"
 
-	// Get the same connection, but with a new token
+	// A new token gets a connection of its own, the events being fetched with it
 	conn2, err := m.getOrCreateConnection(msg, clientConn, &newToken)
 	assert.NoError(t, err)
-	assert.Equal(t, conn, conn2, "Should return the same connection instance")
+	assert.NotSame(t, conn, conn2, "Should not share the connection of another token")
+	assert.Equal(t, &newToken, conn2.Token)
+
+	// The token of the first connection is left as it is
+	assert.Equal(t, &originalToken, conn.Token)
+
+	// The same token shares the connection
+	sameToken := "original-token"
+	conn3, err := m.getOrCreateConnection(msg, clientConn, &sameToken)
+	assert.NoError(t, err)
+	assert.Same(t, conn, conn3)
+}
+
+func TestGetOrCreateConnection_OtherUserToken(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	m := NewMultiplexer(store)
+
+	mockServer := createMockKubeAPIServer()
+	defer mockServer.Close()
+
+	require.NoError(t, store.AddContext(&kubeconfig.Context{
+		Name:    "test-cluster",
+		Cluster: &api.Cluster{Server: mockServer.URL, InsecureSkipTLSVerify: true},
+	}))
+
+	ownerConn, ownerServer := createTestWebSocketConnection()
+	defer ownerServer.Close()
+
+	otherConn, otherServer := createTestWebSocketConnection()
+	defer otherServer.Close()
+
+	msg := Message{ClusterID: "test-cluster", Path: "/api/v1/pods", Query: "watch=true", UserID: "owner"}
 
-	// Verify the token was updated
-	assert.Equal(t, &newToken, conn2.Token, "Token should be updated to the new value")
+	ownerToken := "owner-token"
+	conn, err := m.getOrCreateConnection(msg, ownerConn, &ownerToken)
+	require.NoError(t, err)
+
+	// A client sending the user ID of the owner, with another token or none, doesn't get the
+	// events of the connection of the owner nor changes its token.
+	for _, token := range []string{"other-token", ""} {
+		otherToken := token
+		other, err := m.getOrCreateConnection(msg, otherConn, &otherToken)
+		require.NoError(t, err)
+		assert.NotSame(t, conn, other)
+	}
+
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+
+	assert.False(t, conn.isSubscribed(otherConn))
+	assert.Equal(t, &ownerToken, conn.Token)
 }
 
 func TestReconnect_WithToken(t *testing.T) {
@@ -1200,7 +1299,7 @@ func TestReconnect_WithToken(t *testing.T) {
 	conn.Status.State = StateError // Simulate an error state
 
 	// Add the connection to the multiplexer's connections map
-	connKey := m.createConnectionKey(conn.ClusterID, conn.Path, conn.UserID)
+	connKey := m.createConnectionKey(conn.ClusterID, conn.Path, conn.UserID, conn.Query, conn.Token)
 	m.connections[connKey] = conn
 
 	// Test reconnection with the same token
@@ -1221,7 +1320,7 @@ func TestReconnect_WithToken(t *testing.T) {
 	newConn.Status.State = StateError
 
 	// Update the connection in the multiplexer's map
-	connKey = m.createConnectionKey(newConn.ClusterID, newConn.Path, newConn.UserID)
+	connKey = m.createConnectionKey(newConn.ClusterID, newConn.Path, newConn.UserID, newConn.Query, newConn.Token)
 	m.connections[connKey] = newConn
 
 	// Reconnect with the new token
@@ -1261,7 +1360,7 @@ func TestMonitorConnection_Reconnect(t *testing.T) {
 	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
 	tlsConfig := &tls.Config{InsecureSkipVerify: true} //nolint:gosec
 
-	ws, err := m.dialWebSocket(wsURL, tlsConfig, "", nil)
+	ws, err := m.dialWebSocket(context.Background(), wsURL, tlsConfig, "", nil)
 	require.NoError(t, err)
 
 	conn.WSConn = ws
@@ -1335,7 +1434,7 @@ func TestHandleClusterMessages(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		m.handleClusterMessages(conn, clientConn)
+		m.handleClusterMessages(conn)
 		close(done)
 	}()
 
@@ -1375,7 +1474,7 @@ func TestSendCompleteMessage(t *testing.T) {
 	conn := createTestConnection("test-cluster-1", "test-user-1", "/api/v1/pods", "", clientConn)
 
 	// Test sending complete message
-	err := m.sendCompleteMessage(conn, clientConn)
+	err := m.sendCompleteMessage(conn)
 	assert.NoError(t, err)
 
 	// Verify the complete message was sent
@@ -1390,7 +1489,7 @@ func TestSendCompleteMessage(t *testing.T) {
 
 	// Test sending to closed connection
 	conn.closed = true
-	err = m.sendCompleteMessage(conn, clientConn)
+	err = m.sendCompleteMessage(conn)
 	assert.NoError(t, err) // Should return nil for closed connection
 }
 
@@ -1404,7 +1503,7 @@ func TestSendDataMessage(t *testing.T) {
 
 	// Test sending a text message
 	textMsg := []byte("Hello, World!")
-	err := m.sendDataMessage(conn, clientConn, websocket.TextMessage, textMsg)
+	err := m.sendDataMessage(conn, websocket.TextMessage, textMsg)
 	assert.NoError(t, err)
 
 	// Verify text message
@@ -1416,7 +1515,7 @@ func TestSendDataMessage(t *testing.T) {
 
 	// Test sending a binary message
 	binaryMsg := []byte{0x01, 0x02, 0x03}
-	err = m.sendDataMessage(conn, clientConn, websocket.BinaryMessage, binaryMsg)
+	err = m.sendDataMessage(conn, websocket.BinaryMessage, binaryMsg)
 	assert.NoError(t, err)
 
 	// Verify binary message
@@ -1427,6 +1526,142 @@ func TestSendDataMessage(t *testing.T) {
 
 	// Test sending to closed connection
 	conn.closed = true
-	err = m.sendDataMessage(conn, clientConn, websocket.TextMessage, textMsg)
+	err = m.sendDataMessage(conn, websocket.TextMessage, textMsg)
 	assert.NoError(t, err) // Should return nil even for closed connection
 }
+
+func TestResumeConnection(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+	oldClient, oldServer := createTestWebSocketConnection()
+
+	defer oldServer.Close()
+
+	newClient, newServer := createTestWebSocketConnection()
+	defer newServer.Close()
+
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "watch=true", oldClient)
+	m.connections[m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "", nil)] = conn
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, []byte("event")))
+	}
+
+	m.detachClient(oldClient)
+	assert.True(t, conn.isDetached())
+
+	// Messages keep going into the history while detached.
+	require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, []byte("missed")))
+
+	err := m.resumeConnection(Message{
+		ClusterID: "test-cluster",
+		Path:      "/api/v1/pods",
+		UserID:    "test-user",
+		Type:      "RESUME",
+		Seq:       2,
+	}, newClient, nil)
+	require.NoError(t, err)
+	assert.False(t, conn.isDetached())
+
+	var msg Message
+
+	require.NoError(t, newClient.ReadJSON(&msg))
+	assert.Equal(t, uint64(3), msg.Seq)
+	assert.Equal(t, "event", msg.Data)
+
+	require.NoError(t, newClient.ReadJSON(&msg))
+	assert.Equal(t, uint64(4), msg.Seq)
+	assert.Equal(t, "missed", msg.Data)
+}
+
+func TestResumeConnection_OtherToken(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+	oldClient, oldServer := createTestWebSocketConnection()
+
+	defer oldServer.Close()
+
+	newClient, newServer := createTestWebSocketConnection()
+	defer newServer.Close()
+
+	owner := "owner-token"
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "watch=true", oldClient)
+	conn.Token = &owner
+	m.connections[m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "", &owner)] = conn
+
+	require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, []byte("event")))
+	m.detachClient(oldClient)
+
+	// Another token can't take over the stream, nor replace the token of the connection.
+	other := "other-token"
+	resumed, err := conn.reattach(newClient, "", &other, 0)
+	require.ErrorIs(t, err, ErrResumeForbidden)
+	assert.False(t, resumed)
+	assert.True(t, conn.isDetached())
+	assert.False(t, conn.closed)
+	assert.Equal(t, owner, *conn.Token)
+
+	err = m.resumeConnection(Message{
+		ClusterID: "test-cluster",
+		Path:      "/api/v1/pods",
+		UserID:    "test-user",
+		Type:      "RESUME",
+	}, newClient, &owner)
+	require.NoError(t, err)
+	assert.False(t, conn.isDetached())
+}
+
+func TestResumeConnection_Resync(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+	clientConn, clientServer := createTestWebSocketConnection()
+
+	defer clientServer.Close()
+
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "", clientConn)
+	m.connections[m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "", nil)] = conn
+
+	for i := 0; i < HistorySize+1; i++ {
+		require.NoError(t, m.sendDataMessage(conn, websocket.TextMessage, []byte("event")))
+	}
+
+	for i := 0; i < HistorySize+1; i++ {
+		var msg Message
+		require.NoError(t, clientConn.ReadJSON(&msg))
+	}
+
+	m.detachClient(clientConn)
+
+	// The first message is no longer in the history, so the client has to resync.
+	// There is no such cluster in the store, so the new connection fails.
+	err := m.resumeConnection(Message{
+		ClusterID: "test-cluster",
+		Path:      "/api/v1/pods",
+		UserID:    "test-user",
+		Type:      "RESUME",
+	}, clientConn, nil)
+	assert.Error(t, err)
+	assert.True(t, conn.closed)
+
+	var msg Message
+
+	require.NoError(t, clientConn.ReadJSON(&msg))
+	assert.Equal(t, "RESYNC", msg.Type)
+}
+
+func TestDetachClient_ResumeWindow(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+	m.resumeWindow = 100 * time.Millisecond
+
+	clientConn, clientServer := createTestWebSocketConnection()
+	defer clientServer.Close()
+
+	conn := createTestConnection("test-cluster", "test-user", "/api/v1/pods", "", clientConn)
+	m.connections[m.createConnectionKey("test-cluster", "/api/v1/pods", "test-user", "", nil)] = conn
+
+	m.detachClient(clientConn)
+
+	assert.Eventually(t, func() bool {
+		m.mutex.RLock()
+		defer m.mutex.RUnlock()
+
+		return len(m.connections) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// withContextNamespace returns the path of a list, watch or get request for a namespaced
+// resource without a namespace made for the namespace of the context, like kubectl does. The
+// resources are known to be namespaced once the capabilities of the context are detected,
+// the requests are left as they are until then.
+func (c *HeadlampConfig) withContextNamespace(kContext *kubeconfig.Context, info apirequest.Info,
+	path string,
+) string {
+	if kContext.Namespace == "" || info.Namespace != "" || !info.IsResourceRequest() || c.capabilities == nil {
+		return path
+	}
+
+	switch info.Verb {
+	case "list", "watch", "get":
+	default:
+		return path
+	}
+
+	capabilities := c.capabilities.Get(kContext)
+	if capabilities == nil {
+		return path
+	}
+
+	resource := schema.GroupVersionResource{Group: info.APIGroup, Version: info.APIVersion, Resource: info.Resource}
+	if namespaced, _ := capabilities.IsNamespaced(resource); !namespaced {
+		return path
+	}
+
+	return apirequest.WithNamespace(path, kContext.Namespace)
+}
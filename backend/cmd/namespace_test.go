@@ -0,0 +1,81 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWithContextNamespace(t *testing.T) {
+	capabilities := kubeconfig.NewCapabilityCache(
+		func(_ context.Context, _ *kubeconfig.Context) (*kubeconfig.Capabilities, error) {
+			return &kubeconfig.Capabilities{
+				Namespaced: map[schema.GroupVersionResource]bool{
+					{Version: "v1", Resource: "pods"}:  true,
+					{Version: "v1", Resource: "nodes"}: false,
+				},
+				DetectedAt: time.Now(),
+			}, nil
+		})
+
+	c := &HeadlampConfig{capabilities: capabilities}
+	kContext := &kubeconfig.Context{Name: "minikube", Namespace: "dev"}
+
+	// The requests are left as they are until the capabilities are detected.
+	list := apirequest.Parse(http.MethodGet, "/api/v1/pods", nil)
+	assert.Equal(t, "api/v1/pods", c.withContextNamespace(kContext, list, "api/v1/pods"))
+
+	require.Eventually(t, func() bool {
+		return capabilities.Get(kContext) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{name: "list", method: http.MethodGet, path: "api/v1/pods", want: "api/v1/namespaces/dev/pods"},
+		{name: "get", method: http.MethodGet, path: "api/v1/pods/nginx", want: "api/v1/namespaces/dev/pods/nginx"},
+		{
+			name: "namespaced", method: http.MethodGet,
+			path: "api/v1/namespaces/prod/pods", want: "api/v1/namespaces/prod/pods",
+		},
+		{name: "cluster_scoped", method: http.MethodGet, path: "api/v1/nodes", want: "api/v1/nodes"},
+		{name: "unknown", method: http.MethodGet, path: "apis/example.com/v1/widgets", want: "apis/example.com/v1/widgets"},
+		{name: "create", method: http.MethodPost, path: "api/v1/pods", want: "api/v1/pods"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := apirequest.Parse(tt.method, "/"+tt.path, nil)
+			assert.Equal(t, tt.want, c.withContextNamespace(kContext, info, tt.path))
+		})
+	}
+
+	// The contexts without a namespace aren't changed.
+	assert.Equal(t, "api/v1/pods", c.withContextNamespace(&kubeconfig.Context{Name: "minikube"}, list, "api/v1/pods"))
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// Node drain phases.
+const (
+	DrainPhaseCordoning = "cordoning"
+	DrainPhaseEvicting  = "evicting"
+	DrainPhaseSucceeded = "succeeded"
+	DrainPhaseFailed    = "failed"
+)
+
+// defaultDrainTimeout is how long a drain waits for the pods to be evicted, unless the request says otherwise.
+const defaultDrainTimeout = 5 * time.Minute
+
+// drainOptions are the options of a node drain request.
+type drainOptions struct {
+	// GracePeriodSeconds is given to the evicted pods, instead of their own termination grace period.
+	GracePeriodSeconds *int `json:"gracePeriodSeconds,omitempty"`
+	// TimeoutSeconds is how long to wait for the pods to be evicted, 0 for the default.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// Force evicts the pods without a controller, which are lost. It defaults to true.
+	Force *bool `json:"force,omitempty"`
+	// DeleteEmptyDirData evicts the pods with emptyDir volumes, whose data is lost. It defaults to true.
+	DeleteEmptyDirData *bool `json:"deleteEmptyDirData,omitempty"`
+}
+
+func (o drainOptions) helper(ctx context.Context, clientset kubernetes.Interface) *drain.Helper {
+	helper := &drain.Helper{
+		Ctx:                 ctx,
+		Client:              clientset,
+		Force:               o.Force == nil || *o.Force,
+		DeleteEmptyDirData:  o.DeleteEmptyDirData == nil || *o.DeleteEmptyDirData,
+		IgnoreAllDaemonSets: true,
+		GracePeriodSeconds:  -1,
+		Timeout:             defaultDrainTimeout,
+		Out:                 io.Discard,
+		ErrOut:              io.Discard,
+	}
+
+	if o.GracePeriodSeconds != nil {
+		helper.GracePeriodSeconds = *o.GracePeriodSeconds
+	}
+
+	if o.TimeoutSeconds > 0 {
+		helper.Timeout = time.Duration(o.TimeoutSeconds) * time.Second
+	}
+
+	return helper
+}
+
+// DrainProgress is the progress of a node drain. It is sent to the multiplexer clients that
+// subscribed to the drain with a DRAIN message.
+type DrainProgress struct {
+	Cluster string `json:"cluster"`
+	Node    string `json:"node"`
+	Phase   string `json:"phase"`
+	// Total is the number of pods to evict, DaemonSet and mirror pods are left on the node.
+	Total   int `json:"total"`
+	Evicted int `json:"evicted"`
+	// Pending are the pods, as namespace/name, still to be evicted.
+	Pending []string `json:"pending,omitempty"`
+	Warning string   `json:"warning,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// drainCacheKey is the key of the drain status of a node in the cache.
+func drainCacheKey(cluster, nodeName string) string {
+	return uuid.NewSHA1(uuid.Nil, []byte(nodeName+cluster)).String()
+}
+
+// drainTracker keeps the progress of the running drains and sends it to their subscribers.
+// The progress of a finished drain is kept for a grace period, like its cached status.
+type drainTracker struct {
+	mu          sync.Mutex
+	progress    map[string]DrainProgress
+	finished    map[string]time.Time
+	subscribers map[string]map[StreamClient]struct{}
+	gracePeriod time.Duration
+}
+
+func newDrainTracker() *drainTracker {
+	return &drainTracker{
+		progress:    map[string]DrainProgress{},
+		finished:    map[string]time.Time{},
+		subscribers: map[string]map[StreamClient]struct{}{},
+		gracePeriod: DrainNodeCacheTTL * time.Minute,
+	}
+}
+
+func drainTrackerKey(cluster, nodeName string) string {
+	return cluster + "/" + nodeName
+}
+
+// subscribe sends the progress of the drain of the node to the client, now and on every change.
+func (d *drainTracker) subscribe(cluster, nodeName string, client StreamClient) {
+	key := drainTrackerKey(cluster, nodeName)
+
+	d.mu.Lock()
+
+	if d.subscribers[key] == nil {
+		d.subscribers[key] = map[StreamClient]struct{}{}
+	}
+
+	d.subscribers[key][client] = struct{}{}
+	progress, ok := d.progress[key]
+
+	d.mu.Unlock()
+
+	if ok {
+		writeDrainProgress(client, progress)
+	}
+}
+
+// unsubscribe stops sending drain progress to the client.
+func (d *drainTracker) unsubscribe(client StreamClient) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, clients := range d.subscribers {
+		delete(clients, client)
+
+		if len(clients) == 0 {
+			delete(d.subscribers, key)
+		}
+	}
+}
+
+// get returns the last progress of the drain of the node.
+func (d *drainTracker) get(cluster, nodeName string) (DrainProgress, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	progress, ok := d.progress[drainTrackerKey(cluster, nodeName)]
+
+	return progress, ok
+}
+
+// publish records the progress of a drain and sends it to its subscribers. The clients are
+// written to without holding the lock, so that a slow client doesn't block the other drains.
+func (d *drainTracker) publish(progress DrainProgress) {
+	key := drainTrackerKey(progress.Cluster, progress.Node)
+
+	d.mu.Lock()
+
+	d.progress[key] = progress
+	delete(d.finished, key)
+
+	if progress.Phase == DrainPhaseSucceeded || progress.Phase == DrainPhaseFailed {
+		finishedAt := time.Now()
+		d.finished[key] = finishedAt
+
+		time.AfterFunc(d.gracePeriod, func() { d.forget(key, finishedAt) })
+	}
+
+	clients := make([]StreamClient, 0, len(d.subscribers[key]))
+	for client := range d.subscribers[key] {
+		clients = append(clients, client)
+	}
+
+	d.mu.Unlock()
+
+	for _, client := range clients {
+		writeDrainProgress(client, progress)
+	}
+}
+
+// forget removes the progress of the drain that finished at finishedAt, unless the node
+// was drained again since.
+func (d *drainTracker) forget(key string, finishedAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if at, ok := d.finished[key]; ok && at.Equal(finishedAt) {
+		delete(d.progress, key)
+		delete(d.finished, key)
+	}
+}
+
+func writeDrainProgress(client StreamClient, progress DrainProgress) {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"node": progress.Node}, err, "marshaling drain progress")
+
+		return
+	}
+
+	msg := Message{
+		ClusterID: progress.Cluster,
+		Path:      progress.Node,
+		Data:      string(data),
+		Type:      "DRAIN",
+	}
+
+	if err := client.WriteJSON(msg); err != nil &&
+		!websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		logger.Log(logger.LevelError, map[string]string{"node": progress.Node}, err, "writing drain progress to client")
+	}
+}
+
+// drainRun is a running drain, whose progress is updated from the eviction goroutines.
+type drainRun struct {
+	mu       sync.Mutex
+	progress DrainProgress
+	pending  map[string]struct{}
+	tracker  *drainTracker
+}
+
+func (r *drainRun) update(change func(progress *DrainProgress)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&r.progress)
+
+	r.progress.Pending = make([]string, 0, len(r.pending))
+	for pod := range r.pending {
+		r.progress.Pending = append(r.progress.Pending, pod)
+	}
+
+	sort.Strings(r.progress.Pending)
+
+	if r.tracker != nil {
+		r.tracker.publish(r.progress)
+	}
+}
+
+func (r *drainRun) evicted(pod *corev1.Pod, _ bool, err error) {
+	if err != nil {
+		return
+	}
+
+	r.update(func(progress *DrainProgress) {
+		delete(r.pending, pod.Namespace+"/"+pod.Name)
+
+		progress.Evicted++
+	})
+}
+
+// drainNode cordons the node and evicts its pods, respecting their PodDisruptionBudgets, in
+// the background. The result is cached for the drain-node-status endpoint, and the progress
+// is streamed over the multiplexer. done, if not nil, is called with the result.
+func (c *HeadlampConfig) drainNode(clientset kubernetes.Interface, nodeName string, cluster string, opts drainOptions,
+	done func(error),
+) {
+	run := &drainRun{
+		progress: DrainProgress{Cluster: cluster, Node: nodeName},
+		pending:  map[string]struct{}{},
+	}
+
+	if c.multiplexer != nil {
+		run.tracker = c.multiplexer.drains
+	}
+
+	go func() {
+		ctx := context.Background()
+		cacheKey := drainCacheKey(cluster, nodeName)
+		cacheItemTTL := DrainNodeCacheTTL * time.Minute
+
+		err := c.runDrain(ctx, clientset, run, opts)
+		if done != nil {
+			defer done(err)
+		}
+
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"cluster": cluster, "node": nodeName}, err, "draining node")
+
+			run.update(func(progress *DrainProgress) {
+				progress.Phase = DrainPhaseFailed
+				progress.Error = err.Error()
+			})
+
+			_ = c.cache.SetWithTTL(ctx, cacheKey, "error: "+err.Error(), cacheItemTTL)
+
+			return
+		}
+
+		run.update(func(progress *DrainProgress) { progress.Phase = DrainPhaseSucceeded })
+
+		_ = c.cache.SetWithTTL(ctx, cacheKey, "success", cacheItemTTL)
+	}()
+}
+
+func (c *HeadlampConfig) runDrain(ctx context.Context, clientset kubernetes.Interface, run *drainRun,
+	opts drainOptions,
+) error {
+	helper := opts.helper(ctx, clientset)
+	helper.OnPodDeletionOrEvictionFinished = run.evicted
+
+	run.update(func(progress *DrainProgress) { progress.Phase = DrainPhaseCordoning })
+
+	node, err := clientset.CoreV1().Nodes().Get(ctx, run.progress.Node, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := drain.RunCordonOrUncordon(helper, node, true); err != nil {
+		return err
+	}
+
+	list, errs := helper.GetPodsForDeletion(node.Name)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	pods := list.Pods()
+
+	run.update(func(progress *DrainProgress) {
+		progress.Phase = DrainPhaseEvicting
+		progress.Total = len(pods)
+		progress.Warning = list.Warnings()
+
+		for _, pod := range pods {
+			run.pending[pod.Namespace+"/"+pod.Name] = struct{}{}
+		}
+	})
+
+	return helper.DeleteOrEvictPods(pods)
+}
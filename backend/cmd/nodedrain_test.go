@@ -0,0 +1,214 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// recordingClient is a StreamClient keeping the messages written to it.
+type recordingClient struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (c *recordingClient) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, v.(Message))
+
+	return nil
+}
+
+func (c *recordingClient) Close() error {
+	return nil
+}
+
+func (c *recordingClient) phases(t *testing.T) []string {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	phases := make([]string, 0, len(c.messages))
+
+	for _, msg := range c.messages {
+		var progress DrainProgress
+
+		require.NoError(t, json.Unmarshal([]byte(msg.Data), &progress))
+
+		phases = append(phases, progress.Phase)
+	}
+
+	return phases
+}
+
+func testPod(name string, owner *v1.OwnerReference) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+
+	if owner != nil {
+		pod.OwnerReferences = []v1.OwnerReference{*owner}
+	}
+
+	return pod
+}
+
+func TestDrainNode(t *testing.T) {
+	isController := true
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node-1"}},
+		testPod("web", &v1.OwnerReference{Kind: "ReplicaSet", Name: "web", Controller: &isController}),
+		testPod("bare", nil),
+		testPod("agent", &v1.OwnerReference{
+			APIVersion: "apps/v1", Kind: "DaemonSet", Name: "agent", Controller: &isController,
+		}),
+		&appsv1.DaemonSet{ObjectMeta: v1.ObjectMeta{Name: "agent", Namespace: "default"}},
+	)
+
+	// The fake discovery has no resources unless told, and the evictions need to be found.
+	clientset.Resources = []*v1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []v1.APIResource{{
+			Name: drain.EvictionSubresource, Kind: drain.EvictionKind, Group: "policy", Version: "v1",
+		}},
+	}}
+
+	// Nor does the fake clientset delete the evicted pods.
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+
+		return true, nil, clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"),
+			eviction.Namespace, eviction.Name)
+	})
+
+	config := &HeadlampConfig{
+		cache:       cache.New[interface{}](),
+		multiplexer: NewMultiplexer(kubeconfig.NewContextStore()),
+	}
+
+	client := &recordingClient{}
+	config.multiplexer.drains.subscribe("test", "node-1", client)
+
+	gracePeriod := 0
+	config.drainNode(clientset, "node-1", "test", drainOptions{GracePeriodSeconds: &gracePeriod}, nil)
+
+	require.Eventually(t, func() bool {
+		status, err := config.cache.Get(context.Background(), drainCacheKey("test", "node-1"))
+		return err == nil && status == "success"
+	}, 10*time.Second, 50*time.Millisecond)
+
+	node, err := clientset.CoreV1().Nodes().Get(context.Background(), "node-1", v1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+
+	for _, name := range []string{"web", "bare"} {
+		_, err := clientset.CoreV1().Pods("default").Get(context.Background(), name, v1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err), name)
+	}
+
+	// DaemonSet pods are left on the node.
+	_, err = clientset.CoreV1().Pods("default").Get(context.Background(), "agent", v1.GetOptions{})
+	require.NoError(t, err)
+
+	progress, ok := config.multiplexer.drains.get("test", "node-1")
+	require.True(t, ok)
+	assert.Equal(t, DrainPhaseSucceeded, progress.Phase)
+	assert.Equal(t, 2, progress.Total)
+	assert.Equal(t, 2, progress.Evicted)
+	assert.Empty(t, progress.Pending)
+
+	phases := client.phases(t)
+	require.NotEmpty(t, phases)
+	assert.Equal(t, DrainPhaseCordoning, phases[0])
+	assert.Contains(t, phases, DrainPhaseEvicting)
+	assert.Equal(t, DrainPhaseSucceeded, phases[len(phases)-1])
+}
+
+func TestDrainNodeWithoutForce(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node-1"}},
+		testPod("bare", nil),
+	)
+
+	config := &HeadlampConfig{
+		cache:       cache.New[interface{}](),
+		multiplexer: NewMultiplexer(kubeconfig.NewContextStore()),
+	}
+
+	force := false
+	config.drainNode(clientset, "node-1", "test", drainOptions{Force: &force}, nil)
+
+	require.Eventually(t, func() bool {
+		progress, ok := config.multiplexer.drains.get("test", "node-1")
+		return ok && progress.Phase == DrainPhaseFailed
+	}, 10*time.Second, 50*time.Millisecond)
+
+	// The pod without a controller is not evicted.
+	_, err := clientset.CoreV1().Pods("default").Get(context.Background(), "bare", v1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestDrainTrackerForgetsFinishedDrains(t *testing.T) {
+	tracker := newDrainTracker()
+	tracker.gracePeriod = 50 * time.Millisecond
+
+	tracker.publish(DrainProgress{Cluster: "test", Node: "node-1", Phase: DrainPhaseEvicting})
+	tracker.publish(DrainProgress{Cluster: "test", Node: "node-2", Phase: DrainPhaseSucceeded})
+	tracker.publish(DrainProgress{Cluster: "test", Node: "node-3", Phase: DrainPhaseFailed})
+
+	// The drain of node-3 started again before the grace period of the failed one ended.
+	tracker.publish(DrainProgress{Cluster: "test", Node: "node-3", Phase: DrainPhaseCordoning})
+
+	_, ok := tracker.get("test", "node-2")
+	require.True(t, ok)
+
+	require.Eventually(t, func() bool {
+		_, ok := tracker.get("test", "node-2")
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, node := range []string{"node-1", "node-3"} {
+		_, ok := tracker.get("test", node)
+		assert.True(t, ok, node)
+	}
+}
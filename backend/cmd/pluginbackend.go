@@ -0,0 +1,87 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// startPluginBackends starts the backend processes of the user plugins, if enabled. In
+// the enforce plugin verification mode, only the verified plugins get started.
+func (c *HeadlampConfig) startPluginBackends() {
+	if c.pluginBackends == nil {
+		return
+	}
+
+	c.pluginBackends.Authorize = checkHeadlampBackendToken
+
+	if !c.enablePluginBackends {
+		return
+	}
+
+	var allowed func(dir string) bool
+	if c.pluginVerifier != nil {
+		allowed = c.pluginVerifier.Allowed
+	}
+
+	c.pluginBackends.StartAll(context.Background(), c.PluginDir, allowed)
+}
+
+// pluginBackendPath returns the plugin name and the path relative to /plugins/{name}/api
+// of a request to the endpoints of a plugin backend, or false if it isn't one.
+func (c *HeadlampConfig) pluginBackendPath(r *http.Request) (string, string, bool) {
+	pluginPath, found := strings.CutPrefix(r.URL.Path, c.BaseURL+"/plugins/")
+	if !found {
+		return "", "", false
+	}
+
+	name, rest, _ := strings.Cut(pluginPath, "/")
+
+	apiPath, found := strings.CutPrefix(rest, "api")
+	if !found || (apiPath != "" && !strings.HasPrefix(apiPath, "/")) {
+		return "", "", false
+	}
+
+	return name, apiPath, true
+}
+
+// addPluginBackendRoutes registers the endpoints contributed by plugin backends under
+// /plugins/{name}/api. They only match the plugins with a backend, so the files of the
+// other plugins are served as usual.
+func addPluginBackendRoutes(config *HeadlampConfig, r *mux.Router) {
+	if config.pluginBackends == nil {
+		return
+	}
+
+	r.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		name, _, ok := config.pluginBackendPath(r)
+
+		return ok && config.pluginBackends.Has(name)
+	}).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, apiPath, _ := config.pluginBackendPath(r)
+
+		req := r.Clone(r.Context())
+		req.URL.Path = "/" + strings.TrimPrefix(apiPath, "/")
+		req.URL.RawPath = ""
+
+		config.pluginBackends.Serve(w, req, name)
+	})
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginBackendRoutes(t *testing.T) {
+	backends := plugins.NewBackends()
+	require.NoError(t, backends.Register("my-plugin", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("backend " + r.URL.Path))
+	}), plugins.BackendOptions{Auth: plugins.BackendAuthNone}))
+
+	c := &HeadlampConfig{
+		HeadlampCFG:    &headlampconfig.HeadlampCFG{BaseURL: "/headlamp"},
+		pluginBackends: backends,
+	}
+
+	r := mux.NewRouter().PathPrefix(c.BaseURL).Subrouter()
+	addPluginBackendRoutes(c, r)
+	r.PathPrefix("/plugins/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("files"))
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/headlamp/plugins/my-plugin/api", want: "backend /"},
+		{path: "/headlamp/plugins/my-plugin/api/items/1", want: "backend /items/1"},
+		{path: "/headlamp/plugins/my-plugin/api.js", want: "files"},
+		{path: "/headlamp/plugins/my-plugin/main.js", want: "files"},
+		{path: "/headlamp/plugins/other-plugin/api/items", want: "files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.want, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+)
+
+// ociPluginsInstallTimeout is how long installing the plugins of oci-plugins can take on startup.
+const ociPluginsInstallTimeout = 2 * time.Minute
+
+// pluginCatalogRequest is the payload to install or upgrade a plugin from an OCI registry.
+type pluginCatalogRequest struct {
+	// Ref is the artifact reference, like ghcr.io/org/plugin:1.0.0. It is optional
+	// on upgrades, which then use the ref the plugin was installed from.
+	Ref string `json:"ref"`
+}
+
+// pluginCatalogStatus is the status of a plugin of the catalog.
+type pluginCatalogStatus struct {
+	Source   *plugins.Source   `json:"source,omitempty"`
+	Progress *plugins.Progress `json:"progress,omitempty"`
+}
+
+// installOCIPlugins installs the plugins of oci-plugins that are missing or come from another ref.
+func (c *HeadlampConfig) installOCIPlugins() {
+	if c.pluginCatalog == nil || len(c.ociPlugins) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ociPluginsInstallTimeout)
+	defer cancel()
+
+	c.pluginCatalog.EnsureInstalled(ctx, c.ociPlugins)
+}
+
+// addPluginCatalogRoutes registers the endpoints to install, upgrade and uninstall
+// plugins from OCI registries. Installs and upgrades run in the background, their
+// progress is given by GET /plugin-catalog/{name}.
+func addPluginCatalogRoutes(config *HeadlampConfig, r *mux.Router) {
+	r.HandleFunc("/plugin-catalog", config.handlePluginCatalogList).Methods("GET")
+	r.HandleFunc("/plugin-catalog/{name}", config.handlePluginCatalogStatus).Methods("GET")
+	r.HandleFunc("/plugin-catalog/{name}", config.handlePluginCatalogInstall).Methods("POST", "PUT")
+	r.HandleFunc("/plugin-catalog/{name}", config.handlePluginCatalogUninstall).Methods("DELETE")
+}
+
+func (c *HeadlampConfig) handlePluginCatalogList(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	installed, err := c.pluginCatalog.Installed()
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "listing catalog plugins")
+		http.Error(w, "listing plugins", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(installed); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding catalog plugins")
+	}
+}
+
+func (c *HeadlampConfig) handlePluginCatalogStatus(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	var status pluginCatalogStatus
+
+	if source, err := c.pluginCatalog.Source(name); err == nil {
+		status.Source = &source
+	}
+
+	if progress, ok := c.pluginCatalog.Progress(name); ok {
+		status.Progress = &progress
+	}
+
+	if status.Source == nil && status.Progress == nil {
+		http.Error(w, "plugin not found", http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding plugin status")
+	}
+}
+
+// handlePluginCatalogInstall installs the plugin on POST, and upgrades it on PUT.
+func (c *HeadlampConfig) handlePluginCatalogInstall(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	upgrade := r.Method == http.MethodPut
+
+	var req pluginCatalogRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid plugin catalog payload: "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := plugins.ValidateName(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if req.Ref != "" || !upgrade {
+		if _, err := plugins.ParseReference(req.Ref); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	if upgrade {
+		if _, err := c.pluginCatalog.Source(name); err != nil {
+			pluginCatalogError(w, r, err)
+
+			return
+		}
+	} else if _, err := os.Stat(filepath.Join(c.PluginDir, name)); err == nil {
+		pluginCatalogError(w, r, plugins.ErrPluginExists)
+
+		return
+	}
+
+	if progress, ok := c.pluginCatalog.Progress(name); ok &&
+		progress.Phase != plugins.PhaseInstalled && progress.Phase != plugins.PhaseFailed {
+		pluginCatalogError(w, r, plugins.ErrOperationInProgress)
+
+		return
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{"plugin": name, "ref": req.Ref},
+		nil, "installing plugin from registry")
+
+	go func() {
+		// The errors are logged, and given by the progress.
+		if upgrade {
+			_ = c.pluginCatalog.Upgrade(context.Background(), name, req.Ref)
+		} else {
+			_ = c.pluginCatalog.Install(context.Background(), name, req.Ref)
+		}
+	}()
+
+	w.Header().Set("Location", c.BaseURL+"/plugin-catalog/"+name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *HeadlampConfig) handlePluginCatalogUninstall(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	if err := c.pluginCatalog.Uninstall(name); err != nil {
+		pluginCatalogError(w, r, err)
+
+		return
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{"plugin": name}, nil, "plugin uninstalled")
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// pluginCatalogError writes the response for an error of the plugin catalog.
+func pluginCatalogError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, plugins.ErrPluginNotFromCatalog):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, plugins.ErrPluginExists), errors.Is(err, plugins.ErrOperationInProgress):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "plugin catalog")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakePluginRegistry serves a plugin bundle as org/plugin:1.0.0.
+func newFakePluginRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "main.js", Mode: 0o644, Size: 2, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("v1"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	bundle := buf.Bytes()
+	sum := sha256.Sum256(bundle)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"layers": []map[string]interface{}{{
+			"mediaType": "application/vnd.headlamp.plugin.v1.tar+gzip",
+			"digest":    digest,
+			"size":      len(bundle),
+		}},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/org/plugin/manifests/1.0.0":
+			_, _ = w.Write(manifest)
+		case "/v2/org/plugin/blobs/" + digest:
+			_, _ = w.Write(bundle)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestPluginCatalogRoutes(t *testing.T) {
+	registry := newFakePluginRegistry(t)
+	ref := strings.TrimPrefix(registry.URL, "https://") + "/org/plugin:1.0.0"
+
+	pluginDir := filepath.Join(t.TempDir(), "plugins")
+	catalog := plugins.NewCatalog(pluginDir, t.TempDir())
+	catalog.Client = registry.Client()
+
+	c := &HeadlampConfig{
+		HeadlampCFG:   &headlampconfig.HeadlampCFG{PluginDir: pluginDir},
+		pluginCatalog: catalog,
+	}
+
+	r := mux.NewRouter()
+	addPluginCatalogRoutes(c, r)
+
+	rr, err := getResponseFromRestrictedEndpoint(r, "POST", "/plugin-catalog/my-plugin",
+		pluginCatalogRequest{Ref: "org/plugin"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(r, "POST", "/plugin-catalog/my-plugin", pluginCatalogRequest{Ref: ref})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/plugin-catalog/my-plugin", rr.Header().Get("Location"))
+
+	assert.Eventually(t, func() bool {
+		rr, err := getResponseFromRestrictedEndpoint(r, "GET", "/plugin-catalog/my-plugin", nil)
+		if err != nil || rr.Code != http.StatusOK {
+			return false
+		}
+
+		var status pluginCatalogStatus
+
+		return json.Unmarshal(rr.Body.Bytes(), &status) == nil && status.Progress != nil &&
+			status.Progress.Phase == plugins.PhaseInstalled
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rr, err = getResponseFromRestrictedEndpoint(r, "POST", "/plugin-catalog/my-plugin", pluginCatalogRequest{Ref: ref})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(r, "GET", "/plugin-catalog", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var installed []plugins.Source
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &installed))
+	require.Len(t, installed, 1)
+	assert.Equal(t, ref, installed[0].Ref)
+
+	rr, err = getResponseFromRestrictedEndpoint(r, "DELETE", "/plugin-catalog/my-plugin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(r, "DELETE", "/plugin-catalog/my-plugin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(r, "PUT", "/plugin-catalog/my-plugin", pluginCatalogRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
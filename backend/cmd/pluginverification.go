@@ -0,0 +1,76 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+)
+
+// handlePluginVerification verifies the installed plugins and returns their verification status.
+// It requires the backend token.
+func (c *HeadlampConfig) handlePluginVerification(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	results, err := c.pluginVerifier.VerifyAll(c.StaticPluginDir, c.PluginDir)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "verifying plugins")
+		http.Error(w, "verifying plugins", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding plugin verifications")
+	}
+}
+
+// verifiedPluginsHandler serves the files of the plugins of dir with next, only if the plugin
+// can be served by the verification policy. The request path starts with the plugin name,
+// and paths without one are not found.
+func verifiedPluginsHandler(verifier *plugins.Verifier, dir string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+		if name == "" || name == "." || name == ".." {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if !verifier.Allowed(filepath.Join(dir, name)) {
+			logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"plugin": name},
+				nil, "not serving unverified plugin")
+			http.Error(w, "plugin not verified", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
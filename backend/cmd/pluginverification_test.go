@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifiedPluginsHandler(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "key.pub")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	pluginDir := t.TempDir()
+
+	for _, name := range []string{"signed", "unsigned"} {
+		require.NoError(t, os.Mkdir(filepath.Join(pluginDir, name), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(pluginDir, name, "main.js"), []byte(name), 0o600))
+	}
+
+	manifest, err := plugins.Manifest(filepath.Join(pluginDir, "signed"))
+	require.NoError(t, err)
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, manifest))
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "signed", plugins.SignatureFile),
+		[]byte(signature), 0o600))
+
+	verifier, err := plugins.NewVerifier(plugins.PolicyEnforce, keyFile)
+	require.NoError(t, err)
+
+	handler := verifiedPluginsHandler(verifier, pluginDir, http.FileServer(http.Dir(pluginDir)))
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{path: "/signed/main.js", code: http.StatusOK},
+		{path: "/unsigned/main.js", code: http.StatusForbidden},
+		{path: "/unsigned/", code: http.StatusForbidden},
+		{path: "/", code: http.StatusNotFound},
+		{path: "//signed/main.js", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/doctor"
+	"k8s.io/client-go/rest"
+)
+
+// The preflight checks of the backend, besides the ones of the contexts.
+const (
+	PreflightConfig          = "config"
+	PreflightTLS             = "tls"
+	PreflightListen          = "listen"
+	PreflightInCluster       = "in-cluster"
+	PreflightKubeConfigs     = "kubeconfigs"
+	PreflightDynamicClusters = "dynamic-clusters-dir"
+	PreflightPluginsDir      = "plugins-dir"
+	PreflightPluginCacheDir  = "plugin-cache-dir"
+	PreflightLogFile         = "log-file"
+	PreflightAuditLog        = "audit-log"
+)
+
+// PreflightReport is the report of --preflight.
+type PreflightReport struct {
+	// Status is the worst status of the checks and of the contexts.
+	Status doctor.Status  `json:"status"`
+	Checks []doctor.Check `json:"checks"`
+	// Contexts are the reports of the contexts of the kubeconfigs, checked without connecting
+	// to their clusters.
+	Contexts []doctor.Report `json:"contexts"`
+}
+
+// runPreflight checks that the backend can start with conf, and that the contexts it would
+// load are usable, without starting it. It prints the report as JSON to out, and returns the
+// exit code, 1 if a check failed.
+func runPreflight(conf *config.Config, out io.Writer) int {
+	report := preflight(conf)
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+
+		return 1
+	}
+
+	if report.Status == doctor.StatusError {
+		return 1
+	}
+
+	return 0
+}
+
+// preflight runs the preflight checks of conf.
+func preflight(conf *config.Config) PreflightReport {
+	report := PreflightReport{Checks: []doctor.Check{}, Contexts: []doctor.Report{}}
+
+	// add returns the function adding the result of the check with the name to the report, so
+	// it can take the results of the preflight functions as they are.
+	add := func(name string) func(doctor.Status, string) {
+		return func(status doctor.Status, message string) {
+			report.Checks = append(report.Checks, doctor.Check{Name: name, Status: status, Message: message})
+		}
+	}
+
+	if err := conf.Validate(); err != nil {
+		add(PreflightConfig)(doctor.StatusError, err.Error())
+	} else {
+		add(PreflightConfig)(doctor.StatusOK, "valid")
+	}
+
+	add(PreflightTLS)(preflightTLS(conf))
+	add(PreflightListen)(preflightListen(conf))
+
+	if conf.InCluster {
+		if _, err := rest.InClusterConfig(); err != nil {
+			add(PreflightInCluster)(doctor.StatusError, err.Error())
+		} else {
+			add(PreflightInCluster)(doctor.StatusOK, "running in a cluster")
+		}
+	}
+
+	// The in-cluster backends may have no kubeconfig.
+	if conf.KubeConfigPath == "" {
+		add(PreflightKubeConfigs)(doctor.StatusSkipped, "no kubeconfig")
+	} else if contexts, err := doctorContexts(conf); err != nil {
+		add(PreflightKubeConfigs)(doctor.StatusError, err.Error())
+	} else {
+		add(PreflightKubeConfigs)(doctor.StatusOK, fmt.Sprintf("%d contexts loaded", len(contexts)))
+
+		offline := doctor.New()
+		offline.Offline = true
+
+		report.Contexts = offline.CheckAll(context.Background(), contexts)
+	}
+
+	if conf.EnableDynamicClusters {
+		add(PreflightDynamicClusters)(preflightDynamicClustersDir())
+	}
+
+	if conf.PluginsDir != "" {
+		add(PreflightPluginsDir)(preflightDir(conf.PluginsDir, false))
+	}
+
+	if conf.PluginCacheDir != "" {
+		add(PreflightPluginCacheDir)(preflightDir(conf.PluginCacheDir, true))
+	}
+
+	if conf.LogFile != "" {
+		add(PreflightLogFile)(preflightDir(filepath.Dir(conf.LogFile), true))
+	}
+
+	if conf.AuditLog != "" {
+		add(PreflightAuditLog)(preflightDir(filepath.Dir(conf.AuditLog), true))
+	}
+
+	report.Status = doctor.WorstStatus(report.Checks)
+	for _, contextReport := range report.Contexts {
+		if contextReport.Status == doctor.StatusError {
+			report.Status = doctor.StatusError
+		}
+	}
+
+	return report
+}
+
+// preflightTLS checks that the TLS certificate and key of the server can be loaded.
+func preflightTLS(conf *config.Config) (doctor.Status, string) {
+	if conf.TLSCertPath == "" || conf.TLSKeyPath == "" {
+		return doctor.StatusSkipped, "no TLS certificate"
+	}
+
+	if _, err := tls.LoadX509KeyPair(conf.TLSCertPath, conf.TLSKeyPath); err != nil {
+		return doctor.StatusError, fmt.Sprintf("loading the TLS certificate: %v", err)
+	}
+
+	return doctor.StatusOK, "TLS certificate loaded"
+}
+
+// preflightListen checks that the server can listen on its port, or its socket.
+func preflightListen(conf *config.Config) (doctor.Status, string) {
+	if conf.ListenSocket != "" {
+		stale, err := staleSocket(conf.ListenSocket)
+		if err != nil {
+			return doctor.StatusError, err.Error()
+		}
+
+		status, message := preflightDir(filepath.Dir(conf.ListenSocket), true)
+		if stale && status == doctor.StatusOK {
+			// The stale socket is left for the server to replace.
+			return doctor.StatusWarning, conf.ListenSocket + " is a stale socket, replaced on startup"
+		}
+
+		return status, message
+	}
+
+	addr := net.JoinHostPort(conf.ListenAddr, fmt.Sprint(conf.Port))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return doctor.StatusError, fmt.Sprintf("listening on %s: %v", addr, err)
+	}
+
+	listener.Close()
+
+	return doctor.StatusOK, addr + " is available"
+}
+
+// preflightDynamicClustersDir checks that the dynamic clusters can be stored. A missing
+// directory is reported, not created.
+func preflightDynamicClustersDir() (doctor.Status, string) {
+	dir, err := config.HeadlampKubeConfigsDir()
+	if err != nil {
+		return doctor.StatusError, err.Error()
+	}
+
+	return preflightDir(dir, true)
+}
+
+// preflightDir checks that dir is a directory which can be read, and written to if writable.
+// A missing directory is only a warning if its closest existing parent can be written to, as
+// it's created when needed.
+func preflightDir(dir string, writable bool) (doctor.Status, string) {
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		if status, _ := preflightDir(filepath.Dir(dir), true); status != doctor.StatusError {
+			return doctor.StatusWarning, dir + " doesn't exist yet"
+		}
+
+		return doctor.StatusError, dir + " doesn't exist and can't be created"
+	}
+
+	if err != nil {
+		return doctor.StatusError, err.Error()
+	}
+
+	if !info.IsDir() {
+		return doctor.StatusError, dir + " is not a directory"
+	}
+
+	if _, err := os.ReadDir(dir); err != nil {
+		return doctor.StatusError, fmt.Sprintf("reading %s: %v", dir, err)
+	}
+
+	if !writable {
+		return doctor.StatusOK, dir + " is readable"
+	}
+
+	file, err := os.CreateTemp(dir, ".headlamp-preflight-*")
+	if err != nil {
+		return doctor.StatusError, fmt.Sprintf("writing to %s: %v", dir, err)
+	}
+
+	file.Close()
+	os.Remove(file.Name())
+
+	return doctor.StatusOK, dir + " is writable"
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/doctor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func preflightCheck(t *testing.T, report PreflightReport, name string) doctor.Check {
+	t.Helper()
+
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+
+	t.Fatalf("no %s check in %+v", name, report.Checks)
+
+	return doctor.Check{}
+}
+
+const preflightKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: local
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: local
+  context:
+    cluster: local
+    user: admin
+`
+
+func TestRunPreflight(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	kubeConfigPath := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfigPath, []byte(preflightKubeConfig), 0o600))
+
+	conf, err := config.ParseUnvalidated([]string{
+		"headlamp",
+		"--preflight",
+		"--listen-addr=127.0.0.1",
+		"--port=0",
+		"--kubeconfig=" + kubeConfigPath,
+		"--plugins-dir=" + t.TempDir(),
+		"--log-file=" + filepath.Join(t.TempDir(), "logs", "headlamp.log"),
+		"--enable-dynamic-clusters",
+	})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+
+	code := runPreflight(conf, &out)
+
+	var report PreflightReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+
+	assert.Equal(t, 0, code, out.String())
+	assert.NotEqual(t, doctor.StatusError, report.Status)
+	assert.Len(t, report.Contexts, 1)
+	assert.Equal(t, doctor.StatusOK, preflightCheck(t, report, PreflightListen).Status)
+	assert.Equal(t, doctor.StatusOK, preflightCheck(t, report, PreflightKubeConfigs).Status)
+	assert.Equal(t, doctor.StatusOK, preflightCheck(t, report, PreflightPluginsDir).Status)
+	assert.Equal(t, doctor.StatusWarning, preflightCheck(t, report, PreflightLogFile).Status)
+	assert.Equal(t, doctor.StatusSkipped, preflightCheck(t, report, PreflightTLS).Status)
+
+	// The missing dynamic clusters directory is reported, not created.
+	assert.Equal(t, doctor.StatusWarning, preflightCheck(t, report, PreflightDynamicClusters).Status)
+
+	dir, err := config.HeadlampKubeConfigsDir()
+	require.NoError(t, err)
+	assert.NoDirExists(t, dir)
+
+	for _, contextReport := range report.Contexts {
+		for _, check := range contextReport.Checks {
+			if check.Name == doctor.CheckTCP {
+				assert.Equal(t, doctor.StatusSkipped, check.Status)
+			}
+		}
+	}
+}
+
+func TestRunPreflightErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	conf, err := config.ParseUnvalidated([]string{
+		"headlamp",
+		"--preflight",
+		"--listen-addr=127.0.0.1",
+		"--port=" + strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+		"--kubeconfig=" + filepath.Join(t.TempDir(), "missing"),
+		"--tls-cert-path=./headlamp_testdata/headlamp.crt",
+		"--tls-key-path=./headlamp_testdata/missing.key",
+	})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+
+	assert.Equal(t, 1, runPreflight(conf, &out))
+
+	var report PreflightReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+
+	assert.Equal(t, doctor.StatusError, report.Status)
+	assert.Equal(t, doctor.StatusError, preflightCheck(t, report, PreflightListen).Status)
+	assert.Equal(t, doctor.StatusError, preflightCheck(t, report, PreflightTLS).Status)
+}
+
+func TestPreflightListenStaleSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket permissions are not supported on windows")
+	}
+
+	path := socketPath(t)
+	conf := &config.Config{ListenSocket: path}
+
+	listener, err := listenSocket(path, 0o600)
+	require.NoError(t, err)
+
+	status, _ := preflightListen(conf)
+	assert.Equal(t, doctor.StatusError, status)
+
+	unixListener, ok := listener.(*net.UnixListener)
+	require.True(t, ok)
+	unixListener.SetUnlinkOnClose(false)
+	require.NoError(t, listener.Close())
+
+	// The stale socket is reported, not removed.
+	status, message := preflightListen(conf)
+	assert.Equal(t, doctor.StatusWarning, status)
+	assert.Contains(t, message, "stale socket")
+	assert.FileExists(t, path)
+}
+
+func TestPreflightDir(t *testing.T) {
+	dir := t.TempDir()
+
+	status, _ := preflightDir(dir, true)
+	assert.Equal(t, doctor.StatusOK, status)
+
+	status, _ = preflightDir(filepath.Join(dir, "missing"), true)
+	assert.Equal(t, doctor.StatusWarning, status)
+
+	status, _ = preflightDir(filepath.Join(dir, "missing", "nested"), true)
+	assert.Equal(t, doctor.StatusWarning, status)
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	status, _ = preflightDir(filepath.Join(file, "nested"), true)
+	assert.Equal(t, doctor.StatusError, status)
+
+	status, message := preflightDir(file, false)
+	assert.Equal(t, doctor.StatusError, status)
+	assert.Contains(t, message, "not a directory")
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// reloadConfig parses the configuration again and reloads what can be changed without a
+// restart: the log level, the API tokens and the kubeconfig contexts. The kubeconfig
+// contexts are reconciled like the file watcher does, so the connections to the contexts
+// which are kept are not dropped. It returns the new configuration, or current if it
+// cannot be parsed.
+func (c *HeadlampConfig) reloadConfig(current *config.Config, args []string) (*config.Config, error) {
+	conf, err := config.Parse(args)
+	if err != nil {
+		return current, fmt.Errorf("parsing config: %w", err)
+	}
+
+	var errs []error
+
+	if err := logger.SetLevel(conf.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("setting log level: %w", err))
+	}
+
+	if c.apiTokens != nil {
+		if err := c.apiTokens.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("reloading API tokens: %w", err))
+		}
+	}
+
+	skipFunc := kubeconfig.SkipKubeContextInCommaSeparatedString(c.SkippedKubeContexts)
+
+	err = kubeconfig.SyncContexts(c.KubeConfigStore, c.KubeConfigPath, kubeconfig.KubeConfig, skipFunc)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("reloading kubeconfig: %w", err))
+	}
+
+	if err := c.reloadDynamicClusters(skipFunc); err != nil {
+		errs = append(errs, err)
+	}
+
+	if restartRequired(current, conf) {
+		logger.Log(logger.LevelWarn, nil, nil, "configuration changes other than log-level need a restart")
+	}
+
+	return conf, errors.Join(errs...)
+}
+
+// reloadDynamicClusters loads the dynamic clusters file again, if there is one. The dynamic
+// clusters are only added or updated, since the ones added without being persisted are not in the file.
+func (c *HeadlampConfig) reloadDynamicClusters(skipFunc func(kubeconfig.Context) bool) error {
+	path, err := defaultHeadlampKubeConfigFile()
+	if err != nil {
+		return fmt.Errorf("getting dynamic clusters file: %w", err)
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	err = kubeconfig.LoadAndStoreKubeConfigs(c.KubeConfigStore, path, kubeconfig.DynamicCluster, skipFunc)
+	if err != nil {
+		return fmt.Errorf("reloading dynamic clusters: %w", err)
+	}
+
+	return nil
+}
+
+// restartRequired returns whether a setting which is only read at start changed.
+func restartRequired(current, conf *config.Config) bool {
+	a, b := *current, *conf
+	a.LogLevel, b.LogLevel = "", ""
+
+	return !reflect.DeepEqual(a, b)
+}
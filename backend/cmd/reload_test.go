@@ -0,0 +1,90 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestReloadConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	previousLevel := logger.GetLevel()
+	t.Cleanup(func() { _ = logger.SetLevel(previousLevel) })
+
+	kubeConfigData, err := os.ReadFile("./headlamp_testdata/kubeconfig")
+	require.NoError(t, err)
+
+	kubeConfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeConfigPath, kubeConfigData, 0o600))
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, kubeconfig.LoadAndStoreKubeConfigs(store, kubeConfigPath, kubeconfig.KubeConfig, nil))
+
+	c := &HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			KubeConfigStore: store,
+			KubeConfigPath:  kubeConfigPath,
+		},
+	}
+
+	args := []string{"headlamp-server", "--kubeconfig=" + kubeConfigPath}
+
+	current, err := config.Parse(args)
+	require.NoError(t, err)
+
+	// Remove a context from the kubeconfig, and change the log level.
+	kubeConfig, err := clientcmd.LoadFromFile(kubeConfigPath)
+	require.NoError(t, err)
+
+	delete(kubeConfig.Contexts, "docker-desktop")
+	require.NoError(t, clientcmd.WriteToFile(*kubeConfig, kubeConfigPath))
+
+	conf, err := c.reloadConfig(current, append(args, "--log-level=warn"))
+	require.NoError(t, err)
+	assert.Equal(t, "warn", conf.LogLevel)
+	assert.Equal(t, "warn", logger.GetLevel())
+
+	_, err = store.GetContext("docker-desktop")
+	assert.Error(t, err)
+
+	_, err = store.GetContext("minikube")
+	assert.NoError(t, err)
+
+	// An invalid configuration keeps the current one.
+	conf, err = c.reloadConfig(conf, append(args, "--base-url=headlamp"))
+	assert.Error(t, err)
+	assert.Equal(t, "warn", conf.LogLevel)
+}
+
+func TestRestartRequired(t *testing.T) {
+	current := &config.Config{LogLevel: "info", Port: 4466}
+
+	assert.False(t, restartRequired(current, &config.Config{LogLevel: "debug", Port: 4466}))
+	assert.True(t, restartRequired(current, &config.Config{LogLevel: "info", Port: 4467}))
+}
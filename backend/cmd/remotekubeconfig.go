@@ -0,0 +1,82 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// remoteKubeConfigTimeout is the timeout of the requests fetching the kubeconfigs registered by URL.
+const remoteKubeConfigTimeout = 30 * time.Second
+
+// handleKubeConfigURLs lists the kubeconfigs registered by URL on GET, registers one on POST,
+// with a body like {"url": "https://...", "authHeader": "Bearer ..."}, and unregisters the one
+// of the url query parameter on DELETE. The auth headers are never returned.
+func (c *HeadlampConfig) handleKubeConfigURLs(w http.ResponseWriter, r *http.Request) {
+	if err := checkHeadlampBackendToken(w, r); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "invalid token")
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var source kubeconfig.RemoteSource
+		if err := json.NewDecoder(r.Body).Decode(&source); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+
+			return
+		}
+
+		if err := c.remoteKubeConfigs.Add(r.Context(), source); err != nil {
+			logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"url": source.URL},
+				err, "registering kubeconfig URL")
+
+			status := http.StatusBadGateway
+			if errors.Is(err, kubeconfig.ErrInvalidRemoteURL) {
+				status = http.StatusBadRequest
+			}
+
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if err := c.remoteKubeConfigs.Remove(r.URL.Query().Get("url")); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	if err := json.NewEncoder(w).Encode(c.remoteKubeConfigs.List()); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding kubeconfig URLs")
+	}
+}
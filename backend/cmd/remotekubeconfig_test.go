@@ -0,0 +1,101 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleKubeConfigURLs(t *testing.T) {
+	config := api.NewConfig()
+	config.Clusters["portal"] = &api.Cluster{Server: "https://portal.example.com"}
+	config.AuthInfos["portal"] = &api.AuthInfo{Token: "token"}
+	config.Contexts["portal-prod"] = &api.Context{Cluster: "portal", AuthInfo: "portal"}
+	config.Contexts["portal-staging"] = &api.Context{Cluster: "portal", AuthInfo: "portal"}
+
+	kubeConfigByte, err := clientcmd.Write(*config)
+	require.NoError(t, err)
+
+	portal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(kubeConfigByte)
+	}))
+	t.Cleanup(portal.Close)
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			EnableDynamicClusters: true,
+			KubeConfigStore:       kubeConfigStore,
+		},
+		cache:             cache.New[interface{}](),
+		telemetryConfig:   GetDefaultTestTelemetryConfig(),
+		telemetryHandler:  &telemetry.RequestHandler{},
+		remoteKubeConfigs: kubeconfig.NewRemoteSources(kubeConfigStore, portal.Client()),
+	}
+	handler := createHeadlampHandler(&c)
+
+	rr, err := getResponseFromRestrictedEndpoint(handler, "POST", "/kubeconfig-urls",
+		kubeconfig.RemoteSource{URL: portal.URL, AuthHeader: "Bearer secret"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), "secret")
+
+	kContext, err := kubeConfigStore.GetContext("portal-prod")
+	require.NoError(t, err)
+	assert.Equal(t, "remote", kContext.SourceStr())
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "GET", "/kubeconfig-urls", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var statuses []kubeconfig.RemoteSourceStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.ElementsMatch(t, []string{"portal-prod", "portal-staging"}, statuses[0].Contexts)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "POST", "/kubeconfig-urls",
+		kubeconfig.RemoteSource{URL: "http://example.com/kubeconfig"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "DELETE",
+		"/kubeconfig-urls?url="+url.QueryEscape(portal.URL), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	_, err = kubeConfigStore.GetContext("portal-prod")
+	require.Error(t, err)
+
+	rr, err = getResponseFromRestrictedEndpoint(handler, "DELETE",
+		"/kubeconfig-urls?url="+url.QueryEscape(portal.URL), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
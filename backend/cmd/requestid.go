@@ -0,0 +1,62 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// maxRequestIDLength is the maximum length of a request ID given by the client.
+const maxRequestIDLength = 128
+
+// requestIDMiddleware gives every request an ID, which is carried by the request context
+// for logger.LogCtx, returned to the client in the X-Request-Id header, and forwarded to
+// the cluster when the request is proxied. A valid ID set by the client, or a proxy in
+// front of Headlamp, is kept.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(logger.RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		r.Header.Set(logger.RequestIDHeader, requestID)
+		w.Header().Set(logger.RequestIDHeader, requestID)
+
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// isValidRequestID tells whether a request ID given by the client can be used as is.
+// It must be reasonably short and printable, so it is safe to log and to send back.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		isAlphanumeric := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphanumeric && c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var contextID, headerID string
+
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = logger.RequestIDFromContext(r.Context())
+		headerID = r.Header.Get(logger.RequestIDHeader)
+	}))
+
+	// A new ID is generated.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+	requestID := rr.Header().Get(logger.RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, contextID)
+	assert.Equal(t, requestID, headerID, "the ID is forwarded upstream")
+
+	// A valid ID of the client is kept.
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.Header.Set(logger.RequestIDHeader, "client-id.1")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "client-id.1", rr.Header().Get(logger.RequestIDHeader))
+	assert.Equal(t, "client-id.1", contextID)
+
+	// An invalid one is replaced.
+	req = httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.Header.Set(logger.RequestIDHeader, "bad id\n")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.NotEqual(t, "bad id\n", rr.Header().Get(logger.RequestIDHeader))
+	assert.Equal(t, rr.Header().Get(logger.RequestIDHeader), contextID)
+}
+
+func TestIsValidRequestID(t *testing.T) {
+	assert.True(t, isValidRequestID("0b7e1a2c-7d4e-4d7b-9d6c-2f1d3f8b9a10"))
+	assert.True(t, isValidRequestID("trace:abc_1.2"))
+	assert.False(t, isValidRequestID(""))
+	assert.False(t, isValidRequestID("with space"))
+	assert.False(t, isValidRequestID(strings.Repeat("a", maxRequestIDLength+1)))
+}
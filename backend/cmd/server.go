@@ -24,7 +24,10 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/clustermetrics"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/k8cache"
@@ -32,32 +35,121 @@ import (
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/tunnel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 var k8sResponseCache = cache.New[string]()
 
+// bytesPerMegabyte converts the log-file-max-size flag to bytes.
+const bytesPerMegabyte = 1024 * 1024
+
 func main() {
 	if len(os.Args) == 2 && os.Args[1] == "list-plugins" {
 		runListPlugins()
 		return
 	}
 
+	if len(os.Args) >= 2 && os.Args[1] == "contexts" {
+		os.Exit(runContexts(os.Args[2:], os.Stdout))
+	}
+
+	if len(os.Args) >= 2 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:], os.Stdout))
+	}
+
+	// The preflight checks report an invalid config rather than failing on it.
+	if conf, err := config.ParseUnvalidated(os.Args); err == nil && conf.Preflight {
+		os.Exit(runPreflight(conf, os.Stdout))
+	}
+
 	conf, err := config.Parse(os.Args)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "fetching config:%v")
 		os.Exit(1)
 	}
 
+	if err := logger.SetLevel(conf.LogLevel); err != nil {
+		logger.Log(logger.LevelError, nil, err, "setting log level")
+		os.Exit(1)
+	}
+
+	watchLogLevelSignal()
+
+	if conf.LogFile != "" {
+		logFile, err := logger.AddFileSink(logger.FileOptions{
+			Path:           conf.LogFile,
+			MaxSize:        int64(conf.LogFileMaxSize) * bytesPerMegabyte,
+			RotateInterval: conf.LogFileRotateInterval,
+			MaxBackups:     conf.LogFileMaxBackups,
+			MaxAge:         conf.LogFileMaxAge,
+		})
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"path": conf.LogFile}, err, "opening log file")
+			os.Exit(1)
+		}
+
+		defer logFile.Close()
+	}
+
 	headlampConfig := createHeadlampConfig(conf)
+
+	headlampConfig.auditRecorder, err = audit.NewRecorder(audit.Options{
+		FilePath:    conf.AuditLog,
+		WebhookURL:  conf.AuditWebhook,
+		HistorySize: conf.AuditHistorySize,
+	})
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "setting up audit log")
+		os.Exit(1)
+	}
+
+	if headlampConfig.auditRecorder != nil {
+		defer headlampConfig.auditRecorder.Close()
+	}
+
+	headlampConfig.apiTokens, err = auth.NewAPITokenAuthenticator(auth.APITokenOptions{
+		TokensFile:    conf.APITokensFile,
+		JWTSecretFile: conf.APITokenJWTSecretFile,
+	})
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "loading API tokens")
+		os.Exit(1)
+	}
+
+	watchReloadSignal(headlampConfig, conf)
+
 	StartHeadlampServer(headlampConfig)
 }
 
 func createHeadlampConfig(conf *config.Config) *HeadlampConfig {
 	cache := cache.New[interface{}]()
 	kubeConfigStore := kubeconfig.NewContextStore()
+	kubeConfigStore.SetQuota(kubeconfig.Quota{
+		PerUser: conf.MaxDynamicClustersPerUser,
+		Total:   conf.MaxDynamicClusters,
+	})
+	kubeConfigStore.SetTTLPolicy(kubeconfig.TTLPolicy{
+		Default:    conf.StatelessContextTTL,
+		Max:        conf.StatelessContextMaxTTL,
+		MinRenewal: conf.StatelessContextMinRenewal,
+		RenewBelow: conf.StatelessContextRenewBelow,
+	})
+	kubeConfigStore.SetRejectInsecure(conf.RejectInsecureClusters)
+
+	if groupsFile := contextGroupsFile(conf); groupsFile != "" {
+		if err := kubeConfigStore.SetContextGroupsFile(groupsFile); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"path": groupsFile}, err, "loading context groups")
+			os.Exit(1)
+		}
+	}
+
 	multiplexer := NewMultiplexer(kubeConfigStore)
+	multiplexer.idleTimeout = conf.WebsocketIdleTimeout
+	multiplexer.resumeWindow = conf.WebsocketResumeWindow
+	multiplexer.limits.maxConnections = conf.MaxWebsocketConnections
+	multiplexer.limits.maxSubscriptions = conf.MaxWatchSubscriptions
 
 	headlampConfig := &HeadlampConfig{
 		HeadlampCFG: &headlampconfig.HeadlampCFG{
@@ -65,6 +157,8 @@ func createHeadlampConfig(conf *config.Config) *HeadlampConfig {
 			KubeConfigPath:        conf.KubeConfigPath,
 			SkippedKubeContexts:   conf.SkippedKubeContexts,
 			ListenAddr:            conf.ListenAddr,
+			ListenSocket:          conf.ListenSocket,
+			ListenSocketMode:      parseSocketMode(conf.ListenSocketMode),
 			CacheEnabled:          conf.CacheEnabled,
 			Port:                  conf.Port,
 			DevMode:               conf.DevMode,
@@ -79,6 +173,7 @@ func createHeadlampConfig(conf *config.Config) *HeadlampConfig {
 			ProxyURLs:             strings.Split(conf.ProxyURLs, ","),
 			TLSCertPath:           conf.TLSCertPath,
 			TLSKeyPath:            conf.TLSKeyPath,
+			TLSSelfSigned:         conf.TLSSelfSigned,
 		},
 		oidcClientID:              conf.OidcClientID,
 		oidcValidatorClientID:     conf.OidcValidatorClientID,
@@ -91,6 +186,14 @@ func createHeadlampConfig(conf *config.Config) *HeadlampConfig {
 		oidcUseAccessToken:        conf.OidcUseAccessToken,
 		cache:                     cache,
 		multiplexer:               multiplexer,
+		accessLog:                 conf.AccessLog,
+		accessLogExclude:          strings.Split(conf.AccessLogExclude, ","),
+		allowedVerbs:              parseAllowedVerbs(conf.AllowedVerbs),
+		dryRun:                    conf.DryRun,
+		contextNamespace:          conf.ContextNamespace,
+		recordClusterEvents:       conf.RecordClusterEvents,
+		csrfProtection:            conf.CSRFProtection,
+		headless:                  conf.Headless || conf.APIOnly,
 		telemetryConfig: config.Config{
 			ServiceName:        conf.ServiceName,
 			ServiceVersion:     conf.ServiceVersion,
@@ -104,6 +207,52 @@ func createHeadlampConfig(conf *config.Config) *HeadlampConfig {
 		},
 	}
 
+	// Validated when parsing the config.
+	headlampConfig.ociPlugins, _ = conf.OCIPluginRefs()
+	namingStrategy, _ := kubeconfig.ParseNamingStrategy(conf.ContextNaming)
+	kubeconfig.SetNamingStrategy(namingStrategy)
+	auth.SetHashLongClusterNames(namingStrategy == kubeconfig.NamingRFC1123Subdomain)
+	conflictPolicy, _ := kubeconfig.ParseConflictPolicy(conf.ContextConflictPolicy)
+	kubeconfig.SetConflictPolicy(conflictPolicy)
+	headlampConfig.pluginCatalog = plugins.NewCatalog(conf.PluginsDir, conf.PluginCacheDir)
+	headlampConfig.pluginBackends = plugins.NewBackends()
+	headlampConfig.enablePluginBackends = conf.PluginBackends
+	headlampConfig.helmRepoRefreshInterval = conf.HelmRepoRefreshInterval
+
+	if conf.ClusterMetricsInterval > 0 {
+		headlampConfig.clusterMetrics = clustermetrics.NewAggregator(cache, conf.ClusterMetricsInterval,
+			kubeConfigStore, clustermetrics.NewFetcher(kubeConfigStore))
+	}
+
+	headlampConfig.capabilities = kubeconfig.NewCapabilityCache(nil)
+
+	if conf.OpenAPICacheSize > 0 {
+		headlampConfig.openAPICache = k8cache.NewOpenAPICache(conf.OpenAPICacheSize << 20)
+	}
+
+	if conf.DiscoveryCache {
+		headlampConfig.discoveryCache = k8cache.NewDiscoveryCache(k8sResponseCache, nil)
+	}
+
+	if conf.TunnelToken != "" {
+		headlampConfig.tunnelServer = tunnel.NewServer(kubeConfigStore, conf.TunnelToken)
+	}
+
+	headlampConfig.remoteKubeConfigs = kubeconfig.NewRemoteSources(kubeConfigStore,
+		&http.Client{Timeout: remoteKubeConfigTimeout})
+	headlampConfig.remoteKubeConfigRefreshInterval = conf.KubeConfigURLRefreshInterval
+	headlampConfig.shutdownDrainTimeout = conf.ShutdownDrainTimeout
+
+	if conf.PluginVerification != plugins.PolicyOff {
+		verifier, err := plugins.NewVerifier(conf.PluginVerification, conf.PluginTrustedKeys)
+		if err != nil {
+			logger.Log(logger.LevelError, nil, err, "loading plugin trusted keys")
+			os.Exit(1)
+		}
+
+		headlampConfig.pluginVerifier = verifier
+	}
+
 	if conf.OidcCAFile != "" {
 		caFileContents, err := os.ReadFile(conf.OidcCAFile)
 		if err != nil {
@@ -130,7 +279,7 @@ func GetContextKeyAndKContext(w http.ResponseWriter,
 
 	contextKey, err := c.getContextKeyForRequest(r)
 	if err != nil {
-		c.handleError(w, ctx, span, err, "failed to get context Key:", http.StatusBadRequest)
+		c.handleError(w, ctx, span, err, "failed to get context Key:", contextKeyErrorStatus(err))
 		return nil, nil, "", nil, err
 	}
 
@@ -199,6 +348,11 @@ func CacheMiddleWare(c *HeadlampConfig) mux.MiddlewareFunc {
 
 			next.ServeHTTP(rcw, r)
 
+			// The response of a cancelled request may be cut short.
+			if r.Context().Err() != nil {
+				return
+			}
+
 			err = k8cache.StoreK8sResponseInCache(k8sResponseCache, r.URL, rcw, r, key)
 			if err != nil {
 				c.handleError(w, ctx, span, errors.New(kContext.Error), "error while storing into cache", http.StatusBadRequest)
@@ -208,6 +362,63 @@ func CacheMiddleWare(c *HeadlampConfig) mux.MiddlewareFunc {
 	}
 }
 
+// DiscoveryCacheMiddleware serves the API discovery requests of the clusters from the
+// discovery cache, which is refreshed when the CRDs or APIServices of a cluster change.
+func DiscoveryCacheMiddleware(c *HeadlampConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if c.discoveryCache == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiPath := mux.Vars(r)["api"]
+			if r.Method != http.MethodGet || !k8cache.IsDiscoveryPath(apiPath) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			_, span, contextKey, kContext, err := GetContextKeyAndKContext(w, r, c)
+			if err != nil {
+				return
+			}
+
+			defer span.End()
+
+			c.discoveryCache.Serve(w, r, next, contextKey, kContext, apiPath,
+				clusterToken(r, mux.Vars(r)["clusterName"]))
+		})
+	}
+}
+
+// OpenAPICacheMiddleware serves the OpenAPI v3 requests of the clusters through the OpenAPI
+// cache, so the schemas are only downloaded again from the clusters when they change.
+func OpenAPICacheMiddleware(c *HeadlampConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if c.openAPICache == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiPath := mux.Vars(r)["api"]
+			if r.Method != http.MethodGet || !k8cache.IsOpenAPIPath(apiPath) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			contextKey, err := c.getContextKeyForRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), contextKeyErrorStatus(err))
+
+				return
+			}
+
+			c.openAPICache.Serve(w, r, next, contextKey, apiPath)
+		})
+	}
+}
+
 func runListPlugins() {
 	conf, err := config.Parse(os.Args[2:])
 	if err != nil {
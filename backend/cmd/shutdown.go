@@ -0,0 +1,177 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// shutdownCloseReason is the reason of the close frame sent to the WebSocket clients on shutdown,
+// with the 1012 (service restart) code, so they reconnect.
+const shutdownCloseReason = "server restarting"
+
+// clientRegistry keeps the connected WebSocket clients of the multiplexer. Their connections
+// are hijacked, so the HTTP server doesn't close them on shutdown.
+type clientRegistry struct {
+	mu      sync.Mutex
+	clients map[*WSConnLock]struct{}
+	// closed is set on shutdown, the clients connecting afterwards are turned away.
+	closed bool
+}
+
+// track adds the client. It returns false if the multiplexer is shutting down.
+func (r *clientRegistry) track(client *WSConnLock) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return false
+	}
+
+	if r.clients == nil {
+		r.clients = map[*WSConnLock]struct{}{}
+	}
+
+	r.clients[client] = struct{}{}
+
+	return true
+}
+
+// untrack removes the client.
+func (r *clientRegistry) untrack(client *WSConnLock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.clients, client)
+}
+
+// close marks the registry closed and returns the clients connected until then.
+func (r *clientRegistry) close() []*WSConnLock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	clients := make([]*WSConnLock, 0, len(r.clients))
+	for client := range r.clients {
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+// Shutdown sends a close frame telling the WebSocket clients the server is restarting, and
+// closes the connections to the clusters, which cancels their watches and ends the SSE
+// streams. The clients connecting afterwards get the same close frame.
+func (m *Multiplexer) Shutdown() {
+	for _, client := range m.clients.close() {
+		writeRestartClose(client)
+	}
+
+	m.mutex.RLock()
+	connections := make([]*Connection, 0, len(m.connections))
+
+	for _, conn := range m.connections {
+		connections = append(connections, conn)
+	}
+	m.mutex.RUnlock()
+
+	for _, conn := range connections {
+		m.closeConnection(conn)
+	}
+}
+
+// writeRestartClose writes the close frame of a shutdown to the client.
+func writeRestartClose(client *WSConnLock) {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, shutdownCloseReason)
+
+	client.writeMu.Lock()
+	defer client.writeMu.Unlock()
+
+	if err := client.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second)); err != nil {
+		logger.Log(logger.LevelError, nil, err, "writing close message to client")
+	}
+}
+
+// serveUntilSignal serves until the server fails, or until the process receives SIGTERM or
+// an interrupt and the server is shut down gracefully.
+func (c *HeadlampConfig) serveUntilSignal(server *http.Server, serve func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return c.serveUntilDone(ctx, server, serve)
+}
+
+// serveUntilDone serves with the serve function until it fails, or until ctx is done and the
+// server is shut down: it stops accepting new requests, tells the WebSocket clients it's
+// restarting, and gives the in-flight requests the drain timeout to finish before cancelling
+// them. The proxied requests are cancelled upstream too.
+func (c *HeadlampConfig) serveUntilDone(ctx context.Context, server *http.Server, serve func() error) error {
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	server.BaseContext = func(net.Listener) context.Context { return requestsCtx }
+
+	if c.multiplexer != nil {
+		server.RegisterOnShutdown(c.multiplexer.Shutdown)
+	}
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- serve()
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Log(logger.LevelInfo, map[string]string{"drainTimeout": c.shutdownDrainTimeout.String()},
+		nil, "shutting down, draining the in-flight requests")
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), c.shutdownDrainTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(drainCtx); err != nil {
+		logger.Log(logger.LevelWarn, nil, err, "cancelling the requests still in flight")
+		cancelRequests()
+
+		if err := server.Close(); err != nil {
+			logger.Log(logger.LevelError, nil, err, "closing server")
+		}
+	}
+
+	// Serve returns ErrServerClosed as soon as the server starts shutting down.
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServing serves the handler on a local listener with serveUntilDone until ctx is done.
+// It returns the URL of the server and the channel serveUntilDone returns on.
+func startServing(
+	ctx context.Context, t *testing.T, c *HeadlampConfig, handler http.Handler,
+) (string, <-chan error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: time.Second}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.serveUntilDone(ctx, server, func() error { return server.Serve(listener) })
+	}()
+
+	return "http://" + listener.Addr().String(), done
+}
+
+func TestServeUntilDoneDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	})
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	c := &HeadlampConfig{shutdownDrainTimeout: 5 * time.Second}
+	url, done := startServing(ctx, t, c, handler)
+
+	body := make(chan string, 1)
+
+	go func() {
+		resp, err := http.Get(url) //nolint:noctx
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	<-started
+	shutdown()
+
+	// The new requests are refused while the in-flight one is drained.
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url) //nolint:noctx
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	assert.Equal(t, "done", <-body)
+	require.NoError(t, <-done)
+}
+
+func TestServeUntilDoneCancelsRequestsAfterDrainTimeout(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		cancelled <- r.Context().Err()
+	})
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	c := &HeadlampConfig{shutdownDrainTimeout: 50 * time.Millisecond}
+	url, done := startServing(ctx, t, c, handler)
+
+	go func() {
+		resp, err := http.Get(url) //nolint:noctx
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	shutdown()
+
+	require.NoError(t, <-done)
+	require.ErrorIs(t, <-cancelled, context.Canceled)
+}
+
+func TestServeUntilDoneServeError(t *testing.T) {
+	serveErr := errors.New("address in use")
+	c := &HeadlampConfig{}
+
+	err := c.serveUntilDone(context.Background(), &http.Server{ReadHeaderTimeout: time.Second},
+		func() error { return serveErr })
+	require.ErrorIs(t, err, serveErr)
+}
+
+func TestMultiplexerShutdown(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	server := httptest.NewServer(http.HandlerFunc(m.HandleClientWebSocket))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dialer := newTestDialer()
+
+	client, resp, err := dialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	defer client.Close()
+
+	require.Eventually(t, func() bool {
+		m.clients.mu.Lock()
+		defer m.clients.mu.Unlock()
+
+		return len(m.clients.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	conn := m.createConnection("minikube", "user", "/api/v1/pods", "watch=true", nil, nil)
+	m.connections[m.createConnectionKey("minikube", "/api/v1/pods", "user", "watch=true", nil)] = conn
+
+	m.Shutdown()
+
+	// The cluster connections are closed, cancelling their watches.
+	require.ErrorIs(t, conn.ctx.Err(), context.Canceled)
+	assert.Empty(t, m.connections)
+
+	assertRestartClose := func(client *websocket.Conn) {
+		t.Helper()
+
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+
+		_, _, err := client.ReadMessage()
+
+		var closeErr *websocket.CloseError
+
+		require.ErrorAs(t, err, &closeErr)
+		assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+		assert.Equal(t, shutdownCloseReason, closeErr.Text)
+	}
+
+	assertRestartClose(client)
+
+	// The clients connecting during the shutdown are turned away the same way.
+	late, resp, err := dialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	defer late.Close()
+
+	assertRestartClose(late)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// SSEClient is a StreamClient that delivers multiplexer messages to the client
+// as Server-Sent Events. It is used as a fallback for environments where proxies
+// break WebSocket upgrades.
+type SSEClient struct {
+	// w is the response writer of the event stream.
+	w http.ResponseWriter
+	// flusher flushes each event to the client as soon as it is written.
+	flusher http.Flusher
+	// controller sets the deadline of the writes.
+	controller *http.ResponseController
+	// writeMu is a mutex to synchronize access to write operations.
+	writeMu sync.Mutex
+	// closed is a flag to indicate if the stream is closed.
+	closed bool
+}
+
+// NewSSEClient prepares the response for an event stream and returns an SSEClient
+// writing to it. It returns an error if the response writer cannot be flushed.
+func NewSSEClient(w http.ResponseWriter) (*SSEClient, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming unsupported by response writer")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable response buffering in nginx based proxies.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &SSEClient{w: w, flusher: flusher, controller: http.NewResponseController(w)}, nil
+}
+
+// WriteJSON writes the JSON encoding of v as a single "message" event.
+func (c *SSEClient) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return errors.New("event stream closed")
+	}
+
+	c.setWriteDeadline()
+
+	if _, err := fmt.Fprintf(c.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+
+	c.flusher.Flush()
+
+	return nil
+}
+
+// writeHeartbeat writes an SSE comment line so that idle streams are not
+// closed by intermediate proxies.
+func (c *SSEClient) writeHeartbeat() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return errors.New("event stream closed")
+	}
+
+	c.setWriteDeadline()
+
+	if _, err := fmt.Fprint(c.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+
+	c.flusher.Flush()
+
+	return nil
+}
+
+// setWriteDeadline makes the next write fail if it takes longer than ClientWriteTimeout. The
+// response writers not supporting deadlines, like the recorders of the tests, are left as is.
+// The caller holds writeMu.
+func (c *SSEClient) setWriteDeadline() {
+	_ = c.controller.SetWriteDeadline(time.Now().Add(ClientWriteTimeout))
+}
+
+// Close marks the stream as closed. The underlying response is finished when
+// the handler returns.
+func (c *SSEClient) Close() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.closed = true
+
+	return nil
+}
+
+// HandleClientSSE streams a single watch subscription to the client using
+// Server-Sent Events. The subscription is described by the clusterId, path,
+// query and userId query parameters, which mirror the fields of a WebSocket
+// Message. Events use the same Message protocol as the WebSocket transport.
+func (m *Multiplexer) HandleClientSSE(w http.ResponseWriter, r *http.Request) {
+	msg := Message{
+		ClusterID: r.URL.Query().Get("clusterId"),
+		Path:      r.URL.Query().Get("path"),
+		Query:     r.URL.Query().Get("query"),
+		UserID:    r.URL.Query().Get("userId"),
+		Type:      "REQUEST",
+	}
+
+	if msg.ClusterID == "" || msg.Path == "" {
+		http.Error(w, "clusterId and path are required", http.StatusBadRequest)
+		return
+	}
+
+	clientID := clientIDForRequest(r)
+	if err := m.checkSubscriptionLimit(clientID); err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	token, err := auth.GetTokenFromCookie(r, msg.ClusterID)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"clusterID": msg.ClusterID},
+			err, "getting token for SSE stream")
+	}
+
+	client, err := NewSSEClient(w)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "creating SSE stream")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	defer client.Close()
+
+	m.registerClient(client, clientID)
+	defer m.unregisterClient(client)
+
+	conn, err := m.establishClusterConnection(msg.ClusterID, msg.UserID, msg.Path, msg.Query, client, &token)
+	if err != nil {
+		m.handleConnectionError(client, msg, err)
+
+		return
+	}
+
+	clusterDone := make(chan struct{})
+
+	go func() {
+		defer close(clusterDone)
+
+		m.handleClusterMessages(conn)
+	}()
+
+	m.streamSSE(r, clusterDone, client)
+
+	m.closeConnection(conn)
+}
+
+// streamSSE keeps the event stream open, writing heartbeats, until either the
+// client goes away or the cluster connection is done.
+func (m *Multiplexer) streamSSE(r *http.Request, clusterDone <-chan struct{}, client *SSEClient) {
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-clusterDone:
+			return
+		case <-heartbeat.C:
+			if err := client.writeHeartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSSEClientWriteJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	client, err := NewSSEClient(rec)
+	require.NoError(t, err)
+
+	err = client.WriteJSON(Message{ClusterID: "test-cluster", Type: "DATA", Data: "hello"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "event: message\ndata: ")
+	assert.Contains(t, rec.Body.String(), `"clusterId":"test-cluster"`)
+
+	require.NoError(t, client.Close())
+	assert.Error(t, client.WriteJSON(Message{}))
+}
+
+func TestHandleClientSSE_MissingParams(t *testing.T) {
+	m := NewMultiplexer(kubeconfig.NewContextStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/sseMultiplexer?path=/api/v1/pods", nil)
+	rec := httptest.NewRecorder()
+
+	m.HandleClientSSE(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleClientSSE(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	m := NewMultiplexer(store)
+
+	mockServer := createMockKubeAPIServer()
+	defer mockServer.Close()
+
+	err := store.AddContext(&kubeconfig.Context{
+		Name: "test-cluster",
+		Cluster: &api.Cluster{
+			Server:                mockServer.URL,
+			InsecureSkipTLSVerify: true,
+		},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(m.HandleClientSSE))
+	defer server.Close()
+
+	params := url.Values{}
+	params.Set("clusterId", "test-cluster")
+	params.Set("path", "/api/v1/pods")
+	params.Set("query", "watch=true")
+	params.Set("userId", "test-user")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?"+params.Encode(), nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+
+	var data string
+
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+			break
+		}
+	}
+
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(data), &msg))
+	assert.Equal(t, "STATUS", msg.Type)
+	assert.Equal(t, "test-cluster", msg.ClusterID)
+	assert.Equal(t, "/api/v1/pods", msg.Path)
+	assert.Contains(t, msg.Data, string(StateConnected))
+}
@@ -18,12 +18,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -54,22 +58,48 @@ func MarshalCustomObject(info runtime.Object, contextName string) (kubeconfig.Cu
 	return customObj, nil
 }
 
-// setKeyInCache sets the context in the cache with the given key.
-func (c *HeadlampConfig) setKeyInCache(key string, context kubeconfig.Context) error {
-	// check context is present
+// statelessContextTTL returns the ttl the stateless contexts are added with: the default one of
+// the ttl policy of the store, or ContextCacheTTL if it has none.
+func (c *HeadlampConfig) statelessContextTTL() time.Duration {
+	policy := c.KubeConfigStore.TTLPolicy()
+	if policy.Default <= 0 {
+		policy.Default = ContextCacheTTL
+	}
+
+	return policy.Apply(0)
+}
+
+// setKeyInCache sets the context in the cache with the given key, for the request r.
+func (c *HeadlampConfig) setKeyInCache(r *http.Request, key string, context kubeconfig.Context) error {
+	// check context is present, it may have expired
 	_, err := c.KubeConfigStore.GetContext(key)
-	if err != nil && err.Error() == "key not found" {
+	if errors.Is(err, cache.ErrNotFound) {
 		// To ensure stateless clusters are not visible to other users, they are marked as internal clusters.
 		// They are stored in the proxy cache and accessed through the /config endpoint.
 		context.Internal = true
-		if err = c.KubeConfigStore.AddContextWithKeyAndTTL(&context, key, ContextCacheTTL); err != nil {
+		context.Owner = clientIDForRequest(r)
+		ttl := c.statelessContextTTL()
+		err = c.KubeConfigStore.AddContextWithKeyAndTTL(&context, key, ttl)
+
+		event := contextAuditEvent(r, audit.VerbAddContext, key, &context, err)
+		event.TTL = ttl.String()
+		c.recordAuditEvent(r, event)
+
+		if err != nil {
 			logger.Log(logger.LevelError, map[string]string{"key": key},
 				err, "adding context to cache")
 
 			return err
 		}
 	} else {
-		if err = c.KubeConfigStore.UpdateTTL(key, ContextUpdateCacheTTL); err != nil {
+		ttl := c.KubeConfigStore.TTLPolicy().Apply(ContextUpdateCacheTTL)
+		err = c.KubeConfigStore.UpdateTTL(key, ttl)
+
+		event := contextAuditEvent(r, audit.VerbUpdateTTL, key, &context, err)
+		event.TTL = ttl.String()
+		c.recordAuditEvent(r, event)
+
+		if err != nil {
 			logger.Log(logger.LevelError, map[string]string{"key": key},
 				err, "updating context ttl")
 
@@ -83,24 +113,20 @@ func (c *HeadlampConfig) setKeyInCache(key string, context kubeconfig.Context) e
 // Handles stateless cluster requests if kubeconfig is set and dynamic clusters are enabled.
 // It returns context key which is used to store the context in the cache.
 func (c *HeadlampConfig) handleStatelessReq(r *http.Request, kubeConfig string) (string, error) {
-	var key string
-
 	var contextKey string
 
 	userID := r.Header.Get("X-HEADLAMP-USER-ID")
 	clusterName := mux.Vars(r)["clusterName"]
-	// unique key for the context
-	key = clusterName + userID
 
 	contexts, contextLoadErrors, err := kubeconfig.LoadContextsFromBase64String(kubeConfig, kubeconfig.DynamicCluster)
 	if len(contextLoadErrors) > 0 {
 		// Log all errors
 		for _, contextError := range contextLoadErrors {
-			logger.Log(logger.LevelError, nil, contextError.Error, "loading contexts from kubeconfig")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, contextError.Error, "loading contexts from kubeconfig")
 		}
 
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "loading contexts from kubeconfig")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "loading contexts from kubeconfig")
 
 			return "", err
 		}
@@ -112,32 +138,24 @@ func (c *HeadlampConfig) handleStatelessReq(r *http.Request, kubeConfig string)
 	}
 
 	if len(contexts) == 0 {
-		logger.Log(logger.LevelError, nil, nil, "no contexts found in kubeconfig")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, nil, "no contexts found in kubeconfig")
 		return "", fmt.Errorf("no contexts found in kubeconfig")
 	}
 
 	for _, context := range contexts {
-		info := context.KubeContext.Extensions["headlamp_info"]
-		if info != nil {
-			customObj, err := MarshalCustomObject(info, context.Name)
-			if err != nil {
-				logger.Log(logger.LevelError, map[string]string{"cluster": context.Name},
-					err, "marshaling custom object")
-
-				return "", err
-			}
-
-			// Check if the CustomName field is present
-			if customObj.CustomName != "" {
-				key = customObj.CustomName + userID
-			}
-		} else if context.Name != clusterName {
-			// Skip contexts that don't match the requested cluster name
+		// unique key for the context
+		key, matches, err := statelessContextKey(r, context, clusterName, userID)
+		if err != nil {
+			return "", err
+		}
+
+		// Skip contexts that don't match the requested cluster name
+		if !matches {
 			continue
 		}
 
 		// check context is present
-		if err := c.setKeyInCache(key, context); err != nil {
+		if err := c.setKeyInCache(r, key, context); err != nil {
 			return "", err
 		}
 
@@ -147,6 +165,142 @@ func (c *HeadlampConfig) handleStatelessReq(r *http.Request, kubeConfig string)
 	return contextKey, nil
 }
 
+// statelessContextKey returns the key the stateless context is stored with for the user, and
+// whether it is the context of the requested cluster. The contexts with a custom name in their
+// headlamp_info extension are stored with their custom name.
+func statelessContextKey(r *http.Request, context kubeconfig.Context, clusterName, userID string,
+) (string, bool, error) {
+	info := context.KubeContext.Extensions["headlamp_info"]
+	if info == nil {
+		return clusterName + userID, context.Name == clusterName, nil
+	}
+
+	customObj, err := MarshalCustomObject(info, context.Name)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"cluster": context.Name},
+			err, "marshaling custom object")
+
+		return "", false, err
+	}
+
+	// Check if the CustomName field is present
+	if customObj.CustomName != "" {
+		return customObj.CustomName + userID, true, nil
+	}
+
+	return clusterName + userID, true, nil
+}
+
+// statelessRehydrateRequest is the body of the requests re-creating expired stateless contexts.
+type statelessRehydrateRequest struct {
+	// Key is the key the stateless context was stored with.
+	Key string `json:"key"`
+	// KubeConfig is the base64 encoded kubeconfig of the context, with fresh credentials.
+	KubeConfig string `json:"kubeconfig"`
+}
+
+// statelessRehydrateResponse tells the key and name of the re-created stateless context.
+type statelessRehydrateResponse struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	// Created tells whether the context had expired, rather than having its credentials refreshed.
+	Created bool `json:"created"`
+}
+
+// handleStatelessRehydrate re-creates a stateless context with the key it was stored with, so the
+// links and state of a returning user keep working after the context expired. The key has to be
+// the one the kubeconfig gives for the cluster and the user of the request, so users can only
+// re-create their own contexts. Contexts that haven't expired get the fresh credentials.
+func (c *HeadlampConfig) handleStatelessRehydrate(w http.ResponseWriter, r *http.Request) {
+	var req statelessRehydrateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key == "" || req.KubeConfig == "" {
+		http.Error(w, "key and kubeconfig are required", http.StatusBadRequest)
+
+		return
+	}
+
+	contexts, _, err := kubeconfig.LoadContextsFromBase64String(req.KubeConfig, kubeconfig.DynamicCluster)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "loading contexts from kubeconfig")
+		http.Error(w, "invalid kubeconfig", http.StatusBadRequest)
+
+		return
+	}
+
+	clusterName := mux.Vars(r)["clusterName"]
+	userID := r.Header.Get("X-HEADLAMP-USER-ID")
+
+	for _, context := range contexts {
+		key, matches, err := statelessContextKey(r, context, clusterName, userID)
+		if err != nil {
+			http.Error(w, "invalid kubeconfig", http.StatusBadRequest)
+
+			return
+		}
+
+		if !matches || key != req.Key {
+			continue
+		}
+
+		c.rehydrateStatelessContext(w, r, key, context)
+
+		return
+	}
+
+	http.Error(w, "the kubeconfig has no context for the key", http.StatusBadRequest)
+}
+
+// rehydrateStatelessContext stores the stateless context with the key, unless the key is the
+// one of a context that isn't stateless.
+func (c *HeadlampConfig) rehydrateStatelessContext(w http.ResponseWriter, r *http.Request,
+	key string, context kubeconfig.Context,
+) {
+	existing, err := c.KubeConfigStore.GetContext(key)
+	if err == nil && !existing.Internal {
+		http.Error(w, "the key is the one of a context that isn't stateless", http.StatusConflict)
+
+		return
+	}
+
+	created := err != nil
+
+	// Stateless clusters are internal so they're not visible to other users.
+	context.Internal = true
+	context.Owner = clientIDForRequest(r)
+	ttl := c.statelessContextTTL()
+	err = c.KubeConfigStore.AddContextWithKeyAndTTL(&context, key, ttl)
+
+	event := contextAuditEvent(r, audit.VerbAddContext, key, &context, err)
+	event.TTL = ttl.String()
+	c.recordAuditEvent(r, event)
+
+	if errors.Is(err, kubeconfig.ErrQuotaExceeded) || errors.Is(err, kubeconfig.ErrInsecureContext) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+
+		return
+	}
+
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"key": key}, err, "re-creating stateless context")
+		http.Error(w, "re-creating stateless context", http.StatusInternalServerError)
+
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := statelessRehydrateResponse{Key: key, Name: context.Name, Created: created}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding stateless context")
+	}
+}
+
 // parseKubeConfig parses the kubeconfig and returns a list of contexts and errors.
 // Input is a list of base64 encoded kubeconfigs. Output is a list of clusters.
 // Input: {"kubeconfigs": ["base64 encoded kubeconfig 1", "base64 encoded kubeconfig 2"]}
@@ -162,7 +316,7 @@ func (c *HeadlampConfig) parseKubeConfig(w http.ResponseWriter, r *http.Request)
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&kubeconfigReq); err != nil {
 		// Handle the error, return a bad request response
-		logger.Log(logger.LevelError, nil, err, "decoding config")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "decoding config")
 
 		http.Error(w, "Invalid JSON request body", http.StatusBadRequest)
 	}
@@ -171,7 +325,7 @@ func (c *HeadlampConfig) parseKubeConfig(w http.ResponseWriter, r *http.Request)
 
 	contexts, setupErrors := parseClusterFromKubeConfig(kubeconfigs)
 	if len(setupErrors) > 0 {
-		logger.Log(logger.LevelError, nil, setupErrors, "setting up contexts from kubeconfig")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, setupErrors, "setting up contexts from kubeconfig")
 
 		http.Error(w, "setting up contexts from kubeconfig", http.StatusBadRequest)
 
@@ -181,7 +335,7 @@ func (c *HeadlampConfig) parseKubeConfig(w http.ResponseWriter, r *http.Request)
 	clientConfig := clientConfig{contexts, c.EnableDynamicClusters}
 
 	if err := json.NewEncoder(w).Encode(&clientConfig); err != nil {
-		logger.Log(logger.LevelError, nil, err, "encoding config")
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "encoding config")
 
 		http.Error(w, "Invalid JSON request body", http.StatusBadRequest)
 	}
@@ -236,6 +390,15 @@ func websocketConnContextKey(r *http.Request, clusterName string) string {
 	return contextKey
 }
 
+// contextKeyErrorStatus returns the HTTP status of an error getting the context key of a request.
+func contextKeyErrorStatus(err error) int {
+	if errors.Is(err, kubeconfig.ErrQuotaExceeded) || errors.Is(err, kubeconfig.ErrInsecureContext) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusBadRequest
+}
+
 // getContextKeyForRequest handles every requests. It returns context key
 // which is used to store the context in the cache. The context key is
 // unique for each user. It is found in the "X-HEADLAMP-USER-ID" parameter.
@@ -253,7 +416,7 @@ func (c *HeadlampConfig) getContextKeyForRequest(r *http.Request) (string, error
 		// if kubeConfig is set and dynamic clusters are enabled then handle stateless cluster requests
 		key, err := c.handleStatelessReq(r, kubeConfig)
 		if err != nil {
-			logger.Log(logger.LevelError, nil, err, "handling stateless request")
+			logger.LogCtx(r.Context(), logger.LevelError, nil, err, "handling stateless request")
 
 			return "", err
 		}
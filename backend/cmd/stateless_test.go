@@ -24,6 +24,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
@@ -222,3 +223,118 @@ func TestWebsocketConnContextKey(t *testing.T) {
 		})
 	}
 }
+
+func TestStatelessRehydrate(t *testing.T) {
+	kubeConfigByte, err := os.ReadFile("./headlamp_testdata/kubeconfig")
+	require.NoError(t, err)
+
+	kubeConfig := base64.StdEncoding.EncodeToString(kubeConfigByte)
+	userID := uuid.New().String()
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			EnableDynamicClusters: true,
+			KubeConfigStore:       kubeConfigStore,
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+	handler := createHeadlampHandler(&c)
+
+	rehydrate := func(key, userID string) *httptest.ResponseRecorder {
+		req, err := makeJSONReq(http.MethodPost, "/clusters/minikube/rehydrate",
+			statelessRehydrateRequest{Key: key, KubeConfig: kubeConfig})
+		require.NoError(t, err)
+
+		req.Header.Set("X-HEADLAMP-USER-ID", userID)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// The expired context is re-created with its key.
+	rr := rehydrate("minikube"+userID, userID)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var resp statelessRehydrateResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, statelessRehydrateResponse{Key: "minikube" + userID, Name: "minikube", Created: true}, resp)
+
+	stored, err := kubeConfigStore.GetContext("minikube" + userID)
+	require.NoError(t, err)
+	assert.True(t, stored.Internal)
+
+	// A context that didn't expire gets the fresh credentials.
+	rr = rehydrate("minikube"+userID, userID)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The keys of other users can't be used.
+	rr = rehydrate("minikube"+userID, uuid.New().String())
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Nor the ones of the contexts that aren't stateless.
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{Name: "minikube"}))
+
+	rr = rehydrate("minikube", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	stored, err = kubeConfigStore.GetContext("minikube")
+	require.NoError(t, err)
+	assert.False(t, stored.Internal)
+}
+
+func TestStatelessRehydrateQuota(t *testing.T) {
+	kubeConfigByte, err := os.ReadFile("./headlamp_testdata/kubeconfig")
+	require.NoError(t, err)
+
+	userID := uuid.New().String()
+
+	rehydrateRequest := func(userID string) *http.Request {
+		req, err := makeJSONReq(http.MethodPost, "/clusters/minikube/rehydrate", statelessRehydrateRequest{
+			Key:        "minikube" + userID,
+			KubeConfig: base64.StdEncoding.EncodeToString(kubeConfigByte),
+		})
+		require.NoError(t, err)
+
+		req.Header.Set("Authorization", "Bearer user-token")
+		req.Header.Set("X-HEADLAMP-USER-ID", userID)
+
+		return req
+	}
+
+	kubeConfigStore := kubeconfig.NewContextStore()
+	kubeConfigStore.SetQuota(kubeconfig.Quota{PerUser: 1})
+	require.NoError(t, kubeConfigStore.AddContextWithKeyAndTTL(&kubeconfig.Context{
+		Name:   "other",
+		Source: kubeconfig.DynamicCluster,
+		Owner:  clientIDForRequest(rehydrateRequest(userID)),
+	}, "other"+userID, time.Minute))
+
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			EnableDynamicClusters: true,
+			KubeConfigStore:       kubeConfigStore,
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+	}
+
+	handler := createHeadlampHandler(&c)
+
+	// The quota is the one of the credentials, whatever the user ID the client sends.
+	for _, id := range []string{userID, uuid.New().String()} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, rehydrateRequest(id))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "at most 1 dynamic clusters can be added per user")
+
+		_, err = kubeConfigStore.GetContext("minikube" + id)
+		assert.Error(t, err)
+	}
+}
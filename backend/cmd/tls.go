@@ -0,0 +1,70 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"os"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/servertls"
+)
+
+// serverTLSConfig returns the TLS config to serve with, or nil to serve plain HTTP.
+// Certificates given by path are reloaded when their files change.
+func serverTLSConfig(config *headlampconfig.HeadlampCFG) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch {
+	case config.TLSCertPath != "" && config.TLSKeyPath != "":
+		reloader, err := servertls.NewCertReloader(config.TLSCertPath, config.TLSKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.GetCertificate = reloader.GetCertificate
+	case config.TLSSelfSigned:
+		cert, err := servertls.SelfSigned(selfSignedHosts(config.ListenAddr))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Log(logger.LevelWarn, nil, nil, "Serving TLS with a self-signed certificate")
+
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	default:
+		return nil, nil
+	}
+
+	return tlsConfig, nil
+}
+
+// selfSignedHosts returns the hosts a self-signed certificate is generated for.
+func selfSignedHosts(listenAddr string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+
+	if listenAddr != "" && listenAddr != "0.0.0.0" && listenAddr != "::" && listenAddr != "localhost" {
+		hosts = append(hosts, listenAddr)
+	}
+
+	return hosts
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTLSConfig(t *testing.T) {
+	tlsConfig, err := serverTLSConfig(&headlampconfig.HeadlampCFG{})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = serverTLSConfig(&headlampconfig.HeadlampCFG{TLSSelfSigned: true, ListenAddr: "10.0.0.1"})
+	require.NoError(t, err)
+	require.Len(t, tlsConfig.Certificates, 1)
+	assert.NoError(t, tlsConfig.Certificates[0].Leaf.VerifyHostname("10.0.0.1"))
+
+	_, err = serverTLSConfig(&headlampconfig.HeadlampCFG{TLSCertPath: "/missing/tls.crt", TLSKeyPath: "/missing/tls.key"})
+	assert.Error(t, err)
+}
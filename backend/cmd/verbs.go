@@ -0,0 +1,166 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// parseAllowedVerbs parses the comma separated list of verbs allowed in the requests
+// proxied to the clusters. An empty list, or one with "*", allows every verb.
+func parseAllowedVerbs(list string) []string {
+	var verbs []string
+
+	for _, verb := range strings.Split(list, ",") {
+		verb = strings.ToLower(strings.TrimSpace(verb))
+		if verb == "*" {
+			return nil
+		}
+
+		if verb != "" {
+			verbs = append(verbs, verb)
+		}
+	}
+
+	return verbs
+}
+
+// isVerbAllowed tells whether requests with the verb can be proxied to the clusters.
+func (c *HeadlampConfig) isVerbAllowed(verb string) bool {
+	return len(c.allowedVerbs) == 0 || slices.Contains(c.allowedVerbs, verb)
+}
+
+// backendAction is what a request to a cluster-scoped route of the backend, rather than
+// proxied to a cluster, does in the cluster.
+type backendAction struct {
+	// resource is what the route acts on, e.g. pods/exec.
+	resource string
+	// verbs are the verbs of the Kubernetes requests the route makes.
+	verbs []string
+}
+
+// nodeDrainAction is the drain of a node, which cordons it and evicts its pods.
+var nodeDrainAction = backendAction{resource: "nodes", verbs: []string{"patch", "create"}}
+
+// helmReleaseVerbs are the verbs of the helm routes acting on the releases, by method and
+// path relative to /clusters/{clusterName}/helm. The repository and registry routes don't
+// touch the cluster.
+var helmReleaseVerbs = map[string]string{
+	http.MethodGet + " /releases/list":             "list",
+	http.MethodGet + " /releases":                  "get",
+	http.MethodGet + " /release/history":           "get",
+	http.MethodGet + " /action/status":             "get",
+	http.MethodPost + " /releases/upgrade/preview": "get",
+	http.MethodPost + " /release/install":          "create",
+	http.MethodPut + " /releases/upgrade":          "update",
+	http.MethodPut + " /releases/rollback":         "update",
+	http.MethodDelete + " /releases/uninstall":     "delete",
+}
+
+// methodVerbs are the verbs of the requests by method, for the routes like the plugin
+// backends whose requests to the clusters aren't known.
+var methodVerbs = map[string]string{
+	http.MethodGet:    "get",
+	http.MethodHead:   "get",
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "patch",
+	http.MethodDelete: "delete",
+}
+
+// backendRouteAction returns what the request to a route of the backend does in the cluster,
+// or false if the route doesn't touch the clusters or is proxied to them.
+func (c *HeadlampConfig) backendRouteAction(r *http.Request) (backendAction, bool) {
+	path := strings.TrimPrefix(r.URL.Path, c.BaseURL)
+
+	if rest, ok := strings.CutPrefix(path, "/clusters/"); ok {
+		_, route, _ := strings.Cut(rest, "/")
+
+		switch {
+		case route == "exec" || route == "attach":
+			return backendAction{resource: "pods/" + route, verbs: []string{"create"}}, true
+		case route == "portforward" && r.Method == http.MethodPost:
+			return backendAction{resource: "pods/portforward", verbs: []string{"create"}}, true
+		case strings.HasPrefix(route, "helm/"):
+			verb, ok := helmReleaseVerbs[r.Method+" "+strings.TrimPrefix(route, "helm")]
+
+			return backendAction{resource: "releases", verbs: []string{verb}}, ok
+		}
+
+		return backendAction{}, false
+	}
+
+	if path == "/drain-node" && r.Method == http.MethodPost {
+		return nodeDrainAction, true
+	}
+
+	if name, _, ok := c.pluginBackendPath(r); ok && c.pluginBackends != nil && c.pluginBackends.Has(name) {
+		verb, ok := methodVerbs[r.Method]
+
+		return backendAction{resource: "plugins/" + name, verbs: []string{verb}}, ok
+	}
+
+	return backendAction{}, false
+}
+
+// verbsMiddleware rejects the requests to the cluster-scoped routes of the backend, like exec,
+// helm or the node drains, whose verbs aren't allowed. The requests proxied to the clusters
+// are checked by clusterRequestHandler, which knows their resources.
+func (c *HeadlampConfig) verbsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if action, ok := c.backendRouteAction(r); ok {
+			for _, verb := range action.verbs {
+				if !c.isVerbAllowed(verb) {
+					writeVerbForbidden(w, r, apirequest.Info{Verb: verb, Resource: action.resource})
+
+					return
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeVerbForbidden rejects a request whose verb is not allowed, with a Kubernetes
+// Forbidden status as the API server would, so clients handle it like an RBAC denial.
+func writeVerbForbidden(w http.ResponseWriter, r *http.Request, info apirequest.Info) {
+	err := fmt.Errorf("verb %q is not allowed by this Headlamp server", info.Verb)
+
+	status := apierrors.NewForbidden(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource},
+		info.Name, err).Status()
+
+	logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{
+		"verb": info.Verb, "resource": info.Resource, "namespace": info.Namespace, "name": info.Name,
+	}, err, "rejecting proxied request")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "writing forbidden status")
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/headlampconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseAllowedVerbs(t *testing.T) {
+	assert.Equal(t, []string{"get", "list", "watch"}, parseAllowedVerbs(" get,LIST, watch,"))
+	assert.Empty(t, parseAllowedVerbs(""))
+	assert.Empty(t, parseAllowedVerbs("get,*"))
+
+	c := &HeadlampConfig{}
+	assert.True(t, c.isVerbAllowed("delete"))
+
+	c.allowedVerbs = parseAllowedVerbs("get,list,watch")
+	assert.True(t, c.isVerbAllowed("watch"))
+	assert.False(t, c.isVerbAllowed("delete"))
+}
+
+func TestClusterRequestVerbForbidden(t *testing.T) {
+	c := &HeadlampConfig{allowedVerbs: []string{"get", "list", "watch"}}
+
+	api := "apis/apps/v1/namespaces/default/deployments/nginx"
+	req := httptest.NewRequest(http.MethodDelete, "/clusters/minikube/"+api, nil)
+	req = mux.SetURLVars(req, map[string]string{"clusterName": "minikube", "api": api})
+
+	rr := httptest.NewRecorder()
+	clusterRequestHandler(c).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	var status metav1.Status
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+	assert.Equal(t, metav1.StatusReasonForbidden, status.Reason)
+	assert.Equal(t, "apps", status.Details.Group)
+	assert.Equal(t, "deployments", status.Details.Kind)
+	assert.Equal(t, "nginx", status.Details.Name)
+}
+
+func TestVerbsMiddleware(t *testing.T) {
+	backends := plugins.NewBackends()
+	require.NoError(t, backends.Register("my-plugin", http.NotFoundHandler(),
+		plugins.BackendOptions{Auth: plugins.BackendAuthNone}))
+
+	c := &HeadlampConfig{
+		HeadlampCFG:    &headlampconfig.HeadlampCFG{BaseURL: "/headlamp"},
+		allowedVerbs:   []string{"get", "list", "watch"},
+		pluginBackends: backends,
+	}
+
+	handler := c.verbsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/clusters/minikube/exec?namespace=ns&pod=pod&command=sh", http.StatusForbidden},
+		{http.MethodGet, "/clusters/minikube/attach?namespace=ns&pod=pod", http.StatusForbidden},
+		{http.MethodPost, "/clusters/minikube/portforward", http.StatusForbidden},
+		{http.MethodGet, "/clusters/minikube/portforward/list", http.StatusNoContent},
+		{http.MethodPost, "/clusters/minikube/helm/release/install", http.StatusForbidden},
+		{http.MethodPut, "/clusters/minikube/helm/releases/upgrade", http.StatusForbidden},
+		{http.MethodPut, "/clusters/minikube/helm/releases/rollback", http.StatusForbidden},
+		{http.MethodDelete, "/clusters/minikube/helm/releases/uninstall", http.StatusForbidden},
+		{http.MethodGet, "/clusters/minikube/helm/releases/list", http.StatusNoContent},
+		{http.MethodPost, "/clusters/minikube/helm/releases/upgrade/preview", http.StatusNoContent},
+		{http.MethodPost, "/clusters/minikube/helm/repositories", http.StatusNoContent},
+		{http.MethodPost, "/drain-node", http.StatusForbidden},
+		{http.MethodGet, "/drain-node-status?cluster=minikube&nodeName=node", http.StatusNoContent},
+		{http.MethodPost, "/plugins/my-plugin/api/items", http.StatusForbidden},
+		{http.MethodGet, "/plugins/my-plugin/api/items", http.StatusNoContent},
+		{http.MethodPost, "/plugins/other-plugin/api/items", http.StatusNoContent},
+		// The proxied requests are checked by clusterRequestHandler.
+		{http.MethodDelete, "/clusters/minikube/api/v1/namespaces/default", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/headlamp"+tt.path, nil))
+
+			assert.Equal(t, tt.want, rr.Code)
+
+			if tt.want == http.StatusForbidden {
+				var status metav1.Status
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&status))
+				assert.Equal(t, metav1.StatusReasonForbidden, status.Reason)
+			}
+		})
+	}
+}
+
+func TestVerbsMiddlewareRouter(t *testing.T) {
+	c := HeadlampConfig{
+		HeadlampCFG: &headlampconfig.HeadlampCFG{
+			KubeConfigStore: kubeconfig.NewContextStore(),
+		},
+		cache:            cache.New[interface{}](),
+		telemetryConfig:  GetDefaultTestTelemetryConfig(),
+		telemetryHandler: &telemetry.RequestHandler{},
+		allowedVerbs:     []string{"get", "list", "watch"},
+	}
+
+	handler := createHeadlampHandler(&c)
+
+	rr, err := getResponseFromRestrictedEndpoint(handler, http.MethodPost, "/drain-node",
+		map[string]string{"cluster": "minikube", "nodeName": "node"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), `verb \"patch\" is not allowed`)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apirequest tells what a request to the Kubernetes API does: its verb and the
+// resource it is for, like the API server does to authorize it.
+package apirequest
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Info is what a request to the Kubernetes API does.
+type Info struct {
+	// Verb is the Kubernetes verb of the request, e.g. get, list, watch or delete.
+	// It is the lowercase method for the requests that are not for a resource.
+	Verb        string
+	APIGroup    string
+	APIVersion  string
+	Resource    string
+	Subresource string
+	Namespace   string
+	Name        string
+}
+
+// IsResourceRequest tells whether the request is for a resource, rather than e.g. /version.
+func (i Info) IsResourceRequest() bool {
+	return i.Resource != ""
+}
+
+// Parse tells what a request to the Kubernetes API, with the method, path and query,
+// does, e.g. DELETE /api/v1/namespaces/default/pods/nginx. The query may be nil.
+func Parse(method, path string, query url.Values) Info {
+	var info Info
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		info.APIVersion = parts[1]
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		info.APIGroup = parts[1]
+		info.APIVersion = parts[2]
+		parts = parts[3:]
+	default:
+		info.Verb = strings.ToLower(method)
+
+		return info
+	}
+
+	// The deprecated watch paths, e.g. /api/v1/watch/namespaces/default/pods.
+	watchPath := len(parts) > 0 && parts[0] == "watch"
+	if watchPath {
+		parts = parts[1:]
+	}
+
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		if len(parts) == 2 {
+			// The namespace itself.
+			info.Resource = parts[0]
+			info.Name = parts[1]
+			parts = nil
+		} else {
+			info.Namespace = parts[1]
+			parts = parts[2:]
+		}
+	}
+
+	if len(parts) > 0 {
+		info.Resource = parts[0]
+	}
+
+	if len(parts) > 1 {
+		info.Name = parts[1]
+	}
+
+	if len(parts) > 2 {
+		info.Subresource = parts[2]
+	}
+
+	info.Verb = verb(method, info.Name, watchPath || isWatch(query))
+
+	return info
+}
+
+// WithNamespace returns the path of a request for a resource in all the namespaces, or with
+// no namespace, made for the resource in the namespace, e.g. /api/v1/pods to
+// /api/v1/namespaces/default/pods. The paths already having a namespace are returned as is.
+func WithNamespace(path, namespace string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	prefix := 0
+
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		prefix = 2
+	case len(parts) >= 4 && parts[0] == "apis":
+		prefix = 3
+	default:
+		return path
+	}
+
+	if parts[prefix] == "watch" {
+		prefix++
+	}
+
+	if prefix >= len(parts) || parts[prefix] == "namespaces" {
+		return path
+	}
+
+	namespaced := append([]string{}, parts[:prefix]...)
+	namespaced = append(namespaced, "namespaces", namespace)
+	namespaced = append(namespaced, parts[prefix:]...)
+
+	newPath := strings.Join(namespaced, "/")
+	if strings.HasPrefix(path, "/") {
+		newPath = "/" + newPath
+	}
+
+	return newPath
+}
+
+// isWatch tells whether the query asks to watch the resources.
+func isWatch(query url.Values) bool {
+	watch := query.Get("watch")
+
+	return watch == "true" || watch == "1"
+}
+
+// verb returns the Kubernetes verb of a request for a resource with the method, for a
+// named resource or not.
+func verb(method, name string, watch bool) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case watch:
+			return "watch"
+		case name == "":
+			return "list"
+		default:
+			return "get"
+		}
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		if name == "" {
+			return "deletecollection"
+		}
+
+		return "delete"
+	default:
+		return strings.ToLower(method)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apirequest_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		query  url.Values
+		want   apirequest.Info
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			path:   "/api/v1/namespaces/default/pods/nginx",
+			want:   apirequest.Info{Verb: "get", APIVersion: "v1", Resource: "pods", Namespace: "default", Name: "nginx"},
+		},
+		{
+			name:   "list",
+			method: http.MethodGet,
+			path:   "/apis/apps/v1/deployments",
+			want:   apirequest.Info{Verb: "list", APIGroup: "apps", APIVersion: "v1", Resource: "deployments"},
+		},
+		{
+			name:   "watch_query",
+			method: http.MethodGet,
+			path:   "/api/v1/namespaces/default/pods",
+			query:  url.Values{"watch": {"1"}},
+			want:   apirequest.Info{Verb: "watch", APIVersion: "v1", Resource: "pods", Namespace: "default"},
+		},
+		{
+			name:   "watch_path",
+			method: http.MethodGet,
+			path:   "/api/v1/watch/namespaces/default/pods",
+			want:   apirequest.Info{Verb: "watch", APIVersion: "v1", Resource: "pods", Namespace: "default"},
+		},
+		{
+			name:   "logs",
+			method: http.MethodGet,
+			path:   "/api/v1/namespaces/default/pods/nginx/log",
+			want: apirequest.Info{
+				Verb: "get", APIVersion: "v1", Resource: "pods", Namespace: "default", Name: "nginx", Subresource: "log",
+			},
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/apis/apps/v1/namespaces/default/deployments/nginx",
+			want: apirequest.Info{
+				Verb: "delete", APIGroup: "apps", APIVersion: "v1", Resource: "deployments", Namespace: "default", Name: "nginx",
+			},
+		},
+		{
+			name:   "delete_collection",
+			method: http.MethodDelete,
+			path:   "/api/v1/namespaces/default/pods",
+			want:   apirequest.Info{Verb: "deletecollection", APIVersion: "v1", Resource: "pods", Namespace: "default"},
+		},
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/api/v1/namespaces",
+			want:   apirequest.Info{Verb: "create", APIVersion: "v1", Resource: "namespaces"},
+		},
+		{
+			name:   "namespace",
+			method: http.MethodPatch,
+			path:   "/api/v1/namespaces/old",
+			want:   apirequest.Info{Verb: "patch", APIVersion: "v1", Resource: "namespaces", Name: "old"},
+		},
+		{
+			name:   "non_resource",
+			method: http.MethodGet,
+			path:   "/version",
+			want:   apirequest.Info{Verb: "get"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, apirequest.Parse(tt.method, tt.path, tt.query))
+		})
+	}
+
+	assert.False(t, apirequest.Parse(http.MethodGet, "/version", nil).IsResourceRequest())
+}
+
+func TestWithNamespace(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/api/v1/pods", want: "/api/v1/namespaces/dev/pods"},
+		{path: "api/v1/pods/nginx/log", want: "api/v1/namespaces/dev/pods/nginx/log"},
+		{path: "/apis/apps/v1/deployments", want: "/apis/apps/v1/namespaces/dev/deployments"},
+		{path: "/api/v1/watch/pods", want: "/api/v1/watch/namespaces/dev/pods"},
+		{path: "/api/v1/namespaces/default/pods", want: "/api/v1/namespaces/default/pods"},
+		{path: "/api/v1/namespaces", want: "/api/v1/namespaces"},
+		{path: "/api/v1", want: "/api/v1"},
+		{path: "/apis/apps/v1", want: "/apis/apps/v1"},
+		{path: "/version", want: "/version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, apirequest.WithNamespace(tt.path, "dev"))
+		})
+	}
+}
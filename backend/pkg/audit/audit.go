@@ -0,0 +1,236 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the requests changing cluster resources through Headlamp, and
+// the changes to the registered clusters, so it can be told who made a change and when.
+//
+// Events are written as JSON lines to an append-only file, or posted to a webhook.
+// The recent cluster changes are also kept in memory to be queried.
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Outcomes of an audited request.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Kinds of audit events.
+const (
+	// KindRequest is a request proxied to a cluster.
+	KindRequest = "request"
+	// KindContext is an operation on the contexts of the registered clusters.
+	KindContext = "context"
+)
+
+// Verbs of the KindContext events.
+const (
+	VerbAddContext     = "AddContext"
+	VerbRemoveContext  = "RemoveContext"
+	VerbUpdateTTL      = "UpdateTTL"
+	VerbRename         = "Rename"
+	VerbUpdateMetadata = "UpdateMetadata"
+	VerbUpdateContext  = "UpdateContext"
+	// VerbUseInsecure is the first use of a context skipping the TLS verification.
+	VerbUseInsecure = "UseInsecureContext"
+)
+
+// Event is an audited request or context operation.
+type Event struct {
+	Kind string    `json:"kind"`
+	Time time.Time `json:"time"`
+	// RequestID is the ID of the request in the Headlamp logs.
+	RequestID string `json:"requestId,omitempty"`
+	// User is the user the request token belongs to, if it could be told.
+	User string `json:"user,omitempty"`
+	// Session is the Headlamp user ID of the client, for dynamic clusters.
+	Session string `json:"session,omitempty"`
+	// Client is the address of the client.
+	Client string `json:"client,omitempty"`
+	// Context is the cluster context the request was proxied to, or the operation was made on.
+	Context string `json:"context"`
+	// OriginalName is the name of the context in its kubeconfig, when it differs from the
+	// sanitized Context name.
+	OriginalName string `json:"originalName,omitempty"`
+	// NewName is the new name of a renamed context.
+	NewName string `json:"newName,omitempty"`
+	// Source is where the context comes from, e.g. kubeconfig or dynamic_cluster.
+	Source string `json:"source,omitempty"`
+	// TTL is the time to live of the context, for the contexts that expire.
+	TTL         string `json:"ttl,omitempty"`
+	Verb        string `json:"verb"`
+	Method      string `json:"method,omitempty"`
+	Path        string `json:"path,omitempty"`
+	APIGroup    string `json:"apiGroup,omitempty"`
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Outcome     string `json:"outcome"`
+	// DryRun tells whether the request was made a dry run, changing nothing.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Error is the error the operation failed with.
+	Error string `json:"error,omitempty"`
+}
+
+// SetStatus sets the status of the response to the request, and the outcome it means.
+func (e *Event) SetStatus(status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	e.Status = status
+
+	e.Outcome = OutcomeSuccess
+	if status >= http.StatusBadRequest {
+		e.Outcome = OutcomeFailure
+	}
+}
+
+// SetError sets the outcome of an operation from the error it returned, if any.
+func (e *Event) SetError(err error) {
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+
+		return
+	}
+
+	e.Outcome = OutcomeSuccess
+}
+
+// Sink is where audit events are recorded.
+type Sink interface {
+	Record(event Event) error
+	Close() error
+}
+
+// IsMutating tells whether requests with the method can change resources, and are audited.
+func IsMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// Options configures the sinks of a Recorder.
+type Options struct {
+	// FilePath is the file to append the events to, if set.
+	FilePath string
+	// WebhookURL is the URL to post the events to, if set.
+	WebhookURL string
+	// HistorySize is the number of recent context events kept in memory to be queried.
+	HistorySize int
+}
+
+// Recorder records audit events to its sinks.
+type Recorder struct {
+	sinks []Sink
+	// history keeps the recent context events, if enabled.
+	history *History
+}
+
+// NewRecorder creates a Recorder with the sinks and the history configured by opts.
+// It returns nil if none is configured, auditing being disabled.
+func NewRecorder(opts Options) (*Recorder, error) {
+	var sinks []Sink
+
+	if opts.FilePath != "" {
+		sink, err := NewFileSink(opts.FilePath)
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	if opts.WebhookURL != "" {
+		sink, err := NewWebhookSink(opts.WebhookURL)
+		if err != nil {
+			return nil, errors.Join(err, closeSinks(sinks))
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 0 && opts.HistorySize <= 0 {
+		return nil, nil
+	}
+
+	recorder := NewRecorderWithSinks(sinks...)
+
+	if opts.HistorySize > 0 {
+		recorder.history = NewHistory(opts.HistorySize)
+	}
+
+	return recorder, nil
+}
+
+// NewRecorderWithSinks creates a Recorder recording to the given sinks.
+func NewRecorderWithSinks(sinks ...Sink) *Recorder {
+	return &Recorder{sinks: sinks}
+}
+
+// Record records the event to every sink, and the context events to the history.
+// The errors of the sinks are joined.
+func (r *Recorder) Record(event Event) error {
+	if r.history != nil && event.Kind == KindContext {
+		r.history.Add(event)
+	}
+
+	errs := make([]error, 0, len(r.sinks))
+
+	for _, sink := range r.sinks {
+		errs = append(errs, sink.Record(event))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ContextEvents returns the recent context events matching the query, oldest first.
+// It returns false if the history is disabled.
+func (r *Recorder) ContextEvents(query Query) ([]Event, bool) {
+	if r.history == nil {
+		return nil, false
+	}
+
+	return r.history.Query(query), true
+}
+
+// Close closes the sinks, flushing the pending events.
+func (r *Recorder) Close() error {
+	return closeSinks(r.sinks)
+}
+
+// closeSinks closes the sinks and joins their errors.
+func closeSinks(sinks []Sink) error {
+	errs := make([]error, 0, len(sinks))
+
+	for _, sink := range sinks {
+		errs = append(errs, sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
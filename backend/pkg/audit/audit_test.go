@@ -0,0 +1,275 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventSetRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   audit.Event
+	}{
+		{
+			name:   "delete_deployment",
+			method: http.MethodDelete,
+			path:   "/apis/apps/v1/namespaces/default/deployments/nginx",
+			want: audit.Event{
+				Verb: "delete", APIGroup: "apps", APIVersion: "v1",
+				Resource: "deployments", Namespace: "default", Name: "nginx",
+			},
+		},
+		{
+			name:   "create_pod",
+			method: http.MethodPost,
+			path:   "/api/v1/namespaces/default/pods",
+			want:   audit.Event{Verb: "create", APIVersion: "v1", Resource: "pods", Namespace: "default"},
+		},
+		{
+			name:   "patch_scale",
+			method: http.MethodPatch,
+			path:   "/apis/apps/v1/namespaces/ns/deployments/web/scale",
+			want: audit.Event{
+				Verb: "patch", APIGroup: "apps", APIVersion: "v1",
+				Resource: "deployments", Namespace: "ns", Name: "web", Subresource: "scale",
+			},
+		},
+		{
+			name:   "update_node",
+			method: http.MethodPut,
+			path:   "/api/v1/nodes/node-1",
+			want:   audit.Event{Verb: "update", APIVersion: "v1", Resource: "nodes", Name: "node-1"},
+		},
+		{
+			name:   "delete_namespace",
+			method: http.MethodDelete,
+			path:   "/api/v1/namespaces/old",
+			want:   audit.Event{Verb: "delete", APIVersion: "v1", Resource: "namespaces", Name: "old"},
+		},
+		{
+			name:   "delete_collection",
+			method: http.MethodDelete,
+			path:   "/api/v1/namespaces/default/pods",
+			want:   audit.Event{Verb: "deletecollection", APIVersion: "v1", Resource: "pods", Namespace: "default"},
+		},
+		{
+			name:   "non_resource",
+			method: http.MethodPost,
+			path:   "/version",
+			want:   audit.Event{Verb: "post"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event audit.Event
+			event.SetRequest(tt.method, tt.path)
+
+			tt.want.Method = tt.method
+			tt.want.Path = tt.path
+			assert.Equal(t, tt.want, event)
+		})
+	}
+}
+
+func TestEventSetStatus(t *testing.T) {
+	var event audit.Event
+
+	event.SetStatus(0)
+	assert.Equal(t, http.StatusOK, event.Status)
+	assert.Equal(t, audit.OutcomeSuccess, event.Outcome)
+
+	event.SetStatus(http.StatusForbidden)
+	assert.Equal(t, audit.OutcomeFailure, event.Outcome)
+}
+
+func TestIsMutating(t *testing.T) {
+	assert.False(t, audit.IsMutating(http.MethodGet))
+	assert.False(t, audit.IsMutating(http.MethodHead))
+	assert.True(t, audit.IsMutating(http.MethodDelete))
+	assert.True(t, audit.IsMutating(http.MethodPatch))
+}
+
+func jwt(t *testing.T, claims map[string]string) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	return "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func TestUserFromToken(t *testing.T) {
+	assert.Equal(t, "jane@example.com", audit.UserFromToken(jwt(t, map[string]string{
+		"sub": "1234", "email": "jane@example.com",
+	})))
+	assert.Equal(t, "system:serviceaccount:default:admin", audit.UserFromToken(jwt(t, map[string]string{
+		"sub": "system:serviceaccount:default:admin",
+	})))
+	assert.Empty(t, audit.UserFromToken("opaque-token"))
+	assert.Empty(t, audit.UserFromToken(""))
+}
+
+func TestNewRecorder(t *testing.T) {
+	recorder, err := audit.NewRecorder(audit.Options{})
+	require.NoError(t, err)
+	assert.Nil(t, recorder)
+
+	_, err = audit.NewRecorder(audit.Options{WebhookURL: "not a url"})
+	assert.Error(t, err)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Events are appended to the existing ones.
+	for _, name := range []string{"first", "second"} {
+		recorder, err := audit.NewRecorder(audit.Options{FilePath: path})
+		require.NoError(t, err)
+
+		require.NoError(t, recorder.Record(audit.Event{Time: time.Now(), Context: "minikube", Name: name}))
+		require.NoError(t, recorder.Close())
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	var names []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event audit.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		names = append(names, event.Name)
+	}
+
+	assert.Equal(t, []string{"first", "second"}, names)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(audit.FileMode), info.Mode().Perm())
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []audit.Event
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event audit.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	recorder, err := audit.NewRecorder(audit.Options{WebhookURL: server.URL})
+	require.NoError(t, err)
+
+	require.NoError(t, recorder.Record(audit.Event{Context: "minikube", Verb: "delete"}))
+	require.NoError(t, recorder.Record(audit.Event{Context: "minikube", Verb: "create"}))
+
+	// Closing the recorder posts the queued events.
+	require.NoError(t, recorder.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "delete", events[0].Verb)
+	assert.Equal(t, "create", events[1].Verb)
+}
+
+func TestWebhookSinkRecordAfterClose(t *testing.T) {
+	sink, err := audit.NewWebhookSink("http://127.0.0.1:1")
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	// The requests still served on shutdown may record events once the sink is closed.
+	assert.ErrorIs(t, sink.Record(audit.Event{Context: "minikube", Verb: "delete"}), audit.ErrSinkClosed)
+	require.NoError(t, sink.Close())
+}
+
+func TestHistory(t *testing.T) {
+	history := audit.NewHistory(3)
+	start := time.Now()
+
+	for i, name := range []string{"a", "b", "c", "d"} {
+		history.Add(audit.Event{Kind: audit.KindContext, Context: name, Time: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	contexts := func(events []audit.Event) []string {
+		names := []string{}
+		for _, event := range events {
+			names = append(names, event.Context)
+		}
+
+		return names
+	}
+
+	// The oldest event was dropped.
+	assert.Equal(t, []string{"b", "c", "d"}, contexts(history.Query(audit.Query{})))
+	assert.Equal(t, []string{"c"}, contexts(history.Query(audit.Query{Context: "c"})))
+	assert.Equal(t, []string{"c", "d"}, contexts(history.Query(audit.Query{Since: start.Add(2 * time.Minute)})))
+	assert.Equal(t, []string{"d"}, contexts(history.Query(audit.Query{Limit: 1})))
+
+	// A renamed context is found under its new name too.
+	history.Add(audit.Event{Kind: audit.KindContext, Context: "e", NewName: "f", Verb: audit.VerbRename})
+	assert.Equal(t, []string{"e"}, contexts(history.Query(audit.Query{Context: "f"})))
+}
+
+func TestRecorderContextEvents(t *testing.T) {
+	recorder, err := audit.NewRecorder(audit.Options{HistorySize: 10})
+	require.NoError(t, err)
+
+	require.NoError(t, recorder.Record(audit.Event{Kind: audit.KindRequest, Context: "minikube"}))
+	require.NoError(t, recorder.Record(audit.Event{Kind: audit.KindContext, Context: "minikube"}))
+
+	// Only the context events are kept.
+	events, ok := recorder.ContextEvents(audit.Query{})
+	require.True(t, ok)
+	assert.Len(t, events, 1)
+
+	recorder = audit.NewRecorderWithSinks()
+	_, ok = recorder.ContextEvents(audit.Query{})
+	assert.False(t, ok)
+}
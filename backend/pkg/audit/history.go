@@ -0,0 +1,92 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"sync"
+	"time"
+)
+
+// Query selects events from a History.
+type Query struct {
+	// Context, if set, selects the events of the context, under its old or new name.
+	Context string
+	// Since, if set, selects the events from this time on.
+	Since time.Time
+	// Limit, if positive, is the maximum number of events returned, the latest ones.
+	Limit int
+}
+
+// matches tells whether the event is selected by the query.
+func (q Query) matches(event Event) bool {
+	if q.Context != "" && event.Context != q.Context && event.NewName != q.Context &&
+		event.OriginalName != q.Context {
+		return false
+	}
+
+	return q.Since.IsZero() || !event.Time.Before(q.Since)
+}
+
+// History keeps the latest events in memory, dropping the oldest ones beyond its size.
+type History struct {
+	mu     sync.Mutex
+	events []Event
+	// next is the index of events where the next event goes, once it is full.
+	next int
+	size int
+}
+
+// NewHistory creates a History keeping the latest size events.
+func NewHistory(size int) *History {
+	return &History{events: make([]Event, 0, size), size: size}
+}
+
+// Add adds an event, dropping the oldest one if the history is full.
+func (h *History) Add(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.events) < h.size {
+		h.events = append(h.events, event)
+
+		return
+	}
+
+	h.events[h.next] = event
+	h.next = (h.next + 1) % h.size
+}
+
+// Query returns the events matching the query, oldest first.
+func (h *History) Query(query Query) []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := []Event{}
+
+	for i := range h.events {
+		event := h.events[(h.next+i)%len(h.events)]
+		if query.matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	if query.Limit > 0 && len(events) > query.Limit {
+		events = events[len(events)-query.Limit:]
+	}
+
+	return events
+}
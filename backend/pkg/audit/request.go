@@ -0,0 +1,65 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"strings"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/apirequest"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+)
+
+// userClaims are the token claims telling the user, by order of preference.
+var userClaims = []string{"email", "preferred_username", "sub"}
+
+// SetRequest sets the verb and the resource of the event from the method and the
+// path of a request to the Kubernetes API, e.g. /api/v1/namespaces/default/pods/nginx.
+func (e *Event) SetRequest(method, path string) {
+	info := apirequest.Parse(method, path, nil)
+
+	e.Method = method
+	e.Path = path
+	e.Verb = info.Verb
+	e.APIGroup = info.APIGroup
+	e.APIVersion = info.APIVersion
+	e.Resource = info.Resource
+	e.Subresource = info.Subresource
+	e.Namespace = info.Namespace
+	e.Name = info.Name
+}
+
+// UserFromToken returns the user a token belongs to, from its email, preferred_username
+// or sub claim, or "" if the token is not a JWT.
+func UserFromToken(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	claims, err := auth.DecodeBase64JSON(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	for _, claim := range userClaims {
+		if user, ok := claims[claim].(string); ok && user != "" {
+			return user
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+const (
+	// FileMode is the mode of the audit log file, which only the user running Headlamp can read.
+	FileMode = 0o600
+	// WebhookTimeout is the timeout of the requests posting events to the webhook.
+	WebhookTimeout = 10 * time.Second
+	// WebhookQueueSize is the number of events waiting to be posted to the webhook,
+	// beyond which events are dropped.
+	WebhookQueueSize = 1000
+)
+
+// FileSink appends the events as JSON lines to a file.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens the file to append the events to, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, FileMode)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+// Record appends the event to the file.
+func (s *FileSink) Record(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(line, '\n'))
+
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// WebhookSink posts the events as JSON to a webhook. The events are posted in the
+// background, in order, so the audited requests don't wait for the webhook.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan Event
+	done   chan struct{}
+	// mu guards closed, so no event is queued once the queue is closed.
+	mu     sync.Mutex
+	closed bool
+}
+
+// ErrSinkClosed is returned when recording an event to a closed sink.
+var ErrSinkClosed = errors.New("audit sink is closed")
+
+// NewWebhookSink creates a sink posting the events to the webhook URL.
+func NewWebhookSink(webhookURL string) (*WebhookSink, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid audit webhook URL %q", webhookURL)
+	}
+
+	s := &WebhookSink{
+		url:    webhookURL,
+		client: &http.Client{Timeout: WebhookTimeout},
+		queue:  make(chan Event, WebhookQueueSize),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Record queues the event to be posted. It fails if the queue is full, or with ErrSinkClosed
+// if the sink is closed, as the requests still served on shutdown may record events after it.
+func (s *WebhookSink) Record(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		return errors.New("audit webhook queue is full, dropping event")
+	}
+}
+
+// Close posts the queued events and stops the sink.
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+
+	return nil
+}
+
+// run posts the queued events until the sink is closed.
+func (s *WebhookSink) run() {
+	defer close(s.done)
+
+	for event := range s.queue {
+		if err := s.post(event); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"url": s.url, "context": event.Context,
+				"verb": event.Verb, "path": event.Path}, err, "posting audit event")
+		}
+	}
+}
+
+// post posts an event to the webhook.
+func (s *WebhookSink) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("audit webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
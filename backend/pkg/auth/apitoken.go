@@ -0,0 +1,226 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APITokenHeader is the header carrying a backend API token.
+const APITokenHeader = "X-HEADLAMP-API-TOKEN"
+
+// APITokenOptions configures an APITokenAuthenticator.
+type APITokenOptions struct {
+	// TokensFile is a CSV file of static tokens, one "token,name" per line.
+	TokensFile string
+	// JWTSecretFile is the file holding the secret the HS256 JWT tokens are signed with.
+	JWTSecretFile string
+}
+
+// APITokenAuthenticator authenticates the automation and CLI tools calling the backend
+// with an API token: a static token, or a JWT signed with the configured secret.
+type APITokenAuthenticator struct {
+	opts APITokenOptions
+	now  func() time.Time
+
+	mu sync.RWMutex
+	// static maps the SHA-256 of the static tokens to their name.
+	static    map[[sha256.Size]byte]string
+	jwtSecret []byte
+}
+
+// NewAPITokenAuthenticator loads the tokens configured by opts. It returns nil if
+// there is none, API tokens being disabled.
+func NewAPITokenAuthenticator(opts APITokenOptions) (*APITokenAuthenticator, error) {
+	if opts.TokensFile == "" && opts.JWTSecretFile == "" {
+		return nil, nil
+	}
+
+	a := &APITokenAuthenticator{opts: opts, now: time.Now}
+
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Reload reads the tokens files again, so tokens can be added and revoked without
+// a restart. The current tokens are kept if the files cannot be loaded.
+func (a *APITokenAuthenticator) Reload() error {
+	static := map[[sha256.Size]byte]string{}
+
+	var jwtSecret []byte
+
+	if a.opts.TokensFile != "" {
+		file, err := os.Open(a.opts.TokensFile)
+		if err != nil {
+			return fmt.Errorf("opening API tokens file: %w", err)
+		}
+
+		defer file.Close()
+
+		if err := loadStaticTokens(file, static); err != nil {
+			return fmt.Errorf("loading API tokens file: %w", err)
+		}
+	}
+
+	if a.opts.JWTSecretFile != "" {
+		secret, err := os.ReadFile(a.opts.JWTSecretFile)
+		if err != nil {
+			return fmt.Errorf("reading API token JWT secret: %w", err)
+		}
+
+		jwtSecret = []byte(strings.TrimSpace(string(secret)))
+		if len(jwtSecret) == 0 {
+			return errors.New("API token JWT secret is empty")
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.static = static
+	a.jwtSecret = jwtSecret
+
+	return nil
+}
+
+// loadStaticTokens reads the "token,name" lines of r into static. Empty lines and
+// lines starting with # are skipped.
+func loadStaticTokens(r io.Reader, static map[[sha256.Size]byte]string) error {
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		record, err := csv.NewReader(strings.NewReader(text)).Read()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			return fmt.Errorf("line %d: want token,name", line)
+		}
+
+		static[sha256.Sum256([]byte(record[0]))] = record[1]
+	}
+
+	return scanner.Err()
+}
+
+// Authenticate returns the name of the API token, or an error if it is not valid.
+func (a *APITokenAuthenticator) Authenticate(token string) (string, error) {
+	if token == "" {
+		return "", errors.New("empty API token")
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// The tokens are compared through their hash, so the lookup doesn't leak them.
+	if name, ok := a.static[sha256.Sum256([]byte(token))]; ok {
+		return name, nil
+	}
+
+	if a.jwtSecret != nil && strings.Count(token, ".") == 2 {
+		return a.authenticateJWT(token)
+	}
+
+	return "", errors.New("unknown API token")
+}
+
+// authenticateJWT validates an HS256 JWT signed with the secret, and its exp and nbf
+// claims if any. The name of the token is its sub claim.
+func (a *APITokenAuthenticator) authenticateJWT(token string) (string, error) {
+	parts := strings.Split(token, ".")
+
+	header, err := DecodeBase64JSON(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("decoding JWT header: %w", err)
+	}
+
+	if header["alg"] != "HS256" {
+		return "", fmt.Errorf("unsupported JWT algorithm %v, must be HS256", header["alg"])
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("decoding JWT signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	if subtle.ConstantTimeCompare(signature, mac.Sum(nil)) != 1 {
+		return "", errors.New("invalid JWT signature")
+	}
+
+	claims, err := DecodeBase64JSON(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decoding JWT claims: %w", err)
+	}
+
+	now := a.now()
+
+	if exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0)) {
+		return "", errors.New("JWT is expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return "", errors.New("JWT is not valid yet")
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return "", errors.New("JWT has no sub claim")
+	}
+
+	return sub, nil
+}
+
+// apiTokenNameKey is the context key of the name of the API token a request is authenticated with.
+type apiTokenNameKey struct{}
+
+// WithAPITokenName returns a copy of ctx for a request authenticated with the API token of the name.
+func WithAPITokenName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiTokenNameKey{}, name)
+}
+
+// APITokenNameFromContext returns the name of the API token the request of ctx is
+// authenticated with, or "" if it isn't.
+func APITokenNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(apiTokenNameKey{}).(string)
+
+	return name
+}
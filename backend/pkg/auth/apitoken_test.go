@@ -0,0 +1,167 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signJWT signs the claims as a JWT with the algorithm and secret.
+func signJWT(t *testing.T, alg, secret string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestNewAPITokenAuthenticator(t *testing.T) {
+	authenticator, err := auth.NewAPITokenAuthenticator(auth.APITokenOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, authenticator)
+
+	_, err = auth.NewAPITokenAuthenticator(auth.APITokenOptions{TokensFile: writeFile(t, "tokens.csv", "lonely-token\n")})
+	assert.Error(t, err)
+
+	_, err = auth.NewAPITokenAuthenticator(auth.APITokenOptions{JWTSecretFile: writeFile(t, "secret", "\n")})
+	assert.Error(t, err)
+
+	_, err = auth.NewAPITokenAuthenticator(auth.APITokenOptions{TokensFile: "/does/not/exist"})
+	assert.Error(t, err)
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	tokens := "# automation tokens\nci-token,ci\n\n\"token,with,commas\",scripts\n"
+
+	authenticator, err := auth.NewAPITokenAuthenticator(auth.APITokenOptions{
+		TokensFile:    writeFile(t, "tokens.csv", tokens),
+		JWTSecretFile: writeFile(t, "secret", "jwt-secret\n"),
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{name: "static", token: "ci-token", want: "ci"},
+		{name: "static_quoted", token: "token,with,commas", want: "scripts"},
+		{name: "unknown", token: "other-token", wantErr: true},
+		{name: "empty", token: "", wantErr: true},
+		{
+			name:  "jwt",
+			token: signJWT(t, "HS256", "jwt-secret", map[string]interface{}{"sub": "cli", "exp": now.Add(time.Hour).Unix()}),
+			want:  "cli",
+		},
+		{
+			name:    "jwt_expired",
+			token:   signJWT(t, "HS256", "jwt-secret", map[string]interface{}{"sub": "cli", "exp": now.Add(-time.Hour).Unix()}),
+			wantErr: true,
+		},
+		{
+			name:    "jwt_not_yet_valid",
+			token:   signJWT(t, "HS256", "jwt-secret", map[string]interface{}{"sub": "cli", "nbf": now.Add(time.Hour).Unix()}),
+			wantErr: true,
+		},
+		{
+			name:    "jwt_wrong_secret",
+			token:   signJWT(t, "HS256", "other-secret", map[string]interface{}{"sub": "cli"}),
+			wantErr: true,
+		},
+		{
+			name:    "jwt_wrong_algorithm",
+			token:   signJWT(t, "none", "jwt-secret", map[string]interface{}{"sub": "cli"}),
+			wantErr: true,
+		},
+		{
+			name:    "jwt_without_sub",
+			token:   signJWT(t, "HS256", "jwt-secret", map[string]interface{}{}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := authenticator.Authenticate(tt.token)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestAPITokenReload(t *testing.T) {
+	path := writeFile(t, "tokens.csv", "old-token,ci\n")
+
+	authenticator, err := auth.NewAPITokenAuthenticator(auth.APITokenOptions{TokensFile: path})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("new-token,ci\n"), 0o600))
+	require.NoError(t, authenticator.Reload())
+
+	_, err = authenticator.Authenticate("old-token")
+	assert.Error(t, err)
+
+	name, err := authenticator.Authenticate("new-token")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", name)
+
+	// An invalid file keeps the current tokens.
+	require.NoError(t, os.WriteFile(path, []byte("lonely-token\n"), 0o600))
+	assert.Error(t, authenticator.Reload())
+
+	name, err = authenticator.Authenticate("new-token")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", name)
+}
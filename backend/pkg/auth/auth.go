@@ -115,25 +115,42 @@ func GetExpiryUnixTimeUTC(tokenPayload map[string]interface{}) (time.Time, error
 // IsTokenAboutToExpire reports whether the given token is within JWTExpirationTTL
 // of its expiry time.
 func IsTokenAboutToExpire(token string) bool {
+	expiryUnixTimeUTC, ok := tokenExpiry(token)
+	if !ok {
+		return false
+	}
+
+	// This time comparison is timezone aware, so it works correctly
+	return time.Until(expiryUnixTimeUTC) <= JWTExpirationTTL
+}
+
+// IsTokenExpired reports whether the given token is past its expiry time.
+func IsTokenExpired(token string) bool {
+	expiryUnixTimeUTC, ok := tokenExpiry(token)
+
+	return ok && !time.Now().Before(expiryUnixTimeUTC)
+}
+
+// tokenExpiry returns the expiry time of the given JWT, if it has one.
+func tokenExpiry(token string) (time.Time, bool) {
 	parts := strings.SplitN(token, ".", 3)
 	if len(parts) != 3 || parts[1] == "" {
-		return false
+		return time.Time{}, false
 	}
 
 	payload, err := DecodeBase64JSON(parts[1])
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "failed to decode payload")
-		return false
+		return time.Time{}, false
 	}
 
 	expiryUnixTimeUTC, err := GetExpiryUnixTimeUTC(payload)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "failed to get expiry time")
-		return false
+		return time.Time{}, false
 	}
 
-	// This time comparison is timezone aware, so it works correctly
-	return time.Until(expiryUnixTimeUTC) <= JWTExpirationTTL
+	return expiryUnixTimeUTC, true
 }
 
 // CacheRefreshedToken updates the refresh token in the cache.
@@ -157,6 +174,19 @@ func CacheRefreshedToken(token *oauth2.Token, tokenType string, oldToken string,
 		return err
 	}
 
+	// The new token comes from the token endpoint for the user of the old one.
+	if user := VerifiedUser(ctx, cache, oldToken); user != "" {
+		expiry, ok := tokenExpiry(newToken)
+		if !ok {
+			expiry = token.Expiry
+		}
+
+		if err := CacheVerifiedUser(ctx, cache, newToken, user, expiry); err != nil {
+			logger.Log(logger.LevelError, nil, err, "failed to cache the user of the refreshed token")
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -345,6 +345,29 @@ func TestIsTokenAboutToExpire_Window(t *testing.T) {
 	}
 }
 
+func TestIsTokenExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"expired", makeJWTWithPayload(t, map[string]interface{}{"exp": float64(now.Add(-5 * time.Second).Unix())}), true},
+		{"about to expire", makeJWTWithPayload(t, map[string]interface{}{
+			"exp": float64(now.Add(auth.JWTExpirationTTL / 2).Unix()),
+		}), false},
+		{"not a jwt", "not-a-jwt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auth.IsTokenExpired(tt.token); got != tt.want {
+				t.Fatalf("IsTokenExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsTokenAboutToExpire_InvalidInputs(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -383,6 +406,12 @@ func (cacheStub) UpdateTTL(ctx context.Context, k string, ttl time.Duration) err
 	return nil
 }
 
+func (cacheStub) Stats() cache.Stats {
+	return cache.Stats{}
+}
+
+func (cacheStub) Close() {}
+
 type fakeCache struct {
 	cacheStub
 	store    map[string]interface{}
@@ -17,11 +17,15 @@ limitations under the License.
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
+	"sync/atomic"
 )
 
 const (
@@ -29,6 +33,16 @@ const (
 	chunkSize = 3800
 )
 
+// hashLongClusterNames is set when the long cluster names keep a hash of the full name.
+var hashLongClusterNames atomic.Bool
+
+// SetHashLongClusterNames sets whether the long cluster names keep a hash of the full name
+// in the cookie names. It is set with the rfc1123-subdomain context naming, whose long names
+// would otherwise share their cookies, while the other namings keep the cookies they had.
+func SetHashLongClusterNames(hash bool) {
+	hashLongClusterNames.Store(hash)
+}
+
 // SanitizeClusterName ensures cluster names are safe for use in cookie names.
 func SanitizeClusterName(cluster string) string {
 	// Only allow alphanumeric characters, hyphens, and underscores
@@ -37,7 +51,12 @@ func SanitizeClusterName(cluster string) string {
 
 	// Limit length to prevent issues
 	if len(sanitized) > 50 {
-		sanitized = sanitized[:50]
+		if !hashLongClusterNames.Load() {
+			return sanitized[:50]
+		}
+
+		hash := sha256.Sum256([]byte(cluster))
+		sanitized = sanitized[:41] + "-" + hex.EncodeToString(hash[:4])
 	}
 
 	return sanitized
@@ -124,6 +143,36 @@ func GetTokenFromCookie(r *http.Request, cluster string) (string, error) {
 	return "", nil
 }
 
+// CredentialsHash returns a hash of the credentials of the request: its bearer token, or else
+// its authentication cookies, of all the clusters. It's "" if the request has neither. It tells
+// the clients of the backend apart without keeping their credentials.
+func CredentialsHash(r *http.Request) string {
+	hash := sha256.New()
+
+	if _, token := ParseClusterAndToken(r); token != "" {
+		hash.Write([]byte(token))
+
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+
+	var cookies []string
+
+	for _, cookie := range r.Cookies() {
+		if strings.HasPrefix(cookie.Name, "headlamp-auth-") && cookie.Value != "" {
+			cookies = append(cookies, cookie.Name+"="+cookie.Value)
+		}
+	}
+
+	if len(cookies) == 0 {
+		return ""
+	}
+
+	sort.Strings(cookies)
+	hash.Write([]byte(strings.Join(cookies, "\n")))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
 // ClearTokenCookie clears an authentication cookie for a specific cluster.
 func ClearTokenCookie(w http.ResponseWriter, r *http.Request, cluster string) {
 	sanitizedCluster := SanitizeClusterName(cluster)
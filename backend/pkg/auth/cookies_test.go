@@ -52,6 +52,30 @@ func TestSanitizeClusterName(t *testing.T) {
 	}
 }
 
+func TestSanitizeClusterNameHashLongNames(t *testing.T) {
+	auth.SetHashLongClusterNames(true)
+	t.Cleanup(func() { auth.SetHashLongClusterNames(false) })
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"my-cluster", "my-cluster"},
+		{"very-long-cluster-name-that-exceeds-fifty-characters-limit", "very-long-cluster-name-that-exceeds-fifty-b474573e"},
+		{
+			"very-long-cluster-name-that-exceeds-fifty-characters-limit.eu",
+			"very-long-cluster-name-that-exceeds-fifty-b12823ad",
+		},
+	}
+
+	for _, test := range tests {
+		result := auth.SanitizeClusterName(test.input)
+		if result != test.expected {
+			t.Errorf("SanitizeClusterName(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
 var isSecureContextTests = []struct {
 	name     string
 	setupReq func() *http.Request
@@ -239,3 +263,43 @@ func TestClearAuthCookie(t *testing.T) {
 		t.Errorf("Expected MaxAge to be -1, got %d", cookie.MaxAge)
 	}
 }
+
+func TestCredentialsHash(t *testing.T) {
+	hash := func(header string, cookies ...*http.Cookie) string {
+		req := httptest.NewRequest("GET", localhostOrigin, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		return auth.CredentialsHash(req)
+	}
+
+	if got := hash(""); got != "" {
+		t.Errorf("Expected no hash without credentials, got %q", got)
+	}
+
+	bearer := hash("Bearer token-a")
+	if bearer == "" || strings.Contains(bearer, "token-a") {
+		t.Errorf("Expected a hash of the bearer token, got %q", bearer)
+	}
+
+	if bearer == hash("Bearer token-b") {
+		t.Error("Expected other tokens to have other hashes")
+	}
+
+	cookieA := &http.Cookie{Name: "headlamp-auth-a.0", Value: "token-a"}
+	cookieB := &http.Cookie{Name: "headlamp-auth-b.0", Value: "token-b"}
+	other := &http.Cookie{Name: "other", Value: "value"}
+
+	if hash("", cookieA, cookieB) != hash("", other, cookieB, cookieA) {
+		t.Error("Expected the hash of the auth cookies not to depend on their order or other cookies")
+	}
+
+	if hash("", other) != "" {
+		t.Error("Expected no hash without auth cookies")
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+)
+
+// oidcUserKeyPrefix prefixes the keys of the users of the verified OIDC tokens in the cache.
+const oidcUserKeyPrefix = "oidc-user-"
+
+// CacheVerifiedUser records the user of an OIDC token whose signature was verified by the
+// backend, until the token expires, so the requests made with it can be told apart from
+// the ones made with tokens the backend never verified.
+func CacheVerifiedUser(ctx context.Context, cache cache.Cache[interface{}], token, user string,
+	expiry time.Time,
+) error {
+	ttl := time.Until(expiry)
+	if user == "" || ttl <= 0 {
+		return nil
+	}
+
+	return cache.SetWithTTL(ctx, oidcUserKeyPrefix+token, user, ttl)
+}
+
+// VerifiedUser returns the user of the OIDC token recorded by CacheVerifiedUser, or "" if the
+// backend didn't verify the token.
+func VerifiedUser(ctx context.Context, cache cache.Cache[interface{}], token string) string {
+	if token == "" {
+		return ""
+	}
+
+	user, err := cache.Get(ctx, oidcUserKeyPrefix+token)
+	if err != nil {
+		return ""
+	}
+
+	name, _ := user.(string)
+
+	return name
+}
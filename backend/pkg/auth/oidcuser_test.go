@@ -0,0 +1,55 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestVerifiedUser(t *testing.T) {
+	ctx := context.Background()
+	c := cache.New[interface{}]()
+
+	require.NoError(t, auth.CacheVerifiedUser(ctx, c, "verified", "alice", time.Now().Add(time.Hour)))
+	require.NoError(t, auth.CacheVerifiedUser(ctx, c, "expired", "bob", time.Now().Add(-time.Hour)))
+
+	assert.Equal(t, "alice", auth.VerifiedUser(ctx, c, "verified"))
+	assert.Empty(t, auth.VerifiedUser(ctx, c, "expired"))
+	assert.Empty(t, auth.VerifiedUser(ctx, c, "unknown"))
+	assert.Empty(t, auth.VerifiedUser(ctx, c, ""))
+}
+
+func TestCacheRefreshedTokenKeepsVerifiedUser(t *testing.T) {
+	ctx := context.Background()
+	c := cache.New[interface{}]()
+
+	require.NoError(t, auth.CacheVerifiedUser(ctx, c, "OLD", "alice", time.Now().Add(time.Minute)))
+
+	token := (&oauth2.Token{RefreshToken: "REFRESH_NEW", Expiry: time.Now().Add(time.Hour)}).
+		WithExtra(map[string]interface{}{"id_token": "NEW"})
+	require.NoError(t, auth.CacheRefreshedToken(token, "id_token", "OLD", "REFRESH_OLD", c))
+
+	assert.Equal(t, "alice", auth.VerifiedUser(ctx, c, "NEW"))
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TokenOverrideHeader is the header of a bearer token a request proxied to a cluster is made
+// with instead of the credentials of its context. The token is only used for that request,
+// and never stored.
+const TokenOverrideHeader = "X-HEADLAMP-TOKEN"
+
+// maxTokenOverrideLength bounds the tokens in the TokenOverrideHeader, which are JWTs at most
+// a few kilobytes long.
+const maxTokenOverrideLength = 16 << 10
+
+// ErrInvalidTokenOverride is returned for a TokenOverrideHeader that isn't a valid bearer token.
+var ErrInvalidTokenOverride = errors.New("invalid " + TokenOverrideHeader + " header, it must be a bearer token")
+
+// TokenOverride returns the bearer token in the TokenOverrideHeader of the request, "" if there's
+// none, or ErrInvalidTokenOverride if it isn't a valid bearer token. The token may be given
+// with or without the Bearer prefix.
+func TokenOverride(r *http.Request) (string, error) {
+	values := r.Header.Values(TokenOverrideHeader)
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	if len(values) > 1 {
+		return "", ErrInvalidTokenOverride
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) == 2 && strings.EqualFold(fields[0], "Bearer") {
+		fields = fields[1:]
+	}
+
+	if len(fields) != 1 || strings.EqualFold(fields[0], "Bearer") {
+		return "", ErrInvalidTokenOverride
+	}
+
+	token := fields[0]
+	if len(token) > maxTokenOverrideLength || !bearerTokenRegex.MatchString(token) {
+		return "", ErrInvalidTokenOverride
+	}
+
+	return token, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+)
+
+func TestTokenOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "token", values: []string{"abc.def.ghi"}, want: "abc.def.ghi"},
+		{name: "bearer", values: []string{"Bearer abc.def.ghi"}, want: "abc.def.ghi"},
+		{name: "empty", values: []string{"Bearer "}, wantErr: true},
+		{name: "spaces", values: []string{"abc def"}, wantErr: true},
+		{name: "control", values: []string{"abc\x00def"}, wantErr: true},
+		{name: "too_long", values: []string{strings.Repeat("a", 17<<10)}, wantErr: true},
+		{name: "repeated", values: []string{"abc", "def"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/clusters/minikube/version", nil)
+			for _, value := range tt.values {
+				r.Header.Add(auth.TokenOverrideHeader, value)
+			}
+
+			got, err := auth.TokenOverride(r)
+			if tt.wantErr {
+				if !errors.Is(err, auth.ErrInvalidTokenOverride) {
+					t.Fatalf("TokenOverride() error = %v, want ErrInvalidTokenOverride", err)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Fatalf("TokenOverride() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
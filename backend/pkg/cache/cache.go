@@ -17,8 +17,11 @@ limitations under the License.
 package cache
 
 import (
+	"container/heap"
 	"context"
 	"errors"
+	"hash/maphash"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -32,31 +35,170 @@ type Cache[T any] interface {
 	Get(ctx context.Context, key string) (T, error)
 	GetAll(ctx context.Context, selectFunc Matcher) (map[string]T, error)
 	UpdateTTL(ctx context.Context, key string, ttl time.Duration) error
+	Stats() Stats
+	// Close stops removing the expired entries in the background. The cache can still be
+	// used, the expired entries being removed only when new ones need room.
+	Close()
 }
 
+// Stats are the statistics of a cache, to size its capacity and debug its memory usage.
+type Stats struct {
+	// Entries is the number of stored entries, including the expired ones not cleaned up yet.
+	Entries int `json:"entries"`
+	// Bytes is the approximate size of the keys and values of the entries. Strings and byte
+	// slices count their length, and the other values the size of their type, or of the type
+	// they point to, without what they reference.
+	Bytes int64 `json:"bytes"`
+	// Capacity is the maximum number of entries, or 0 if the cache is unbounded.
+	Capacity int `json:"capacity"`
+	// Evictions is the number of entries removed to keep the cache within its capacity.
+	Evictions uint64 `json:"evictions"`
+	// Expired is the number of entries removed because their TTL expired.
+	Expired uint64 `json:"expired"`
+}
+
+// Option configures a cache created with New.
+type Option func(*options)
+
+type options struct {
+	maxEntries int
+	shards     int
+}
+
+// WithMaxEntries bounds the cache to n entries. When it's full, storing a new key drops
+// the expired entries, or else evicts the oldest stored one. n <= 0 means unbounded.
+//
+// The capacity is split among the shards, so the entry evicted is the oldest of the shard
+// of the new key. Small caches use fewer shards, down to one, to keep evicting the oldest.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithShards splits the cache into n shards, each with its own lock, so the goroutines
+// using different keys don't wait on each other. n <= 0 means DefaultShards.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// DefaultShards is the number of shards of a cache, unless set with WithShards.
+const DefaultShards = 32
+
+// minShardEntries is the minimum capacity of a shard of a bounded cache.
+const minShardEntries = 64
+
 // Matcher is a function that returns true if the key matches.
 type Matcher func(key string) bool
 
-var (
-	ErrNotFound     = errors.New("key not found")
-	cleanUpInterval = 10 * time.Second
-)
+var ErrNotFound = errors.New("key not found")
 
 type cacheValue[T any] struct {
 	value     T
 	expiresAt time.Time
+	// deadline is the item of the entry in the deadlines, if it has a TTL.
+	deadline *timedKey
+	// stored is the item of the entry in the stored times, if the shard is bounded.
+	stored *timedKey
 }
+
 type cache[T any] struct {
-	store           map[string]cacheValue[T]
-	lock            sync.RWMutex
-	cleanUpInterval time.Duration
+	// seed hashes the keys to pick their shard.
+	seed       maphash.Seed
+	shards     []*shard[T]
+	maxEntries int
+	// wake tells the expiring goroutine that the soonest deadline of a shard changed.
+	wake chan struct{}
+	// done stops the expiring goroutine once closed.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// shard holds the entries of the keys hashed to it.
+type shard[T any] struct {
+	store      map[string]cacheValue[T]
+	lock       sync.RWMutex
+	maxEntries int
+	// deadlines are the entries with a TTL, soonest to expire first. They are guarded by lock.
+	deadlines timeHeap
+	// stored are the entries of a bounded shard, oldest stored first, so the one to evict is
+	// found without scanning all the entries. They are guarded by lock.
+	stored timeHeap
+	wake   chan struct{}
+	// evictions and expired are the counters of the stats. They are guarded by lock.
+	evictions uint64
+	expired   uint64
+}
+
+// timedKey is a key with a time: when its entry expires, or when it was stored.
+type timedKey struct {
+	key string
+	at  time.Time
+	// index is the index of the item in its heap.
+	index int
+}
+
+// timeHeap is a min-heap of timed keys, implementing heap.Interface, so the entries that
+// expire first or were stored first are found without scanning all the entries.
+type timeHeap []*timedKey
+
+func (h timeHeap) Len() int { return len(h) }
+
+func (h timeHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h timeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timeHeap) Push(x any) {
+	t, _ := x.(*timedKey)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timeHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return t
 }
 
 // New creates a new cache.
-func New[T any]() Cache[T] {
+func New[T any](opts ...Option) Cache[T] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	cache := &cache[T]{
-		store:           make(map[string]cacheValue[T]),
-		cleanUpInterval: cleanUpInterval,
+		seed:       maphash.MakeSeed(),
+		maxEntries: o.maxEntries,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	n := shardCount(o)
+	for i := range n {
+		shard := &shard[T]{
+			store: make(map[string]cacheValue[T]),
+			wake:  cache.wake,
+		}
+
+		// The capacity is split evenly, the first shards taking the remainder.
+		if o.maxEntries > 0 {
+			shard.maxEntries = o.maxEntries / n
+			if i < o.maxEntries%n {
+				shard.maxEntries++
+			}
+		}
+
+		cache.shards = append(cache.shards, shard)
 	}
 
 	go cache.cleanUp()
@@ -64,6 +206,29 @@ func New[T any]() Cache[T] {
 	return cache
 }
 
+// shardCount returns the number of shards of a cache created with the options.
+func shardCount(o options) int {
+	n := o.shards
+	if n <= 0 {
+		n = DefaultShards
+	}
+
+	if o.maxEntries > 0 {
+		n = min(n, max(1, o.maxEntries/minShardEntries))
+	}
+
+	return n
+}
+
+// shard returns the shard of the key.
+func (c *cache[T]) shard(key string) *shard[T] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
 // Set stores a value in the cache.
 func (c *cache[T]) Set(ctx context.Context, key string, value T) error {
 	return c.SetWithTTL(ctx, key, value, 0)
@@ -71,38 +236,143 @@ func (c *cache[T]) Set(ctx context.Context, key string, value T) error {
 
 // SetWithTTL stores a value in the cache with a TTL.
 func (c *cache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	s := c.shard(key)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
 
 	expiresAt := time.Time{}
 	if ttl != 0 {
-		expiresAt = time.Now().Add(ttl)
+		expiresAt = now.Add(ttl)
+	}
+
+	existing, ok := s.store[key]
+	if !ok {
+		s.makeRoom(now)
 	}
 
-	c.store[key] = cacheValue[T]{
+	s.store[key] = s.withDeadline(key, cacheValue[T]{
 		value:     value,
 		expiresAt: expiresAt,
-	}
+		deadline:  existing.deadline,
+		stored:    s.storedAt(key, existing.stored, now),
+	})
 
 	return nil
 }
 
+// storedAt records that the entry of the key, whose item in the stored times is stored, if
+// any, was stored at now, and returns its item. Only the bounded shards record the stored
+// times. s.lock must be held.
+func (s *shard[T]) storedAt(key string, stored *timedKey, now time.Time) *timedKey {
+	if s.maxEntries <= 0 {
+		return nil
+	}
+
+	if stored == nil {
+		stored = &timedKey{key: key, at: now}
+		heap.Push(&s.stored, stored)
+
+		return stored
+	}
+
+	stored.at = now
+	heap.Fix(&s.stored, stored.index)
+
+	return stored
+}
+
+// withDeadline updates the deadline of the entry of the key to its expiry, adding it to the
+// deadlines or removing it from them as needed, and returns the entry. s.lock must be held.
+func (s *shard[T]) withDeadline(key string, value cacheValue[T]) cacheValue[T] {
+	switch {
+	case value.expiresAt.IsZero() && value.deadline != nil:
+		heap.Remove(&s.deadlines, value.deadline.index)
+		value.deadline = nil
+	case value.expiresAt.IsZero():
+	case value.deadline != nil:
+		value.deadline.at = value.expiresAt
+		heap.Fix(&s.deadlines, value.deadline.index)
+	default:
+		value.deadline = &timedKey{key: key, at: value.expiresAt}
+		heap.Push(&s.deadlines, value.deadline)
+	}
+
+	if value.deadline != nil && value.deadline.index == 0 {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return value
+}
+
+// remove removes the entry of the key. s.lock must be held.
+func (s *shard[T]) remove(key string) {
+	value, ok := s.store[key]
+	if !ok {
+		return
+	}
+
+	if value.deadline != nil {
+		heap.Remove(&s.deadlines, value.deadline.index)
+	}
+
+	if value.stored != nil {
+		heap.Remove(&s.stored, value.stored.index)
+	}
+
+	delete(s.store, key)
+}
+
+// makeRoom removes entries until a new one fits within the capacity of the shard: the
+// expired ones first, then the oldest stored ones, taken from the stored times. s.lock
+// must be held.
+func (s *shard[T]) makeRoom(now time.Time) {
+	if s.maxEntries <= 0 || len(s.store) < s.maxEntries {
+		return
+	}
+
+	s.removeExpired(now)
+
+	for len(s.store) >= s.maxEntries && len(s.stored) > 0 {
+		s.remove(s.stored[0].key)
+		s.evictions++
+	}
+}
+
+// removeExpired removes the expired entries, taking them from the deadlines. s.lock must
+// be held.
+func (s *shard[T]) removeExpired(now time.Time) {
+	for len(s.deadlines) > 0 && s.deadlines[0].at.Before(now) {
+		s.remove(s.deadlines[0].key)
+		s.expired++
+	}
+}
+
 // Delete removes a value from the cache.
 func (c *cache[T]) Delete(ctx context.Context, key string) error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	s := c.shard(key)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	delete(c.store, key)
+	s.remove(key)
 
 	return nil
 }
 
 // Get retrieves a value from the cache.
 func (c *cache[T]) Get(ctx context.Context, key string) (T, error) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	s := c.shard(key)
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
 
-	value, ok := c.store[key]
+	value, ok := s.store[key]
 	if !ok {
 		return *new(T), ErrNotFound
 	}
@@ -114,58 +384,128 @@ func (c *cache[T]) Get(ctx context.Context, key string) (T, error) {
 	return *new(T), ErrNotFound
 }
 
-// GetAll retrieves all values from the cache.
+// GetAll retrieves all values from the cache. The shards are read one after the other, so
+// the values aren't a snapshot of the cache at a single point in time.
 func (c *cache[T]) GetAll(ctx context.Context, selectFunc Matcher) (map[string]T, error) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
-
 	values := make(map[string]T)
+	now := time.Now()
 
-	for key, value := range c.store {
-		if selectFunc != nil && !selectFunc(key) {
-			continue
-		}
+	for _, s := range c.shards {
+		s.lock.RLock()
+
+		for key, value := range s.store {
+			if selectFunc != nil && !selectFunc(key) {
+				continue
+			}
 
-		if (value.expiresAt.IsZero()) || (!value.expiresAt.IsZero() && value.expiresAt.After(time.Now())) {
-			values[key] = value.value
+			if value.expiresAt.IsZero() || value.expiresAt.After(now) {
+				values[key] = value.value
+			}
 		}
+
+		s.lock.RUnlock()
 	}
 
 	return values, nil
 }
 
-// cleanUp removes expired values from the cache.
+// cleanUp removes expired values from the cache as they expire, until the cache is closed.
+// It sleeps until the soonest deadline of the shards, or until it's woken up because a sooner
+// one was added.
 func (c *cache[T]) cleanUp() {
-	ticker := time.NewTicker(c.cleanUpInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
 	for {
-		<-ticker.C
+		select {
+		case <-timer.C:
+		case <-c.wake:
+		case <-c.done:
+			return
+		}
+
+		soonest := time.Time{}
 
-		c.lock.Lock()
-		for key, value := range c.store {
-			if !value.expiresAt.IsZero() && value.expiresAt.Before(time.Now()) {
-				delete(c.store, key)
+		for _, s := range c.shards {
+			s.lock.Lock()
+			s.removeExpired(time.Now())
+
+			if len(s.deadlines) > 0 && (soonest.IsZero() || s.deadlines[0].at.Before(soonest)) {
+				soonest = s.deadlines[0].at
 			}
+			s.lock.Unlock()
+		}
+
+		if !soonest.IsZero() {
+			// The deadline passes once it's before the current time, so wait a bit past it.
+			timer.Reset(time.Until(soonest) + time.Millisecond)
+		} else {
+			timer.Stop()
 		}
-		c.lock.Unlock()
 	}
 }
 
 // UpdateTTL updates the TTL of a value in the cache.
 func (c *cache[T]) UpdateTTL(ctx context.Context, key string, ttl time.Duration) error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	s := c.shard(key)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	value, ok := c.store[key]
+	value, ok := s.store[key]
 	if !ok {
 		return ErrNotFound
 	}
 
 	if value.expiresAt.IsZero() || value.expiresAt.After(time.Now()) {
 		value.expiresAt = time.Now().Add(ttl)
-		c.store[key] = value
+		s.store[key] = s.withDeadline(key, value)
 	}
 
 	return nil
 }
+
+// Close stops the goroutine removing the expired entries. It can be called more than once.
+func (c *cache[T]) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// Stats returns the statistics of the cache, adding up those of its shards.
+func (c *cache[T]) Stats() Stats {
+	stats := Stats{Capacity: c.maxEntries}
+
+	for _, s := range c.shards {
+		s.lock.RLock()
+
+		stats.Entries += len(s.store)
+		stats.Evictions += s.evictions
+		stats.Expired += s.expired
+
+		for key, value := range s.store {
+			stats.Bytes += int64(len(key)) + approximateSize(value.value)
+		}
+
+		s.lock.RUnlock()
+	}
+
+	return stats
+}
+
+// approximateSize returns the approximate size of a value, as documented in Stats.Bytes.
+func approximateSize(value any) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return int64(t.Size())
+}
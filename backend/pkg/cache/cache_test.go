@@ -18,6 +18,9 @@ package cache_test
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
 	"testing"
 	"time"
 
@@ -108,3 +111,147 @@ func TestCacheGetAll(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(values))
 }
+
+func TestCacheStats(t *testing.T) {
+	ch := cache.New[string](cache.WithMaxEntries(2))
+
+	require.NoError(t, ch.Set(context.Background(), "key1", "value1"))
+	require.NoError(t, ch.Set(context.Background(), "key2", "value2"))
+
+	stats := ch.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(20), stats.Bytes)
+	assert.Equal(t, 2, stats.Capacity)
+
+	// Updating a key doesn't evict anything.
+	require.NoError(t, ch.Set(context.Background(), "key2", "value22"))
+	assert.Zero(t, ch.Stats().Evictions)
+
+	// The oldest entry is evicted to make room for a new key.
+	require.NoError(t, ch.Set(context.Background(), "key3", "value3"))
+
+	_, err := ch.Get(context.Background(), "key1")
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
+	stats = ch.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	// The expired entries are dropped before evicting the others.
+	require.NoError(t, ch.SetWithTTL(context.Background(), "key2", "value2", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, ch.Set(context.Background(), "key4", "value4"))
+
+	_, err = ch.Get(context.Background(), "key3")
+	require.NoError(t, err)
+
+	stats = ch.Stats()
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, uint64(1), stats.Expired)
+}
+
+func TestCacheStatsPointerValues(t *testing.T) {
+	type value struct {
+		a, b int64
+	}
+
+	ch := cache.New[*value]()
+	require.NoError(t, ch.Set(context.Background(), "key", &value{}))
+
+	stats := ch.Stats()
+	assert.Equal(t, int64(len("key")+16), stats.Bytes)
+	assert.Zero(t, stats.Capacity)
+}
+
+func TestCacheExpiresOnDeadline(t *testing.T) {
+	ch := cache.New[string]()
+
+	require.NoError(t, ch.SetWithTTL(context.Background(), "short", "value", 50*time.Millisecond))
+	require.NoError(t, ch.SetWithTTL(context.Background(), "long", "value", time.Hour))
+
+	// Storing a key again without a TTL drops its deadline.
+	require.NoError(t, ch.SetWithTTL(context.Background(), "kept", "value", 50*time.Millisecond))
+	require.NoError(t, ch.Set(context.Background(), "kept", "value"))
+
+	// The expired entries are removed at their deadline, not on the next periodic scan.
+	assert.Eventually(t, func() bool {
+		return ch.Stats().Expired == 1
+	}, time.Second, 10*time.Millisecond)
+
+	stats := ch.Stats()
+	assert.Equal(t, 2, stats.Entries)
+
+	_, err := ch.Get(context.Background(), "kept")
+	require.NoError(t, err)
+
+	// Deleting a key drops its deadline too.
+	require.NoError(t, ch.Delete(context.Background(), "long"))
+	assert.Equal(t, 1, ch.Stats().Entries)
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	ch := cache.New[int](cache.WithMaxEntries(1000))
+
+	var wg sync.WaitGroup
+
+	for g := range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				key := fmt.Sprintf("key%d", (g*1000+i)%2000)
+
+				require.NoError(t, ch.SetWithTTL(context.Background(), key, i, time.Minute))
+				_, _ = ch.Get(context.Background(), key)
+				_ = ch.UpdateTTL(context.Background(), key, time.Hour)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	stats := ch.Stats()
+	assert.LessOrEqual(t, stats.Entries, 1000)
+	assert.Positive(t, stats.Evictions)
+}
+
+// BenchmarkCacheMixed measures a mix of reads, writes and TTL updates of random keys by many
+// goroutines, as the multiplexer subscriptions do looking up contexts and tokens, with a
+// single shard and with the default shards.
+func BenchmarkCacheMixed(b *testing.B) {
+	const keys = 10000
+
+	for _, shards := range []int{1, cache.DefaultShards} {
+		for _, parallelism := range []int{1, 16, 64} {
+			b.Run(fmt.Sprintf("shards=%d/goroutines=%dxGOMAXPROCS", shards, parallelism), func(b *testing.B) {
+				ch := cache.New[string](cache.WithShards(shards))
+
+				for i := range keys {
+					_ = ch.SetWithTTL(context.Background(), fmt.Sprintf("key%d", i), "value", time.Hour)
+				}
+
+				b.SetParallelism(parallelism)
+				b.ResetTimer()
+
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						key := fmt.Sprintf("key%d", rand.IntN(keys))
+
+						switch op := rand.IntN(100); {
+						case op < 80:
+							_, _ = ch.Get(context.Background(), key)
+						case op < 90:
+							_ = ch.Set(context.Background(), key, "value")
+						case op < 95:
+							_ = ch.SetWithTTL(context.Background(), key, "value", time.Hour)
+						default:
+							_ = ch.UpdateTTL(context.Background(), key, time.Hour)
+						}
+					}
+				})
+			})
+		}
+	}
+}
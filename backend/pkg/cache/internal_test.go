@@ -0,0 +1,199 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// removeExpiredByScan is the removal of the expired entries the deadlines replaced,
+// scanning all the entries, kept as the baseline of the benchmarks.
+func (s *shard[T]) removeExpiredByScan(now time.Time) {
+	for key, value := range s.store {
+		if !value.expiresAt.IsZero() && value.expiresAt.Before(now) {
+			s.remove(key)
+			s.expired++
+		}
+	}
+}
+
+// evictOldestByScan is the eviction the stored times replaced, scanning all the entries for
+// the oldest stored one, kept as the baseline of the benchmarks.
+func (s *shard[T]) evictOldestByScan() {
+	oldestKey := ""
+	oldest := time.Time{}
+
+	for key, value := range s.store {
+		if oldestKey == "" || value.stored.at.Before(oldest) {
+			oldestKey, oldest = key, value.stored.at
+		}
+	}
+
+	s.remove(oldestKey)
+	s.evictions++
+}
+
+// BenchmarkRemoveExpired measures removing the expired entries when one of n entries with a
+// TTL has expired, as happens with thousands of contexts and tokens expiring at different
+// times, by scanning the entries and with the deadlines.
+func BenchmarkRemoveExpired(b *testing.B) {
+	for _, method := range []string{"scan", "heap"} {
+		for _, n := range []int{1000, 10000, 100000} {
+			b.Run(fmt.Sprintf("%s/entries=%d", method, n), func(b *testing.B) {
+				c := New[string](WithShards(1)).(*cache[string])
+				b.Cleanup(c.Close)
+
+				for i := range n {
+					_ = c.SetWithTTL(context.Background(), fmt.Sprintf("key%d", i), "value", time.Hour)
+				}
+
+				s := c.shard("expired")
+
+				b.ResetTimer()
+
+				for range b.N {
+					_ = c.SetWithTTL(context.Background(), "expired", "value", -time.Second)
+
+					s.lock.Lock()
+					if method == "scan" {
+						s.removeExpiredByScan(time.Now())
+					} else {
+						s.removeExpired(time.Now())
+					}
+					s.lock.Unlock()
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkEvict measures storing a new key in a full cache of n entries, evicting the
+// oldest one by scanning the entries and with the stored times.
+func BenchmarkEvict(b *testing.B) {
+	for _, method := range []string{"scan", "heap"} {
+		for _, n := range []int{1000, 10000, 100000} {
+			b.Run(fmt.Sprintf("%s/entries=%d", method, n), func(b *testing.B) {
+				c := New[string](WithMaxEntries(n), WithShards(1)).(*cache[string])
+				b.Cleanup(c.Close)
+
+				for i := range n {
+					_ = c.Set(context.Background(), fmt.Sprintf("key%d", i), "value")
+				}
+
+				s := c.shards[0]
+
+				b.ResetTimer()
+
+				for i := range b.N {
+					if method == "scan" {
+						s.lock.Lock()
+						s.evictOldestByScan()
+						s.lock.Unlock()
+					}
+
+					_ = c.Set(context.Background(), fmt.Sprintf("new%d", i), "value")
+				}
+			})
+		}
+	}
+}
+
+func TestEvictOldestStored(t *testing.T) {
+	c := New[string](WithMaxEntries(3)).(*cache[string])
+	t.Cleanup(c.Close)
+
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, c.Set(ctx, key, key))
+		time.Sleep(time.Millisecond)
+	}
+
+	// Storing a again makes b the oldest.
+	assert.NoError(t, c.Set(ctx, "a", "a"))
+	assert.NoError(t, c.SetWithTTL(ctx, "d", "d", time.Hour))
+
+	_, err := c.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"a", "c", "d"} {
+		_, err := c.Get(ctx, key)
+		assert.NoError(t, err, key)
+	}
+
+	// The removed entries leave the stored times and the deadlines.
+	assert.NoError(t, c.Delete(ctx, "d"))
+
+	s := c.shards[0]
+	assert.Len(t, s.stored, 2)
+	assert.Empty(t, s.deadlines)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestCacheClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	caches := make([]Cache[string], 0, 50)
+	for range 50 {
+		caches = append(caches, New[string]())
+	}
+
+	for _, c := range caches {
+		c.Close()
+		c.Close()
+	}
+
+	// Not assert.Eventually, which checks from a goroutine of its own.
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+	// A closed cache can still be used.
+	assert.NoError(t, caches[0].Set(context.Background(), "key", "value"))
+}
+
+func TestShardCapacity(t *testing.T) {
+	// Small caches keep a single shard, so they evict their oldest entry.
+	c := New[string](WithMaxEntries(2)).(*cache[string])
+	assert.Len(t, c.shards, 1)
+
+	// The capacity of the larger ones is split among the shards, adding up to it.
+	c = New[string](WithMaxEntries(1000), WithShards(8)).(*cache[string])
+	assert.Len(t, c.shards, 8)
+
+	total := 0
+	for _, s := range c.shards {
+		assert.InDelta(t, 125, s.maxEntries, 1)
+		total += s.maxEntries
+	}
+
+	assert.Equal(t, 1000, total)
+
+	c = New[string](WithMaxEntries(1000)).(*cache[string])
+	assert.Len(t, c.shards, 1000/minShardEntries)
+
+	c = New[string]().(*cache[string])
+	assert.Len(t, c.shards, DefaultShards)
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clustermetrics polls the metrics.k8s.io API of the clusters on an interval and
+// caches the node and pod metrics, so the dashboards share the results instead of each
+// polling metrics-server.
+package clustermetrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// DefaultInterval is how often the metrics of a cluster are polled.
+const DefaultInterval = 30 * time.Second
+
+// idleIntervals is after how many intervals without requests a cluster stops being polled.
+const idleIntervals = 5
+
+// ErrMetricsUnavailable means metrics-server isn't installed in the cluster.
+var ErrMetricsUnavailable = errors.New("the metrics.k8s.io API is not available")
+
+// NodeMetrics is the resource usage of a node.
+type NodeMetrics struct {
+	Name      string            `json:"name"`
+	Timestamp time.Time         `json:"timestamp"`
+	Window    string            `json:"window"`
+	Usage     map[string]string `json:"usage"`
+}
+
+// ContainerMetrics is the resource usage of a container.
+type ContainerMetrics struct {
+	Name  string            `json:"name"`
+	Usage map[string]string `json:"usage"`
+}
+
+// PodMetrics is the resource usage of a pod, by container.
+type PodMetrics struct {
+	Name       string             `json:"name"`
+	Namespace  string             `json:"namespace"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Window     string             `json:"window"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+// Snapshot are the metrics of a cluster at a point in time.
+type Snapshot struct {
+	Cluster   string        `json:"cluster"`
+	FetchedAt time.Time     `json:"fetchedAt"`
+	Nodes     []NodeMetrics `json:"nodes"`
+	Pods      []PodMetrics  `json:"pods"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Fetcher gets the metrics of a cluster, with the token of the user.
+type Fetcher func(ctx context.Context, cluster, token string) (*Snapshot, error)
+
+// tracked is a cluster, for a user, whose metrics are polled.
+type tracked struct {
+	cluster       string
+	token         string
+	lastRequested time.Time
+}
+
+// Aggregator polls the metrics of the clusters of its store that are being requested, and
+// caches them. Metrics are cached per user, as their permissions may differ.
+type Aggregator struct {
+	cache    cache.Cache[interface{}]
+	interval time.Duration
+	store    kubeconfig.ContextStore
+	fetch    Fetcher
+
+	mu       sync.Mutex
+	tracked  map[string]*tracked
+	inFlight map[string]chan struct{}
+}
+
+// NewAggregator returns an aggregator polling the clusters of store every interval with fetch,
+// and caching in c.
+func NewAggregator(
+	c cache.Cache[interface{}], interval time.Duration, store kubeconfig.ContextStore, fetch Fetcher,
+) *Aggregator {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Aggregator{
+		cache:    c,
+		interval: interval,
+		store:    store,
+		fetch:    fetch,
+		tracked:  map[string]*tracked{},
+		inFlight: map[string]chan struct{}{},
+	}
+}
+
+func cacheKey(cluster, token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return "clustermetrics_" + cluster + "_" + hex.EncodeToString(sum[:8])
+}
+
+// Get returns the metrics of the cluster, from the cache if they were polled within the
+// interval, and keeps polling them while they are requested. Clusters that aren't in the
+// store aren't polled.
+func (a *Aggregator) Get(ctx context.Context, cluster, token string) *Snapshot {
+	if _, err := a.store.GetContext(cluster); err != nil {
+		return &Snapshot{Cluster: cluster, FetchedAt: time.Now(), Error: err.Error()}
+	}
+
+	key := cacheKey(cluster, token)
+
+	a.mu.Lock()
+
+	if t, ok := a.tracked[key]; ok {
+		t.lastRequested = time.Now()
+	} else {
+		a.tracked[key] = &tracked{cluster: cluster, token: token, lastRequested: time.Now()}
+	}
+
+	a.mu.Unlock()
+
+	if snapshot, ok := a.cached(ctx, key); ok {
+		return snapshot
+	}
+
+	return a.refresh(ctx, key, cluster, token)
+}
+
+func (a *Aggregator) cached(ctx context.Context, key string) (*Snapshot, bool) {
+	value, err := a.cache.Get(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+
+	snapshot, ok := value.(*Snapshot)
+
+	return snapshot, ok
+}
+
+// refresh fetches the metrics of the cluster and caches them. Concurrent refreshes of the
+// same key wait for the first one, so metrics-server is queried once.
+func (a *Aggregator) refresh(ctx context.Context, key, cluster, token string) *Snapshot {
+	for {
+		a.mu.Lock()
+
+		done, ok := a.inFlight[key]
+		if !ok {
+			break
+		}
+
+		a.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return &Snapshot{Cluster: cluster, FetchedAt: time.Now(), Error: ctx.Err().Error()}
+		}
+
+		if snapshot, ok := a.cached(ctx, key); ok {
+			return snapshot
+		}
+	}
+
+	done := make(chan struct{})
+	a.inFlight[key] = done
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.inFlight, key)
+		a.mu.Unlock()
+		close(done)
+	}()
+
+	snapshot, err := a.fetch(ctx, cluster, token)
+	if err != nil {
+		snapshot = &Snapshot{Cluster: cluster, FetchedAt: time.Now(), Error: err.Error()}
+	}
+
+	// Kept for two intervals, so a poll that is a bit late doesn't leave a gap.
+	if err := a.cache.SetWithTTL(ctx, key, snapshot, 2*a.interval); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": cluster}, err, "caching cluster metrics")
+	}
+
+	return snapshot
+}
+
+// Run polls the requested clusters every interval until ctx is done. Clusters that
+// weren't requested for a while stop being polled.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.poll(ctx)
+		}
+	}
+}
+
+// poll refreshes the tracked clusters at the same time, each for at most an interval, so a
+// cluster that is slow or unreachable doesn't hold back the others. Clusters that were
+// removed from the store stop being tracked.
+func (a *Aggregator) poll(ctx context.Context) {
+	idleSince := time.Now().Add(-idleIntervals * a.interval)
+
+	a.mu.Lock()
+
+	polled := make(map[string]tracked, len(a.tracked))
+
+	for key, t := range a.tracked {
+		if t.lastRequested.Before(idleSince) {
+			delete(a.tracked, key)
+
+			continue
+		}
+
+		if _, err := a.store.GetContext(t.cluster); err != nil {
+			delete(a.tracked, key)
+
+			continue
+		}
+
+		polled[key] = *t
+	}
+
+	a.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for key, t := range polled {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			refreshCtx, cancel := context.WithTimeout(ctx, a.interval)
+			defer cancel()
+
+			a.refresh(refreshCtx, key, t.cluster, t.token)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// metricsList is a list of the metrics.k8s.io API.
+type metricsList[T any] struct {
+	Items []T `json:"items"`
+}
+
+type objectMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type nodeMetricsItem struct {
+	Metadata  objectMeta        `json:"metadata"`
+	Timestamp time.Time         `json:"timestamp"`
+	Window    string            `json:"window"`
+	Usage     map[string]string `json:"usage"`
+}
+
+type podMetricsItem struct {
+	Metadata   objectMeta         `json:"metadata"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Window     string             `json:"window"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+// NewFetcher returns a fetcher querying the metrics.k8s.io API of the contexts of the store.
+func NewFetcher(store kubeconfig.ContextStore) Fetcher {
+	return func(ctx context.Context, cluster, token string) (*Snapshot, error) {
+		kContext, err := store.GetContext(cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		clientset, err := kContext.ClientSetWithToken(token)
+		if err != nil {
+			return nil, err
+		}
+
+		get := func(resource string, into interface{}) error {
+			body, err := clientset.Discovery().RESTClient().Get().
+				AbsPath("/apis/metrics.k8s.io/v1beta1/" + resource).DoRaw(ctx)
+			if apierrors.IsNotFound(err) {
+				return ErrMetricsUnavailable
+			}
+
+			if err != nil {
+				return fmt.Errorf("getting %s metrics: %w", resource, err)
+			}
+
+			return json.Unmarshal(body, into)
+		}
+
+		var nodes metricsList[nodeMetricsItem]
+		if err := get("nodes", &nodes); err != nil {
+			return nil, err
+		}
+
+		var pods metricsList[podMetricsItem]
+		if err := get("pods", &pods); err != nil {
+			return nil, err
+		}
+
+		return newSnapshot(cluster, nodes.Items, pods.Items), nil
+	}
+}
+
+func newSnapshot(cluster string, nodes []nodeMetricsItem, pods []podMetricsItem) *Snapshot {
+	snapshot := &Snapshot{
+		Cluster:   cluster,
+		FetchedAt: time.Now(),
+		Nodes:     make([]NodeMetrics, 0, len(nodes)),
+		Pods:      make([]PodMetrics, 0, len(pods)),
+	}
+
+	for _, node := range nodes {
+		snapshot.Nodes = append(snapshot.Nodes, NodeMetrics{
+			Name:      node.Metadata.Name,
+			Timestamp: node.Timestamp,
+			Window:    node.Window,
+			Usage:     node.Usage,
+		})
+	}
+
+	for _, pod := range pods {
+		snapshot.Pods = append(snapshot.Pods, PodMetrics{
+			Name:       pod.Metadata.Name,
+			Namespace:  pod.Metadata.Namespace,
+			Timestamp:  pod.Timestamp,
+			Window:     pod.Window,
+			Containers: pod.Containers,
+		})
+	}
+
+	return snapshot
+}
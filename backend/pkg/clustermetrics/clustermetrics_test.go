@@ -0,0 +1,267 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustermetrics_test
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/clustermetrics"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newStore returns a store with a context for each of the names.
+func newStore(t *testing.T, names ...string) kubeconfig.ContextStore {
+	t.Helper()
+
+	store := kubeconfig.NewContextStore()
+
+	for _, name := range names {
+		require.NoError(t, store.AddContext(&kubeconfig.Context{
+			Name:        name,
+			KubeContext: &api.Context{Cluster: name, AuthInfo: name},
+			Cluster:     &api.Cluster{Server: "https://" + name + ".example.com"},
+			AuthInfo:    &api.AuthInfo{},
+		}))
+	}
+
+	return store
+}
+
+func TestAggregatorSharesFetches(t *testing.T) {
+	var fetches atomic.Int32
+
+	fetch := func(ctx context.Context, cluster, token string) (*clustermetrics.Snapshot, error) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+
+		return &clustermetrics.Snapshot{Cluster: cluster, Nodes: []clustermetrics.NodeMetrics{{Name: token}}}, nil
+	}
+
+	aggregator := clustermetrics.NewAggregator(cache.New[interface{}](), time.Minute, newStore(t, "minikube"), fetch)
+
+	var wg sync.WaitGroup
+
+	for range 5 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			snapshot := aggregator.Get(context.Background(), "minikube", "token-a")
+			assert.Equal(t, "token-a", snapshot.Nodes[0].Name)
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Cached within the interval.
+	aggregator.Get(context.Background(), "minikube", "token-a")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Users don't share metrics, as their permissions may differ.
+	snapshot := aggregator.Get(context.Background(), "minikube", "token-b")
+	assert.Equal(t, "token-b", snapshot.Nodes[0].Name)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestAggregatorError(t *testing.T) {
+	fetch := func(ctx context.Context, cluster, token string) (*clustermetrics.Snapshot, error) {
+		return nil, clustermetrics.ErrMetricsUnavailable
+	}
+
+	aggregator := clustermetrics.NewAggregator(cache.New[interface{}](), time.Minute, newStore(t, "minikube"), fetch)
+
+	snapshot := aggregator.Get(context.Background(), "minikube", "")
+	assert.Equal(t, "minikube", snapshot.Cluster)
+	assert.Equal(t, clustermetrics.ErrMetricsUnavailable.Error(), snapshot.Error)
+}
+
+func TestAggregatorRunPollsRequestedClusters(t *testing.T) {
+	var fetches atomic.Int32
+
+	fetch := func(ctx context.Context, cluster, token string) (*clustermetrics.Snapshot, error) {
+		fetches.Add(1)
+
+		return &clustermetrics.Snapshot{Cluster: cluster}, nil
+	}
+
+	aggregator := clustermetrics.NewAggregator(cache.New[interface{}](), 20*time.Millisecond,
+		newStore(t, "minikube"), fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go aggregator.Run(ctx)
+
+	// Nothing is polled until it's requested.
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, int32(0), fetches.Load())
+
+	aggregator.Get(ctx, "minikube", "")
+
+	require.Eventually(t, func() bool { return fetches.Load() >= 3 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestAggregatorUnknownCluster(t *testing.T) {
+	var fetches atomic.Int32
+
+	fetch := func(ctx context.Context, cluster, token string) (*clustermetrics.Snapshot, error) {
+		fetches.Add(1)
+
+		return &clustermetrics.Snapshot{Cluster: cluster}, nil
+	}
+
+	store := newStore(t, "minikube")
+	aggregator := clustermetrics.NewAggregator(cache.New[interface{}](), 20*time.Millisecond, store, fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go aggregator.Run(ctx)
+
+	// Clusters that aren't in the store are neither fetched nor polled.
+	snapshot := aggregator.Get(ctx, "unknown", "")
+	assert.Equal(t, "unknown", snapshot.Cluster)
+	assert.NotEmpty(t, snapshot.Error)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, int32(0), fetches.Load())
+
+	// Clusters removed from the store stop being polled.
+	aggregator.Get(ctx, "minikube", "")
+	require.NoError(t, store.RemoveContext("minikube"))
+
+	time.Sleep(60 * time.Millisecond)
+
+	polled := fetches.Load()
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, polled, fetches.Load())
+}
+
+func TestAggregatorPollsClustersConcurrently(t *testing.T) {
+	var fast atomic.Int32
+
+	fetch := func(ctx context.Context, cluster, token string) (*clustermetrics.Snapshot, error) {
+		if cluster == "fast" {
+			fast.Add(1)
+
+			return &clustermetrics.Snapshot{Cluster: cluster}, nil
+		}
+
+		// The slow cluster doesn't answer, until the poll gives up on it.
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	aggregator := clustermetrics.NewAggregator(cache.New[interface{}](), 20*time.Millisecond,
+		newStore(t, "fast", "slow"), fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	aggregator.Get(ctx, "fast", "")
+
+	// Requested without waiting for it, as it never answers.
+	slowCtx, slowCancel := context.WithCancel(ctx)
+	slowCancel()
+	aggregator.Get(slowCtx, "slow", "")
+
+	go aggregator.Run(ctx)
+
+	require.Eventually(t, func() bool { return fast.Load() >= 4 }, 2*time.Second, 10*time.Millisecond)
+
+	snapshot := aggregator.Get(ctx, "slow", "")
+	assert.Equal(t, context.DeadlineExceeded.Error(), snapshot.Error)
+}
+
+func TestFetcher(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/apis/metrics.k8s.io/v1beta1/nodes":
+			_, _ = w.Write([]byte(`{"items":[{"metadata":{"name":"node-1"},"timestamp":"2025-01-01T00:00:00Z",` +
+				`"window":"20s","usage":{"cpu":"250m","memory":"1Gi"}}]}`))
+		case "/apis/metrics.k8s.io/v1beta1/pods":
+			_, _ = w.Write([]byte(`{"items":[{"metadata":{"name":"web","namespace":"default"},` +
+				`"window":"15s","containers":[{"name":"nginx","usage":{"cpu":"5m","memory":"10Mi"}}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{
+		Name:        "test",
+		KubeContext: &api.Context{Cluster: "test", AuthInfo: "test"},
+		Cluster:     &api.Cluster{Server: server.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{},
+	}))
+
+	snapshot, err := clustermetrics.NewFetcher(store)(context.Background(), "test", "token")
+	require.NoError(t, err)
+
+	require.Len(t, snapshot.Nodes, 1)
+	assert.Equal(t, "node-1", snapshot.Nodes[0].Name)
+	assert.Equal(t, "250m", snapshot.Nodes[0].Usage["cpu"])
+
+	require.Len(t, snapshot.Pods, 1)
+	assert.Equal(t, "default", snapshot.Pods[0].Namespace)
+	assert.Equal(t, "10Mi", snapshot.Pods[0].Containers[0].Usage["memory"])
+
+	_, err = clustermetrics.NewFetcher(store)(context.Background(), "unknown", "token")
+	require.Error(t, err)
+}
+
+func TestFetcherWithoutMetricsServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	t.Cleanup(server.Close)
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{
+		Name:        "test",
+		KubeContext: &api.Context{Cluster: "test", AuthInfo: "test"},
+		Cluster:     &api.Cluster{Server: server.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{},
+	}))
+
+	_, err := clustermetrics.NewFetcher(store)(context.Background(), "test", "")
+	assert.True(t, errors.Is(err, clustermetrics.ErrMetricsUnavailable))
+}
@@ -10,16 +10,23 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/providers/basicflag"
 	"github.com/knadh/koanf/providers/env"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
 )
 
 const defaultPort = 4466
 
+// minTunnelTokenLength is the minimum length of the secret the tokens of the tunnel agents are made from.
+const minTunnelTokenLength = 16
+
 type Config struct {
 	InCluster                 bool   `koanf:"in-cluster"`
 	DevMode                   bool   `koanf:"dev"`
@@ -28,6 +35,8 @@ type Config struct {
 	EnableHelm                bool   `koanf:"enable-helm"`
 	EnableDynamicClusters     bool   `koanf:"enable-dynamic-clusters"`
 	ListenAddr                string `koanf:"listen-addr"`
+	ListenSocket              string `koanf:"listen-socket"`
+	ListenSocketMode          string `koanf:"listen-socket-mode"`
 	WatchPluginsChanges       bool   `koanf:"watch-plugins-changes"`
 	Port                      uint   `koanf:"port"`
 	KubeConfigPath            string `koanf:"kubeconfig"`
@@ -57,8 +66,82 @@ type Config struct {
 	StdoutTraceEnabled *bool    `koanf:"stdout-trace-enabled"`
 	SamplingRate       *float64 `koanf:"sampling-rate"`
 	// TLS config
-	TLSCertPath string `koanf:"tls-cert-path"`
-	TLSKeyPath  string `koanf:"tls-key-path"`
+	TLSCertPath   string `koanf:"tls-cert-path"`
+	TLSKeyPath    string `koanf:"tls-key-path"`
+	TLSSelfSigned bool   `koanf:"tls-self-signed"`
+	// WebSocket multiplexer config
+	WebsocketIdleTimeout    time.Duration `koanf:"websocket-idle-timeout"`
+	WebsocketResumeWindow   time.Duration `koanf:"websocket-resume-window"`
+	MaxWebsocketConnections int           `koanf:"max-websocket-connections-per-client"`
+	MaxWatchSubscriptions   int           `koanf:"max-watch-subscriptions-per-client"`
+	// Access log config
+	AccessLog        bool   `koanf:"access-log"`
+	AccessLogExclude string `koanf:"access-log-exclude"`
+	// Log config
+	LogLevel string `koanf:"log-level"`
+	// Log file config
+	LogFile               string        `koanf:"log-file"`
+	LogFileMaxSize        int           `koanf:"log-file-max-size"`
+	LogFileRotateInterval time.Duration `koanf:"log-file-rotate-interval"`
+	LogFileMaxBackups     int           `koanf:"log-file-max-backups"`
+	LogFileMaxAge         time.Duration `koanf:"log-file-max-age"`
+	// Audit config
+	AuditLog         string `koanf:"audit-log"`
+	AuditWebhook     string `koanf:"audit-webhook"`
+	AuditHistorySize int    `koanf:"audit-history-size"`
+	// Proxy config
+	AllowedVerbs string `koanf:"allowed-verbs"`
+	DryRun       bool   `koanf:"dry-run"`
+	// ContextNamespace applies the namespace of the contexts to the requests without one.
+	ContextNamespace bool `koanf:"context-namespace"`
+	// RecordClusterEvents records the actions of the backend on the clusters as Kubernetes Events.
+	RecordClusterEvents bool `koanf:"record-cluster-events"`
+	// CSRF config
+	CSRFProtection bool `koanf:"csrf-protection"`
+	// API token config
+	APITokensFile         string `koanf:"api-tokens-file"`
+	APITokenJWTSecretFile string `koanf:"api-token-jwt-secret-file"`
+	// Headless config
+	Headless bool `koanf:"headless"`
+	APIOnly  bool `koanf:"api-only"`
+	// Plugin catalog config
+	OCIPlugins     string `koanf:"oci-plugins"`
+	PluginCacheDir string `koanf:"plugin-cache-dir"`
+	// Plugin verification config
+	PluginVerification string `koanf:"plugin-verification"`
+	PluginTrustedKeys  string `koanf:"plugin-trusted-keys"`
+	// Plugin backends config
+	PluginBackends bool `koanf:"plugin-backends"`
+	// Helm config
+	HelmRepoRefreshInterval time.Duration `koanf:"helm-repo-refresh-interval"`
+	// Cluster metrics config
+	ClusterMetricsInterval time.Duration `koanf:"cluster-metrics-interval"`
+	// Discovery cache config
+	DiscoveryCache bool `koanf:"discovery-cache"`
+	// OpenAPI cache config
+	OpenAPICacheSize int `koanf:"openapi-cache-size"`
+	// Context naming config
+	ContextNaming         string `koanf:"context-naming"`
+	ContextConflictPolicy string `koanf:"context-conflict-policy"`
+	ContextGroupsFile     string `koanf:"context-groups-file"`
+	// Reverse tunnel config
+	TunnelToken string `koanf:"tunnel-token"`
+	// Dynamic cluster quota config
+	MaxDynamicClustersPerUser int `koanf:"max-dynamic-clusters-per-user"`
+	MaxDynamicClusters        int `koanf:"max-dynamic-clusters"`
+	// Stateless cluster ttl config
+	StatelessContextTTL        time.Duration `koanf:"stateless-context-ttl"`
+	StatelessContextMaxTTL     time.Duration `koanf:"stateless-context-max-ttl"`
+	StatelessContextMinRenewal time.Duration `koanf:"stateless-context-min-renewal"`
+	StatelessContextRenewBelow time.Duration `koanf:"stateless-context-renew-below"`
+	// Insecure cluster config
+	RejectInsecureClusters bool `koanf:"reject-insecure-clusters"`
+	// Kubeconfig URL config
+	KubeConfigURLRefreshInterval time.Duration `koanf:"kubeconfig-url-refresh-interval"`
+	// Shutdown config
+	ShutdownDrainTimeout time.Duration `koanf:"shutdown-drain-timeout"`
+	// Preflight config
+	Preflight bool `koanf:"preflight"`
 }
 
 func (c *Config) Validate() error {
@@ -87,6 +170,72 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if _, err := strconv.ParseUint(c.ListenSocketMode, 8, 32); err != nil {
+		return fmt.Errorf("invalid listen-socket-mode: %w", err)
+	}
+
+	if (c.TLSCertPath == "") != (c.TLSKeyPath == "") {
+		return errors.New("tls-cert-path and tls-key-path need to be given together")
+	}
+
+	if _, err := c.OCIPluginRefs(); err != nil {
+		return fmt.Errorf("invalid oci-plugins: %w", err)
+	}
+
+	if err := plugins.ValidatePolicy(c.PluginVerification); err != nil {
+		return err
+	}
+
+	if c.PluginVerification != plugins.PolicyOff && c.PluginTrustedKeys == "" {
+		return errors.New("plugin-trusted-keys is required when plugin-verification is warn or enforce")
+	}
+
+	if c.HelmRepoRefreshInterval < 0 {
+		return errors.New("helm-repo-refresh-interval can't be negative")
+	}
+
+	if c.ClusterMetricsInterval < 0 {
+		return errors.New("cluster-metrics-interval can't be negative")
+	}
+
+	if c.OpenAPICacheSize < 0 {
+		return errors.New("openapi-cache-size can't be negative")
+	}
+
+	if _, err := kubeconfig.ParseNamingStrategy(c.ContextNaming); err != nil {
+		return err
+	}
+
+	if _, err := kubeconfig.ParseConflictPolicy(c.ContextConflictPolicy); err != nil {
+		return err
+	}
+
+	if c.TunnelToken != "" && len(c.TunnelToken) < minTunnelTokenLength {
+		return fmt.Errorf("tunnel-token needs to be at least %d characters", minTunnelTokenLength)
+	}
+
+	if c.MaxDynamicClustersPerUser < 0 || c.MaxDynamicClusters < 0 {
+		return errors.New("max-dynamic-clusters-per-user and max-dynamic-clusters can't be negative")
+	}
+
+	if c.StatelessContextTTL < 0 || c.StatelessContextMaxTTL < 0 || c.StatelessContextMinRenewal < 0 ||
+		c.StatelessContextRenewBelow < 0 {
+		return errors.New("stateless-context-ttl, stateless-context-max-ttl, stateless-context-min-renewal " +
+			"and stateless-context-renew-below can't be negative")
+	}
+
+	if c.StatelessContextMaxTTL > 0 && c.StatelessContextTTL > c.StatelessContextMaxTTL {
+		return errors.New("stateless-context-ttl can't be longer than stateless-context-max-ttl")
+	}
+
+	if c.KubeConfigURLRefreshInterval < 0 {
+		return errors.New("kubeconfig-url-refresh-interval can't be negative")
+	}
+
+	if c.ShutdownDrainTimeout < 0 {
+		return errors.New("shutdown-drain-timeout can't be negative")
+	}
+
 	if c.BaseURL != "" && !strings.HasPrefix(c.BaseURL, "/") {
 		return errors.New("base-url needs to start with a '/' or be empty")
 	}
@@ -111,6 +260,36 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// OCIPluginRefs returns the plugins to install from OCI registries, given in oci-plugins
+// as comma separated name=ref pairs, by name.
+func (c *Config) OCIPluginRefs() (map[string]string, error) {
+	refs := map[string]string{}
+
+	for _, entry := range strings.Split(c.OCIPlugins, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, ref, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("%q is not a name=ref pair", entry)
+		}
+
+		if err := plugins.ValidateName(name); err != nil {
+			return nil, err
+		}
+
+		if _, err := plugins.ParseReference(ref); err != nil {
+			return nil, err
+		}
+
+		refs[name] = ref
+	}
+
+	return refs, nil
+}
+
 // normalizeArgs skips the first arg for flag parsing.
 func normalizeArgs(args []string) []string {
 	if len(args) == 0 {
@@ -227,6 +406,23 @@ func setKubeConfigPath(config *Config) {
 // the value of port will be 3456.
 
 func Parse(args []string) (*Config, error) {
+	config, err := ParseUnvalidated(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate parsed config.
+	if err := config.Validate(); err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating config")
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ParseUnvalidated parses the config like Parse, without validating it, so the preflight
+// checks can report why it isn't valid.
+func ParseUnvalidated(args []string) (*Config, error) {
 	var config Config
 
 	f := flagset()
@@ -267,28 +463,15 @@ func Parse(args []string) (*Config, error) {
 	patchWatchPluginsChanges(&config, explicitFlags)
 	setKubeConfigPath(&config)
 
-	// 8. Validate parsed config.
-	if err := config.Validate(); err != nil {
-		logger.Log(logger.LevelError, nil, err, "validating config")
-		return nil, err
-	}
-
 	return &config, nil
 }
 
 // MakeHeadlampKubeConfigsDir returns the default directory to store kubeconfig
 // files of clusters that are loaded in Headlamp.
 func MakeHeadlampKubeConfigsDir() (string, error) {
-	userConfigDir, err := os.UserConfigDir()
+	kubeConfigDir, err := userKubeConfigsDir()
 
 	if err == nil {
-		kubeConfigDir := filepath.Join(userConfigDir, "Headlamp", "kubeconfigs")
-		if runtime.GOOS == "windows" {
-			// golang is wrong for config folder on windows.
-			// This matches env-paths and headlamp-plugin.
-			kubeConfigDir = filepath.Join(userConfigDir, "Headlamp", "Config", "kubeconfigs")
-		}
-
 		// Create the directory if it doesn't exist.
 		fileMode := 0o755
 
@@ -307,6 +490,40 @@ func MakeHeadlampKubeConfigsDir() (string, error) {
 	return "", fmt.Errorf("failed to get default kubeconfig persistence directory: %v", err)
 }
 
+// HeadlampKubeConfigsDir returns the default directory to store kubeconfig files without
+// creating it: the one in the config directory of the user, or the directory of the
+// executable if the user has none.
+func HeadlampKubeConfigsDir() (string, error) {
+	kubeConfigDir, err := userKubeConfigsDir()
+	if err == nil {
+		return kubeConfigDir, nil
+	}
+
+	ex, err := os.Executable()
+	if err == nil {
+		return filepath.Dir(ex), nil
+	}
+
+	return "", fmt.Errorf("failed to get default kubeconfig persistence directory: %v", err)
+}
+
+// userKubeConfigsDir returns the default directory of the kubeconfig files, in the config
+// directory of the user.
+func userKubeConfigsDir() (string, error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "windows" {
+		// golang is wrong for config folder on windows.
+		// This matches env-paths and headlamp-plugin.
+		return filepath.Join(userConfigDir, "Headlamp", "Config", "kubeconfigs"), nil
+	}
+
+	return filepath.Join(userConfigDir, "Headlamp", "kubeconfigs"), nil
+}
+
 func DefaultHeadlampKubeConfigFile() (string, error) {
 	kubeConfigDir, err := MakeHeadlampKubeConfigsDir()
 	if err != nil {
@@ -334,6 +551,8 @@ func flagset() *flag.FlagSet {
 	f.String("base-url", "", "Base URL path. eg. /headlamp")
 	f.String("listen-addr", "", "Address to listen on; default is empty, which means listening to any address")
 	f.Uint("port", defaultPort, "Port to listen from")
+	f.String("listen-socket", "", "Unix socket to listen on instead of listen-addr and port")
+	f.String("listen-socket-mode", "0600", "Permissions of the listen-socket file, in octal")
 	f.String("proxy-urls", "", "Allow proxy requests to specified URLs")
 
 	f.String("oidc-client-id", "", "ClientID for OIDC")
@@ -357,8 +576,120 @@ func flagset() *flag.FlagSet {
 	f.Bool("stdout-trace-enabled", false, "Enable tracing output to stdout")
 	f.Float64("sampling-rate", 1.0, "Sampling rate for traces")
 	// TLS flags
-	f.String("tls-cert-path", "", "Certificate for serving TLS")
-	f.String("tls-key-path", "", "Key for serving TLS")
+	f.String("tls-cert-path", "", "Certificate for serving TLS, reloaded when it changes")
+	f.String("tls-key-path", "", "Key for serving TLS, reloaded when it changes")
+	f.Bool("tls-self-signed", false, "Serve TLS with a generated self-signed certificate, "+
+		"if no tls-cert-path and tls-key-path are given")
+	// WebSocket multiplexer flags
+	f.Duration("websocket-idle-timeout", 90*time.Second,
+		"Drop multiplexer WebSocket clients that send nothing, pongs included, for this long; 0 disables it")
+	f.Duration("websocket-resume-window", 2*time.Minute,
+		"How long watches of a disconnected WebSocket client are kept for it to resume them")
+	f.Int("max-websocket-connections-per-client", 0,
+		"Maximum number of concurrent multiplexer WebSocket connections per client; 0 means no limit")
+	f.Int("max-watch-subscriptions-per-client", 0,
+		"Maximum number of active watch subscriptions per client; 0 means no limit")
+	// Access log flags
+	f.Bool("access-log", false, "Log every HTTP request served")
+	f.String("access-log-exclude", "/assets/,/static/,/static-plugins/,/favicon",
+		"Comma separated list of path prefixes not to write to the access log")
+	// Log flags
+	f.String("log-level", "info", "Log level: debug, info, warn or error, with optional per-component "+
+		"overrides for kubeconfig, proxy, multiplexer, helm and plugins, e.g. info,proxy=debug")
+	// Log file flags
+	f.String("log-file", "", "Also write the logs to this file, which is rotated; default is only stderr")
+	f.Int("log-file-max-size", 100, "Size in megabytes after which the log file is rotated; 0 disables it")
+	f.Duration("log-file-rotate-interval", 24*time.Hour, "Age after which the log file is rotated; 0 disables it")
+	f.Int("log-file-max-backups", 5, "Number of rotated log files to keep; 0 keeps them all")
+	f.Duration("log-file-max-age", 7*24*time.Hour, "How long rotated log files are kept; 0 keeps them forever")
+	// Audit flags
+	f.String("audit-log", "", "Append an audit event for every request changing cluster resources to this file")
+	f.String("audit-webhook", "", "Post an audit event for every request changing cluster resources to this URL")
+	f.Int("audit-history-size", 1000,
+		"Number of recent cluster context operations kept for the /audit/contexts endpoint; 0 disables it")
+	// Proxy flags
+	f.String("allowed-verbs", "", "Comma separated list of the Kubernetes verbs allowed in the requests to the "+
+		"clusters, proxied or made by exec, helm, node drains and plugin backends, e.g. get,list,watch for a "+
+		"read-only Headlamp; default allows them all")
+	f.Bool("dry-run", false, "Make every request changing cluster resources a dry run, which changes nothing, "+
+		"and reject the helm actions, exec sessions, port forwards and node drains, which can't be; "+
+		"it can also be enabled for one context with dryRun in its headlamp_info extension")
+	f.Bool("context-namespace", false, "Make the list, watch and get requests for namespaced resources "+
+		"without a namespace use the namespace set on their context in the kubeconfig, like kubectl does")
+	f.Bool("record-cluster-events", false, "Record the node drains, helm actions and scalings made through "+
+		"Headlamp as Kubernetes Events in their cluster, with the user who made them; it can also be set for "+
+		"one context with recordEvents in its headlamp_info extension")
+	// CSRF flags
+	f.Bool("csrf-protection", false, "Require the token from /csrf-token in the X-CSRF-Token header "+
+		"of the requests changing the backend state, like adding or removing clusters")
+	// API token flags
+	f.String("api-tokens-file", "", "CSV file of static API tokens, one token,name per line, "+
+		"accepted in the X-HEADLAMP-API-TOKEN header")
+	f.String("api-token-jwt-secret-file", "", "File with the secret of the HS256 JWTs accepted as API tokens, "+
+		"named by their sub claim")
+	// Headless flags
+	f.Bool("headless", false, "Serve only the context management, proxy and streaming APIs, "+
+		"without the frontend and plugins")
+	f.Bool("api-only", false, "Same as headless")
+	// Plugin catalog flags
+	f.String("oci-plugins", "", "Plugins to install from OCI registries, as comma separated name=ref pairs, "+
+		"e.g. my-plugin=ghcr.io/org/my-plugin:1.0.0")
+	f.String("plugin-cache-dir", plugins.DefaultCacheDir(), "Directory to cache the plugin bundles downloaded "+
+		"from OCI registries in")
+	// Plugin verification flags
+	f.String("plugin-verification", plugins.PolicyOff, "Plugin signature verification policy: off, "+
+		"warn to log the plugins not signed with a trusted key, or enforce to not serve them")
+	f.String("plugin-trusted-keys", "", "PEM file, or directory of PEM files, with the public keys, "+
+		"ed25519 or ECDSA, of the trusted plugin publishers")
+	// Plugin backends flags
+	f.Bool("plugin-backends", false, "Start the backend processes of the plugins with a backend.json, "+
+		"which serve the plugin endpoints under /plugins/{name}/api")
+	// Helm flags
+	f.Duration("helm-repo-refresh-interval", 0, "How often the Helm repository indexes are refreshed "+
+		"in the background; 0 disables it")
+	// Cluster metrics flags
+	f.Duration("cluster-metrics-interval", 30*time.Second, "How often the metrics-server metrics of the "+
+		"requested clusters are polled and cached for /cluster-metrics; 0 disables the endpoint")
+	// Discovery cache flags
+	f.Bool("discovery-cache", true, "Cache the API discovery documents of the clusters until their "+
+		"CustomResourceDefinitions or APIServices change")
+	// OpenAPI cache flags
+	f.Int("openapi-cache-size", 256, "Maximum size in MiB of the OpenAPI v3 schemas of the clusters "+
+		"cached by the backend; 0 disables the cache")
+	// Context naming flags
+	f.String("context-naming", string(kubeconfig.NamingDefault), "How the context names are made URL safe: "+
+		"default, rfc1123-label (63 characters), rfc1123-subdomain (253 characters, keeping the dots, "+
+		"with labels of 63), hash or escape")
+	f.String("context-conflict-policy", string(kubeconfig.ConflictSkip), "What to do with the contexts of "+
+		"different kubeconfig files getting the same name but different servers: skip the later ones, "+
+		"suffix their names, or prefer-newest file")
+	f.String("context-groups-file", "", "JSON file the cluster groups are loaded from at startup and saved "+
+		"to when they change; defaults to context-groups.json in the Headlamp kubeconfigs directory, "+
+		"or keeps them in memory only when running in-cluster")
+	f.String("tunnel-token", "", "Secret the tokens of the in-cluster agents opening reverse tunnels to "+
+		"their clusters are made from: the token of a cluster is the hex HMAC-SHA256 of its name keyed with "+
+		"the secret; the tunnel endpoint is disabled if empty")
+	f.Int("max-dynamic-clusters-per-user", 0,
+		"Maximum number of stateless and dynamically added clusters per user; 0 means no limit")
+	f.Int("max-dynamic-clusters", 0,
+		"Maximum number of stateless and dynamically added clusters of all the users; 0 means no limit")
+	f.Duration("stateless-context-ttl", 5*time.Minute, "How long the stateless clusters are kept in the "+
+		"backend, with their credentials, after they're added")
+	f.Duration("stateless-context-max-ttl", 0, "Longest ttl the stateless clusters can be given, "+
+		"including through the cluster API; 0 means no limit")
+	f.Duration("stateless-context-min-renewal", 0, "Shortest time between two renewals of the ttl of a "+
+		"stateless cluster, the ones coming sooner are ignored; 0 means no limit")
+	f.Duration("stateless-context-renew-below", 0, "Only renew the ttl of a stateless cluster once less "+
+		"than this remains of it, the renewals coming earlier are ignored; 0 renews it every time")
+	f.Bool("reject-insecure-clusters", false, "Reject the clusters with insecure-skip-tls-verify set, "+
+		"which don't verify the certificate of their API server, from every source")
+	f.Duration("kubeconfig-url-refresh-interval", 5*time.Minute, "How often the kubeconfigs registered "+
+		"by URL are revalidated, and their contexts updated if they changed; 0 disables it")
+	f.Duration("shutdown-drain-timeout", 20*time.Second, "How long the in-flight requests are given to "+
+		"finish on SIGTERM before they are cancelled")
+	f.Bool("preflight", false, "Check the config, the kubeconfigs and their exec plugins, the listen port "+
+		"and the persistence directories without starting the server, print a JSON report and exit, "+
+		"with 1 if something would keep the server from working")
 
 	return f
 }
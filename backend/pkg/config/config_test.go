@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
 	"github.com/stretchr/testify/assert"
@@ -139,6 +140,81 @@ func TestParseErrors(t *testing.T) {
 			args:          []string{"go run ./cmd", "--base-url=testingthis"},
 			errorContains: "base-url",
 		},
+		{
+			name:          "tls_cert_without_key",
+			args:          []string{"go run ./cmd", "--tls-cert-path=/etc/headlamp/tls.crt"},
+			errorContains: "tls-cert-path and tls-key-path",
+		},
+		{
+			name:          "invalid_oci_plugins",
+			args:          []string{"go run ./cmd", "--oci-plugins=my-plugin=org/my-plugin:1.0.0"},
+			errorContains: "oci-plugins",
+		},
+		{
+			name:          "invalid_plugin_verification",
+			args:          []string{"go run ./cmd", "--plugin-verification=strict"},
+			errorContains: "plugin verification policy",
+		},
+		{
+			name:          "plugin_verification_without_keys",
+			args:          []string{"go run ./cmd", "--plugin-verification=enforce"},
+			errorContains: "plugin-trusted-keys",
+		},
+		{
+			name:          "negative_helm_repo_refresh_interval",
+			args:          []string{"go run ./cmd", "--helm-repo-refresh-interval=-1m"},
+			errorContains: "helm-repo-refresh-interval",
+		},
+		{
+			name:          "negative_cluster_metrics_interval",
+			args:          []string{"go run ./cmd", "--cluster-metrics-interval=-1s"},
+			errorContains: "cluster-metrics-interval",
+		},
+		{
+			name:          "negative_openapi_cache_size",
+			args:          []string{"go run ./cmd", "--openapi-cache-size=-1"},
+			errorContains: "openapi-cache-size",
+		},
+		{
+			name:          "invalid_context_naming",
+			args:          []string{"go run ./cmd", "--context-naming=dns"},
+			errorContains: "invalid context naming strategy",
+		},
+		{
+			name:          "invalid_context_conflict_policy",
+			args:          []string{"go run ./cmd", "--context-conflict-policy=overwrite"},
+			errorContains: "invalid context conflict policy",
+		},
+		{
+			name:          "short_tunnel_token",
+			args:          []string{"go run ./cmd", "--tunnel-token=secret"},
+			errorContains: "tunnel-token needs to be at least",
+		},
+		{
+			name:          "negative_max_dynamic_clusters",
+			args:          []string{"go run ./cmd", "--max-dynamic-clusters=-1"},
+			errorContains: "can't be negative",
+		},
+		{
+			name:          "negative_kubeconfig_url_refresh_interval",
+			args:          []string{"go run ./cmd", "--kubeconfig-url-refresh-interval=-1m"},
+			errorContains: "kubeconfig-url-refresh-interval",
+		},
+		{
+			name:          "stateless_context_ttl_over_max",
+			args:          []string{"go run ./cmd", "--stateless-context-ttl=2h", "--stateless-context-max-ttl=1h"},
+			errorContains: "stateless-context-ttl can't be longer than stateless-context-max-ttl",
+		},
+		{
+			name:          "negative_shutdown_drain_timeout",
+			args:          []string{"go run ./cmd", "--shutdown-drain-timeout=-1s"},
+			errorContains: "shutdown-drain-timeout",
+		},
+		{
+			name:          "invalid_listen_socket_mode",
+			args:          []string{"go run ./cmd", "--listen-socket-mode=rw"},
+			errorContains: "listen-socket-mode",
+		},
 	}
 
 	for _, tt := range tests {
@@ -151,6 +227,13 @@ func TestParseErrors(t *testing.T) {
 	}
 }
 
+func TestParseUnvalidated(t *testing.T) {
+	conf, err := config.ParseUnvalidated([]string{"go run ./cmd", "--shutdown-drain-timeout=-1s", "--preflight"})
+	require.NoError(t, err)
+	assert.True(t, conf.Preflight)
+	assert.ErrorContains(t, conf.Validate(), "shutdown-drain-timeout")
+}
+
 func TestParseFlags(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -178,6 +261,256 @@ func TestParseFlags(t *testing.T) {
 				assert.Equal(t, filepath.Join(getTestDataPath(), "valid_ca.pem"), conf.OidcCAFile)
 			},
 		},
+		{
+			name: "websocket_timeout_flags",
+			args: []string{"go run ./cmd", "--websocket-idle-timeout=45s", "--websocket-resume-window=5m"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, 45*time.Second, conf.WebsocketIdleTimeout)
+				assert.Equal(t, 5*time.Minute, conf.WebsocketResumeWindow)
+			},
+		},
+		{
+			name: "access_log_flags",
+			args: []string{"go run ./cmd", "--access-log", "--access-log-exclude=/static/"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.AccessLog)
+				assert.Equal(t, "/static/", conf.AccessLogExclude)
+			},
+		},
+		{
+			name: "log_level_flag",
+			args: []string{"go run ./cmd", "--log-level=debug"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "debug", conf.LogLevel)
+			},
+		},
+		{
+			name: "log_level_components_flag",
+			args: []string{"go run ./cmd", "--log-level=info,proxy=debug"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "info,proxy=debug", conf.LogLevel)
+			},
+		},
+		{
+			name: "log_file_flags",
+			args: []string{"go run ./cmd", "--log-file=/tmp/headlamp.log", "--log-file-max-size=10", "--log-file-max-age=48h"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "/tmp/headlamp.log", conf.LogFile)
+				assert.Equal(t, 10, conf.LogFileMaxSize)
+				assert.Equal(t, 24*time.Hour, conf.LogFileRotateInterval)
+				assert.Equal(t, 5, conf.LogFileMaxBackups)
+				assert.Equal(t, 48*time.Hour, conf.LogFileMaxAge)
+			},
+		},
+		{
+			name: "audit_flags",
+			args: []string{"go run ./cmd", "--audit-log=/tmp/audit.log", "--audit-webhook=https://audit.example.com"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "/tmp/audit.log", conf.AuditLog)
+				assert.Equal(t, "https://audit.example.com", conf.AuditWebhook)
+				assert.Equal(t, 1000, conf.AuditHistorySize)
+			},
+		},
+		{
+			name: "allowed_verbs_flag",
+			args: []string{"go run ./cmd", "--allowed-verbs=get,list,watch"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "get,list,watch", conf.AllowedVerbs)
+			},
+		},
+		{
+			name: "dry_run_flag",
+			args: []string{"go run ./cmd", "--dry-run"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.DryRun)
+			},
+		},
+		{
+			name: "csrf_protection_flag",
+			args: []string{"go run ./cmd", "--csrf-protection"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.CSRFProtection)
+			},
+		},
+		{
+			name: "api_token_flags",
+			args: []string{
+				"go run ./cmd", "--api-tokens-file=/etc/headlamp/tokens.csv",
+				"--api-token-jwt-secret-file=/etc/headlamp/jwt-secret",
+			},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "/etc/headlamp/tokens.csv", conf.APITokensFile)
+				assert.Equal(t, "/etc/headlamp/jwt-secret", conf.APITokenJWTSecretFile)
+			},
+		},
+		{
+			name: "headless_flags",
+			args: []string{"go run ./cmd", "--headless", "--api-only"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.Headless)
+				assert.True(t, conf.APIOnly)
+			},
+		},
+		{
+			name: "oci_plugins_flag",
+			args: []string{
+				"go run ./cmd", "--oci-plugins=a=ghcr.io/org/a:1.0.0, b=ghcr.io/org/b",
+				"--plugin-cache-dir=/tmp/bundles",
+			},
+			verify: func(t *testing.T, conf *config.Config) {
+				refs, err := conf.OCIPluginRefs()
+				require.NoError(t, err)
+				assert.Equal(t, map[string]string{"a": "ghcr.io/org/a:1.0.0", "b": "ghcr.io/org/b"}, refs)
+				assert.Equal(t, "/tmp/bundles", conf.PluginCacheDir)
+			},
+		},
+		{
+			name: "plugin_verification_flags",
+			args: []string{
+				"go run ./cmd", "--plugin-verification=warn", "--plugin-trusted-keys=/etc/headlamp/keys",
+			},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "warn", conf.PluginVerification)
+				assert.Equal(t, "/etc/headlamp/keys", conf.PluginTrustedKeys)
+			},
+		},
+		{
+			name: "plugin_backends_flag",
+			args: []string{"go run ./cmd", "--plugin-backends"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.PluginBackends)
+			},
+		},
+		{
+			name: "helm_repo_refresh_interval_flag",
+			args: []string{"go run ./cmd", "--helm-repo-refresh-interval=30m"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, 30*time.Minute, conf.HelmRepoRefreshInterval)
+			},
+		},
+		{
+			name: "cluster_metrics_interval_flag",
+			args: []string{"go run ./cmd", "--cluster-metrics-interval=0"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Zero(t, conf.ClusterMetricsInterval)
+			},
+		},
+		{
+			name: "discovery_cache_flag",
+			args: []string{"go run ./cmd", "--discovery-cache=false"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.False(t, conf.DiscoveryCache)
+			},
+		},
+		{
+			name: "openapi_cache_size_flag",
+			args: []string{"go run ./cmd", "--openapi-cache-size=64"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, 64, conf.OpenAPICacheSize)
+			},
+		},
+		{
+			name: "context_naming_flag",
+			args: []string{"go run ./cmd", "--context-naming=rfc1123-label"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "rfc1123-label", conf.ContextNaming)
+			},
+		},
+		{
+			name: "context_conflict_policy_flag",
+			args: []string{"go run ./cmd", "--context-conflict-policy=suffix"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "suffix", conf.ContextConflictPolicy)
+			},
+		},
+		{
+			name: "tunnel_token_flag",
+			args: []string{"go run ./cmd", "--tunnel-token=0123456789abcdef"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "0123456789abcdef", conf.TunnelToken)
+			},
+		},
+		{
+			name: "dynamic_cluster_quota_flags",
+			args: []string{"go run ./cmd", "--max-dynamic-clusters-per-user=5", "--max-dynamic-clusters=100"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, 5, conf.MaxDynamicClustersPerUser)
+				assert.Equal(t, 100, conf.MaxDynamicClusters)
+			},
+		},
+		{
+			name: "kubeconfig_url_refresh_interval_flag",
+			args: []string{"go run ./cmd", "--kubeconfig-url-refresh-interval=1m"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, time.Minute, conf.KubeConfigURLRefreshInterval)
+			},
+		},
+		{
+			name: "stateless_context_ttl_flags",
+			args: []string{
+				"go run ./cmd", "--stateless-context-ttl=10m", "--stateless-context-max-ttl=1h",
+				"--stateless-context-min-renewal=15s", "--stateless-context-renew-below=2m",
+			},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, 10*time.Minute, conf.StatelessContextTTL)
+				assert.Equal(t, time.Hour, conf.StatelessContextMaxTTL)
+				assert.Equal(t, 15*time.Second, conf.StatelessContextMinRenewal)
+				assert.Equal(t, 2*time.Minute, conf.StatelessContextRenewBelow)
+			},
+		},
+		{
+			name: "reject_insecure_clusters_flag",
+			args: []string{"go run ./cmd", "--reject-insecure-clusters"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.RejectInsecureClusters)
+			},
+		},
+		{
+			name: "context_namespace_flag",
+			args: []string{"go run ./cmd", "--context-namespace"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.ContextNamespace)
+			},
+		},
+		{
+			name: "record_cluster_events_flag",
+			args: []string{"go run ./cmd", "--record-cluster-events"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.RecordClusterEvents)
+			},
+		},
+		{
+			name: "shutdown_drain_timeout_flag",
+			args: []string{"go run ./cmd", "--shutdown-drain-timeout=5s"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, 5*time.Second, conf.ShutdownDrainTimeout)
+			},
+		},
+		{
+			name: "preflight_flag",
+			args: []string{"go run ./cmd", "--preflight"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.Preflight)
+			},
+		},
+		{
+			name: "tls_self_signed_flag",
+			args: []string{"go run ./cmd", "--tls-self-signed"},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.True(t, conf.TLSSelfSigned)
+				assert.Empty(t, conf.TLSCertPath)
+			},
+		},
+		{
+			name: "listen_socket_flags",
+			args: []string{
+				"go run ./cmd", "--listen-socket=/run/headlamp/headlamp.sock", "--listen-socket-mode=0660",
+			},
+			verify: func(t *testing.T, conf *config.Config) {
+				assert.Equal(t, "/run/headlamp/headlamp.sock", conf.ListenSocket)
+				assert.Equal(t, "0660", conf.ListenSocketMode)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,516 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package doctor diagnoses why a context cannot be used: it checks its kubeconfig,
+// certificates and exec plugin, and the connectivity and authentication to its cluster.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusOK means the check passed.
+	StatusOK Status = "ok"
+	// StatusWarning means the check passed, but something will need attention.
+	StatusWarning Status = "warning"
+	// StatusError means the check failed.
+	StatusError Status = "error"
+	// StatusSkipped means the check doesn't apply, or a check it depends on failed.
+	StatusSkipped Status = "skipped"
+)
+
+// The checks, in the order they are run.
+const (
+	CheckKubeConfig   = "kubeconfig"
+	CheckCertificates = "certificates"
+	CheckExecPlugin   = "exec-plugin"
+	CheckDNS          = "dns"
+	CheckTCP          = "tcp"
+	CheckTLS          = "tls"
+	CheckAuth         = "auth"
+)
+
+const (
+	// DefaultTimeout is the default timeout of each network check.
+	DefaultTimeout = 5 * time.Second
+	// DefaultExpiryWarning is how long before their expiry certificates are warned about.
+	DefaultExpiryWarning = 14 * 24 * time.Hour
+	// DefaultConcurrency is how many contexts are checked at the same time by default.
+	DefaultConcurrency = 8
+)
+
+// Check is the result of one check of a context.
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the result of the checks of a context.
+type Report struct {
+	Context      string `json:"context"`
+	OriginalName string `json:"originalName,omitempty"`
+	Server       string `json:"server,omitempty"`
+	// Status is the worst status of the checks.
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// Doctor checks contexts.
+type Doctor struct {
+	// Timeout is the timeout of each network check.
+	Timeout time.Duration
+	// ExpiryWarning is how long before their expiry certificates are warned about.
+	ExpiryWarning time.Duration
+	// Concurrency is how many contexts are checked at the same time.
+	Concurrency int
+	// Offline skips the checks connecting to the clusters, to check a configuration before
+	// deploying it, where the clusters may not be reachable.
+	Offline bool
+
+	now      func() time.Time
+	lookPath func(string) (string, error)
+	resolver *net.Resolver
+}
+
+// New returns a Doctor with the default settings.
+func New() *Doctor {
+	return &Doctor{
+		Timeout:       DefaultTimeout,
+		ExpiryWarning: DefaultExpiryWarning,
+		Concurrency:   DefaultConcurrency,
+		now:           time.Now,
+		lookPath:      exec.LookPath,
+		resolver:      net.DefaultResolver,
+	}
+}
+
+// CheckAll checks the contexts concurrently, and returns their reports in the same order.
+func (d *Doctor) CheckAll(ctx context.Context, contexts []*kubeconfig.Context) []Report {
+	reports := make([]Report, len(contexts))
+	sem := make(chan struct{}, max(d.Concurrency, 1))
+
+	var wg sync.WaitGroup
+
+	for i, kContext := range contexts {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			reports[i] = d.Check(ctx, kContext)
+		}()
+	}
+
+	wg.Wait()
+
+	return reports
+}
+
+// Check runs the checks of a context. A check is skipped when one it depends on
+// failed, e.g. TCP is not checked if the server name doesn't resolve.
+func (d *Doctor) Check(ctx context.Context, kContext *kubeconfig.Context) Report {
+	report := Report{Context: kContext.Name, OriginalName: kContext.OriginalName}
+	if kContext.Cluster != nil {
+		report.Server = kContext.Cluster.Server
+	}
+
+	add := func(name string, status Status, message string) bool {
+		report.Checks = append(report.Checks, Check{Name: name, Status: status, Message: message})
+
+		return status != StatusError
+	}
+
+	skipRest := func(names []string, reason string) {
+		for _, name := range names {
+			add(name, StatusSkipped, reason)
+		}
+	}
+
+	restConfig, server, err := d.checkKubeConfig(kContext)
+	if !add(CheckKubeConfig, statusOf(err), messageOf(err, "valid, server "+report.Server)) {
+		skipRest([]string{CheckCertificates, CheckExecPlugin, CheckDNS, CheckTCP, CheckTLS, CheckAuth},
+			"the kubeconfig is invalid")
+		report.Status = WorstStatus(report.Checks)
+
+		return report
+	}
+
+	status, message := d.checkCertificates(kContext)
+	add(CheckCertificates, status, message)
+
+	status, message = d.checkExecPlugin(kContext)
+	add(CheckExecPlugin, status, message)
+
+	if d.Offline {
+		skipRest([]string{CheckDNS, CheckTCP, CheckTLS, CheckAuth}, "offline")
+		report.Status = WorstStatus(report.Checks)
+
+		return report
+	}
+
+	network := []struct {
+		name  string
+		check func(context.Context, *rest.Config, *url.URL) (Status, string)
+	}{
+		{CheckDNS, d.checkDNS},
+		{CheckTCP, d.checkTCP},
+		{CheckTLS, d.checkTLS},
+		{CheckAuth, d.checkAuth},
+	}
+
+	for i, check := range network {
+		checkCtx, cancel := context.WithTimeout(ctx, d.Timeout)
+		status, message := check.check(checkCtx, restConfig, server)
+
+		cancel()
+
+		if !add(check.name, status, message) {
+			remaining := make([]string, 0, len(network)-i-1)
+			for _, next := range network[i+1:] {
+				remaining = append(remaining, next.name)
+			}
+
+			skipRest(remaining, "the "+check.name+" check failed")
+
+			break
+		}
+	}
+
+	report.Status = WorstStatus(report.Checks)
+
+	return report
+}
+
+// checkKubeConfig checks that the context is complete and its client config can be built.
+func (d *Doctor) checkKubeConfig(kContext *kubeconfig.Context) (*rest.Config, *url.URL, error) {
+	if kContext.Error != "" {
+		return nil, nil, errors.New(kContext.Error)
+	}
+
+	if kContext.Cluster == nil || kContext.Cluster.Server == "" {
+		return nil, nil, errors.New("the context has no cluster server")
+	}
+
+	server, err := url.Parse(kContext.Cluster.Server)
+	if err != nil || server.Host == "" {
+		return nil, nil, fmt.Errorf("invalid cluster server %q", kContext.Cluster.Server)
+	}
+
+	restConfig, err := kContext.RESTConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("building client config: %w", err)
+	}
+
+	restConfig.Timeout = d.Timeout
+
+	return restConfig, server, nil
+}
+
+// checkCertificates checks the expiry of the certificate authority and client certificates.
+func (d *Doctor) checkCertificates(kContext *kubeconfig.Context) (Status, string) {
+	type source struct {
+		name string
+		data []byte
+		file string
+	}
+
+	var sources []source
+
+	sources = append(sources, source{
+		"certificate authority", kContext.Cluster.CertificateAuthorityData, kContext.Cluster.CertificateAuthority,
+	})
+
+	if kContext.AuthInfo != nil {
+		sources = append(sources, source{
+			"client certificate", kContext.AuthInfo.ClientCertificateData, kContext.AuthInfo.ClientCertificate,
+		})
+	}
+
+	status, messages := StatusSkipped, []string{}
+
+	for _, s := range sources {
+		data := s.data
+
+		if len(data) == 0 && s.file != "" {
+			var err error
+
+			if data, err = os.ReadFile(s.file); err != nil {
+				return StatusError, fmt.Sprintf("reading %s: %v", s.name, err)
+			}
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		certs, err := parseCertificates(data)
+		if err != nil {
+			return StatusError, fmt.Sprintf("parsing %s: %v", s.name, err)
+		}
+
+		certStatus, message := d.expiryStatus(s.name, certs)
+		status = worseStatus(status, certStatus)
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return StatusSkipped, "no certificates"
+	}
+
+	return status, strings.Join(messages, "; ")
+}
+
+// expiryStatus returns the status of the certificate expiring first.
+func (d *Doctor) expiryStatus(name string, certs []*x509.Certificate) (Status, string) {
+	first := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+
+	expiry := first.NotAfter.UTC().Format(time.RFC3339)
+	now := d.now()
+
+	switch {
+	case now.After(first.NotAfter):
+		return StatusError, fmt.Sprintf("%s expired on %s", name, expiry)
+	case now.Add(d.ExpiryWarning).After(first.NotAfter):
+		return StatusWarning, fmt.Sprintf("%s expires soon, on %s", name, expiry)
+	default:
+		return StatusOK, fmt.Sprintf("%s valid until %s", name, expiry)
+	}
+}
+
+// parseCertificates parses the PEM encoded certificates of data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+
+	return certs, nil
+}
+
+// checkExecPlugin checks that the command of the exec credential plugin can be found.
+func (d *Doctor) checkExecPlugin(kContext *kubeconfig.Context) (Status, string) {
+	if kContext.AuthInfo == nil || kContext.AuthInfo.Exec == nil {
+		return StatusSkipped, "no exec plugin"
+	}
+
+	command := kContext.AuthInfo.Exec.Command
+
+	path, err := d.lookPath(command)
+	if err != nil {
+		message := fmt.Sprintf("exec plugin %q not found: %v", command, err)
+		if hint := kContext.AuthInfo.Exec.InstallHint; hint != "" {
+			message += ": " + hint
+		}
+
+		return StatusError, message
+	}
+
+	return StatusOK, "exec plugin found at " + path
+}
+
+// checkDNS checks that the server host name resolves.
+func (d *Doctor) checkDNS(ctx context.Context, _ *rest.Config, server *url.URL) (Status, string) {
+	host := server.Hostname()
+	if net.ParseIP(host) != nil {
+		return StatusSkipped, "the server is an IP address"
+	}
+
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return StatusError, err.Error()
+	}
+
+	return StatusOK, fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))
+}
+
+// checkTCP checks that the server accepts connections.
+func (d *Doctor) checkTCP(ctx context.Context, _ *rest.Config, server *url.URL) (Status, string) {
+	addr := hostPort(server)
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return StatusError, err.Error()
+	}
+
+	conn.Close()
+
+	return StatusOK, "connected to " + addr
+}
+
+// checkTLS checks the TLS handshake with the server, with the TLS settings of the context.
+func (d *Doctor) checkTLS(ctx context.Context, restConfig *rest.Config, server *url.URL) (Status, string) {
+	if server.Scheme != "https" {
+		return StatusSkipped, "the server doesn't use TLS"
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(restConfig)
+	if err != nil {
+		return StatusError, fmt.Sprintf("building TLS config: %v", err)
+	}
+
+	dialer := &tls.Dialer{Config: tlsConfig}
+
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort(server))
+	if err != nil {
+		return StatusError, err.Error()
+	}
+
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok || len(tlsConn.ConnectionState().PeerCertificates) == 0 {
+		return StatusOK, "TLS handshake succeeded"
+	}
+
+	if restConfig.Insecure {
+		return StatusWarning, "TLS handshake succeeded, but the server certificate is not verified"
+	}
+
+	return d.expiryStatus("server certificate", tlsConn.ConnectionState().PeerCertificates[:1])
+}
+
+// checkAuth checks that the cluster accepts the credentials of the context.
+func (d *Doctor) checkAuth(ctx context.Context, restConfig *rest.Config, _ *url.URL) (Status, string) {
+	if restConfig.AuthProvider != nil {
+		return StatusSkipped, "the OIDC token is obtained in the browser"
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return StatusError, fmt.Sprintf("creating client: %v", err)
+	}
+
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx,
+		&authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+
+	switch {
+	case err == nil:
+		if review.Status.UserInfo.Username == "system:anonymous" {
+			return StatusWarning, "the cluster accepts the requests as anonymous"
+		}
+
+		return StatusOK, "authenticated as " + review.Status.UserInfo.Username
+	case apierrors.IsUnauthorized(err):
+		return StatusError, "the cluster rejected the credentials: " + err.Error()
+	case apierrors.IsNotFound(err), apierrors.IsForbidden(err):
+		// Clusters older than 1.28 don't serve SelfSubjectReview.
+		version, err := clientset.Discovery().ServerVersion()
+		if err != nil {
+			return StatusError, err.Error()
+		}
+
+		return StatusWarning, fmt.Sprintf("reached Kubernetes %s, but the user could not be verified", version.GitVersion)
+	default:
+		return StatusError, err.Error()
+	}
+}
+
+// hostPort returns the host:port address of the server, with the default port of its scheme.
+func hostPort(server *url.URL) string {
+	port := server.Port()
+	if port == "" {
+		port = "443"
+		if server.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	return net.JoinHostPort(server.Hostname(), port)
+}
+
+func statusOf(err error) Status {
+	if err != nil {
+		return StatusError
+	}
+
+	return StatusOK
+}
+
+func messageOf(err error, ok string) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return ok
+}
+
+// statusRank orders the statuses from the best to the worst.
+var statusRank = map[Status]int{StatusSkipped: 0, StatusOK: 1, StatusWarning: 2, StatusError: 3}
+
+func worseStatus(a, b Status) Status {
+	if statusRank[b] > statusRank[a] {
+		return b
+	}
+
+	return a
+}
+
+// WorstStatus returns the worst status of the checks, ok if they were all skipped.
+func WorstStatus(checks []Check) Status {
+	status := StatusOK
+
+	for _, check := range checks {
+		status = worseStatus(status, check.Status)
+	}
+
+	return status
+}
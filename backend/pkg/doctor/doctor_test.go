@@ -0,0 +1,188 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/doctor"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newAPIServer returns a fake API server answering SelfSubjectReviews for the token.
+func newAPIServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"kind":"SelfSubjectReview","apiVersion":"authentication.k8s.io/v1",` +
+			`"status":{"userInfo":{"username":"alice"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func serverContext(server *httptest.Server, token string) *kubeconfig.Context {
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	return &kubeconfig.Context{
+		Name:        "test",
+		KubeContext: &api.Context{Cluster: "test", AuthInfo: "test"},
+		Cluster:     &api.Cluster{Server: server.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{Token: token},
+	}
+}
+
+func statuses(report doctor.Report) map[string]doctor.Status {
+	result := map[string]doctor.Status{}
+	for _, check := range report.Checks {
+		result[check.Name] = check.Status
+	}
+
+	return result
+}
+
+func TestCheckHealthyContext(t *testing.T) {
+	server := newAPIServer(t, "token")
+
+	report := doctor.New().Check(context.Background(), serverContext(server, "token"))
+
+	assert.Equal(t, doctor.StatusOK, report.Status)
+	assert.Equal(t, map[string]doctor.Status{
+		doctor.CheckKubeConfig:   doctor.StatusOK,
+		doctor.CheckCertificates: doctor.StatusOK,
+		doctor.CheckExecPlugin:   doctor.StatusSkipped,
+		doctor.CheckDNS:          doctor.StatusSkipped,
+		doctor.CheckTCP:          doctor.StatusOK,
+		doctor.CheckTLS:          doctor.StatusOK,
+		doctor.CheckAuth:         doctor.StatusOK,
+	}, statuses(report))
+	assert.Equal(t, "authenticated as alice", report.Checks[len(report.Checks)-1].Message)
+}
+
+func TestCheckRejectedCredentials(t *testing.T) {
+	server := newAPIServer(t, "token")
+
+	report := doctor.New().Check(context.Background(), serverContext(server, "wrong"))
+
+	assert.Equal(t, doctor.StatusError, report.Status)
+	assert.Equal(t, doctor.StatusError, statuses(report)[doctor.CheckAuth])
+}
+
+func TestCheckUnreachableServer(t *testing.T) {
+	server := newAPIServer(t, "token")
+	kContext := serverContext(server, "token")
+	server.Close()
+
+	report := doctor.New().Check(context.Background(), kContext)
+
+	checks := statuses(report)
+	assert.Equal(t, doctor.StatusError, checks[doctor.CheckTCP])
+	assert.Equal(t, doctor.StatusSkipped, checks[doctor.CheckTLS])
+	assert.Equal(t, doctor.StatusSkipped, checks[doctor.CheckAuth])
+}
+
+func TestCheckOffline(t *testing.T) {
+	server := newAPIServer(t, "token")
+	kContext := serverContext(server, "token")
+	server.Close()
+
+	d := doctor.New()
+	d.Offline = true
+
+	report := d.Check(context.Background(), kContext)
+
+	checks := statuses(report)
+	assert.Equal(t, doctor.StatusOK, checks[doctor.CheckKubeConfig])
+	assert.Equal(t, doctor.StatusSkipped, checks[doctor.CheckTCP])
+	assert.Equal(t, doctor.StatusSkipped, checks[doctor.CheckAuth])
+	assert.NotEqual(t, doctor.StatusError, report.Status)
+}
+
+func TestCheckInvalidContext(t *testing.T) {
+	report := doctor.New().Check(context.Background(), &kubeconfig.Context{Name: "broken", Error: "cluster not found"})
+
+	assert.Equal(t, doctor.StatusError, report.Status)
+	require.Len(t, report.Checks, 7)
+	assert.Equal(t, "cluster not found", report.Checks[0].Message)
+
+	for _, check := range report.Checks[1:] {
+		assert.Equal(t, doctor.StatusSkipped, check.Status, check.Name)
+	}
+}
+
+func TestCheckMissingExecPlugin(t *testing.T) {
+	server := newAPIServer(t, "token")
+	kContext := serverContext(server, "token")
+	kContext.AuthInfo = &api.AuthInfo{Exec: &api.ExecConfig{
+		Command:         "headlamp-missing-credential-plugin",
+		APIVersion:      "client.authentication.k8s.io/v1",
+		InstallHint:     "install it from the cloud provider",
+		InteractiveMode: api.NeverExecInteractiveMode,
+	}}
+
+	report := doctor.New().CheckAll(context.Background(), []*kubeconfig.Context{kContext})[0]
+
+	checks := statuses(report)
+	assert.Equal(t, doctor.StatusError, checks[doctor.CheckExecPlugin])
+	assert.Contains(t, report.Checks[2].Message, "install it from the cloud provider")
+}
+
+func TestCheckExpiredCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	server := newAPIServer(t, "token")
+	kContext := serverContext(server, "token")
+	kContext.Cluster.CertificateAuthorityData = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	report := doctor.New().Check(context.Background(), kContext)
+
+	assert.Equal(t, doctor.StatusError, statuses(report)[doctor.CheckCertificates])
+	assert.Contains(t, report.Checks[1].Message, "certificate authority expired")
+}
@@ -1,6 +1,8 @@
 package headlampconfig
 
 import (
+	"os"
+
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
 )
@@ -8,6 +10,8 @@ import (
 type HeadlampCFG struct {
 	UseInCluster          bool
 	ListenAddr            string
+	ListenSocket          string
+	ListenSocketMode      os.FileMode
 	CacheEnabled          bool
 	DevMode               bool
 	Insecure              bool
@@ -27,4 +31,5 @@ type HeadlampCFG struct {
 	ProxyURLs             []string
 	TLSCertPath           string
 	TLSKeyPath            string
+	TLSSelfSigned         bool
 }
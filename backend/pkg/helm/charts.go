@@ -55,7 +55,7 @@ func listCharts(filter string, settings *cli.EnvSettings) ([]chartInfo, error) {
 		name := re.Name
 		repoIndexFile := filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(name))
 
-		indexFile, err := repo.LoadIndexFile(repoIndexFile)
+		indexFile, err := loadIndexFile(repoIndexFile)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,312 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	// CredentialsKeyEnv is the environment variable with the base64 encoded 32 bytes key the
+	// credentials are encrypted with. It is kept apart from the credentials file, which can't
+	// be written nor read without it.
+	CredentialsKeyEnv = "HEADLAMP_HELM_CREDENTIALS_KEY"
+	// credentialsFile has the encrypted credentials of the repositories, next to the repository config.
+	credentialsFile     = "repository-credentials.enc"
+	credentialsFileMode = os.FileMode(0o600)
+	credentialsKeySize  = 32
+)
+
+// ErrCredentialsKeyMissing is returned when storing or reading repository credentials without a key.
+var ErrCredentialsKeyMissing = errors.New("repository credentials need a key in " + CredentialsKeyEnv)
+
+// RepositoryCredentials are the credentials of a chart repository. They are stored encrypted,
+// instead of in the Helm repository config, and are never returned by the API.
+type RepositoryCredentials struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// CAData, CertData and KeyData are PEM encoded.
+	CAData                string `json:"caData,omitempty"`
+	CertData              string `json:"certData,omitempty"`
+	KeyData               string `json:"keyData,omitempty"`
+	InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
+	PassCredentialsAll    bool   `json:"passCredentialsAll,omitempty"`
+}
+
+// credentialsMutex serializes the changes of the credentials file.
+var credentialsMutex sync.Mutex
+
+func credentialsPath(settings *cli.EnvSettings) string {
+	return filepath.Join(filepath.Dir(settings.RepositoryConfig), credentialsFile)
+}
+
+// credentialsKey returns the key the credentials are encrypted with, from CredentialsKeyEnv.
+func credentialsKey() ([]byte, error) {
+	encoded := os.Getenv(CredentialsKeyEnv)
+	if encoded == "" {
+		return nil, ErrCredentialsKeyMissing
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != credentialsKeySize {
+		return nil, fmt.Errorf("%s must be %d base64 encoded bytes", CredentialsKeyEnv, credentialsKeySize)
+	}
+
+	return key, nil
+}
+
+func credentialsCipher() (cipher.AEAD, error) {
+	key, err := credentialsKey()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// loadCredentials returns the stored credentials, by repository name.
+func loadCredentials(settings *cli.EnvSettings) (map[string]RepositoryCredentials, error) {
+	credentials := map[string]RepositoryCredentials{}
+
+	data, err := os.ReadFile(credentialsPath(settings))
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := credentialsCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("invalid repository credentials file")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting repository credentials: %w", err)
+	}
+
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func saveCredentials(settings *cli.EnvSettings, credentials map[string]RepositoryCredentials) error {
+	path := credentialsPath(settings)
+
+	if len(credentials) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	aead, err := credentialsCipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, aead.Seal(nonce, nonce, plaintext, nil), credentialsFileMode)
+}
+
+// writeFileAtomic writes the data to a temporary file next to path, which is then renamed to
+// path, so that path is never left partially written.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), defaultNewConfigFolderMode); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// setCredentials stores the credentials of a repository, or removes them if they are nil.
+func setCredentials(settings *cli.EnvSettings, name string, creds *RepositoryCredentials) error {
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+
+	credentials, err := loadCredentials(settings)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := credentials[name]; !ok && creds == nil {
+		return nil
+	}
+
+	if creds == nil {
+		delete(credentials, name)
+	} else {
+		credentials[name] = *creds
+	}
+
+	return saveCredentials(settings, credentials)
+}
+
+// repositoryCredentials returns the stored credentials of a repository, or nil if it has none.
+func repositoryCredentials(settings *cli.EnvSettings, name string) (*RepositoryCredentials, error) {
+	credentials, err := loadCredentials(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, ok := credentials[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return &creds, nil
+}
+
+// tlsFiles writes the TLS data of the credentials to temporary files, as Helm reads them from
+// files, and returns their paths and a function removing them.
+func (c *RepositoryCredentials) tlsFiles() (caFile, certFile, keyFile string, cleanup func(), err error) {
+	cleanup = func() {}
+
+	if c.CAData == "" && c.CertData == "" && c.KeyData == "" {
+		return "", "", "", cleanup, nil
+	}
+
+	dir, err := os.MkdirTemp("", "headlamp-helm-repo-")
+	if err != nil {
+		return "", "", "", cleanup, err
+	}
+
+	cleanup = func() { _ = os.RemoveAll(dir) }
+
+	write := func(name, data string) (string, error) {
+		if data == "" {
+			return "", nil
+		}
+
+		path := filepath.Join(dir, name)
+
+		return path, os.WriteFile(path, []byte(data), credentialsFileMode)
+	}
+
+	if caFile, err = write("ca.pem", c.CAData); err == nil {
+		if certFile, err = write("cert.pem", c.CertData); err == nil {
+			keyFile, err = write("key.pem", c.KeyData)
+		}
+	}
+
+	if err != nil {
+		cleanup()
+
+		return "", "", "", func() {}, err
+	}
+
+	return caFile, certFile, keyFile, cleanup, nil
+}
+
+// applyToEntry sets the credentials on a repository entry, to download its index.
+func (c *RepositoryCredentials) applyToEntry(entry *repo.Entry) (func(), error) {
+	caFile, certFile, keyFile, cleanup, err := c.tlsFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Username = c.Username
+	entry.Password = c.Password
+	entry.CAFile = caFile
+	entry.CertFile = certFile
+	entry.KeyFile = keyFile
+	entry.InsecureSkipTLSverify = c.InsecureSkipTLSVerify
+	entry.PassCredentialsAll = c.PassCredentialsAll
+
+	return cleanup, nil
+}
+
+// applyToChartPathOptions sets the credentials on the options used to download a chart.
+func (c *RepositoryCredentials) applyToChartPathOptions(options *action.ChartPathOptions) (func(), error) {
+	caFile, certFile, keyFile, cleanup, err := c.tlsFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	options.Username = c.Username
+	options.Password = c.Password
+	options.CaFile = caFile
+	options.CertFile = certFile
+	options.KeyFile = keyFile
+	options.InsecureSkipTLSverify = c.InsecureSkipTLSVerify
+	options.PassCredentialsAll = c.PassCredentialsAll
+
+	return cleanup, nil
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/yaml"
+)
+
+// Kinds of change in an upgrade preview.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// ValueChange is a change of a value, at its dotted path like image.tag.
+type ValueChange struct {
+	Path     string      `json:"path"`
+	Change   string      `json:"change"`
+	Current  interface{} `json:"current,omitempty"`
+	Proposed interface{} `json:"proposed,omitempty"`
+}
+
+// ManifestChange is a change of a resource of the release manifest.
+type ManifestChange struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Change    string `json:"change"`
+	// Current and Proposed are the YAML of the resource before and after the upgrade.
+	Current  string `json:"current,omitempty"`
+	Proposed string `json:"proposed,omitempty"`
+	// Fields are the changes of the resource fields, when it is modified.
+	Fields []ValueChange `json:"fields,omitempty"`
+}
+
+type UpgradePreviewResponse struct {
+	Revision        int              `json:"revision"`
+	ChartVersion    string           `json:"chartVersion"`
+	NewChartVersion string           `json:"newChartVersion"`
+	Values          []ValueChange    `json:"values"`
+	Manifests       []ManifestChange `json:"manifests"`
+}
+
+// PreviewUpgrade renders the upgrade of a release, without applying it, and returns what
+// changes in its values and manifests compared to the current release.
+func (h *Handler) PreviewUpgrade(w http.ResponseWriter, r *http.Request) {
+	var req UpgradeReleaseRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		handleError(w, req.Name, err, "parsing request for upgrade preview", http.StatusBadRequest)
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		handleError(w, req.Name, err, "validating request for upgrade preview", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.Configuration.Releases.Last(req.Name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		handleError(w, req.Name, err, "release not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		handleError(w, req.Name, err, "getting release", http.StatusInternalServerError)
+		return
+	}
+
+	values := make(map[string]interface{})
+
+	valuesStr, err := base64.StdEncoding.DecodeString(req.Values)
+	if err != nil {
+		handleError(w, req.Name, err, "decoding values", http.StatusBadRequest)
+		return
+	}
+
+	err = yaml.Unmarshal(valuesStr, &values)
+	if err != nil {
+		handleError(w, req.Name, err, "un-marshalling values", http.StatusBadRequest)
+		return
+	}
+
+	upgradeClient := action.NewUpgrade(h.Configuration)
+	upgradeClient.Namespace = req.Namespace
+	upgradeClient.Description = req.Description
+	upgradeClient.ChartPathOptions.Version = req.Version
+	// Render on the client side, so nothing is sent to the cluster.
+	upgradeClient.DryRun = true
+	upgradeClient.DryRunOption = "client"
+
+	chart, err := h.getChart("upgrade_preview", req.Chart, req.Name, upgradeClient.ChartPathOptions, true, h.EnvSettings)
+	if err != nil {
+		handleError(w, req.Name, err, "getting chart", http.StatusBadRequest)
+		return
+	}
+
+	proposed, err := upgradeClient.Run(req.Name, chart, values)
+	if err != nil {
+		handleError(w, req.Name, err, "rendering upgrade", http.StatusUnprocessableEntity)
+		return
+	}
+
+	manifests, err := diffManifests(current.Manifest, proposed.Manifest)
+	if err != nil {
+		handleError(w, req.Name, err, "comparing manifests", http.StatusInternalServerError)
+		return
+	}
+
+	resp := UpgradePreviewResponse{
+		Revision:  current.Version,
+		Values:    diffValues("", current.Config, proposed.Config),
+		Manifests: manifests,
+	}
+
+	if current.Chart != nil && current.Chart.Metadata != nil {
+		resp.ChartVersion = current.Chart.Metadata.Version
+	}
+
+	if chart.Metadata != nil {
+		resp.NewChartVersion = chart.Metadata.Version
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"request": "preview_upgrade", "releaseName": req.Name},
+			err, "encoding response")
+	}
+}
+
+// diffValues returns the changes from current to proposed, sorted by path. Maps are
+// compared key by key and any other value, like a list, as a whole.
+func diffValues(path string, current, proposed interface{}) []ValueChange {
+	currentMap, currentIsMap := current.(map[string]interface{})
+	proposedMap, proposedIsMap := proposed.(map[string]interface{})
+
+	if (currentIsMap && proposedIsMap) || path == "" {
+		changes := []ValueChange{}
+
+		for key := range mergedKeys(currentMap, proposedMap) {
+			currentValue, inCurrent := currentMap[key]
+			proposedValue, inProposed := proposedMap[key]
+			keyPath := joinPath(path, key)
+
+			switch {
+			case !inCurrent:
+				changes = append(changes, ValueChange{Path: keyPath, Change: ChangeAdded, Proposed: proposedValue})
+			case !inProposed:
+				changes = append(changes, ValueChange{Path: keyPath, Change: ChangeRemoved, Current: currentValue})
+			default:
+				changes = append(changes, diffValues(keyPath, currentValue, proposedValue)...)
+			}
+		}
+
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+		return changes
+	}
+
+	if reflect.DeepEqual(current, proposed) {
+		return nil
+	}
+
+	return []ValueChange{{Path: path, Change: ChangeModified, Current: current, Proposed: proposed}}
+}
+
+func mergedKeys(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+
+	for key := range a {
+		keys[key] = struct{}{}
+	}
+
+	for key := range b {
+		keys[key] = struct{}{}
+	}
+
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+type manifestResource struct {
+	kind      string
+	name      string
+	namespace string
+	content   string
+	object    map[string]interface{}
+}
+
+func (m manifestResource) key() string {
+	return m.kind + "/" + m.namespace + "/" + m.name
+}
+
+// parseManifest returns the resources of a release manifest, by kind, namespace and name.
+func parseManifest(manifest string) (map[string]manifestResource, error) {
+	resources := map[string]manifestResource{}
+
+	for _, content := range releaseutil.SplitManifests(manifest) {
+		object := map[string]interface{}{}
+
+		if err := yaml.Unmarshal([]byte(content), &object); err != nil {
+			return nil, fmt.Errorf("parsing manifest: %w", err)
+		}
+
+		if len(object) == 0 {
+			continue
+		}
+
+		resource := manifestResource{content: strings.TrimSpace(content), object: object}
+		resource.kind, _ = object["kind"].(string)
+
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			resource.name, _ = metadata["name"].(string)
+			resource.namespace, _ = metadata["namespace"].(string)
+		}
+
+		resources[resource.key()] = resource
+	}
+
+	return resources, nil
+}
+
+// diffManifests returns the resources that are added, removed or modified from the current
+// manifest to the proposed one, sorted by kind, namespace and name.
+func diffManifests(current, proposed string) ([]ManifestChange, error) {
+	currentResources, err := parseManifest(current)
+	if err != nil {
+		return nil, err
+	}
+
+	proposedResources, err := parseManifest(proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(currentResources)+len(proposedResources))
+
+	for key := range currentResources {
+		keys = append(keys, key)
+	}
+
+	for key := range proposedResources {
+		if _, ok := currentResources[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	changes := []ManifestChange{}
+
+	for _, key := range keys {
+		currentResource, inCurrent := currentResources[key]
+		proposedResource, inProposed := proposedResources[key]
+
+		switch {
+		case !inCurrent:
+			changes = append(changes, manifestChange(proposedResource, ChangeAdded, "", proposedResource.content))
+		case !inProposed:
+			changes = append(changes, manifestChange(currentResource, ChangeRemoved, currentResource.content, ""))
+		default:
+			fields := diffValues("", currentResource.object, proposedResource.object)
+			if len(fields) == 0 {
+				continue
+			}
+
+			change := manifestChange(currentResource, ChangeModified, currentResource.content, proposedResource.content)
+			change.Fields = fields
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func manifestChange(resource manifestResource, change, current, proposed string) ManifestChange {
+	return ManifestChange{
+		Kind:      resource.kind,
+		Name:      resource.name,
+		Namespace: resource.namespace,
+		Change:    change,
+		Current:   current,
+		Proposed:  proposed,
+	}
+}
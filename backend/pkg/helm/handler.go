@@ -46,6 +46,21 @@ type Handler struct {
 	*action.Configuration
 	*cli.EnvSettings
 	Cache cache.Cache[interface{}]
+	// OnActionDone, if set, is called once an install, upgrade, uninstall or rollback of a
+	// release started by the handler is done.
+	OnActionDone func(ActionResult)
+	// namespace is the namespace of the releases of the handler.
+	namespace string
+}
+
+// ActionResult is the outcome of an action on a release.
+type ActionResult struct {
+	// Action is install, upgrade, uninstall or rollback.
+	Action    string
+	Release   string
+	Namespace string
+	// Err is the reason the action failed, nil if it succeeded.
+	Err error
 }
 
 func NewActionConfig(clientConfig clientcmd.ClientConfig, namespace string) (*action.Configuration, error) {
@@ -85,10 +100,19 @@ func NewHandlerWithSettings(clientConfig clientcmd.ClientConfig,
 		return nil, err
 	}
 
+	// Used to pull oci:// charts.
+	actionConfig.RegistryClient, err = newRegistryClient(settings)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "unable to create registry client")
+
+		return nil, err
+	}
+
 	return &Handler{
 		Configuration: actionConfig,
 		EnvSettings:   settings,
 		Cache:         cache,
+		namespace:     namespace,
 	}, nil
 }
 
@@ -207,4 +231,14 @@ func (h *Handler) setReleaseStatusSilent(actionName, releaseName, status string,
 		logger.Log(logger.LevelError, map[string]string{"releaseName": releaseName, "status": status},
 			cacheErr, "unable to set status")
 	}
+
+	if h.OnActionDone == nil || status == processing {
+		return
+	}
+
+	if status == failed && err == nil {
+		err = fmt.Errorf("%s of release %s failed", actionName, releaseName)
+	}
+
+	h.OnActionDone(ActionResult{Action: actionName, Release: releaseName, Namespace: h.namespace, Err: err})
 }
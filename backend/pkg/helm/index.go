@@ -0,0 +1,155 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+type cachedIndex struct {
+	modTime time.Time
+	size    int64
+	index   *repo.IndexFile
+}
+
+// indexCache keeps the parsed repository indexes, by file, so searching charts doesn't
+// parse them every time. An index is parsed again when its file changes.
+var indexCache = struct {
+	sync.Mutex
+	indexes map[string]cachedIndex
+}{indexes: map[string]cachedIndex{}}
+
+// loadIndexFile returns the parsed index of the file, from the cache if it didn't change.
+func loadIndexFile(path string) (*repo.IndexFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	indexCache.Lock()
+	cached, ok := indexCache.indexes[path]
+	indexCache.Unlock()
+
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.index, nil
+	}
+
+	index, err := repo.LoadIndexFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	indexCache.Lock()
+	indexCache.indexes[path] = cachedIndex{modTime: info.ModTime(), size: info.Size(), index: index}
+	indexCache.Unlock()
+
+	return index, nil
+}
+
+// downloadIndex downloads the index of a repository, with its stored credentials, into the repository cache.
+func downloadIndex(entry *repo.Entry, settings *cli.EnvSettings) error {
+	creds, err := repositoryCredentials(settings, entry.Name)
+	if err != nil {
+		return err
+	}
+
+	if creds != nil {
+		entryWithCreds := *entry
+
+		cleanup, err := creds.applyToEntry(&entryWithCreds)
+		if err != nil {
+			return err
+		}
+
+		defer cleanup()
+
+		entry = &entryWithCreds
+	}
+
+	chartRepo, err := repo.NewChartRepository(entry, getter.All(settings))
+	if err != nil {
+		return err
+	}
+
+	chartRepo.CachePath = settings.RepositoryCache
+
+	_, err = chartRepo.DownloadIndexFile()
+
+	return err
+}
+
+// RefreshRepositories downloads the index of every repository, so chart searches are up to date.
+// A repository that fails is logged and doesn't stop the others.
+func RefreshRepositories(settings *cli.EnvSettings) error {
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, entry := range repoFile.Repositories {
+		if err := downloadIndex(entry, settings); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"repository": entry.Name},
+				err, "refreshing repository index")
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		// Parse the new index now, rather than on the next search.
+		_, err := loadIndexFile(filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(entry.Name)))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RefreshRepositoriesEvery refreshes the repository indexes every interval, until ctx is done.
+func RefreshRepositoriesEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RefreshRepositories(settings); err != nil {
+				logger.Log(logger.LevelWarn, nil, err, "refreshing helm repositories")
+			}
+		}
+	}
+}
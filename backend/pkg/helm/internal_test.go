@@ -0,0 +1,229 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestDiffValues(t *testing.T) {
+	current := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.24"},
+		"debug":    true,
+		"ports":    []interface{}{80},
+	}
+	proposed := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.25"},
+		"ports":    []interface{}{80, 443},
+		"service":  map[string]interface{}{"type": "ClusterIP"},
+	}
+
+	assert.Equal(t, []ValueChange{
+		{Path: "debug", Change: ChangeRemoved, Current: true},
+		{Path: "image.tag", Change: ChangeModified, Current: "1.24", Proposed: "1.25"},
+		{Path: "ports", Change: ChangeModified, Current: []interface{}{80}, Proposed: []interface{}{80, 443}},
+		{Path: "service", Change: ChangeAdded, Proposed: map[string]interface{}{"type": "ClusterIP"}},
+	}, diffValues("", current, proposed))
+
+	assert.Empty(t, diffValues("", nil, map[string]interface{}{}))
+}
+
+func TestDiffManifests(t *testing.T) {
+	current := `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  type: ClusterIP
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old
+`
+	proposed := `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  type: ClusterIP
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 2
+---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: new
+  namespace: apps
+`
+
+	changes, err := diffManifests(current, proposed)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, "ConfigMap", changes[0].Kind)
+	assert.Equal(t, ChangeRemoved, changes[0].Change)
+	assert.Empty(t, changes[0].Proposed)
+
+	assert.Equal(t, "Deployment", changes[1].Kind)
+	assert.Equal(t, ChangeModified, changes[1].Change)
+	assert.Equal(t, []ValueChange{
+		{Path: "spec.replicas", Change: ChangeModified, Current: float64(1), Proposed: float64(2)},
+	}, changes[1].Fields)
+
+	assert.Equal(t, "Secret", changes[2].Kind)
+	assert.Equal(t, "apps", changes[2].Namespace)
+	assert.Equal(t, ChangeAdded, changes[2].Change)
+	assert.Contains(t, changes[2].Proposed, "name: new")
+}
+
+func TestRepositoryCredentials(t *testing.T) {
+	settings := cli.New()
+	settings.RepositoryConfig = filepath.Join(t.TempDir(), "repositories.yaml")
+
+	creds := &RepositoryCredentials{Username: "user", Password: "s3cr3t-password", CAData: "ca"}
+
+	// Without a key, the credentials are neither stored nor read.
+	t.Setenv(CredentialsKeyEnv, "")
+	require.ErrorIs(t, setCredentials(settings, "private", creds), ErrCredentialsKeyMissing)
+
+	t.Setenv(CredentialsKeyEnv, base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	require.NoError(t, setCredentials(settings, "private", creds))
+
+	path := credentialsPath(settings)
+
+	encrypted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "s3cr3t-password")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, credentialsFileMode, info.Mode().Perm())
+
+	// The key is not stored next to the credentials.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	t.Setenv(CredentialsKeyEnv, "")
+	_, err = repositoryCredentials(settings, "private")
+	require.ErrorIs(t, err, ErrCredentialsKeyMissing)
+
+	t.Setenv(CredentialsKeyEnv, base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = repositoryCredentials(settings, "private")
+	require.ErrorContains(t, err, CredentialsKeyEnv)
+
+	t.Setenv(CredentialsKeyEnv, base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+
+	stored, err := repositoryCredentials(settings, "private")
+	require.NoError(t, err)
+	assert.Equal(t, creds, stored)
+
+	missing, err := repositoryCredentials(settings, "public")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	entry := &repo.Entry{Name: "private"}
+
+	cleanup, err := stored.applyToEntry(entry)
+	require.NoError(t, err)
+
+	assert.Equal(t, "user", entry.Username)
+	assert.Empty(t, entry.CertFile)
+
+	ca, err := os.ReadFile(entry.CAFile)
+	require.NoError(t, err)
+	assert.Equal(t, "ca", string(ca))
+
+	cleanup()
+	assert.NoFileExists(t, entry.CAFile)
+
+	require.NoError(t, setCredentials(settings, "private", nil))
+	assert.NoFileExists(t, path)
+}
+
+func TestLoadIndexFileCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("apiVersion: v1\nentries: {}\n"), 0o600))
+
+	first, err := loadIndexFile(path)
+	require.NoError(t, err)
+
+	second, err := loadIndexFile(path)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	third, err := loadIndexFile(path)
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+}
+
+func TestSetReleaseStatusSilentOnActionDone(t *testing.T) {
+	var results []ActionResult
+
+	h := &Handler{
+		Cache:        cache.New[interface{}](),
+		namespace:    "web",
+		OnActionDone: func(result ActionResult) { results = append(results, result) },
+	}
+
+	h.setReleaseStatusSilent("install", "nginx", processing, nil)
+	assert.Empty(t, results)
+
+	h.setReleaseStatusSilent("install", "nginx", success, nil)
+	h.setReleaseStatusSilent("upgrade", "nginx", failed, nil)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, ActionResult{Action: "install", Release: "nginx", Namespace: "web"}, results[0])
+	assert.Equal(t, "upgrade", results[1].Action)
+	assert.Error(t, results[1].Err)
+}
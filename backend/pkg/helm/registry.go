@@ -0,0 +1,205 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// RegistryLoginRequest is the payload to log in to an OCI registry. The credentials are
+// stored in the registry config file of helm, and used to pull oci:// charts.
+type RegistryLoginRequest struct {
+	Host     string `json:"host" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	// Insecure skips the verification of the registry certificate.
+	Insecure bool `json:"insecure"`
+}
+
+// RegistryLogoutRequest is the payload to log out of an OCI registry.
+type RegistryLogoutRequest struct {
+	Host string `json:"host" validate:"required"`
+}
+
+// ListRegistriesResponse lists the OCI registries with stored credentials.
+type ListRegistriesResponse struct {
+	Registries []string `json:"registries"`
+}
+
+// newRegistryClient returns a client for OCI registries, using the credentials stored in
+// the registry config file.
+func newRegistryClient(settings *cli.EnvSettings) (*registry.Client, error) {
+	return registry.NewClient(
+		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+		registry.ClientOptEnableCache(true),
+		registry.ClientOptWriter(io.Discard),
+	)
+}
+
+// registryHost returns the host of a registry given as a host or an oci:// URL.
+func registryHost(host string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(host, "oci://"), "/")
+}
+
+// RegistryLogin logs in to an OCI registry, storing its credentials.
+func (h *Handler) RegistryLogin(w http.ResponseWriter, r *http.Request) {
+	var req RegistryLoginRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for registry login")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	host := registryHost(req.Host)
+
+	// The credentials are stored in a file of the helm config dir, which may not exist yet.
+	err := os.MkdirAll(filepath.Dir(h.EnvSettings.RegistryConfig), defaultNewConfigFolderMode)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "creating registry config dir")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	client, err := newRegistryClient(h.EnvSettings)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "creating registry client")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	err = client.Login(host,
+		registry.LoginOptBasicAuth(req.Username, req.Password),
+		registry.LoginOptInsecure(req.Insecure),
+	)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"host": host}, err, "logging in to registry")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+
+		return
+	}
+
+	logger.Log(logger.LevelInfo, map[string]string{"host": host}, nil, "logged in to registry")
+
+	h.returnResponse(w, host, http.StatusOK, "login succeeded")
+}
+
+// RegistryLogout logs out of an OCI registry, removing its stored credentials.
+func (h *Handler) RegistryLogout(w http.ResponseWriter, r *http.Request) {
+	var req RegistryLogoutRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log(logger.LevelError, nil, err, "parsing request for registry logout")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	host := registryHost(req.Host)
+
+	client, err := newRegistryClient(h.EnvSettings)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "creating registry client")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if err := client.Logout(host); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"host": host}, err, "logging out of registry")
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	h.returnResponse(w, host, http.StatusOK, "logout succeeded")
+}
+
+// listRegistries returns the registries with credentials in the registry config file.
+func listRegistries(registryConfig string) ([]string, error) {
+	content, err := os.ReadFile(registryConfig)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+
+	if len(strings.TrimSpace(string(content))) > 0 {
+		if err := json.Unmarshal(content, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	registries := make([]string, 0, len(config.Auths))
+	for host := range config.Auths {
+		registries = append(registries, host)
+	}
+
+	sort.Strings(registries)
+
+	return registries, nil
+}
+
+// ListRegistries lists the OCI registries with stored credentials, without the credentials.
+func (h *Handler) ListRegistries(w http.ResponseWriter, r *http.Request) {
+	registries, err := listRegistries(h.EnvSettings.RegistryConfig)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "listing registries")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(ListRegistriesResponse{Registries: registries}); err != nil {
+		logger.Log(logger.LevelError, nil, err, "encoding response")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+func TestListRegistries(t *testing.T) {
+	registrySettings := cli.New()
+	registrySettings.RegistryConfig = filepath.Join(t.TempDir(), "registry", "config.json")
+
+	helmHandler := &helm.Handler{EnvSettings: registrySettings}
+
+	listRegistries := func() []string {
+		t.Helper()
+
+		rr := httptest.NewRecorder()
+		helmHandler.ListRegistries(rr, httptest.NewRequest(http.MethodGet, "/helm/registries", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response helm.ListRegistriesResponse
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+		return response.Registries
+	}
+
+	// No registry config file yet.
+	assert.Empty(t, listRegistries())
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(registrySettings.RegistryConfig), 0o700))
+	require.NoError(t, os.WriteFile(registrySettings.RegistryConfig, []byte(`{"auths": {
+		"registry.example.com": {"auth": "dXNlcjpwYXNz"},
+		"ghcr.io": {"auth": "dXNlcjpwYXNz"}
+	}}`), 0o600))
+
+	registries := listRegistries()
+	assert.Equal(t, []string{"ghcr.io", "registry.example.com"}, registries)
+}
+
+func TestRegistryLoginValidation(t *testing.T) {
+	registrySettings := cli.New()
+	registrySettings.RegistryConfig = filepath.Join(t.TempDir(), "registry", "config.json")
+
+	helmHandler := &helm.Handler{EnvSettings: registrySettings}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{`},
+		{name: "missing password", body: `{"host": "oci://ghcr.io", "username": "user"}`},
+		{name: "missing host", body: `{"username": "user", "password": "pass"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/helm/registries/login", strings.NewReader(tt.body))
+
+			helmHandler.RegistryLogin(rr, req)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	helmHandler.RegistryLogout(rr, httptest.NewRequest(http.MethodPost, "/helm/registries/logout",
+		strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
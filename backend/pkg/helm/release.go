@@ -21,6 +21,10 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/schema"
@@ -228,6 +232,50 @@ type GetReleaseHistoryRequest struct {
 
 type GetReleaseHistoryResponse struct {
 	Releases []*release.Release `json:"releases"`
+	// Revisions summarizes the releases, oldest first.
+	Revisions []ReleaseRevision `json:"revisions"`
+}
+
+// ReleaseRevision is a revision of a release, with what is needed to choose one to roll back to.
+type ReleaseRevision struct {
+	Revision     int                    `json:"revision"`
+	Status       string                 `json:"status"`
+	Chart        string                 `json:"chart"`
+	ChartVersion string                 `json:"chartVersion"`
+	AppVersion   string                 `json:"appVersion"`
+	Description  string                 `json:"description"`
+	Updated      time.Time              `json:"updated"`
+	Values       map[string]interface{} `json:"values"`
+}
+
+// releaseRevisions returns the revisions of the releases, sorted by revision.
+func releaseRevisions(releases []*release.Release) []ReleaseRevision {
+	revisions := make([]ReleaseRevision, 0, len(releases))
+
+	for _, rel := range releases {
+		revision := ReleaseRevision{
+			Revision: rel.Version,
+			Values:   rel.Config,
+		}
+
+		if rel.Info != nil {
+			revision.Status = rel.Info.Status.String()
+			revision.Description = rel.Info.Description
+			revision.Updated = rel.Info.LastDeployed.Time
+		}
+
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			revision.Chart = rel.Chart.Metadata.Name
+			revision.ChartVersion = rel.Chart.Metadata.Version
+			revision.AppVersion = rel.Chart.Metadata.AppVersion
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+
+	return revisions
 }
 
 func (h *Handler) GetReleaseHistory(w http.ResponseWriter, r *http.Request) {
@@ -245,9 +293,11 @@ func (h *Handler) GetReleaseHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// check if release exists
-	_, err = h.Configuration.Releases.Deployed(req.Name)
-	if err == driver.ErrReleaseNotFound {
+	getClient := action.NewHistory(h.Configuration)
+
+	// The history is read even when no revision is deployed, like after a failed upgrade.
+	result, err := getClient.Run(req.Name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
 		logger.Log(logger.LevelError, map[string]string{"releaseName": req.Name, "request": "get_release_history"},
 			err, "release not found")
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -255,9 +305,6 @@ func (h *Handler) GetReleaseHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	getClient := action.NewHistory(h.Configuration)
-
-	result, err := getClient.Run(req.Name)
 	if err != nil {
 		logger.Log(logger.LevelError, map[string]string{"request": "get_release_history", "releaseName": req.Name},
 			err, "getting release history")
@@ -267,7 +314,8 @@ func (h *Handler) GetReleaseHistory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := GetReleaseHistoryResponse{
-		Releases: result,
+		Releases:  result,
+		Revisions: releaseRevisions(result),
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -393,11 +441,11 @@ func (h *Handler) RollbackRelease(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// check if release exists
-	_, err = h.Configuration.Releases.Deployed(req.Name)
-	if err == driver.ErrReleaseNotFound {
-		logger.Log(logger.LevelError, map[string]string{"releaseName": req.Name},
-			err, "release not found")
+	// check if the revision to roll back to exists, the release may have no deployed revision
+	_, err = h.Configuration.Releases.Get(req.Name, req.Revision)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		logger.Log(logger.LevelError, map[string]string{"releaseName": req.Name, "revision": strconv.Itoa(req.Revision)},
+			err, "release revision not found")
 		http.Error(w, err.Error(), http.StatusNotFound)
 
 		return
@@ -532,6 +580,25 @@ func (h *Handler) getChart(
 	dependencyUpdate bool,
 	settings *cli.EnvSettings,
 ) (*chart.Chart, error) {
+	// charts of a repository, like repo/chart, are downloaded with its stored credentials
+	if repoName, _, ok := strings.Cut(reqChart, "/"); ok && !strings.Contains(reqChart, "://") {
+		creds, err := repositoryCredentials(settings, repoName)
+		if err != nil {
+			h.logActionState(zlog.Error(), err, actionName, reqChart, reqName, failed, "reading repository credentials")
+			return nil, err
+		}
+
+		if creds != nil {
+			cleanup, err := creds.applyToChartPathOptions(&chartPathOptions)
+			if err != nil {
+				h.logActionState(zlog.Error(), err, actionName, reqChart, reqName, failed, "writing repository TLS files")
+				return nil, err
+			}
+
+			defer cleanup()
+		}
+	}
+
 	// locate chart
 	chartPath, err := chartPathOptions.LocateChart(reqChart, settings)
 	if err != nil {
@@ -563,6 +630,7 @@ func (h *Handler) getChart(
 				Getters:          getter.All(settings),
 				RepositoryConfig: settings.RepositoryConfig,
 				RepositoryCache:  settings.RepositoryCache,
+				RegistryClient:   h.Configuration.RegistryClient,
 			}
 
 			err = manager.Update()
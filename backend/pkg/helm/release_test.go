@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -33,6 +34,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -251,3 +257,86 @@ func TestUninstallRelease(t *testing.T) {
 
 	pingStatusTillSuccess(t, "uninstall", "helm-test-asdf", helmHandler.Cache)
 }
+
+func newMemoryHelmHandler(t *testing.T, releases ...*release.Release) *helm.Handler {
+	t.Helper()
+
+	store := storage.Init(driver.NewMemory())
+
+	for _, rel := range releases {
+		require.NoError(t, store.Create(rel))
+	}
+
+	return &helm.Handler{
+		Configuration: &action.Configuration{
+			Releases:   store,
+			KubeClient: &kubefake.PrintingKubeClient{Out: io.Discard},
+			Log:        func(string, ...interface{}) {},
+		},
+		Cache: cache.New[interface{}](),
+	}
+}
+
+func testRelease(version int, status release.Status, chartVersion string) *release.Release {
+	return &release.Release{
+		Name:      "history-test",
+		Namespace: "default",
+		Version:   version,
+		Info:      &release.Info{Status: status, Description: "revision " + chartVersion},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "nginx", Version: chartVersion, AppVersion: "1.25"}},
+		Config:    map[string]interface{}{"replicas": float64(version)},
+	}
+}
+
+func TestGetReleaseHistoryRevisions(t *testing.T) {
+	// The last revision failed, so no revision is deployed.
+	helmHandler := newMemoryHelmHandler(t,
+		testRelease(2, release.StatusSuperseded, "1.1.0"),
+		testRelease(1, release.StatusSuperseded, "1.0.0"),
+		testRelease(3, release.StatusFailed, "1.2.0"),
+	)
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET",
+		"/clusters/minikube/helm/release/history?name=history-test&namespace=default", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	helmHandler.GetReleaseHistory(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp helm.GetReleaseHistoryResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Revisions, 3)
+
+	for i, revision := range resp.Revisions {
+		assert.Equal(t, i+1, revision.Revision)
+		assert.Equal(t, "nginx", revision.Chart)
+		assert.Equal(t, float64(i+1), revision.Values["replicas"])
+	}
+
+	assert.Equal(t, "1.2.0", resp.Revisions[2].ChartVersion)
+	assert.Equal(t, "failed", resp.Revisions[2].Status)
+
+	req, err = http.NewRequestWithContext(context.Background(), "GET",
+		"/clusters/minikube/helm/release/history?name=unknown&namespace=default", nil)
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	helmHandler.GetReleaseHistory(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestRollbackReleaseUnknownRevision(t *testing.T) {
+	helmHandler := newMemoryHelmHandler(t, testRelease(1, release.StatusDeployed, "1.0.0"))
+
+	body, err := json.Marshal(helm.RollbackReleaseRequest{Name: "history-test", Namespace: "default", Revision: 5})
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(context.Background(), "PUT",
+		"/clusters/minikube/helm/releases/rollback", bytes.NewBuffer(body))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	helmHandler.RollbackRelease(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
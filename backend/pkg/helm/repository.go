@@ -29,7 +29,6 @@ import (
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 
 	"helm.sh/helm/v3/pkg/cli"
-	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
@@ -42,9 +41,8 @@ const (
 type AddUpdateRepoRequest struct {
 	Name string `json:"name"`
 	URL  string `json:"url"`
-	// TODO: Figure out how to support auth
-	// like username, password, certfile etc
-	// https://github.com/helm/helm/blob/39ca699ca790e02ba36753dec6ba4177cc68d417/cmd/helm/repo_add.go#L169
+	// Credentials are stored encrypted. When updating, nil keeps the stored ones.
+	Credentials *RepositoryCredentials `json:"credentials,omitempty"`
 }
 
 // Creates a filename if it's not there, including any missing directories.
@@ -81,7 +79,7 @@ func lockRepositoryFile(lockCtx context.Context, repositoryConfig string) (bool,
 const timeoutForLock = 30 * time.Second
 
 // Adds a repository with name, url to the helm config. Returns error if there is one.
-func addRepository(name string, url string, settings *cli.EnvSettings) error {
+func addRepository(name string, url string, creds *RepositoryCredentials, settings *cli.EnvSettings) error {
 	err := createFileIfNotThere(settings.RepositoryConfig)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "creating empty RepositoryConfig file")
@@ -113,20 +111,20 @@ func addRepository(name string, url string, settings *cli.EnvSettings) error {
 		return err
 	}
 
-	// add repo
+	// add repo, its credentials are stored apart from the repo file
 	newRepo := &repo.Entry{
 		Name: name,
 		URL:  url,
 	}
 
-	repo, err := repo.NewChartRepository(newRepo, getter.All(settings))
+	err = setCredentials(settings, name, creds)
 	if err != nil {
-		logger.Log(logger.LevelError, nil, err, "creating chart repository")
+		logger.Log(logger.LevelError, nil, err, "storing repository credentials")
 		return err
 	}
 
 	// download chart repo index
-	_, err = repo.DownloadIndexFile()
+	err = downloadIndex(newRepo, settings)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "downloading index file")
 		return err
@@ -156,7 +154,7 @@ func (h *Handler) AddRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = addRepository(request.Name, request.URL, h.EnvSettings)
+	err = addRepository(request.Name, request.URL, request.Credentials, h.EnvSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -181,8 +179,9 @@ func (h *Handler) AddRepo(w http.ResponseWriter, r *http.Request) {
 
 // List repository.
 type repositoryInfo struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	HasCredentials bool   `json:"hasCredentials"`
 }
 type ListRepoResponse struct {
 	Repositories []repositoryInfo `json:"repositories"`
@@ -211,13 +210,22 @@ func listRepositories(settings *cli.EnvSettings) ([]repositoryInfo, error) {
 		return nil, err
 	}
 
+	credentials, err := loadCredentials(settings)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "reading repository credentials")
+		return nil, err
+	}
+
 	// response
 	repositories := make([]repositoryInfo, 0, len(repoFile.Repositories))
 
 	for _, repo := range repoFile.Repositories {
+		_, hasCredentials := credentials[repo.Name]
+
 		repositories = append(repositories, repositoryInfo{
-			Name: repo.Name,
-			URL:  repo.URL,
+			Name:           repo.Name,
+			URL:            repo.URL,
+			HasCredentials: hasCredentials,
 		})
 	}
 
@@ -286,6 +294,12 @@ func RemoveRepository(name string, settings *cli.EnvSettings) error {
 		return err
 	}
 
+	err = setCredentials(settings, name, nil)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "removing repository credentials")
+		return err
+	}
+
 	return nil
 }
 
@@ -302,7 +316,7 @@ func (h *Handler) RemoveRepo(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func UpdateRepository(name, url string, settings *cli.EnvSettings) error {
+func UpdateRepository(name, url string, creds *RepositoryCredentials, settings *cli.EnvSettings) error {
 	err := createFileIfNotThere(settings.RepositoryConfig)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "creating empty RepositoryConfig file")
@@ -334,6 +348,14 @@ func UpdateRepository(name, url string, settings *cli.EnvSettings) error {
 		URL:  url,
 	})
 
+	if creds != nil {
+		err = setCredentials(settings, name, creds)
+		if err != nil {
+			logger.Log(logger.LevelError, nil, err, "storing repository credentials")
+			return err
+		}
+	}
+
 	err = repoFile.WriteFile(settings.RepositoryConfig, defaultNewConfigFileMode)
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "writing repo file")
@@ -356,7 +378,7 @@ func (h *Handler) UpdateRepository(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = UpdateRepository(request.Name, request.URL, h.EnvSettings)
+	err = UpdateRepository(request.Name, request.URL, request.Credentials, h.EnvSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -100,6 +100,17 @@ func (m *MockCache) UpdateTTL(ctx context.Context, key string, ttl time.Duration
 	return nil
 }
 
+// Stats Mocks the statistics of the cache.
+func (m *MockCache) Stats() cache.Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return cache.Stats{Entries: len(m.store)}
+}
+
+// Close Mocks stopping the cache.
+func (m *MockCache) Close() {}
+
 // TestGetResponseBody checks that the response body is correctly decoded
 // based on the content encoding (e.g., gzip).
 func TestGetResponseBody(t *testing.T) {
@@ -0,0 +1,272 @@
+// Copyright 2025 The Kubernetes Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	watchCache "k8s.io/client-go/tools/cache"
+)
+
+const (
+	// DiscoveryTTL is how long a discovery document is cached while the API extensions
+	// of its context are watched.
+	DiscoveryTTL = 10 * time.Minute
+	// DiscoveryUnwatchedTTL is how long a discovery document is cached when the API
+	// extensions of its context can't be watched, e.g. when the user can't list CRDs.
+	DiscoveryUnwatchedTTL = 30 * time.Second
+
+	discoveryKeyPrefix = "discovery+"
+)
+
+// discoveryPathRegex matches the discovery endpoints: the API versions, the API groups
+// and the resources of an API group version.
+var discoveryPathRegex = regexp.MustCompile(`^/(api|api/[^/]+|apis|apis/[^/]+/[^/]+)/?$`)
+
+// discoveryWatchedResources are the resources whose changes change the served APIs.
+var discoveryWatchedResources = []schema.GroupVersionResource{
+	{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+	{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"},
+}
+
+// IsDiscoveryPath tells whether the given API path, without the /clusters/{cluster} prefix,
+// is a discovery endpoint.
+func IsDiscoveryPath(apiPath string) bool {
+	return discoveryPathRegex.MatchString("/" + strings.TrimPrefix(apiPath, "/"))
+}
+
+// DynamicClientFunc returns a dynamic client for the context, authenticated with
+// the token if it's not empty.
+type DynamicClientFunc func(kContext *kubeconfig.Context, token string) (dynamic.Interface, error)
+
+// DiscoveryCache caches the discovery documents of the contexts, so the clients don't
+// fetch them from the clusters every time. The cached documents of a context are dropped
+// when its CustomResourceDefinitions or APIServices change, so new APIs show up right away.
+type DiscoveryCache struct {
+	cache     cache.Cache[string]
+	newClient DynamicClientFunc
+	// watchers holds the *discoveryWatcher of each context key.
+	watchers sync.Map
+}
+
+type discoveryWatcher struct {
+	cancel context.CancelFunc
+	synced func() bool
+}
+
+// NewDiscoveryCache returns a DiscoveryCache storing the documents in the given cache.
+// If newClient is nil, the clients are created from the REST config of the contexts.
+func NewDiscoveryCache(c cache.Cache[string], newClient DynamicClientFunc) *DiscoveryCache {
+	if newClient == nil {
+		newClient = defaultDynamicClient
+	}
+
+	return &DiscoveryCache{cache: c, newClient: newClient}
+}
+
+func defaultDynamicClient(kContext *kubeconfig.Context, token string) (dynamic.Interface, error) {
+	restConf, err := kContext.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		restConf.BearerToken = token
+	}
+
+	return dynamic.NewForConfig(restConf)
+}
+
+// Serve writes the cached discovery document of the request if there is one, otherwise it
+// calls next and caches its successful response. The apiPath is the path of the request
+// without the /clusters/{cluster} prefix.
+func (d *DiscoveryCache) Serve(w http.ResponseWriter, r *http.Request, next http.Handler,
+	contextKey string, kContext *kubeconfig.Context, apiPath, token string,
+) {
+	key := discoveryKey(contextKey, token, apiPath, r.Header.Get("Accept"))
+
+	served, err := LoadFromCache(d.cache, true, key, w, r)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"key": key}, err,
+			"loading discovery document from cache")
+	}
+
+	if served {
+		return
+	}
+
+	watched := d.watch(contextKey, kContext, token)
+
+	rcw := NewResponseCapture(w)
+	next.ServeHTTP(rcw, r)
+
+	// The response of a cancelled request may be cut short.
+	if rcw.StatusCode != http.StatusOK || r.Context().Err() != nil {
+		return
+	}
+
+	ttl := DiscoveryUnwatchedTTL
+	if watched() {
+		ttl = DiscoveryTTL
+	}
+
+	if err := d.store(key, rcw, ttl); err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"key": key}, err,
+			"storing discovery document in cache")
+	}
+}
+
+func (d *DiscoveryCache) store(key string, rcw *ResponseCapture, ttl time.Duration) error {
+	encoding := rcw.Header().Get("Content-Encoding")
+
+	body, err := GetResponseBody(rcw.Body.Bytes(), encoding)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(CachedResponseData{
+		StatusCode: rcw.StatusCode,
+		Headers:    FilterHeaderForCache(rcw.Header(), encoding),
+		Body:       body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.cache.SetWithTTL(context.Background(), key, string(data), ttl)
+}
+
+// Invalidate drops the cached discovery documents of the context.
+func (d *DiscoveryCache) Invalidate(contextKey string) {
+	prefix := discoveryKeyPrefix + contextKey + "+"
+
+	keys, err := d.cache.GetAll(context.Background(), func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"context": contextKey}, err,
+			"listing cached discovery documents")
+
+		return
+	}
+
+	for key := range keys {
+		_ = d.cache.Delete(context.Background(), key)
+	}
+}
+
+// Stop stops watching the API extensions of the context and drops its cached documents.
+func (d *DiscoveryCache) Stop(contextKey string) {
+	if w, ok := d.watchers.LoadAndDelete(contextKey); ok {
+		w.(*discoveryWatcher).cancel()
+	}
+
+	d.Invalidate(contextKey)
+}
+
+// Stats returns the statistics of the cache the documents are stored in. It's shared with
+// the cached responses of the clusters, so they are counted too.
+func (d *DiscoveryCache) Stats() cache.Stats {
+	return d.cache.Stats()
+}
+
+// watch starts watching the API extensions of the context if it's not watched yet, and
+// returns a function telling whether the watch is established.
+func (d *DiscoveryCache) watch(contextKey string, kContext *kubeconfig.Context, token string) func() bool {
+	if w, ok := d.watchers.Load(contextKey); ok {
+		return w.(*discoveryWatcher).synced
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher := &discoveryWatcher{cancel: cancel, synced: func() bool { return false }}
+
+	if w, loaded := d.watchers.LoadOrStore(contextKey, watcher); loaded {
+		cancel()
+
+		return w.(*discoveryWatcher).synced
+	}
+
+	client, err := d.newClient(kContext, token)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"context": contextKey}, err,
+			"creating client to watch API extensions")
+
+		return watcher.synced
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	handler := watchCache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(_ interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				d.Invalidate(contextKey)
+			}
+		},
+		UpdateFunc: func(_, _ interface{}) { d.Invalidate(contextKey) },
+		DeleteFunc: func(_ interface{}) { d.Invalidate(contextKey) },
+	}
+
+	registrations := make([]watchCache.ResourceEventHandlerRegistration, 0, len(discoveryWatchedResources))
+
+	for _, gvr := range discoveryWatchedResources {
+		registration, err := factory.ForResource(gvr).Informer().AddEventHandler(handler)
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"context": contextKey, "resource": gvr.String()}, err,
+				"watching API extensions")
+
+			return watcher.synced
+		}
+
+		registrations = append(registrations, registration)
+	}
+
+	factory.Start(ctx.Done())
+
+	synced := func() bool {
+		for _, registration := range registrations {
+			if !registration.HasSynced() {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	d.watchers.Store(contextKey, &discoveryWatcher{cancel: cancel, synced: synced})
+
+	return synced
+}
+
+// discoveryKey returns the cache key of a discovery document. The documents are cached
+// per token as the discovery endpoints are subject to authorization, and per Accept
+// header as the aggregated discovery documents are negotiated through it.
+func discoveryKey(contextKey, token, apiPath, accept string) string {
+	tokenHash := sha256.Sum256([]byte(token))
+
+	return discoveryKeyPrefix + contextKey + "+" + hex.EncodeToString(tokenHash[:8]) + "+" +
+		"/" + strings.Trim(apiPath, "/") + "+" + accept
+}
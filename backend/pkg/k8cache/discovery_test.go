@@ -0,0 +1,177 @@
+// Copyright 2025 The Kubernetes Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8cache_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/k8cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestIsDiscoveryPath(t *testing.T) {
+	tests := map[string]bool{
+		"/api":                          true,
+		"api/v1":                        true,
+		"/apis":                         true,
+		"/apis/apps/v1":                 true,
+		"/apis/apps/v1/":                true,
+		"/api/v1/pods":                  false,
+		"/apis/apps/v1/deployments":     false,
+		"/apis/apps":                    false,
+		"/api/v1/namespaces/default":    false,
+		"/version":                      false,
+		"/openapi/v3/apis/apps/v1":      false,
+		"/apis/metrics.k8s.io/v1beta1/": true,
+	}
+
+	for path, want := range tests {
+		assert.Equal(t, want, k8cache.IsDiscoveryPath(path), path)
+	}
+}
+
+var crdGVR = schema.GroupVersionResource{
+	Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions",
+}
+
+func newFakeDynamicClient() *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			crdGVR: "CustomResourceDefinitionList",
+			{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}: "APIServiceList",
+		})
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	client := newFakeDynamicClient()
+	discoveryCache := k8cache.NewDiscoveryCache(cache.New[string](),
+		func(*kubeconfig.Context, string) (dynamic.Interface, error) { return client, nil })
+
+	defer discoveryCache.Stop("minikube")
+
+	var calls atomic.Int32
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"APIGroupList"}`))
+	})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/apis", nil)
+		rr := httptest.NewRecorder()
+		discoveryCache.Serve(rr, req, next, "minikube", &kubeconfig.Context{}, "/apis", token)
+
+		return rr
+	}
+
+	rr := serve("token")
+	assert.Equal(t, `{"kind":"APIGroupList"}`, rr.Body.String())
+	assert.Empty(t, rr.Header().Get("X-HEADLAMP-CACHE"))
+
+	rr = serve("token")
+	assert.Equal(t, `{"kind":"APIGroupList"}`, rr.Body.String())
+	assert.Equal(t, "true", rr.Header().Get("X-HEADLAMP-CACHE"))
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Documents aren't shared between tokens.
+	serve("other-token")
+	assert.Equal(t, int32(2), calls.Load())
+
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName("widgets.example.com")
+
+	// The watch may not be established right after the first request.
+	require.Eventually(t, func() bool {
+		_, err := client.Resource(crdGVR).Create(context.Background(), crd, metav1.CreateOptions{})
+		if err != nil {
+			_ = client.Resource(crdGVR).Delete(context.Background(), crd.GetName(), metav1.DeleteOptions{})
+		}
+
+		return serve("token").Header().Get("X-HEADLAMP-CACHE") == ""
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestDiscoveryCacheSkipsFailures(t *testing.T) {
+	discoveryCache := k8cache.NewDiscoveryCache(cache.New[string](),
+		func(*kubeconfig.Context, string) (dynamic.Interface, error) { return newFakeDynamicClient(), nil })
+
+	defer discoveryCache.Stop("minikube")
+
+	var calls atomic.Int32
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/apis/metrics.k8s.io/v1beta1", nil)
+		rr := httptest.NewRecorder()
+		discoveryCache.Serve(rr, req, next, "minikube", &kubeconfig.Context{}, "/apis/metrics.k8s.io/v1beta1", "")
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	}
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDiscoveryCacheSkipsCancelledRequests(t *testing.T) {
+	discoveryCache := k8cache.NewDiscoveryCache(cache.New[string](),
+		func(*kubeconfig.Context, string) (dynamic.Interface, error) { return newFakeDynamicClient(), nil })
+
+	defer discoveryCache.Stop("minikube")
+
+	var calls atomic.Int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The client goes away while the document is being proxied, which is cut short.
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"kind":"APIResourceList","resources":[`))
+
+		cancel()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/apis/metrics.k8s.io/v1beta1", nil)
+	discoveryCache.Serve(httptest.NewRecorder(), req.WithContext(ctx), next, "minikube", &kubeconfig.Context{},
+		"/apis/metrics.k8s.io/v1beta1", "")
+
+	next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"kind":"APIResourceList","resources":[]}`))
+	})
+
+	rr := httptest.NewRecorder()
+	discoveryCache.Serve(rr, req, next, "minikube", &kubeconfig.Context{}, "/apis/metrics.k8s.io/v1beta1", "")
+	assert.Equal(t, int32(2), calls.Load())
+	assert.JSONEq(t, `{"kind":"APIResourceList","resources":[]}`, rr.Body.String())
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 The Kubernetes Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8cache
+
+import (
+	"container/list"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// openAPIHeaders are the headers of the OpenAPI responses that are cached with them.
+var openAPIHeaders = []string{"Content-Type", "Cache-Control", "Expires", "Last-Modified"}
+
+// IsOpenAPIPath tells whether the given API path, without the /clusters/{cluster} prefix,
+// is an OpenAPI v3 endpoint.
+func IsOpenAPIPath(apiPath string) bool {
+	apiPath = strings.TrimPrefix(apiPath, "/")
+
+	return apiPath == "openapi/v3" || strings.HasPrefix(apiPath, "openapi/v3/")
+}
+
+// OpenAPICache caches the OpenAPI v3 schemas of the contexts, which are several megabytes
+// each. The cached schemas are revalidated with their ETag on every request, so the clusters
+// still authorize each request but only send the schemas again when they change. The least
+// recently used schemas are dropped when the cache grows over its maximum size.
+type OpenAPICache struct {
+	maxSize int
+
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// lru holds the *openAPIEntry values, the most recently used first.
+	lru *list.List
+}
+
+type openAPIEntry struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// NewOpenAPICache returns an OpenAPICache holding up to maxSize bytes of schemas.
+func NewOpenAPICache(maxSize int) *OpenAPICache {
+	return &OpenAPICache{
+		maxSize: maxSize,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Serve serves the OpenAPI request through next, revalidating the cached schema of the
+// request if there is one, and caches the schema next returns. The apiPath is the path of
+// the request without the /clusters/{cluster} prefix.
+func (c *OpenAPICache) Serve(w http.ResponseWriter, r *http.Request, next http.Handler, contextKey, apiPath string) {
+	key := contextKey + "+/" + strings.TrimPrefix(apiPath, "/") + "?" + r.URL.RawQuery
+	cached := c.get(key)
+
+	upstream := r.Clone(r.Context())
+	// The transport asks for and decompresses gzip itself when the request doesn't ask for an
+	// encoding, so the schemas are cached decompressed but still transferred compressed.
+	upstream.Header.Del("Accept-Encoding")
+	upstream.Header.Del("If-None-Match")
+
+	if cached != nil {
+		upstream.Header.Set("If-None-Match", cached.etag)
+	}
+
+	rr := httptest.NewRecorder()
+	next.ServeHTTP(rr, upstream)
+
+	// The client of a cancelled request is gone, and its response may be cut short.
+	if r.Context().Err() != nil {
+		return
+	}
+
+	switch {
+	case rr.Code == http.StatusNotModified && cached != nil:
+		w.Header().Set("X-HEADLAMP-CACHE", "true")
+		writeOpenAPIEntry(w, r, cached)
+	case rr.Code == http.StatusOK && rr.Header().Get("ETag") != "":
+		entry := &openAPIEntry{
+			key:    key,
+			etag:   rr.Header().Get("ETag"),
+			header: make(http.Header),
+			body:   rr.Body.Bytes(),
+		}
+
+		for _, name := range openAPIHeaders {
+			if value := rr.Header().Get(name); value != "" {
+				entry.header.Set(name, value)
+			}
+		}
+
+		c.put(entry)
+		writeOpenAPIEntry(w, r, entry)
+	default:
+		for name, values := range rr.Header() {
+			w.Header()[name] = values
+		}
+
+		w.WriteHeader(rr.Code)
+		_, _ = w.Write(rr.Body.Bytes())
+	}
+}
+
+// writeOpenAPIEntry writes the cached schema, or that it's not modified if the client
+// already has it.
+func writeOpenAPIEntry(w http.ResponseWriter, r *http.Request, entry *openAPIEntry) {
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set("ETag", entry.etag)
+
+	if r.Header.Get("If-None-Match") == entry.etag {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(entry.body)
+}
+
+func (c *OpenAPICache) get(key string) *openAPIEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.lru.MoveToFront(element)
+
+	entry, _ := element.Value.(*openAPIEntry)
+
+	return entry
+}
+
+func (c *OpenAPICache) put(entry *openAPIEntry) {
+	if len(entry.body) > c.maxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element)
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += len(entry.body)
+
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *OpenAPICache) remove(element *list.Element) {
+	entry, _ := c.lru.Remove(element).(*openAPIEntry)
+	delete(c.entries, entry.key)
+	c.size -= len(entry.body)
+}
+
+// Size returns the number of bytes of schemas in the cache.
+func (c *OpenAPICache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
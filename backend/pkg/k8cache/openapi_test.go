@@ -0,0 +1,152 @@
+// Copyright 2025 The Kubernetes Authors.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8cache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/k8cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsOpenAPIPath(t *testing.T) {
+	tests := map[string]bool{
+		"openapi/v3":                    true,
+		"/openapi/v3/apis/apps/v1":      true,
+		"openapi/v3/api/v1":             true,
+		"openapi/v2":                    false,
+		"openapi/v3x":                   false,
+		"/apis/apps/v1":                 false,
+		"/api/v1/namespaces/openapi/v3": false,
+	}
+
+	for path, want := range tests {
+		assert.Equal(t, want, k8cache.IsOpenAPIPath(path), path)
+	}
+}
+
+// fakeOpenAPIServer serves the schema with the etag, and records the requests it got.
+type fakeOpenAPIServer struct {
+	etag     string
+	schema   string
+	requests []*http.Request
+}
+
+func (s *fakeOpenAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, r)
+
+	if r.Header.Get("Authorization") == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	w.Header().Set("ETag", s.etag)
+
+	if r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(s.schema))
+}
+
+func serveOpenAPI(c *k8cache.OpenAPICache, next http.Handler, token, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/openapi/v3/apis/apps/v1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	rr := httptest.NewRecorder()
+	c.Serve(rr, req, next, "minikube", "openapi/v3/apis/apps/v1")
+
+	return rr
+}
+
+func TestOpenAPICache(t *testing.T) {
+	server := &fakeOpenAPIServer{etag: `"v1"`, schema: `{"components":{}}`}
+	openAPICache := k8cache.NewOpenAPICache(1 << 20)
+
+	rr := serveOpenAPI(openAPICache, server, "token", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"components":{}}`, rr.Body.String())
+	assert.Equal(t, `"v1"`, rr.Header().Get("ETag"))
+	assert.Empty(t, server.requests[0].Header.Get("Accept-Encoding"))
+	assert.Empty(t, server.requests[0].Header.Get("If-None-Match"))
+
+	// The cached schema is revalidated rather than downloaded again.
+	rr = serveOpenAPI(openAPICache, server, "token", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"components":{}}`, rr.Body.String())
+	assert.Equal(t, "true", rr.Header().Get("X-HEADLAMP-CACHE"))
+	assert.Equal(t, `"v1"`, server.requests[1].Header.Get("If-None-Match"))
+
+	// Clients having the schema are told it's not modified.
+	rr = serveOpenAPI(openAPICache, server, "token", `"v1"`)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	// The cluster still authorizes the requests.
+	rr = serveOpenAPI(openAPICache, server, "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	server.etag = `"v2"`
+	server.schema = `{"components":{"schemas":{}}}`
+
+	rr = serveOpenAPI(openAPICache, server, "token", `"v1"`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"components":{"schemas":{}}}`, rr.Body.String())
+	assert.Equal(t, `"v2"`, rr.Header().Get("ETag"))
+	assert.Equal(t, len(`{"components":{"schemas":{}}}`), openAPICache.Size())
+}
+
+func TestOpenAPICacheEviction(t *testing.T) {
+	openAPICache := k8cache.NewOpenAPICache(10)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		_, _ = w.Write([]byte(strings.Repeat("x", 4)))
+	})
+
+	for _, group := range []string{"apps", "batch", "policy"} {
+		req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/openapi/v3/apis/"+group+"/v1", nil)
+		openAPICache.Serve(httptest.NewRecorder(), req, next, "minikube", "openapi/v3/apis/"+group+"/v1")
+	}
+
+	assert.Equal(t, 8, openAPICache.Size())
+
+	// Schemas bigger than the cache aren't cached.
+	big := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"big"`)
+		_, _ = w.Write([]byte(strings.Repeat("x", 11)))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/clusters/minikube/openapi/v3/api/v1", nil)
+	rr := httptest.NewRecorder()
+	openAPICache.Serve(rr, req, big, "minikube", "openapi/v3/api/v1")
+	assert.Equal(t, 11, rr.Body.Len())
+	assert.Equal(t, 8, openAPICache.Size())
+}
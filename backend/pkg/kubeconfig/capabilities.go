@@ -0,0 +1,294 @@
+package kubeconfig
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	// CapabilitiesTTL is how long the detected capabilities of a context are used
+	// before they are detected again.
+	CapabilitiesTTL = 10 * time.Minute
+	// capabilitiesRetryTTL is how long a failed detection is kept before retrying it.
+	capabilitiesRetryTTL = time.Minute
+	// capabilitiesTimeout bounds a detection, so unreachable clusters don't pile up goroutines.
+	capabilitiesTimeout = 30 * time.Second
+)
+
+// Platforms a cluster can be detected to run on.
+const (
+	PlatformEKS       = "eks"
+	PlatformGKE       = "gke"
+	PlatformAKS       = "aks"
+	PlatformK3s       = "k3s"
+	PlatformOpenShift = "openshift"
+)
+
+// Capabilities are the version, platform and notable APIs of a cluster, so the clients
+// can adapt their features to it.
+type Capabilities struct {
+	// Version is the git version of the API server, e.g. v1.30.2+k3s1.
+	Version string `json:"version,omitempty"`
+	// Platform is the Kubernetes distribution of the cluster, empty if unknown.
+	Platform string `json:"platform,omitempty"`
+	// MetricsServer tells whether the metrics.k8s.io API is served.
+	MetricsServer bool `json:"metricsServer"`
+	// NetworkingAPIs are the served versions of the networking.k8s.io API groups,
+	// such as the Gateway API, by group.
+	NetworkingAPIs map[string][]string `json:"networkingAPIs,omitempty"`
+	// Namespaced tells whether the resources served by the cluster are namespaced. It's used
+	// by the backend, and not sent to the clients.
+	Namespaced map[schema.GroupVersionResource]bool `json:"-"`
+	// DetectedAt is when the capabilities were detected.
+	DetectedAt time.Time `json:"detectedAt"`
+	// Error is why the capabilities couldn't be detected, if they couldn't.
+	Error string `json:"error,omitempty"`
+}
+
+// DetectCapabilities detects the capabilities of the cluster behind the client. The server
+// is the URL of the API server, which tells some managed platforms apart.
+func DetectCapabilities(client discovery.DiscoveryInterface, server string) (*Capabilities, error) {
+	version, err := client.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities := &Capabilities{
+		Version:    version.GitVersion,
+		DetectedAt: time.Now(),
+	}
+
+	var openShift bool
+
+	for _, group := range groups.Groups {
+		switch {
+		case group.Name == "metrics.k8s.io":
+			capabilities.MetricsServer = true
+		case group.Name == "config.openshift.io":
+			openShift = true
+		case group.Name == "networking.k8s.io" || strings.HasSuffix(group.Name, ".networking.k8s.io"):
+			if capabilities.NetworkingAPIs == nil {
+				capabilities.NetworkingAPIs = map[string][]string{}
+			}
+
+			for _, version := range group.Versions {
+				capabilities.NetworkingAPIs[group.Name] = append(capabilities.NetworkingAPIs[group.Name], version.Version)
+			}
+
+			sort.Strings(capabilities.NetworkingAPIs[group.Name])
+		}
+	}
+
+	capabilities.Platform = detectPlatform(version.GitVersion, server, openShift)
+	capabilities.Namespaced = detectNamespaced(client)
+
+	return capabilities, nil
+}
+
+// detectNamespaced tells whether the resources served by the cluster behind the client are
+// namespaced. The resources of the API groups whose discovery failed are left out.
+func detectNamespaced(client discovery.DiscoveryInterface) map[schema.GroupVersionResource]bool {
+	_, resourceLists, err := client.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		logger.Log(logger.LevelWarn, nil, err, "discovering cluster resources")
+
+		return nil
+	}
+
+	var namespaced map[schema.GroupVersionResource]bool
+
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, resource := range resourceList.APIResources {
+			if namespaced == nil {
+				namespaced = map[schema.GroupVersionResource]bool{}
+			}
+
+			// The subresources, e.g. pods/log, are namespaced like their resource.
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+
+			namespaced[gv.WithResource(resource.Name)] = resource.Namespaced
+		}
+	}
+
+	return namespaced
+}
+
+// IsNamespaced tells whether the resource is namespaced, and whether it is known to the
+// capabilities.
+func (c *Capabilities) IsNamespaced(resource schema.GroupVersionResource) (namespaced bool, known bool) {
+	namespaced, known = c.Namespaced[resource]
+
+	return namespaced, known
+}
+
+// detectPlatform tells the platform of a cluster from the version and URL of its API server.
+func detectPlatform(gitVersion, server string, openShift bool) string {
+	var host string
+	if u, err := url.Parse(server); err == nil {
+		host = u.Hostname()
+	}
+
+	switch {
+	case openShift:
+		return PlatformOpenShift
+	case strings.Contains(gitVersion, "-eks-") || strings.HasSuffix(host, ".eks.amazonaws.com"):
+		return PlatformEKS
+	case strings.Contains(gitVersion, "-gke."):
+		return PlatformGKE
+	case strings.HasSuffix(host, ".azmk8s.io"):
+		return PlatformAKS
+	case strings.Contains(gitVersion, "+k3s"):
+		return PlatformK3s
+	}
+
+	return ""
+}
+
+// DetectFunc detects the capabilities of the cluster of a context.
+type DetectFunc func(ctx context.Context, kContext *Context) (*Capabilities, error)
+
+// CapabilityCache caches the capabilities of the contexts. They are detected in the
+// background the first time they are asked for and again once they expire, so getting
+// them never waits on the cluster.
+type CapabilityCache struct {
+	detect DetectFunc
+
+	mu       sync.Mutex
+	entries  map[string]*Capabilities
+	inFlight map[string]bool
+	// revision is bumped every time capabilities are stored or dropped. It's guarded by mu.
+	revision uint64
+}
+
+// NewCapabilityCache returns a CapabilityCache detecting the capabilities with detect,
+// or with the credentials of the contexts if detect is nil.
+func NewCapabilityCache(detect DetectFunc) *CapabilityCache {
+	if detect == nil {
+		detect = detectContextCapabilities
+	}
+
+	return &CapabilityCache{
+		detect:   detect,
+		entries:  map[string]*Capabilities{},
+		inFlight: map[string]bool{},
+	}
+}
+
+func detectContextCapabilities(_ context.Context, kContext *Context) (*Capabilities, error) {
+	restConf, err := kContext.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	restConf.Timeout = capabilitiesTimeout
+
+	client, err := discovery.NewDiscoveryClientForConfig(restConf)
+	if err != nil {
+		return nil, err
+	}
+
+	return DetectCapabilities(client, kContext.Cluster.Server)
+}
+
+// Get returns the cached capabilities of the context, nil if they weren't detected yet.
+// It starts detecting them if they are missing or expired.
+func (c *CapabilityCache) Get(kContext *Context) *Capabilities {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	capabilities := c.entries[kContext.Name]
+	if !c.expired(capabilities) || c.inFlight[kContext.Name] {
+		return capabilities
+	}
+
+	c.inFlight[kContext.Name] = true
+
+	go c.refresh(kContext)
+
+	return capabilities
+}
+
+// Detect detects the capabilities of the context in the background, e.g. when it's added.
+func (c *CapabilityCache) Detect(kContext *Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[kContext.Name] {
+		return
+	}
+
+	c.inFlight[kContext.Name] = true
+
+	go c.refresh(kContext)
+}
+
+// Forget drops the capabilities of the context, e.g. when it's removed.
+func (c *CapabilityCache) Forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, name)
+	c.revision++
+}
+
+func (c *CapabilityCache) expired(capabilities *Capabilities) bool {
+	if capabilities == nil {
+		return true
+	}
+
+	ttl := CapabilitiesTTL
+	if capabilities.Error != "" {
+		ttl = capabilitiesRetryTTL
+	}
+
+	return time.Since(capabilities.DetectedAt) > ttl
+}
+
+func (c *CapabilityCache) refresh(kContext *Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), capabilitiesTimeout)
+	defer cancel()
+
+	capabilities, err := c.detect(ctx, kContext)
+	if err != nil {
+		logger.Log(logger.LevelWarn, map[string]string{"context": kContext.Name}, err,
+			"detecting cluster capabilities")
+
+		capabilities = &Capabilities{DetectedAt: time.Now(), Error: err.Error()}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[kContext.Name] = capabilities
+	c.revision++
+	delete(c.inFlight, kContext.Name)
+}
+
+// Revision returns the revision of the cached capabilities, which changes every time they
+// are detected again or dropped.
+func (c *CapabilityCache) Revision() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.revision
+}
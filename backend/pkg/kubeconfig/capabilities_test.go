@@ -0,0 +1,192 @@
+package kubeconfig_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newFakeDiscovery(gitVersion string, groupVersions ...string) *fakediscovery.FakeDiscovery {
+	resources := make([]*metav1.APIResourceList, 0, len(groupVersions))
+	for _, gv := range groupVersions {
+		resources = append(resources, &metav1.APIResourceList{GroupVersion: gv})
+	}
+
+	return &fakediscovery.FakeDiscovery{
+		Fake:               &k8stesting.Fake{Resources: resources},
+		FakedServerVersion: &version.Info{GitVersion: gitVersion},
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	tests := []struct {
+		name       string
+		gitVersion string
+		server     string
+		groups     []string
+		want       kubeconfig.Capabilities
+	}{
+		{
+			name:       "k3s",
+			gitVersion: "v1.30.2+k3s1",
+			server:     "https://127.0.0.1:6443",
+			groups:     []string{"v1", "metrics.k8s.io/v1beta1", "networking.k8s.io/v1"},
+			want: kubeconfig.Capabilities{
+				Version:        "v1.30.2+k3s1",
+				Platform:       kubeconfig.PlatformK3s,
+				MetricsServer:  true,
+				NetworkingAPIs: map[string][]string{"networking.k8s.io": {"v1"}},
+			},
+		},
+		{
+			name:       "eks",
+			gitVersion: "v1.29.6-eks-db838b0",
+			server:     "https://ABC.gr7.us-west-2.eks.amazonaws.com",
+			groups:     []string{"v1", "networking.k8s.io/v1"},
+			want: kubeconfig.Capabilities{
+				Version:        "v1.29.6-eks-db838b0",
+				Platform:       kubeconfig.PlatformEKS,
+				NetworkingAPIs: map[string][]string{"networking.k8s.io": {"v1"}},
+			},
+		},
+		{
+			name:       "gke",
+			gitVersion: "v1.30.1-gke.1329003",
+			server:     "https://34.1.2.3",
+			groups: []string{
+				"gateway.networking.k8s.io/v1beta1", "gateway.networking.k8s.io/v1", "networking.k8s.io/v1",
+			},
+			want: kubeconfig.Capabilities{
+				Version:  "v1.30.1-gke.1329003",
+				Platform: kubeconfig.PlatformGKE,
+				NetworkingAPIs: map[string][]string{
+					"gateway.networking.k8s.io": {"v1", "v1beta1"},
+					"networking.k8s.io":         {"v1"},
+				},
+			},
+		},
+		{
+			name:       "aks",
+			gitVersion: "v1.29.4",
+			server:     "https://my-cluster-dns-abc.hcp.westeurope.azmk8s.io:443",
+			want:       kubeconfig.Capabilities{Version: "v1.29.4", Platform: kubeconfig.PlatformAKS},
+		},
+		{
+			name:       "openshift",
+			gitVersion: "v1.28.9+416ecaf",
+			server:     "https://api.example.com:6443",
+			groups:     []string{"config.openshift.io/v1"},
+			want:       kubeconfig.Capabilities{Version: "v1.28.9+416ecaf", Platform: kubeconfig.PlatformOpenShift},
+		},
+		{
+			name:       "unknown",
+			gitVersion: "v1.31.0",
+			server:     "https://127.0.0.1:6443",
+			want:       kubeconfig.Capabilities{Version: "v1.31.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kubeconfig.DetectCapabilities(newFakeDiscovery(tt.gitVersion, tt.groups...), tt.server)
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now(), got.DetectedAt, time.Minute)
+
+			got.DetectedAt = time.Time{}
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func TestDetectCapabilitiesNamespaced(t *testing.T) {
+	client := newFakeDiscovery("v1.31.0")
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Namespaced: true},
+				{Name: "pods/log", Namespaced: true},
+				{Name: "nodes"},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true}},
+		},
+	}
+
+	got, err := kubeconfig.DetectCapabilities(client, "https://127.0.0.1:6443")
+	require.NoError(t, err)
+
+	tests := []struct {
+		resource   schema.GroupVersionResource
+		namespaced bool
+		known      bool
+	}{
+		{resource: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, namespaced: true, known: true},
+		{resource: schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, known: true},
+		{
+			resource:   schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			namespaced: true, known: true,
+		},
+		{resource: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}},
+	}
+
+	for _, tt := range tests {
+		namespaced, known := got.IsNamespaced(tt.resource)
+		assert.Equal(t, tt.namespaced, namespaced, tt.resource.String())
+		assert.Equal(t, tt.known, known, tt.resource.String())
+	}
+}
+
+func TestCapabilityCache(t *testing.T) {
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+	capabilityCache := kubeconfig.NewCapabilityCache(
+		func(_ context.Context, kContext *kubeconfig.Context) (*kubeconfig.Capabilities, error) {
+			calls.Add(1)
+			<-release
+
+			if kContext.Name == "broken" {
+				return nil, errors.New("unreachable")
+			}
+
+			return &kubeconfig.Capabilities{Version: "v1.30.0", DetectedAt: time.Now()}, nil
+		})
+
+	minikube := &kubeconfig.Context{Name: "minikube"}
+
+	// The detection runs in the background, once for concurrent gets.
+	assert.Nil(t, capabilityCache.Get(minikube))
+	assert.Nil(t, capabilityCache.Get(minikube))
+	close(release)
+
+	require.Eventually(t, func() bool {
+		return capabilityCache.Get(minikube) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "v1.30.0", capabilityCache.Get(minikube).Version)
+	assert.Equal(t, int32(1), calls.Load())
+
+	broken := &kubeconfig.Context{Name: "broken"}
+	capabilityCache.Detect(broken)
+
+	require.Eventually(t, func() bool {
+		return capabilityCache.Get(broken) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "unreachable", capabilityCache.Get(broken).Error)
+
+	capabilityCache.Forget("minikube")
+	assert.Nil(t, capabilityCache.Get(minikube))
+}
@@ -0,0 +1,212 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ConflictPolicy is what is done with the contexts of different kubeconfig files that get
+// the same name but point at different servers.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the context loaded first, like kubectl does when merging kubeconfigs.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictSuffix keeps both contexts, adding a numeric suffix to the names of the later ones.
+	ConflictSuffix ConflictPolicy = "suffix"
+	// ConflictPreferNewest keeps the context of the most recently modified kubeconfig file.
+	ConflictPreferNewest ConflictPolicy = "prefer-newest"
+)
+
+// Resolutions of the context conflicts.
+const (
+	ResolutionSkipped  = "skipped"
+	ResolutionRenamed  = "renamed"
+	ResolutionReplaced = "replaced"
+)
+
+var conflictPolicy atomic.Value
+
+// ParseConflictPolicy returns the conflict policy called name.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(name); policy {
+	case ConflictSkip, ConflictSuffix, ConflictPreferNewest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid context conflict policy %q, it must be %s, %s or %s", name,
+			ConflictSkip, ConflictSuffix, ConflictPreferNewest)
+	}
+}
+
+// SetConflictPolicy sets the policy applied to the conflicting contexts loaded from then on.
+func SetConflictPolicy(policy ConflictPolicy) {
+	conflictPolicy.Store(policy)
+}
+
+// currentConflictPolicy returns the policy set with SetConflictPolicy, or ConflictSkip.
+func currentConflictPolicy() ConflictPolicy {
+	if policy, ok := conflictPolicy.Load().(ConflictPolicy); ok {
+		return policy
+	}
+
+	return ConflictSkip
+}
+
+// ConflictingContext is a context involved in a conflict.
+type ConflictingContext struct {
+	OriginalName   string `json:"originalName"`
+	Server         string `json:"server"`
+	KubeConfigPath string `json:"kubeConfigPath"`
+}
+
+// ContextConflict reports two contexts loaded with the same name but different servers,
+// and how the conflict was resolved.
+type ContextConflict struct {
+	// Name is the name both contexts got.
+	Name string `json:"name"`
+	// Kept is the context that is loaded as Name.
+	Kept ConflictingContext `json:"kept"`
+	// Other is the context that was skipped, renamed or replaced.
+	Other ConflictingContext `json:"other"`
+	// Resolution is what was done with the other context.
+	Resolution string `json:"resolution"`
+	// RenamedTo is the name the other context was loaded as, if it was renamed.
+	RenamedTo string `json:"renamedTo,omitempty"`
+}
+
+func (c *ContextConflict) Error() string {
+	message := fmt.Sprintf("context %q of %s conflicts with the one of %s (%s), it was %s",
+		c.Other.OriginalName, c.Other.KubeConfigPath, c.Kept.KubeConfigPath, c.Kept.Server, c.Resolution)
+	if c.RenamedTo != "" {
+		message += " to " + c.RenamedTo
+	}
+
+	return message
+}
+
+// Is tells whether target is ErrNameConflict, so the conflicts match it.
+func (c *ContextConflict) Is(target error) bool {
+	return target == ErrNameConflict
+}
+
+// ContextConflicts returns the context conflicts in err, like the ones in the errors
+// of LoadAndStoreKubeConfigs.
+func ContextConflicts(err error) []*ContextConflict {
+	var conflicts []*ContextConflict
+
+	switch wrapped := err.(type) {
+	case *ContextConflict:
+		conflicts = append(conflicts, wrapped)
+	case interface{ Unwrap() []error }:
+		for _, err := range wrapped.Unwrap() {
+			conflicts = append(conflicts, ContextConflicts(err)...)
+		}
+	case interface{ Unwrap() error }:
+		conflicts = ContextConflicts(wrapped.Unwrap())
+	}
+
+	return conflicts
+}
+
+// resolveConflicts applies the policy to the contexts that got the same name but point at
+// different servers, and returns the contexts to load with the conflicts found. The
+// contexts with the same name and server are duplicates rather than conflicts, and kept.
+func resolveConflicts(contexts []Context, policy ConflictPolicy) ([]Context, []*ContextConflict) {
+	var conflicts []*ContextConflict
+
+	resolved := make([]Context, 0, len(contexts))
+	// byName are the indexes in resolved of the contexts by name.
+	byName := map[string]int{}
+	modTimes := map[string]time.Time{}
+
+	for _, context := range contexts {
+		i, exists := byName[context.Name]
+		if !exists || server(resolved[i]) == server(context) {
+			byName[context.Name] = len(resolved)
+			resolved = append(resolved, context)
+
+			continue
+		}
+
+		conflict := &ContextConflict{Name: context.Name, Kept: conflicting(resolved[i]), Other: conflicting(context)}
+		conflicts = append(conflicts, conflict)
+
+		switch policy {
+		case ConflictSuffix:
+			rename(&context, byName)
+
+			conflict.Resolution = ResolutionRenamed
+			conflict.RenamedTo = context.Name
+			byName[context.Name] = len(resolved)
+			resolved = append(resolved, context)
+		case ConflictPreferNewest:
+			conflict.Resolution = ResolutionSkipped
+
+			if modTime(modTimes, context.KubeConfigPath).After(modTime(modTimes, resolved[i].KubeConfigPath)) {
+				conflict.Kept, conflict.Other = conflict.Other, conflict.Kept
+				conflict.Resolution = ResolutionReplaced
+				resolved[i] = context
+			}
+		default:
+			conflict.Resolution = ResolutionSkipped
+		}
+	}
+
+	return resolved, conflicts
+}
+
+// rename adds the first numeric suffix to the name of the context that no context has.
+func rename(context *Context, byName map[string]int) {
+	for n := 2; ; n++ {
+		name := context.Name + "-" + strconv.Itoa(n)
+		if _, taken := byName[name]; !taken {
+			context.Name = name
+
+			break
+		}
+	}
+
+	if context.KubeConfigPath != "" {
+		context.ClusterID = fmt.Sprintf("%s+%s", context.KubeConfigPath, context.Name)
+	}
+}
+
+func server(context Context) string {
+	if context.Cluster == nil {
+		return ""
+	}
+
+	return context.Cluster.Server
+}
+
+func conflicting(context Context) ConflictingContext {
+	originalName := context.OriginalName
+	if originalName == "" {
+		originalName = context.Name
+	}
+
+	return ConflictingContext{
+		OriginalName:   originalName,
+		Server:         server(context),
+		KubeConfigPath: context.KubeConfigPath,
+	}
+}
+
+// modTime returns the modification time of the file, caching it in modTimes.
+func modTime(modTimes map[string]time.Time, path string) time.Time {
+	if t, ok := modTimes[path]; ok {
+		return t
+	}
+
+	var t time.Time
+	if info, err := os.Stat(path); err == nil {
+		t = info.ModTime()
+	}
+
+	modTimes[path] = t
+
+	return t
+}
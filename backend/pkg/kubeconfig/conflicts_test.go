@@ -0,0 +1,134 @@
+package kubeconfig_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKubeconfig writes a kubeconfig with a context of each of the given names and servers.
+func writeKubeconfig(t *testing.T, path string, servers map[string]string) {
+	t.Helper()
+
+	var clusters, contexts strings.Builder
+
+	for name, server := range servers {
+		fmt.Fprintf(&clusters, "- name: %s\n  cluster:\n    server: %s\n", name, server)
+		fmt.Fprintf(&contexts, "- name: %s\n  context:\n    cluster: %s\n    user: user\n", name, name)
+	}
+
+	data := "apiVersion: v1\nkind: Config\nclusters:\n" + clusters.String() + "contexts:\n" + contexts.String() +
+		"users:\n- name: user\n  user:\n    token: token\n"
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	policy, err := kubeconfig.ParseConflictPolicy("prefer-newest")
+	require.NoError(t, err)
+	assert.Equal(t, kubeconfig.ConflictPreferNewest, policy)
+
+	_, err = kubeconfig.ParseConflictPolicy("overwrite")
+	assert.ErrorContains(t, err, "invalid context conflict policy")
+}
+
+func TestLoadContextsConflicts(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+
+	writeKubeconfig(t, first, map[string]string{"prod": "https://eu.example.com", "dev": "https://dev.example.com"})
+	writeKubeconfig(t, second, map[string]string{"prod": "https://us.example.com", "dev": "https://dev.example.com"})
+
+	// The first file is older, for prefer-newest.
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(first, old, old))
+
+	delimiter := ":"
+	if runtime.GOOS == "windows" {
+		delimiter = ";"
+	}
+
+	paths := first + delimiter + second
+
+	tests := []struct {
+		policy     kubeconfig.ConflictPolicy
+		servers    map[string]string
+		resolution string
+		renamedTo  string
+		keptPath   string
+	}{
+		{
+			policy:     kubeconfig.ConflictSkip,
+			servers:    map[string]string{"prod": "https://eu.example.com"},
+			resolution: kubeconfig.ResolutionSkipped,
+			keptPath:   first,
+		},
+		{
+			policy:     kubeconfig.ConflictSuffix,
+			servers:    map[string]string{"prod": "https://eu.example.com", "prod-2": "https://us.example.com"},
+			resolution: kubeconfig.ResolutionRenamed,
+			renamedTo:  "prod-2",
+			keptPath:   first,
+		},
+		{
+			policy:     kubeconfig.ConflictPreferNewest,
+			servers:    map[string]string{"prod": "https://us.example.com"},
+			resolution: kubeconfig.ResolutionReplaced,
+			keptPath:   second,
+		},
+	}
+
+	t.Cleanup(func() { kubeconfig.SetConflictPolicy(kubeconfig.ConflictSkip) })
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			kubeconfig.SetConflictPolicy(tt.policy)
+
+			contexts, contextErrors, err := kubeconfig.LoadContextsFromMultipleFiles(paths, kubeconfig.DynamicCluster)
+			require.NoError(t, err)
+
+			servers := map[string]string{}
+
+			for _, context := range contexts {
+				if context.Name != "dev" {
+					servers[context.Name] = context.Cluster.Server
+				}
+			}
+
+			assert.Equal(t, tt.servers, servers)
+
+			// The dev contexts are duplicates rather than conflicts.
+			require.Len(t, contextErrors, 1)
+
+			conflicts := kubeconfig.ContextConflicts(contextErrors[0].Error)
+			require.Len(t, conflicts, 1)
+			assert.Equal(t, "prod", conflicts[0].Name)
+			assert.Equal(t, tt.resolution, conflicts[0].Resolution)
+			assert.Equal(t, tt.renamedTo, conflicts[0].RenamedTo)
+			assert.Equal(t, tt.keptPath, conflicts[0].Kept.KubeConfigPath)
+		})
+	}
+
+	// The conflicts are reported by LoadAndStoreKubeConfigs too.
+	kubeconfig.SetConflictPolicy(kubeconfig.ConflictSkip)
+
+	store := kubeconfig.NewContextStore()
+	err := kubeconfig.LoadAndStoreKubeConfigs(store, paths, kubeconfig.DynamicCluster, nil)
+
+	conflicts := kubeconfig.ContextConflicts(err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "https://eu.example.com", conflicts[0].Kept.Server)
+	assert.Equal(t, "https://us.example.com", conflicts[0].Other.Server)
+
+	prod, err := store.GetContext("prod")
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu.example.com", prod.Cluster.Server)
+}
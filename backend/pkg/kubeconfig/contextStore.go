@@ -3,9 +3,18 @@ package kubeconfig
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ContextStore is an interface for storing and retrieving contexts.
@@ -16,10 +25,79 @@ type ContextStore interface {
 	RemoveContext(name string) error
 	AddContextWithKeyAndTTL(headlampContext *Context, key string, ttl time.Duration) error
 	UpdateTTL(key string, ttl time.Duration) error
+	GetContextByOriginalName(name string) (*Context, error)
+	GetContextsByServer(server string) ([]*Context, error)
+	GetContextsWithKeyPrefix(prefix string) (map[string]*Context, error)
+	Watch(ctx context.Context) <-chan ContextEvent
+	SetQuota(quota Quota)
+	SetRejectInsecure(reject bool)
+	SetTTLPolicy(policy TTLPolicy)
+	TTLPolicy() TTLPolicy
+	SetContextGroup(group ContextGroup) error
+	RemoveContextGroup(name string) error
+	ContextGroups() []ContextGroup
+	SetContextGroupsFile(path string) error
+	Stats() cache.Stats
+	UpdateContext(key string, update ContextUpdate) (*Context, error)
+	Revision() uint64
+	SetLoadErrors(kubeConfigPath string, loadErrors []LoadError)
+	LoadErrors() []LoadError
 }
 
 type contextStore struct {
 	cache cache.Cache[*Context]
+
+	// mu guards the indexes of the keys of the contexts.
+	mu sync.Mutex
+	// keysByOriginalName are the keys of the contexts by the names they have in their kubeconfig.
+	keysByOriginalName map[string]string
+	// originalNamesByKey is the reverse of keysByOriginalName, to update it on removals.
+	originalNamesByKey map[string]string
+	// keysByServer are the keys of the contexts by the normalized URL of their API server.
+	keysByServer map[string]map[string]struct{}
+	// serversByKey is the reverse of keysByServer, to update it on removals.
+	serversByKey map[string]string
+	// expiries are the expiries of the contexts stored with a ttl, by key. They are guarded by mu.
+	expiries map[string]*expiry
+	// expiredAt are the times the contexts expired, by key, to tell them apart from the ones
+	// that never existed for a while. They are guarded by mu.
+	expiredAt map[string]time.Time
+	// renewedAt are the last times the ttl of the contexts was updated, by key. They are
+	// guarded by mu.
+	renewedAt map[string]time.Time
+
+	// watchMu guards the watchers.
+	watchMu sync.Mutex
+	// watchers are the channels of the watchers of the changes of the contexts.
+	watchers map[chan ContextEvent]struct{}
+
+	// quotaMu guards the quota and rejectInsecure, and serializes the additions of the contexts
+	// to enforce them.
+	quotaMu sync.Mutex
+	// quota caps the dynamic clusters in the store.
+	quota Quota
+	// rejectInsecure rejects the contexts skipping the TLS verification of their API server.
+	rejectInsecure bool
+
+	// ttlPolicyMu guards the ttlPolicy.
+	ttlPolicyMu sync.RWMutex
+	// ttlPolicy bounds the ttl of the contexts in the store.
+	ttlPolicy TTLPolicy
+
+	// groupsMu guards the groups.
+	groupsMu sync.RWMutex
+	// groups are the groups of the contexts, by name.
+	groups map[string]ContextGroup
+	// groupsFile is the file the groups are saved to, if any. It's guarded by groupsMu.
+	groupsFile string
+
+	// revision is bumped on every change of the contexts, see Revision.
+	revision atomic.Uint64
+
+	// loadErrorsMu guards the loadErrors.
+	loadErrorsMu sync.Mutex
+	// loadErrors are the errors of the last loading of the kubeconfig files, by path.
+	loadErrors map[string][]LoadError
 }
 
 // NewContextStore creates a new ContextStore.
@@ -27,7 +105,17 @@ func NewContextStore() ContextStore {
 	cache := cache.New[*Context]()
 
 	return &contextStore{
-		cache: cache,
+		cache:              cache,
+		keysByOriginalName: map[string]string{},
+		originalNamesByKey: map[string]string{},
+		keysByServer:       map[string]map[string]struct{}{},
+		serversByKey:       map[string]string{},
+		expiries:           map[string]*expiry{},
+		expiredAt:          map[string]time.Time{},
+		renewedAt:          map[string]time.Time{},
+		loadErrors:         map[string][]LoadError{},
+		groups:             map[string]ContextGroup{},
+		watchers:           map[chan ContextEvent]struct{}{},
 	}
 }
 
@@ -57,7 +145,83 @@ func (c *contextStore) AddContext(headlampContext *Context) error {
 		}
 	}
 
-	return c.cache.Set(context.Background(), name, headlampContext)
+	existed, err := c.set(headlampContext, name, 0)
+	if err != nil {
+		return err
+	}
+
+	c.index(headlampContext, name, 0)
+	c.emit(ContextEvent{Type: addedEventType(existed), Key: name, Context: headlampContext})
+
+	return nil
+}
+
+// addedEventType returns the type of the event of a context stored under a key.
+func addedEventType(existed bool) ContextEventType {
+	if existed {
+		return ContextModified
+	}
+
+	return ContextAdded
+}
+
+// index indexes the key of the context by its original name and by its API server, and
+// tracks its ttl if it has one. The internal contexts, which are the dynamic clusters of the
+// users, aren't indexed by name as they're private to them.
+func (c *contextStore) index(headlampContext *Context, key string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unindex(key)
+	c.schedule(key, headlampContext, ttl)
+	delete(c.expiredAt, key)
+
+	if headlampContext.Cluster != nil && headlampContext.Cluster.Server != "" {
+		server := normalizeServer(headlampContext.Cluster.Server)
+
+		if c.keysByServer[server] == nil {
+			c.keysByServer[server] = map[string]struct{}{}
+		}
+
+		c.keysByServer[server][key] = struct{}{}
+		c.serversByKey[key] = server
+	}
+
+	if headlampContext.Internal {
+		return
+	}
+
+	originalName := headlampContext.OriginalName
+	if originalName == "" {
+		originalName = headlampContext.Name
+	}
+
+	if previousKey, ok := c.keysByOriginalName[originalName]; ok {
+		delete(c.originalNamesByKey, previousKey)
+	}
+
+	c.keysByOriginalName[originalName] = key
+	c.originalNamesByKey[key] = originalName
+}
+
+// unindex removes the key from the indexes. c.mu must be held.
+func (c *contextStore) unindex(key string) {
+	delete(c.renewedAt, key)
+
+	if originalName, ok := c.originalNamesByKey[key]; ok {
+		delete(c.keysByOriginalName, originalName)
+		delete(c.originalNamesByKey, key)
+	}
+
+	if server, ok := c.serversByKey[key]; ok {
+		delete(c.keysByServer[server], key)
+
+		if len(c.keysByServer[server]) == 0 {
+			delete(c.keysByServer, server)
+		}
+
+		delete(c.serversByKey, key)
+	}
 }
 
 // GetContexts returns all contexts in the store.
@@ -76,9 +240,14 @@ func (c *contextStore) GetContexts() ([]*Context, error) {
 	return contexts, nil
 }
 
-// GetContext returns a context from the store.
+// GetContext returns a context from the store. It returns ErrContextExpired if the context
+// expired recently, or else ErrContextNotFound if there's no such context.
 func (c *contextStore) GetContext(name string) (*Context, error) {
 	context, err := c.cache.Get(context.Background(), name)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, c.notFound(name)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -88,15 +257,215 @@ func (c *contextStore) GetContext(name string) (*Context, error) {
 
 // RemoveContext removes a context from the store.
 func (c *contextStore) RemoveContext(name string) error {
-	return c.cache.Delete(context.Background(), name)
+	headlampContext, getErr := c.cache.Get(context.Background(), name)
+
+	c.mu.Lock()
+	c.unindex(name)
+	c.unschedule(name)
+	delete(c.expiredAt, name)
+	c.mu.Unlock()
+
+	if err := c.cache.Delete(context.Background(), name); err != nil {
+		return err
+	}
+
+	if getErr == nil {
+		c.emit(ContextEvent{Type: ContextRemoved, Key: name, Context: headlampContext})
+	}
+
+	return nil
 }
 
-// AddContextWithKeyAndTTL adds a context to the store with a ttl.
+// AddContextWithKeyAndTTL adds a context to the store with a ttl, within the TTLPolicy of the
+// store. A ttl of 0 gets the default one of the policy.
 func (c *contextStore) AddContextWithKeyAndTTL(headlampContext *Context, key string, ttl time.Duration) error {
-	return c.cache.SetWithTTL(context.Background(), key, headlampContext, ttl)
+	ttl = c.TTLPolicy().Apply(ttl)
+
+	existed, err := c.set(headlampContext, key, ttl)
+	if err != nil {
+		return err
+	}
+
+	c.index(headlampContext, key, ttl)
+
+	event := ContextEvent{Type: addedEventType(existed), Key: key, Context: headlampContext}
+	if ttl > 0 {
+		event.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	c.emit(event)
+
+	return nil
 }
 
-// UpdateTTL updates the ttl of a context.
+// GetContextByOriginalName returns the context called name in its kubeconfig, before its
+// name was made URL safe. It returns ErrContextNotFound if there's no such context.
+func (c *contextStore) GetContextByOriginalName(name string) (*Context, error) {
+	c.mu.Lock()
+	key, ok := c.keysByOriginalName[name]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, &Error{Code: CodeContextNotFound, Context: name, Err: cache.ErrNotFound}
+	}
+
+	headlampContext, err := c.cache.Get(context.Background(), key)
+	if errors.Is(err, cache.ErrNotFound) {
+		// The context expired, so it's dropped from the index too.
+		c.mu.Lock()
+
+		if c.keysByOriginalName[name] == key {
+			c.unindex(key)
+		}
+
+		c.mu.Unlock()
+
+		return nil, c.notFound(key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return headlampContext, nil
+}
+
+// GetContextsByServer returns the contexts whose cluster is the API server at the URL server,
+// whatever their names. The URLs are compared ignoring the case of the scheme and host, the
+// default port and a trailing slash.
+func (c *contextStore) GetContextsByServer(server string) ([]*Context, error) {
+	server = normalizeServer(server)
+
+	c.mu.Lock()
+
+	keys := make([]string, 0, len(c.keysByServer[server]))
+	for key := range c.keysByServer[server] {
+		keys = append(keys, key)
+	}
+
+	c.mu.Unlock()
+
+	contexts := []*Context{}
+
+	for _, key := range keys {
+		headlampContext, err := c.cache.Get(context.Background(), key)
+		if errors.Is(err, cache.ErrNotFound) {
+			// The context expired, so it's dropped from the indexes too.
+			c.mu.Lock()
+
+			if c.serversByKey[key] == server {
+				c.unindex(key)
+			}
+
+			c.mu.Unlock()
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		contexts = append(contexts, headlampContext)
+	}
+
+	return contexts, nil
+}
+
+// GetContextsWithKeyPrefix returns the contexts whose keys in the store start with prefix, by key.
+func (c *contextStore) GetContextsWithKeyPrefix(prefix string) (map[string]*Context, error) {
+	return c.cache.GetAll(context.Background(), func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// normalizeServer returns the URL of an API server in the form it is indexed by.
+func normalizeServer(server string) string {
+	u, err := url.Parse(strings.TrimSpace(server))
+	if err != nil || u.Host == "" {
+		return strings.TrimSuffix(strings.TrimSpace(server), "/")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	if port := u.Port(); (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		u.Host = u.Hostname()
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	return u.String()
+}
+
+// UpdateTTL updates the ttl of a context, within the TTLPolicy of the store. The updates
+// coming less than its MinRenewal after the previous one, or while more than its RenewBelow
+// of the ttl remains, are ignored, so calling it repeatedly writes to the cache only once.
+// It returns ErrContextExpired if the context expired recently, or else ErrContextNotFound
+// if there's no such context.
 func (c *contextStore) UpdateTTL(key string, ttl time.Duration) error {
-	return c.cache.UpdateTTL(context.Background(), key, ttl)
+	policy := c.TTLPolicy()
+	ttl = policy.Apply(ttl)
+
+	claimed, release := c.claimRenewal(key, ttl, policy)
+	if !claimed {
+		return nil
+	}
+
+	if err := c.cache.UpdateTTL(context.Background(), key, ttl); err != nil {
+		release()
+
+		if errors.Is(err, cache.ErrNotFound) {
+			return c.notFound(key)
+		}
+
+		return err
+	}
+
+	// The ttl of an expired context isn't updated.
+	headlampContext, err := c.cache.Get(context.Background(), key)
+	if err != nil {
+		release()
+
+		return nil
+	}
+
+	c.mu.Lock()
+	c.schedule(key, headlampContext, ttl)
+	c.mu.Unlock()
+
+	c.revision.Add(1)
+
+	return nil
+}
+
+// Revision returns the revision of the contexts in the store, which changes every time a
+// context is added, replaced, updated or removed, or expires, so the clients can tell whether
+// the contexts changed since they last listed them. It starts over when the process restarts.
+func (c *contextStore) Revision() uint64 {
+	return c.revision.Load()
+}
+
+// Stats returns the statistics of the cache of the contexts.
+func (c *contextStore) Stats() cache.Stats {
+	return c.cache.Stats()
+}
+
+// GetContextWithSpan gets a context from the store like GetContext, recording the
+// lookup in a span that is a child of the span in ctx.
+func GetContextWithSpan(ctx context.Context, store ContextStore, name string) (*Context, error) {
+	_, span := otel.Tracer("kubeconfig").Start(ctx, "ContextStore.GetContext",
+		trace.WithAttributes(attribute.String("context.key", name)))
+	defer span.End()
+
+	kContext, err := store.GetContext(name)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting context")
+	}
+
+	return kContext, err
 }
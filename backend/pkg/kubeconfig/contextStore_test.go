@@ -1,12 +1,19 @@
 package kubeconfig_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/client-go/tools/clientcmd/api"
 )
 
 func TestContextStore(t *testing.T) {
@@ -40,7 +47,8 @@ func TestContextStore(t *testing.T) {
 
 	_, err = store.GetContext("test")
 	require.Error(t, err)
-	require.Equal(t, cache.ErrNotFound, err)
+	require.ErrorIs(t, err, cache.ErrNotFound)
+	require.ErrorIs(t, err, kubeconfig.ErrContextNotFound)
 
 	// Add context with key and ttl
 	err = store.AddContextWithKeyAndTTL(&kubeconfig.Context{Name: "testwithttl"}, "testwithttl", 2*time.Second)
@@ -55,6 +63,11 @@ func TestContextStore(t *testing.T) {
 	err = store.UpdateTTL("testwithttl", 2*time.Second)
 	require.NoError(t, err)
 
+	// Update the ttl of a missing context
+	err = store.UpdateTTL("missing", 2*time.Second)
+	require.ErrorIs(t, err, kubeconfig.ErrContextNotFound)
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
 	// Test GetContext after updating ttl
 	value, err = store.GetContext("testwithttl")
 	require.NoError(t, err)
@@ -66,5 +79,193 @@ func TestContextStore(t *testing.T) {
 	// Test GetContext
 	_, err = store.GetContext("testwithttl")
 	require.Error(t, err)
-	require.Equal(t, cache.ErrNotFound, err)
+	require.ErrorIs(t, err, cache.ErrNotFound)
+	require.ErrorIs(t, err, kubeconfig.ErrContextExpired)
+}
+
+func TestContextStoreGetContextByOriginalName(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "team--prod", OriginalName: "team/prod"}))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube"}))
+
+	kContext, err := store.GetContextByOriginalName("team/prod")
+	require.NoError(t, err)
+	require.Equal(t, "team--prod", kContext.Name)
+
+	// Contexts without an original name are indexed by their name.
+	kContext, err = store.GetContextByOriginalName("minikube")
+	require.NoError(t, err)
+	require.Equal(t, "minikube", kContext.Name)
+
+	_, err = store.GetContextByOriginalName("team--prod")
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
+	// Renaming a context moves it in the index.
+	require.NoError(t, store.RemoveContext("team--prod"))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "team-prod-1a2b", OriginalName: "team/prod"}))
+
+	kContext, err = store.GetContextByOriginalName("team/prod")
+	require.NoError(t, err)
+	require.Equal(t, "team-prod-1a2b", kContext.Name)
+
+	require.NoError(t, store.RemoveContext("team-prod-1a2b"))
+
+	_, err = store.GetContextByOriginalName("team/prod")
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
+	// The dynamic clusters of the users aren't indexed.
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "minikube", Internal: true}, "minikube-user1", time.Minute))
+
+	kContext, err = store.GetContextByOriginalName("minikube")
+	require.NoError(t, err)
+	require.False(t, kContext.Internal)
+
+	// Expired contexts aren't found.
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "short-lived"}, "short-lived", 100*time.Millisecond))
+
+	require.Eventually(t, func() bool {
+		_, err := store.GetContextByOriginalName("short-lived")
+
+		return errors.Is(err, cache.ErrNotFound)
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestContextStoreGetContextsByServer(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	withServer := func(name, server string) *kubeconfig.Context {
+		return &kubeconfig.Context{Name: name, Cluster: &api.Cluster{Server: server}}
+	}
+
+	require.NoError(t, store.AddContext(withServer("prod", "https://prod.example.com")))
+	require.NoError(t, store.AddContext(withServer("prod-admin", "https://PROD.example.com:443/")))
+	require.NoError(t, store.AddContext(withServer("staging", "https://staging.example.com")))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "no-cluster"}))
+
+	names := func(server string) []string {
+		contexts, err := store.GetContextsByServer(server)
+		require.NoError(t, err)
+
+		names := []string{}
+		for _, kContext := range contexts {
+			names = append(names, kContext.Name)
+		}
+
+		return names
+	}
+
+	require.ElementsMatch(t, []string{"prod", "prod-admin"}, names("https://prod.example.com/"))
+	require.ElementsMatch(t, []string{"staging"}, names("https://staging.example.com"))
+	require.Empty(t, names("https://unknown.example.com"))
+
+	// Moving a context to another server moves it in the index.
+	require.NoError(t, store.AddContext(withServer("prod-admin", "https://staging.example.com")))
+	require.ElementsMatch(t, []string{"prod"}, names("https://prod.example.com"))
+	require.ElementsMatch(t, []string{"staging", "prod-admin"}, names("https://staging.example.com"))
+
+	require.NoError(t, store.RemoveContext("prod"))
+	require.Empty(t, names("https://prod.example.com"))
+
+	// Expired contexts aren't found.
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		withServer("short-lived", "https://edge.example.com"), "short-lived", 100*time.Millisecond))
+	require.ElementsMatch(t, []string{"short-lived"}, names("https://edge.example.com"))
+
+	require.Eventually(t, func() bool {
+		return len(names("https://edge.example.com")) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestContextStoreGetContextsWithKeyPrefix(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube"}))
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "minikube", Internal: true}, "minikube-user1", time.Minute))
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "minikube", Internal: true}, "minikube-user2", time.Minute))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "kind"}))
+
+	contexts, err := store.GetContextsWithKeyPrefix("minikube-")
+	require.NoError(t, err)
+	require.Len(t, contexts, 2)
+	require.Contains(t, contexts, "minikube-user1")
+	require.Contains(t, contexts, "minikube-user2")
+
+	contexts, err = store.GetContextsWithKeyPrefix("")
+	require.NoError(t, err)
+	require.Len(t, contexts, 4)
+}
+
+func TestGetContextWithSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	originalTP := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(originalTP)
+		_ = tp.Shutdown(context.Background())
+	})
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "test"}))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+
+	kContext, err := kubeconfig.GetContextWithSpan(ctx, store, "test")
+	require.NoError(t, err)
+	require.Equal(t, "test", kContext.Name)
+
+	_, err = kubeconfig.GetContextWithSpan(ctx, store, "non-existent-context")
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+
+	for _, span := range spans[:2] {
+		require.Equal(t, "ContextStore.GetContext", span.Name())
+		require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestContextStoreRevision(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	revision := store.Revision()
+	changed := func() bool {
+		previous := revision
+		revision = store.Revision()
+
+		return revision != previous
+	}
+
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "test"}))
+	require.True(t, changed())
+
+	_, err := store.GetContexts()
+	require.NoError(t, err)
+	require.False(t, changed())
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(&kubeconfig.Context{Name: "dynamic"}, "dynamic", time.Minute))
+	require.True(t, changed())
+
+	require.NoError(t, store.UpdateTTL("dynamic", 2*time.Minute))
+	require.True(t, changed())
+
+	require.NoError(t, store.RemoveContext("test"))
+	require.True(t, changed())
+
+	// Removing a missing context changes nothing.
+	require.NoError(t, store.RemoveContext("test"))
+	require.False(t, changed())
 }
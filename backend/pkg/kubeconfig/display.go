@@ -0,0 +1,83 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// maxIconLength is the maximum length of the icon of a context.
+	maxIconLength = 100
+	// maxDescriptionLength is the maximum length, in characters, of the description of a context.
+	maxDescriptionLength = 500
+)
+
+var (
+	// iconPattern matches the Iconify names of the icons, e.g. mdi:server.
+	iconPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*:[a-z0-9]+(-[a-z0-9]+)*$`)
+	// colorPattern matches the hex colors, e.g. #d32f2f.
+	colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
+// DisplayMetadata is how a context is displayed, the same for all the users, e.g. to tell the
+// production clusters apart from the staging ones. It is kept in the headlamp_info extension
+// of the contexts of the kubeconfig files.
+type DisplayMetadata struct {
+	// Icon is the Iconify name of the icon of the context, e.g. mdi:server.
+	Icon string `json:"icon,omitempty"`
+	// Color is the hex color of the context, e.g. #d32f2f.
+	Color string `json:"color,omitempty"`
+	// Description describes the context.
+	Description string `json:"description,omitempty"`
+}
+
+// Validate checks the display metadata. All the fields are optional.
+func (m DisplayMetadata) Validate() error {
+	if m.Icon != "" && (len(m.Icon) > maxIconLength || !iconPattern.MatchString(m.Icon)) {
+		return fmt.Errorf("invalid icon %q, it must be an icon name like mdi:server", m.Icon)
+	}
+
+	if m.Color != "" && !colorPattern.MatchString(m.Color) {
+		return fmt.Errorf("invalid color %q, it must be a hex color like #d32f2f", m.Color)
+	}
+
+	if utf8.RuneCountInString(m.Description) > maxDescriptionLength {
+		return fmt.Errorf("description is longer than %d characters", maxDescriptionLength)
+	}
+
+	return nil
+}
+
+// headlampInfo returns the headlamp_info extension of the context, if it has a valid one.
+func headlampInfo(kubeContext *api.Context) (CustomObject, bool) {
+	if kubeContext == nil || kubeContext.Extensions["headlamp_info"] == nil {
+		return CustomObject{}, false
+	}
+
+	infoBytes, err := json.Marshal(kubeContext.Extensions["headlamp_info"])
+	if err != nil {
+		return CustomObject{}, false
+	}
+
+	var customObj CustomObject
+	if err := json.Unmarshal(infoBytes, &customObj); err != nil {
+		return CustomObject{}, false
+	}
+
+	return customObj, true
+}
+
+// displayMetadata returns the display metadata in the headlamp_info extension of the context.
+// The metadata that isn't valid is dropped, as it may have been edited by hand.
+func displayMetadata(kubeContext *api.Context) DisplayMetadata {
+	customObj, ok := headlampInfo(kubeContext)
+	if !ok || customObj.DisplayMetadata.Validate() != nil {
+		return DisplayMetadata{}
+	}
+
+	return customObj.DisplayMetadata
+}
@@ -0,0 +1,84 @@
+package kubeconfig_test
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestDisplayMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata kubeconfig.DisplayMetadata
+		wantErr  string
+	}{
+		{name: "empty"},
+		{
+			name:     "valid",
+			metadata: kubeconfig.DisplayMetadata{Icon: "mdi:server-network", Color: "#D32F2F", Description: "Production"},
+		},
+		{name: "short color", metadata: kubeconfig.DisplayMetadata{Color: "#fff"}},
+		{name: "icon without prefix", metadata: kubeconfig.DisplayMetadata{Icon: "server"}, wantErr: "invalid icon"},
+		{name: "icon url", metadata: kubeconfig.DisplayMetadata{Icon: "https://example.com/x.svg"}, wantErr: "invalid icon"},
+		{name: "named color", metadata: kubeconfig.DisplayMetadata{Color: "red"}, wantErr: "invalid color"},
+		{
+			name:     "long description",
+			metadata: kubeconfig.DisplayMetadata{Description: string(make([]rune, 501))},
+			wantErr:  "description is longer than 500 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.metadata.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadContextsDisplayMetadata(t *testing.T) {
+	withInfo := func(info *kubeconfig.CustomObject) *api.Context {
+		return &api.Context{Cluster: "cluster", Extensions: map[string]runtime.Object{"headlamp_info": info}}
+	}
+
+	config := &api.Config{
+		Clusters: map[string]*api.Cluster{"cluster": {Server: "https://example.com"}},
+		Contexts: map[string]*api.Context{
+			"prod": withInfo(&kubeconfig.CustomObject{
+				DryRun:          true,
+				DisplayMetadata: kubeconfig.DisplayMetadata{Icon: "mdi:fire", Color: "#d32f2f", Description: "Production"},
+			}),
+			// Invalid metadata, edited by hand, is dropped.
+			"staging": withInfo(&kubeconfig.CustomObject{
+				DisplayMetadata: kubeconfig.DisplayMetadata{Color: "javascript:alert(1)"},
+			}),
+			"dev": {Cluster: "cluster"},
+		},
+	}
+
+	contexts, errs := kubeconfig.LoadContextsFromAPIConfig(config, true)
+	require.Empty(t, errs)
+	require.Len(t, contexts, 3)
+
+	byName := map[string]*kubeconfig.Context{}
+	for _, kContext := range contexts {
+		byName[kContext.Name] = &kContext
+	}
+
+	assert.Equal(t, kubeconfig.DisplayMetadata{Icon: "mdi:fire", Color: "#d32f2f", Description: "Production"},
+		byName["prod"].DisplayMetadata)
+	assert.True(t, byName["prod"].IsDryRun())
+	assert.Equal(t, kubeconfig.DisplayMetadata{}, byName["staging"].DisplayMetadata)
+	assert.Equal(t, kubeconfig.DisplayMetadata{}, byName["dev"].DisplayMetadata)
+}
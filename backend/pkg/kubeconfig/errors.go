@@ -0,0 +1,140 @@
+package kubeconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// ErrorCode is the machine-readable code of an Error, sent to the clients so they can react
+// to it, e.g. by asking the user to log in again rather than offering to retry.
+type ErrorCode string
+
+const (
+	// CodeContextNotFound is the code of the errors for contexts that don't exist.
+	CodeContextNotFound ErrorCode = "context_not_found"
+	// CodeContextExpired is the code of the errors for contexts whose ttl ran out.
+	CodeContextExpired ErrorCode = "context_expired"
+	// CodeAuthExpired is the code of the errors for credentials that expired and couldn't be
+	// refreshed.
+	CodeAuthExpired ErrorCode = "auth_expired"
+	// CodeClusterUnreachable is the code of the errors for clusters that didn't answer.
+	CodeClusterUnreachable ErrorCode = "cluster_unreachable"
+	// CodeNameConflict is the code of the errors for contexts whose name is already taken.
+	CodeNameConflict ErrorCode = "name_conflict"
+)
+
+var (
+	// ErrContextNotFound is returned when there's no context with a name or key.
+	ErrContextNotFound = &Error{Code: CodeContextNotFound}
+	// ErrContextExpired is returned when a context was removed because its ttl ran out.
+	ErrContextExpired = &Error{Code: CodeContextExpired}
+	// ErrAuthExpired is returned when the credentials of a context expired.
+	ErrAuthExpired = &Error{Code: CodeAuthExpired}
+	// ErrClusterUnreachable is returned when a request to the cluster of a context failed.
+	ErrClusterUnreachable = &Error{Code: CodeClusterUnreachable}
+	// ErrNameConflict is returned when the name of a context is already used by another one.
+	ErrNameConflict = &Error{Code: CodeNameConflict}
+)
+
+// errorMessages are the messages of the error codes.
+var errorMessages = map[ErrorCode]string{
+	CodeContextNotFound:    "context not found",
+	CodeContextExpired:     "context expired",
+	CodeAuthExpired:        "authentication expired",
+	CodeClusterUnreachable: "cluster unreachable",
+	CodeNameConflict:       "context name conflict",
+}
+
+// errorStatuses are the HTTP statuses of the error codes.
+var errorStatuses = map[ErrorCode]int{
+	CodeContextNotFound:    http.StatusNotFound,
+	CodeContextExpired:     http.StatusGone,
+	CodeAuthExpired:        http.StatusUnauthorized,
+	CodeClusterUnreachable: http.StatusBadGateway,
+	CodeNameConflict:       http.StatusConflict,
+}
+
+// Error is an error about a context, with a code telling what went wrong. It matches the
+// Err* variables of its code with errors.Is, and the error it wraps.
+type Error struct {
+	Code ErrorCode
+	// Context is the name or key of the context, if known.
+	Context string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	message := errorMessages[e.Code]
+	if message == "" {
+		message = string(e.Code)
+	}
+
+	if e.Context != "" {
+		message = fmt.Sprintf("%s: %q", message, e.Context)
+	}
+
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is tells whether target is the Err* variable of the code of the error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && t.Context == "" && t.Err == nil && t.Code == e.Code
+}
+
+// Code returns the code of the error in err, or "" if there's none.
+func Code(err error) ErrorCode {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Code
+	}
+
+	if len(ContextConflicts(err)) > 0 {
+		return CodeNameConflict
+	}
+
+	return ""
+}
+
+// HTTPStatus returns the HTTP status of the code of err, or status if it has no code.
+func HTTPStatus(err error, status int) int {
+	if codeStatus, ok := errorStatuses[Code(err)]; ok {
+		return codeStatus
+	}
+
+	return status
+}
+
+// ErrorResponse is the JSON body of the responses for the errors, so the clients can tell
+// them apart by their code.
+type ErrorResponse struct {
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code,omitempty"`
+}
+
+// WriteError answers with err as an ErrorResponse, with the HTTP status of its code, or
+// status if it has none.
+func WriteError(ctx context.Context, w http.ResponseWriter, err error, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(HTTPStatus(err, status))
+
+	if encodeErr := json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error(), Code: Code(err)}); encodeErr != nil {
+		logger.LogCtx(ctx, logger.LevelError, nil, encodeErr, "encoding error response")
+	}
+}
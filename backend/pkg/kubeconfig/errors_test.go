@@ -0,0 +1,85 @@
+package kubeconfig_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCodes(t *testing.T) {
+	err := fmt.Errorf("getting context: %w",
+		&kubeconfig.Error{Code: kubeconfig.CodeContextExpired, Context: "minikube", Err: cache.ErrNotFound})
+
+	assert.ErrorIs(t, err, kubeconfig.ErrContextExpired)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.NotErrorIs(t, err, kubeconfig.ErrContextNotFound)
+	assert.Equal(t, kubeconfig.CodeContextExpired, kubeconfig.Code(err))
+	assert.Equal(t, http.StatusGone, kubeconfig.HTTPStatus(err, http.StatusInternalServerError))
+	assert.Equal(t, `getting context: context expired: "minikube": key not found`, err.Error())
+
+	// The conflicts and the taken names are name conflicts.
+	conflict := &kubeconfig.ContextConflict{Name: "minikube"}
+	assert.ErrorIs(t, conflict, kubeconfig.ErrNameConflict)
+	assert.Equal(t, kubeconfig.CodeNameConflict, kubeconfig.Code(errors.Join(conflict)))
+	assert.ErrorIs(t, kubeconfig.ErrRemoteNameTaken, kubeconfig.ErrNameConflict)
+
+	// The other errors have no code, and keep their status.
+	assert.Empty(t, kubeconfig.Code(errors.New("boom")))
+	assert.Equal(t, http.StatusBadRequest, kubeconfig.HTTPStatus(errors.New("boom"), http.StatusBadRequest))
+}
+
+func TestWriteError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	kubeconfig.WriteError(context.Background(), rr,
+		&kubeconfig.Error{Code: kubeconfig.CodeAuthExpired, Context: "minikube"}, http.StatusBadRequest)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response kubeconfig.ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, kubeconfig.CodeAuthExpired, response.Code)
+	assert.Equal(t, `authentication expired: "minikube"`, response.Error)
+
+	rr = httptest.NewRecorder()
+	kubeconfig.WriteError(context.Background(), rr, errors.New("boom"), http.StatusBadRequest)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error": "boom"}`, rr.Body.String())
+}
+
+func TestContextStoreExpiredContext(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	_, err := store.GetContext("missing")
+	require.ErrorIs(t, err, kubeconfig.ErrContextNotFound)
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(&kubeconfig.Context{Name: "stateless"}, "stateless",
+		50*time.Millisecond))
+
+	require.Eventually(t, func() bool {
+		_, err := store.GetContext("stateless")
+
+		return errors.Is(err, kubeconfig.ErrContextExpired)
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = store.UpdateContext("stateless", kubeconfig.ContextUpdate{})
+	require.ErrorIs(t, err, kubeconfig.ErrContextExpired)
+
+	// A context removed rather than expired isn't found.
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "stateless"}))
+	require.NoError(t, store.RemoveContext("stateless"))
+
+	_, err = store.GetContext("stateless")
+	require.ErrorIs(t, err, kubeconfig.ErrContextNotFound)
+}
@@ -0,0 +1,203 @@
+package kubeconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// ContextEventType is the type of a change of the contexts in a store.
+type ContextEventType string
+
+const (
+	// ContextAdded is the type of the events of the contexts added to a store.
+	ContextAdded ContextEventType = "ADDED"
+	// ContextModified is the type of the events of the contexts replaced in a store.
+	ContextModified ContextEventType = "MODIFIED"
+	// ContextRemoved is the type of the events of the contexts removed from a store.
+	ContextRemoved ContextEventType = "REMOVED"
+	// ContextExpiring is the type of the events of the contexts about to expire.
+	ContextExpiring ContextEventType = "EXPIRING"
+	// ContextExpired is the type of the events of the contexts whose ttl ran out.
+	ContextExpired ContextEventType = "EXPIRED"
+)
+
+// ExpiryWarning is how long before a context expires its watchers are told it is expiring.
+// Contexts with a ttl under twice as long are told halfway through it.
+const ExpiryWarning = time.Minute
+
+// ExpiredMemory is how long the store tells the contexts that expired apart from the ones
+// that never existed, returning ErrContextExpired for them.
+const ExpiredMemory = time.Hour
+
+// watchBufferSize is the number of events buffered for a watcher before they are dropped.
+const watchBufferSize = 64
+
+// ContextEvent is a change of the contexts in a store.
+type ContextEvent struct {
+	// Type is the type of the change.
+	Type ContextEventType
+	// Key is the key of the context in the store.
+	Key string
+	// Context is the context, as it was before it was removed for the removals.
+	Context *Context
+	// ExpiresAt is when the context expires, for the contexts stored with a ttl.
+	ExpiresAt time.Time
+}
+
+// expiry tracks the ttl of a context, to tell the watchers when it's expiring and when it expired.
+type expiry struct {
+	context   *Context
+	expiresAt time.Time
+	warn      *time.Timer
+	expire    *time.Timer
+}
+
+// stop stops the timers of the expiry.
+func (e *expiry) stop() {
+	e.warn.Stop()
+	e.expire.Stop()
+}
+
+// Watch returns the changes of the contexts in the store until ctx is done, when the
+// channel is closed. The events are dropped if the watcher doesn't keep up with them.
+func (c *contextStore) Watch(ctx context.Context) <-chan ContextEvent {
+	ch := make(chan ContextEvent, watchBufferSize)
+
+	c.watchMu.Lock()
+	c.watchers[ch] = struct{}{}
+	c.watchMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.watchMu.Lock()
+		delete(c.watchers, ch)
+		close(ch)
+		c.watchMu.Unlock()
+	}()
+
+	return ch
+}
+
+// emit sends the event to the watchers.
+func (c *contextStore) emit(event ContextEvent) {
+	// The contexts about to expire are still there.
+	if event.Type != ContextExpiring {
+		c.revision.Add(1)
+	}
+
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+
+	for ch := range c.watchers {
+		select {
+		case ch <- event:
+		default:
+			logger.Log(logger.LevelWarn, map[string]string{"key": event.Key, "type": string(event.Type)},
+				nil, "dropping context event for slow watcher")
+		}
+	}
+}
+
+// schedule tells the watchers when the context at key expires in ttl, replacing its previous
+// expiry. c.mu must be held.
+func (c *contextStore) schedule(key string, headlampContext *Context, ttl time.Duration) {
+	c.unschedule(key)
+
+	if ttl <= 0 {
+		return
+	}
+
+	exp := &expiry{context: headlampContext, expiresAt: time.Now().Add(ttl)}
+
+	exp.warn = time.AfterFunc(ttl-min(ExpiryWarning, ttl/2), func() {
+		c.expiring(key, exp)
+	})
+	exp.expire = time.AfterFunc(ttl, func() {
+		c.expired(key, exp)
+	})
+
+	c.expiries[key] = exp
+}
+
+// unschedule stops tracking the expiry of the context at key. c.mu must be held.
+func (c *contextStore) unschedule(key string) {
+	if exp, ok := c.expiries[key]; ok {
+		exp.stop()
+		delete(c.expiries, key)
+	}
+}
+
+// expiring tells the watchers the context at key is about to expire.
+func (c *contextStore) expiring(key string, exp *expiry) {
+	c.mu.Lock()
+	current := c.expiries[key] == exp
+	c.mu.Unlock()
+
+	if current {
+		c.emit(ContextEvent{Type: ContextExpiring, Key: key, Context: exp.context, ExpiresAt: exp.expiresAt})
+	}
+}
+
+// expired drops the context at key from the indexes once its ttl ran out, and tells the watchers.
+func (c *contextStore) expired(key string, exp *expiry) {
+	c.mu.Lock()
+
+	// The context was replaced, removed or had its ttl updated since.
+	if c.expiries[key] != exp {
+		c.mu.Unlock()
+
+		return
+	}
+
+	delete(c.expiries, key)
+
+	if _, err := c.cache.Get(context.Background(), key); err == nil {
+		c.mu.Unlock()
+
+		return
+	}
+
+	c.unindex(key)
+	c.rememberExpired(key, exp.expiresAt)
+	c.mu.Unlock()
+
+	c.emit(ContextEvent{Type: ContextExpired, Key: key, Context: exp.context, ExpiresAt: exp.expiresAt})
+}
+
+// rememberExpired remembers the context at key expired at expiresAt for ExpiredMemory,
+// forgetting the ones that expired before that. c.mu must be held.
+func (c *contextStore) rememberExpired(key string, expiresAt time.Time) {
+	for expiredKey, at := range c.expiredAt {
+		if time.Since(at) > ExpiredMemory {
+			delete(c.expiredAt, expiredKey)
+		}
+	}
+
+	c.expiredAt[key] = expiresAt
+}
+
+// notFound returns the error for a context missing at key: ErrContextExpired if it expired
+// less than ExpiredMemory ago, or ErrContextNotFound.
+func (c *contextStore) notFound(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expired := false
+
+	if at, ok := c.expiredAt[key]; ok {
+		expired = time.Since(at) <= ExpiredMemory
+	} else if exp, ok := c.expiries[key]; ok {
+		// The context expired, but the watchers weren't told yet.
+		expired = !time.Now().Before(exp.expiresAt)
+	}
+
+	if expired {
+		return &Error{Code: CodeContextExpired, Context: key, Err: cache.ErrNotFound}
+	}
+
+	return &Error{Code: CodeContextNotFound, Context: key, Err: cache.ErrNotFound}
+}
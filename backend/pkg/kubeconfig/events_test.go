@@ -0,0 +1,102 @@
+package kubeconfig_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextEvent returns the next event of the watch, failing the test if there's none in time.
+func nextEvent(t *testing.T, events <-chan kubeconfig.ContextEvent) kubeconfig.ContextEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no context event")
+
+		return kubeconfig.ContextEvent{}
+	}
+}
+
+func TestContextStoreWatch(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := store.Watch(ctx)
+
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube"}))
+
+	event := nextEvent(t, events)
+	assert.Equal(t, kubeconfig.ContextAdded, event.Type)
+	assert.Equal(t, "minikube", event.Key)
+	assert.True(t, event.ExpiresAt.IsZero())
+
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube"}))
+	assert.Equal(t, kubeconfig.ContextModified, nextEvent(t, events).Type)
+
+	require.NoError(t, store.RemoveContext("minikube"))
+
+	event = nextEvent(t, events)
+	assert.Equal(t, kubeconfig.ContextRemoved, event.Type)
+	assert.Equal(t, "minikube", event.Context.Name)
+
+	// Removing a missing context isn't a change.
+	require.NoError(t, store.RemoveContext("minikube"))
+
+	cancel()
+
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestContextStoreWatchExpiry(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := store.Watch(ctx)
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "minikube", Internal: true}, "minikube-user1", 200*time.Millisecond))
+
+	event := nextEvent(t, events)
+	assert.Equal(t, kubeconfig.ContextAdded, event.Type)
+	assert.False(t, event.ExpiresAt.IsZero())
+
+	// Contexts with a short ttl are told halfway through it.
+	event = nextEvent(t, events)
+	assert.Equal(t, kubeconfig.ContextExpiring, event.Type)
+	assert.Equal(t, "minikube-user1", event.Key)
+	assert.WithinDuration(t, time.Now().Add(100*time.Millisecond), event.ExpiresAt, 100*time.Millisecond)
+
+	event = nextEvent(t, events)
+	assert.Equal(t, kubeconfig.ContextExpired, event.Type)
+	assert.Equal(t, "minikube", event.Context.Name)
+
+	// Updating the ttl of a context postpones its expiry.
+	require.NoError(t, store.AddContextWithKeyAndTTL(
+		&kubeconfig.Context{Name: "kind", Internal: true}, "kind-user1", time.Hour))
+	assert.Equal(t, kubeconfig.ContextAdded, nextEvent(t, events).Type)
+
+	require.NoError(t, store.UpdateTTL("kind-user1", 200*time.Millisecond))
+	assert.Equal(t, kubeconfig.ContextExpiring, nextEvent(t, events).Type)
+
+	require.NoError(t, store.UpdateTTL("kind-user1", time.Hour))
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected %s event", event.Type)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Removed contexts don't expire.
+	require.NoError(t, store.RemoveContext("kind-user1"))
+	assert.Equal(t, kubeconfig.ContextRemoved, nextEvent(t, events).Type)
+}
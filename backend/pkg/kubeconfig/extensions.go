@@ -0,0 +1,51 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// headlampInfoExtension is the name of the extension with the settings of Headlamp for a
+// context, which isn't passed through in the Extensions of the context.
+const headlampInfoExtension = "headlamp_info"
+
+// kubeConfigExtensions returns the extensions of the cluster and the context, as JSON, other
+// than headlamp_info. The ones of the context replace the ones of the cluster with the same
+// name. The extensions that can't be encoded are dropped.
+func kubeConfigExtensions(cluster *api.Cluster, kubeContext *api.Context) map[string]json.RawMessage {
+	var extensions map[string]json.RawMessage
+
+	add := func(from map[string]k8sruntime.Object) {
+		for name, extension := range from {
+			if name == headlampInfoExtension || extension == nil {
+				continue
+			}
+
+			raw, err := json.Marshal(extension)
+			if err != nil {
+				logger.Log(logger.LevelWarn, map[string]string{"extension": name}, err, "encoding kubeconfig extension")
+
+				continue
+			}
+
+			if extensions == nil {
+				extensions = map[string]json.RawMessage{}
+			}
+
+			extensions[name] = raw
+		}
+	}
+
+	if cluster != nil {
+		add(cluster.Extensions)
+	}
+
+	if kubeContext != nil {
+		add(kubeContext.Extensions)
+	}
+
+	return extensions
+}
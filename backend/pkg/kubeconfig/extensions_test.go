@@ -0,0 +1,50 @@
+package kubeconfig_test
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestContextExtensions(t *testing.T) {
+	extension := func(raw string) runtime.Object {
+		return &runtime.Unknown{Raw: []byte(raw), ContentType: runtime.ContentTypeJSON}
+	}
+
+	config := &api.Config{
+		Clusters: map[string]*api.Cluster{
+			"prod": {
+				Server: "https://prod:6443",
+				Extensions: map[string]runtime.Object{
+					"example.com/owner":  extension(`{"team":"platform"}`),
+					"example.com/region": extension(`{"name":"eu-west-1"}`),
+				},
+			},
+		},
+		Contexts: map[string]*api.Context{
+			"prod": {
+				Cluster: "prod",
+				Extensions: map[string]runtime.Object{
+					"example.com/owner": extension(`{"team":"payments"}`),
+					"headlamp_info":     extension(`{"customName":"production"}`),
+				},
+			},
+		},
+	}
+
+	contexts, errs := kubeconfig.LoadContextsFromAPIConfig(config, true)
+	require.Empty(t, errs)
+	require.Len(t, contexts, 1)
+
+	extensions := contexts[0].Extensions
+	require.Len(t, extensions, 2)
+
+	// The extensions of the context replace the ones of the cluster.
+	assert.JSONEq(t, `{"team":"payments"}`, string(extensions["example.com/owner"]))
+	assert.JSONEq(t, `{"name":"eu-west-1"}`, string(extensions["example.com/region"]))
+	assert.NotContains(t, extensions, "headlamp_info")
+}
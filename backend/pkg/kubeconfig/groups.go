@@ -0,0 +1,230 @@
+package kubeconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var (
+	// ErrInvalidContextGroup is returned when setting an invalid context group.
+	ErrInvalidContextGroup = errors.New("invalid context group")
+	// ErrContextGroupNotFound is returned when there's no context group with a name.
+	ErrContextGroupNotFound = errors.New("context group not found")
+)
+
+// ContextGroup is a folder of contexts, so the clients can list many clusters as a hierarchy
+// rather than a flat list. A context can be in several groups.
+type ContextGroup struct {
+	// Name is the name of the group, a DNS label.
+	Name string `json:"name"`
+	// Members are the names of the contexts in the group. The ones not in the store are
+	// ignored.
+	Members []string `json:"members,omitempty"`
+	// Selector is a label selector, like env=prod, adding the contexts whose labels match it
+	// to the group. Only the dynamic clusters have labels.
+	Selector string `json:"selector,omitempty"`
+
+	// selector is Selector parsed, set by the store when the group is set.
+	selector labels.Selector
+}
+
+// contextGroupsFileMode is the mode of the file the groups are persisted to.
+const contextGroupsFileMode = 0o600
+
+// Validate checks the group: the name must be a DNS label and the selector a valid label
+// selector.
+func (g ContextGroup) Validate() error {
+	_, err := g.parse()
+
+	return err
+}
+
+// parse validates the group and returns its selector parsed.
+func (g ContextGroup) parse() (labels.Selector, error) {
+	if errs := validation.IsDNS1123Label(g.Name); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: name %q: %s", ErrInvalidContextGroup, g.Name, errs[0])
+	}
+
+	selector, err := labels.Parse(g.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: selector: %v", ErrInvalidContextGroup, err)
+	}
+
+	return selector, nil
+}
+
+// Contains tells whether the context is in the group, as a member or by its labels.
+func (g ContextGroup) Contains(headlampContext *Context) bool {
+	if slices.Contains(g.Members, headlampContext.Name) {
+		return true
+	}
+
+	if g.Selector == "" {
+		return false
+	}
+
+	// The groups of the store have their selector parsed already.
+	selector := g.selector
+	if selector == nil {
+		var err error
+		if selector, err = labels.Parse(g.Selector); err != nil {
+			return false
+		}
+	}
+
+	return selector.Matches(labels.Set(headlampContext.Labels))
+}
+
+// SetContextGroup adds the group to the store, or replaces the one with its name. If the
+// store has a groups file, the groups are saved to it.
+func (c *contextStore) SetContextGroup(group ContextGroup) error {
+	selector, err := group.parse()
+	if err != nil {
+		return err
+	}
+
+	group.Members = slices.Clone(group.Members)
+	group.selector = selector
+
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+
+	groups := maps.Clone(c.groups)
+	groups[group.Name] = group
+
+	return c.saveGroups(groups)
+}
+
+// RemoveContextGroup removes the group with the name from the store, leaving its contexts
+// as they are. If the store has a groups file, the groups are saved to it.
+func (c *contextStore) RemoveContextGroup(name string) error {
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+
+	if _, ok := c.groups[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrContextGroupNotFound, name)
+	}
+
+	groups := maps.Clone(c.groups)
+	delete(groups, name)
+
+	return c.saveGroups(groups)
+}
+
+// ContextGroups returns the groups of the store, sorted by name.
+func (c *contextStore) ContextGroups() []ContextGroup {
+	c.groupsMu.RLock()
+	defer c.groupsMu.RUnlock()
+
+	return sortedGroups(c.groups)
+}
+
+// SetContextGroupsFile loads the groups of the store from the JSON file at path, replacing
+// the ones it has, and saves them to it whenever they change from then on. A missing file
+// is the same as an empty one, and is created on the first change.
+func (c *contextStore) SetContextGroupsFile(path string) error {
+	groups := map[string]ContextGroup{}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading context groups: %w", err)
+	}
+
+	if err == nil {
+		var stored []ContextGroup
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return fmt.Errorf("parsing context groups %q: %w", path, err)
+		}
+
+		for _, group := range stored {
+			if group.selector, err = group.parse(); err != nil {
+				return fmt.Errorf("loading context groups %q: %w", path, err)
+			}
+
+			groups[group.Name] = group
+		}
+	}
+
+	c.groupsMu.Lock()
+	defer c.groupsMu.Unlock()
+
+	c.groupsFile = path
+	c.groups = groups
+
+	return nil
+}
+
+// saveGroups writes the groups to the groups file, if any, and makes them the groups of the
+// store once they're written. c.groupsMu must be held.
+func (c *contextStore) saveGroups(groups map[string]ContextGroup) error {
+	if c.groupsFile != "" {
+		data, err := json.MarshalIndent(sortedGroups(groups), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := writeFileAtomic(c.groupsFile, data, contextGroupsFileMode); err != nil {
+			return fmt.Errorf("saving context groups: %w", err)
+		}
+	}
+
+	c.groups = groups
+
+	return nil
+}
+
+// sortedGroups returns copies of the groups, sorted by name.
+func sortedGroups(byName map[string]ContextGroup) []ContextGroup {
+	groups := make([]ContextGroup, 0, len(byName))
+	for _, group := range byName {
+		group.Members = slices.Clone(group.Members)
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
+// writeFileAtomic writes the data to a temporary file next to path, which is then renamed to
+// path, so that path is never left partially written.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+
+	return err
+}
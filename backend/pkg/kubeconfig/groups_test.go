@@ -0,0 +1,94 @@
+package kubeconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextGroupValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   kubeconfig.ContextGroup
+		wantErr bool
+	}{
+		{name: "members", group: kubeconfig.ContextGroup{Name: "prod", Members: []string{"a"}}},
+		{name: "selector", group: kubeconfig.ContextGroup{Name: "prod", Selector: "env in (prod,staging)"}},
+		{name: "no name", group: kubeconfig.ContextGroup{}, wantErr: true},
+		{name: "invalid name", group: kubeconfig.ContextGroup{Name: "Prod/EU"}, wantErr: true},
+		{name: "invalid selector", group: kubeconfig.ContextGroup{Name: "prod", Selector: "env in"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.group.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, kubeconfig.ErrInvalidContextGroup)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestContextGroupContains(t *testing.T) {
+	group := kubeconfig.ContextGroup{Name: "prod", Members: []string{"a"}, Selector: "env=prod"}
+
+	assert.True(t, group.Contains(&kubeconfig.Context{Name: "a"}))
+	assert.True(t, group.Contains(&kubeconfig.Context{Name: "b", Labels: map[string]string{"env": "prod"}}))
+	assert.False(t, group.Contains(&kubeconfig.Context{Name: "c", Labels: map[string]string{"env": "dev"}}))
+	assert.False(t, kubeconfig.ContextGroup{Name: "empty"}.Contains(&kubeconfig.Context{Name: "a"}))
+}
+
+func TestContextStoreGroups(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	require.NoError(t, store.SetContextGroup(kubeconfig.ContextGroup{Name: "prod", Members: []string{"a"}}))
+	require.NoError(t, store.SetContextGroup(kubeconfig.ContextGroup{Name: "dev", Selector: "env=dev"}))
+	require.ErrorIs(t, store.SetContextGroup(kubeconfig.ContextGroup{Name: ""}), kubeconfig.ErrInvalidContextGroup)
+
+	groups := store.ContextGroups()
+	require.Len(t, groups, 2)
+	assert.Equal(t, "dev", groups[0].Name)
+	assert.Equal(t, "prod", groups[1].Name)
+
+	// Setting a group again replaces it.
+	require.NoError(t, store.SetContextGroup(kubeconfig.ContextGroup{Name: "prod", Members: []string{"b"}}))
+	assert.Equal(t, []string{"b"}, store.ContextGroups()[1].Members)
+
+	require.NoError(t, store.RemoveContextGroup("dev"))
+	require.ErrorIs(t, store.RemoveContextGroup("dev"), kubeconfig.ErrContextGroupNotFound)
+	assert.Len(t, store.ContextGroups(), 1)
+}
+
+func TestContextStoreGroupsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups", "context-groups.json")
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.SetContextGroupsFile(path))
+	assert.Empty(t, store.ContextGroups())
+
+	require.NoError(t, store.SetContextGroup(kubeconfig.ContextGroup{Name: "prod", Members: []string{"a"}}))
+	require.NoError(t, store.SetContextGroup(kubeconfig.ContextGroup{Name: "dev", Selector: "env=dev"}))
+	require.NoError(t, store.RemoveContextGroup("prod"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Another store loads the groups saved by the first one.
+	reloaded := kubeconfig.NewContextStore()
+	require.NoError(t, reloaded.SetContextGroupsFile(path))
+
+	groups := reloaded.ContextGroups()
+	require.Len(t, groups, 1)
+	assert.Equal(t, "dev", groups[0].Name)
+	assert.True(t, groups[0].Contains(&kubeconfig.Context{Name: "b", Labels: map[string]string{"env": "dev"}}))
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name": "Bad_Name"}]`), 0o600))
+	require.ErrorIs(t, kubeconfig.NewContextStore().SetContextGroupsFile(path), kubeconfig.ErrInvalidContextGroup)
+}
@@ -0,0 +1,38 @@
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInsecureContext is returned when adding a context skipping the TLS verification of its
+// API server to a store rejecting them.
+var ErrInsecureContext = errors.New("contexts skipping TLS verification are not allowed")
+
+// SkipsTLSVerify tells whether the context doesn't verify the TLS certificate of its API
+// server, with insecure-skip-tls-verify.
+func (c *Context) SkipsTLSVerify() bool {
+	return c.Cluster != nil && c.Cluster.InsecureSkipTLSVerify
+}
+
+// SetRejectInsecure sets whether the contexts skipping the TLS verification of their API
+// server are rejected when they're added from then on. The contexts already in the store
+// are kept.
+func (c *contextStore) SetRejectInsecure(reject bool) {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	c.rejectInsecure = reject
+}
+
+// checkInsecure flags the context as Insecure if it skips the TLS verification, and returns
+// ErrInsecureContext if the store rejects such contexts. c.quotaMu must be held.
+func (c *contextStore) checkInsecure(headlampContext *Context) error {
+	headlampContext.Insecure = headlampContext.SkipsTLSVerify()
+
+	if headlampContext.Insecure && c.rejectInsecure {
+		return fmt.Errorf("%w: %q", ErrInsecureContext, headlampContext.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package kubeconfig_test
+
+import (
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// insecureContext returns a context skipping the TLS verification of its API server.
+func insecureContext(name string) *kubeconfig.Context {
+	return &kubeconfig.Context{
+		Name:        name,
+		KubeContext: &api.Context{Cluster: name},
+		Cluster:     &api.Cluster{Server: "https://" + name + ":6443", InsecureSkipTLSVerify: true},
+	}
+}
+
+func TestContextStoreInsecure(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	require.NoError(t, store.AddContext(insecureContext("insecure")))
+	require.NoError(t, store.AddContext(&kubeconfig.Context{
+		Name:    "secure",
+		Cluster: &api.Cluster{Server: "https://secure:6443"},
+	}))
+
+	insecure, err := store.GetContext("insecure")
+	require.NoError(t, err)
+	assert.True(t, insecure.Insecure)
+
+	secure, err := store.GetContext("secure")
+	require.NoError(t, err)
+	assert.False(t, secure.Insecure)
+}
+
+func TestContextStoreRejectInsecure(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetRejectInsecure(true)
+
+	err := store.AddContext(insecureContext("insecure"))
+	require.ErrorIs(t, err, kubeconfig.ErrInsecureContext)
+
+	err = store.AddContextWithKeyAndTTL(insecureContext("stateless"), "stateless-user1", 0)
+	require.ErrorIs(t, err, kubeconfig.ErrInsecureContext)
+
+	contexts, err := store.GetContexts()
+	require.NoError(t, err)
+	assert.Empty(t, contexts)
+}
@@ -1,15 +1,20 @@
 package kubeconfig
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,6 +24,7 @@ import (
 
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/exec"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/apis/clientauthentication"
 	rest "k8s.io/client-go/rest"
@@ -32,8 +38,17 @@ const (
 	KubeConfig = 1 << iota
 	DynamicCluster
 	InCluster
+	Tunnel
+	Remote
 )
 
+// DialFunc dials the connections to the API server of a context.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// proxyLogSampler samples the proxy setup logs, which are logged for every context
+// loaded and can flood the output with hundreds of contexts.
+var proxyLogSampler = logger.NewSampler(10, 100, time.Minute)
+
 // Context contains all information related to a kubernetes context.
 type Context struct {
 	Name        string                 `json:"name"`
@@ -49,6 +64,40 @@ type Context struct {
 	KubeConfigPath string `json:"kubeConfigPath"`
 	// ClusterID is the unique identifier for the cluster, consisting of the filepath and context name.
 	ClusterID string `json:"clusterID"`
+	// OriginalName is the name of the context in the kubeconfig, before it was made DNS friendly.
+	OriginalName string `json:"originalName,omitempty"`
+	// Owner identifies who added the dynamic cluster, by what authenticated the request rather
+	// than the user ID the client sends. The quota of the dynamic clusters per user is enforced
+	// by it.
+	Owner string `json:"-"`
+	// DisplayMetadata is how the context is displayed to all the users.
+	DisplayMetadata
+	// Labels are the labels of a dynamic cluster, set when updating it.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Extensions are the extensions of the cluster and the context in the kubeconfig, other than
+	// headlamp_info, like the metadata written by the tooling provisioning the cluster. They're
+	// passed through as they are, and can't be changed through Headlamp.
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+	// Namespace is the namespace set on the context in its kubeconfig, if any, which kubectl
+	// uses for the requests not giving one.
+	Namespace string `json:"namespace,omitempty"`
+	// Insecure tells whether the context skips the verification of the TLS certificate of its
+	// API server, with insecure-skip-tls-verify. It is set when the context is stored.
+	Insecure bool `json:"insecure,omitempty"`
+	// tokenProxy proxies the requests made with a token instead of the credentials of the context.
+	// It's set up on the first such request, possibly by concurrent ones, for the contexts of a
+	// store, which get it when they're stored. The other contexts set up one for every request.
+	tokenProxy *lazyProxy
+	// dial, when set, dials the connections to the API server instead of the network,
+	// e.g. through the reverse tunnel of an agent.
+	dial DialFunc
+}
+
+// lazyProxy is a reverse proxy set up on its first use.
+type lazyProxy struct {
+	once  sync.Once
+	proxy *httputil.ReverseProxy
+	err   error
 }
 
 type OidcConfig struct {
@@ -71,6 +120,12 @@ type CustomObject struct {
 	metav1.TypeMeta
 	metav1.ObjectMeta
 	CustomName string `json:"customName"`
+	// DryRun makes every mutating request proxied to the context a dry run.
+	DryRun bool `json:"dryRun,omitempty"`
+	// RecordEvents records the actions of the backend on the context as Kubernetes Events in its
+	// cluster, or not, whatever the default.
+	RecordEvents *bool `json:"recordEvents,omitempty"`
+	DisplayMetadata
 }
 
 // DeepCopyObject returns a copy of the CustomObject.
@@ -88,6 +143,14 @@ func (o *CustomObject) DeepCopy() *CustomObject {
 	o.ObjectMeta.DeepCopyInto(&copied.ObjectMeta)
 	copied.TypeMeta = o.TypeMeta
 	copied.CustomName = o.CustomName
+	copied.DryRun = o.DryRun
+
+	if o.RecordEvents != nil {
+		recordEvents := *o.RecordEvents
+		copied.RecordEvents = &recordEvents
+	}
+
+	copied.DisplayMetadata = o.DisplayMetadata
 
 	return copied
 }
@@ -197,7 +260,23 @@ func (c *Context) RESTConfig() (*rest.Config, error) {
 		return nil, errors.New("clientConfig is nil")
 	}
 
-	return clientConfig.ClientConfig()
+	conf, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.dial != nil {
+		conf.Dial = c.dial
+	}
+
+	return conf, nil
+}
+
+// SetDialer makes the connections to the API server of the context go through dial.
+func (c *Context) SetDialer(dial DialFunc) {
+	c.dial = dial
+	c.proxy = nil
+	c.tokenProxy = nil
 }
 
 // makeTransportFor creates an HTTP transport configuration with special handling for
@@ -306,6 +385,70 @@ func (c *Context) ProxyRequest(writer http.ResponseWriter, request *http.Request
 	return nil
 }
 
+// ProxyRequestWithToken proxies the request to the cluster with the bearer token instead of
+// the credentials of the context, which are left out of the request altogether, like its
+// client certificate, so the cluster only sees the token. The token is only used for this
+// request.
+func (c *Context) ProxyRequestWithToken(writer http.ResponseWriter, request *http.Request, token string) error {
+	proxy, err := c.getTokenProxy()
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+	proxy.ServeHTTP(writer, request)
+
+	return nil
+}
+
+// getTokenProxy returns the reverse proxy of the requests made with a token, setting it up
+// on the first call if the context has a lazyProxy for it.
+func (c *Context) getTokenProxy() (*httputil.ReverseProxy, error) {
+	lazy := c.tokenProxy
+	if lazy == nil {
+		return c.newTokenProxy()
+	}
+
+	lazy.once.Do(func() {
+		lazy.proxy, lazy.err = c.newTokenProxy()
+	})
+
+	return lazy.proxy, lazy.err
+}
+
+// prepareTokenProxy gives the context a lazyProxy for its token proxy, if it has none. It must
+// be called before the context is shared, as when it's stored.
+func (c *Context) prepareTokenProxy() {
+	if c.tokenProxy == nil {
+		c.tokenProxy = &lazyProxy{}
+	}
+}
+
+// newTokenProxy creates the reverse proxy of the requests made with a token, which connects
+// to the cluster like the context without its credentials.
+func (c *Context) newTokenProxy() (*httputil.ReverseProxy, error) {
+	URL, err := url.Parse(c.Cluster.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	restConf, err := c.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	roundTripper, err := rest.TransportFor(rest.AnonymousClientConfig(restConf))
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(URL)
+	proxy.Transport = otelhttp.NewTransport(roundTripper)
+	proxy.ErrorHandler = c.proxyErrorHandler
+
+	return proxy, nil
+}
+
 // ClientSetWithToken returns a kubernetes clientset for the context.
 func (c *Context) ClientSetWithToken(token string) (*kubernetes.Clientset, error) {
 	restConf, err := c.RESTConfig()
@@ -320,6 +463,26 @@ func (c *Context) ClientSetWithToken(token string) (*kubernetes.Clientset, error
 	return kubernetes.NewForConfig(restConf)
 }
 
+// IsDryRun tells whether the mutating requests proxied to the context are to be dry runs,
+// as set in the headlamp_info extension of the context.
+func (c *Context) IsDryRun() bool {
+	customObj, ok := headlampInfo(c.KubeContext)
+
+	return ok && customObj.DryRun
+}
+
+// RecordsEvents tells whether the actions of the backend on the context, like node drains, are
+// recorded as Kubernetes Events in its cluster, as set in the headlamp_info extension of the
+// context, or byDefault if it isn't.
+func (c *Context) RecordsEvents(byDefault bool) bool {
+	customObj, ok := headlampInfo(c.KubeContext)
+	if !ok || customObj.RecordEvents == nil {
+		return byDefault
+	}
+
+	return *customObj.RecordEvents
+}
+
 // SourceStr returns the source from which the context was loaded.
 func (c *Context) SourceStr() string {
 	switch c.Source {
@@ -329,6 +492,10 @@ func (c *Context) SourceStr() string {
 		return "dynamic_cluster"
 	case InCluster:
 		return "incluster"
+	case Tunnel:
+		return "tunnel"
+	case Remote:
+		return "remote"
 	default:
 		return "unknown"
 	}
@@ -343,22 +510,43 @@ func (c *Context) SetupProxy() error {
 
 	proxy := httputil.NewSingleHostReverseProxy(URL)
 
+	// A nil round tripper falls back to http.DefaultTransport.
+	var roundTripper http.RoundTripper
+
 	restConf, err := c.RESTConfig()
 	if err == nil {
-		roundTripper, err := makeTransportFor(restConf)
-		if err == nil {
-			proxy.Transport = roundTripper
+		if rt, err := makeTransportFor(restConf); err == nil {
+			roundTripper = rt
 		}
 	}
 
+	// Trace the upstream calls, propagating the trace context to the API server in the headers.
+	proxy.Transport = otelhttp.NewTransport(roundTripper)
+	proxy.ErrorHandler = c.proxyErrorHandler
+
 	c.proxy = proxy
 
-	logger.Log(logger.LevelInfo, map[string]string{"context": c.Name, "clusterURL": c.Cluster.Server},
+	proxyLogSampler.Log(logger.LevelInfo, map[string]string{"context": c.Name, "clusterURL": c.Cluster.Server},
 		nil, "Proxy setup")
 
 	return nil
 }
 
+// proxyErrorHandler answers the proxied requests that failed with ErrClusterUnreachable, a
+// 502 like the default handler, except the ones cancelled by their client, which has gone
+// and can't be answered.
+func (c *Context) proxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		logger.LogCtx(r.Context(), logger.LevelDebug, map[string]string{"url": r.URL.Path},
+			err, "proxied request cancelled by the client")
+
+		return
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelError, map[string]string{"url": r.URL.Path}, err, "proxying request")
+	WriteError(r.Context(), w, &Error{Code: CodeClusterUnreachable, Context: c.Name, Err: err}, http.StatusBadGateway)
+}
+
 // AuthType returns the authentication type for the context.
 func (c *Context) AuthType() string {
 	if (c.OidcConf != nil) || (c.AuthInfo != nil && c.AuthInfo.AuthProvider != nil) {
@@ -368,10 +556,13 @@ func (c *Context) AuthType() string {
 	return ""
 }
 
-// ContextLoadError represents an error associated with a specific context.
+// ContextLoadError represents an error associated with a specific context. The errors of the
+// kubeconfig files that couldn't be loaded at all have no ContextName.
 type ContextLoadError struct {
 	ContextName string
-	Error       error
+	// KubeConfigPath is the path of the kubeconfig file of the context, if it was loaded from one.
+	KubeConfigPath string
+	Error          error
 }
 
 // LoadContextsFromFile loads contexts from a kubeconfig file.
@@ -398,6 +589,10 @@ func LoadContextsFromFile(kubeConfigPath string, source int) ([]Context, []Conte
 		contexts[i].ClusterID = fmt.Sprintf("%s+%s", kubeConfigPath, contexts[i].Name)
 	}
 
+	for i := range contextErrors {
+		contextErrors[i].KubeConfigPath = kubeConfigPath
+	}
+
 	return contexts, contextErrors, nil
 }
 
@@ -416,7 +611,12 @@ func LoadContextsFromBase64String(kubeConfig string, source int) ([]Context, []C
 	return loadContextsFromData(kubeConfigByte, source, skipProxySetup)
 }
 
-// LoadContextsFromMultipleFiles loads contexts from the given kubeconfig files.
+// LoadContextsFromMultipleFiles loads contexts from the given kubeconfig files. A file that
+// can't be read or parsed doesn't stop the others from being loaded: it's returned as a
+// ContextLoadError without a ContextName. The contexts of different files getting the same
+// name but pointing at different servers are resolved with the policy set with
+// SetConflictPolicy, and their conflicts returned as ContextLoadErrors whose Error is a
+// *ContextConflict.
 func LoadContextsFromMultipleFiles(kubeConfigs string, source int) ([]Context, []ContextLoadError, error) {
 	var contexts []Context
 
@@ -426,13 +626,24 @@ func LoadContextsFromMultipleFiles(kubeConfigs string, source int) ([]Context, [
 	for _, kubeConfigPath := range kubeConfigPaths {
 		kubeConfigContexts, errs, err := LoadContextsFromFile(kubeConfigPath, source)
 		if err != nil {
-			return nil, nil, err
+			contextErrors = append(contextErrors, ContextLoadError{KubeConfigPath: kubeConfigPath, Error: err})
+
+			continue
 		}
 
 		contexts = append(contexts, kubeConfigContexts...)
 		contextErrors = append(contextErrors, errs...)
 	}
 
+	contexts, conflicts := resolveConflicts(contexts, currentConflictPolicy())
+	for _, conflict := range conflicts {
+		contextErrors = append(contextErrors, ContextLoadError{
+			ContextName:    conflict.Name,
+			KubeConfigPath: conflict.Other.KubeConfigPath,
+			Error:          conflict,
+		})
+	}
+
 	return contexts, contextErrors, nil
 }
 
@@ -457,11 +668,17 @@ func loadContextsFromData(data []byte, source int, skipProxySetup bool) ([]Conte
 	}
 
 	// Process each context
-	for _, rawContext := range rawContexts {
+	for i, rawContext := range rawContexts {
 		context, err := ProcessContext(rawContext, kubeconfig, source, skipProxySetup)
 		if err != nil {
+			// The contexts without a name are reported by their position in the file.
+			contextName := context.Name
+			if contextName == "" {
+				contextName = fmt.Sprintf("contexts[%d]", i)
+			}
+
 			contextErrors = append(contextErrors, ContextLoadError{
-				ContextName: context.Name,
+				ContextName: contextName,
 				Error:       err,
 			})
 
@@ -514,10 +731,10 @@ func ProcessContext(
 	var errs []error
 
 	var context Context
-	// Extract context information
+	// Extract context information, there's nothing else to process without it
 	contextMap, contextName, err := extractContextInfo(rawContext)
 	if err != nil {
-		errs = append(errs, err)
+		return context, err
 	}
 
 	// Extract cluster and user names
@@ -550,6 +767,11 @@ func ProcessContext(
 		errs = append(errs, err)
 	}
 
+	// The invalid contexts are reported by their name.
+	if len(errs) > 0 && context.Name == "" {
+		context.Name = contextName
+	}
+
 	return context, errors.Join(errs...)
 }
 
@@ -770,7 +992,10 @@ func toStringKeyMap(m map[interface{}]interface{}) map[interface{}]interface{} {
 
 // getCluster gets the cluster details from the kubeconfig.
 func getCluster(kubeconfig map[string]interface{}, clusterName string) (map[interface{}]interface{}, error) {
-	clusters := kubeconfig["clusters"].([]interface{})
+	clusters, ok := kubeconfig["clusters"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid or missing clusters in kubeconfig")
+	}
 
 	for _, cluster := range clusters {
 		clusterMap, ok := cluster.(map[interface{}]interface{})
@@ -850,16 +1075,22 @@ func convertToContext(contextName string, clientConfig *api.Config, source int,
 
 	authInfo := clientConfig.AuthInfos[context.AuthInfo]
 
-	// Make contextName DNS friendly.
-	contextName = makeDNSFriendly(contextName)
+	originalName := contextName
+
+	// Make contextName safe to use in URLs.
+	contextName = currentNamingStrategy().ContextName(contextName)
 
 	newContext := Context{
-		Name:        contextName,
-		KubeContext: context,
-		Cluster:     cluster,
-		AuthInfo:    authInfo,
-		Source:      source,
+		Name:         contextName,
+		KubeContext:  context,
+		Cluster:      cluster,
+		AuthInfo:     authInfo,
+		Source:       source,
+		OriginalName: originalName,
+		Namespace:    context.Namespace,
+		Extensions:   kubeConfigExtensions(cluster, context),
 	}
+	newContext.DisplayMetadata = displayMetadata(context)
 
 	if !skipProxySetup {
 		err := newContext.SetupProxy()
@@ -886,15 +1117,21 @@ func LoadContextsFromAPIConfig(config *api.Config, skipProxySetup bool) ([]Conte
 		// Note: nil authInfo is valid as authInfo can be provided by token.
 		authInfo := config.AuthInfos[context.AuthInfo]
 
-		// Make contextName DNS friendly.
-		contextName = makeDNSFriendly(contextName)
+		originalName := contextName
+
+		// Make contextName safe to use in URLs.
+		contextName = currentNamingStrategy().ContextName(contextName)
 
 		context := Context{
-			Name:        contextName,
-			KubeContext: context,
-			Cluster:     cluster,
-			AuthInfo:    authInfo,
+			Name:         contextName,
+			KubeContext:  context,
+			Cluster:      cluster,
+			AuthInfo:     authInfo,
+			OriginalName: originalName,
+			Namespace:    context.Namespace,
+			Extensions:   kubeConfigExtensions(cluster, context),
 		}
+		context.DisplayMetadata = displayMetadata(context.KubeContext)
 
 		if !skipProxySetup {
 			err := context.SetupProxy()
@@ -1021,14 +1258,27 @@ func LoadAndStoreKubeConfigs(kubeConfigStore ContextStore, kubeConfigs string, s
 
 		kubeConfigContext := kubeConfigContext
 
+		// The contexts the store refuses, e.g. insecure ones, are reported like the broken ones.
 		err := kubeConfigStore.AddContext(&kubeConfigContext)
 		if err != nil {
-			errs = append(errs, err)
+			contextErrors = append(contextErrors, ContextLoadError{
+				ContextName:    kubeConfigContext.Name,
+				KubeConfigPath: kubeConfigContext.KubeConfigPath,
+				Error:          err,
+			})
 		}
 	}
 
+	recordLoadErrors(kubeConfigStore, splitKubeConfigPath(kubeConfigs), contextErrors)
+
 	for _, contextError := range contextErrors {
-		errs = append(errs, fmt.Errorf("error in context %s: %v", contextError.ContextName, contextError.Error))
+		if contextError.ContextName == "" {
+			errs = append(errs, fmt.Errorf("error in kubeconfig %s: %w", contextError.KubeConfigPath, contextError.Error))
+
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("error in context %s: %w", contextError.ContextName, contextError.Error))
 	}
 
 	return errors.Join(errs...)
@@ -3,11 +3,13 @@ package kubeconfig_test
 import (
 	"context"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/config"
@@ -16,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd/api"
 )
 
 var kubeConfigFilePath = filepath.Join(getTestDataPath(), "kubeconfig1")
@@ -133,6 +136,37 @@ func TestLoadContextFromFile(t *testing.T) {
 	}
 }
 
+func TestLoadContextNamespace(t *testing.T) {
+	contexts, _, err := kubeconfig.LoadContextsFromFile("./test_data/kubeconfig1", kubeconfig.KubeConfig)
+	require.NoError(t, err)
+
+	namespaces := map[string]string{}
+	for _, ctx := range contexts {
+		namespaces[ctx.Name] = ctx.Namespace
+	}
+
+	assert.Equal(t, "default", namespaces["minikube"])
+	assert.Empty(t, namespaces["docker-desktop"])
+}
+
+func TestLoadContextExtensions(t *testing.T) {
+	contexts, _, err := kubeconfig.LoadContextsFromFile("./test_data/kubeconfig1", kubeconfig.KubeConfig)
+	require.NoError(t, err)
+
+	var minikube kubeconfig.Context
+
+	for _, ctx := range contexts {
+		if ctx.Name == "minikube" {
+			minikube = ctx
+		}
+	}
+
+	require.Contains(t, minikube.Extensions, "cluster_info")
+	require.Contains(t, minikube.Extensions, "context_info")
+	assert.JSONEq(t, `{"last-update":"Mon, 26 Dec 2022 20:33:03 IST","provider":"minikube.sigs.k8s.io",`+
+		`"version":"v1.28.0"}`, string(minikube.Extensions["context_info"]))
+}
+
 // TestLoadContextsWithDuplicateNames validates the behavior of the LoadContextsFromMultipleFiles function.
 func TestLoadContextsWithDuplicateNames(t *testing.T) {
 	// Simulate two kubeconfig files with duplicate context names
@@ -339,6 +373,122 @@ func TestContext(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "minor")
 }
 
+func TestProxyRequestWithToken(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	testContext := &kubeconfig.Context{
+		Name:        "tokens",
+		KubeContext: &api.Context{Cluster: "tokens", AuthInfo: "tokens"},
+		Cluster:     &api.Cluster{Server: server.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{Token: "stored"},
+	}
+
+	proxy := func(token string) string {
+		request, err := http.NewRequestWithContext(context.Background(), "GET", "/version", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+
+		if token == "" {
+			require.NoError(t, testContext.ProxyRequest(rr, request))
+		} else {
+			require.NoError(t, testContext.ProxyRequestWithToken(rr, request, token))
+		}
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		return rr.Body.String()
+	}
+
+	assert.Equal(t, "Bearer stored", proxy(""))
+	assert.Equal(t, "Bearer override", proxy("override"))
+
+	// The token is only used for the request it's given with.
+	assert.Equal(t, "Bearer stored", proxy(""))
+
+	// The first requests with a token of a stored context may come at the same time.
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{
+		Name:        "concurrent",
+		KubeContext: &api.Context{Cluster: "concurrent", AuthInfo: "concurrent"},
+		Cluster:     &api.Cluster{Server: server.URL, CertificateAuthorityData: caData},
+		AuthInfo:    &api.AuthInfo{},
+	}))
+
+	testContext, err := store.GetContext("concurrent")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			request := httptest.NewRequest(http.MethodGet, "/version", nil)
+			rr := httptest.NewRecorder()
+
+			assert.NoError(t, testContext.ProxyRequestWithToken(rr, request, fmt.Sprint("token", i)))
+			assert.Equal(t, fmt.Sprint("Bearer token", i), rr.Body.String())
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestProxyRequestCancelled(t *testing.T) {
+	started := make(chan struct{})
+
+	// The API server takes its time to list, until the request is cancelled.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	testContext := &kubeconfig.Context{
+		Name:     "slow",
+		Cluster:  &api.Cluster{Server: server.URL},
+		AuthInfo: &api.AuthInfo{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", "/api/v1/pods", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+
+	require.NoError(t, testContext.ProxyRequest(rr, request))
+
+	// The client is gone, so it isn't answered with a proxy error.
+	assert.Empty(t, rr.Body.String())
+	assert.NotEqual(t, http.StatusBadGateway, rr.Code)
+
+	// The other proxy errors are still answered with a 502.
+	server.Close()
+
+	request, err = http.NewRequestWithContext(context.Background(), "GET", "/api/v1/pods", nil)
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+
+	require.NoError(t, testContext.ProxyRequest(rr, request))
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"cluster_unreachable"`)
+}
+
 func TestLoadContextsFromBase64String(t *testing.T) {
 	t.Run("valid_base64", func(t *testing.T) {
 		kubeConfigFile := kubeConfigFilePath
@@ -0,0 +1,105 @@
+package kubeconfig
+
+import (
+	"errors"
+	"sort"
+)
+
+// LoadErrorKind tells what couldn't be loaded from a kubeconfig file.
+type LoadErrorKind string
+
+const (
+	// LoadErrorFile is the kind of the errors of the files that couldn't be read or parsed.
+	LoadErrorFile LoadErrorKind = "file"
+	// LoadErrorContext is the kind of the errors of the contexts that were skipped because
+	// they're invalid, e.g. refer to a missing user.
+	LoadErrorContext LoadErrorKind = "context"
+	// LoadErrorConflict is the kind of the errors of the contexts conflicting with the
+	// context of another file.
+	LoadErrorConflict LoadErrorKind = "conflict"
+)
+
+// LoadError is a context skipped while loading a kubeconfig file, or a file that couldn't be
+// loaded at all, while the rest was loaded.
+type LoadError struct {
+	Kind LoadErrorKind `json:"kind"`
+	// KubeConfigPath is the path of the kubeconfig file.
+	KubeConfigPath string `json:"kubeConfigPath"`
+	// ContextName is the name of the context in the file, empty for the file errors.
+	ContextName string `json:"contextName,omitempty"`
+	// Error is the reason the context or file wasn't loaded.
+	Error string `json:"error"`
+}
+
+// newLoadError returns the LoadError of a ContextLoadError.
+func newLoadError(contextError ContextLoadError) LoadError {
+	loadError := LoadError{
+		Kind:           LoadErrorContext,
+		KubeConfigPath: contextError.KubeConfigPath,
+		ContextName:    contextError.ContextName,
+		Error:          contextError.Error.Error(),
+	}
+
+	var conflict *ContextConflict
+
+	switch {
+	case contextError.ContextName == "":
+		loadError.Kind = LoadErrorFile
+	case errors.As(contextError.Error, &conflict):
+		loadError.Kind = LoadErrorConflict
+	}
+
+	return loadError
+}
+
+// recordLoadErrors replaces the load errors of the kubeconfig files at paths in the store
+// with the ones in contextErrors, so the files loaded fine again have none.
+func recordLoadErrors(kubeConfigStore ContextStore, paths []string, contextErrors []ContextLoadError) {
+	loadErrors := make(map[string][]LoadError, len(paths))
+	for _, path := range paths {
+		loadErrors[path] = nil
+	}
+
+	for _, contextError := range contextErrors {
+		loadErrors[contextError.KubeConfigPath] = append(loadErrors[contextError.KubeConfigPath],
+			newLoadError(contextError))
+	}
+
+	for path, pathErrors := range loadErrors {
+		kubeConfigStore.SetLoadErrors(path, pathErrors)
+	}
+}
+
+// SetLoadErrors replaces the load errors of the kubeconfig file at kubeConfigPath.
+func (c *contextStore) SetLoadErrors(kubeConfigPath string, loadErrors []LoadError) {
+	c.loadErrorsMu.Lock()
+	defer c.loadErrorsMu.Unlock()
+
+	if len(loadErrors) == 0 {
+		delete(c.loadErrors, kubeConfigPath)
+
+		return
+	}
+
+	c.loadErrors[kubeConfigPath] = loadErrors
+}
+
+// LoadErrors returns the errors of the last loading of the kubeconfig files, sorted by path.
+func (c *contextStore) LoadErrors() []LoadError {
+	c.loadErrorsMu.Lock()
+	defer c.loadErrorsMu.Unlock()
+
+	paths := make([]string, 0, len(c.loadErrors))
+	for path := range c.loadErrors {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	loadErrors := []LoadError{}
+	for _, path := range paths {
+		loadErrors = append(loadErrors, c.loadErrors[path]...)
+	}
+
+	return loadErrors
+}
@@ -0,0 +1,100 @@
+package kubeconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const partiallyBrokenKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://cluster:6443
+contexts:
+- name: valid
+  context:
+    cluster: cluster
+    user: user
+- name: missing-user
+  context:
+    cluster: cluster
+    user: nobody
+- context:
+    cluster: cluster
+    user: user
+users:
+- name: user
+  user:
+    token: token
+`
+
+func TestLoadAndStoreKubeConfigsLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	partial := filepath.Join(dir, "partial")
+	broken := filepath.Join(dir, "broken")
+	noClusters := filepath.Join(dir, "no-clusters")
+
+	require.NoError(t, os.WriteFile(partial, []byte(partiallyBrokenKubeconfig), 0o600))
+	require.NoError(t, os.WriteFile(broken, []byte("contexts: [\n"), 0o600))
+	require.NoError(t, os.WriteFile(noClusters, []byte(
+		"contexts:\n- name: orphan\n  context:\n    cluster: cluster\n    user: user\n"), 0o600))
+
+	paths := partial + string(os.PathListSeparator) + broken + string(os.PathListSeparator) + noClusters
+
+	store := kubeconfig.NewContextStore()
+
+	err := kubeconfig.LoadAndStoreKubeConfigs(store, paths, kubeconfig.DynamicCluster, nil)
+	require.Error(t, err)
+
+	// The broken contexts and files don't stop the others from being loaded.
+	contexts, err := store.GetContexts()
+	require.NoError(t, err)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, "valid", contexts[0].Name)
+
+	kinds := map[string]kubeconfig.LoadErrorKind{}
+
+	for _, loadError := range store.LoadErrors() {
+		assert.NotEmpty(t, loadError.Error)
+
+		kinds[filepath.Base(loadError.KubeConfigPath)+"/"+loadError.ContextName] = loadError.Kind
+	}
+
+	assert.Equal(t, map[string]kubeconfig.LoadErrorKind{
+		"partial/missing-user": kubeconfig.LoadErrorContext,
+		"partial/contexts[2]":  kubeconfig.LoadErrorContext,
+		"broken/":              kubeconfig.LoadErrorFile,
+		"no-clusters/orphan":   kubeconfig.LoadErrorContext,
+	}, kinds)
+
+	// The errors of a file are cleared once it loads fine.
+	require.NoError(t, os.WriteFile(broken, []byte(partiallyBrokenKubeconfig), 0o600))
+	require.Error(t, kubeconfig.LoadAndStoreKubeConfigs(store, broken, kubeconfig.DynamicCluster, nil))
+
+	for _, loadError := range store.LoadErrors() {
+		assert.Equal(t, kubeconfig.LoadErrorContext, loadError.Kind)
+	}
+}
+
+func TestSyncContextsKeepsUnreadableFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(partiallyBrokenKubeconfig), 0o600))
+
+	store := kubeconfig.NewContextStore()
+	require.Error(t, kubeconfig.LoadAndStoreKubeConfigs(store, path, kubeconfig.KubeConfig, nil))
+
+	// A half written file doesn't drop its contexts.
+	require.NoError(t, os.WriteFile(path, []byte("contexts: [\n"), 0o600))
+	require.Error(t, kubeconfig.SyncContexts(store, path, kubeconfig.KubeConfig, nil))
+
+	_, err := store.GetContext("valid")
+	require.NoError(t, err)
+	require.Len(t, store.LoadErrors(), 1)
+	assert.Equal(t, kubeconfig.LoadErrorFile, store.LoadErrors()[0].Kind)
+}
@@ -0,0 +1,222 @@
+package kubeconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// NamingStrategy is how the names of the contexts are made safe to use in URLs. The names
+// given by the strategies are also the keys of the contexts in the store.
+type NamingStrategy string
+
+const (
+	// NamingDefault replaces the slashes with -- and the spaces with __.
+	NamingDefault NamingStrategy = "default"
+	// NamingRFC1123Label makes the names lowercase RFC 1123 labels, as Kubernetes object names.
+	NamingRFC1123Label NamingStrategy = "rfc1123-label"
+	// NamingRFC1123Subdomain makes the names lowercase RFC 1123 subdomains, which keep the dots.
+	NamingRFC1123Subdomain NamingStrategy = "rfc1123-subdomain"
+	// NamingHash names the contexts after a hash of their names, hiding them from URLs.
+	NamingHash NamingStrategy = "hash"
+	// NamingEscape keeps the names, escaping the characters that aren't URL safe as ~XX.
+	NamingEscape NamingStrategy = "escape"
+)
+
+const (
+	rfc1123LabelMaxLength     = 63
+	rfc1123SubdomainMaxLength = 253
+	// nameHashLength is the number of hex digits of the hashes added to the names.
+	nameHashLength = 8
+)
+
+var namingStrategy atomic.Value
+
+// ParseNamingStrategy returns the naming strategy called name.
+func ParseNamingStrategy(name string) (NamingStrategy, error) {
+	switch strategy := NamingStrategy(name); strategy {
+	case NamingDefault, NamingRFC1123Label, NamingRFC1123Subdomain, NamingHash, NamingEscape:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid context naming strategy %q, it must be %s, %s, %s, %s or %s", name,
+			NamingDefault, NamingRFC1123Label, NamingRFC1123Subdomain, NamingHash, NamingEscape)
+	}
+}
+
+// SetNamingStrategy sets the naming strategy of the contexts loaded from then on.
+func SetNamingStrategy(strategy NamingStrategy) {
+	namingStrategy.Store(strategy)
+}
+
+// currentNamingStrategy returns the naming strategy set with SetNamingStrategy, or NamingDefault.
+func currentNamingStrategy() NamingStrategy {
+	if strategy, ok := namingStrategy.Load().(NamingStrategy); ok {
+		return strategy
+	}
+
+	return NamingDefault
+}
+
+// ContextName returns the name of the context called name in the kubeconfig.
+func (s NamingStrategy) ContextName(name string) string {
+	switch s {
+	case NamingRFC1123Label:
+		return rfc1123Name(name, false, rfc1123LabelMaxLength)
+	case NamingRFC1123Subdomain:
+		return rfc1123Name(name, true, rfc1123SubdomainMaxLength)
+	case NamingHash:
+		return "ctx-" + nameHash(name, 2*nameHashLength)
+	case NamingEscape:
+		return escapeName(name)
+	default:
+		return makeDNSFriendly(name)
+	}
+}
+
+// rfc1123Name makes name a lowercase RFC 1123 label, or a subdomain if allowDots is set.
+// Names that had to be changed get a hash of the original name, so they stay unique. The
+// names too long to fit keep their provider prefix and cluster name, if they're GKE or EKS
+// names, as their project, zone, region and account make most of their length. The labels
+// of subdomains are also limited to the length of a label.
+func rfc1123Name(name string, allowDots bool, maxLength int) string {
+	sanitized := sanitizeRFC1123(name, allowDots)
+	if sanitized == name && len(sanitized) <= maxLength && labelsFit(sanitized) {
+		return sanitized
+	}
+
+	hash := nameHash(name, nameHashLength)
+	budget := maxLength - len(hash) - 1
+
+	var prefix string
+
+	if len(sanitized) > budget || !labelsFit(sanitized+"-"+hash) {
+		var cluster string
+		if prefix, cluster = providerParts(name); prefix != "" {
+			sanitized = sanitizeRFC1123(cluster, allowDots)
+			budget -= len(prefix) + 1
+		}
+	}
+
+	if len(sanitized) > budget {
+		sanitized = trimLabels(sanitized[:budget])
+	}
+
+	parts := make([]string, 0, 3)
+
+	for _, part := range []string{prefix, sanitized, hash} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return fitLabels(strings.Join(parts, "-"), hash)
+}
+
+// labelsFit tells whether the dot separated labels of name fit in RFC 1123 labels.
+func labelsFit(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if len(label) > rfc1123LabelMaxLength {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fitLabels truncates the dot separated labels of name that don't fit in RFC 1123 labels,
+// keeping the hash ending the last one.
+func fitLabels(name, hash string) string {
+	labels := strings.Split(name, ".")
+	last := len(labels) - 1
+
+	for i, label := range labels {
+		switch {
+		case len(label) <= rfc1123LabelMaxLength:
+		case i == last:
+			labels[i] = strings.TrimRight(label[:rfc1123LabelMaxLength-len(hash)-1], "-") + "-" + hash
+		default:
+			labels[i] = strings.TrimRight(label[:rfc1123LabelMaxLength], "-")
+		}
+	}
+
+	return strings.Join(labels, ".")
+}
+
+// sanitizeRFC1123 lowercases name and replaces its characters that can't be in RFC 1123
+// labels, or subdomains if allowDots is set, with dashes.
+func sanitizeRFC1123(name string, allowDots bool) string {
+	return trimLabels(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		case r == '.' && allowDots:
+			return r
+		default:
+			return '-'
+		}
+	}, name))
+}
+
+// eksARNRegex matches the ARNs of EKS clusters, in every AWS partition.
+var eksARNRegex = regexp.MustCompile(`^arn:aws[a-z-]*:eks:[^:]*:[^:]*:cluster/(.+)$`)
+
+// providerParts splits the GKE (gke_project_zone_cluster) and EKS ARN context names into
+// the sanitized prefix of their provider and the name of their cluster. The prefix is empty
+// for other names.
+func providerParts(name string) (prefix, cluster string) {
+	if parts := strings.SplitN(name, "_", 4); len(parts) == 4 && parts[0] == "gke" {
+		return "gke", parts[3]
+	}
+
+	if match := eksARNRegex.FindStringSubmatch(name); match != nil {
+		return "arn-aws-eks", match[1]
+	}
+
+	return "", name
+}
+
+// trimLabels trims the dashes around the dot separated labels of name, and drops the
+// empty ones, as RFC 1123 labels start and end with an alphanumeric character.
+func trimLabels(name string) string {
+	labels := strings.Split(name, ".")
+	trimmed := labels[:0]
+
+	for _, label := range labels {
+		if label = strings.Trim(label, "-"); label != "" {
+			trimmed = append(trimmed, label)
+		}
+	}
+
+	return strings.Join(trimmed, ".")
+}
+
+// escapeName escapes the bytes of name that aren't unreserved URL characters as ~XX, so
+// the names can be told apart and read back. The % escaping isn't used as the routes
+// match the unescaped paths.
+func escapeName(name string) string {
+	var escaped strings.Builder
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' {
+			escaped.WriteByte(c)
+
+			continue
+		}
+
+		fmt.Fprintf(&escaped, "~%02X", c)
+	}
+
+	return escaped.String()
+}
+
+func nameHash(name string, length int) string {
+	sum := sha256.Sum256([]byte(name))
+
+	return hex.EncodeToString(sum[:])[:length]
+}
@@ -0,0 +1,111 @@
+package kubeconfig_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamingStrategy(t *testing.T) {
+	strategy, err := kubeconfig.ParseNamingStrategy("rfc1123-label")
+	require.NoError(t, err)
+	assert.Equal(t, kubeconfig.NamingRFC1123Label, strategy)
+
+	_, err = kubeconfig.ParseNamingStrategy("dns")
+	assert.ErrorContains(t, err, "invalid context naming strategy")
+}
+
+func TestNamingStrategyContextName(t *testing.T) {
+	tests := []struct {
+		strategy kubeconfig.NamingStrategy
+		name     string
+		want     string
+	}{
+		{kubeconfig.NamingDefault, "team/my cluster", "team--my__cluster"},
+		{kubeconfig.NamingRFC1123Label, "minikube", "minikube"},
+		{kubeconfig.NamingRFC1123Label, "gke_project_zone_Prod", "gke-project-zone-prod-c60eae50"},
+		{kubeconfig.NamingRFC1123Label, "kind.local", "kind-local-efa3828a"},
+		{kubeconfig.NamingRFC1123Subdomain, "kind.local", "kind.local"},
+		{kubeconfig.NamingRFC1123Subdomain, "-my.-cluster-.", "my.cluster-71ac5e67"},
+		{kubeconfig.NamingHash, "minikube", "ctx-5086431107cae015"},
+		{kubeconfig.NamingEscape, "team/Prod cluster~1", "team~2FProd~20cluster~7E1"},
+		{kubeconfig.NamingEscape, "kind-kind_1.2", "kind-kind_1.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy)+"/"+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.strategy.ContextName(tt.name))
+		})
+	}
+}
+
+func TestNamingStrategyRFC1123(t *testing.T) {
+	label := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	subdomain := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+	names := []string{
+		"arn:aws:eks:us-east-1:1234:cluster/prod",
+		"---",
+		"Ünïcødé cluster",
+		strings.Repeat("a", 300),
+		strings.Repeat("a.", 150),
+	}
+
+	for _, name := range names {
+		got := kubeconfig.NamingRFC1123Label.ContextName(name)
+		assert.Regexp(t, label, got, name)
+		assert.LessOrEqual(t, len(got), 63, name)
+
+		got = kubeconfig.NamingRFC1123Subdomain.ContextName(name)
+		assert.Regexp(t, subdomain, got, name)
+		assert.LessOrEqual(t, len(got), 253, name)
+
+		for _, l := range strings.Split(got, ".") {
+			assert.LessOrEqual(t, len(l), 63, name)
+		}
+	}
+
+	// The labels of subdomains are as long as labels, even without dots.
+	got := kubeconfig.NamingRFC1123Subdomain.ContextName(strings.Repeat("a", 100))
+	assert.Regexp(t, `^a{54}-[0-9a-f]{8}$`, got)
+
+	got = kubeconfig.NamingRFC1123Subdomain.ContextName(strings.Repeat("b", 70) + "." + strings.Repeat("c", 70))
+	assert.Regexp(t, `^b{63}\.c{54}-[0-9a-f]{8}$`, got)
+
+	// Names that only differ in the replaced characters stay unique.
+	assert.NotEqual(t, kubeconfig.NamingRFC1123Label.ContextName("a/b"),
+		kubeconfig.NamingRFC1123Label.ContextName("a b"))
+}
+
+func TestNamingStrategyLongProviderNames(t *testing.T) {
+	gke := "gke_my-very-long-project-name-for-production_europe-west1-b_payments"
+	otherGKE := "gke_my-very-long-project-name-for-staging-env_europe-west1-b_payments"
+	eks := "arn:aws:eks:eu-central-1:123456789012:cluster/payments-production"
+	govEKS := "arn:aws-us-gov:eks:us-gov-west-1:123456789012:cluster/payments-production"
+
+	got := kubeconfig.NamingRFC1123Label.ContextName(gke)
+	assert.Regexp(t, `^gke-payments-[0-9a-f]{8}$`, got)
+	assert.NotEqual(t, got, kubeconfig.NamingRFC1123Label.ContextName(otherGKE), "different clusters must not merge")
+
+	got = kubeconfig.NamingRFC1123Label.ContextName(eks)
+	assert.Regexp(t, `^arn-aws-eks-payments-production-[0-9a-f]{8}$`, got)
+	assert.NotEqual(t, got, kubeconfig.NamingRFC1123Label.ContextName(govEKS))
+
+	// The cluster name is truncated when even it doesn't fit, keeping the provider prefix.
+	got = kubeconfig.NamingRFC1123Label.ContextName("gke_project_zone_" + strings.Repeat("c", 80))
+	assert.Regexp(t, `^gke-c+-[0-9a-f]{8}$`, got)
+	assert.Len(t, got, 63)
+
+	// The names that fit aren't shortened.
+	assert.Regexp(t, `^gke-project-us-east1-prod-[0-9a-f]{8}$`,
+		kubeconfig.NamingRFC1123Label.ContextName("gke_project_us-east1_prod"))
+	assert.Regexp(t, `^gke-my-project-us-east1-prod-[0-9a-f]{8}$`,
+		kubeconfig.NamingRFC1123Subdomain.ContextName("gke_my-project_us-east1_prod"))
+
+	// Nor are the subdomains, unless their labels are too long.
+	assert.Regexp(t, `^gke-payments-[0-9a-f]{8}$`, kubeconfig.NamingRFC1123Subdomain.ContextName(gke))
+}
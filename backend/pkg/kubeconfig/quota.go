@@ -0,0 +1,91 @@
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when adding a dynamic cluster would exceed the quota of the store.
+var ErrQuotaExceeded = errors.New("dynamic cluster quota exceeded")
+
+// Quota caps the dynamic clusters, i.e. the stateless ones and the ones added through the API,
+// kept in a store. Zero means no limit.
+type Quota struct {
+	// PerUser is the maximum number of dynamic clusters of a user, as told by the Owner of
+	// the contexts.
+	PerUser int
+	// Total is the maximum number of dynamic clusters of all the users.
+	Total int
+}
+
+// SetQuota sets the quota enforced on the dynamic clusters added from then on. The clusters
+// already in the store are kept, even if they exceed it.
+func (c *contextStore) SetQuota(quota Quota) {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	c.quota = quota
+}
+
+// set stores the context at key, within the quota, unless it's an insecure one the store
+// rejects. It returns whether a context was replaced, which doesn't count against the quota.
+func (c *contextStore) set(headlampContext *Context, key string, ttl time.Duration) (bool, error) {
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	if err := c.checkInsecure(headlampContext); err != nil {
+		return false, err
+	}
+
+	headlampContext.prepareTokenProxy()
+
+	_, err := c.cache.Get(context.Background(), key)
+	existed := err == nil
+
+	if !existed {
+		if err := c.checkQuota(headlampContext); err != nil {
+			return false, err
+		}
+	}
+
+	return existed, c.cache.SetWithTTL(context.Background(), key, headlampContext, ttl)
+}
+
+// checkQuota returns ErrQuotaExceeded if adding the context would exceed the quota.
+// c.quotaMu must be held.
+func (c *contextStore) checkQuota(headlampContext *Context) error {
+	if headlampContext.Source != DynamicCluster || (c.quota.PerUser <= 0 && c.quota.Total <= 0) {
+		return nil
+	}
+
+	contexts, err := c.cache.GetAll(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	total, owned := 0, 0
+
+	for _, existing := range contexts {
+		if existing.Source != DynamicCluster {
+			continue
+		}
+
+		total++
+
+		if existing.Owner == headlampContext.Owner {
+			owned++
+		}
+	}
+
+	if c.quota.Total > 0 && total >= c.quota.Total {
+		return fmt.Errorf("%w: at most %d dynamic clusters can be added", ErrQuotaExceeded, c.quota.Total)
+	}
+
+	if c.quota.PerUser > 0 && owned >= c.quota.PerUser {
+		return fmt.Errorf("%w: at most %d dynamic clusters can be added per user", ErrQuotaExceeded, c.quota.PerUser)
+	}
+
+	return nil
+}
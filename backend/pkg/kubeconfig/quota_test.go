@@ -0,0 +1,61 @@
+package kubeconfig_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dynamicCluster returns a dynamic cluster added by the user.
+func dynamicCluster(name, owner string) *kubeconfig.Context {
+	return &kubeconfig.Context{Name: name, Source: kubeconfig.DynamicCluster, Owner: owner}
+}
+
+func TestContextStoreQuotaPerUser(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetQuota(kubeconfig.Quota{PerUser: 2})
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("a", "user1"), "auser1", time.Minute))
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("b", "user1"), "buser1", time.Minute))
+
+	err := store.AddContextWithKeyAndTTL(dynamicCluster("c", "user1"), "cuser1", time.Minute)
+	require.ErrorIs(t, err, kubeconfig.ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "at most 2 dynamic clusters can be added per user")
+
+	_, err = store.GetContext("cuser1")
+	require.Error(t, err)
+
+	// Replacing a cluster doesn't add one.
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("b", "user1"), "buser1", time.Minute))
+
+	// The quota of the other users is their own.
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("c", "user2"), "cuser2", time.Minute))
+
+	// The clusters of the kubeconfig don't count.
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube", Source: kubeconfig.KubeConfig}))
+
+	// Removing a cluster frees its place.
+	require.NoError(t, store.RemoveContext("auser1"))
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("c", "user1"), "cuser1", time.Minute))
+}
+
+func TestContextStoreQuotaTotal(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetQuota(kubeconfig.Quota{PerUser: 5, Total: 2})
+
+	require.NoError(t, store.AddContext(dynamicCluster("a", "")))
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("b", "user1"), "buser1", time.Minute))
+
+	err := store.AddContextWithKeyAndTTL(dynamicCluster("c", "user2"), "cuser2", time.Minute)
+	require.ErrorIs(t, err, kubeconfig.ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "at most 2 dynamic clusters can be added")
+
+	require.ErrorIs(t, store.AddContext(dynamicCluster("d", "")), kubeconfig.ErrQuotaExceeded)
+
+	// No quota, no limit.
+	store.SetQuota(kubeconfig.Quota{})
+	require.NoError(t, store.AddContext(dynamicCluster("d", "")))
+}
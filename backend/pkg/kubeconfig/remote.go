@@ -0,0 +1,306 @@
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// maxRemoteKubeConfigSize is the maximum size of a kubeconfig fetched from a URL.
+const maxRemoteKubeConfigSize = 10 << 20
+
+var (
+	// ErrInvalidRemoteURL is returned when a kubeconfig URL isn't an https URL.
+	ErrInvalidRemoteURL = errors.New("kubeconfig URL must be an https URL")
+	// ErrRemoteSourceNotFound is returned when removing a kubeconfig URL that isn't registered.
+	ErrRemoteSourceNotFound = errors.New("kubeconfig URL not registered")
+	// ErrRemoteNameTaken is returned for the contexts of a kubeconfig URL whose name is
+	// already used by a context of another source. It matches ErrNameConflict.
+	ErrRemoteNameTaken = fmt.Errorf("%w: the name is used by a context of another source", ErrNameConflict)
+)
+
+// RemoteSource is a kubeconfig served at an https URL, e.g. generated by an internal portal.
+type RemoteSource struct {
+	URL string `json:"url"`
+	// AuthHeader is the Authorization header sent when fetching the kubeconfig, if any.
+	AuthHeader string `json:"authHeader,omitempty"`
+}
+
+// RemoteSourceStatus is the state of a registered kubeconfig URL. It has no auth header,
+// so it can be returned to the clients.
+type RemoteSourceStatus struct {
+	URL string `json:"url"`
+	// Contexts are the names of the contexts loaded from the kubeconfig.
+	Contexts []string `json:"contexts"`
+	// FetchedAt is when the kubeconfig was last fetched or revalidated.
+	FetchedAt time.Time `json:"fetchedAt"`
+	// Error is why the last fetch failed, if it did. The contexts of the last successful
+	// fetch are kept meanwhile.
+	Error string `json:"error,omitempty"`
+}
+
+// remoteSource is a registered kubeconfig URL and the validators of the last fetched kubeconfig.
+type remoteSource struct {
+	RemoteSource
+	etag         string
+	lastModified string
+	contexts     []string
+	fetchedAt    time.Time
+	err          error
+}
+
+// RemoteSources loads the contexts of the kubeconfigs served at https URLs into a store, and
+// keeps them in sync with the kubeconfigs, which are revalidated with their ETag or Last-Modified
+// headers so unchanged kubeconfigs aren't downloaded nor loaded again.
+type RemoteSources struct {
+	store  ContextStore
+	client *http.Client
+
+	mu      sync.Mutex
+	sources map[string]*remoteSource
+}
+
+// NewRemoteSources creates the kubeconfig URLs of the store, fetched with the client.
+func NewRemoteSources(store ContextStore, client *http.Client) *RemoteSources {
+	return &RemoteSources{
+		store:   store,
+		client:  client,
+		sources: map[string]*remoteSource{},
+	}
+}
+
+// Add fetches the kubeconfig of the source and loads its contexts, and registers the source
+// to be refreshed. It replaces the source with the same URL. The source isn't registered if its
+// kubeconfig can't be fetched or loaded.
+func (r *RemoteSources) Add(ctx context.Context, source RemoteSource) error {
+	u, err := url.Parse(source.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ErrInvalidRemoteURL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	src := &remoteSource{RemoteSource: source}
+
+	// The contexts of the replaced source are reconciled with the new kubeconfig.
+	if existing, ok := r.sources[source.URL]; ok {
+		src.contexts = existing.contexts
+	}
+
+	if err := r.refresh(ctx, src); err != nil {
+		return err
+	}
+
+	r.sources[source.URL] = src
+
+	return nil
+}
+
+// Remove unregisters the kubeconfig URL and removes its contexts from the store.
+func (r *RemoteSources) Remove(rawURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	src, ok := r.sources[rawURL]
+	if !ok {
+		return ErrRemoteSourceNotFound
+	}
+
+	delete(r.sources, rawURL)
+	r.reconcile(src, nil)
+
+	return nil
+}
+
+// List returns the status of the registered kubeconfig URLs, sorted by URL.
+func (r *RemoteSources) List() []RemoteSourceStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]RemoteSourceStatus, 0, len(r.sources))
+
+	for _, src := range r.sources {
+		status := RemoteSourceStatus{
+			URL:       src.URL,
+			Contexts:  append([]string{}, src.contexts...),
+			FetchedAt: src.fetchedAt,
+		}
+
+		if src.err != nil {
+			status.Error = src.err.Error()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].URL < statuses[j].URL })
+
+	return statuses
+}
+
+// Refresh revalidates the kubeconfigs of all the sources, and reconciles the store with the
+// ones that changed.
+func (r *RemoteSources) Refresh(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, src := range r.sources {
+		if err := r.refresh(ctx, src); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"url": src.URL}, err, "refreshing kubeconfig URL")
+		}
+	}
+}
+
+// Run refreshes the sources every interval until ctx is done.
+func (r *RemoteSources) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Refresh(ctx)
+		}
+	}
+}
+
+// refresh fetches the kubeconfig of the source, unless it's unchanged, and reconciles the
+// store with its contexts. r.mu must be held.
+func (r *RemoteSources) refresh(ctx context.Context, src *remoteSource) error {
+	src.fetchedAt = time.Now()
+	src.err = r.fetchAndLoad(ctx, src)
+
+	return src.err
+}
+
+// fetchAndLoad fetches the kubeconfig of the source and loads its contexts, if it changed.
+func (r *RemoteSources) fetchAndLoad(ctx context.Context, src *remoteSource) error {
+	data, header, err := r.fetch(ctx, src)
+	if err != nil || data == nil {
+		return err
+	}
+
+	contexts, contextErrors, err := loadContextsFromData(data, Remote, false)
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig from %s: %w", src.URL, err)
+	}
+
+	for _, contextError := range contextErrors {
+		logger.Log(logger.LevelError, map[string]string{"url": src.URL, "context": contextError.ContextName},
+			contextError.Error, "loading context from kubeconfig URL")
+	}
+
+	for i := range contexts {
+		contexts[i].KubeConfigPath = src.URL
+		contexts[i].ClusterID = fmt.Sprintf("%s+%s", src.URL, contexts[i].Name)
+	}
+
+	r.reconcile(src, contexts)
+
+	// The validators are kept only once the kubeconfig is loaded, so a kubeconfig that failed
+	// to load is downloaded again instead of being reported as unchanged.
+	src.etag = header.Get("ETag")
+	src.lastModified = header.Get("Last-Modified")
+
+	return nil
+}
+
+// fetch downloads the kubeconfig of the source. It returns no data if it's unchanged since
+// the last fetch.
+func (r *RemoteSources) fetch(ctx context.Context, src *remoteSource) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if src.AuthHeader != "" {
+		req.Header.Set("Authorization", src.AuthHeader)
+	}
+
+	if src.etag != "" {
+		req.Header.Set("If-None-Match", src.etag)
+	}
+
+	if src.lastModified != "" {
+		req.Header.Set("If-Modified-Since", src.lastModified)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching kubeconfig: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, resp.Header, nil
+	case http.StatusOK:
+	default:
+		return nil, nil, fmt.Errorf("fetching kubeconfig from %s: %s", src.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteKubeConfigSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading kubeconfig: %w", err)
+	}
+
+	if len(data) > maxRemoteKubeConfigSize {
+		return nil, nil, fmt.Errorf("kubeconfig from %s is larger than %d bytes", src.URL, maxRemoteKubeConfigSize)
+	}
+
+	return data, resp.Header, nil
+}
+
+// reconcile stores the contexts of the source, and removes the ones it no longer has. The
+// contexts whose name is used by a context of another source are skipped. r.mu must be held.
+func (r *RemoteSources) reconcile(src *remoteSource, contexts []Context) {
+	names := make([]string, 0, len(contexts))
+	kept := map[string]bool{}
+
+	for i := range contexts {
+		kContext := &contexts[i]
+
+		existing, err := r.store.GetContext(kContext.Name)
+		if err == nil && (existing.Source != Remote || existing.KubeConfigPath != src.URL) {
+			logger.Log(logger.LevelError, map[string]string{"url": src.URL, "context": kContext.Name},
+				ErrRemoteNameTaken, "skipping context from kubeconfig URL")
+
+			continue
+		}
+
+		if err := r.store.AddContext(kContext); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"url": src.URL, "context": kContext.Name},
+				err, "adding context from kubeconfig URL")
+
+			continue
+		}
+
+		names = append(names, kContext.Name)
+		kept[kContext.Name] = true
+	}
+
+	for _, name := range src.contexts {
+		if kept[name] {
+			continue
+		}
+
+		if err := r.store.RemoveContext(name); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"url": src.URL, "context": name},
+				err, "removing context of kubeconfig URL")
+		}
+	}
+
+	src.contexts = names
+}
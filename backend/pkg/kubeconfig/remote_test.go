@@ -0,0 +1,188 @@
+package kubeconfig_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// remoteKubeConfig returns a kubeconfig with a context per name.
+func remoteKubeConfig(t *testing.T, names ...string) []byte {
+	t.Helper()
+
+	config := api.NewConfig()
+
+	for _, name := range names {
+		config.Clusters[name] = &api.Cluster{Server: "https://" + name + ".example.com"}
+		config.AuthInfos[name] = &api.AuthInfo{Token: "token"}
+		config.Contexts[name] = &api.Context{Cluster: name, AuthInfo: name}
+	}
+
+	data, err := clientcmd.Write(*config)
+	require.NoError(t, err)
+
+	return data
+}
+
+// kubeConfigPortal serves a kubeconfig with an ETag, as the portals generating them do.
+type kubeConfigPortal struct {
+	mu          sync.Mutex
+	kubeConfig  []byte
+	etag        string
+	downloads   int
+	notModified int
+	status      int
+}
+
+func (p *kubeConfigPortal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer portal-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+		return
+	}
+
+	if r.Header.Get("If-None-Match") == p.etag {
+		p.notModified++
+
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	p.downloads++
+
+	w.Header().Set("ETag", p.etag)
+	_, _ = w.Write(p.kubeConfig)
+}
+
+func (p *kubeConfigPortal) serve(kubeConfig []byte, etag string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.kubeConfig, p.etag = kubeConfig, etag
+}
+
+func (p *kubeConfigPortal) fail(status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+}
+
+func TestRemoteSources(t *testing.T) {
+	portal := &kubeConfigPortal{kubeConfig: remoteKubeConfig(t, "a", "b"), etag: `"v1"`}
+	server := httptest.NewTLSServer(portal)
+	t.Cleanup(server.Close)
+
+	store := kubeconfig.NewContextStore()
+	sources := kubeconfig.NewRemoteSources(store, server.Client())
+	source := kubeconfig.RemoteSource{URL: server.URL + "/kubeconfig", AuthHeader: "Bearer portal-token"}
+
+	require.NoError(t, sources.Add(context.Background(), source))
+
+	for _, name := range []string{"a", "b"} {
+		kContext, err := store.GetContext(name)
+		require.NoError(t, err)
+		assert.Equal(t, kubeconfig.Remote, kContext.Source)
+		assert.Equal(t, "remote", kContext.SourceStr())
+		assert.Equal(t, source.URL, kContext.KubeConfigPath)
+	}
+
+	// An unchanged kubeconfig isn't downloaded again.
+	sources.Refresh(context.Background())
+	assert.Equal(t, 1, portal.downloads)
+	assert.Equal(t, 1, portal.notModified)
+
+	// The contexts gone from the kubeconfig are removed, and the new ones added.
+	portal.serve(remoteKubeConfig(t, "b", "c"), `"v2"`)
+	sources.Refresh(context.Background())
+	assert.Equal(t, 2, portal.downloads)
+
+	_, err := store.GetContext("a")
+	require.Error(t, err)
+
+	_, err = store.GetContext("c")
+	require.NoError(t, err)
+
+	statuses := sources.List()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, source.URL, statuses[0].URL)
+	assert.ElementsMatch(t, []string{"b", "c"}, statuses[0].Contexts)
+	assert.Empty(t, statuses[0].Error)
+
+	// The contexts are kept while the kubeconfig can't be fetched.
+	portal.fail(http.StatusServiceUnavailable)
+	sources.Refresh(context.Background())
+	assert.Contains(t, sources.List()[0].Error, "503")
+
+	_, err = store.GetContext("c")
+	require.NoError(t, err)
+
+	require.NoError(t, sources.Remove(source.URL))
+	assert.Empty(t, sources.List())
+
+	contexts, err := store.GetContexts()
+	require.NoError(t, err)
+	assert.Empty(t, contexts)
+
+	require.ErrorIs(t, sources.Remove(source.URL), kubeconfig.ErrRemoteSourceNotFound)
+}
+
+func TestRemoteSourcesAddErrors(t *testing.T) {
+	portal := &kubeConfigPortal{kubeConfig: remoteKubeConfig(t, "a"), etag: `"v1"`}
+	server := httptest.NewTLSServer(portal)
+	t.Cleanup(server.Close)
+
+	store := kubeconfig.NewContextStore()
+	sources := kubeconfig.NewRemoteSources(store, server.Client())
+
+	for _, rawURL := range []string{"http://example.com/kubeconfig", "file:///etc/kubeconfig", "https://"} {
+		err := sources.Add(context.Background(), kubeconfig.RemoteSource{URL: rawURL})
+		require.ErrorIs(t, err, kubeconfig.ErrInvalidRemoteURL, rawURL)
+	}
+
+	// Without the auth header the portal refuses to serve the kubeconfig.
+	err := sources.Add(context.Background(), kubeconfig.RemoteSource{URL: server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.Empty(t, sources.List())
+}
+
+func TestRemoteSourcesNameTaken(t *testing.T) {
+	portal := &kubeConfigPortal{kubeConfig: remoteKubeConfig(t, "a", "minikube"), etag: `"v1"`}
+	server := httptest.NewTLSServer(portal)
+	t.Cleanup(server.Close)
+
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "minikube", Source: kubeconfig.KubeConfig}))
+
+	sources := kubeconfig.NewRemoteSources(store, server.Client())
+	source := kubeconfig.RemoteSource{URL: server.URL, AuthHeader: "Bearer portal-token"}
+	require.NoError(t, sources.Add(context.Background(), source))
+
+	kContext, err := store.GetContext("minikube")
+	require.NoError(t, err)
+	assert.Equal(t, kubeconfig.KubeConfig, kContext.Source)
+	assert.Equal(t, []string{"a"}, sources.List()[0].Contexts)
+
+	// Removing the source leaves the contexts of the other sources.
+	require.NoError(t, sources.Remove(source.URL))
+
+	_, err = store.GetContext("minikube")
+	require.NoError(t, err)
+}
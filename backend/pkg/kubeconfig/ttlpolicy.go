@@ -0,0 +1,92 @@
+package kubeconfig
+
+import (
+	"time"
+)
+
+// TTLPolicy bounds how long the contexts stored with a ttl, like the stateless clusters, and
+// so the credentials of the users in them, are kept in a store. Zero means no bound.
+type TTLPolicy struct {
+	// Default is the ttl of the contexts added without one.
+	Default time.Duration
+	// Max is the longest ttl a context can have, the longer ones are cut to it.
+	Max time.Duration
+	// MinRenewal is the shortest time between two updates of the ttl of a context with
+	// UpdateTTL, the ones coming sooner are ignored.
+	MinRenewal time.Duration
+	// RenewBelow coalesces the renewals: the ttl of a context is only written again with
+	// UpdateTTL once less than RenewBelow of it remains, so the clients renewing it on every
+	// request don't write to the cache every time. Shortening a ttl is always written.
+	RenewBelow time.Duration
+}
+
+// Apply returns the ttl a context asked to be stored with ttl gets: Default if ttl isn't
+// positive, cut to Max.
+func (p TTLPolicy) Apply(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = p.Default
+	}
+
+	if p.Max > 0 && (ttl <= 0 || ttl > p.Max) {
+		ttl = p.Max
+	}
+
+	return ttl
+}
+
+// SetTTLPolicy sets the policy applied to the ttl of the contexts added or renewed from then
+// on. The contexts already in the store keep their ttl.
+func (c *contextStore) SetTTLPolicy(policy TTLPolicy) {
+	c.ttlPolicyMu.Lock()
+	defer c.ttlPolicyMu.Unlock()
+
+	c.ttlPolicy = policy
+}
+
+// TTLPolicy returns the policy applied to the ttl of the contexts.
+func (c *contextStore) TTLPolicy() TTLPolicy {
+	c.ttlPolicyMu.RLock()
+	defer c.ttlPolicyMu.RUnlock()
+
+	return c.ttlPolicy
+}
+
+// claimRenewal tells whether the ttl of the context at key should be updated to ttl with
+// policy, and if so records the renewal right away so the concurrent ones for the same
+// context are coalesced into it. The returned function gives the claim back if the update
+// fails.
+func (c *contextStore) claimRenewal(key string, ttl time.Duration, policy TTLPolicy) (bool, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	previous, renewed := c.renewedAt[key]
+
+	if renewed && policy.MinRenewal > 0 && now.Sub(previous) < policy.MinRenewal {
+		return false, nil
+	}
+
+	if exp, ok := c.expiries[key]; ok && policy.RenewBelow > 0 {
+		remaining := exp.expiresAt.Sub(now)
+		if remaining > policy.RenewBelow && ttl >= remaining {
+			return false, nil
+		}
+	}
+
+	c.renewedAt[key] = now
+
+	return true, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.renewedAt[key] != now {
+			return
+		}
+
+		if renewed {
+			c.renewedAt[key] = previous
+		} else {
+			delete(c.renewedAt, key)
+		}
+	}
+}
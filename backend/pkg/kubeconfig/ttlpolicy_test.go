@@ -0,0 +1,134 @@
+package kubeconfig_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLPolicyApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy kubeconfig.TTLPolicy
+		ttl    time.Duration
+		want   time.Duration
+	}{
+		{name: "no policy", ttl: time.Hour, want: time.Hour},
+		{name: "no policy nor ttl", ttl: 0, want: 0},
+		{name: "default", policy: kubeconfig.TTLPolicy{Default: time.Minute}, ttl: 0, want: time.Minute},
+		{name: "given ttl", policy: kubeconfig.TTLPolicy{Default: time.Minute}, ttl: time.Hour, want: time.Hour},
+		{name: "over max", policy: kubeconfig.TTLPolicy{Max: time.Hour}, ttl: 2 * time.Hour, want: time.Hour},
+		{name: "no ttl with max", policy: kubeconfig.TTLPolicy{Max: time.Hour}, ttl: 0, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Apply(tt.ttl))
+		})
+	}
+}
+
+func TestContextStoreTTLPolicy(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetTTLPolicy(kubeconfig.TTLPolicy{Default: time.Minute, Max: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := store.Watch(ctx)
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("a", "user1"), "auser1", 0))
+	event := <-events
+	assert.WithinDuration(t, time.Now().Add(time.Minute), event.ExpiresAt, time.Second)
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("b", "user1"), "buser1", 24*time.Hour))
+	event = <-events
+	assert.WithinDuration(t, time.Now().Add(time.Hour), event.ExpiresAt, time.Second)
+
+	ttl := 24 * time.Hour
+	_, err := store.UpdateContext("buser1", kubeconfig.ContextUpdate{TTL: &ttl})
+	require.NoError(t, err)
+
+	event = <-events
+	assert.WithinDuration(t, time.Now().Add(time.Hour), event.ExpiresAt, time.Second)
+}
+
+func TestContextStoreTTLMinRenewal(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetTTLPolicy(kubeconfig.TTLPolicy{MinRenewal: time.Hour})
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("a", "user1"), "auser1", time.Minute))
+
+	revision := store.Revision()
+
+	require.NoError(t, store.UpdateTTL("auser1", time.Minute))
+	assert.Greater(t, store.Revision(), revision)
+
+	// The renewals coming sooner than MinRenewal are ignored.
+	revision = store.Revision()
+
+	require.NoError(t, store.UpdateTTL("auser1", time.Minute))
+	assert.Equal(t, revision, store.Revision())
+
+	// Adding the context again starts over.
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("a", "user1"), "auser1", time.Minute))
+
+	revision = store.Revision()
+
+	require.NoError(t, store.UpdateTTL("auser1", time.Minute))
+	assert.Greater(t, store.Revision(), revision)
+}
+
+func TestContextStoreTTLRenewBelow(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetTTLPolicy(kubeconfig.TTLPolicy{RenewBelow: time.Minute})
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("a", "user1"), "auser1", time.Hour))
+
+	// More than RenewBelow of the ttl remains, so the renewal is coalesced.
+	revision := store.Revision()
+
+	require.NoError(t, store.UpdateTTL("auser1", time.Hour))
+	assert.Equal(t, revision, store.Revision())
+
+	// Shortening the ttl is always written.
+	require.NoError(t, store.UpdateTTL("auser1", 30*time.Second))
+	assert.Greater(t, store.Revision(), revision)
+
+	// Less than RenewBelow remains, so it's renewed.
+	revision = store.Revision()
+
+	require.NoError(t, store.UpdateTTL("auser1", time.Hour))
+	assert.Greater(t, store.Revision(), revision)
+}
+
+func TestContextStoreTTLRenewalBurst(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	store.SetTTLPolicy(kubeconfig.TTLPolicy{MinRenewal: time.Hour})
+
+	require.NoError(t, store.AddContextWithKeyAndTTL(dynamicCluster("a", "user1"), "auser1", time.Minute))
+
+	revision := store.Revision()
+
+	var wg sync.WaitGroup
+
+	for range 20 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, store.UpdateTTL("auser1", time.Minute))
+		}()
+	}
+
+	wg.Wait()
+
+	// Only one of the concurrent renewals is written.
+	assert.Equal(t, revision+1, store.Revision())
+}
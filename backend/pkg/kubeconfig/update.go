@@ -0,0 +1,153 @@
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ErrInvalidUpdate is returned when updating a context with an invalid update.
+var ErrInvalidUpdate = errors.New("invalid context update")
+
+// ContextUpdate is a partial update of a context in a store. The fields left nil are unchanged.
+type ContextUpdate struct {
+	// Token replaces the bearer token of the context.
+	Token *string
+	// Server replaces the URL of the API server of the context.
+	Server *string
+	// TTL replaces the ttl of the context, cut to the Max of the TTLPolicy of the store. The
+	// contexts stored without a ttl get one.
+	TTL *time.Duration
+	// Labels replace the labels of the context.
+	Labels map[string]string
+}
+
+// Validate checks the update: the server must be an http or https URL, the ttl positive and
+// the labels valid Kubernetes labels.
+func (u ContextUpdate) Validate() error {
+	if u.Server != nil {
+		parsed, err := url.Parse(*u.Server)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("%w: server %q must be an http or https URL", ErrInvalidUpdate, *u.Server)
+		}
+	}
+
+	if u.TTL != nil && *u.TTL <= 0 {
+		return fmt.Errorf("%w: ttl must be positive", ErrInvalidUpdate)
+	}
+
+	for key, value := range u.Labels {
+		errs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(value)...)
+		if len(errs) > 0 {
+			return fmt.Errorf("%w: label %q: %s", ErrInvalidUpdate, key, strings.Join(errs, "; "))
+		}
+	}
+
+	return nil
+}
+
+// UpdateContext applies the update to the context at key in place of removing and adding it
+// again, and tells the watchers it was modified. The context keeps its key, source and
+// remaining ttl unless the update changes it. A new proxy is set up for the updated token or
+// server, while the requests and watches already open go on with the old one. It returns the
+// updated context, or ErrContextNotFound or ErrContextExpired if there's no context at key.
+func (c *contextStore) UpdateContext(key string, update ContextUpdate) (*Context, error) {
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The context is replaced like the additions, which are serialized by quotaMu.
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+
+	existing, err := c.cache.Get(context.Background(), key)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, c.notFound(key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	updated := existing.withUpdate(update)
+
+	ttl := c.remainingTTL(key)
+	if update.TTL != nil {
+		ttl = c.TTLPolicy().Apply(*update.TTL)
+	}
+
+	if err := c.cache.SetWithTTL(context.Background(), key, updated, ttl); err != nil {
+		return nil, err
+	}
+
+	c.index(updated, key, ttl)
+
+	event := ContextEvent{Type: ContextModified, Key: key, Context: updated}
+	if ttl > 0 {
+		event.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	c.emit(event)
+
+	return updated, nil
+}
+
+// withUpdate returns a copy of the context with the update applied. The cluster and auth info
+// are copied before they're changed, as the context may be in use.
+func (c *Context) withUpdate(update ContextUpdate) *Context {
+	updated := *c
+
+	if update.Token != nil {
+		authInfo := &api.AuthInfo{}
+		if c.AuthInfo != nil {
+			authInfo = c.AuthInfo.DeepCopy()
+		}
+
+		authInfo.Token = *update.Token
+		authInfo.TokenFile = ""
+		updated.AuthInfo = authInfo
+		updated.proxy = nil
+	}
+
+	if update.Server != nil {
+		cluster := &api.Cluster{}
+		if c.Cluster != nil {
+			cluster = c.Cluster.DeepCopy()
+		}
+
+		cluster.Server = *update.Server
+		updated.Cluster = cluster
+		updated.proxy = nil
+		updated.tokenProxy = nil
+		updated.prepareTokenProxy()
+	}
+
+	if update.Labels != nil {
+		updated.Labels = maps.Clone(update.Labels)
+	}
+
+	return &updated
+}
+
+// remainingTTL returns the time left before the context at key expires, or 0 if it was
+// stored without a ttl.
+func (c *contextStore) remainingTTL(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	exp, ok := c.expiries[key]
+	if !ok {
+		return 0
+	}
+
+	// A ttl of 0 would keep the context forever, so one about to expire gets the shortest one.
+	return max(time.Until(exp.expiresAt), time.Nanosecond)
+}
@@ -0,0 +1,119 @@
+package kubeconfig_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestUpdateContext(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	original := &kubeconfig.Context{
+		Name:        "dynamic",
+		Source:      kubeconfig.DynamicCluster,
+		KubeContext: &api.Context{Cluster: "dynamic", AuthInfo: "dynamic"},
+		Cluster:     &api.Cluster{Server: "https://old.example.com"},
+		AuthInfo:    &api.AuthInfo{Token: "old-token"},
+	}
+	require.NoError(t, store.AddContext(original))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := store.Watch(ctx)
+
+	token, server := "new-token", "https://new.example.com"
+	updated, err := store.UpdateContext("dynamic", kubeconfig.ContextUpdate{
+		Token:  &token,
+		Server: &server,
+		Labels: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+
+	restConfig, err := updated.RESTConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "new-token", restConfig.BearerToken)
+	assert.Equal(t, "https://new.example.com", restConfig.Host)
+	assert.Equal(t, map[string]string{"env": "prod"}, updated.Labels)
+	assert.Equal(t, kubeconfig.DynamicCluster, updated.Source)
+
+	// The context in use is left as it was.
+	assert.Equal(t, "old-token", original.AuthInfo.Token)
+	assert.Equal(t, "https://old.example.com", original.Cluster.Server)
+
+	stored, err := store.GetContext("dynamic")
+	require.NoError(t, err)
+	assert.Same(t, updated, stored)
+
+	contexts, err := store.GetContextsByServer("https://new.example.com")
+	require.NoError(t, err)
+	assert.Len(t, contexts, 1)
+
+	event := nextEvent(t, events)
+	assert.Equal(t, kubeconfig.ContextModified, event.Type)
+	assert.Equal(t, "dynamic", event.Key)
+	assert.True(t, event.ExpiresAt.IsZero())
+
+	// The fields left out are unchanged.
+	updated, err = store.UpdateContext("dynamic", kubeconfig.ContextUpdate{})
+	require.NoError(t, err)
+	assert.Equal(t, "new-token", updated.AuthInfo.Token)
+	assert.Equal(t, map[string]string{"env": "prod"}, updated.Labels)
+
+	_, err = store.UpdateContext("missing", kubeconfig.ContextUpdate{Token: &token})
+	require.ErrorIs(t, err, cache.ErrNotFound)
+}
+
+func TestUpdateContextTTL(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+
+	stateless := &kubeconfig.Context{Name: "stateless", Source: kubeconfig.DynamicCluster, Internal: true}
+	require.NoError(t, store.AddContextWithKeyAndTTL(stateless, "stateless-user1", time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := store.Watch(ctx)
+
+	// The remaining ttl is kept.
+	token := "new-token"
+	_, err := store.UpdateContext("stateless-user1", kubeconfig.ContextUpdate{Token: &token})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), nextEvent(t, events).ExpiresAt, time.Second)
+
+	ttl := 200 * time.Millisecond
+	_, err = store.UpdateContext("stateless-user1", kubeconfig.ContextUpdate{TTL: &ttl})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(ttl), nextEvent(t, events).ExpiresAt, 100*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, err := store.GetContext("stateless-user1")
+
+		return err != nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestUpdateContextInvalid(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "dynamic", Source: kubeconfig.DynamicCluster}))
+
+	server := "ftp://example.com"
+	ttl := -time.Minute
+
+	for name, update := range map[string]kubeconfig.ContextUpdate{
+		"server":      {Server: &server},
+		"ttl":         {TTL: &ttl},
+		"label key":   {Labels: map[string]string{"not a key": "value"}},
+		"label value": {Labels: map[string]string{"env": "not a value"}},
+	} {
+		_, err := store.UpdateContext("dynamic", update)
+		require.ErrorIs(t, err, kubeconfig.ErrInvalidUpdate, name)
+	}
+}
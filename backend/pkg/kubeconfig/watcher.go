@@ -53,7 +53,7 @@ func LoadAndWatchFiles(kubeConfigStore ContextStore, paths string, source int, i
 					logger.Log(logger.LevelInfo, map[string]string{"event": event.Name},
 						nil, "watcher: kubeconfig file changed, reloading contexts")
 
-					err := syncContexts(kubeConfigStore, paths, source, ignoreFunc)
+					err := SyncContexts(kubeConfigStore, paths, source, ignoreFunc)
 					if err != nil {
 						logger.Log(logger.LevelError, nil, err, "watcher: error synchronizing contexts")
 					}
@@ -105,39 +105,60 @@ func addFilesToWatcher(watcher *fsnotify.Watcher, paths []string) {
 	}
 }
 
-// syncContexts synchronizes the contexts in the store with the ones in the kubeconfig files.
-func syncContexts(kubeConfigStore ContextStore, paths string, source int, ignoreFunc shouldBeSkippedFunc) error {
+// SyncContexts synchronizes the contexts in the store with the ones in the kubeconfig files:
+// new contexts are added, changed ones updated, and the ones gone or skipped by ignoreFunc removed.
+func SyncContexts(kubeConfigStore ContextStore, paths string, source int, ignoreFunc shouldBeSkippedFunc) error {
 	// First read all kubeconfig files to get new contexts
-	newContexts, _, err := LoadContextsFromMultipleFiles(paths, source)
+	newContexts, contextErrors, err := LoadContextsFromMultipleFiles(paths, source)
 	if err != nil {
 		return fmt.Errorf("error reading kubeconfig files: %v", err)
 	}
 
+	// The contexts of the files that couldn't be loaded, e.g. while they're being written,
+	// are kept until they can.
+	unreadable := map[string]bool{}
+
+	for _, contextError := range contextErrors {
+		if contextError.ContextName == "" {
+			unreadable[contextError.KubeConfigPath] = true
+		}
+	}
+
 	// Get existing contexts from store
 	existingContexts, err := kubeConfigStore.GetContexts()
 	if err != nil {
 		return fmt.Errorf("error getting existing contexts: %v", err)
 	}
 
-	// Find and remove contexts that no longer exist in the kubeconfig
-	// but only for contexts that came from KubeConfig source
-	for _, existingCtx := range existingContexts {
-		// Skip contexts from other sources
-		if existingCtx.Source != KubeConfig {
+	// Find the stored contexts that are still in the kubeconfig by their original names, so
+	// the ones stored under a name the naming strategy no longer gives them are replaced.
+	kept := map[string]bool{}
+
+	for _, newCtx := range newContexts {
+		if ignoreFunc != nil && ignoreFunc(newCtx) {
 			continue
 		}
 
-		found := false
+		originalName := newCtx.OriginalName
+		if originalName == "" {
+			originalName = newCtx.Name
+		}
 
-		for _, newCtx := range newContexts {
-			if existingCtx.Name == newCtx.Name {
-				found = true
+		existingCtx, err := kubeConfigStore.GetContextByOriginalName(originalName)
+		if err == nil && existingCtx.Name == newCtx.Name {
+			kept[existingCtx.Name] = true
+		}
+	}
 
-				break
-			}
+	// Find and remove contexts that no longer exist in the kubeconfig
+	// but only for contexts that came from KubeConfig source
+	for _, existingCtx := range existingContexts {
+		// Skip contexts from other sources
+		if existingCtx.Source != KubeConfig || unreadable[existingCtx.KubeConfigPath] {
+			continue
 		}
 
-		if !found {
+		if !kept[existingCtx.Name] {
 			err := kubeConfigStore.RemoveContext(existingCtx.Name)
 			if err != nil {
 				logger.Log(logger.LevelError, nil, err, "error removing context")
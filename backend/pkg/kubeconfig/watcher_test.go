@@ -107,3 +107,39 @@ func TestWatchAndLoadFiles(t *testing.T) {
 		}
 	}()
 }
+
+func TestSyncContexts(t *testing.T) {
+	kubeConfigStore := kubeconfig.NewContextStore()
+
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{Name: "gone", Source: kubeconfig.KubeConfig}))
+	require.NoError(t, kubeConfigStore.AddContext(&kubeconfig.Context{Name: "dynamic", Source: kubeconfig.DynamicCluster}))
+
+	skipMinikube := kubeconfig.SkipKubeContextInCommaSeparatedString("minikube")
+
+	err := kubeconfig.SyncContexts(kubeConfigStore, "./test_data/kubeconfig1", kubeconfig.KubeConfig, skipMinikube)
+	require.NoError(t, err)
+
+	_, err = kubeConfigStore.GetContext("docker-desktop")
+	require.NoError(t, err)
+
+	_, err = kubeConfigStore.GetContext("dynamic")
+	require.NoError(t, err, "contexts of other sources should be kept")
+
+	_, err = kubeConfigStore.GetContext("gone")
+	require.Error(t, err, "contexts not in the kubeconfig anymore should be removed")
+
+	_, err = kubeConfigStore.GetContext("minikube")
+	require.Error(t, err, "skipped contexts should not be added")
+
+	// A context skipped after it was loaded is removed.
+	require.NoError(t, kubeconfig.SyncContexts(kubeConfigStore, "./test_data/kubeconfig1", kubeconfig.KubeConfig, nil))
+
+	_, err = kubeConfigStore.GetContext("minikube")
+	require.NoError(t, err)
+
+	err = kubeconfig.SyncContexts(kubeConfigStore, "./test_data/kubeconfig1", kubeconfig.KubeConfig, skipMinikube)
+	require.NoError(t, err)
+
+	_, err = kubeConfigStore.GetContext("minikube")
+	require.Error(t, err)
+}
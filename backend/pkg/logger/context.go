@@ -0,0 +1,98 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"context"
+	"maps"
+)
+
+// RequestIDHeader is the header carrying the request ID, in the responses to the
+// client and in the requests proxied to the clusters.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestIDField is the log field of the request ID.
+const RequestIDField = "request_id"
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+
+	return requestID
+}
+
+// componentKey is the context key of the log component.
+type componentKey struct{}
+
+// WithComponent returns a copy of ctx carrying the component the logs with it come from,
+// whose log level applies to them.
+func WithComponent(ctx context.Context, component string) context.Context {
+	return context.WithValue(ctx, componentKey{}, component)
+}
+
+// ComponentFromContext returns the log component carried by ctx, or "" if there is none.
+func ComponentFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	component, _ := ctx.Value(componentKey{}).(string)
+
+	return component
+}
+
+// LogCtx logs like Log, adding the request ID and the component carried by ctx to the
+// logged fields, so the logs of a request can be correlated with each other and with the client.
+func LogCtx(ctx context.Context, level uint, str map[string]string, err interface{}, msg string) {
+	logFunc(level, contextFields(ctx, str), err, msg)
+}
+
+// contextFields returns the fields with the request ID and the component carried by ctx added.
+// The fields given are left alone.
+func contextFields(ctx context.Context, str map[string]string) map[string]string {
+	requestID := RequestIDFromContext(ctx)
+	component := ComponentFromContext(ctx)
+
+	if requestID == "" && component == "" {
+		return str
+	}
+
+	fields := make(map[string]string, len(str)+2)
+	maps.Copy(fields, str)
+
+	if requestID != "" {
+		fields[RequestIDField] = requestID
+	}
+
+	if component != "" && fields[ComponentField] == "" {
+		fields[ComponentField] = component
+	}
+
+	return fields
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogCtx(t *testing.T) {
+	var fields []map[string]string
+
+	originalLogFunc := logger.SetLogFunc(func(level uint, str map[string]string, err interface{}, msg string) {
+		fields = append(fields, str)
+	})
+	defer logger.SetLogFunc(originalLogFunc)
+
+	ctx := logger.WithRequestID(context.Background(), "request-1")
+	assert.Equal(t, "request-1", logger.RequestIDFromContext(ctx))
+
+	str := map[string]string{"key": "value"}
+
+	logger.LogCtx(ctx, logger.LevelInfo, str, nil, "with request ID")
+	logger.LogCtx(context.Background(), logger.LevelInfo, nil, nil, "without request ID")
+
+	ctx = logger.WithComponent(ctx, logger.ComponentProxy)
+	assert.Equal(t, logger.ComponentProxy, logger.ComponentFromContext(ctx))
+
+	logger.LogCtx(ctx, logger.LevelInfo, str, nil, "with component")
+
+	assert.Equal(t, []map[string]string{
+		{"key": "value", logger.RequestIDField: "request-1"},
+		nil,
+		{"key": "value", logger.RequestIDField: "request-1", logger.ComponentField: logger.ComponentProxy},
+	}, fields)
+
+	// The fields of the caller are left alone.
+	assert.Equal(t, map[string]string{"key": "value"}, str)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// backupTimeFormat is the time format of the suffix of rotated log files.
+// It has no colons, which are not allowed in file names on Windows.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// logFileMode is the file mode of log files.
+const logFileMode = 0o600
+
+// logDirMode is the file mode of the directory created for log files.
+const logDirMode = 0o755
+
+// FileOptions configures a log file.
+type FileOptions struct {
+	// Path is the path of the log file.
+	Path string
+	// MaxSize is the size in bytes after which the file is rotated; 0 disables it.
+	MaxSize int64
+	// RotateInterval is the age after which the file is rotated; 0 disables it.
+	RotateInterval time.Duration
+	// MaxBackups is the number of rotated files to keep; 0 keeps them all.
+	MaxBackups int
+	// MaxAge is how long rotated files are kept; 0 keeps them forever.
+	MaxAge time.Duration
+}
+
+// RotatingFile is an io.Writer appending to a log file, which is rotated when it gets
+// too big or too old. Rotated files are renamed with the time of the rotation as suffix,
+// and removed according to the retention options.
+type RotatingFile struct {
+	opts     FileOptions
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile opens the log file, creating it and its directory if needed.
+func NewRotatingFile(opts FileOptions) (*RotatingFile, error) {
+	if opts.Path == "" {
+		return nil, errors.New("log file path is required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), logDirMode); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+
+	f := &RotatingFile{opts: opts}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// open opens the log file for appending.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFileMode)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("reading log file info: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+
+	return nil
+}
+
+// Write appends p to the log file, rotating it first if writing p would make it too big
+// or if it is too old.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize
+	tooOld := f.opts.RotateInterval > 0 && time.Since(f.openedAt) >= f.opts.RotateInterval
+
+	if tooBig || tooOld {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Close closes the log file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+// rotate renames the log file with the current time as suffix, opens a new one
+// and removes the rotated files which are not retained anymore.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("closing log file: %w", err)
+	}
+
+	f.file = nil
+
+	backup := f.opts.Path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(f.opts.Path, backup); err != nil {
+		return fmt.Errorf("rotating log file: %w", err)
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.removeOldBackups()
+
+	return nil
+}
+
+// removeOldBackups removes the rotated files beyond MaxBackups or older than MaxAge.
+func (f *RotatingFile) removeOldBackups() {
+	backups, err := filepath.Glob(f.opts.Path + ".*")
+	if err != nil {
+		return
+	}
+
+	// The time suffix sorts backups from the oldest to the newest.
+	sort.Strings(backups)
+
+	for i, backup := range backups {
+		suffix := strings.TrimPrefix(backup, f.opts.Path+".")
+
+		rotatedAt, err := time.ParseInLocation(backupTimeFormat, suffix, time.Local)
+		if err != nil {
+			// Not a file rotated by us.
+			continue
+		}
+
+		tooMany := f.opts.MaxBackups > 0 && i < len(backups)-f.opts.MaxBackups
+		tooOld := f.opts.MaxAge > 0 && time.Since(rotatedAt) > f.opts.MaxAge
+
+		if tooMany || tooOld {
+			_ = os.Remove(backup)
+		}
+	}
+}
+
+// AddFileSink makes the logger write to a rotating log file, in addition to stderr.
+// The returned RotatingFile should be closed when the program exits.
+func AddFileSink(opts FileOptions) (*RotatingFile, error) {
+	file, err := NewRotatingFile(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	zlog.Logger = zerolog.New(io.MultiWriter(os.Stderr, file)).With().Timestamp().Logger()
+
+	return file, nil
+}
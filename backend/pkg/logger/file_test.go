@@ -0,0 +1,93 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_Size(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "headlamp.log")
+
+	f, err := logger.NewRotatingFile(logger.FileOptions{Path: path, MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	for _, line := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+
+		// Rotated files are named after the rotation time, in milliseconds.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "line four\n", string(data))
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 2, "only MaxBackups rotated files are kept")
+
+	data, err = os.ReadFile(backups[1])
+	require.NoError(t, err)
+	assert.Equal(t, "line three\n", string(data))
+}
+
+func TestRotatingFile_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headlamp.log")
+
+	f, err := logger.NewRotatingFile(logger.FileOptions{Path: path, RotateInterval: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	_, err = f.Write([]byte("old\n"))
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = f.Write([]byte("new\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.True(t, strings.HasPrefix(filepath.Base(backups[0]), "headlamp.log."))
+}
+
+func TestRotatingFile_Closed(t *testing.T) {
+	f, err := logger.NewRotatingFile(logger.FileOptions{Path: filepath.Join(t.TempDir(), "headlamp.log")})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = f.Write([]byte("line\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	var logged []map[string]string
+
+	originalLogFunc := SetLogFunc(func(level uint, str map[string]string, err interface{}, msg string) {
+		if msg == "hot" {
+			logged = append(logged, str)
+		}
+	})
+	defer SetLogFunc(originalLogFunc)
+
+	now := time.Now()
+	sampler := NewSampler(2, 3, time.Minute)
+	sampler.now = func() time.Time { return now }
+
+	for range 8 {
+		sampler.Log(LevelInfo, nil, nil, "hot")
+	}
+
+	// The first 2, then the 5th and 8th, each with the logs dropped before it.
+	assert.Equal(t, []map[string]string{nil, nil, {SampledField: "2"}, {SampledField: "2"}}, logged)
+
+	// Other messages are sampled separately.
+	ok, _ := sampler.sample("cold")
+	assert.True(t, ok)
+
+	sampler.Log(LevelInfo, nil, nil, "hot")
+
+	// The next interval starts over, reporting the logs dropped at the end of the previous one.
+	now = now.Add(time.Minute)
+	logged = nil
+
+	sampler.Log(LevelInfo, map[string]string{"key": "value"}, nil, "hot")
+	sampler.Log(LevelInfo, nil, nil, "hot")
+
+	assert.Equal(t, []map[string]string{{"key": "value", SampledField: "1"}, nil}, logged)
+}
+
+func TestSamplerWithoutEvery(t *testing.T) {
+	sampler := NewSampler(1, 0, time.Minute)
+
+	ok, _ := sampler.sample("msg")
+	assert.True(t, ok)
+
+	for range 10 {
+		ok, _ = sampler.sample("msg")
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// The components whose log level can be set separately, with a level spec like "info,proxy=debug".
+const (
+	ComponentKubeconfig  = "kubeconfig"
+	ComponentProxy       = "proxy"
+	ComponentMultiplexer = "multiplexer"
+	ComponentHelm        = "helm"
+	ComponentPlugins     = "plugins"
+)
+
+// ComponentField is the log field of the component a log comes from.
+const ComponentField = "component"
+
+// components are the components whose log level can be set.
+var components = []string{
+	ComponentKubeconfig, ComponentProxy, ComponentMultiplexer, ComponentHelm, ComponentPlugins,
+}
+
+// componentSources maps source paths to the component of the logs from there.
+var componentSources = map[string]string{
+	"/pkg/kubeconfig/": ComponentKubeconfig,
+	"/pkg/helm/":       ComponentHelm,
+	"/pkg/plugins/":    ComponentPlugins,
+	"/cmd/multiplexer": ComponentMultiplexer,
+	"/cmd/sse.go":      ComponentMultiplexer,
+}
+
+// levelNames maps the names of the log levels to their zerolog level.
+var levelNames = map[string]zerolog.Level{
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+}
+
+// levelConfig is a log level, with overrides for some components.
+type levelConfig struct {
+	level      zerolog.Level
+	components map[string]zerolog.Level
+}
+
+// levelFor returns the log level of a component, "" being no component in particular.
+func (c *levelConfig) levelFor(component string) zerolog.Level {
+	if level, ok := c.components[component]; ok {
+		return level
+	}
+
+	return c.level
+}
+
+// minLevel returns the most verbose level of the config.
+func (c *levelConfig) minLevel() zerolog.Level {
+	level := c.level
+
+	for _, l := range c.components {
+		level = min(level, l)
+	}
+
+	return level
+}
+
+// String returns the level spec of the config, e.g. "info,proxy=debug".
+func (c *levelConfig) String() string {
+	parts := []string{c.level.String()}
+
+	for _, component := range slices.Sorted(maps.Keys(c.components)) {
+		parts = append(parts, component+"="+c.components[component].String())
+	}
+
+	return strings.Join(parts, ",")
+}
+
+var (
+	// levelMu protects the log level state below.
+	levelMu sync.Mutex
+	// baseLevel is the level config to go back to after a temporary level change
+	// or after debug logging is toggled off.
+	baseLevel = &levelConfig{level: zerolog.InfoLevel}
+	// revertTimer reverts a temporary level change.
+	revertTimer *time.Timer
+	// currentLevel is the level config in use. It is read on every log, without levelMu.
+	currentLevel atomic.Pointer[levelConfig]
+)
+
+func init() {
+	applyLevel(baseLevel)
+}
+
+// applyLevel makes config the level config in use. The zerolog global level is set to
+// the most verbose level of the config, log filters the other components further.
+func applyLevel(config *levelConfig) {
+	currentLevel.Store(config)
+	zerolog.SetGlobalLevel(config.minLevel())
+}
+
+// parseLevel returns the zerolog level of a level name.
+func parseLevel(name string) (zerolog.Level, error) {
+	level, ok := levelNames[name]
+	if !ok {
+		return zerolog.NoLevel, fmt.Errorf("invalid log level %q, must be one of debug, info, warn or error", name)
+	}
+
+	return level, nil
+}
+
+// parseLevelSpec parses a level spec: a level, optionally followed by comma separated
+// component=level overrides, e.g. "info,proxy=debug".
+func parseLevelSpec(spec string) (*levelConfig, error) {
+	parts := strings.Split(spec, ",")
+
+	level, err := parseLevel(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	config := &levelConfig{level: level, components: map[string]zerolog.Level{}}
+
+	for _, part := range parts[1:] {
+		component, name, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid component log level %q, must be component=level", part)
+		}
+
+		if !slices.Contains(components, component) {
+			return nil, fmt.Errorf("invalid log component %q, must be one of %s",
+				component, strings.Join(components, ", "))
+		}
+
+		if config.components[component], err = parseLevel(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// componentForSource returns the component of the logs from a source file, or "" if it
+// isn't part of one.
+func componentForSource(file string) string {
+	for path, component := range componentSources {
+		if strings.Contains(file, path) {
+			return component
+		}
+	}
+
+	return ""
+}
+
+// enabled tells whether a log at level from component is to be logged.
+func enabled(level uint, component string) bool {
+	config := currentLevel.Load()
+	if len(config.components) == 0 {
+		// The zerolog global level filters the logs.
+		return true
+	}
+
+	var l zerolog.Level
+
+	switch level {
+	case LevelWarn:
+		l = zerolog.WarnLevel
+	case LevelError:
+		l = zerolog.ErrorLevel
+	case LevelDebug:
+		l = zerolog.DebugLevel
+	default:
+		l = zerolog.InfoLevel
+	}
+
+	return l >= config.levelFor(component)
+}
+
+// stopRevert cancels a pending revert of a temporary level change. levelMu must be held.
+func stopRevert() {
+	if revertTimer != nil {
+		revertTimer.Stop()
+		revertTimer = nil
+	}
+}
+
+// SetLevel sets the log level: debug, info, warn or error, optionally followed by
+// overrides for some components, e.g. "info,proxy=debug".
+func SetLevel(spec string) error {
+	config, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	stopRevert()
+
+	baseLevel = config
+	applyLevel(config)
+
+	return nil
+}
+
+// SetLevelFor sets the log level spec for the given duration, after which the previous
+// level is restored. It is meant to enable debug logging temporarily.
+func SetLevelFor(spec string, d time.Duration) error {
+	config, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	stopRevert()
+
+	applyLevel(config)
+
+	var timer *time.Timer
+
+	timer = time.AfterFunc(d, func() {
+		levelMu.Lock()
+		defer levelMu.Unlock()
+
+		// The level was changed again in the meantime.
+		if revertTimer != timer {
+			return
+		}
+
+		revertTimer = nil
+
+		applyLevel(baseLevel)
+	})
+	revertTimer = timer
+
+	return nil
+}
+
+// ToggleDebug switches every component to the debug level, or back to the previous
+// level if it is debug already. It returns the new level spec.
+func ToggleDebug() string {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	stopRevert()
+
+	if currentLevel.Load().String() == zerolog.DebugLevel.String() {
+		applyLevel(baseLevel)
+	} else {
+		applyLevel(&levelConfig{level: zerolog.DebugLevel})
+	}
+
+	return currentLevel.Load().String()
+}
+
+// GetLevel returns the current log level spec, e.g. "info" or "info,proxy=debug".
+func GetLevel() string {
+	return currentLevel.Load().String()
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(func() { _ = logger.SetLevel("info") })
+
+	assert.Equal(t, "info", logger.GetLevel())
+
+	require.NoError(t, logger.SetLevel("warn"))
+	assert.Equal(t, "warn", logger.GetLevel())
+
+	assert.Error(t, logger.SetLevel("verbose"))
+	assert.Equal(t, "warn", logger.GetLevel())
+
+	// Toggling debug goes back to the level set before.
+	assert.Equal(t, "debug", logger.ToggleDebug())
+	assert.Equal(t, "warn", logger.ToggleDebug())
+}
+
+func TestSetLevelFor(t *testing.T) {
+	t.Cleanup(func() { _ = logger.SetLevel("info") })
+
+	require.NoError(t, logger.SetLevel("info"))
+	require.NoError(t, logger.SetLevelFor("debug", 50*time.Millisecond))
+	assert.Equal(t, "debug", logger.GetLevel())
+
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == "info"
+	}, time.Second, 10*time.Millisecond)
+
+	// Setting the level cancels the revert.
+	require.NoError(t, logger.SetLevelFor("debug", 50*time.Millisecond))
+	require.NoError(t, logger.SetLevel("error"))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "error", logger.GetLevel())
+}
+
+func TestSetLevelComponents(t *testing.T) {
+	t.Cleanup(func() { _ = logger.SetLevel("info") })
+
+	require.NoError(t, logger.SetLevel("info, proxy=debug,helm=error"))
+	assert.Equal(t, "info,helm=error,proxy=debug", logger.GetLevel())
+
+	for _, spec := range []string{"info,proxy", "info,unknown=debug", "info,proxy=verbose", "proxy=debug"} {
+		assert.Error(t, logger.SetLevel(spec), spec)
+	}
+
+	assert.Equal(t, "info,helm=error,proxy=debug", logger.GetLevel())
+
+	// Toggling debug sets every component to debug, and goes back to the overrides.
+	assert.Equal(t, "debug", logger.ToggleDebug())
+	assert.Equal(t, "info,helm=error,proxy=debug", logger.ToggleDebug())
+}
+
+func TestComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+
+	originalLogger := zlog.Logger
+	zlog.Logger = zerolog.New(&buf)
+
+	t.Cleanup(func() {
+		zlog.Logger = originalLogger
+		_ = logger.SetLevel("info")
+	})
+
+	require.NoError(t, logger.SetLevel("info,proxy=debug,helm=error"))
+
+	proxyCtx := logger.WithComponent(context.Background(), logger.ComponentProxy)
+	helmCtx := logger.WithComponent(context.Background(), logger.ComponentHelm)
+
+	logger.LogCtx(proxyCtx, logger.LevelDebug, nil, nil, "proxy debug")
+	logger.LogCtx(helmCtx, logger.LevelInfo, nil, nil, "helm info")
+	logger.LogCtx(helmCtx, logger.LevelError, nil, nil, "helm error")
+	logger.Log(logger.LevelDebug, nil, nil, "default debug")
+	logger.Log(logger.LevelInfo, nil, nil, "default info")
+
+	logs := buf.String()
+	assert.Contains(t, logs, "proxy debug")
+	assert.Contains(t, logs, `"component":"proxy"`)
+	assert.NotContains(t, logs, "helm info")
+	assert.Contains(t, logs, "helm error")
+	assert.NotContains(t, logs, "default debug")
+	assert.Contains(t, logs, "default info")
+}
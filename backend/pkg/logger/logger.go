@@ -30,6 +30,9 @@ const (
 	LevelWarn
 	// LevelError is the error level.
 	LevelError
+	// LevelDebug is the debug level. Debug messages are only logged when
+	// the log level is debug, see SetLevel.
+	LevelDebug
 )
 
 // callerDepth is the depth of the caller in the stack.
@@ -48,8 +51,19 @@ func Log(level uint, str map[string]string, err interface{}, msg string) {
 
 // Log is a wrapper function for logging. It uses zlog package and logs to stdout.
 // It logs the message, source file and line number.
-// It logs the message at the level specified.
+// It logs the message at the level specified, if the level of its component allows it.
 func log(level uint, str map[string]string, err interface{}, msg string) {
+	_, file, line, ok := runtime.Caller(callerDepth)
+
+	component := str[ComponentField]
+	if component == "" && ok {
+		component = componentForSource(file)
+	}
+
+	if !enabled(level, component) {
+		return
+	}
+
 	var event *zerolog.Event
 
 	switch level {
@@ -59,15 +73,25 @@ func log(level uint, str map[string]string, err interface{}, msg string) {
 		event = zlog.Warn()
 	case LevelError:
 		event = zlog.Error()
+	case LevelDebug:
+		event = zlog.Debug()
 	default:
 		event = zlog.Info()
 	}
 
+	// The level is disabled.
+	if event == nil {
+		return
+	}
+
+	if component != "" && str[ComponentField] == "" {
+		event.Str(ComponentField, component)
+	}
+
 	for k, v := range str {
 		event.Str(k, v)
 	}
 
-	_, file, line, ok := runtime.Caller(callerDepth)
 	if ok {
 		event.Str("source", file)
 		event.Int("line", line)
@@ -92,9 +116,10 @@ func log(level uint, str map[string]string, err interface{}, msg string) {
 	}
 }
 
-// SetLogFunc sets the logging function.
+// SetLogFunc sets the logging function. It returns the previous one, so it can be restored.
 func SetLogFunc(lf LogFunc) LogFunc {
+	previous := logFunc
 	logFunc = lf
 
-	return logFunc
+	return previous
 }
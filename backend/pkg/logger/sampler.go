@@ -0,0 +1,128 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SampledField is the log field counting the logs a Sampler dropped since the last one it let through.
+const SampledField = "sampled_out"
+
+// Sampler rate limits the logs of hot paths. In every interval, the first logs of each
+// message are logged, then only one in every few. The errors are sampled like the other
+// logs, so a Sampler must not be used where every error has to be logged.
+type Sampler struct {
+	first    int
+	every    int
+	interval time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	counters map[string]*sampleCounter
+}
+
+// sampleCounter counts the logs of a message in the current interval.
+type sampleCounter struct {
+	start time.Time
+	count int
+	// dropped is the number of logs dropped since the last one logged.
+	dropped int
+}
+
+// NewSampler creates a Sampler logging, for each message, the first logs of every
+// interval, then one log in every. If every is less than 1, the logs after the first
+// ones are dropped until the next interval.
+func NewSampler(first, every int, interval time.Duration) *Sampler {
+	return &Sampler{
+		first:    first,
+		every:    every,
+		interval: interval,
+		now:      time.Now,
+		counters: map[string]*sampleCounter{},
+	}
+}
+
+// sample tells whether the log of msg is to be logged, and how many logs of msg were
+// dropped since the last one logged.
+func (s *Sampler) sample(msg string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	// The logs dropped at the end of the previous interval are reported with the next one logged.
+	counter, ok := s.counters[msg]
+	if !ok || now.Sub(counter.start) >= s.interval {
+		counter = &sampleCounter{start: now, dropped: dropped(counter)}
+		s.counters[msg] = counter
+	}
+
+	counter.count++
+
+	if counter.count <= s.first || (s.every > 0 && (counter.count-s.first)%s.every == 0) {
+		dropped := counter.dropped
+		counter.dropped = 0
+
+		return true, dropped
+	}
+
+	counter.dropped++
+
+	return false, 0
+}
+
+// dropped returns the logs dropped by counter, which may be nil.
+func dropped(counter *sampleCounter) int {
+	if counter == nil {
+		return 0
+	}
+
+	return counter.dropped
+}
+
+// sampledFields returns the fields with the number of logs dropped added, if any.
+// The fields given are left alone.
+func sampledFields(str map[string]string, dropped int) map[string]string {
+	if dropped == 0 {
+		return str
+	}
+
+	fields := make(map[string]string, len(str)+1)
+	maps.Copy(fields, str)
+	fields[SampledField] = strconv.Itoa(dropped)
+
+	return fields
+}
+
+// Log logs like Log, if the sampler lets the log through.
+func (s *Sampler) Log(level uint, str map[string]string, err interface{}, msg string) {
+	if ok, dropped := s.sample(msg); ok {
+		logFunc(level, sampledFields(str, dropped), err, msg)
+	}
+}
+
+// LogCtx logs like LogCtx, if the sampler lets the log through.
+func (s *Sampler) LogCtx(ctx context.Context, level uint, str map[string]string, err interface{}, msg string) {
+	if ok, dropped := s.sample(msg); ok {
+		logFunc(level, contextFields(ctx, sampledFields(str, dropped)), err, msg)
+	}
+}
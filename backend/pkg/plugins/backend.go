@@ -0,0 +1,478 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+const (
+	// BackendManifestFile is the file, in the plugin folder, describing the plugin backend process.
+	BackendManifestFile = "backend.json"
+	// BackendAuthToken makes the plugin endpoints require the backend token. It is the default.
+	BackendAuthToken = "token"
+	// BackendAuthNone makes the plugin endpoints public.
+	BackendAuthNone = "none"
+	// defaultBackendRateLimit is the default number of requests per second to a plugin backend.
+	defaultBackendRateLimit = 10
+	// backendStartTimeout is how long a plugin backend process has to listen on its socket.
+	backendStartTimeout = 10 * time.Second
+	// backendTokenHeader is the header with the backend token, which isn't forwarded to plugin processes.
+	backendTokenHeader = "X-HEADLAMP_BACKEND-TOKEN"
+)
+
+// backendEnv are the environment variables of the backend passed on to the plugin processes.
+// The others, like the backend token or the secrets of the config, are kept from them.
+var backendEnv = []string{
+	"PATH", "HOME", "TMPDIR",
+	// Needed by the processes on Windows.
+	"SYSTEMROOT", "USERPROFILE", "TEMP", "TMP",
+}
+
+// ErrBackendExists is returned when registering a plugin backend twice.
+var ErrBackendExists = errors.New("plugin backend already registered")
+
+// BackendOptions are the auth and rate limit of the endpoints of a plugin backend.
+type BackendOptions struct {
+	// Auth is BackendAuthToken, the default, or BackendAuthNone.
+	Auth string `json:"auth,omitempty"`
+	// RateLimit is the number of requests per second allowed, 10 by default.
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	// Burst is the number of requests allowed at once, twice the rate limit by default.
+	Burst int `json:"burst,omitempty"`
+}
+
+// BackendManifest describes the process serving the endpoints of a plugin. The process
+// serves HTTP on the Unix socket given in the HEADLAMP_PLUGIN_SOCKET environment variable.
+type BackendManifest struct {
+	// Command is the path of the executable, relative to the plugin folder.
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	BackendOptions
+}
+
+// backend serves the endpoints of a plugin.
+type backend struct {
+	handler http.Handler
+	opts    BackendOptions
+	limiter *tokenBucket
+	cmd     *exec.Cmd
+	socket  string
+}
+
+// Backends are the backends of the plugins, serving the endpoints plugins contribute
+// under /plugins/{name}/api. They are Go handlers registered with Register, or processes
+// started from the plugin folders.
+type Backends struct {
+	// Authorize checks that the request can use the endpoints requiring auth. It writes
+	// the error response when it returns an error.
+	Authorize func(w http.ResponseWriter, r *http.Request) error
+
+	mu        sync.RWMutex
+	backends  map[string]*backend
+	socketDir string
+}
+
+// NewBackends returns an empty set of plugin backends.
+func NewBackends() *Backends {
+	return &Backends{backends: map[string]*backend{}}
+}
+
+// Register registers the handler serving the endpoints of the plugin.
+func (b *Backends) Register(name string, handler http.Handler, opts BackendOptions) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	if opts.Auth == "" {
+		opts.Auth = BackendAuthToken
+	}
+
+	if opts.Auth != BackendAuthToken && opts.Auth != BackendAuthNone {
+		return fmt.Errorf("invalid auth %q of plugin backend %s", opts.Auth, name)
+	}
+
+	if opts.RateLimit <= 0 {
+		opts.RateLimit = defaultBackendRateLimit
+	}
+
+	if opts.Burst <= 0 {
+		opts.Burst = int(2 * opts.RateLimit)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.backends[name]; ok {
+		return fmt.Errorf("%w: %s", ErrBackendExists, name)
+	}
+
+	b.backends[name] = &backend{
+		handler: handler,
+		opts:    opts,
+		limiter: newTokenBucket(opts.RateLimit, opts.Burst),
+	}
+
+	return nil
+}
+
+// Unregister removes the backend of the plugin, stopping its process if it has one.
+func (b *Backends) Unregister(name string) {
+	b.mu.Lock()
+	be, ok := b.backends[name]
+	delete(b.backends, name)
+	b.mu.Unlock()
+
+	if ok {
+		be.stop()
+	}
+}
+
+// Has tells whether the plugin has a backend.
+func (b *Backends) Has(name string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, ok := b.backends[name]
+
+	return ok
+}
+
+// Names returns the names of the plugins with a backend.
+func (b *Backends) Names() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	names := make([]string, 0, len(b.backends))
+	for name := range b.backends {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// StartAll starts the backend processes of the plugins in pluginDir that have a backend
+// manifest and are allowed. Errors are logged.
+func (b *Backends) StartAll(ctx context.Context, pluginDir string, allowed func(dir string) bool) {
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Log(logger.LevelError, map[string]string{"pluginDir": pluginDir}, err, "reading plugin directory")
+		}
+
+		return
+	}
+
+	for _, entry := range entries {
+		dir := filepath.Join(pluginDir, entry.Name())
+
+		if !entry.IsDir() || ValidateName(entry.Name()) != nil {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, BackendManifestFile)); err != nil {
+			continue
+		}
+
+		if allowed != nil && !allowed(dir) {
+			logger.Log(logger.LevelWarn, map[string]string{"plugin": entry.Name()},
+				nil, "not starting backend of unverified plugin")
+
+			continue
+		}
+
+		if err := b.Start(ctx, entry.Name(), dir); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"plugin": entry.Name()}, err, "starting plugin backend")
+		}
+	}
+}
+
+// pluginEnv returns the environment of the plugin processes: the variables of backendEnv set
+// for the backend.
+func pluginEnv() []string {
+	env := make([]string, 0, len(backendEnv))
+
+	for _, name := range backendEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
+
+// Start starts the backend process of the plugin in dir, as described by its manifest,
+// and registers it once it listens on its socket.
+func (b *Backends) Start(ctx context.Context, name, dir string) error {
+	manifest, err := readBackendManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	socketDir, err := b.ensureSocketDir()
+	if err != nil {
+		return err
+	}
+
+	socket := filepath.Join(socketDir, name+".sock")
+	_ = os.Remove(socket)
+
+	cmd := exec.Command(filepath.Join(dir, filepath.FromSlash(manifest.Command)), manifest.Args...) //nolint:gosec
+	cmd.Dir = dir
+	cmd.Env = append(pluginEnv(), "HEADLAMP_PLUGIN_SOCKET="+socket, "HEADLAMP_PLUGIN_NAME="+name)
+	cmd.Stdout = &processLogger{plugin: name, level: logger.LevelInfo}
+	cmd.Stderr = &processLogger{plugin: name, level: logger.LevelWarn}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", manifest.Command, err)
+	}
+
+	exited := make(chan error, 1)
+
+	go func() {
+		err := cmd.Wait()
+		logger.Log(logger.LevelWarn, map[string]string{"plugin": name}, err, "plugin backend exited")
+		exited <- err
+	}()
+
+	if err := waitForSocket(ctx, socket, exited); err != nil {
+		_ = cmd.Process.Kill()
+
+		return err
+	}
+
+	if err := b.Register(name, socketProxy(socket), manifest.BackendOptions); err != nil {
+		_ = cmd.Process.Kill()
+
+		return err
+	}
+
+	b.mu.Lock()
+	b.backends[name].cmd = cmd
+	b.backends[name].socket = socket
+	b.mu.Unlock()
+
+	logger.Log(logger.LevelInfo, map[string]string{"plugin": name}, nil, "plugin backend started")
+
+	return nil
+}
+
+// Stop stops all the plugin backend processes, and unregisters all the backends.
+func (b *Backends) Stop() {
+	for _, name := range b.Names() {
+		b.Unregister(name)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.socketDir != "" {
+		_ = os.RemoveAll(b.socketDir)
+		b.socketDir = ""
+	}
+}
+
+// Serve serves the request to the endpoints of the plugin, whose path is relative to /plugins/{name}/api.
+func (b *Backends) Serve(w http.ResponseWriter, r *http.Request, name string) {
+	b.mu.RLock()
+	be, ok := b.backends[name]
+	b.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, "plugin backend not found", http.StatusNotFound)
+
+		return
+	}
+
+	if be.opts.Auth != BackendAuthNone && b.Authorize != nil {
+		if err := b.Authorize(w, r); err != nil {
+			return
+		}
+	}
+
+	if !be.limiter.allow() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "plugin backend rate limit exceeded", http.StatusTooManyRequests)
+
+		return
+	}
+
+	be.handler.ServeHTTP(w, r)
+}
+
+func (b *Backends) ensureSocketDir() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.socketDir == "" {
+		dir, err := os.MkdirTemp("", "headlamp-plugins-")
+		if err != nil {
+			return "", err
+		}
+
+		b.socketDir = dir
+	}
+
+	return b.socketDir, nil
+}
+
+func (be *backend) stop() {
+	if be.cmd != nil && be.cmd.Process != nil {
+		_ = be.cmd.Process.Kill()
+	}
+
+	if be.socket != "" {
+		_ = os.Remove(be.socket)
+	}
+}
+
+// readBackendManifest reads the backend manifest of the plugin in dir.
+func readBackendManifest(dir string) (BackendManifest, error) {
+	var manifest BackendManifest
+
+	content, err := os.ReadFile(filepath.Join(dir, BackendManifestFile))
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return manifest, fmt.Errorf("parsing %s: %w", BackendManifestFile, err)
+	}
+
+	command := filepath.Clean(filepath.FromSlash(manifest.Command))
+	if manifest.Command == "" || filepath.IsAbs(command) || command == ".." ||
+		strings.HasPrefix(command, ".."+string(filepath.Separator)) {
+		return manifest, fmt.Errorf("invalid command %q, it must be relative to the plugin folder", manifest.Command)
+	}
+
+	return manifest, nil
+}
+
+// waitForSocket waits for a server to listen on the socket.
+func waitForSocket(ctx context.Context, socket string, exited <-chan error) error {
+	ctx, cancel := context.WithTimeout(ctx, backendStartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(50 * time.Millisecond) //nolint:mnd
+	defer ticker.Stop()
+
+	var dialer net.Dialer
+
+	for {
+		conn, err := dialer.DialContext(ctx, "unix", socket)
+		if err == nil {
+			return conn.Close()
+		}
+
+		select {
+		case err := <-exited:
+			return fmt.Errorf("plugin backend exited before listening: %w", err)
+		case <-ctx.Done():
+			return fmt.Errorf("plugin backend not listening on its socket: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// socketProxy returns a reverse proxy to the HTTP server listening on the Unix socket.
+func socketProxy(socket string) http.Handler {
+	target := &url.URL{Scheme: "http", Host: "plugin"}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Header.Del(backendTokenHeader)
+	}
+
+	proxy.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+
+	return proxy
+}
+
+// processLogger logs the output of a plugin backend process.
+type processLogger struct {
+	plugin string
+	level  uint
+}
+
+func (l *processLogger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		logger.Log(l.level, map[string]string{"plugin": l.plugin}, nil, line)
+	}
+
+	return len(p), nil
+}
+
+// tokenBucket allows rate requests per second, and bursts of burst requests.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+func (t *tokenBucket) allow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if !t.last.IsZero() {
+		t.tokens = min(t.burst, t.tokens+now.Sub(t.last).Seconds()*t.rate)
+	}
+
+	t.last = now
+
+	if t.tokens < 1 {
+		return false
+	}
+
+	t.tokens--
+
+	return true
+}
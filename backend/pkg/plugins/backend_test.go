@@ -0,0 +1,196 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveBackend serves the request to the plugin backend, and returns the response.
+func serveBackend(backends *plugins.Backends, name, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	rr := httptest.NewRecorder()
+	backends.Serve(rr, req, name)
+
+	return rr
+}
+
+func TestBackendsRegister(t *testing.T) {
+	backends := plugins.NewBackends()
+	backends.Authorize = func(w http.ResponseWriter, r *http.Request) error {
+		if r.Header.Get("X-HEADLAMP_BACKEND-TOKEN") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+
+			return errors.New("invalid token")
+		}
+
+		return nil
+	}
+
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "hello "+r.URL.Path)
+	})
+
+	require.NoError(t, backends.Register("private", hello, plugins.BackendOptions{}))
+	require.NoError(t, backends.Register("public", hello, plugins.BackendOptions{
+		Auth: plugins.BackendAuthNone, RateLimit: 0.001, Burst: 1,
+	}))
+
+	err := backends.Register("public", hello, plugins.BackendOptions{})
+	assert.ErrorIs(t, err, plugins.ErrBackendExists)
+
+	err = backends.Register("other", hello, plugins.BackendOptions{Auth: "basic"})
+	assert.Error(t, err)
+
+	rr := serveBackend(backends, "private", "/items", nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = serveBackend(backends, "private", "/items", map[string]string{"X-HEADLAMP_BACKEND-TOKEN": "secret"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "hello /items", rr.Body.String())
+
+	rr = serveBackend(backends, "public", "/", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = serveBackend(backends, "public", "/", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+
+	rr = serveBackend(backends, "missing", "/", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	backends.Unregister("private")
+	assert.Equal(t, []string{"public"}, backends.Names())
+}
+
+// TestHelperPluginBackend isn't a real test. It is the plugin backend process started by
+// TestBackendsProcess, serving on the socket it is given. It answers /env with its environment.
+func TestHelperPluginBackend(t *testing.T) {
+	socket := os.Getenv("HEADLAMP_PLUGIN_SOCKET")
+	if socket == "" {
+		t.Skip("only run as a plugin backend process")
+	}
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	_ = http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { //nolint:gosec
+		if r.URL.Path == "/env" {
+			_, _ = fmt.Fprint(w, strings.Join(os.Environ(), "\n"))
+
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, "%s %s %q", os.Getenv("HEADLAMP_PLUGIN_NAME"), r.URL.Path,
+			r.Header.Get("X-HEADLAMP_BACKEND-TOKEN"))
+	}))
+}
+
+func TestBackendsProcess(t *testing.T) {
+	// The secrets of the backend aren't passed on to the plugin processes.
+	t.Setenv("HEADLAMP_BACKEND_TOKEN", "backend-secret")
+	t.Setenv("HEADLAMP_HELM_CREDENTIALS_KEY", "helm-secret")
+	t.Setenv("HEADLAMP_CONFIG_OIDC_CLIENT_SECRET", "oidc-secret")
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	pluginDir := t.TempDir()
+	dir := filepath.Join(pluginDir, "my-plugin")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0o755))
+	require.NoError(t, os.Symlink(executable, filepath.Join(dir, "bin", "backend")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, plugins.BackendManifestFile), []byte(`{
+		"command": "bin/backend",
+		"args": ["-test.run=^TestHelperPluginBackend$"]
+	}`), 0o600))
+
+	// Not allowed, so not started.
+	unverified := filepath.Join(pluginDir, "unverified")
+	require.NoError(t, os.Mkdir(unverified, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(unverified, plugins.BackendManifestFile),
+		[]byte(`{"command": "backend"}`), 0o600))
+
+	backends := plugins.NewBackends()
+	t.Cleanup(backends.Stop)
+
+	backends.StartAll(context.Background(), pluginDir, func(dir string) bool {
+		return filepath.Base(dir) != "unverified"
+	})
+	require.Equal(t, []string{"my-plugin"}, backends.Names())
+
+	rr := serveBackend(backends, "my-plugin", "/items", map[string]string{"X-HEADLAMP_BACKEND-TOKEN": "secret"})
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `my-plugin /items ""`, string(body))
+
+	rr = serveBackend(backends, "my-plugin", "/env", map[string]string{"X-HEADLAMP_BACKEND-TOKEN": "secret"})
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	env := rr.Body.String()
+	assert.Contains(t, env, "HEADLAMP_PLUGIN_NAME=my-plugin")
+	assert.NotContains(t, env, "backend-secret")
+	assert.NotContains(t, env, "helm-secret")
+	assert.NotContains(t, env, "oidc-secret")
+
+	backends.Stop()
+	assert.Empty(t, backends.Names())
+}
+
+func TestBackendsStartErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{name: "absolute", manifest: `{"command": "/bin/sh"}`},
+		{name: "outside", manifest: `{"command": "../backend"}`},
+		{name: "missing", manifest: `{"command": "backend"}`},
+		{name: "invalid", manifest: `{`},
+	}
+
+	backends := plugins.NewBackends()
+	t.Cleanup(backends.Stop)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, plugins.BackendManifestFile), []byte(tt.manifest), 0o600))
+
+			assert.Error(t, backends.Start(context.Background(), tt.name, dir))
+		})
+	}
+
+	assert.Empty(t, backends.Names())
+}
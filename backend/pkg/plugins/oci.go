@@ -0,0 +1,866 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+const (
+	// OCISourceFile is the file, in the plugin folder, recording the artifact a plugin was installed from.
+	OCISourceFile = ".oci-source.json"
+	// maxBundleSize is the maximum size of a plugin bundle or manifest.
+	maxBundleSize = 100 << 20
+	// maxBundleFiles is the maximum number of files in a plugin bundle.
+	maxBundleFiles = 10000
+	// maxExtractedSize is the maximum total size of the files extracted from a plugin bundle.
+	maxExtractedSize = 500 << 20
+)
+
+// Phases of a plugin installation.
+const (
+	PhaseResolving   = "resolving"
+	PhaseDownloading = "downloading"
+	PhaseExtracting  = "extracting"
+	PhaseInstalled   = "installed"
+	PhaseFailed      = "failed"
+)
+
+// manifestMediaTypes are the manifest media types accepted from registries.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// bundleMediaTypes are the layer media types accepted as plugin bundles, all gzipped tarballs.
+var bundleMediaTypes = []string{
+	"application/vnd.headlamp.plugin.v1.tar+gzip",
+	"application/vnd.oci.image.layer.v1.tar+gzip",
+	"application/vnd.docker.image.rootfs.diff.tar.gzip",
+	"application/tar+gzip",
+}
+
+var (
+	// ErrPluginExists is returned when installing a plugin that is already installed.
+	ErrPluginExists = errors.New("plugin already installed")
+	// ErrPluginNotFromCatalog is returned when upgrading or uninstalling a plugin not installed from a registry.
+	ErrPluginNotFromCatalog = errors.New("plugin not installed from an OCI registry")
+	// ErrOperationInProgress is returned when the plugin is already being installed or upgraded.
+	ErrOperationInProgress = errors.New("plugin operation in progress")
+	// ErrDigestMismatch is returned when downloaded content doesn't match its digest.
+	ErrDigestMismatch = errors.New("digest mismatch")
+	// ErrBundleTooLarge is returned when the files of a plugin bundle are larger than allowed.
+	ErrBundleTooLarge = errors.New("plugin bundle too large")
+)
+
+// Reference is a reference to an artifact in an OCI registry,
+// like ghcr.io/org/plugin:1.0.0 or ghcr.io/org/plugin@sha256:....
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an OCI artifact reference. The registry host is required,
+// and the tag defaults to latest when there is no digest.
+func ParseReference(ref string) (Reference, error) {
+	var r Reference
+
+	host, rest, found := strings.Cut(ref, "/")
+	if !found || host == "" || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return r, fmt.Errorf("invalid reference %q: it must start with the registry host", ref)
+	}
+
+	r.Registry = host
+
+	if repo, digest, ok := strings.Cut(rest, "@"); ok {
+		if !validDigest(digest) {
+			return r, fmt.Errorf("invalid reference %q: only sha256 digests are supported", ref)
+		}
+
+		r.Digest = digest
+		rest = repo
+	}
+
+	// A colon after the last slash separates the tag.
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		r.Tag = rest[i+1:]
+		rest = rest[:i]
+	}
+
+	if rest == "" || strings.Contains(rest, "//") || strings.ToLower(rest) != rest {
+		return r, fmt.Errorf("invalid reference %q: invalid repository", ref)
+	}
+
+	r.Repository = rest
+
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+
+	return r, nil
+}
+
+// validDigest checks that digest is a sha256 digest, like sha256:<64 hex digits>.
+func validDigest(digest string) bool {
+	algorithm, encoded, _ := strings.Cut(digest, ":")
+	if algorithm != "sha256" || len(encoded) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(encoded)
+
+	return err == nil && strings.ToLower(encoded) == encoded
+}
+
+// String returns the reference in its canonical form.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+
+	return s
+}
+
+// Source is the artifact an installed plugin comes from.
+type Source struct {
+	Name   string `json:"name"`
+	Ref    string `json:"ref"`
+	Digest string `json:"digest"`
+}
+
+// Progress is the state of the last installation or upgrade of a plugin.
+type Progress struct {
+	Name      string `json:"name"`
+	Ref       string `json:"ref"`
+	Phase     string `json:"phase"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Catalog installs, upgrades and uninstalls plugin bundles stored in OCI registries.
+// Downloaded bundles are cached by digest in the cache dir.
+type Catalog struct {
+	// PluginDir is where the plugins are installed.
+	PluginDir string
+	// CacheDir is where the downloaded bundles are cached.
+	CacheDir string
+	// Client makes the registry requests.
+	Client *http.Client
+
+	mu       sync.Mutex
+	progress map[string]*Progress
+	active   map[string]bool
+}
+
+// NewCatalog returns a catalog installing plugins in pluginDir and caching bundles in cacheDir.
+func NewCatalog(pluginDir, cacheDir string) *Catalog {
+	return &Catalog{
+		PluginDir: pluginDir,
+		CacheDir:  cacheDir,
+		Client:    http.DefaultClient,
+		progress:  map[string]*Progress{},
+		active:    map[string]bool{},
+	}
+}
+
+// DefaultCacheDir returns the default dir to cache plugin bundles in.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "Headlamp", "plugin-bundles")
+}
+
+// ValidateName checks that name can be used as a plugin folder name.
+func ValidateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") ||
+		strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid plugin name %q", name)
+	}
+
+	return nil
+}
+
+// Install installs the plugin from the artifact ref as name. It fails if a plugin with that name exists.
+func (c *Catalog) Install(ctx context.Context, name, ref string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(c.PluginDir, name)); err == nil {
+		return fmt.Errorf("%w: %s", ErrPluginExists, name)
+	}
+
+	return c.install(ctx, name, ref)
+}
+
+// Upgrade reinstalls the plugin from the artifact ref, or from the ref it was installed from if ref is empty.
+func (c *Catalog) Upgrade(ctx context.Context, name, ref string) error {
+	source, err := c.Source(name)
+	if err != nil {
+		return err
+	}
+
+	if ref == "" {
+		ref = source.Ref
+	}
+
+	return c.install(ctx, name, ref)
+}
+
+// Uninstall removes a plugin installed from a registry.
+func (c *Catalog) Uninstall(name string) error {
+	if _, err := c.Source(name); err != nil {
+		return err
+	}
+
+	if !c.begin(name) {
+		return fmt.Errorf("%w: %s", ErrOperationInProgress, name)
+	}
+	defer c.end(name)
+
+	if err := Delete(c.PluginDir, name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.progress, name)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Source returns the artifact the plugin was installed from.
+func (c *Catalog) Source(name string) (Source, error) {
+	var source Source
+
+	if err := ValidateName(name); err != nil {
+		return source, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(c.PluginDir, name, OCISourceFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return source, fmt.Errorf("%w: %s", ErrPluginNotFromCatalog, name)
+		}
+
+		return source, err
+	}
+
+	if err := json.Unmarshal(content, &source); err != nil {
+		return source, fmt.Errorf("reading source of plugin %s: %w", name, err)
+	}
+
+	return source, nil
+}
+
+// Installed lists the plugins installed from registries.
+func (c *Catalog) Installed() ([]Source, error) {
+	entries, err := os.ReadDir(c.PluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Source{}, nil
+		}
+
+		return nil, err
+	}
+
+	sources := []Source{}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateName(entry.Name()) != nil {
+			continue
+		}
+
+		source, err := c.Source(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// Progress returns the state of the last installation or upgrade of the plugin.
+func (c *Catalog) Progress(name string) (Progress, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.progress[name]
+	if !ok {
+		return Progress{}, false
+	}
+
+	return *p, true
+}
+
+// EnsureInstalled installs the plugins, given as name to ref, that are missing
+// or were installed from a different ref. Errors are logged.
+func (c *Catalog) EnsureInstalled(ctx context.Context, refs map[string]string) {
+	for name, ref := range refs {
+		source, err := c.Source(name)
+
+		switch {
+		case err == nil && source.Ref == ref:
+			continue
+		case err == nil:
+			err = c.Upgrade(ctx, name, ref)
+		case errors.Is(err, ErrPluginNotFromCatalog):
+			err = c.Install(ctx, name, ref)
+		}
+
+		if err != nil {
+			logger.Log(logger.LevelError, map[string]string{"plugin": name, "ref": ref}, err, "installing plugin")
+		}
+	}
+}
+
+func (c *Catalog) begin(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active[name] {
+		return false
+	}
+
+	c.active[name] = true
+
+	return true
+}
+
+func (c *Catalog) end(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.active, name)
+}
+
+// update changes the progress of the plugin installation.
+func (c *Catalog) update(name string, f func(p *Progress)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f(c.progress[name])
+}
+
+func (c *Catalog) install(ctx context.Context, name, ref string) error {
+	if !c.begin(name) {
+		return fmt.Errorf("%w: %s", ErrOperationInProgress, name)
+	}
+	defer c.end(name)
+
+	c.mu.Lock()
+	c.progress[name] = &Progress{Name: name, Ref: ref, Phase: PhaseResolving}
+	c.mu.Unlock()
+
+	digest, err := c.installBundle(ctx, name, ref)
+	if err != nil {
+		c.update(name, func(p *Progress) {
+			p.Phase = PhaseFailed
+			p.Error = err.Error()
+		})
+
+		logger.Log(logger.LevelError, map[string]string{"plugin": name, "ref": ref}, err, "installing plugin")
+
+		return err
+	}
+
+	c.update(name, func(p *Progress) {
+		p.Phase = PhaseInstalled
+		p.Digest = digest
+	})
+
+	logger.Log(logger.LevelInfo, map[string]string{"plugin": name, "ref": ref, "digest": digest},
+		nil, "plugin installed")
+
+	return nil
+}
+
+// installBundle downloads the bundle of ref and installs it as name. It returns the digest of the bundle.
+func (c *Catalog) installBundle(ctx context.Context, name, ref string) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	registry := &registryClient{client: c.Client, ref: r}
+
+	layer, err := registry.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.update(name, func(p *Progress) {
+		p.Phase = PhaseDownloading
+		p.Total = layer.Size
+		p.Digest = layer.Digest
+	})
+
+	bundle, err := c.fetchBundle(ctx, registry, layer, func(n int64) {
+		c.update(name, func(p *Progress) { p.Completed = n })
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c.update(name, func(p *Progress) { p.Phase = PhaseExtracting })
+
+	source := Source{Name: name, Ref: ref, Digest: layer.Digest}
+	if err := c.extract(bundle, source); err != nil {
+		return "", err
+	}
+
+	return layer.Digest, nil
+}
+
+// fetchBundle returns the path of the cached bundle, downloading it if it isn't cached.
+func (c *Catalog) fetchBundle(ctx context.Context, registry *registryClient, layer descriptor,
+	progress func(n int64),
+) (string, error) {
+	_, encoded, _ := strings.Cut(layer.Digest, ":")
+	blobPath := filepath.Join(c.CacheDir, "blobs", "sha256", encoded)
+
+	if err := verifyFile(blobPath, layer.Digest); err == nil {
+		progress(layer.Size)
+
+		return blobPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0o755); err != nil {
+		return "", err
+	}
+
+	body, err := registry.get(ctx, "blobs/"+layer.Digest, "")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(blobPath), ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	w := io.MultiWriter(tmp, hash, &progressWriter{report: progress})
+
+	_, err = io.Copy(w, io.LimitReader(body, maxBundleSize))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", layer.Digest, err)
+	}
+
+	if "sha256:"+hex.EncodeToString(hash.Sum(nil)) != layer.Digest {
+		return "", fmt.Errorf("%w: blob %s", ErrDigestMismatch, layer.Digest)
+	}
+
+	return blobPath, os.Rename(tmp.Name(), blobPath)
+}
+
+// extract extracts the bundle into the plugin folder, replacing the previous version, if any.
+func (c *Catalog) extract(bundle string, source Source) error {
+	if err := os.MkdirAll(c.PluginDir, 0o755); err != nil {
+		return err
+	}
+
+	// Staged in the plugin dir, so it can be renamed in place. Hidden folders aren't listed as plugins.
+	staging, err := os.MkdirTemp(c.PluginDir, "."+source.Name+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := untar(bundle, staging, maxExtractedSize); err != nil {
+		return fmt.Errorf("extracting bundle: %w", err)
+	}
+
+	root, err := bundleRoot(staging)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(root, OCISourceFile), content, 0o600); err != nil {
+		return err
+	}
+
+	target := filepath.Join(c.PluginDir, source.Name)
+	backup := staging + ".old"
+
+	if err := os.Rename(target, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Rename(root, target); err != nil {
+		_ = os.Rename(backup, target)
+
+		return err
+	}
+
+	return os.RemoveAll(backup)
+}
+
+// bundleRoot returns the folder of the extracted bundle with main.js. Bundles have it
+// either at their root or in their single top folder, as made by headlamp-plugin package.
+func bundleRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "main.js")); err == nil {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 1 && entries[0].IsDir() {
+		root := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(root, "main.js")); err == nil {
+			return root, nil
+		}
+	}
+
+	return "", errors.New("invalid plugin bundle: main.js not found")
+}
+
+// untar extracts the regular files and folders of the gzipped tarball into dir,
+// failing when their total size is larger than limit.
+func untar(bundle, dir string, limit int64) error {
+	f, err := os.Open(bundle)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	var size int64
+
+	for files := 0; ; files++ {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if files > maxBundleFiles {
+			return errors.New("too many files")
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %q", header.Name)
+		}
+
+		target := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o755)
+		case tar.TypeReg:
+			if header.Size > limit-size {
+				return fmt.Errorf("%w: %s", ErrBundleTooLarge, header.Name)
+			}
+
+			var n int64
+
+			n, err = writeFile(target, tr, limit-size)
+			size += n
+		default:
+			// Links and devices are not needed by plugins.
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeFile writes the content of r to path, failing when it's larger than limit.
+func writeFile(path string, r io.Reader, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if err == nil && n > limit {
+		err = fmt.Errorf("%w: %s", ErrBundleTooLarge, filepath.Base(path))
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return n, err
+}
+
+// verifyFile checks that the file has the sha256 digest.
+func verifyFile(path, digest string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+
+	if "sha256:"+hex.EncodeToString(hash.Sum(nil)) != digest {
+		return ErrDigestMismatch
+	}
+
+	return nil
+}
+
+// progressWriter reports the number of bytes written so far.
+type progressWriter struct {
+	written int64
+	report  func(n int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	w.report(w.written)
+
+	return len(p), nil
+}
+
+// descriptor describes content in a registry.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// manifest is an OCI image manifest, or a Docker v2 one.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// registryClient makes the requests of the OCI distribution API for a reference.
+type registryClient struct {
+	client *http.Client
+	ref    Reference
+	token  string
+}
+
+// resolve fetches the manifest of the reference and returns the descriptor of the plugin bundle.
+func (rc *registryClient) resolve(ctx context.Context) (descriptor, error) {
+	var layer descriptor
+
+	version := rc.ref.Digest
+	if version == "" {
+		version = rc.ref.Tag
+	}
+
+	body, err := rc.get(ctx, "manifests/"+version, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return layer, err
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(body, maxBundleSize))
+	if err != nil {
+		return layer, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	if rc.ref.Digest != "" {
+		sum := sha256.Sum256(content)
+		if "sha256:"+hex.EncodeToString(sum[:]) != rc.ref.Digest {
+			return layer, fmt.Errorf("%w: manifest %s", ErrDigestMismatch, rc.ref.Digest)
+		}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return layer, fmt.Errorf("parsing manifest: %w", err)
+	}
+
+	for _, l := range m.Layers {
+		for _, mediaType := range bundleMediaTypes {
+			if l.MediaType == mediaType {
+				if !validDigest(l.Digest) {
+					return layer, fmt.Errorf("invalid layer digest %q", l.Digest)
+				}
+
+				return l, nil
+			}
+		}
+	}
+
+	return layer, fmt.Errorf("no plugin bundle in %s", rc.ref)
+}
+
+// get makes a GET request to the repository path, getting an anonymous token if the registry requires one.
+func (rc *registryClient) get(ctx context.Context, path, accept string) (io.ReadCloser, error) {
+	u := "https://" + rc.ref.Registry + "/v2/" + rc.ref.Repository + "/" + path
+
+	resp, err := rc.do(ctx, u, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && rc.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if rc.token, err = rc.fetchToken(ctx, challenge); err != nil {
+			return nil, err
+		}
+
+		if resp, err = rc.do(ctx, u, accept); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("getting %s: %s", u, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func (rc *registryClient) do(ctx context.Context, u, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	if rc.token != "" {
+		req.Header.Set("Authorization", "Bearer "+rc.token)
+	}
+
+	return rc.client.Do(req)
+}
+
+// fetchToken gets an anonymous pull token as asked by the Bearer challenge of the registry.
+func (rc *registryClient) fetchToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported registry authentication %q", scheme)
+	}
+
+	values := map[string]string{}
+
+	for _, param := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+		values[k] = strings.Trim(v, `"`)
+	}
+
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", fmt.Errorf("invalid registry token realm %q", values["realm"])
+	}
+
+	query := realm.Query()
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+
+	scope := values["scope"]
+	if scope == "" {
+		scope = "repository:" + rc.ref.Repository + ":pull"
+	}
+
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	resp, err := rc.do(ctx, realm.String(), "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting registry token: %s", resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBundleSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("parsing registry token: %w", err)
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+
+	return token.AccessToken, nil
+}
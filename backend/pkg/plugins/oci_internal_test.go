@@ -0,0 +1,86 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBundle writes a gzipped tarball with the files, in order, and returns its path.
+func writeBundle(t *testing.T, files ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "bundle.tgz")
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for i := 0; i < len(files); i += 2 {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: files[i], Mode: 0o644, Size: int64(len(files[i+1])), Typeflag: tar.TypeReg,
+		}))
+		_, err = tw.Write([]byte(files[i+1]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	return path
+}
+
+func TestUntarLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr bool
+	}{
+		{name: "within_limit", files: []string{"main.js", "12345", "index.js", "12345"}},
+		{name: "file_too_large", files: []string{"main.js", "12345678901"}, wantErr: true},
+		{name: "total_too_large", files: []string{"main.js", "123456", "index.js", "123456"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			err := untar(writeBundle(t, tt.files...), dir, 10)
+			if !tt.wantErr {
+				require.NoError(t, err)
+
+				content, err := os.ReadFile(filepath.Join(dir, "index.js"))
+				require.NoError(t, err)
+				assert.Equal(t, "12345", string(content))
+
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrBundleTooLarge)
+		})
+	}
+}
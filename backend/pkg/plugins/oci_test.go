@@ -0,0 +1,302 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		ref     string
+		want    plugins.Reference
+		wantErr bool
+	}{
+		{
+			ref:  "ghcr.io/org/plugin:1.0.0",
+			want: plugins.Reference{Registry: "ghcr.io", Repository: "org/plugin", Tag: "1.0.0"},
+		},
+		{
+			ref:  "localhost:5000/plugin",
+			want: plugins.Reference{Registry: "localhost:5000", Repository: "plugin", Tag: "latest"},
+		},
+		{
+			ref:  "ghcr.io/org/plugin@" + digest,
+			want: plugins.Reference{Registry: "ghcr.io", Repository: "org/plugin", Digest: digest},
+		},
+		{ref: "org/plugin:1.0.0", wantErr: true},
+		{ref: "ghcr.io/org/plugin@sha256:abc", wantErr: true},
+		{ref: "ghcr.io/Org/plugin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := plugins.ParseReference(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ref, strings.TrimSuffix(got.String(), ":latest"))
+		})
+	}
+}
+
+// makeBundle returns a gzipped tarball with the files.
+func makeBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry serves the bundles by tag, asking for an anonymous token first.
+type fakeRegistry struct {
+	server       *httptest.Server
+	manifests    map[string][]byte
+	blobs        map[string][]byte
+	blobRequests int
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	t.Helper()
+
+	reg := &fakeRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "repository:org/plugin:pull", r.URL.Query().Get("scope"))
+		_, _ = w.Write([]byte(`{"token":"secret"}`))
+	})
+	mux.HandleFunc("/v2/org/plugin/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, reg.server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		kind, version, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/org/plugin/"), "/")
+
+		var content []byte
+
+		switch kind {
+		case "manifests":
+			content = reg.manifests[version]
+		case "blobs":
+			reg.blobRequests++
+			content = reg.blobs[version]
+		}
+
+		if content == nil {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write(content)
+	})
+
+	reg.server = httptest.NewTLSServer(mux)
+	t.Cleanup(reg.server.Close)
+
+	return reg
+}
+
+// push stores the bundle under the tag, and returns the manifest digest.
+func (reg *fakeRegistry) push(t *testing.T, tag string, bundle []byte) string {
+	t.Helper()
+
+	digest := sha256Digest(bundle)
+	reg.blobs[digest] = bundle
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers": []map[string]interface{}{{
+			"mediaType": "application/vnd.headlamp.plugin.v1.tar+gzip",
+			"digest":    digest,
+			"size":      len(bundle),
+		}},
+	})
+	require.NoError(t, err)
+
+	reg.manifests[tag] = manifest
+	reg.manifests[sha256Digest(manifest)] = manifest
+
+	return sha256Digest(manifest)
+}
+
+func (reg *fakeRegistry) ref(version string) string {
+	host := strings.TrimPrefix(reg.server.URL, "https://")
+	if strings.HasPrefix(version, "sha256:") {
+		return host + "/org/plugin@" + version
+	}
+
+	return host + "/org/plugin:" + version
+}
+
+func newTestCatalog(t *testing.T, reg *fakeRegistry) *plugins.Catalog {
+	t.Helper()
+
+	catalog := plugins.NewCatalog(filepath.Join(t.TempDir(), "plugins"), t.TempDir())
+	catalog.Client = reg.server.Client()
+
+	return catalog
+}
+
+func TestCatalogInstallUpgradeUninstall(t *testing.T) {
+	reg := newFakeRegistry(t)
+	reg.push(t, "1.0.0", makeBundle(t, map[string]string{
+		"my-plugin/main.js":      "v1",
+		"my-plugin/package.json": `{"name":"my-plugin"}`,
+	}))
+	manifestDigest := reg.push(t, "2.0.0", makeBundle(t, map[string]string{"main.js": "v2"}))
+
+	catalog := newTestCatalog(t, reg)
+	ctx := context.Background()
+
+	require.NoError(t, catalog.Install(ctx, "my-plugin", reg.ref("1.0.0")))
+
+	content, err := os.ReadFile(filepath.Join(catalog.PluginDir, "my-plugin", "main.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	progress, ok := catalog.Progress("my-plugin")
+	require.True(t, ok)
+	assert.Equal(t, plugins.PhaseInstalled, progress.Phase)
+	assert.Equal(t, progress.Total, progress.Completed)
+
+	paths, err := plugins.GeneratePluginPaths("", catalog.PluginDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plugins/my-plugin"}, paths)
+
+	err = catalog.Install(ctx, "my-plugin", reg.ref("1.0.0"))
+	assert.ErrorIs(t, err, plugins.ErrPluginExists)
+
+	require.NoError(t, catalog.Upgrade(ctx, "my-plugin", reg.ref(manifestDigest)))
+
+	content, err = os.ReadFile(filepath.Join(catalog.PluginDir, "my-plugin", "main.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+
+	installed, err := catalog.Installed()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	assert.Equal(t, reg.ref(manifestDigest), installed[0].Ref)
+
+	// Reinstalling uses the cached bundle.
+	blobRequests := reg.blobRequests
+	require.NoError(t, catalog.Upgrade(ctx, "my-plugin", ""))
+	assert.Equal(t, blobRequests, reg.blobRequests)
+
+	require.NoError(t, catalog.Uninstall("my-plugin"))
+
+	_, err = os.Stat(filepath.Join(catalog.PluginDir, "my-plugin"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = catalog.Uninstall("my-plugin")
+	assert.ErrorIs(t, err, plugins.ErrPluginNotFromCatalog)
+}
+
+func TestCatalogInstallErrors(t *testing.T) {
+	reg := newFakeRegistry(t)
+	reg.push(t, "no-main", makeBundle(t, map[string]string{"index.js": ""}))
+	reg.push(t, "traversal", makeBundle(t, map[string]string{"../main.js": ""}))
+
+	tampered := makeBundle(t, map[string]string{"main.js": ""})
+	reg.push(t, "tampered", tampered)
+	reg.blobs[sha256Digest(tampered)] = makeBundle(t, map[string]string{"main.js": "evil"})
+
+	catalog := newTestCatalog(t, reg)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		ref  string
+	}{
+		{name: "no-main", ref: reg.ref("no-main")},
+		{name: "traversal", ref: reg.ref("traversal")},
+		{name: "tampered", ref: reg.ref("tampered")},
+		{name: "missing", ref: reg.ref("missing")},
+		{name: "bad-digest", ref: reg.ref("sha256:" + strings.Repeat("0", 64))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, catalog.Install(ctx, tt.name, tt.ref))
+
+			progress, ok := catalog.Progress(tt.name)
+			require.True(t, ok)
+			assert.Equal(t, plugins.PhaseFailed, progress.Phase)
+			assert.NotEmpty(t, progress.Error)
+
+			_, err := os.Stat(filepath.Join(catalog.PluginDir, tt.name))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+
+	assert.Error(t, catalog.Install(ctx, "../escape", reg.ref("no-main")))
+
+	entries, err := os.ReadDir(catalog.PluginDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
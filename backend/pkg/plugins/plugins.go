@@ -200,6 +200,11 @@ func pluginBasePathListForDir(pluginDir string, baseURL string) ([]string, error
 	pluginListURLs := make([]string, 0, len(files))
 
 	for _, f := range files {
+		// Hidden folders, like the ones staging plugin installations, are not plugins.
+		if f.IsDir() && strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+
 		if !f.IsDir() {
 			pluginPath := filepath.Join(pluginDir, f.Name())
 			logger.Log(logger.LevelInfo, map[string]string{"pluginPath": pluginPath},
@@ -0,0 +1,447 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// Plugin verification policies.
+const (
+	// PolicyOff doesn't verify plugins.
+	PolicyOff = "off"
+	// PolicyWarn serves all plugins, logging the ones that aren't verified.
+	PolicyWarn = "warn"
+	// PolicyEnforce only serves verified plugins.
+	PolicyEnforce = "enforce"
+)
+
+// Plugin verification statuses.
+const (
+	StatusVerified = "verified"
+	StatusUnsigned = "unsigned"
+	StatusInvalid  = "invalid"
+)
+
+// SignatureFile is the file, in the plugin folder, with the base64 signature of the plugin manifest.
+const SignatureFile = "headlamp-plugin.sig"
+
+// ValidatePolicy checks that policy is a plugin verification policy.
+func ValidatePolicy(policy string) error {
+	switch policy {
+	case PolicyOff, PolicyWarn, PolicyEnforce:
+		return nil
+	default:
+		return fmt.Errorf("invalid plugin verification policy %q, it must be off, warn or enforce", policy)
+	}
+}
+
+// Verification is the verification status of a plugin.
+type Verification struct {
+	// Path is the plugin path, as listed by GeneratePluginPaths, like plugins/my-plugin.
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// Digest is the sha256 of the plugin manifest.
+	Digest string `json:"digest,omitempty"`
+	// KeyID identifies the trusted key the plugin is signed with.
+	KeyID string `json:"keyID,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// trustedKey is a publisher key that plugins can be signed with.
+type trustedKey struct {
+	id  string
+	key interface{}
+}
+
+// Verifier checks the signatures of the plugins with the trusted publisher keys.
+// It remembers the last verification of each plugin folder, until its files change.
+type Verifier struct {
+	Policy string
+
+	keys    []trustedKey
+	mu      sync.Mutex
+	results map[string]verification
+}
+
+// verification is a verification of a plugin folder, with the stamp of its files when it was verified.
+type verification struct {
+	Verification
+	stamp string
+}
+
+// NewVerifier returns a verifier with the policy and the PEM public keys, ed25519 or
+// ECDSA, of keysPath. keysPath is a file, or a folder whose files are all read.
+func NewVerifier(policy, keysPath string) (*Verifier, error) {
+	if err := ValidatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	v := &Verifier{Policy: policy, results: map[string]verification{}}
+
+	if policy == PolicyOff {
+		return v, nil
+	}
+
+	if keysPath == "" {
+		return nil, errors.New("plugin verification needs trusted keys")
+	}
+
+	files := []string{keysPath}
+
+	if info, err := os.Stat(keysPath); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(keysPath)
+		if err != nil {
+			return nil, err
+		}
+
+		files = files[:0]
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(keysPath, entry.Name()))
+			}
+		}
+	}
+
+	for _, file := range files {
+		keys, err := readPublicKeys(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading trusted keys from %s: %w", file, err)
+		}
+
+		v.keys = append(v.keys, keys...)
+	}
+
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("no trusted keys in %s", keysPath)
+	}
+
+	return v, nil
+}
+
+// readPublicKeys reads the PEM public keys of the file.
+func readPublicKeys(file string) ([]trustedKey, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []trustedKey
+
+	for {
+		var block *pem.Block
+
+		block, content = pem.Decode(content)
+		if block == nil {
+			return keys, nil
+		}
+
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+
+		sum := sha256.Sum256(block.Bytes)
+		keys = append(keys, trustedKey{id: hex.EncodeToString(sum[:8]), key: key})
+	}
+}
+
+// Manifest returns the manifest of the plugin folder, which is what is signed. It has a
+// line per file, sorted by path, with its sha256 and its slash separated path, like the
+// output of sha256sum. The signature file and the OCI source file are not included.
+func Manifest(dir string) ([]byte, error) {
+	type entry struct {
+		path string
+		sum  string
+	}
+
+	var entries []entry
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if rel == SignatureFile || rel == OCISourceFile {
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", rel)
+		}
+
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry{path: rel, sum: sum})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+
+	var manifest bytes.Buffer
+
+	for _, e := range entries {
+		fmt.Fprintf(&manifest, "%s  %s\n", e.sum, e.path)
+	}
+
+	return manifest.Bytes(), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// filesStamp returns a stamp of the paths, sizes and modification times of the files in dir,
+// which changes when a file is added, removed or written.
+func filesStamp(dir string) (string, error) {
+	hash := sha256.New()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\x00%d\n", path, info.Mode(), info.Size(), info.ModTime().UnixNano())
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Verify checks the signature of the plugin in dir, listed as pluginPath, and remembers the result.
+func (v *Verifier) Verify(dir, pluginPath string) Verification {
+	// The stamp is taken first, so that files changed while verifying are verified again.
+	stamp, stampErr := filesStamp(dir)
+
+	result := v.verify(dir)
+	result.Path = pluginPath
+
+	v.mu.Lock()
+	if stampErr == nil {
+		v.results[dir] = verification{Verification: result, stamp: stamp}
+	} else {
+		delete(v.results, dir)
+	}
+	v.mu.Unlock()
+
+	return result
+}
+
+func (v *Verifier) verify(dir string) Verification {
+	signature, err := os.ReadFile(filepath.Join(dir, SignatureFile))
+	if os.IsNotExist(err) {
+		return Verification{Status: StatusUnsigned}
+	}
+
+	if err != nil {
+		return Verification{Status: StatusInvalid, Error: err.Error()}
+	}
+
+	signature, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return Verification{Status: StatusInvalid, Error: "decoding signature: " + err.Error()}
+	}
+
+	manifest, err := Manifest(dir)
+	if err != nil {
+		return Verification{Status: StatusInvalid, Error: err.Error()}
+	}
+
+	digest := sha256.Sum256(manifest)
+	result := Verification{Status: StatusInvalid, Digest: "sha256:" + hex.EncodeToString(digest[:])}
+
+	for _, k := range v.keys {
+		var ok bool
+
+		switch key := k.key.(type) {
+		case ed25519.PublicKey:
+			ok = ed25519.Verify(key, manifest, signature)
+		case *ecdsa.PublicKey:
+			ok = ecdsa.VerifyASN1(key, digest[:], signature)
+		}
+
+		if ok {
+			result.Status = StatusVerified
+			result.KeyID = k.id
+
+			return result
+		}
+	}
+
+	result.Error = "the signature doesn't match the plugin files and trusted keys"
+
+	return result
+}
+
+// Allowed tells whether the plugin in dir can be served, verifying it again if its
+// files changed since it was last verified.
+func (v *Verifier) Allowed(dir string) bool {
+	if v.Policy != PolicyEnforce {
+		return true
+	}
+
+	return v.cachedVerify(dir, "").Status == StatusVerified
+}
+
+// cachedVerify returns the last verification of the plugin in dir, listed as pluginPath, or
+// verifies it again if its files changed since.
+func (v *Verifier) cachedVerify(dir, pluginPath string) Verification {
+	stamp, err := filesStamp(dir)
+	if err != nil {
+		return Verification{Path: pluginPath, Status: StatusInvalid, Error: err.Error()}
+	}
+
+	v.mu.Lock()
+	cached, ok := v.results[dir]
+	v.mu.Unlock()
+
+	if ok && cached.stamp == stamp {
+		result := cached.Verification
+		result.Path = pluginPath
+
+		return result
+	}
+
+	return v.Verify(dir, pluginPath)
+}
+
+// VerifyAll verifies the plugins of the static and user plugin dirs.
+func (v *Verifier) VerifyAll(staticPluginDir, pluginDir string) ([]Verification, error) {
+	pluginPaths, err := GeneratePluginPaths(staticPluginDir, pluginDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	results := make([]Verification, 0, len(pluginPaths))
+
+	for _, pluginPath := range pluginPaths {
+		results = append(results, v.Verify(pluginPathDir(pluginPath, staticPluginDir, pluginDir), pluginPath))
+	}
+
+	return results, nil
+}
+
+// Filter verifies the plugins, given by their paths, and returns the ones that can be served.
+// Only the plugins whose files changed since they were last verified are verified again. The
+// plugins that aren't verified are logged.
+func (v *Verifier) Filter(pluginPaths []string, staticPluginDir, pluginDir string) []string {
+	if v.Policy == PolicyOff {
+		return pluginPaths
+	}
+
+	allowed := make([]string, 0, len(pluginPaths))
+
+	for _, pluginPath := range pluginPaths {
+		result := v.cachedVerify(pluginPathDir(pluginPath, staticPluginDir, pluginDir), pluginPath)
+		if result.Status == StatusVerified {
+			allowed = append(allowed, pluginPath)
+
+			continue
+		}
+
+		var level uint = logger.LevelWarn
+		if v.Policy == PolicyEnforce {
+			level = logger.LevelError
+		}
+
+		logger.Log(level, map[string]string{"plugin": pluginPath, "status": result.Status, "policy": v.Policy},
+			result.Error, "plugin not verified")
+
+		if v.Policy == PolicyWarn {
+			allowed = append(allowed, pluginPath)
+		}
+	}
+
+	return allowed
+}
+
+// pluginPathDir returns the folder of the plugin listed as pluginPath.
+func pluginPathDir(pluginPath, staticPluginDir, pluginDir string) string {
+	name := filepath.Base(pluginPath)
+
+	if strings.HasPrefix(pluginPath, "static-plugins") {
+		return filepath.Join(staticPluginDir, name)
+	}
+
+	return filepath.Join(pluginDir, name)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePublicKey writes the PEM public key to a file in dir.
+func writePublicKey(t *testing.T, dir, name string, key interface{}) {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	content := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+}
+
+// writePlugin writes a plugin with the files in pluginDir.
+func writePlugin(t *testing.T, pluginDir, name string, files map[string]string) string {
+	t.Helper()
+
+	dir := filepath.Join(pluginDir, name)
+
+	for file, content := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	return dir
+}
+
+func writeSignature(t *testing.T, dir string, signature []byte) {
+	t.Helper()
+
+	content := base64.StdEncoding.EncodeToString(signature)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, plugins.SignatureFile), []byte(content), 0o600))
+}
+
+func TestManifest(t *testing.T) {
+	dir := writePlugin(t, t.TempDir(), "p", map[string]string{
+		"main.js":              "a",
+		"package.json":         "{}",
+		"dist/x.js":            "",
+		plugins.OCISourceFile:  "{}",
+		plugins.SignatureFile:  "sig",
+		"dist.js":              "",
+		"locales/en/main.json": "{}",
+	})
+
+	manifest, err := plugins.Manifest(dir)
+	require.NoError(t, err)
+
+	// Like sha256sum output, sorted by path.
+	assert.Equal(t, ""+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  dist.js\n"+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  dist/x.js\n"+
+		"44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a  locales/en/main.json\n"+
+		"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  main.js\n"+
+		"44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a  package.json\n",
+		string(manifest))
+}
+
+func TestVerifier(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keysDir := t.TempDir()
+	writePublicKey(t, keysDir, "ed25519.pub", edPublic)
+	writePublicKey(t, keysDir, "ecdsa.pub", &ecPrivate.PublicKey)
+
+	pluginDir := t.TempDir()
+	files := map[string]string{"main.js": "console.log('hi')", "package.json": `{"name":"p"}`}
+
+	// Signed with the ed25519 key.
+	edDir := writePlugin(t, pluginDir, "ed", files)
+	manifest, err := plugins.Manifest(edDir)
+	require.NoError(t, err)
+	writeSignature(t, edDir, ed25519.Sign(edPrivate, manifest))
+
+	// Signed with the ECDSA key, as cosign sign-blob does for the manifest.
+	ecDir := writePlugin(t, pluginDir, "ec", files)
+	digest := sha256.Sum256(manifest)
+	ecSignature, err := ecdsa.SignASN1(rand.Reader, ecPrivate, digest[:])
+	require.NoError(t, err)
+	writeSignature(t, ecDir, ecSignature)
+
+	// Signed with an untrusted key.
+	writeSignature(t, writePlugin(t, pluginDir, "untrusted", files), ed25519.Sign(otherPrivate, manifest))
+
+	// Changed after being signed.
+	tamperedDir := writePlugin(t, pluginDir, "tampered", files)
+	writeSignature(t, tamperedDir, ed25519.Sign(edPrivate, manifest))
+	require.NoError(t, os.WriteFile(filepath.Join(tamperedDir, "main.js"), []byte("evil"), 0o600))
+
+	writePlugin(t, pluginDir, "unsigned", files)
+
+	verifier, err := plugins.NewVerifier(plugins.PolicyEnforce, keysDir)
+	require.NoError(t, err)
+
+	results, err := verifier.VerifyAll("", pluginDir)
+	require.NoError(t, err)
+
+	statuses := map[string]string{}
+	for _, result := range results {
+		statuses[result.Path] = result.Status
+	}
+
+	assert.Equal(t, map[string]string{
+		"plugins/ec":        plugins.StatusVerified,
+		"plugins/ed":        plugins.StatusVerified,
+		"plugins/tampered":  plugins.StatusInvalid,
+		"plugins/unsigned":  plugins.StatusUnsigned,
+		"plugins/untrusted": plugins.StatusInvalid,
+	}, statuses)
+
+	assert.True(t, verifier.Allowed(edDir))
+	assert.False(t, verifier.Allowed(tamperedDir))
+
+	// A verified plugin changed afterwards is verified again.
+	changedDir := writePlugin(t, pluginDir, "changed", files)
+	writeSignature(t, changedDir, ed25519.Sign(edPrivate, manifest))
+	assert.True(t, verifier.Allowed(changedDir))
+	require.NoError(t, os.WriteFile(filepath.Join(changedDir, "evil.js"), []byte("evil"), 0o600))
+	assert.False(t, verifier.Allowed(changedDir))
+	require.NoError(t, os.Remove(filepath.Join(changedDir, "evil.js")))
+	require.NoError(t, os.WriteFile(filepath.Join(changedDir, "main.js"), []byte("console.log('hi!')"), 0o600))
+	assert.False(t, verifier.Allowed(changedDir))
+	require.NoError(t, os.RemoveAll(changedDir))
+
+	paths := []string{"plugins/ec", "plugins/ed", "plugins/tampered", "plugins/unsigned", "plugins/untrusted"}
+	assert.Equal(t, []string{"plugins/ec", "plugins/ed"}, verifier.Filter(paths, "", pluginDir))
+
+	verifier.Policy = plugins.PolicyWarn
+	assert.Equal(t, paths, verifier.Filter(paths, "", pluginDir))
+	assert.True(t, verifier.Allowed(tamperedDir))
+}
+
+func TestVerifierFilterCached(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keysDir := t.TempDir()
+	writePublicKey(t, keysDir, "ed25519.pub", edPublic)
+
+	pluginDir := t.TempDir()
+	dir := writePlugin(t, pluginDir, "p", map[string]string{"main.js": "console.log('hi')"})
+	manifest, err := plugins.Manifest(dir)
+	require.NoError(t, err)
+	writeSignature(t, dir, ed25519.Sign(edPrivate, manifest))
+
+	verifier, err := plugins.NewVerifier(plugins.PolicyEnforce, keysDir)
+	require.NoError(t, err)
+
+	paths := []string{"plugins/p"}
+	assert.Equal(t, paths, verifier.Filter(paths, "", pluginDir))
+
+	// A change keeping the size and modification time of the files isn't seen, as the plugin
+	// isn't hashed again.
+	mainJS := filepath.Join(dir, "main.js")
+	info, err := os.Stat(mainJS)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(mainJS, []byte("console.log('no')"), 0o600))
+	require.NoError(t, os.Chtimes(mainJS, info.ModTime(), info.ModTime()))
+	assert.Equal(t, paths, verifier.Filter(paths, "", pluginDir))
+
+	// Once the files change, the plugin is verified again.
+	require.NoError(t, os.Chtimes(mainJS, time.Now(), info.ModTime().Add(time.Second)))
+	assert.Empty(t, verifier.Filter(paths, "", pluginDir))
+}
+
+func TestNewVerifierErrors(t *testing.T) {
+	_, err := plugins.NewVerifier("strict", "")
+	assert.Error(t, err)
+
+	_, err = plugins.NewVerifier(plugins.PolicyEnforce, "")
+	assert.Error(t, err)
+
+	_, err = plugins.NewVerifier(plugins.PolicyWarn, t.TempDir())
+	assert.ErrorContains(t, err, "no trusted keys")
+
+	verifier, err := plugins.NewVerifier(plugins.PolicyOff, "")
+	require.NoError(t, err)
+	assert.True(t, verifier.Allowed(t.TempDir()))
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podexec proxies pod exec and attach sessions to the client over a WebSocket.
+//
+// The backend connects to the API server with the stored context credentials, using
+// the WebSocket v5 channel protocol and falling back to SPDY for older clusters. The
+// client speaks the v5 channel protocol with the backend regardless, including terminal
+// resize messages and closing stdin to half-close the session.
+package podexec
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// SubresourceExec is the pod subresource to run a command in a container.
+	SubresourceExec = "exec"
+	// SubresourceAttach is the pod subresource to attach to the running process of a container.
+	SubresourceAttach = "attach"
+)
+
+// execRequest describes an exec or attach session. It is read from the query parameters.
+type execRequest struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+	Stdin     bool
+	Tty       bool
+}
+
+// parseExecRequest reads an execRequest for the given subresource from the query parameters
+// namespace, pod, container, command (repeated for each argument), stdin and tty.
+func parseExecRequest(r *http.Request, subresource string) (execRequest, error) {
+	query := r.URL.Query()
+
+	req := execRequest{
+		Namespace: query.Get("namespace"),
+		Pod:       query.Get("pod"),
+		Container: query.Get("container"),
+		Command:   query["command"],
+	}
+
+	var err error
+
+	if req.Stdin, err = parseBool(query, "stdin"); err != nil {
+		return execRequest{}, err
+	}
+
+	if req.Tty, err = parseBool(query, "tty"); err != nil {
+		return execRequest{}, err
+	}
+
+	if req.Namespace == "" {
+		return execRequest{}, errors.New("namespace is required")
+	}
+
+	if req.Pod == "" {
+		return execRequest{}, errors.New("pod name is required")
+	}
+
+	if subresource == SubresourceExec && len(req.Command) == 0 {
+		return execRequest{}, errors.New("command is required")
+	}
+
+	return req, nil
+}
+
+// parseBool parses an optional boolean query parameter.
+func parseBool(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q", name, value)
+	}
+
+	return b, nil
+}
+
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{Protocol},
+	CheckOrigin:  checkSameOrigin,
+}
+
+// checkSameOrigin accepts the upgrades without an Origin, which don't come from a browser,
+// and the ones from the origin of the backend. Other websites the user visits can't open
+// sessions running commands with the credentials of the contexts.
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// HandleExec runs a command in a pod container and streams it to the client.
+func HandleExec(kubeConfigStore kubeconfig.ContextStore, w http.ResponseWriter, r *http.Request) {
+	handleStream(kubeConfigStore, w, r, SubresourceExec)
+}
+
+// HandleAttach attaches to the running process of a pod container and streams it to the client.
+func HandleAttach(kubeConfigStore kubeconfig.ContextStore, w http.ResponseWriter, r *http.Request) {
+	handleStream(kubeConfigStore, w, r, SubresourceAttach)
+}
+
+// handleStream proxies an exec or attach session, given by subresource, to the client.
+func handleStream(kubeConfigStore kubeconfig.ContextStore, w http.ResponseWriter, r *http.Request,
+	subresource string,
+) {
+	if !checkSameOrigin(r) {
+		logger.Log(logger.LevelWarn, map[string]string{"origin": r.Header.Get("Origin")}, nil,
+			"rejecting cross-origin "+subresource+" request")
+		http.Error(w, "cross-origin request", http.StatusForbidden)
+
+		return
+	}
+
+	req, err := parseExecRequest(r, subresource)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "validating "+subresource+" request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	clusterName := mux.Vars(r)["clusterName"]
+	token, _ := auth.GetTokenFromCookie(r, clusterName)
+
+	// Browsers cannot set headers on WebSocket requests, so the user ID can come in the query too.
+	userID := r.Header.Get("X-HEADLAMP-USER-ID")
+	if userID == "" {
+		userID = r.URL.Query().Get("userId")
+	}
+
+	contextKey := clusterName + userID
+
+	kContext, err := kubeConfigStore.GetContext(contextKey)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": contextKey}, err, "getting kubeconfig context")
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	executor, err := newExecutor(kContext, token, subresource, req)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"cluster": contextKey}, err, "creating "+subresource+" executor")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "upgrading connection")
+
+		return
+	}
+
+	newSession(conn).run(r.Context(), executor, req)
+}
+
+// newExecutor creates the executor for the session, authenticated with the context
+// credentials, or with the token when there is one.
+func newExecutor(kContext *kubeconfig.Context, token, subresource string, req execRequest,
+) (remotecommand.Executor, error) {
+	clientset, err := kContext.ClientSetWithToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	rConf, err := kContext.RESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get REST config: %w", err)
+	}
+
+	if token != "" {
+		rConf.BearerToken = token
+	}
+
+	streamURL := clientset.CoreV1().RESTClient().Post().
+		Namespace(req.Namespace).
+		Resource("pods").
+		Name(req.Pod).
+		SubResource(subresource).
+		VersionedParams(podOptions(subresource, req), scheme.ParameterCodec).
+		URL()
+
+	return newFallbackExecutor(rConf, streamURL)
+}
+
+// podOptions returns the options of the exec or attach subresource for req.
+func podOptions(subresource string, req execRequest) runtime.Object {
+	if subresource == SubresourceAttach {
+		return &corev1.PodAttachOptions{
+			Container: req.Container,
+			Stdin:     req.Stdin,
+			Stdout:    true,
+			Stderr:    !req.Tty,
+			TTY:       req.Tty,
+		}
+	}
+
+	return &corev1.PodExecOptions{
+		Container: req.Container,
+		Command:   req.Command,
+		Stdin:     req.Stdin,
+		Stdout:    true,
+		Stderr:    !req.Tty,
+		TTY:       req.Tty,
+	}
+}
+
+// newFallbackExecutor creates an executor that uses the WebSocket v5 protocol and falls
+// back to SPDY when the API server does not support it, like kubectl does.
+func newFallbackExecutor(rConf *rest.Config, streamURL *url.URL) (remotecommand.Executor, error) {
+	spdyExecutor, err := remotecommand.NewSPDYExecutor(rConf, http.MethodPost, streamURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SPDY executor: %w", err)
+	}
+
+	websocketExecutor, err := remotecommand.NewWebSocketExecutor(rConf, http.MethodGet, streamURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WebSocket executor: %w", err)
+	}
+
+	return remotecommand.NewFallbackExecutor(websocketExecutor, spdyExecutor, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+}
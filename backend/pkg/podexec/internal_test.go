@@ -0,0 +1,244 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podexec
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// echoExecutor is a remotecommand.Executor that echoes stdin to stdout and
+// reports the terminal sizes it is given.
+type echoExecutor struct {
+	sizes   chan remotecommand.TerminalSize
+	exitErr error
+}
+
+func (e *echoExecutor) Stream(options remotecommand.StreamOptions) error {
+	return e.StreamWithContext(context.Background(), options)
+}
+
+func (e *echoExecutor) StreamWithContext(_ context.Context, options remotecommand.StreamOptions) error {
+	if options.TerminalSizeQueue != nil {
+		go func() {
+			for size := options.TerminalSizeQueue.Next(); size != nil; size = options.TerminalSizeQueue.Next() {
+				e.sizes <- *size
+			}
+		}()
+	}
+
+	if _, err := io.Copy(options.Stdout, options.Stdin); err != nil {
+		return err
+	}
+
+	return e.exitErr
+}
+
+func startSession(t *testing.T, executor remotecommand.Executor, req execRequest) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		newSession(conn).run(context.Background(), executor, req)
+	}))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{Protocol}}
+
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	assert.Equal(t, Protocol, conn.Subprotocol())
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) (byte, []byte) {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.NotEmpty(t, data)
+
+	return data[0], data[1:]
+}
+
+func TestSession(t *testing.T) {
+	executor := &echoExecutor{sizes: make(chan remotecommand.TerminalSize, 1)}
+	conn := startSession(t, executor, execRequest{Namespace: "default", Pod: "pod", Stdin: true, Tty: true})
+
+	// Resize the terminal.
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage,
+		append([]byte{ResizeChannel}, []byte(`{"Width":80,"Height":24}`)...)))
+
+	select {
+	case size := <-executor.sizes:
+		assert.Equal(t, remotecommand.TerminalSize{Width: 80, Height: 24}, size)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for terminal size")
+	}
+
+	// Stdin is echoed on stdout.
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, append([]byte{StdinChannel}, []byte("hello")...)))
+
+	channel, data := readFrame(t, conn)
+	assert.Equal(t, StdoutChannel, channel)
+	assert.Equal(t, "hello", string(data))
+
+	// Closing stdin ends the command, which reports its status and closes the connection.
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{CloseChannel, StdinChannel}))
+
+	channel, data = readFrame(t, conn)
+	assert.Equal(t, ErrorChannel, channel)
+
+	var status metav1.Status
+	require.NoError(t, json.Unmarshal(data, &status))
+	assert.Equal(t, metav1.StatusSuccess, status.Status)
+
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestSession_ExitCode(t *testing.T) {
+	executor := &echoExecutor{exitErr: utilexec.CodeExitError{Err: io.ErrUnexpectedEOF, Code: 3}}
+	conn := startSession(t, executor, execRequest{Namespace: "default", Pod: "pod", Stdin: true})
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{CloseChannel, StdinChannel}))
+
+	channel, data := readFrame(t, conn)
+	assert.Equal(t, ErrorChannel, channel)
+
+	var status metav1.Status
+	require.NoError(t, json.Unmarshal(data, &status))
+	assert.Equal(t, metav1.StatusFailure, status.Status)
+	require.NotNil(t, status.Details)
+	require.Len(t, status.Details.Causes, 1)
+	assert.Equal(t, "3", status.Details.Causes[0].Message)
+}
+
+func TestParseExecRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		subresource string
+		wantErr     bool
+	}{
+		{"exec", "namespace=ns&pod=pod&command=sh&command=-c&tty=true&stdin=1", SubresourceExec, false},
+		{"exec_without_command", "namespace=ns&pod=pod", SubresourceExec, true},
+		{"attach_without_command", "namespace=ns&pod=pod&container=c", SubresourceAttach, false},
+		{"missing_namespace", "pod=pod&command=sh", SubresourceExec, true},
+		{"missing_pod", "namespace=ns&command=sh", SubresourceExec, true},
+		{"invalid_tty", "namespace=ns&pod=pod&command=sh&tty=maybe", SubresourceExec, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/clusters/test/"+tt.subresource+"?"+tt.query, nil)
+
+			req, err := parseExecRequest(r, tt.subresource)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "ns", req.Namespace)
+			assert.Equal(t, "pod", req.Pod)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/clusters/test/exec?namespace=ns&pod=pod&command=sh&command=-c&tty=true", nil)
+	req, err := parseExecRequest(r, SubresourceExec)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c"}, req.Command)
+	assert.True(t, req.Tty)
+	assert.False(t, req.Stdin)
+}
+
+func TestCheckSameOrigin(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{"no_origin", "", true},
+		{"same_origin", "http://localhost:4466", true},
+		{"foreign_origin", "https://evil.example.com", false},
+		{"foreign_port", "http://localhost:3000", false},
+		{"invalid_origin", "://", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:4466/clusters/test/exec", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			assert.Equal(t, tt.want, checkSameOrigin(r))
+		})
+	}
+}
+
+func TestHandleExec_ForeignOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HandleExec(nil, w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{Protocol}}
+	header := http.Header{"Origin": {"https://evil.example.com"}}
+
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+
+		"/clusters/test/exec?namespace=ns&pod=pod&command=sh", header)
+	if conn != nil {
+		conn.Close()
+	}
+
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	require.NotNil(t, resp)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilremotecommand "k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// The channels of the client protocol. Every WebSocket message starts with the
+// channel byte, as in the Kubernetes v5 channel protocol.
+const (
+	StdinChannel  byte = 0
+	StdoutChannel byte = 1
+	StderrChannel byte = 2
+	ErrorChannel  byte = 3
+	ResizeChannel byte = 4
+	// CloseChannel messages carry a second byte with the channel being closed.
+	// The client closes StdinChannel to half-close the session.
+	CloseChannel byte = 255
+)
+
+// Protocol is the WebSocket subprotocol spoken with the client.
+const Protocol = utilremotecommand.StreamProtocolV5Name
+
+// closeGracePeriod is the time given to the client to receive the close message.
+const closeGracePeriod = time.Second
+
+// session bridges a client WebSocket to the standard streams of a remote command.
+type session struct {
+	// conn is the WebSocket connection to the client.
+	conn *websocket.Conn
+	// writeMu is a mutex to synchronize access to write operations on conn.
+	writeMu sync.Mutex
+	// stdin tells whether the remote command reads the client stdin.
+	stdin bool
+	// stdinReader is the stdin of the remote command.
+	stdinReader *io.PipeReader
+	// stdinWriter receives the stdin messages of the client.
+	stdinWriter *io.PipeWriter
+	// sizes receives the terminal resize messages of the client.
+	sizes chan remotecommand.TerminalSize
+	// done is closed when the remote command is over.
+	done chan struct{}
+	// cancel cancels the remote command, when the client goes away.
+	cancel context.CancelFunc
+}
+
+// newSession creates a session for the given client connection.
+func newSession(conn *websocket.Conn) *session {
+	stdinReader, stdinWriter := io.Pipe()
+
+	return &session{
+		conn:        conn,
+		stdinReader: stdinReader,
+		stdinWriter: stdinWriter,
+		sizes:       make(chan remotecommand.TerminalSize, 1),
+		done:        make(chan struct{}),
+	}
+}
+
+// run streams the remote command until it is over, then sends its status on the
+// error channel and closes the client connection.
+func (s *session) run(ctx context.Context, executor remotecommand.Executor, req execRequest) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	defer s.cancel()
+
+	s.stdin = req.Stdin
+
+	go s.readLoop()
+
+	opts := remotecommand.StreamOptions{
+		Stdout: channelWriter{s: s, channel: StdoutChannel},
+		Tty:    req.Tty,
+	}
+
+	if req.Stdin {
+		opts.Stdin = s.stdinReader
+	}
+
+	// With a TTY stderr is merged into stdout.
+	if req.Tty {
+		opts.TerminalSizeQueue = s
+	} else {
+		opts.Stderr = channelWriter{s: s, channel: StderrChannel}
+	}
+
+	err := executor.StreamWithContext(ctx, opts)
+
+	close(s.done)
+	s.stdinReader.Close()
+
+	s.writeStatus(err)
+	s.close()
+}
+
+// readLoop reads the client messages until the client goes away. Stdin messages are
+// written to the remote command, resize messages are queued for it, and closing the
+// stdin channel half-closes the session, sending EOF to the remote command.
+func (s *session) readLoop() {
+	defer s.stdinWriter.Close()
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			// The client is gone, there is nobody left to stream to.
+			s.cancel()
+
+			return
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		switch data[0] {
+		case StdinChannel:
+			if !s.stdin {
+				continue
+			}
+
+			if _, err := s.stdinWriter.Write(data[1:]); err != nil {
+				return
+			}
+		case ResizeChannel:
+			s.queueResize(data[1:])
+		case CloseChannel:
+			if len(data) > 1 && data[1] == StdinChannel {
+				s.stdinWriter.Close()
+			}
+		}
+	}
+}
+
+// queueResize queues a terminal resize, replacing any resize not consumed yet.
+func (s *session) queueResize(data []byte) {
+	var size remotecommand.TerminalSize
+	if err := json.Unmarshal(data, &size); err != nil {
+		logger.Log(logger.LevelError, nil, err, "unmarshaling terminal size")
+
+		return
+	}
+
+	for {
+		select {
+		case s.sizes <- size:
+			return
+		case <-s.sizes:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Next implements remotecommand.TerminalSizeQueue. It returns nil once the
+// remote command is over.
+func (s *session) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-s.sizes:
+		return &size
+	case <-s.done:
+		return nil
+	}
+}
+
+// writeFrame writes data to the client on the given channel.
+func (s *session) writeFrame(channel byte, data []byte) error {
+	frame := make([]byte, len(data)+1)
+	frame[0] = channel
+	copy(frame[1:], data)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.conn.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+// writeStatus writes the outcome of the remote command on the error channel,
+// as a Status the same way the API server does.
+func (s *session) writeStatus(err error) {
+	data, jsonErr := json.Marshal(statusFor(err))
+	if jsonErr != nil {
+		logger.Log(logger.LevelError, nil, jsonErr, "marshaling exec status")
+
+		return
+	}
+
+	if err := s.writeFrame(ErrorChannel, data); err != nil {
+		logger.Log(logger.LevelInfo, nil, err, "writing exec status to client")
+	}
+}
+
+// close closes the client connection with a normal closure.
+func (s *session) close() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+
+	//nolint:errcheck // The client may already be gone.
+	s.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(closeGracePeriod))
+	s.conn.Close()
+}
+
+// statusFor returns the Status reported to the client for the error returned by
+// the remote command.
+func statusFor(err error) metav1.Status {
+	if err == nil {
+		return metav1.Status{Status: metav1.StatusSuccess}
+	}
+
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  utilremotecommand.NonZeroExitCodeReason,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{
+					{
+						Type:    utilremotecommand.ExitCodeCauseType,
+						Message: strconv.Itoa(exitErr.ExitStatus()),
+					},
+				},
+			},
+		}
+	}
+
+	return metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+}
+
+// channelWriter writes to one of the output channels of the client.
+type channelWriter struct {
+	s       *session
+	channel byte
+}
+
+// Write implements io.Writer.
+func (w channelWriter) Write(p []byte) (int, error) {
+	if err := w.s.writeFrame(w.channel, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -26,7 +26,6 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
-	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -47,6 +46,9 @@ import (
 const (
 	RUNNING = "Running"
 	STOPPED = "Stopped"
+	// RECONNECTING is the status of a port forward whose connection to the pod broke
+	// while it is being reconnected.
+	RECONNECTING = "Reconnecting"
 )
 
 const (
@@ -92,6 +94,12 @@ type portForward struct {
 	TargetPort       string `json:"targetPort"`
 	Status           string `json:"status"`
 	Error            string `json:"error"`
+	// Owner is the user ID of the session that started the port forward.
+	Owner string `json:"owner"`
+	// ReconnectAttempt is the current reconnection attempt while the status is RECONNECTING.
+	ReconnectAttempt int `json:"reconnectAttempt,omitempty"`
+	// selector finds the pods that can replace Pod when it goes away, see podSelector.
+	selector string
 }
 
 func getFreePort() (int, error) {
@@ -166,7 +174,7 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 		return
 	}
 
-	err = startPortForward(kContext, cache, p, token, clusterName)
+	err = startPortForward(kContext, cache, p, token, clusterName, requestOwner(r))
 	if err != nil {
 		logger.Log(logger.LevelError, nil, err, "starting portforward")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -187,7 +195,7 @@ func StartPortForward(kubeConfigStore kubeconfig.ContextStore, cache cache.Cache
 // checkPortForwardPermission checks if the current user has permission to create pods/portforward.
 // It uses SelfSubjectAccessReview to verify RBAC permissions for the specified namespace and pod.
 // Returns an error if permission is denied or if the permission check fails.
-func checkPortForwardPermission(clientset *kubernetes.Clientset, namespace, podName string) error {
+func checkPortForwardPermission(clientset kubernetes.Interface, namespace, podName string) error {
 	ctx := context.Background()
 
 	// Create a SelfSubjectAccessReview to check permissions
@@ -288,49 +296,6 @@ func safeCloseChan(ch chan struct{}) {
 	}
 }
 
-// monitorPodAndManagePortForward runs in a goroutine and periodically checks if the
-// target pod for a port-forward is still running. If the pod is not running
-// (or if an unrecoverable error occurs during check), it signals the port-forward
-// to stop by closing its stopChan and updates its status in the cache.
-// It stops when the associated port-forward's closeChan is closed.
-func monitorPodAndManagePortForward(
-	clientset *kubernetes.Clientset,
-	cache cache.Cache[interface{}],
-	pfDetails *portForward,
-) {
-	ticker := time.NewTicker(PodAvailabilityCheckTimer * time.Second)
-	defer ticker.Stop()
-
-	logParams := map[string]string{"id": pfDetails.ID, "pod": pfDetails.Pod, "namespace": pfDetails.Namespace}
-
-	for {
-		select {
-		case <-ticker.C:
-			err := checkIfPodIsRunning(clientset, pfDetails.Namespace, pfDetails.Pod)
-			if err != nil {
-				if errors.Is(err, syscall.ECONNREFUSED) {
-					logger.Log(logger.LevelInfo, logParams, err, "checking pod (ECONNREFUSED), continuing")
-					continue
-				}
-
-				errMsg := fmt.Sprintf("Pod %s/%s check failed: %v", pfDetails.Namespace, pfDetails.Pod, err)
-				logger.Log(logger.LevelError, logParams, errors.New(errMsg), "stopping port-forward due to pod status")
-
-				pfDetails.Status = STOPPED
-				pfDetails.Error = errMsg
-				portforwardstore(cache, *pfDetails)
-				safeCloseChan(pfDetails.closeChan)
-
-				return
-			}
-		case <-pfDetails.closeChan:
-			logger.Log(logger.LevelInfo, logParams, nil, "Pod monitor stopping: port forward closeChan was closed.")
-
-			return
-		}
-	}
-}
-
 func handlePortForwardError(
 	cache cache.Cache[interface{}],
 	pfDetails *portForward,
@@ -361,112 +326,16 @@ func handlePortForwardSuccess(
 ) {
 	pfDetails.Status = RUNNING
 	pfDetails.Error = ""
+	pfDetails.ReconnectAttempt = 0
 	portforwardstore(cache, *pfDetails)
 	logger.Log(logger.LevelInfo, logParams, nil, "Port forward ready and running.")
 }
 
-// handlePortForwardReadiness waits for the port forward to be ready, handling potential
-// errors from errOut, timeouts, or premature stop signals.
-// It updates the portForward details in the cache based on the outcome.
-func handlePortForwardReadiness(
-	cache cache.Cache[interface{}],
-	pfDetails *portForward,
-	readyChan chan struct{},
-	errOut *bytes.Buffer,
-	logParams map[string]string,
-	forwardErrChan <-chan error,
-) error {
-	select {
-	case <-readyChan:
-		if errOut.String() != "" {
-			return handlePortForwardError(cache, pfDetails, logParams,
-				fmt.Sprintf("portforward failed to start, stderr: %s", errOut.String()), false)
-		}
-
-		handlePortForwardSuccess(cache, pfDetails, logParams)
-	case err := <-forwardErrChan:
-		return handlePortForwardError(cache, pfDetails, logParams, err.Error(), false)
-	case <-time.After(PortForwardReadinessTimeout):
-		return handlePortForwardError(cache, pfDetails, logParams, "timeout waiting for portforward to become ready", false)
-	case <-pfDetails.closeChan:
-		msg := "portforward stopped before becoming ready"
-
-		if pfDetails.Status == RUNNING {
-			pfDetails.Status = STOPPED
-		}
-
-		if pfDetails.Error == "" {
-			pfDetails.Error = msg
-		}
-
-		portforwardstore(cache, *pfDetails)
-		logger.Log(logger.LevelInfo, logParams, nil, msg)
-
-		return errors.New(msg)
-	}
-
-	return nil
-}
-
-// runAndMonitorPortForward starts the actual port forwarding in a goroutine,
-// then handles its readiness, and if ready, starts another goroutine to
-// monitor the target pod's status.
-func runAndMonitorPortForward(
-	clientset *kubernetes.Clientset,
-	cache cache.Cache[interface{}],
-	pfDetails *portForward,
-	forwarder *portforward.PortForwarder,
-	readyChan chan struct{},
-	errOut *bytes.Buffer,
-) error {
-	logParams := map[string]string{
-		"id": pfDetails.ID, "pod": pfDetails.Pod, "port": pfDetails.Port, "targetPort": pfDetails.TargetPort,
-	}
-	forwardErrChan := make(chan error, 1)
-
-	go func() {
-		if err := forwarder.ForwardPorts(); err != nil {
-			logger.Log(logger.LevelError, logParams, err, "ForwardPorts() failed")
-
-			pfDetails.Status = STOPPED
-			pfDetails.Error = err.Error()
-
-			portforwardstore(cache, *pfDetails)
-			select {
-			case forwardErrChan <- err:
-			default:
-			}
-			safeCloseChan(pfDetails.closeChan)
-		} else {
-			logger.Log(logger.LevelInfo, logParams, nil, "ForwardPorts() exited.")
-
-			if pfDetails.Status == RUNNING {
-				pfDetails.Status = STOPPED
-				if pfDetails.Error == "" {
-					pfDetails.Error = "Port forward stopped."
-				}
-
-				portforwardstore(cache, *pfDetails)
-			}
-		}
-
-		close(forwardErrChan)
-	}()
-
-	err := handlePortForwardReadiness(cache, pfDetails, readyChan, errOut, logParams, forwardErrChan)
-	if err != nil {
-		return err
-	}
-
-	go monitorPodAndManagePortForward(clientset, cache, pfDetails)
-
-	return nil
-}
-
 // startPortForward starts a port forward. This is the internal function that was refactored.
-// It sets up Kubernetes clients, initializes the port forwarder, and manages its lifecycle.
+// It sets up Kubernetes clients, starts the port forwarder, and then supervises it in the
+// background, reconnecting when the connection to the pod breaks.
 func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{}],
-	p portForwardRequest, token string, clusterName string,
+	p portForwardRequest, token string, clusterName string, owner string,
 ) error {
 	clientset, rConf, err := getKubeClientAndConfig(kContext, token)
 	if err != nil {
@@ -479,27 +348,9 @@ func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{
 		return fmt.Errorf("permission check failed: %w", err)
 	}
 
-	portMapping := p.Port + ":" + p.TargetPort
-
-	var (
-		forwarder           *portforward.PortForwarder
-		stopChan, readyChan chan struct{}
-		outBuffer, errOut   *bytes.Buffer
-		errInit             error
-	)
-
-	forwarder, stopChan, readyChan, outBuffer, errOut, errInit = initPortForwarder(
-		rConf, p.Namespace, p.Pod, portMapping,
-	)
-	if errInit != nil {
-		return fmt.Errorf("failed to initialize port forwarder: %w", errInit)
-	}
-
-	_ = outBuffer // Avoid unused variable error if outBuffer isn't used directly later
-
 	pfDetails := &portForward{
 		ID:               p.ID,
-		closeChan:        stopChan,
+		closeChan:        make(chan struct{}),
 		Pod:              p.Pod,
 		Cluster:          clusterName,
 		Namespace:        p.Namespace,
@@ -509,12 +360,26 @@ func startPortForward(kContext *kubeconfig.Context, cache cache.Cache[interface{
 		Status:           RUNNING,
 		Port:             p.Port,
 		Error:            "",
+		Owner:            owner,
 	}
 
-	return runAndMonitorPortForward(clientset, cache, pfDetails, forwarder, readyChan, errOut)
+	logParams := portForwardLogParams(pfDetails)
+
+	attempt, err := startForwarder(rConf, pfDetails)
+	if err != nil {
+		return handlePortForwardError(cache, pfDetails, logParams, err.Error(), false)
+	}
+
+	handlePortForwardSuccess(cache, pfDetails, logParams)
+
+	pfDetails.selector = podSelector(clientset, pfDetails)
+
+	go superviseForward(clientset, rConf, cache, pfDetails, attempt)
+
+	return nil
 }
 
-func checkIfPodIsRunning(clientset *kubernetes.Clientset, namespace string, pod string) error {
+func checkIfPodIsRunning(clientset kubernetes.Interface, namespace string, pod string) error {
 	ctx := context.Background()
 
 	p, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod, v1.GetOptions{})
@@ -669,3 +534,38 @@ func GetPortForwardByID(cache cache.Cache[interface{}], w http.ResponseWriter, r
 		return
 	}
 }
+
+// requestOwner returns who owns the port forwards started by the request: the API token it is
+// authenticated with, or else the Headlamp session of the client, whose backend token
+// authenticates it. It is "" if there is neither.
+func requestOwner(r *http.Request) string {
+	if name := auth.APITokenNameFromContext(r.Context()); name != "" {
+		return "apitoken:" + name
+	}
+
+	return r.Header.Get("X-HEADLAMP-USER-ID")
+}
+
+// GetAllPortForwards handles the request to list the port forwards of every cluster started
+// by the owner of the request. The request must be authenticated, and gets an empty list if
+// it has no owner.
+func GetAllPortForwards(cache cache.Cache[interface{}], w http.ResponseWriter, r *http.Request) {
+	owner := requestOwner(r)
+
+	ports := []portForward{}
+
+	for _, p := range getAllPortForwards(cache) {
+		if owner != "" && p.Owner == owner {
+			ports = append(ports, p)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(ports); err != nil {
+		logger.Log(logger.LevelError, nil, err, "writing json payload to response")
+		http.Error(w, "failed to write json payload to response "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
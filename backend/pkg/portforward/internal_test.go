@@ -18,11 +18,23 @@ package portforward
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/auth"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // TestPortforwardKeyGenerator tests portforwardKeyGenerator function.
@@ -142,6 +154,174 @@ func TestGetPortForwardList(t *testing.T) {
 	assert.ElementsMatch(t, []portForward{p3}, pfList)
 }
 
+// TestGetAllPortForwards tests that GetAllPortForwards only lists the port forwards of the
+// owner of the request.
+func TestGetAllPortForwards(t *testing.T) {
+	cache := cache.New[interface{}]()
+
+	portforwardstore(cache, portForward{ID: "id1", Cluster: "cluster1", Owner: "user1"})
+	portforwardstore(cache, portForward{ID: "id2", Cluster: "cluster2", Owner: "user2"})
+	portforwardstore(cache, portForward{ID: "id3", Cluster: "cluster1", Owner: "apitoken:ci"})
+
+	list := func(r *http.Request) []string {
+		rr := httptest.NewRecorder()
+		GetAllPortForwards(cache, rr, r)
+
+		var ports []portForward
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&ports))
+
+		ids := []string{}
+		for _, p := range ports {
+			ids = append(ids, p.ID)
+		}
+
+		return ids
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/portforwards", nil)
+	r.Header.Set("X-HEADLAMP-USER-ID", "user1")
+	assert.Equal(t, []string{"id1"}, list(r))
+
+	// An API token owns the port forwards it started, whatever the user ID header says.
+	r = httptest.NewRequest(http.MethodGet, "/portforwards", nil)
+	r.Header.Set("X-HEADLAMP-USER-ID", "user2")
+	r = r.WithContext(auth.WithAPITokenName(r.Context(), "ci"))
+	assert.Equal(t, []string{"id3"}, list(r))
+
+	// Without an owner nothing is listed, rather than everything.
+	assert.Empty(t, list(httptest.NewRequest(http.MethodGet, "/portforwards", nil)))
+}
+
+// TestStopPortForwardsForContext tests StopPortForwardsForContext function.
+func TestStopPortForwardsForContext(t *testing.T) {
+	ch := make(chan struct{})
+	p1 := portForward{ID: "id1", Cluster: "cluster", closeChan: ch}
+	p2 := portForward{ID: "id2", Cluster: "cluster2"}
+
+	cache := cache.New[interface{}]()
+
+	portforwardstore(cache, p1)
+	portforwardstore(cache, p2)
+
+	StopPortForwardsForContext(cache, "cluster")
+
+	_, ok := <-ch
+	assert.False(t, ok, "port forward should be stopped")
+
+	assert.ElementsMatch(t, []portForward{p2}, getAllPortForwards(cache))
+}
+
+// TestCleanupPortForwards tests CleanupPortForwards function.
+func TestCleanupPortForwards(t *testing.T) {
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "cluster"}))
+
+	p1 := portForward{ID: "id1", Cluster: "cluster"}
+	p2 := portForward{ID: "id2", Cluster: "removed-cluster", closeChan: make(chan struct{})}
+
+	cache := cache.New[interface{}]()
+
+	portforwardstore(cache, p1)
+	portforwardstore(cache, p2)
+
+	CleanupPortForwards(store, cache)
+
+	assert.ElementsMatch(t, []portForward{p1}, getAllPortForwards(cache))
+}
+
+// newTestPod returns a pod of the ReplicaSet "web-abc" in the given phase.
+func newTestPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	isController := true
+
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "web", "pod-template-hash": "abc"},
+			OwnerReferences: []v1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-abc", Controller: &isController},
+			},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+// newTestClientset returns a fake clientset with the given objects, which allows every access review.
+func newTestClientset(objects ...runtime.Object) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	clientset.PrependReactor("create", "selfsubjectaccessreviews",
+		func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, &authv1.SelfSubjectAccessReview{
+				Status: authv1.SubjectAccessReviewStatus{Allowed: true},
+			}, nil
+		})
+
+	return clientset
+}
+
+// TestNextBackoff tests nextBackoff function.
+func TestNextBackoff(t *testing.T) {
+	assert.Equal(t, 2*time.Second, nextBackoff(time.Second))
+	assert.Equal(t, ReconnectMaxBackoff, nextBackoff(20*time.Second))
+	assert.Equal(t, ReconnectMaxBackoff, nextBackoff(ReconnectMaxBackoff))
+}
+
+// TestPodSelector tests podSelector function.
+func TestPodSelector(t *testing.T) {
+	bare := newTestPod("bare", corev1.PodRunning)
+	bare.OwnerReferences = nil
+
+	svc := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web", "tier": "frontend"}},
+	}
+
+	clientset := newTestClientset(newTestPod("web-abc-1", corev1.PodRunning), bare, svc)
+
+	// The revision labels are left out of the selector.
+	assert.Equal(t, "app=web", podSelector(clientset, &portForward{Namespace: "default", Pod: "web-abc-1"}))
+
+	// A pod without a controller is not replaced.
+	assert.Equal(t, "", podSelector(clientset, &portForward{Namespace: "default", Pod: "bare"}))
+
+	// The service selector is used for service port forwards.
+	assert.Equal(t, "app=web,tier=frontend",
+		podSelector(clientset, &portForward{Namespace: "default", Pod: "bare", Service: "web"}))
+}
+
+// TestResolvePod tests resolvePod function.
+func TestResolvePod(t *testing.T) {
+	clientset := newTestClientset(
+		newTestPod("web-abc-1", corev1.PodFailed),
+		newTestPod("web-abc-2", corev1.PodPending),
+		newTestPod("web-abc-3", corev1.PodRunning),
+	)
+
+	// A running pod is kept.
+	pf := &portForward{Namespace: "default", Pod: "web-abc-3", selector: "app=web"}
+	require.NoError(t, resolvePod(clientset, pf))
+	assert.Equal(t, "web-abc-3", pf.Pod)
+
+	// A pod which is not running is replaced by a running pod of the selector.
+	pf = &portForward{Namespace: "default", Pod: "web-abc-1", selector: "app=web"}
+	require.NoError(t, resolvePod(clientset, pf))
+	assert.Equal(t, "web-abc-3", pf.Pod)
+
+	// A deleted pod too.
+	pf = &portForward{Namespace: "default", Pod: "web-abc-0", selector: "app=web"}
+	require.NoError(t, resolvePod(clientset, pf))
+	assert.Equal(t, "web-abc-3", pf.Pod)
+
+	// Without a selector the pod can't be replaced.
+	pf = &portForward{Namespace: "default", Pod: "web-abc-1"}
+	assert.Error(t, resolvePod(clientset, pf))
+
+	// Nor without a running pod.
+	pf = &portForward{Namespace: "default", Pod: "web-abc-1", selector: "app=other"}
+	assert.Error(t, resolvePod(clientset, pf))
+	assert.Equal(t, "web-abc-1", pf.Pod)
+}
+
 // Test portForwardRequest.Validate() function.
 func TestPortForwardRequestValidate(t *testing.T) {
 	req := portForwardRequest{}
@@ -0,0 +1,328 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// ReconnectInitialBackoff is the delay before the first attempt to reconnect a port forward.
+	ReconnectInitialBackoff = time.Second
+	// ReconnectMaxBackoff is the maximum delay between two reconnection attempts.
+	ReconnectMaxBackoff = 30 * time.Second
+	// MaxReconnectAttempts is the number of reconnection attempts before a port forward is stopped.
+	MaxReconnectAttempts = 10
+)
+
+// revisionLabels are the labels controllers set on their pods which differ between
+// revisions, so a pod of a newer revision can replace the forwarded one.
+var revisionLabels = []string{"pod-template-hash", "controller-revision-hash"}
+
+// forwardAttempt is a port forwarder connected to the pod of a port forward.
+// A port forward goes through a new attempt every time it reconnects.
+type forwardAttempt struct {
+	// stopChan stops the forwarder.
+	stopChan chan struct{}
+	// done receives the result of ForwardPorts when the forwarder exits.
+	done chan error
+}
+
+// stop stops the forwarder, it may already be stopped.
+func (a *forwardAttempt) stop() {
+	safeCloseChan(a.stopChan)
+}
+
+// portForwardLogParams returns the log parameters of a port forward.
+func portForwardLogParams(pfDetails *portForward) map[string]string {
+	return map[string]string{
+		"id": pfDetails.ID, "pod": pfDetails.Pod, "namespace": pfDetails.Namespace,
+		"port": pfDetails.Port, "targetPort": pfDetails.TargetPort,
+	}
+}
+
+// isClosed tells whether the channel is closed.
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// startForwarder forwards the local port of the port forward to its current pod and
+// waits for the forwarder to be ready. The forwarder stops with the port forward.
+func startForwarder(rConf *rest.Config, pfDetails *portForward) (*forwardAttempt, error) {
+	forwarder, stopChan, readyChan, _, errOut, err := initPortForwarder(
+		rConf, pfDetails.Namespace, pfDetails.Pod, pfDetails.Port+":"+pfDetails.TargetPort,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize port forwarder: %w", err)
+	}
+
+	attempt := &forwardAttempt{stopChan: stopChan, done: make(chan error, 1)}
+
+	go func() {
+		attempt.done <- forwarder.ForwardPorts()
+	}()
+
+	go func() {
+		select {
+		case <-pfDetails.closeChan:
+			attempt.stop()
+		case <-stopChan:
+		}
+	}()
+
+	select {
+	case <-readyChan:
+		if errOut.String() != "" {
+			attempt.stop()
+
+			return nil, fmt.Errorf("portforward failed to start, stderr: %s", errOut.String())
+		}
+
+		return attempt, nil
+	case err := <-attempt.done:
+		attempt.stop()
+
+		if err == nil {
+			err = errors.New("portforward stopped before becoming ready")
+		}
+
+		return nil, err
+	case <-time.After(PortForwardReadinessTimeout):
+		attempt.stop()
+
+		return nil, errors.New("timeout waiting for portforward to become ready")
+	case <-pfDetails.closeChan:
+		return nil, errors.New("portforward stopped before becoming ready")
+	}
+}
+
+// superviseForward runs until the port forward is stopped. It checks periodically that
+// the pod is still running and reconnects the port forward when the pod stops running
+// or the connection to it is lost.
+func superviseForward(
+	clientset kubernetes.Interface,
+	rConf *rest.Config,
+	cache cache.Cache[interface{}],
+	pfDetails *portForward,
+	attempt *forwardAttempt,
+) {
+	ticker := time.NewTicker(PodAvailabilityCheckTimer * time.Second)
+	defer ticker.Stop()
+
+	for attempt != nil {
+		logParams := portForwardLogParams(pfDetails)
+
+		select {
+		case <-pfDetails.closeChan:
+			logger.Log(logger.LevelInfo, logParams, nil, "Pod monitor stopping: port forward closeChan was closed.")
+
+			return
+		case err := <-attempt.done:
+			// The forwarder exits without an error only when it is stopped.
+			if err == nil {
+				return
+			}
+
+			logger.Log(logger.LevelError, logParams, err, "ForwardPorts() failed, reconnecting")
+
+			attempt = reconnectPortForward(clientset, rConf, cache, pfDetails, err)
+		case <-ticker.C:
+			err := checkIfPodIsRunning(clientset, pfDetails.Namespace, pfDetails.Pod)
+			if err == nil {
+				continue
+			}
+
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				logger.Log(logger.LevelInfo, logParams, err, "checking pod (ECONNREFUSED), continuing")
+
+				continue
+			}
+
+			logger.Log(logger.LevelError, logParams, err, "pod check failed, reconnecting port-forward")
+
+			attempt.stop()
+			attempt = reconnectPortForward(clientset, rConf, cache, pfDetails,
+				fmt.Errorf("pod %s/%s check failed: %w", pfDetails.Namespace, pfDetails.Pod, err))
+		}
+	}
+}
+
+// reconnectPortForward reconnects the port forward on the same local port, backing off
+// exponentially between attempts. The pod is resolved again before each attempt, in case
+// it was replaced. The port forward is RECONNECTING until it is RUNNING again, or STOPPED
+// after MaxReconnectAttempts. It returns the new attempt, or nil if it is stopped.
+func reconnectPortForward(
+	clientset kubernetes.Interface,
+	rConf *rest.Config,
+	cache cache.Cache[interface{}],
+	pfDetails *portForward,
+	cause error,
+) *forwardAttempt {
+	backoff := ReconnectInitialBackoff
+	lastErr := cause
+
+	for i := 1; i <= MaxReconnectAttempts; i++ {
+		if isClosed(pfDetails.closeChan) {
+			return nil
+		}
+
+		pfDetails.Status = RECONNECTING
+		pfDetails.Error = lastErr.Error()
+		pfDetails.ReconnectAttempt = i
+		portforwardstore(cache, *pfDetails)
+
+		select {
+		case <-pfDetails.closeChan:
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff = nextBackoff(backoff)
+
+		if lastErr = resolvePod(clientset, pfDetails); lastErr != nil {
+			logger.Log(logger.LevelError, portForwardLogParams(pfDetails), lastErr, "resolving pod to reconnect")
+
+			continue
+		}
+
+		attempt, err := startForwarder(rConf, pfDetails)
+		if err != nil {
+			lastErr = err
+			logger.Log(logger.LevelError, portForwardLogParams(pfDetails), err, "reconnecting port-forward")
+
+			continue
+		}
+
+		// The port forward may have been stopped while reconnecting.
+		if isClosed(pfDetails.closeChan) {
+			attempt.stop()
+
+			return nil
+		}
+
+		handlePortForwardSuccess(cache, pfDetails, portForwardLogParams(pfDetails))
+
+		return attempt
+	}
+
+	_ = handlePortForwardError(cache, pfDetails, portForwardLogParams(pfDetails),
+		fmt.Sprintf("giving up reconnecting after %d attempts: %v", MaxReconnectAttempts, lastErr), true)
+
+	return nil
+}
+
+// nextBackoff doubles the backoff, up to ReconnectMaxBackoff.
+func nextBackoff(backoff time.Duration) time.Duration {
+	return min(2*backoff, ReconnectMaxBackoff)
+}
+
+// podSelector returns the label selector of the pods that can replace the pod of the
+// port forward: the selector of its service, or else the labels the pod shares with the
+// other pods of its controller. It is empty when the pod can't be replaced by another one.
+func podSelector(clientset kubernetes.Interface, pfDetails *portForward) string {
+	ctx := context.Background()
+
+	if pfDetails.Service != "" {
+		namespace := pfDetails.ServiceNamespace
+		if namespace == "" {
+			namespace = pfDetails.Namespace
+		}
+
+		svc, err := clientset.CoreV1().Services(namespace).Get(ctx, pfDetails.Service, v1.GetOptions{})
+		if err == nil && namespace == pfDetails.Namespace && len(svc.Spec.Selector) > 0 {
+			return labels.SelectorFromSet(svc.Spec.Selector).String()
+		}
+	}
+
+	pod, err := clientset.CoreV1().Pods(pfDetails.Namespace).Get(ctx, pfDetails.Pod, v1.GetOptions{})
+	if err != nil || v1.GetControllerOf(pod) == nil {
+		return ""
+	}
+
+	set := labels.Set{}
+
+	for key, value := range pod.Labels {
+		set[key] = value
+	}
+
+	for _, key := range revisionLabels {
+		delete(set, key)
+	}
+
+	if len(set) == 0 {
+		return ""
+	}
+
+	return labels.SelectorFromSet(set).String()
+}
+
+// resolvePod makes sure the pod of the port forward is running. If it is not, the pod is
+// replaced by a running pod matching the selector of the port forward, for example the
+// pod its controller created to replace it.
+func resolvePod(clientset kubernetes.Interface, pfDetails *portForward) error {
+	err := checkIfPodIsRunning(clientset, pfDetails.Namespace, pfDetails.Pod)
+	if err == nil {
+		return nil
+	}
+
+	if pfDetails.selector == "" {
+		return fmt.Errorf("pod %s/%s is not available: %w", pfDetails.Namespace, pfDetails.Pod, err)
+	}
+
+	pods, err := clientset.CoreV1().Pods(pfDetails.Namespace).List(context.Background(),
+		v1.ListOptions{LabelSelector: pfDetails.selector})
+	if err != nil {
+		return fmt.Errorf("listing pods for %q: %w", pfDetails.selector, err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		// The user may not be allowed to forward every pod of the selector.
+		if err := checkPortForwardPermission(clientset, pfDetails.Namespace, pod.Name); err != nil {
+			continue
+		}
+
+		logger.Log(logger.LevelInfo, portForwardLogParams(pfDetails), nil,
+			"replacing port-forward pod with "+pod.Name)
+
+		pfDetails.Pod = pod.Name
+
+		return nil
+	}
+
+	return fmt.Errorf("no running pod matches %q", pfDetails.selector)
+}
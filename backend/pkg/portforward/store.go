@@ -18,15 +18,22 @@ package portforward
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/cache"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
 	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
 )
 
 const storeKeyPrefix = "PORT_FORWARD_"
 
+// ContextCheckInterval is the interval at which port forwards are checked for
+// contexts that were removed or expired.
+const ContextCheckInterval = 30 * time.Second
+
 // portforwardKeyGenerator generates a unique key
 // based on the cluster name, id,service name, and pod name.
 func portforwardKeyGenerator(p portForward) string {
@@ -68,9 +75,10 @@ func stopOrDeletePortForward(cache cache.Cache[interface{}], cluster string, id
 		return err
 	}
 
+	// close the channel to stop the portforward, it may already be stopped
+	safeCloseChan(portforward.closeChan)
+
 	if isStopRequest {
-		// close the channel to stop the portforward
-		portforward.closeChan <- struct{}{}
 		portforward.Status = STOPPED
 		portforwardstore(cache, portforward)
 	} else {
@@ -120,3 +128,81 @@ func getPortForwardByID(cache cache.Cache[interface{}], cluster string, id strin
 
 	return pf, nil
 }
+
+// getAllPortForwards returns the port forwards of every cluster.
+func getAllPortForwards(cache cache.Cache[interface{}]) []portForward {
+	portforwards, err := cache.GetAll(context.Background(), func(key string) bool {
+		return strings.HasPrefix(key, storeKeyPrefix)
+	})
+	if err != nil {
+		logger.Log(logger.LevelError, nil, err, "getting all portforwards")
+
+		return nil
+	}
+
+	portForwards := []portForward{}
+
+	for _, v := range portforwards {
+		if pf, ok := v.(portForward); ok {
+			portForwards = append(portForwards, pf)
+		}
+	}
+
+	return portForwards
+}
+
+// StopPortForwardsForContext stops and deletes every port forward of the given context.
+// It is called when the context is removed, so its port forwards don't outlive it.
+func StopPortForwardsForContext(cache cache.Cache[interface{}], contextKey string) {
+	for _, pf := range getAllPortForwards(cache) {
+		// The cluster is a key prefix, so compare it exactly to leave similarly named clusters alone.
+		if pf.Cluster != contextKey {
+			continue
+		}
+
+		safeCloseChan(pf.closeChan)
+
+		if err := cache.Delete(context.Background(), portforwardKeyGenerator(pf)); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"cluster": contextKey, "id": pf.ID},
+				err, "deleting portforward")
+		}
+	}
+}
+
+// CleanupPortForwards stops and deletes the port forwards whose context is no longer in
+// the store, either because it was removed or because it expired.
+func CleanupPortForwards(kubeConfigStore kubeconfig.ContextStore, portForwardCache cache.Cache[interface{}]) {
+	stale := map[string]bool{}
+
+	for _, pf := range getAllPortForwards(portForwardCache) {
+		if _, checked := stale[pf.Cluster]; checked {
+			continue
+		}
+
+		_, err := kubeConfigStore.GetContext(pf.Cluster)
+		stale[pf.Cluster] = errors.Is(err, cache.ErrNotFound)
+	}
+
+	for contextKey, isStale := range stale {
+		if isStale {
+			logger.Log(logger.LevelInfo, map[string]string{"cluster": contextKey},
+				nil, "stopping portforwards of removed context")
+			StopPortForwardsForContext(portForwardCache, contextKey)
+		}
+	}
+}
+
+// WatchContexts runs CleanupPortForwards every ContextCheckInterval until ctx is done.
+func WatchContexts(ctx context.Context, kubeConfigStore kubeconfig.ContextStore, cache cache.Cache[interface{}]) {
+	ticker := time.NewTicker(ContextCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			CleanupPortForwards(kubeConfigStore, cache)
+		}
+	}
+}
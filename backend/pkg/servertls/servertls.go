@@ -0,0 +1,197 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package servertls provides the certificates the backend serves TLS with.
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+// CheckInterval is how often the certificate files are checked for changes.
+const CheckInterval = 10 * time.Second
+
+// SelfSignedValidity is how long a self-signed certificate is valid.
+const SelfSignedValidity = 365 * 24 * time.Hour
+
+// CertReloader serves the certificate in certPath and keyPath, and reloads it
+// when the files change, so renewed certificates are used without a restart.
+type CertReloader struct {
+	certPath string
+	keyPath  string
+	interval time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+// NewCertReloader loads the certificate in certPath and keyPath, and returns an error
+// if it cannot be loaded.
+func NewCertReloader(certPath, keyPath string) (*CertReloader, error) {
+	r := &CertReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		interval: CheckInterval,
+		now:      time.Now,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	r.lastCheck = r.now()
+
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It can be used as tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.now().Sub(r.lastCheck) < r.interval {
+		return r.cert, nil
+	}
+
+	r.lastCheck = r.now()
+
+	changed, err := r.changed()
+	if err != nil {
+		logger.Log(logger.LevelWarn, map[string]string{"cert": r.certPath, "key": r.keyPath},
+			err, "checking TLS certificate, keeping the current one")
+
+		return r.cert, nil
+	}
+
+	if changed {
+		if err := r.reload(); err != nil {
+			logger.Log(logger.LevelError, map[string]string{"cert": r.certPath, "key": r.keyPath},
+				err, "reloading TLS certificate, keeping the current one")
+
+			return r.cert, nil
+		}
+
+		logger.Log(logger.LevelInfo, map[string]string{"cert": r.certPath}, nil, "reloaded TLS certificate")
+	}
+
+	return r.cert, nil
+}
+
+// changed returns whether the certificate or the key file changed since they were loaded.
+func (r *CertReloader) changed() (bool, error) {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return false, err
+	}
+
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return false, err
+	}
+
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime), nil
+}
+
+// reload loads the certificate and records the modification times of its files.
+func (r *CertReloader) reload() error {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return fmt.Errorf("reading TLS certificate: %w", err)
+	}
+
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return fmt.Errorf("reading TLS key: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+
+	return nil
+}
+
+// SelfSigned generates a self-signed certificate for the given host names and IP addresses.
+// It is meant for deployments without a certificate, browsers will warn about it.
+func SelfSigned(hosts []string) (*tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("at least one host is needed for a self-signed certificate")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)) //nolint:mnd
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Headlamp"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(SelfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -2,6 +2,8 @@ package telemetry
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -23,6 +25,9 @@ type Metrics struct {
 	ActiveRequestsGauge metric.Int64UpDownCounter
 	// ClusterProxyRequests counts requests made through the cluster proxy
 	ClusterProxyRequests metric.Int64Counter
+	// UpstreamRequests counts the finished API server requests and watches by outcome,
+	// completed or cancelled by the client
+	UpstreamRequests metric.Int64Counter
 	// PluginLoadCount tracks the number of plugin loads
 	PluginLoadCount metric.Int64Counter
 	// PluginDeleteCount tracks the number of plugin deletions
@@ -31,6 +36,27 @@ type Metrics struct {
 	ErrorCounter metric.Int64Counter
 	// KubeconfigRefreshCounter tracks the number of kubeconfig refresh operations
 	KubeconfigRefreshCounter metric.Int64Counter
+	// MultiplexerClients tracks the number of open multiplexer WebSocket connections
+	MultiplexerClients metric.Int64UpDownCounter
+	// MultiplexerLimitExceeded counts connections and subscriptions rejected by per-client limits
+	MultiplexerLimitExceeded metric.Int64Counter
+}
+
+// Outcomes of the upstream requests.
+const (
+	// OutcomeCompleted is the outcome of the upstream requests that ran to their end.
+	OutcomeCompleted = "completed"
+	// OutcomeCancelled is the outcome of the upstream requests cancelled as their client went away.
+	OutcomeCancelled = "cancelled"
+)
+
+// Outcome returns the outcome of an upstream request made with ctx, once it's finished.
+func Outcome(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return OutcomeCancelled
+	}
+
+	return OutcomeCompleted
 }
 
 // NewMetrics creates and registers a set of common application metrics.
@@ -90,6 +116,15 @@ func initRequestMetrics(meter metric.Meter, metrics *Metrics) error {
 		return err
 	}
 
+	metrics.UpstreamRequests, err = meter.Int64Counter(
+		"headlamp.upstream.requests",
+		metric.WithDescription("Number of finished API server requests and watches, by whether they "+
+			"completed or were cancelled by the client"),
+	)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -121,6 +156,22 @@ func initApplicationMetrics(meter metric.Meter, metrics *Metrics) error {
 		return err
 	}
 
+	metrics.MultiplexerClients, err = meter.Int64UpDownCounter(
+		"headlamp.multiplexer.clients",
+		metric.WithDescription("Number of open multiplexer WebSocket connections"),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.MultiplexerLimitExceeded, err = meter.Int64Counter(
+		"headlamp.multiplexer.limit_exceeded",
+		metric.WithDescription("Number of multiplexer connections and subscriptions rejected by per-client limits"),
+	)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -182,6 +233,14 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements the http.Flusher interface so that streaming responses,
+// like Server-Sent Events, keep working behind the metrics middleware.
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // Hijack implements the http.Hijacker interface to support WebSocket connections.
 // This method delegates to the underlying ResponseWriter's Hijacker implementation.
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
@@ -91,8 +91,11 @@ func TestNewMetrics(t *testing.T) {
 	assert.NotNil(t, metrics.RequestDuration)
 	assert.NotNil(t, metrics.ActiveRequestsGauge)
 	assert.NotNil(t, metrics.ClusterProxyRequests)
+	assert.NotNil(t, metrics.UpstreamRequests)
 	assert.NotNil(t, metrics.PluginLoadCount)
 	assert.NotNil(t, metrics.ErrorCounter)
+	assert.NotNil(t, metrics.MultiplexerClients)
+	assert.NotNil(t, metrics.MultiplexerLimitExceeded)
 
 	ctx := context.Background()
 	metrics.RequestCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("test", "value")))
@@ -118,6 +121,21 @@ func TestNewMetrics(t *testing.T) {
 	assert.True(t, found, "Expected to find http.server.request_count metric")
 }
 
+func TestOutcome(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, tel.OutcomeCompleted, tel.Outcome(ctx))
+
+	cancel()
+	assert.Equal(t, tel.OutcomeCancelled, tel.Outcome(ctx))
+
+	// A request running out of time wasn't cancelled by its client.
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	<-ctx.Done()
+	assert.Equal(t, tel.OutcomeCompleted, tel.Outcome(ctx))
+}
+
 func TestRequestCounterMiddleware(t *testing.T) { //nolint:funlen // long function due to several test cases.
 	provider, reader := setupTestMeter(t)
 	t.Cleanup(func() {
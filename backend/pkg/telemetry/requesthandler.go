@@ -67,6 +67,14 @@ func (h *RequestHandler) RecordClusterProxyRequestsCount(ctx context.Context, at
 	}
 }
 
+// RecordUpstreamOutcome counts a finished upstream request or watch by its outcome, see Outcome.
+func (h *RequestHandler) RecordUpstreamOutcome(ctx context.Context, outcome string, attrs ...attribute.KeyValue) {
+	if h.metrics != nil {
+		h.metrics.UpstreamRequests.Add(ctx, 1,
+			metric.WithAttributes(append(attrs, attribute.String("outcome", outcome))...))
+	}
+}
+
 // RecordRequestCount increments request counter metrics with HTTP request details.
 // It automatically adds method and path attributes from the request along with any additional attributes provided.
 func (h *RequestHandler) RecordRequestCount(ctx context.Context, r *http.Request, attrs ...attribute.KeyValue) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 	"time"
 
@@ -201,7 +202,7 @@ func createTracingExporter(cfg cfg.Config) (trace.SpanExporter, error) { //nolin
 	}
 
 	isJaegerConfigured := *cfg.JaegerEndpoint != ""
-	isOTLPConfigured := *cfg.OTLPEndpoint != ""
+	isOTLPConfigured := *cfg.OTLPEndpoint != "" || otlpEnvConfigured()
 
 	if isJaegerConfigured {
 		enabledExporters++
@@ -254,24 +255,52 @@ func createTracingExporter(cfg cfg.Config) (trace.SpanExporter, error) { //nolin
 // createOTLPExporter creates an OpenTelemetry Protocol (OTLP) exporter
 // that can send traces to compatible backends like Jaeger, etc
 // OTLP-compatible systems. It supports both HTTP and gRPC transport protocols.
+// When the standard OTEL_EXPORTER_OTLP_* environment variables set the endpoint,
+// the exporter is configured from them instead of the flags, including the
+// protocol, headers, TLS and timeout.
 func createOTLPExporter(cfg cfg.Config) (trace.SpanExporter, error) {
 	var client otlptrace.Client
 
-	if *cfg.UseOTLPHTTP {
-		client = otlptracehttp.NewClient(
-			otlptracehttp.WithEndpoint(*cfg.OTLPEndpoint),
-			otlptracehttp.WithInsecure(),
-		)
+	fromEnv := otlpEnvConfigured()
+
+	if useOTLPHTTP(cfg) {
+		var opts []otlptracehttp.Option
+		if !fromEnv {
+			opts = append(opts, otlptracehttp.WithEndpoint(*cfg.OTLPEndpoint), otlptracehttp.WithInsecure())
+		}
+
+		client = otlptracehttp.NewClient(opts...)
 	} else {
-		client = otlptracegrpc.NewClient(
-			otlptracegrpc.WithEndpoint(*cfg.OTLPEndpoint),
-			otlptracegrpc.WithInsecure(),
-		)
+		var opts []otlptracegrpc.Option
+		if !fromEnv {
+			opts = append(opts, otlptracegrpc.WithEndpoint(*cfg.OTLPEndpoint), otlptracegrpc.WithInsecure())
+		}
+
+		client = otlptracegrpc.NewClient(opts...)
 	}
 
 	return otlptrace.New(context.Background(), client)
 }
 
+// otlpEnvConfigured tells whether the OTLP endpoint is set by the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables.
+func otlpEnvConfigured() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// useOTLPHTTP tells whether to export traces over HTTP instead of gRPC. The standard
+// OTEL_EXPORTER_OTLP_TRACES_PROTOCOL and OTEL_EXPORTER_OTLP_PROTOCOL environment
+// variables take precedence over the use-otlp-http flag.
+func useOTLPHTTP(cfg cfg.Config) bool {
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if protocol := os.Getenv(env); protocol != "" {
+			return strings.HasPrefix(protocol, "http")
+		}
+	}
+
+	return cfg.UseOTLPHTTP != nil && *cfg.UseOTLPHTTP
+}
+
 // createStdoutExporter creates an exporter that writes traces to stdout.
 // This is primarily useful for debugging or development environments.
 func createStdoutExporter() (trace.SpanExporter, error) {
@@ -8,6 +8,7 @@ import (
 	cfg "github.com/kubernetes-sigs/headlamp/backend/pkg/config"
 	tel "github.com/kubernetes-sigs/headlamp/backend/pkg/telemetry"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewTelemetry(t *testing.T) { //nolint:funlen // multiple test cases function
@@ -95,3 +96,34 @@ func TestNewTelemetry(t *testing.T) { //nolint:funlen // multiple test cases fun
 		})
 	}
 }
+
+func TestNewTelemetryOTLPFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")
+
+	testVersion := "1.0.0"
+	sampleRate := 1.0
+	emptyStr := ""
+	trueVal := true
+	falseVal := false
+
+	telemetry, err := tel.NewTelemetry(cfg.Config{
+		ServiceName:        "test-service",
+		ServiceVersion:     &testVersion,
+		TracingEnabled:     &trueVal,
+		StdoutTraceEnabled: &falseVal,
+		SamplingRate:       &sampleRate,
+		MetricsEnabled:     &falseVal,
+		JaegerEndpoint:     &emptyStr,
+		OTLPEndpoint:       &emptyStr,
+		UseOTLPHTTP:        &falseVal,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, telemetry)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Nothing was exported, so shutting down doesn't need the collector.
+	assert.NoError(t, telemetry.Shutdown(ctx))
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+)
+
+const (
+	// DefaultUpstream is the address an in-cluster agent connects the streams to.
+	DefaultUpstream = "kubernetes.default.svc:443"
+	// minRetryDelay is the delay before reconnecting after the first failure.
+	minRetryDelay = time.Second
+	// maxRetryDelay is the maximum delay between reconnections.
+	maxRetryDelay = 30 * time.Second
+	// upstreamDialTimeout is the maximum time to connect a stream to the upstream.
+	upstreamDialTimeout = 10 * time.Second
+)
+
+// Agent runs in a cluster, and connects the streams opened by the backend to the API server.
+type Agent struct {
+	// URL is the WebSocket URL of the tunnel endpoint of the backend.
+	URL string
+	// Token is the agent token of the cluster, the AgentToken of the tunnel secret of the
+	// backend and the registered name.
+	Token string
+	// Registration describes the cluster to the backend.
+	Registration Registration
+	// Upstream is the address of the API server, defaulting to DefaultUpstream.
+	Upstream string
+	// TLSConfig is the TLS configuration to connect to the backend.
+	TLSConfig *tls.Config
+}
+
+// Run keeps the agent connected to the backend until ctx is done, reconnecting with backoff.
+func (a *Agent) Run(ctx context.Context) error {
+	delay := minRetryDelay
+
+	for {
+		connectedAt := time.Now()
+
+		err := a.Serve(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// A connection that lasted resets the backoff.
+		if time.Since(connectedAt) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+
+		logger.Log(logger.LevelWarn, map[string]string{"retryIn": delay.String()}, err, "tunnel disconnected")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// Serve connects to the backend, registers the cluster and serves the tunnel until it
+// is closed or ctx is done.
+func (a *Agent) Serve(ctx context.Context) error {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: registrationTimeout,
+		TLSClientConfig:  a.TLSConfig,
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, a.URL, http.Header{"Authorization": {"Bearer " + a.Token}})
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", a.URL, err)
+	}
+
+	if err := conn.WriteJSON(a.Registration); err != nil {
+		conn.Close()
+
+		return fmt.Errorf("registering: %w", err)
+	}
+
+	session := newSession(conn, a.connect)
+	if err := session.keepAlive(); err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Close()
+		case <-session.Done():
+		}
+	}()
+
+	return session.run()
+}
+
+// connect connects a stream opened by the backend to the upstream.
+func (a *Agent) connect(st *stream) {
+	defer st.Close()
+
+	upstream := a.Upstream
+	if upstream == "" {
+		upstream = DefaultUpstream
+	}
+
+	dialer := net.Dialer{Timeout: upstreamDialTimeout}
+
+	conn, err := dialer.Dial("tcp", upstream)
+	if err != nil {
+		logger.Log(logger.LevelError, map[string]string{"upstream": upstream}, err, "connecting tunnel stream")
+
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		// Closing the stream ends the copy to the upstream below.
+		_, _ = io.Copy(st, conn)
+		st.Close()
+	}()
+
+	_, _ = io.Copy(conn, st)
+}
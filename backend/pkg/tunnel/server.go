@@ -0,0 +1,256 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/logger"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// DefaultServer is the address of the API server of a cluster, as seen from its agent.
+	DefaultServer = "https://kubernetes.default.svc"
+	// registrationTimeout is the time an agent has to register once connected.
+	registrationTimeout = 10 * time.Second
+)
+
+// ErrNameTaken is returned when an agent registers the name of a context not from a tunnel.
+// It matches kubeconfig.ErrNameConflict.
+var ErrNameTaken = fmt.Errorf("%w: the name is used by a context not from a tunnel", kubeconfig.ErrNameConflict)
+
+// ErrUnauthorized is returned when an agent registers a name its token isn't for.
+var ErrUnauthorized = errors.New("the agent token is not the token of the registered name")
+
+// AgentToken returns the token the agent of the cluster called name authenticates with: the
+// hex HMAC-SHA256 of the name keyed with the tunnel secret of the backend. Each cluster has its
+// own token, so that the agent of a cluster can't register, nor take over, another cluster.
+// It is the output of: echo -n name | openssl dgst -sha256 -hmac secret.
+func AgentToken(secret, name string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(name))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Registration is the first message of an agent, describing its cluster. The agent
+// doesn't send credentials: the users authenticate to the API server with their own.
+type Registration struct {
+	// Name is the name of the context created for the cluster.
+	Name string `json:"name"`
+	// Server is the URL of the API server, defaulting to DefaultServer.
+	Server string `json:"server,omitempty"`
+	// CAData is the PEM CA bundle of the API server.
+	CAData []byte `json:"caData,omitempty"`
+}
+
+// Validate checks the registration.
+func (r *Registration) Validate() error {
+	if errs := validation.IsDNS1123Subdomain(r.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", r.Name, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// Server accepts the agents and keeps a context in the store for each connected one.
+type Server struct {
+	store    kubeconfig.ContextStore
+	secret   string
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewServer creates a server accepting the agents presenting the AgentToken of secret and
+// the name they register as bearer token.
+func NewServer(store kubeconfig.ContextStore, secret string) *Server {
+	return &Server{
+		store:    store,
+		secret:   secret,
+		sessions: map[string]*Session{},
+		upgrader: websocket.Upgrader{
+			// The agents aren't browsers, and are authenticated with their token.
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+	}
+}
+
+// ServeHTTP accepts an agent, and serves its tunnel until it disconnects.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" || s.secret == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.LogCtx(r.Context(), logger.LevelError, nil, err, "upgrading tunnel connection")
+
+		return
+	}
+
+	reg, err := readRegistration(conn)
+	if err != nil {
+		closeWithReason(conn, websocket.CloseProtocolError, err)
+
+		return
+	}
+
+	// The token is only known to be the one of the cluster once it's registered.
+	if !s.authorized(token, reg.Name) {
+		logger.LogCtx(r.Context(), logger.LevelWarn, map[string]string{"context": reg.Name},
+			ErrUnauthorized, "rejecting tunnel")
+		closeWithReason(conn, websocket.ClosePolicyViolation, ErrUnauthorized)
+
+		return
+	}
+
+	session := newSession(conn, nil)
+	if err := session.keepAlive(); err != nil {
+		conn.Close()
+
+		return
+	}
+
+	if err := s.register(reg, session); err != nil {
+		closeWithReason(conn, websocket.ClosePolicyViolation, err)
+
+		return
+	}
+
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{"context": reg.Name}, nil, "tunnel registered")
+
+	err = session.run()
+
+	s.unregister(reg.Name, session)
+
+	logger.LogCtx(r.Context(), logger.LevelInfo, map[string]string{"context": reg.Name}, err, "tunnel unregistered")
+}
+
+// Session returns the session of the connected agent of the context.
+func (s *Server) Session(name string) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[name]
+
+	return session, ok
+}
+
+// authorized tells whether token is the agent token of the cluster called name.
+func (s *Server) authorized(token, name string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(AgentToken(s.secret, name))) == 1
+}
+
+// register stores the context of the cluster of the agent, replacing the session of a
+// previous connection of the agent, which had the same token.
+func (s *Server) register(reg *Registration, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.store.GetContext(reg.Name)
+	if err == nil && existing.Source != kubeconfig.Tunnel {
+		return ErrNameTaken
+	}
+
+	server := reg.Server
+	if server == "" {
+		server = DefaultServer
+	}
+
+	kContext := &kubeconfig.Context{
+		Name:        reg.Name,
+		KubeContext: &api.Context{Cluster: reg.Name, AuthInfo: reg.Name},
+		Cluster:     &api.Cluster{Server: server, CertificateAuthorityData: reg.CAData},
+		AuthInfo:    &api.AuthInfo{},
+		Source:      kubeconfig.Tunnel,
+	}
+	kContext.SetDialer(session.Dial)
+
+	if err := s.store.AddContext(kContext); err != nil {
+		return err
+	}
+
+	if previous, ok := s.sessions[reg.Name]; ok {
+		previous.Close()
+	}
+
+	s.sessions[reg.Name] = session
+
+	return nil
+}
+
+// unregister removes the context of a disconnected agent, unless it reconnected since.
+func (s *Server) unregister(name string, session *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions[name] != session {
+		return
+	}
+
+	delete(s.sessions, name)
+
+	if err := s.store.RemoveContext(name); err != nil {
+		logger.Log(logger.LevelError, map[string]string{"context": name}, err, "removing tunnel context")
+	}
+}
+
+// readRegistration reads the registration of an agent.
+func readRegistration(conn *websocket.Conn) (*Registration, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(registrationTimeout)); err != nil {
+		return nil, err
+	}
+
+	var reg Registration
+	if err := conn.ReadJSON(&reg); err != nil {
+		return nil, fmt.Errorf("reading registration: %w", err)
+	}
+
+	if err := reg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &reg, conn.SetReadDeadline(time.Time{})
+}
+
+// closeWithReason closes the WebSocket, telling the peer why.
+func closeWithReason(conn *websocket.Conn, code int, err error) {
+	msg := websocket.FormatCloseMessage(code, err.Error())
+
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeTimeout))
+	conn.Close()
+}
@@ -0,0 +1,441 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tunnel implements reverse tunnels to clusters with no inbound connectivity.
+//
+// An agent running in the cluster dials out to the backend over a WebSocket and
+// registers the cluster. The backend then opens streams through the WebSocket, which
+// the agent connects to the API server of the cluster. The streams carry raw TCP, so
+// TLS and authentication stay end-to-end between the backend and the API server.
+//
+// Each WebSocket binary message is a frame: a type byte, the big-endian stream ID on
+// four bytes and the payload.
+package tunnel
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// frameOpen opens a stream, sent by the backend.
+	frameOpen byte = iota + 1
+	// frameData carries data of a stream.
+	frameData
+	// frameClose closes a stream, sent by either side.
+	frameClose
+)
+
+const (
+	// headerSize is the size of the header of a frame.
+	headerSize = 5
+	// maxPayload is the maximum payload of a data frame.
+	maxPayload = 32 * 1024
+	// streamBuffer is the number of frames buffered for a stream before the tunnel
+	// waits for the stream to be read.
+	streamBuffer = 64
+	// writeTimeout is the maximum time to write a frame.
+	writeTimeout = 10 * time.Second
+	// pingInterval is the interval the backend pings the agent at.
+	pingInterval = 30 * time.Second
+	// pongTimeout is the time after which a silent tunnel is considered dead.
+	pongTimeout = 3 * pingInterval
+)
+
+// ErrSessionClosed is returned when opening a stream on a closed session.
+var ErrSessionClosed = errors.New("tunnel session closed")
+
+// Session multiplexes streams over the WebSocket of a tunnel.
+type Session struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	streams map[uint32]*stream
+	nextID  uint32
+
+	// accept, set on the agent side, is called for the streams opened by the backend.
+	accept func(*stream)
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSession creates a session over conn. Its read loop must be started with run.
+func newSession(conn *websocket.Conn, accept func(*stream)) *Session {
+	return &Session{
+		conn:    conn,
+		streams: map[uint32]*stream{},
+		accept:  accept,
+		done:    make(chan struct{}),
+	}
+}
+
+// Dial opens a stream to the API server of the cluster. The address is ignored, the
+// agent connecting the stream to its upstream.
+func (s *Session) Dial(ctx context.Context, _, _ string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+
+		return nil, ErrSessionClosed
+	default:
+	}
+
+	s.nextID++
+	st := newStream(s, s.nextID)
+	s.streams[st.id] = st
+
+	s.mu.Unlock()
+
+	if err := s.writeFrame(frameOpen, st.id, nil); err != nil {
+		s.forget(st.id)
+
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// Done returns a channel closed when the session is closed.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close closes the session and all its streams.
+func (s *Session) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		err = s.conn.Close()
+	})
+
+	return err
+}
+
+// run reads the frames until the WebSocket fails, then closes the session. It returns
+// the error of the WebSocket, or nil if the session was closed.
+func (s *Session) run() error {
+	defer s.Close()
+
+	for {
+		msgType, msg, err := s.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		if msgType != websocket.BinaryMessage || len(msg) < headerSize {
+			continue
+		}
+
+		id := binary.BigEndian.Uint32(msg[1:headerSize])
+
+		switch msg[0] {
+		case frameOpen:
+			s.open(id)
+		case frameData:
+			s.deliver(id, msg[headerSize:])
+		case frameClose:
+			s.remoteClose(id)
+		}
+	}
+}
+
+// keepAlive pings the peer and closes the session when it stops answering.
+func (s *Session) keepAlive() error {
+	extend := func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	}
+
+	s.conn.SetPongHandler(extend)
+
+	if err := extend(""); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+					s.Close()
+
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// open accepts a stream opened by the peer, or refuses it if the session doesn't accept any.
+func (s *Session) open(id uint32) {
+	if s.accept == nil {
+		_ = s.writeFrame(frameClose, id, nil)
+
+		return
+	}
+
+	st := newStream(s, id)
+
+	s.mu.Lock()
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	go s.accept(st)
+}
+
+// deliver queues the payload of a data frame for its stream.
+func (s *Session) deliver(id uint32, payload []byte) {
+	s.mu.Lock()
+	st, ok := s.streams[id]
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	select {
+	case st.frames <- payload:
+	case <-st.closed:
+	case <-s.done:
+	}
+}
+
+// remoteClose ends a stream closed by the peer, once its queued data is read.
+func (s *Session) remoteClose(id uint32) {
+	s.mu.Lock()
+	st, ok := s.streams[id]
+	delete(s.streams, id)
+	s.mu.Unlock()
+
+	if ok {
+		close(st.frames)
+	}
+}
+
+// forget removes a stream from the session.
+func (s *Session) forget(id uint32) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+// writeFrame writes a frame to the WebSocket.
+func (s *Session) writeFrame(frameType byte, id uint32, payload []byte) error {
+	msg := make([]byte, headerSize+len(payload))
+	msg[0] = frameType
+	binary.BigEndian.PutUint32(msg[1:headerSize], id)
+	copy(msg[headerSize:], payload)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+
+	return s.conn.WriteMessage(websocket.BinaryMessage, msg)
+}
+
+// stream is a connection multiplexed over a session.
+type stream struct {
+	session *Session
+	id      uint32
+
+	// frames holds the received payloads, and is closed when the peer closes the stream.
+	frames chan []byte
+	buf    []byte
+
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	deadlineMu   sync.Mutex
+	readDeadline time.Time
+}
+
+func newStream(s *Session, id uint32) *stream {
+	return &stream{
+		session: s,
+		id:      id,
+		frames:  make(chan []byte, streamBuffer),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Read reads the data received on the stream.
+func (st *stream) Read(p []byte) (int, error) {
+	if len(st.buf) == 0 {
+		payload, err := st.next()
+		if err != nil {
+			return 0, err
+		}
+
+		st.buf = payload
+	}
+
+	n := copy(p, st.buf)
+	st.buf = st.buf[n:]
+
+	return n, nil
+}
+
+// next waits for the next payload received on the stream.
+func (st *stream) next() ([]byte, error) {
+	var timeout <-chan time.Time
+
+	st.deadlineMu.Lock()
+	deadline := st.readDeadline
+	st.deadlineMu.Unlock()
+
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case payload, ok := <-st.frames:
+		if !ok {
+			return nil, io.EOF
+		}
+
+		return payload, nil
+	case <-st.closed:
+		return nil, net.ErrClosed
+	case <-st.session.done:
+		return nil, io.EOF
+	case <-timeout:
+		return nil, os.ErrDeadlineExceeded
+	}
+}
+
+// Write sends data on the stream, split in frames of at most maxPayload bytes.
+func (st *stream) Write(p []byte) (int, error) {
+	written := 0
+
+	for written < len(p) {
+		select {
+		case <-st.closed:
+			return written, net.ErrClosed
+		default:
+		}
+
+		end := min(written+maxPayload, len(p))
+		if err := st.session.writeFrame(frameData, st.id, p[written:end]); err != nil {
+			return written, err
+		}
+
+		written = end
+	}
+
+	return written, nil
+}
+
+// Close closes the stream and tells the peer.
+func (st *stream) Close() error {
+	var err error
+
+	st.closeOnce.Do(func() {
+		close(st.closed)
+		st.session.forget(st.id)
+
+		err = st.session.writeFrame(frameClose, st.id, nil)
+		if errors.Is(err, ErrSessionClosed) {
+			err = nil
+		}
+	})
+
+	return err
+}
+
+// LocalAddr returns the address of the stream.
+func (st *stream) LocalAddr() net.Addr {
+	return addr(st.id)
+}
+
+// RemoteAddr returns the address of the stream.
+func (st *stream) RemoteAddr() net.Addr {
+	return addr(st.id)
+}
+
+// SetDeadline sets the read deadline of the stream. The writes are bounded by the
+// write timeout of the tunnel.
+func (st *stream) SetDeadline(t time.Time) error {
+	return st.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the stream.
+func (st *stream) SetReadDeadline(t time.Time) error {
+	st.deadlineMu.Lock()
+	st.readDeadline = t
+	st.deadlineMu.Unlock()
+
+	return nil
+}
+
+// SetWriteDeadline does nothing, the writes being bounded by the write timeout of the tunnel.
+func (st *stream) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// addr is the address of a stream.
+type addr uint32
+
+// Network returns the network of the address.
+func (a addr) Network() string {
+	return "tunnel"
+}
+
+// String returns the stream ID.
+func (a addr) String() string {
+	return "stream-" + strconv.FormatUint(uint64(a), 10)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/kubeconfig"
+	"github.com/kubernetes-sigs/headlamp/backend/pkg/tunnel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tunnelSecret = "tunnel-secret"
+
+// newUpstream starts a TLS server standing for the API server of a cluster.
+func newUpstream(t *testing.T) (*httptest.Server, []byte) {
+	t.Helper()
+
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `","auth":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw})
+
+	return upstream, caData
+}
+
+// newAgent creates an agent of the tunnel server, connecting the streams to upstream.
+func newAgent(t *testing.T, server *httptest.Server, upstream *httptest.Server, caData []byte) *tunnel.Agent {
+	t.Helper()
+
+	return &tunnel.Agent{
+		URL:   "ws" + strings.TrimPrefix(server.URL, "http"),
+		Token: tunnel.AgentToken(tunnelSecret, "edge"),
+		Registration: tunnel.Registration{
+			Name: "edge",
+			// Not reachable: the requests only get to the upstream through the tunnel.
+			Server: "https://127.0.0.1:1",
+			CAData: caData,
+		},
+		Upstream: upstream.Listener.Addr().String(),
+	}
+}
+
+func TestTunnel(t *testing.T) {
+	upstream, caData := newUpstream(t)
+	store := kubeconfig.NewContextStore()
+
+	server := httptest.NewServer(tunnel.NewServer(store, tunnelSecret))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- newAgent(t, server, upstream, caData).Run(ctx)
+	}()
+
+	var kContext *kubeconfig.Context
+
+	require.Eventually(t, func() bool {
+		var err error
+
+		kContext, err = store.GetContext("edge")
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, kubeconfig.Tunnel, kContext.Source)
+	assert.Equal(t, "tunnel", kContext.SourceStr())
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/version", nil)
+		req.Header.Set("Authorization", "Bearer user-token")
+
+		rr := httptest.NewRecorder()
+		require.NoError(t, kContext.ProxyRequest(rr, req))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"path":"/version","auth":"Bearer user-token"}`, rr.Body.String())
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent didn't stop")
+	}
+
+	require.Eventually(t, func() bool {
+		_, err := store.GetContext("edge")
+
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTunnelUnauthorized(t *testing.T) {
+	upstream, caData := newUpstream(t)
+	store := kubeconfig.NewContextStore()
+
+	server := httptest.NewServer(tunnel.NewServer(store, tunnelSecret))
+	t.Cleanup(server.Close)
+
+	agent := newAgent(t, server, upstream, caData)
+	agent.Token = "wrong"
+
+	err := agent.Serve(context.Background())
+	require.Error(t, err)
+
+	_, err = store.GetContext("edge")
+	assert.Error(t, err)
+}
+
+func TestTunnelTokenOfAnotherCluster(t *testing.T) {
+	upstream, caData := newUpstream(t)
+	store := kubeconfig.NewContextStore()
+
+	server := httptest.NewServer(tunnel.NewServer(store, tunnelSecret))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = newAgent(t, server, upstream, caData).Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, err := store.GetContext("edge")
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Neither the agent of another cluster nor the secret can take over the live session.
+	for _, token := range []string{tunnel.AgentToken(tunnelSecret, "other"), tunnelSecret} {
+		agent := newAgent(t, server, upstream, caData)
+		agent.Registration.CAData = nil
+		agent.Token = token
+
+		err := agent.Serve(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tunnel.ErrUnauthorized.Error())
+
+		kContext, err := store.GetContext("edge")
+		require.NoError(t, err)
+		assert.Equal(t, caData, kContext.Cluster.CertificateAuthorityData)
+	}
+}
+
+func TestTunnelNameTaken(t *testing.T) {
+	upstream, caData := newUpstream(t)
+	store := kubeconfig.NewContextStore()
+	require.NoError(t, store.AddContext(&kubeconfig.Context{Name: "edge", Source: kubeconfig.KubeConfig}))
+
+	server := httptest.NewServer(tunnel.NewServer(store, tunnelSecret))
+	t.Cleanup(server.Close)
+
+	err := newAgent(t, server, upstream, caData).Serve(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), tunnel.ErrNameTaken.Error())
+
+	kContext, err := store.GetContext("edge")
+	require.NoError(t, err)
+	assert.Equal(t, kubeconfig.KubeConfig, kContext.Source)
+}
+
+func TestRegistrationValidate(t *testing.T) {
+	assert.NoError(t, (&tunnel.Registration{Name: "edge.example.com"}).Validate())
+	assert.Error(t, (&tunnel.Registration{Name: ""}).Validate())
+	assert.Error(t, (&tunnel.Registration{Name: "Edge/1"}).Validate())
+}